@@ -0,0 +1,274 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package actuator 提供查看应用内部状态的管理端点，包括 bean 及其依赖关系、
+// 生效的属性及其来源、路由映射、配置结构体、协程快照、构建信息以及启动耗时。
+// 管理端点可以挂载到业务 Web 服务器上，也可以通过单独的管理端口对外提供服务。
+// 另外还可以通过 loggers 端点在运行时修改日志的输出级别。
+package actuator
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/buildinfo"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+// Bean bean 的描述信息。
+type Bean struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Source       string   `json:"source"`                 // 注册点
+	Dependencies []string `json:"dependencies,omitempty"` // 注入的其他 bean 的 ID
+}
+
+// Property 属性值及其来源。
+type Property struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin,omitempty"` // 属性来源，如配置文件的路径
+}
+
+// ConfigProp 绑定到属性上的配置结构体。
+type ConfigProp struct {
+	Bean   string      `json:"bean"`   // 所属 bean 的 ID
+	Prefix string      `json:"prefix"` // 绑定的属性前缀
+	Type   string      `json:"type"`
+	Value  interface{} `json:"value"`
+}
+
+// StartupStep 启动过程中一个阶段的耗时。
+type StartupStep struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	Duration  float64   `json:"durationMs"`
+}
+
+// BeanStartup bean 注入的耗时，Total 包含注入其依赖项的耗时，Self 不包含。
+type BeanStartup struct {
+	ID     string  `json:"id"`
+	Source string  `json:"source"`
+	Total  float64 `json:"totalMs"`
+	Self   float64 `json:"selfMs"`
+}
+
+// Startup 启动耗时报告，Beans 按照 Self 从大到小排列。
+type Startup struct {
+	Steps []StartupStep `json:"steps"`
+	Beans []BeanStartup `json:"beans"`
+}
+
+// Inspector 提供应用启动后容器内部状态的快照，由 gs 包实现。
+type Inspector interface {
+	Beans() []Bean
+	Properties() []Property
+	ConfigProps() []ConfigProp
+	Startup() Startup
+}
+
+// Endpoint 管理端点，导出为该接口的 bean 被挂载到 {base-path}/{ID} 上。
+type Endpoint interface {
+	ID() string
+	http.Handler
+}
+
+// Config 管理端点配置，通常绑定到 management 前缀的属性上。
+type Config struct {
+	Port         int      `value:"${port:=0}"`               // 管理端口，0 表示挂载到业务 Web 服务器上
+	BasePath     string   `value:"${base-path:=/actuator}"`  // 端点的路径前缀
+	Exposure     []string `value:"${exposure}"`              // 开放的端点，为空时开放全部端点
+	Username     string   `value:"${username:=}"`            // 设置后开启 Basic 认证
+	Password     string   `value:"${password:=}"`            // Basic 认证的密码
+	Token        string   `value:"${token:=}"`               // 设置后开启 Bearer 认证
+	SanitizeKeys []string `value:"${sanitize-keys}"`         // 需要脱敏的属性名关键字，为空时使用 DefaultSanitizeKeys
+	Pprof        bool     `value:"${pprof.enabled:=false}"`  // 是否开启 pprof 端点，建议只在管理端口上开启
+	Expvar       bool     `value:"${expvar.enabled:=false}"` // 是否开启 expvar 端点
+}
+
+// Server 管理端点服务器，收集内置端点和导出为 Endpoint 接口的 bean ，端口为
+// 0 时挂载到业务 Web 服务器上，否则在管理端口上启动单独的 HTTP 服务器。健康检
+// 查和 Prometheus 指标仍然由业务 Web 服务器提供。
+type Server struct {
+	Router     web.Router      `autowire:""`
+	Inspector  Inspector       `autowire:""`
+	Endpoints  []Endpoint      `autowire:""`
+	LevelStore LevelStore      `autowire:"?"`
+	BuildInfo  *buildinfo.Info `autowire:"?"`
+
+	config    *Config // 使用指针避免容器对其进行属性绑定
+	endpoints map[string]Endpoint
+	server    *http.Server
+	wg        sync.WaitGroup
+}
+
+// NewServer Server 的构造函数。
+func NewServer(config Config) *Server {
+	return &Server{config: &config}
+}
+
+// OnInit 挂载所有开放的管理端点。
+func (s *Server) OnInit() error {
+
+	base := "/" + strings.Trim(s.config.BasePath, "/")
+
+	s.endpoints = make(map[string]Endpoint)
+	builtin := []Endpoint{
+		&beansEndpoint{s.Inspector},
+		&envEndpoint{s.Inspector, s.sanitizer()},
+		&configPropsEndpoint{s.Inspector, s.sanitizer()},
+		&mappingsEndpoint{s.Router},
+		threadDumpEndpoint{},
+		&infoEndpoint{s.Inspector, s.BuildInfo},
+		&loggersEndpoint{s.LevelStore},
+		&startupEndpoint{s.Inspector},
+	}
+	if s.config.Pprof {
+		builtin = append(builtin, &pprofEndpoint{prefix: base + "/pprof"})
+	}
+	if s.config.Expvar {
+		builtin = append(builtin, expvarEndpoint{})
+	}
+	for _, e := range append(builtin, s.Endpoints...) {
+		if s.exposed(e.ID()) {
+			s.endpoints[e.ID()] = e
+		}
+	}
+
+	if s.config.Port == 0 {
+		s.Router.HandleGet(base, web.WrapH(s.auth(http.HandlerFunc(s.index))))
+		for id, e := range s.endpoints {
+			h := web.WrapH(s.auth(e))
+			s.Router.HandleRequest(web.MethodGetPost, base+"/"+id, h)
+			if t, ok := e.(subtree); ok && t.subtree() {
+				s.Router.HandleRequest(web.MethodGetPost, base+"/"+id+"/*", h)
+			}
+		}
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(base, s.auth(http.HandlerFunc(s.index)))
+	for id, e := range s.endpoints {
+		mux.Handle(base+"/"+id, s.auth(e))
+		if t, ok := e.(subtree); ok && t.subtree() {
+			mux.Handle(base+"/"+id+"/", s.auth(e))
+		}
+	}
+
+	addr := net.JoinHostPort("", strconv.Itoa(s.config.Port))
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.server = &http.Server{Handler: mux}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		log.Infof("management server started on %s", addr)
+		if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("management server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// OnDestroy 关闭管理端口上的 HTTP 服务器。
+func (s *Server) OnDestroy() {
+	if s.server == nil {
+		return
+	}
+	if err := s.server.Shutdown(context.Background()); err != nil {
+		log.Error(err)
+	}
+	s.wg.Wait()
+}
+
+// exposed 返回端点是否对外开放。
+func (s *Server) exposed(id string) bool {
+	if len(s.config.Exposure) == 0 {
+		return true
+	}
+	for _, v := range s.config.Exposure {
+		if v == id || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// index 返回所有开放的端点的路径。
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	base := "/" + strings.Trim(s.config.BasePath, "/")
+	links := make(map[string]string)
+	for id := range s.endpoints {
+		links[id] = base + "/" + id
+	}
+	writeJSON(w, map[string]interface{}{"links": links})
+}
+
+// auth 对管理端点进行认证，用户名和令牌都没有设置时不进行认证。
+func (s *Server) auth(h http.Handler) http.Handler {
+
+	if s.config.Username == "" && s.config.Token == "" {
+		return h
+	}
+
+	equal := func(a, b string) bool {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Username != "" {
+			if user, pass, ok := r.BasicAuth(); ok &&
+				equal(user, s.config.Username) && equal(pass, s.config.Password) {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		if s.config.Token != "" {
+			const prefix = "Bearer "
+			if v := r.Header.Get("Authorization"); strings.HasPrefix(v, prefix) &&
+				equal(strings.TrimPrefix(v, prefix), s.config.Token) {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		if s.config.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="actuator"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Error(err)
+	}
+}
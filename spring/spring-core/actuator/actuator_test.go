@@ -0,0 +1,334 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/actuator"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type dbConfig struct {
+	URL      string        `value:"${url}"`
+	Password string        `value:"${password:=}"`
+	Timeout  time.Duration `value:"${timeout:=3s}"`
+}
+
+type inspector struct{}
+
+func (inspector) Beans() []actuator.Bean {
+	return []actuator.Bean{{ID: "*sql.DB:db", Name: "db", Type: "*sql.DB"}}
+}
+
+func (inspector) Properties() []actuator.Property {
+	return []actuator.Property{
+		{Key: "db.password", Value: "123456", Origin: "config/application.yaml"},
+		{Key: "db.url", Value: "mysql://localhost", Origin: "config/application.yaml"},
+		{Key: "info.app.name", Value: "demo", Origin: "code"},
+	}
+}
+
+func (inspector) ConfigProps() []actuator.ConfigProp {
+	return []actuator.ConfigProp{{
+		Bean:   "*sql.DB:db",
+		Prefix: "db",
+		Type:   "actuator_test.dbConfig",
+		Value:  dbConfig{URL: "mysql://localhost", Password: "123456", Timeout: time.Second},
+	}}
+}
+
+func (inspector) Startup() actuator.Startup {
+	return actuator.Startup{
+		Steps: []actuator.StartupStep{{Name: "wire-beans", Duration: 12.5}},
+		Beans: []actuator.BeanStartup{
+			{ID: "*sql.DB:db", Total: 10, Self: 10},
+			{ID: "*redis.Client:redis", Total: 2, Self: 2},
+		},
+	}
+}
+
+func startServer(t *testing.T, config actuator.Config, store ...actuator.LevelStore) (*actuator.Server, string) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	config.Port = l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	if config.BasePath == "" {
+		config.BasePath = "/actuator"
+	}
+	config.SanitizeKeys = []string{"password"}
+
+	s := actuator.NewServer(config)
+	s.Router = web.NewRouter()
+	s.Inspector = inspector{}
+	if len(store) > 0 {
+		s.LevelStore = store[0]
+	}
+	assert.Nil(t, s.OnInit())
+	return s, "http://127.0.0.1:" + strconv.Itoa(config.Port) + config.BasePath
+}
+
+func get(t *testing.T, url string, fn func(r *http.Request)) (int, string) {
+	return do(t, http.MethodGet, url, "", fn)
+}
+
+func do(t *testing.T, method string, url string, body string, fn func(r *http.Request)) (int, string) {
+	r, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.Nil(t, err)
+	if fn != nil {
+		fn(r)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestServer(t *testing.T) {
+
+	s, base := startServer(t, actuator.Config{})
+	defer s.OnDestroy()
+
+	code, body := get(t, base+"/env", nil)
+	assert.Equal(t, code, http.StatusOK)
+	var env struct {
+		Properties []actuator.Property `json:"properties"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &env))
+	assert.Equal(t, env.Properties[0].Value, "******")
+	assert.Equal(t, env.Properties[1].Value, "mysql://localhost")
+	assert.Equal(t, env.Properties[1].Origin, "config/application.yaml")
+
+	code, body = get(t, base+"/env?prefix=info.", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Nil(t, json.Unmarshal([]byte(body), &env))
+	assert.Equal(t, len(env.Properties), 1)
+
+	code, body = get(t, base+"/configprops", nil)
+	assert.Equal(t, code, http.StatusOK)
+	var props struct {
+		ConfigProps []struct {
+			Value map[string]interface{} `json:"value"`
+		} `json:"configprops"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &props))
+	assert.Equal(t, props.ConfigProps[0].Value, map[string]interface{}{
+		"url":      "mysql://localhost",
+		"password": "******",
+		"timeout":  "1s",
+	})
+
+	code, body = get(t, base+"/beans", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"*sql.DB:db"`))
+
+	code, body = get(t, base+"/info", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"app.name": "demo"`))
+	assert.True(t, strings.Contains(body, `"go": "go`))
+
+	code, body = get(t, base+"/startup?top=1", nil)
+	assert.Equal(t, code, http.StatusOK)
+	var startup actuator.Startup
+	assert.Nil(t, json.Unmarshal([]byte(body), &startup))
+	assert.Equal(t, len(startup.Beans), 1)
+	assert.Equal(t, startup.Beans[0].ID, "*sql.DB:db")
+	assert.Equal(t, startup.Steps[0].Duration, 12.5)
+
+	code, body = get(t, base+"/threaddump", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, "goroutine"))
+
+	code, body = get(t, base, nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"mappings": "/actuator/mappings"`))
+}
+
+func TestServer_Exposure(t *testing.T) {
+
+	s, base := startServer(t, actuator.Config{Exposure: []string{"info"}})
+	defer s.OnDestroy()
+
+	code, _ := get(t, base+"/info", nil)
+	assert.Equal(t, code, http.StatusOK)
+
+	code, _ = get(t, base+"/env", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestServer_Auth(t *testing.T) {
+
+	s, base := startServer(t, actuator.Config{
+		Username: "admin",
+		Password: "secret",
+		Token:    "abc",
+	})
+	defer s.OnDestroy()
+
+	code, _ := get(t, base+"/info", nil)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	code, _ = get(t, base+"/info", func(r *http.Request) {
+		r.SetBasicAuth("admin", "wrong")
+	})
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	code, _ = get(t, base+"/info", func(r *http.Request) {
+		r.SetBasicAuth("admin", "secret")
+	})
+	assert.Equal(t, code, http.StatusOK)
+
+	code, _ = get(t, base+"/info", func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer abc")
+	})
+	assert.Equal(t, code, http.StatusOK)
+}
+
+type levelStore map[string]string
+
+func (s levelStore) SaveLevel(name string, level string) error {
+	if name == "fail" {
+		return errors.New("config server unavailable")
+	}
+	s[name] = level
+	return nil
+}
+
+func TestServer_Loggers(t *testing.T) {
+
+	store := levelStore{}
+	s, base := startServer(t, actuator.Config{}, store)
+	defer s.OnDestroy()
+	defer log.Reset()
+
+	code, _ := do(t, http.MethodPost, base+"/loggers?name=mq", `{"configuredLevel":"debug"}`, nil)
+	assert.Equal(t, code, http.StatusNoContent)
+	assert.Equal(t, log.LoggerLevel("mq.kafka"), log.DebugLevel)
+	assert.Equal(t, store, levelStore{"mq": "debug"})
+
+	code, body := get(t, base+"/loggers?name=mq.kafka", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"effectiveLevel": "debug"`))
+	assert.False(t, strings.Contains(body, "configuredLevel"))
+
+	code, body = get(t, base+"/loggers", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"mq": {`))
+
+	code, _ = do(t, http.MethodPost, base+"/loggers?name=mq", `{"configuredLevel":"verbose"}`, nil)
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	code, _ = do(t, http.MethodPost, base+"/loggers?name=fail", `{"configuredLevel":"debug"}`, nil)
+	assert.Equal(t, code, http.StatusInternalServerError)
+	assert.Equal(t, log.LoggerLevel("fail"), log.InfoLevel)
+
+	code, _ = do(t, http.MethodPost, base+"/loggers?name=mq", `{}`, nil)
+	assert.Equal(t, code, http.StatusNoContent)
+	assert.Equal(t, log.LoggerLevel("mq"), log.InfoLevel)
+	assert.Equal(t, store, levelStore{"mq": ""})
+
+	code, _ = do(t, http.MethodPost, base+"/loggers", `{"configuredLevel":"warn"}`, nil)
+	assert.Equal(t, code, http.StatusNoContent)
+	assert.Equal(t, log.GetLevel(), log.WarnLevel)
+}
+
+func TestServer_Pprof(t *testing.T) {
+
+	s, base := startServer(t, actuator.Config{Pprof: true, Expvar: true})
+	defer s.OnDestroy()
+
+	code, body := get(t, base+"/pprof", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"href": "/actuator/pprof/heap"`))
+
+	code, body = get(t, base+"/pprof/goroutine?debug=1", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.HasPrefix(body, "goroutine profile:"))
+
+	code, body = get(t, base+"/pprof/heap?gc=1", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, len(body) > 0)
+
+	code, body = get(t, base+"/pprof/profile?seconds=1", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, len(body) > 0)
+
+	code, _ = get(t, base+"/pprof/unknown", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, body = get(t, base+"/expvar", nil)
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, strings.Contains(body, `"memstats"`))
+}
+
+func TestServer_PprofDisabled(t *testing.T) {
+
+	s, base := startServer(t, actuator.Config{})
+	defer s.OnDestroy()
+
+	code, _ := get(t, base+"/pprof/heap", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _ = get(t, base+"/expvar", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestServer_Bind(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	c := gs.New()
+	c.Property("management.port", port)
+	c.Property("management.exposure[0]", "env")
+	c.Object(web.NewRouter()).Export((*web.Router)(nil))
+	c.Object(&inspector{}).Export((*actuator.Inspector)(nil))
+	c.Provide(actuator.NewServer, "${management}")
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+
+	base := "http://127.0.0.1:" + strconv.Itoa(port) + "/actuator"
+
+	// 未配置 sanitize-keys 时使用默认的脱敏关键字。
+	code, body := get(t, base+"/env", nil)
+	assert.Equal(t, code, http.StatusOK)
+	var env struct {
+		Properties []actuator.Property `json:"properties"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &env))
+	assert.Equal(t, env.Properties[0].Value, "******")
+
+	code, _ = get(t, base+"/beans", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/buildinfo"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+// beansEndpoint 返回所有的 bean 及其依赖关系。
+type beansEndpoint struct {
+	inspector Inspector
+}
+
+func (e *beansEndpoint) ID() string {
+	return "beans"
+}
+
+func (e *beansEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{"beans": e.inspector.Beans()})
+}
+
+// envEndpoint 返回生效的属性及其来源，可以通过 prefix 参数过滤属性。
+type envEndpoint struct {
+	inspector Inspector
+	sanitizer *sanitizer
+}
+
+func (e *envEndpoint) ID() string {
+	return "env"
+}
+
+func (e *envEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	properties := make([]Property, 0)
+	for _, p := range e.inspector.Properties() {
+		if !strings.HasPrefix(p.Key, prefix) {
+			continue
+		}
+		if e.sanitizer.sensitive(p.Key) {
+			p.Value = masked
+		}
+		properties = append(properties, p)
+	}
+	writeJSON(w, map[string]interface{}{"properties": properties})
+}
+
+// configPropsEndpoint 返回绑定到属性上的配置结构体。
+type configPropsEndpoint struct {
+	inspector Inspector
+	sanitizer *sanitizer
+}
+
+func (e *configPropsEndpoint) ID() string {
+	return "configprops"
+}
+
+func (e *configPropsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	props := make([]ConfigProp, 0)
+	for _, p := range e.inspector.ConfigProps() {
+		p.Value = e.sanitizer.sanitize(p.Value)
+		props = append(props, p)
+	}
+	writeJSON(w, map[string]interface{}{"configprops": props})
+}
+
+type mapping struct {
+	Methods []string `json:"methods"`
+	Path    string   `json:"path"`
+	Handler string   `json:"handler"`
+	Source  string   `json:"source"`
+}
+
+// mappingsEndpoint 返回所有的 HTTP 路由映射。
+type mappingsEndpoint struct {
+	router web.Router
+}
+
+func (e *mappingsEndpoint) ID() string {
+	return "mappings"
+}
+
+func (e *mappingsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mappings := make([]mapping, 0)
+	for _, m := range e.router.Mappers() {
+		file, line, fnName := m.Handler().FileLine()
+		mappings = append(mappings, mapping{
+			Methods: web.GetMethod(m.Method()),
+			Path:    m.Path(),
+			Handler: fnName,
+			Source:  file + ":" + strconv.Itoa(line),
+		})
+	}
+	writeJSON(w, map[string]interface{}{"mappings": mappings})
+}
+
+// threadDumpEndpoint 返回所有协程的调用栈，debug 参数与 pprof 的含义相同，
+// 默认为 2 即输出每个协程的完整调用栈。
+type threadDumpEndpoint struct{}
+
+func (threadDumpEndpoint) ID() string {
+	return "threaddump"
+}
+
+func (threadDumpEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	debugLevel := 2
+	if s := r.URL.Query().Get("debug"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil {
+			debugLevel = n
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := pprof.Lookup("goroutine").WriteTo(w, debugLevel); err != nil {
+		log.Error(err)
+	}
+}
+
+// infoEndpoint 返回 info 前缀的属性以及程序的构建信息。
+type infoEndpoint struct {
+	inspector Inspector
+	build     *buildinfo.Info
+}
+
+func (e *infoEndpoint) ID() string {
+	return "info"
+}
+
+func (e *infoEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	const prefix = "info."
+	app := make(map[string]string)
+	for _, p := range e.inspector.Properties() {
+		if strings.HasPrefix(p.Key, prefix) {
+			app[strings.TrimPrefix(p.Key, prefix)] = p.Value
+		}
+	}
+
+	build := e.build
+	if build == nil {
+		build = buildinfo.Read("")
+	}
+	writeJSON(w, map[string]interface{}{"app": app, "build": build})
+}
+
+// startupEndpoint 返回启动过程中各个阶段以及各个 bean 的耗时，top 参数限制返回
+// 的 bean 的数量。
+type startupEndpoint struct {
+	inspector Inspector
+}
+
+func (e *startupEndpoint) ID() string {
+	return "startup"
+}
+
+func (e *startupEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := e.inspector.Startup()
+	if n, err := strconv.Atoi(r.URL.Query().Get("top")); err == nil && n >= 0 && n < len(s.Beans) {
+		s.Beans = s.Beans[:n]
+	}
+	writeJSON(w, s)
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// rootLogger 全局日志的名称。
+const rootLogger = "root"
+
+// LevelStore 持久化日志级别的修改，例如写回远程配置中心的 logging.level.{name}
+// 属性，使修改在应用重启后仍然生效。level 为空表示删除该日志的级别。
+type LevelStore interface {
+	SaveLevel(name string, level string) error
+}
+
+type loggerLevel struct {
+	ConfiguredLevel string `json:"configuredLevel,omitempty"`
+	EffectiveLevel  string `json:"effectiveLevel,omitempty"`
+}
+
+// loggersEndpoint 查看和修改日志的输出级别。GET 请求返回所有设置过级别的日志，
+// 指定 name 参数时只返回该日志的级别；POST 请求修改 name 参数指定的日志的级别，
+// 请求体为 {"configuredLevel":"debug"}，级别为空时恢复使用上一层级的级别。name
+// 为空或者为 root 时表示全局级别。
+type loggersEndpoint struct {
+	store LevelStore
+}
+
+func (e *loggersEndpoint) ID() string {
+	return "loggers"
+}
+
+func (e *loggersEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		e.get(w, r)
+	case http.MethodPost:
+		e.post(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (e *loggersEndpoint) get(w http.ResponseWriter, r *http.Request) {
+
+	if name := r.URL.Query().Get("name"); name != "" {
+		writeJSON(w, describeLogger(name))
+		return
+	}
+
+	var levels []string
+	for level := log.TraceLevel; level <= log.FatalLevel; level++ {
+		levels = append(levels, level.String())
+	}
+
+	loggers := map[string]loggerLevel{rootLogger: describeLogger(rootLogger)}
+	for name := range log.Loggers() {
+		loggers[name] = describeLogger(name)
+	}
+	writeJSON(w, map[string]interface{}{"levels": levels, "loggers": loggers})
+}
+
+func (e *loggersEndpoint) post(w http.ResponseWriter, r *http.Request) {
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = rootLogger
+	}
+
+	var req loggerLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level := log.InfoLevel
+	if req.ConfiguredLevel != "" {
+		var err error
+		if level, err = log.ParseLevel(req.ConfiguredLevel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if e.store != nil {
+		if err := e.store.SaveLevel(name, req.ConfiguredLevel); err != nil {
+			log.Errorf("save level of logger %s error: %v", name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	switch {
+	case name == rootLogger:
+		log.SetLevel(level)
+	case req.ConfiguredLevel == "":
+		log.ResetLoggerLevel(name)
+	default:
+		log.SetLoggerLevel(name, level)
+	}
+	log.Infof("set level of logger %s to %q", name, req.ConfiguredLevel)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// describeLogger 返回日志设置的级别和实际生效的级别。
+func describeLogger(name string) loggerLevel {
+	if name == rootLogger {
+		level := log.GetLevel().String()
+		return loggerLevel{ConfiguredLevel: level, EffectiveLevel: level}
+	}
+	var r loggerLevel
+	if level, ok := log.Loggers()[name]; ok {
+		r.ConfiguredLevel = level.String()
+	}
+	r.EffectiveLevel = log.LoggerLevel(name).String()
+	return r
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subtree 可以由 Endpoint 实现的可选接口，返回 true 时端点同时处理其路径下的
+// 所有子路径。
+type subtree interface {
+	subtree() bool
+}
+
+// pprofEndpoint 提供与 net/http/pprof 兼容的性能分析端点，可以直接使用 go tool
+// pprof 访问，但不会像 net/http/pprof 那样注册到 http.DefaultServeMux 上。其中
+// {path} 返回所有 profile 的列表，{path}/profile?seconds=N 采集 N 秒的 CPU
+// profile，{path}/trace?seconds=N 采集 N 秒的执行追踪，{path}/{name}?debug=N
+// 下载 heap、goroutine 等 profile ，gc 参数不为空时先执行 GC 。
+type pprofEndpoint struct {
+	prefix string
+}
+
+func (e *pprofEndpoint) ID() string {
+	return "pprof"
+}
+
+func (e *pprofEndpoint) subtree() bool {
+	return true
+}
+
+func (e *pprofEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, e.prefix), "/")
+	switch name {
+	case "":
+		e.index(w, r)
+	case "profile":
+		e.cpu(w, r)
+	case "trace":
+		e.trace(w, r)
+	default:
+		e.lookup(w, r, name)
+	}
+}
+
+func (e *pprofEndpoint) index(w http.ResponseWriter, r *http.Request) {
+	type profile struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+		Href  string `json:"href"`
+	}
+	profiles := []profile{
+		{Name: "profile", Href: e.prefix + "/profile?seconds=30"},
+		{Name: "trace", Href: e.prefix + "/trace?seconds=1"},
+	}
+	for _, p := range pprof.Profiles() {
+		profiles = append(profiles, profile{
+			Name:  p.Name(),
+			Count: p.Count(),
+			Href:  e.prefix + "/" + p.Name(),
+		})
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	writeJSON(w, map[string]interface{}{"profiles": profiles})
+}
+
+// cpu 采集 CPU profile 。
+func (e *pprofEndpoint) cpu(w http.ResponseWriter, r *http.Request) {
+	attachment(w, "profile")
+	if err := pprof.StartCPUProfile(w); err != nil {
+		serveError(w, http.StatusInternalServerError, fmt.Sprintf("could not enable CPU profiling: %v", err))
+		return
+	}
+	sleep(r, seconds(r, 30))
+	pprof.StopCPUProfile()
+}
+
+// trace 采集执行追踪。
+func (e *pprofEndpoint) trace(w http.ResponseWriter, r *http.Request) {
+	attachment(w, "trace")
+	if err := trace.Start(w); err != nil {
+		serveError(w, http.StatusInternalServerError, fmt.Sprintf("could not enable tracing: %v", err))
+		return
+	}
+	sleep(r, seconds(r, 1))
+	trace.Stop()
+}
+
+// lookup 下载名为 name 的 profile ，debug 不为 0 时返回文本格式。
+func (e *pprofEndpoint) lookup(w http.ResponseWriter, r *http.Request, name string) {
+
+	p := pprof.Lookup(name)
+	if p == nil {
+		serveError(w, http.StatusNotFound, "unknown profile "+name)
+		return
+	}
+
+	debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+	if name == "heap" && r.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+
+	if debug != 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		attachment(w, name)
+	}
+	_ = p.WriteTo(w, debug)
+}
+
+func attachment(w http.ResponseWriter, name string) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+}
+
+func serveError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Del("Content-Disposition")
+	http.Error(w, msg, code)
+}
+
+// seconds 返回 seconds 参数指定的采集时长，没有指定时返回 def 秒。
+func seconds(r *http.Request, def int) time.Duration {
+	if n, err := strconv.Atoi(r.URL.Query().Get("seconds")); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return time.Duration(def) * time.Second
+}
+
+// sleep 等待 d 时长，客户端断开连接时提前返回。
+func sleep(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
+
+// expvarEndpoint 返回通过 expvar 包发布的变量。
+type expvarEndpoint struct{}
+
+func (expvarEndpoint) ID() string {
+	return "expvar"
+}
+
+func (expvarEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	expvar.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// masked 脱敏后的属性值。
+const masked = "******"
+
+// DefaultSanitizeKeys 未配置 sanitize-keys 时使用的脱敏关键字。
+var DefaultSanitizeKeys = []string{"password", "secret", "token", "credential"}
+
+// sanitizer 对名称中包含敏感关键字的属性值进行脱敏。
+type sanitizer struct {
+	keys []string
+}
+
+func (s *Server) sanitizer() *sanitizer {
+	src := s.config.SanitizeKeys
+	if len(src) == 0 {
+		src = DefaultSanitizeKeys
+	}
+	var keys []string
+	for _, k := range src {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, strings.ToLower(k))
+		}
+	}
+	return &sanitizer{keys: keys}
+}
+
+// sensitive 返回名为 key 的属性是否需要脱敏。
+func (s *sanitizer) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range s.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitize 将配置结构体转换为 map 和 slice 组成的通用结构并进行脱敏，结构体
+// 字段优先使用 value 标签中的属性名作为名称。
+func (s *sanitizer) sanitize(i interface{}) interface{} {
+	return s.value("", reflect.ValueOf(i))
+}
+
+func (s *sanitizer) value(key string, v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.value(key, v.Elem())
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t
+		}
+		m := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" { // 忽略未导出的字段
+				continue
+			}
+			name := tagKey(f.Tag.Get("value"))
+			if name == "" {
+				name = f.Name
+			}
+			m[name] = s.value(name, v.Field(i))
+		}
+		return m
+	case reflect.Map:
+		m := make(map[string]interface{})
+		for _, k := range v.MapKeys() {
+			name := fmt.Sprint(k.Interface())
+			m[name] = s.value(name, v.MapIndex(k))
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		r := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r = append(r, s.value(key, v.Index(i)))
+		}
+		return r
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return v.Type().String()
+	default:
+		if s.sensitive(key) {
+			return masked
+		}
+		if d, ok := v.Interface().(time.Duration); ok {
+			return d.String()
+		}
+		return v.Interface()
+	}
+}
+
+// tagKey 返回形如 ${key:=def} 的 value 标签中的属性名。
+func tagKey(tag string) string {
+	if !strings.HasPrefix(tag, "${") {
+		return ""
+	}
+	tag = strings.TrimSuffix(strings.TrimPrefix(tag, "${"), "}")
+	if i := strings.Index(tag, ":="); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bodylimit 提供了限制请求体大小的 Web 过滤器，可以按照路径前缀设置不同
+// 的上限，并且可以安全地解压 gzip 和 deflate 格式的请求体，解压后的长度同样受到
+// 限制，避免压缩炸弹耗尽内存。超过上限的请求返回 problem+json 格式的 413 响应。
+package bodylimit
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-spring/spring-core/web"
+)
+
+// ErrTooLarge 请求体超过了上限，读取请求体时返回该错误。
+var ErrTooLarge = errors.New("bodylimit: request body too large")
+
+// MIMEApplicationProblemJSON RFC 7807 定义的错误响应格式。
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Config 请求体大小限制配置，通常绑定到 web.body-limit 前缀的属性上。
+type Config struct {
+	MaxSize             int64    `value:"${max-size:=10485760}"`       // 请求体的最大长度，0 表示不限制
+	Routes              []Route  `value:"${routes}"`                   // 按照路径前缀设置的最大长度，最长的前缀优先
+	Decompress          bool     `value:"${decompress:=false}"`        // 是否解压 gzip 和 deflate 格式的请求体
+	MaxDecompressedSize int64    `value:"${max-decompressed-size:=0}"` // 解压后的最大长度，0 表示与请求体的最大长度相同
+	URLPatterns         []string `value:"${url-patterns}"`             // 过滤器作用的路由，默认为全部路由
+}
+
+// Route 路径前缀对应的请求体的最大长度。
+type Route struct {
+	Path    string `value:"${path}"`
+	MaxSize int64  `value:"${max-size}"`
+}
+
+// Filter 限制请求体大小的过滤器。Content-Length 超过上限的请求直接被拒绝，其他请求
+// 在读取请求体超过上限时返回 ErrTooLarge ，处理函数因此 panic 时返回 413 响应。
+type Filter struct {
+	config *Config // 使用指针避免容器对其进行属性绑定
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	return &Filter{config: &config}
+}
+
+// URLPatterns 返回过滤器作用的路由。
+func (f *Filter) URLPatterns() []string {
+	if len(f.config.URLPatterns) == 0 {
+		return []string{"/*"}
+	}
+	return f.config.URLPatterns
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	w := ctx.ResponseWriter()
+	if code, detail := f.wrap(ctx.Request()); code != 0 {
+		writeProblem(w, code, detail)
+		return
+	}
+	defer f.recover(w)
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code, detail := f.wrap(r); code != 0 {
+			writeProblem(w, code, detail)
+			return
+		}
+		defer f.recover(w)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maxSize 返回 path 对应的请求体的最大长度。
+func (f *Filter) maxSize(path string) int64 {
+	n, size := -1, f.config.MaxSize
+	for _, r := range f.config.Routes {
+		if strings.HasPrefix(path, r.Path) && len(r.Path) > n {
+			n, size = len(r.Path), r.MaxSize
+		}
+	}
+	return size
+}
+
+// wrap 使用限制长度的 reader 替换请求体，请求需要被拒绝时返回响应码和原因。
+func (f *Filter) wrap(r *http.Request) (int, string) {
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return 0, ""
+	}
+
+	max := f.maxSize(r.URL.Path)
+	if max > 0 {
+		if r.ContentLength > max {
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", max)
+		}
+		r.Body = &limitReader{r: r.Body, c: r.Body, n: max}
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if !f.config.Decompress || encoding == "" || encoding == "identity" {
+		return 0, ""
+	}
+
+	var (
+		zr  io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err = gzip.NewReader(r.Body)
+	case "deflate":
+		zr = flate.NewReader(r.Body)
+	default:
+		return http.StatusUnsupportedMediaType, "unsupported content encoding " + encoding
+	}
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", max)
+		}
+		return http.StatusBadRequest, "invalid " + encoding + " body"
+	}
+
+	if n := f.config.MaxDecompressedSize; n > 0 {
+		max = n
+	}
+	body := io.ReadCloser(&multiCloser{Reader: zr, closers: []io.Closer{zr, r.Body}})
+	if max > 0 {
+		body = &limitReader{r: body, c: body, n: max}
+	}
+	r.Body = body
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return 0, ""
+}
+
+// recover 处理函数因为请求体超过上限而 panic 时返回 413 响应。
+func (f *Filter) recover(w http.ResponseWriter) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if tooLarge(r) {
+		writeProblem(w, http.StatusRequestEntityTooLarge, ErrTooLarge.Error())
+		return
+	}
+	panic(r)
+}
+
+// tooLarge 判断 panic 的值是否由请求体超过上限导致。
+func tooLarge(r interface{}) bool {
+	switch e := r.(type) {
+	case *web.HttpError:
+		return tooLarge(e.Internal)
+	case error:
+		return errors.Is(e, ErrTooLarge)
+	}
+	return false
+}
+
+// problem RFC 7807 定义的错误响应。
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, code int, detail string) {
+	b, _ := json.Marshal(&problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+	})
+	w.Header().Set(web.HeaderContentType, MIMEApplicationProblemJSON)
+	w.Header().Set("Connection", "close") // 剩余的请求体没有被读取
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+// limitReader 最多读取 n 个字节，超过时返回 ErrTooLarge 。
+type limitReader struct {
+	r io.Reader
+	c io.Closer
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrTooLarge
+	}
+	// 多读取一个字节用于判断是否超过上限
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), ErrTooLarge
+	}
+	return n, err
+}
+
+func (l *limitReader) Close() error {
+	return l.c.Close()
+}
+
+// multiCloser 关闭时依次关闭解压 reader 和原始的请求体。
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
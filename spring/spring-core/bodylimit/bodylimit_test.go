@@ -0,0 +1,108 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bodylimit_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-core/bodylimit"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func gzipBody(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(s))
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestFilter(t *testing.T) {
+
+	var config bodylimit.Config
+	p := conf.New()
+	p.Set("web.body-limit.max-size", 10)
+	p.Set("web.body-limit.decompress", true)
+	p.Set("web.body-limit.max-decompressed-size", 100)
+	p.Set("web.body-limit.routes[0].path", "/upload")
+	p.Set("web.body-limit.routes[0].max-size", 1000)
+	err := p.Bind(&config, conf.Tag("${web.body-limit}"))
+	assert.Nil(t, err)
+	assert.Equal(t, config.Routes, []bodylimit.Route{{Path: "/upload", MaxSize: 1000}})
+
+	f := bodylimit.NewFilter(config)
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		_, _ = w.Write(b)
+	}))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789")))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "0123456789")
+
+	// Content-Length 超过上限时直接拒绝
+	w = serve(httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789a")))
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, w.Header().Get("Content-Type"), bodylimit.MIMEApplicationProblemJSON)
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+	assert.Equal(t, m["status"], float64(413))
+	assert.Equal(t, m["detail"], "request body exceeds 10 bytes")
+
+	// 未知长度的请求体在读取时超过上限
+	r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("0123456789a")))
+	r.ContentLength = -1
+	assert.Equal(t, serve(r).Code, http.StatusRequestEntityTooLarge)
+
+	// 路径前缀对应的上限
+	r = httptest.NewRequest(http.MethodPost, "/upload/file", strings.NewReader(strings.Repeat("a", 500)))
+	assert.Equal(t, serve(r).Code, http.StatusOK)
+
+	// 解压后的请求体
+	r = httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(gzipBody(t, "hello")))
+	r.Header.Set("Content-Encoding", "gzip")
+	w = serve(r)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "hello")
+
+	// 压缩炸弹
+	r = httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(gzipBody(t, strings.Repeat("a", 10000))))
+	r.Header.Set("Content-Encoding", "gzip")
+	assert.Equal(t, serve(r).Code, http.StatusRequestEntityTooLarge)
+
+	r = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("x"))
+	r.Header.Set("Content-Encoding", "br")
+	assert.Equal(t, serve(r).Code, http.StatusUnsupportedMediaType)
+}
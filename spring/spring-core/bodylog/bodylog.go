@@ -0,0 +1,144 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bodylog 提供记录请求和响应报文的过滤器，用于调试与外部系统的集成。
+// 报文在记录之前按照配置的 JSON 路径、表单字段以及请求头进行脱敏，避免个人信息
+// 等敏感数据进入日志。
+package bodylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redactor 按照 JSON 路径对报文进行脱敏。路径使用点号分隔，* 匹配任意的 key
+// 或者数组元素，例如 password 、user.id_card 、cards.*.number ，路径可以以 $.
+// 开头。只有一段的路径匹配任意层级上的同名字段，字段名不区分大小写。
+type Redactor struct {
+	paths   [][]string
+	anyKeys map[string]bool // 只有一段的路径
+	mask    string
+}
+
+// NewRedactor Redactor 的构造函数，mask 是替换敏感数据的字符串。
+func NewRedactor(paths []string, mask string) *Redactor {
+	r := &Redactor{anyKeys: make(map[string]bool), mask: mask}
+	for _, p := range paths {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "$.")
+		if p == "" {
+			continue
+		}
+		s := strings.Split(strings.ToLower(p), ".")
+		if len(s) == 1 {
+			r.anyKeys[s[0]] = true
+		} else {
+			r.paths = append(r.paths, s)
+		}
+	}
+	return r
+}
+
+// JSON 对 JSON 报文进行脱敏，报文不是合法的 JSON 时返回 false 。脱敏后对象的
+// 字段按照字典序输出。
+func (r *Redactor) JSON(b []byte) ([]byte, bool) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil || d.More() {
+		return nil, false
+	}
+	v = r.redact(v, nil)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (r *Redactor) redact(v interface{}, path []string) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, val := range x {
+			p := append(path[:len(path):len(path)], strings.ToLower(k))
+			if r.match(p) {
+				x[k] = r.mask
+			} else {
+				x[k] = r.redact(val, p)
+			}
+		}
+	case []interface{}:
+		for i, val := range x {
+			x[i] = r.redact(val, append(path[:len(path):len(path)], "*"))
+		}
+	}
+	return v
+}
+
+// match 判断字段路径是否需要脱敏，数组元素在路径中使用 * 表示。
+func (r *Redactor) match(path []string) bool {
+	if r.anyKeys[path[len(path)-1]] {
+		return true
+	}
+	for _, p := range r.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		matched := true
+		for i, s := range p {
+			if s != "*" && s != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Form 对表单报文进行脱敏，字段名匹配只有一段的路径，报文不是合法的表单时返回
+// false 。
+func (r *Redactor) Form(b []byte) ([]byte, bool) {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, false
+	}
+	for k, vs := range values {
+		if r.anyKeys[strings.ToLower(k)] {
+			for i := range vs {
+				vs[i] = r.mask
+			}
+		}
+	}
+	return []byte(values.Encode()), true
+}
+
+// Header 返回脱敏后的请求头或者响应头，names 中的头部的值被替换。
+func (r *Redactor) Header(h http.Header, names map[string]bool) http.Header {
+	ret := make(http.Header, len(h))
+	for k, vs := range h {
+		if names[http.CanonicalHeaderKey(k)] {
+			ret[k] = []string{r.mask}
+		} else {
+			ret[k] = vs
+		}
+	}
+	return ret
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bodylog_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-core/bodylog"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestRedactor(t *testing.T) {
+
+	r := bodylog.NewRedactor([]string{"password", "$.user.id_card", "cards.*.number"}, "***")
+
+	t.Run("json", func(t *testing.T) {
+		b, ok := r.JSON([]byte(`{"name":"jim","Password":"p","user":{"id_card":"123","name":"x"},
+			"cards":[{"number":"6222","bank":"b"}],"nested":{"password":"q","amount":1.50}}`))
+		assert.True(t, ok)
+		assert.Equal(t, string(b), `{"Password":"***","cards":[{"bank":"b","number":"***"}],"name":"jim","nested":{"amount":1.50,"password":"***"},"user":{"id_card":"***","name":"x"}}`)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, ok := r.JSON([]byte(`{"password":`))
+		assert.False(t, ok)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		b, ok := r.Form([]byte(`name=jim&password=p`))
+		assert.True(t, ok)
+		assert.Equal(t, string(b), `name=jim&password=%2A%2A%2A`)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		h := r.Header(http.Header{"Authorization": {"Bearer x"}, "Accept": {"*/*"}}, map[string]bool{"Authorization": true})
+		assert.Equal(t, h, http.Header{"Authorization": {"***"}, "Accept": {"*/*"}})
+	})
+}
+
+func TestFilter(t *testing.T) {
+
+	var msgs []string
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {
+		msgs = append(msgs, e.GetMsg())
+	})
+	defer log.Reset()
+
+	f := bodylog.NewFilter(bodylog.Config{
+		MaxSize:      32,
+		Redact:       []string{"password", "token"},
+		Mask:         "***",
+		Headers:      true,
+		ExcludePaths: []string{"/health"},
+	})
+
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "sid=1")
+			_, _ = w.Write([]byte(`{"token":"t"}`))
+		case "/upload":
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(strings.Repeat("x", 40)))
+		default:
+			_, _ = w.Write(b)
+		}
+	}))
+
+	t.Run("redact", func(t *testing.T) {
+		msgs = nil
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"jim","password":"p"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Basic abc")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, len(msgs), 1)
+		assert.True(t, strings.HasPrefix(msgs[0], "http payload POST /login status=200 cost="))
+		assert.True(t, strings.Contains(msgs[0], ` request-headers=map[Authorization:[***] Content-Type:[application/json]]`))
+		assert.True(t, strings.Contains(msgs[0], ` request="{\"password\":\"***\",\"user\":\"jim\"}"`))
+		assert.True(t, strings.Contains(msgs[0], ` response-headers=map[Content-Type:[application/json] Set-Cookie:[***]]`))
+		assert.True(t, strings.HasSuffix(msgs[0], ` response="{\"token\":\"***\"}"`))
+		assert.False(t, strings.Contains(msgs[0], "abc"))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		msgs = nil
+		body := `{"user":"jim","password":"p","padding":"0123456789"}`
+		r := httptest.NewRequest(http.MethodPut, "/upload", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		assert.True(t, strings.Contains(msgs[0], ` status=201 `))
+		assert.True(t, strings.Contains(msgs[0], ` request="<truncated application/json body, 52 bytes>"`))
+		assert.True(t, strings.HasSuffix(msgs[0], ` response="`+strings.Repeat("x", 32)+`...(40 bytes)"`))
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		msgs = nil
+		r := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("\x00\x01"))
+		r.Header.Set("Content-Type", "application/octet-stream")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		assert.True(t, strings.Contains(msgs[0], ` request="<binary body, 2 bytes>"`))
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		msgs = nil
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, len(msgs), 0)
+	})
+}
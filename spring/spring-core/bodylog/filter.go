@@ -0,0 +1,254 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bodylog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+// defaultRedactHeaders 总是需要脱敏的头部。
+var defaultRedactHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key",
+}
+
+// Config 报文日志配置，通常绑定到 web.body-log 前缀的属性上。
+type Config struct {
+	MaxSize       int      `value:"${max-size:=4096}"` // 记录的报文的最大长度，超过时被截断
+	Redact        []string `value:"${redact}"`         // 需要脱敏的 JSON 路径
+	Mask          string   `value:"${mask:=******}"`   // 替换敏感数据的字符串
+	Headers       bool     `value:"${headers:=false}"` // 是否记录请求头和响应头
+	RedactHeaders []string `value:"${redact-headers}"` // 除了认证和 cookie 之外需要脱敏的头部
+	ExcludePaths  []string `value:"${exclude-paths}"`  // 不记录报文的路径前缀
+}
+
+// Filter 记录请求和响应报文的过滤器。
+type Filter struct {
+	config   *Config // 使用指针避免容器对其进行属性绑定
+	redactor *Redactor
+	headers  map[string]bool
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	headers := make(map[string]bool)
+	for _, h := range append(defaultRedactHeaders, config.RedactHeaders...) {
+		headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return &Filter{
+		config:   &config,
+		redactor: NewRedactor(config.Redact, config.Mask),
+		headers:  headers,
+	}
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	r := ctx.Request()
+	if f.excluded(r.URL.Path) {
+		chain.Next(ctx)
+		return
+	}
+	start := time.Now()
+	body := f.capture(r)
+	defer func() {
+		w := ctx.ResponseWriter()
+		resp := w.Body() // 只有文本格式的响应才会被缓存
+		if len(resp) > f.config.MaxSize {
+			resp = resp[:f.config.MaxSize]
+		}
+		f.log(r.Context(), r, body, w.Status(), w.Header(), resp, w.Size(), time.Since(start))
+	}()
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.excluded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		body := f.capture(r)
+		cw := &captureWriter{ResponseWriter: w, max: f.config.MaxSize}
+		defer func() {
+			f.log(r.Context(), r, body, cw.status(), w.Header(), cw.buf.Bytes(), cw.size, time.Since(start))
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func (f *Filter) excluded(path string) bool {
+	for _, p := range f.config.ExcludePaths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// capture 预先读取请求体的前 MaxSize+1 个字节，再与剩余的部分一起作为新的请求
+// 体，这样即使处理函数没有读取请求体也能够记录它。
+func (f *Filter) capture(r *http.Request) *captureReader {
+	c := &captureReader{}
+	if r.Body == nil || r.Body == http.NoBody {
+		return c
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(f.config.MaxSize)+1))
+	c.size = len(head)
+	if len(head) > f.config.MaxSize {
+		c.buf = head[:f.config.MaxSize]
+	} else {
+		c.buf = head
+	}
+	var rest io.Reader = &countReader{r: r.Body, n: &c.size}
+	if err != nil {
+		rest = errReader{err}
+	}
+	c.Reader = io.MultiReader(bytes.NewReader(head), rest)
+	c.Closer = r.Body
+	r.Body = c
+	return c
+}
+
+func (f *Filter) log(ctx context.Context, r *http.Request, req *captureReader,
+	status int, header http.Header, resp []byte, respSize int, cost time.Duration) {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "http payload %s %s status=%d cost=%v", r.Method, r.URL.RequestURI(), status, cost)
+	if f.config.Headers {
+		fmt.Fprintf(&sb, " request-headers=%v", f.redactor.Header(r.Header, f.headers))
+	}
+	size := req.size
+	if r.ContentLength > int64(size) { // 处理函数没有读取完请求体
+		size = int(r.ContentLength)
+	}
+	if s := f.format(req.buf, size, r.Header.Get(web.HeaderContentType)); s != "" {
+		fmt.Fprintf(&sb, " request=%q", s)
+	}
+	if f.config.Headers {
+		fmt.Fprintf(&sb, " response-headers=%v", f.redactor.Header(header, f.headers))
+	}
+	if s := f.format(resp, respSize, header.Get(web.HeaderContentType)); s != "" {
+		fmt.Fprintf(&sb, " response=%q", s)
+	}
+	log.Ctx(ctx).Info(sb.String())
+}
+
+// format 返回报文在日志中的形式。被截断的 JSON 和表单报文无法脱敏，因此只记录
+// 它们的长度，二进制报文同样只记录长度。
+func (f *Filter) format(b []byte, size int, contentType string) string {
+
+	if size == 0 {
+		return ""
+	}
+
+	truncated := size > len(b)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var redact func([]byte) ([]byte, bool)
+	switch {
+	case mediaType == web.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		redact = f.redactor.JSON
+	case mediaType == web.MIMEApplicationForm:
+		redact = f.redactor.Form
+	case strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml"):
+		if truncated {
+			return fmt.Sprintf("%s...(%d bytes)", b, size)
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("<binary body, %d bytes>", size)
+	}
+
+	if truncated || len(b) == 0 {
+		return fmt.Sprintf("<truncated %s body, %d bytes>", mediaType, size)
+	}
+	out, ok := redact(b)
+	if !ok {
+		return fmt.Sprintf("<invalid %s body, %d bytes>", mediaType, size)
+	}
+	return string(out)
+}
+
+// captureReader 请求体的前 max 个字节和请求体的总长度，总长度只包含处理函数
+// 实际读取的部分。
+type captureReader struct {
+	io.Reader
+	io.Closer
+	buf  []byte
+	size int
+}
+
+type countReader struct {
+	r io.Reader
+	n *int
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += n
+	return n, err
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// captureWriter 保存写入的前 max 个字节。
+type captureWriter struct {
+	http.ResponseWriter
+	buf  bytes.Buffer
+	max  int
+	size int
+	code int
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.size += n
+	if left := w.max - w.buf.Len(); left > 0 {
+		if left > n {
+			left = n
+		}
+		w.buf.Write(p[:left])
+	}
+	return n, err
+}
+
+func (w *captureWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package buildinfo 提供程序的构建信息，包括模块版本、 git 提交和 Go 版本等，
+// 数据来源于编译器写入二进制文件的 debug.BuildInfo 。
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Info 程序的构建信息。
+type Info struct {
+	Name       string            `json:"name,omitempty"`        // 应用名称
+	Path       string            `json:"path,omitempty"`        // 主模块的路径
+	Version    string            `json:"version,omitempty"`     // 主模块的版本
+	Commit     string            `json:"commit,omitempty"`      // git 提交
+	CommitTime string            `json:"commit_time,omitempty"` // git 提交时间
+	Modified   bool              `json:"modified"`              // 工作区是否有未提交的修改
+	GoVersion  string            `json:"go"`                    // Go 版本
+	OS         string            `json:"os"`                    // 操作系统
+	Arch       string            `json:"arch"`                  // CPU 架构
+	Settings   map[string]string `json:"settings,omitempty"`    // 全部的构建设置
+}
+
+// Read 读取程序的构建信息，name 为应用的名称。
+func Read(name string) *Info {
+	info := &Info{
+		Name:      name,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = bi.Path
+	info.Version = bi.Main.Version
+	info.Settings = make(map[string]string)
+	for _, s := range bi.Settings {
+		info.Settings[s.Key] = s.Value
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// String 返回一行便于打印的构建信息。
+func (info *Info) String() string {
+	var s []string
+	if info.Name != "" {
+		s = append(s, info.Name)
+	}
+	if info.Version != "" {
+		s = append(s, info.Version)
+	}
+	if info.Commit != "" {
+		commit := info.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if info.Modified {
+			commit += "-dirty"
+		}
+		s = append(s, "commit "+commit)
+	}
+	s = append(s, fmt.Sprintf("%s %s/%s", info.GoVersion, info.OS, info.Arch))
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package buildinfo_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-core/buildinfo"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestRead(t *testing.T) {
+	info := buildinfo.Read("demo")
+	assert.Equal(t, info.Name, "demo")
+	assert.Equal(t, info.GoVersion, runtime.Version())
+	assert.True(t, strings.HasPrefix(info.String(), "demo, "))
+	assert.True(t, strings.HasSuffix(info.String(), runtime.GOOS+"/"+runtime.GOARCH))
+}
+
+func TestInfo_String(t *testing.T) {
+	info := &buildinfo.Info{
+		Name:      "demo",
+		Version:   "v1.2.3",
+		Commit:    "0123456789abcdef",
+		Modified:  true,
+		GoVersion: "go1.18",
+		OS:        "linux",
+		Arch:      "amd64",
+	}
+	assert.Equal(t, info.String(), "demo, v1.2.3, commit 0123456789ab-dirty, go1.18 linux/amd64")
+}
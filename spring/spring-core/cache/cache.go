@@ -0,0 +1,425 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cache 提供了缓存抽象，通过 Cacheable 等函数替代手写的旁路缓存逻辑，
+// 缓存按照名称进行配置，支持进程内 LRU、Redis 和二者组合的两级缓存。两级缓存
+// 通过 Redis 的发布订阅在实例之间广播失效消息。
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/redis"
+)
+
+// Cache 缓存接口，值是序列化之后的字节。
+type Cache interface {
+
+	// Get 获取 key 对应的值，ok 为 false 时表示 key 不存在或者已经过期。
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set 保存 key 对应的值，ttl 为 0 时表示永不过期。
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete 删除 keys 对应的值。
+	Delete(ctx context.Context, keys ...string) error
+
+	// Clear 删除缓存中所有的值。
+	Clear(ctx context.Context) error
+}
+
+// Spec 单个缓存的配置。
+type Spec struct {
+	Type         string        `value:"${type:=memory}"`         // 缓存类型，可选 memory、redis、tiered
+	TTL          time.Duration `value:"${ttl:=0}"`               // 未指定 ttl 时使用的过期时间，0 表示永不过期
+	MaxSize      int           `value:"${max-size:=10000}"`      // 进程内缓存的最大条目数，0 表示不限制
+	LocalTTL     time.Duration `value:"${local-ttl:=1m}"`        // 两级缓存中进程内缓存的最长过期时间
+	LocalMaxSize int           `value:"${local-max-size:=1000}"` // 两级缓存中进程内缓存的最大条目数
+	Serializer   string        `value:"${serializer:=json}"`     // 值的序列化方式，可选 json、gob 或者通过 RegisterSerializer 注册的名称
+}
+
+// Config 缓存配置，通常绑定到 cache 前缀的属性上，例如:
+//
+//	cache.caches.user.type=tiered
+//	cache.caches.user.ttl=10m
+type Config struct {
+	KeyPrefix string          `value:"${key-prefix:=cache:}"`          // Redis 缓存 key 的前缀
+	Channel   string          `value:"${channel:=cache:invalidation}"` // 两级缓存广播失效消息的 Redis channel
+	Caches    map[string]Spec `value:"${caches}"`                      // 按照名称配置的缓存
+}
+
+// Serializer 缓存值的序列化接口。
+type Serializer = redis.Codec
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var serializers = map[string]Serializer{
+	"json": redis.JSON,
+	"gob":  gobSerializer{},
+}
+
+// RegisterSerializer 注册名为 name 的序列化方式，缓存通过 serializer 属性引用。
+// 该函数不是并发安全的，应该在 init 函数中调用。
+func RegisterSerializer(name string, s Serializer) {
+	serializers[name] = s
+}
+
+// invalidation 两级缓存广播的失效消息。
+type invalidation struct {
+	ID    string   `json:"id"` // 发送消息的实例，实例忽略自己发送的消息
+	Cache string   `json:"cache"`
+	Keys  []string `json:"keys,omitempty"`
+	Clear bool     `json:"clear,omitempty"`
+}
+
+// Manager 按照名称管理缓存，未配置的缓存在第一次使用时创建为默认配置的进程内
+// 缓存。
+type Manager struct {
+	Redis   redis.Client      `autowire:"?"`
+	Metrics *metrics.Registry `autowire:"?"`
+	Config  Config            `value:"${cache}"`
+
+	mu          sync.RWMutex
+	id          string
+	caches      map[string]Cache
+	ttls        map[string]time.Duration
+	serializers map[string]Serializer
+	tiers       map[string]*tiered
+	group       group
+	requests    *metrics.CounterVec
+	evictions   *metrics.CounterVec
+	unsubscribe func()
+}
+
+// Default 默认的缓存管理器，Cacheable 等函数使用的都是这个管理器，同时也是
+// 容器中的缓存管理器。
+var Default = NewManager()
+
+// NewManager Manager 的构造函数。
+func NewManager() *Manager {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return &Manager{
+		id:          hex.EncodeToString(b[:]),
+		caches:      make(map[string]Cache),
+		ttls:        make(map[string]time.Duration),
+		serializers: make(map[string]Serializer),
+		tiers:       make(map[string]*tiered),
+	}
+}
+
+// OnInit 根据配置创建缓存，存在两级缓存并且 Redis 客户端实现了 redis.PubSub
+// 接口时订阅失效消息。
+func (m *Manager) OnInit() error {
+	if m.Metrics != nil {
+		m.requests = m.Metrics.Counter("cache_requests_total", "Total number of cache lookups by result.", "cache", "result")
+		m.evictions = m.Metrics.Counter("cache_evictions_total", "Total number of entries evicted by the size limit.", "cache")
+	}
+	for name, spec := range m.Config.Caches {
+		if spec.Serializer == "" {
+			spec.Serializer = "json"
+		}
+		s, ok := serializers[spec.Serializer]
+		if !ok {
+			return fmt.Errorf("cache %s has unknown serializer %q", name, spec.Serializer)
+		}
+		c, err := m.newCache(name, spec)
+		if err != nil {
+			return err
+		}
+		m.Register(name, c, spec.TTL)
+		m.serializers[name] = s
+	}
+	if ps, ok := m.Redis.(redis.PubSub); ok && len(m.tiers) > 0 {
+		unsubscribe, err := ps.Subscribe(context.Background(), m.Config.Channel, m.invalidate)
+		if err != nil {
+			return err
+		}
+		m.unsubscribe = unsubscribe
+	}
+	return nil
+}
+
+// OnDestroy 取消订阅失效消息。
+func (m *Manager) OnDestroy() {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+}
+
+func (m *Manager) newCache(name string, spec Spec) (Cache, error) {
+	switch spec.Type {
+	case "memory":
+		return m.newMemory(name, spec.MaxSize), nil
+	case "redis", "tiered":
+		if m.Redis == nil {
+			return nil, fmt.Errorf("cache %s requires a redis.Client bean", name)
+		}
+		c := NewRedis(m.Redis, m.Config.KeyPrefix+name+":")
+		if spec.Type == "redis" {
+			return c, nil
+		}
+		t := &tiered{local: m.newMemory(name, spec.LocalMaxSize), remote: c, localTTL: spec.LocalTTL}
+		if ps, ok := m.Redis.(redis.PubSub); ok {
+			t.publish = m.publisher(ps, name)
+		}
+		m.tiers[name] = t
+		return t, nil
+	default:
+		return nil, fmt.Errorf("cache %s has unknown type %q", name, spec.Type)
+	}
+}
+
+func (m *Manager) newMemory(name string, maxSize int) Cache {
+	return newMemory(maxSize, func() {
+		if m.evictions != nil {
+			m.evictions.With(name).Inc()
+		}
+	})
+}
+
+// publisher 返回名为 name 的两级缓存广播失效消息的函数，广播失败时只记录日志，
+// 其他实例的本地缓存在过期之后恢复一致。
+func (m *Manager) publisher(ps redis.PubSub, name string) func(ctx context.Context, keys []string) {
+	return func(ctx context.Context, keys []string) {
+		b, err := json.Marshal(invalidation{ID: m.id, Cache: name, Keys: keys, Clear: keys == nil})
+		if err == nil {
+			err = ps.Publish(ctx, m.Config.Channel, string(b))
+		}
+		if err != nil {
+			log.Ctx(ctx).Warnf("cache %s publish invalidation error: %v", name, err)
+		}
+	}
+}
+
+// invalidate 收到其他实例的失效消息时删除本地缓存中对应的值。
+func (m *Manager) invalidate(message string) {
+	ctx := context.Background()
+	var msg invalidation
+	if err := json.Unmarshal([]byte(message), &msg); err != nil {
+		log.Ctx(ctx).Warnf("cache decode invalidation %q error: %v", message, err)
+		return
+	}
+	if msg.ID == m.id {
+		return
+	}
+	m.mu.RLock()
+	t, ok := m.tiers[msg.Cache]
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if msg.Clear {
+		_ = t.local.Clear(ctx)
+		return
+	}
+	_ = t.local.Delete(ctx, msg.Keys...)
+}
+
+// Register 注册名为 name 的缓存，ttl 为未指定 ttl 时使用的过期时间。
+func (m *Manager) Register(name string, c Cache, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.caches[name] = c
+	m.ttls[name] = ttl
+}
+
+// Cache 返回名为 name 的缓存。
+func (m *Manager) Cache(name string) Cache {
+	m.mu.RLock()
+	c, ok := m.caches[name]
+	m.mu.RUnlock()
+	if ok {
+		return c
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok = m.caches[name]; !ok {
+		c = m.newMemory(name, 10000)
+		m.caches[name] = c
+	}
+	return c
+}
+
+func (m *Manager) ttl(name string, ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ttls[name]
+}
+
+// serializer 返回名为 name 的缓存使用的序列化方式。
+func (m *Manager) serializer(name string) Serializer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.serializers[name]; ok {
+		return s
+	}
+	return redis.JSON
+}
+
+func (m *Manager) record(name, result string) {
+	if m.requests != nil {
+		m.requests.With(name, result).Inc()
+	}
+}
+
+// Evict 删除名为 name 的缓存中 keys 对应的值。
+func (m *Manager) Evict(ctx context.Context, name string, keys ...string) error {
+	return m.Cache(name).Delete(ctx, keys...)
+}
+
+// Clear 删除名为 name 的缓存中所有的值。
+func (m *Manager) Clear(ctx context.Context, name string) error {
+	return m.Cache(name).Clear(ctx)
+}
+
+// Load 从 m 中名为 name 的缓存获取 key 对应的值，不存在时调用 loader 获取值并
+// 写入缓存，ttl 为 0 时使用缓存配置的过期时间。同一时刻对同一个 key 只会调用
+// 一次 loader ，其他调用者共享 loader 的结果。缓存读写失败时只记录日志，不影响
+// 通过 loader 获取值。
+func Load[T any](ctx context.Context, m *Manager, name, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+
+	var v T
+	c := m.Cache(name)
+	s := m.serializer(name)
+	b, ok, err := c.Get(ctx, key)
+	if err != nil {
+		log.Ctx(ctx).Warnf("cache %s get %s error: %v", name, key, err)
+	} else if ok {
+		if err = s.Unmarshal(b, &v); err == nil {
+			m.record(name, "hit")
+			return v, nil
+		}
+		log.Ctx(ctx).Warnf("cache %s decode %s error: %v", name, key, err)
+	}
+	m.record(name, "miss")
+
+	r, err := m.group.Do(name+"\x00"+key, func() (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if b, err := s.Marshal(v); err != nil {
+			log.Ctx(ctx).Warnf("cache %s encode %s error: %v", name, key, err)
+		} else if err = c.Set(ctx, key, b, m.ttl(name, ttl)); err != nil {
+			log.Ctx(ctx).Warnf("cache %s set %s error: %v", name, key, err)
+		}
+		return v, nil
+	})
+	if err != nil {
+		return v, err
+	}
+	v, _ = r.(T)
+	return v, nil
+}
+
+// Put 将 v 写入 m 中名为 name 的缓存，ttl 为 0 时使用缓存配置的过期时间。
+func Put[T any](ctx context.Context, m *Manager, name, key string, v T, ttl time.Duration) error {
+	b, err := m.serializer(name).Marshal(v)
+	if err != nil {
+		return err
+	}
+	return m.Cache(name).Set(ctx, key, b, m.ttl(name, ttl))
+}
+
+// Cacheable 从默认管理器中名为 name 的缓存获取 key 对应的值，不存在时调用 loader
+// 获取值并写入缓存，例如:
+//
+//	user, err := cache.Cacheable(ctx, "user", id, 0, func(ctx context.Context) (*User, error) {
+//		return dao.GetUser(ctx, id)
+//	})
+func Cacheable[T any](ctx context.Context, name, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	return Load(ctx, Default, name, key, ttl, loader)
+}
+
+// CachePut 将 v 写入默认管理器中名为 name 的缓存。
+func CachePut[T any](ctx context.Context, name, key string, v T, ttl time.Duration) error {
+	return Put(ctx, Default, name, key, v, ttl)
+}
+
+// Evict 删除默认管理器中名为 name 的缓存中 keys 对应的值。
+func Evict(ctx context.Context, name string, keys ...string) error {
+	return Default.Evict(ctx, name, keys...)
+}
+
+// Clear 删除默认管理器中名为 name 的缓存中所有的值。
+func Clear(ctx context.Context, name string) error {
+	return Default.Clear(ctx, name)
+}
+
+// errPanicked loader 发生 panic 时等待同一个 key 的其他调用者得到的错误。
+var errPanicked = errors.New("cache: loader panicked")
+
+// group 合并对同一个 key 的并发调用。
+type group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+func (g *group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &call{err: errPanicked}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
@@ -0,0 +1,384 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/cache"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// mapClient 只实现了缓存用到的命令，忽略过期时间。
+type mapClient struct {
+	redis.Client
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func (c *mapClient) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.m[key]; ok {
+		return v, nil
+	}
+	return "", redis.ErrNil
+}
+
+func (c *mapClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *mapClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int64
+	for _, k := range keys {
+		if _, ok := c.m[k]; ok {
+			delete(c.m, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *mapClient) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, _ := strconv.ParseInt(c.m[key], 10, 64)
+	n++
+	c.m[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// pubSubClient 在同一个进程内模拟 Redis 的发布订阅，多个客户端共享存储和订阅者。
+type pubSubClient struct {
+	*mapClient
+	subs *[]func(message string)
+}
+
+func (c *pubSubClient) Publish(ctx context.Context, channel string, message string) error {
+	for _, fn := range *c.subs {
+		fn(message)
+	}
+	return nil
+}
+
+func (c *pubSubClient) Subscribe(ctx context.Context, channel string, fn func(message string)) (func(), error) {
+	*c.subs = append(*c.subs, fn)
+	return func() {}, nil
+}
+
+func get(t *testing.T, c cache.Cache, key string) (string, bool) {
+	b, ok, err := c.Get(context.Background(), key)
+	assert.Nil(t, err)
+	return string(b), ok
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(2)
+
+	assert.Nil(t, c.Set(ctx, "a", []byte("1"), 0))
+	assert.Nil(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, ok := get(t, c, "a")
+	assert.True(t, ok)
+
+	// b 是最久未使用的条目
+	assert.Nil(t, c.Set(ctx, "c", []byte("3"), 0))
+	_, ok = get(t, c, "b")
+	assert.False(t, ok)
+	v, ok := get(t, c, "a")
+	assert.True(t, ok)
+	assert.Equal(t, v, "1")
+
+	assert.Nil(t, c.Set(ctx, "d", []byte("4"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok = get(t, c, "d")
+	assert.False(t, ok)
+
+	assert.Nil(t, c.Delete(ctx, "a"))
+	_, ok = get(t, c, "a")
+	assert.False(t, ok)
+
+	assert.Nil(t, c.Clear(ctx))
+	_, ok = get(t, c, "c")
+	assert.False(t, ok)
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	client := &mapClient{m: map[string]string{}}
+	c := cache.NewRedis(client, "cache:user:")
+
+	assert.Nil(t, c.Set(ctx, "1", []byte("tom"), time.Minute))
+	assert.Equal(t, client.m, map[string]string{"cache:user:0:1": "tom"})
+	v, ok := get(t, c, "1")
+	assert.True(t, ok)
+	assert.Equal(t, v, "tom")
+
+	assert.Nil(t, c.Delete(ctx, "1"))
+	_, ok = get(t, c, "1")
+	assert.False(t, ok)
+
+	assert.Nil(t, c.Set(ctx, "2", []byte("jerry"), time.Minute))
+	assert.Nil(t, c.Clear(ctx))
+	_, ok = get(t, c, "2")
+	assert.False(t, ok)
+	assert.Nil(t, c.Set(ctx, "2", []byte("spike"), time.Minute))
+	assert.Equal(t, client.m["cache:user:1:2"], "spike")
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	local, remote := cache.NewMemory(0), cache.NewMemory(0)
+	c := cache.NewTiered(local, remote, time.Minute)
+
+	assert.Nil(t, remote.Set(ctx, "a", []byte("1"), 0))
+	v, ok := get(t, c, "a")
+	assert.True(t, ok)
+	assert.Equal(t, v, "1")
+	v, ok = get(t, local, "a")
+	assert.True(t, ok)
+	assert.Equal(t, v, "1")
+
+	assert.Nil(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, ok = get(t, local, "b")
+	assert.True(t, ok)
+	_, ok = get(t, remote, "b")
+	assert.True(t, ok)
+
+	assert.Nil(t, c.Delete(ctx, "a", "b"))
+	_, ok = get(t, local, "a")
+	assert.False(t, ok)
+	_, ok = get(t, remote, "b")
+	assert.False(t, ok)
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	m := cache.NewManager()
+	assert.Nil(t, m.OnInit())
+
+	var n int32
+	loader := func(ctx context.Context) (*user, error) {
+		atomic.AddInt32(&n, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &user{ID: 1, Name: "tom"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := cache.Load(ctx, m, "user", "1", 0, loader)
+			assert.Nil(t, err)
+			assert.Equal(t, u, &user{ID: 1, Name: "tom"})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+
+	u, err := cache.Load(ctx, m, "user", "1", 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, u.Name, "tom")
+	assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+
+	assert.Nil(t, m.Evict(ctx, "user", "1"))
+	_, err = cache.Load(ctx, m, "user", "1", 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, atomic.LoadInt32(&n), int32(2))
+
+	assert.Nil(t, cache.Put(ctx, m, "user", "2", &user{ID: 2, Name: "jerry"}, 0))
+	u, err = cache.Load(ctx, m, "user", "2", 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, u.Name, "jerry")
+
+	_, err = cache.Load(ctx, m, "user", "3", 0, func(ctx context.Context) (*user, error) {
+		return nil, errors.New("not found")
+	})
+	assert.Error(t, err, "not found")
+	_, ok := get(t, m.Cache("user"), "3")
+	assert.False(t, ok)
+}
+
+func TestCacheable(t *testing.T) {
+	ctx := context.Background()
+	defer cache.Clear(ctx, "count")
+
+	var n int
+	loader := func(ctx context.Context) (int, error) {
+		n++
+		return n, nil
+	}
+	for i := 0; i < 3; i++ {
+		v, err := cache.Cacheable(ctx, "count", "k", 0, loader)
+		assert.Nil(t, err)
+		assert.Equal(t, v, 1)
+	}
+	assert.Nil(t, cache.Clear(ctx, "count"))
+	v, err := cache.Cacheable(ctx, "count", "k", 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, v, 2)
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+
+	m := cache.NewManager()
+	m.Redis = &mapClient{m: map[string]string{}}
+	m.Config.KeyPrefix = "cache:"
+	m.Config.Caches = map[string]cache.Spec{
+		"user":  {Type: "tiered", TTL: time.Minute, LocalTTL: time.Second, LocalMaxSize: 10},
+		"order": {Type: "redis"},
+	}
+	assert.Nil(t, m.OnInit())
+
+	assert.Nil(t, cache.Put(ctx, m, "user", "1", "tom", 0))
+	assert.Nil(t, cache.Put(ctx, m, "order", "1", 100, 0))
+	assert.Equal(t, m.Redis.(*mapClient).m, map[string]string{
+		"cache:user:0:1":  `"tom"`,
+		"cache:order:0:1": `100`,
+	})
+
+	m = cache.NewManager()
+	m.Config.Caches = map[string]cache.Spec{"user": {Type: "redis"}}
+	assert.Error(t, m.OnInit(), "cache user requires a redis.Client bean")
+
+	m = cache.NewManager()
+	m.Config.Caches = map[string]cache.Spec{"user": {Type: "disk"}}
+	assert.Error(t, m.OnInit(), "cache user has unknown type \"disk\"")
+}
+
+func TestConfig(t *testing.T) {
+	p := conf.New()
+	p.Set("cache.caches.user.type", "tiered")
+	p.Set("cache.caches.user.ttl", "10m")
+	p.Set("cache.caches.order.max-size", "100")
+	var c cache.Config
+	assert.Nil(t, p.Bind(&c, conf.Tag("${cache}")))
+	assert.Equal(t, c, cache.Config{
+		KeyPrefix: "cache:",
+		Channel:   "cache:invalidation",
+		Caches: map[string]cache.Spec{
+			"user":  {Type: "tiered", TTL: 10 * time.Minute, MaxSize: 10000, LocalTTL: time.Minute, LocalMaxSize: 1000, Serializer: "json"},
+			"order": {Type: "memory", MaxSize: 100, LocalTTL: time.Minute, LocalMaxSize: 1000, Serializer: "json"},
+		},
+	})
+}
+
+func TestManager_Invalidation(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mapClient{m: map[string]string{}}
+	var subs []func(message string)
+	newManager := func() *cache.Manager {
+		m := cache.NewManager()
+		m.Redis = &pubSubClient{mapClient: store, subs: &subs}
+		m.Config.Caches = map[string]cache.Spec{
+			"user": {Type: "tiered", LocalTTL: time.Minute, LocalMaxSize: 10},
+		}
+		assert.Nil(t, m.OnInit())
+		return m
+	}
+	m1, m2 := newManager(), newManager()
+	assert.Equal(t, len(subs), 2)
+
+	assert.Nil(t, cache.Put(ctx, m1, "user", "1", "tom", 0))
+	v, ok := get(t, m2.Cache("user"), "1")
+	assert.True(t, ok)
+	assert.Equal(t, v, `"tom"`)
+
+	// m1 的写入使 m2 本地缓存中的值失效
+	assert.Nil(t, cache.Put(ctx, m1, "user", "1", "jerry", 0))
+	v, _ = get(t, m2.Cache("user"), "1")
+	assert.Equal(t, v, `"jerry"`)
+
+	assert.Nil(t, m1.Evict(ctx, "user", "1"))
+	_, ok = get(t, m2.Cache("user"), "1")
+	assert.False(t, ok)
+
+	assert.Nil(t, cache.Put(ctx, m2, "user", "2", "spike", 0))
+	_, ok = get(t, m1.Cache("user"), "2")
+	assert.True(t, ok)
+	assert.Nil(t, m2.Clear(ctx, "user"))
+	_, ok = get(t, m1.Cache("user"), "2")
+	assert.False(t, ok)
+}
+
+func TestManager_Serializer(t *testing.T) {
+	ctx := context.Background()
+
+	m := cache.NewManager()
+	m.Config.Caches = map[string]cache.Spec{"user": {Type: "memory", Serializer: "gob"}}
+	assert.Nil(t, m.OnInit())
+
+	assert.Nil(t, cache.Put(ctx, m, "user", "1", &user{ID: 1, Name: "tom"}, 0))
+	b, ok, err := m.Cache("user").Get(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.NotEqual(t, b[0], byte('{'))
+	u, err := cache.Load(ctx, m, "user", "1", 0, func(ctx context.Context) (*user, error) {
+		return nil, errors.New("not cached")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, u, &user{ID: 1, Name: "tom"})
+
+	m = cache.NewManager()
+	m.Config.Caches = map[string]cache.Spec{"user": {Type: "memory", Serializer: "xml"}}
+	assert.Error(t, m.OnInit(), "cache user has unknown serializer \"xml\"")
+}
+
+func TestManager_Metrics(t *testing.T) {
+	ctx := context.Background()
+
+	r := metrics.NewRegistry()
+	m := cache.NewManager()
+	m.Metrics = r
+	m.Config.Caches = map[string]cache.Spec{"user": {Type: "memory", MaxSize: 1}}
+	assert.Nil(t, m.OnInit())
+
+	loader := func(ctx context.Context) (int, error) { return 1, nil }
+	for _, key := range []string{"1", "1", "2"} {
+		_, err := cache.Load(ctx, m, "user", key, 0, loader)
+		assert.Nil(t, err)
+	}
+	requests := r.Counter("cache_requests_total", "", "cache", "result")
+	assert.Equal(t, requests.With("user", "hit").Value(), float64(1))
+	assert.Equal(t, requests.With("user", "miss").Value(), float64(2))
+	evictions := r.Counter("cache_evictions_total", "", "cache")
+	assert.Equal(t, evictions.With("user").Value(), float64(1))
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key    string
+	value  []byte
+	expire time.Time
+}
+
+// memory 基于 LRU 淘汰策略的进程内缓存。
+type memory struct {
+	mu      sync.Mutex
+	maxSize int
+	lru     *list.List
+	items   map[string]*list.Element
+	onEvict func() // 淘汰条目时的回调，用于统计淘汰次数
+}
+
+// NewMemory 创建进程内缓存，条目数超过 maxSize 时淘汰最久未使用的条目，maxSize
+// 为 0 时表示不限制。
+func NewMemory(maxSize int) Cache {
+	return newMemory(maxSize, nil)
+}
+
+func newMemory(maxSize int, onEvict func()) *memory {
+	return &memory{
+		maxSize: maxSize,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+		onEvict: onEvict,
+	}
+}
+
+func (c *memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	v := e.Value.(*entry)
+	if !v.expire.IsZero() && !time.Now().Before(v.expire) {
+		c.lru.Remove(e)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(e)
+	return v.value, true, nil
+}
+
+func (c *memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		v := e.Value.(*entry)
+		v.value, v.expire = value, expire
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(&entry{key: key, value: value, expire: expire})
+	if c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*entry).key)
+		if c.onEvict != nil {
+			c.onEvict()
+		}
+	}
+	return nil
+}
+
+func (c *memory) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if e, ok := c.items[key]; ok {
+			c.lru.Remove(e)
+			delete(c.items, key)
+		}
+	}
+	return nil
+}
+
+func (c *memory) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.items = make(map[string]*list.Element)
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+)
+
+// redisCache 基于 Redis 的缓存。Redis 客户端没有提供遍历 key 的命令，所以缓存
+// 的 key 中包含一个版本号，Clear 通过递增版本号使之前所有的 key 失效，失效的
+// key 在过期之后由 Redis 删除。
+type redisCache struct {
+	client redis.Client
+	prefix string
+}
+
+// NewRedis 创建基于 Redis 的缓存，prefix 为缓存中所有 key 的前缀。
+func NewRedis(client redis.Client, prefix string) Cache {
+	return &redisCache{client: client, prefix: prefix}
+}
+
+func (c *redisCache) version(ctx context.Context) (string, error) {
+	v, err := c.client.Get(ctx, c.prefix+"version")
+	if errors.Is(err, redis.ErrNil) {
+		return "0", nil
+	}
+	return v, err
+}
+
+func (c *redisCache) key(ctx context.Context, key string) (string, error) {
+	v, err := c.version(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.prefix + v + ":" + key, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	k, err := c.key(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	s, err := c.client.Get(ctx, k)
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(s), true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	k, err := c.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, k, string(value), ttl)
+}
+
+func (c *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	v, err := c.version(ctx)
+	if err != nil {
+		return err
+	}
+	ks := make([]string, len(keys))
+	for i, key := range keys {
+		ks[i] = c.prefix + v + ":" + key
+	}
+	_, err = c.client.Del(ctx, ks...)
+	return err
+}
+
+func (c *redisCache) Clear(ctx context.Context) error {
+	_, err := c.client.Incr(ctx, c.prefix+"version")
+	return err
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// tiered 由进程内缓存和远程缓存组成的两级缓存。设置了 publish 时，写入和删除
+// 之后广播失效消息，其他实例收到消息之后删除本地缓存中对应的值，否则本地缓存
+// 中的值最多会在 localTTL 时间内与远程缓存不一致。
+type tiered struct {
+	local    Cache
+	remote   Cache
+	localTTL time.Duration
+	publish  func(ctx context.Context, keys []string) // keys 为 nil 时表示清空
+}
+
+// NewTiered 创建两级缓存，读取时优先读取 local ，local 中不存在时读取 remote 并
+// 回写到 local 中，写入和删除时同时操作两级缓存。
+func NewTiered(local, remote Cache, localTTL time.Duration) Cache {
+	return &tiered{local: local, remote: remote, localTTL: localTTL}
+}
+
+func (c *tiered) ttl(ttl time.Duration) time.Duration {
+	if ttl <= 0 || (c.localTTL > 0 && c.localTTL < ttl) {
+		return c.localTTL
+	}
+	return ttl
+}
+
+func (c *tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if b, ok, err := c.local.Get(ctx, key); err == nil && ok {
+		return b, true, nil
+	}
+	b, ok, err := c.remote.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	_ = c.local.Set(ctx, key, b, c.localTTL)
+	return b, true, nil
+}
+
+func (c *tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	c.notify(ctx, []string{key})
+	return c.local.Set(ctx, key, value, c.ttl(ttl))
+}
+
+func (c *tiered) notify(ctx context.Context, keys []string) {
+	if c.publish != nil {
+		c.publish(ctx, keys)
+	}
+}
+
+func (c *tiered) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := c.local.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	if err := c.remote.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	c.notify(ctx, keys)
+	return nil
+}
+
+func (c *tiered) Clear(ctx context.Context) error {
+	if err := c.local.Clear(ctx); err != nil {
+		return err
+	}
+	if err := c.remote.Clear(ctx); err != nil {
+		return err
+	}
+	c.notify(ctx, nil)
+	return nil
+}
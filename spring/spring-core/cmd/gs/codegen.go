@@ -0,0 +1,311 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// generatedHeader 生成文件的头部注释，也用于判断文件是否可以覆盖。
+const generatedHeader = "// Code generated by gs codegen. DO NOT EDIT."
+
+// runCodegen 扫描目录下通过 Provide 方法注册的包级别构造函数，为它们生成静态
+// 调用器，使容器在刷新时不再通过反射调用这些构造函数。可变参数函数、泛型函数以及
+// 函数字面量仍然通过反射调用，后两者可以改用 gs.Provide1 等泛型函数进行注册。
+func runCodegen(args []string, out io.Writer) error {
+
+	fs := flag.NewFlagSet("codegen", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dir := fs.String("dir", ".", "package directory")
+	output := fs.String("o", "zz_gs_codegen.go", "output file name")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: gs codegen [-dir dir] [-o file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	file := filepath.Join(*dir, *output)
+	if b, err := os.ReadFile(file); err == nil {
+		if !bytes.HasPrefix(b, []byte(generatedHeader)) {
+			return fmt.Errorf("%s already exists and isn't generated by gs codegen", file)
+		}
+	}
+
+	pkg, err := scanPackage(*dir, *output)
+	if err != nil {
+		return err
+	}
+	if len(pkg.ctors) == 0 {
+		fmt.Fprintln(out, "no constructors found in", *dir)
+		return nil
+	}
+
+	b, err := pkg.generate()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(file, b, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "generate %s with %d constructors\n", file, len(pkg.ctors))
+	return nil
+}
+
+// ctorFunc 构造函数的签名。
+type ctorFunc struct {
+	name    string
+	params  []string // 参数类型
+	results int      // 返回值个数
+}
+
+type codegenPackage struct {
+	name    string
+	fset    *token.FileSet
+	imports map[string]string // 包名到导入路径的映射
+	ctors   []*ctorFunc
+}
+
+// scanPackage 解析目录下除测试文件和输出文件之外的所有 Go 文件。
+func scanPackage(dir string, output string) (*codegenPackage, error) {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &codegenPackage{fset: token.NewFileSet(), imports: make(map[string]string)}
+	funcs := make(map[string]*ast.FuncDecl)
+	funcFiles := make(map[string]*ast.File)
+	provided := make(map[string]bool)
+
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") || filepath.Base(name) == output {
+			continue
+		}
+		f, err := parser.ParseFile(pkg.fset, name, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.name == "" {
+			pkg.name = f.Name.Name
+		} else if pkg.name != f.Name.Name {
+			return nil, fmt.Errorf("found packages %s and %s in %s", pkg.name, f.Name.Name, dir)
+		}
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Type.TypeParams == nil {
+				funcs[fd.Name.Name] = fd
+				funcFiles[fd.Name.Name] = f
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if c, ok := n.(*ast.CallExpr); ok && len(c.Args) > 0 {
+				if s, ok := c.Fun.(*ast.SelectorExpr); ok && s.Sel.Name == "Provide" {
+					if id, ok := c.Args[0].(*ast.Ident); ok {
+						provided[id.Name] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	var names []string
+	for name := range provided {
+		if _, ok := funcs[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fd := funcs[name]
+		ctor, err := pkg.ctorFunc(fd, funcFiles[name])
+		if err != nil {
+			return nil, err
+		}
+		if ctor != nil {
+			pkg.ctors = append(pkg.ctors, ctor)
+		}
+	}
+	return pkg, nil
+}
+
+// ctorFunc 返回构造函数的签名，不支持的函数返回 nil 。
+func (pkg *codegenPackage) ctorFunc(fd *ast.FuncDecl, f *ast.File) (*ctorFunc, error) {
+
+	if fd.Type.Results == nil || len(fd.Type.Results.List) == 0 {
+		return nil, nil
+	}
+
+	ctor := &ctorFunc{name: fd.Name.Name}
+	for _, p := range fd.Type.Params.List {
+		if _, ok := p.Type.(*ast.Ellipsis); ok {
+			return nil, nil
+		}
+		s, err := pkg.typeString(p.Type, f)
+		if err != nil {
+			return nil, err
+		}
+		n := len(p.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			ctor.params = append(ctor.params, s)
+		}
+	}
+
+	for _, r := range fd.Type.Results.List {
+		if _, err := pkg.typeString(r.Type, f); err != nil {
+			return nil, err
+		}
+		if n := len(r.Names); n > 0 {
+			ctor.results += n
+		} else {
+			ctor.results++
+		}
+	}
+	return ctor, nil
+}
+
+// typeString 返回类型表达式的字符串形式，并记录类型表达式引用的包。
+func (pkg *codegenPackage) typeString(expr ast.Expr, f *ast.File) (string, error) {
+
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		s, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		id, ok := s.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path, ok := importPath(f, id.Name)
+		if !ok {
+			err = fmt.Errorf("can't find import of %s", id.Name)
+			return false
+		}
+		if p, ok := pkg.imports[id.Name]; ok && p != path {
+			err = fmt.Errorf("package name %s refers to both %s and %s", id.Name, p, path)
+			return false
+		}
+		pkg.imports[id.Name] = path
+		return false
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = printer.Fprint(&buf, pkg.fset, expr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// importPath 返回文件中包名 name 对应的导入路径，没有别名时使用导入路径的最后
+// 一个元素作为包名，最后一个元素是版本号时使用倒数第二个元素。
+func importPath(f *ast.File, name string) (string, bool) {
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return path, true
+			}
+			continue
+		}
+		elems := strings.Split(path, "/")
+		base := elems[len(elems)-1]
+		if n := len(elems); n > 1 && isMajorVersion(base) {
+			base = elems[n-2]
+		}
+		if base == name || strings.TrimPrefix(base, "go-") == name {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// generate 生成静态调用器的代码。
+func (pkg *codegenPackage) generate() ([]byte, error) {
+
+	if _, ok := pkg.imports["gsarg"]; ok {
+		return nil, errors.New("package name gsarg is reserved by gs codegen")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, generatedHeader)
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.name)
+	fmt.Fprintln(&buf, "import (")
+	fmt.Fprintln(&buf, `	gsarg "github.com/go-spring/spring-core/gs/arg"`)
+	var names []string
+	for name := range pkg.imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%s %q\n", name, pkg.imports[name])
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "func init() {")
+	for _, c := range pkg.ctors {
+		fmt.Fprintf(&buf, "\tgsarg.RegisterInvoker(%s, func(in []interface{}) []interface{} {\n", c.name)
+		var args, results []string
+		for i, t := range c.params {
+			fmt.Fprintf(&buf, "\t\ta%d, _ := in[%d].(%s)\n", i, i, t)
+			args = append(args, fmt.Sprintf("a%d", i))
+		}
+		for i := 0; i < c.results; i++ {
+			results = append(results, fmt.Sprintf("r%d", i))
+		}
+		fmt.Fprintf(&buf, "\t\t%s := %s(%s)\n", strings.Join(results, ", "), c.name, strings.Join(args, ", "))
+		fmt.Fprintf(&buf, "\t\treturn []interface{}{%s}\n", strings.Join(results, ", "))
+		fmt.Fprintln(&buf, "\t})")
+	}
+	fmt.Fprintln(&buf, "}")
+	return format.Source(buf.Bytes())
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// generator 代码生成器。
+type generator struct {
+	name  string
+	usage string
+	run   func(fs *flag.FlagSet, dir string, name string, out io.Writer) error
+	flags func(fs *flag.FlagSet)
+}
+
+var generators = []*generator{
+	{
+		name:  "bean",
+		usage: "generate a bean with its constructor and registration",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("prefix", "", "property prefix bound to the bean config, default is the kebab case of the name")
+		},
+		run: genBean,
+	},
+	{
+		name:  "controller",
+		usage: "generate a controller with CRUD routes",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("path", "", "base path of the routes, default is the kebab case of the name")
+		},
+		run: genController,
+	},
+}
+
+// runGen 生成 bean 或者控制器的代码，代码写入到 dir 目录下以名称命名的文件中。
+func runGen(args []string, out io.Writer) error {
+
+	if len(args) == 0 {
+		genUsage(out)
+		return errors.New("no generator")
+	}
+
+	for _, g := range generators {
+		if g.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet("gen "+g.name, flag.ContinueOnError)
+		fs.SetOutput(out)
+		dir := fs.String("dir", ".", "output directory")
+		g.flags(fs)
+		fs.Usage = func() {
+			fmt.Fprintf(out, "Usage: gs gen %s [flags] <Name>\n", g.name)
+			fs.PrintDefaults()
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return errors.New("name is required")
+		}
+		return g.run(fs, *dir, exportedName(fs.Arg(0)), out)
+	}
+
+	genUsage(out)
+	return fmt.Errorf("unknown generator %q", args[0])
+}
+
+func genUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: gs gen <generator> [flags] <Name>")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Generators:")
+	for _, g := range generators {
+		fmt.Fprintf(out, "  %-12s %s\n", g.name, g.usage)
+	}
+}
+
+// kebabName 将名称转换为短横线分隔的小写形式，例如 UserService 转换为 user-service 。
+func kebabName(name string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(fileName(name), ".go"), "_", "-")
+}
+
+type beanData struct {
+	Package string
+	Name    string
+	Prefix  string
+}
+
+func genBean(fs *flag.FlagSet, dir string, name string, out io.Writer) error {
+	prefix := fs.Lookup("prefix").Value.String()
+	if prefix == "" {
+		prefix = kebabName(name)
+	}
+	data := beanData{Package: packageName(dir), Name: name, Prefix: prefix}
+	return render(out, filepath.Join(dir, fileName(name)), beanTemplate, data)
+}
+
+type controllerData struct {
+	Package string
+	Name    string
+	Path    string
+}
+
+func genController(fs *flag.FlagSet, dir string, name string, out io.Writer) error {
+	name = strings.TrimSuffix(name, "Controller")
+	p := fs.Lookup("path").Value.String()
+	if p == "" {
+		p = kebabName(name)
+	}
+	p = "/" + strings.Trim(p, "/")
+	data := controllerData{Package: packageName(dir), Name: name, Path: p}
+	return render(out, filepath.Join(dir, fileName(name+"Controller")), controllerTemplate, data)
+}
+
+const beanTemplate = `package {{.Package}}
+
+import (
+	"github.com/go-spring/spring-core/gs"
+)
+
+func init() {
+	gs.Provide(New{{.Name}}, "${ {{- .Prefix -}} }")
+}
+
+// {{.Name}}Config {{.Name}} 的配置，绑定到 {{.Prefix}} 前缀的属性上。
+type {{.Name}}Config struct {
+}
+
+// {{.Name}} TODO 添加描述。
+type {{.Name}} struct {
+	config *{{.Name}}Config // 使用指针避免容器对其进行属性绑定
+}
+
+// New{{.Name}} {{.Name}} 的构造函数。
+func New{{.Name}}(config {{.Name}}Config) *{{.Name}} {
+	return &{{.Name}}{config: &config}
+}
+
+// OnInit 容器完成注入后调用。
+func (s *{{.Name}}) OnInit() error {
+	return nil
+}
+
+// OnDestroy 容器关闭时调用。
+func (s *{{.Name}}) OnDestroy() {
+}
+`
+
+const controllerTemplate = `package {{.Package}}
+
+import (
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/web"
+)
+
+func init() {
+	gs.Object(new({{.Name}}Controller)).Init(func(c *{{.Name}}Controller) {
+		gs.GetMapping("{{.Path}}", c.List)
+		gs.GetMapping("{{.Path}}/{id}", c.Get)
+		gs.PostMapping("{{.Path}}", c.Create)
+		gs.PutMapping("{{.Path}}/{id}", c.Update)
+		gs.DeleteMapping("{{.Path}}/{id}", c.Delete)
+	})
+}
+
+// {{.Name}}Controller 处理 {{.Path}} 路径下的请求。
+type {{.Name}}Controller struct {
+}
+
+// List 返回全部资源。
+func (c *{{.Name}}Controller) List(ctx web.Context) {
+	ctx.JSON(web.SUCCESS.Data([]interface{}{}))
+}
+
+// Get 返回 id 对应的资源。
+func (c *{{.Name}}Controller) Get(ctx web.Context) {
+	ctx.JSON(web.SUCCESS.Data(ctx.PathParam("id")))
+}
+
+// Create 创建资源。
+func (c *{{.Name}}Controller) Create(ctx web.Context) {
+	ctx.JSON(web.SUCCESS)
+}
+
+// Update 更新 id 对应的资源。
+func (c *{{.Name}}Controller) Update(ctx web.Context) {
+	ctx.JSON(web.SUCCESS.Data(ctx.PathParam("id")))
+}
+
+// Delete 删除 id 对应的资源。
+func (c *{{.Name}}Controller) Delete(ctx web.Context) {
+	ctx.JSON(web.SUCCESS.Data(ctx.PathParam("id")))
+}
+`
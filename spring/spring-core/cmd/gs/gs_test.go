@@ -0,0 +1,229 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func parseFile(t *testing.T, file string) string {
+	b, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), file, b, parser.AllErrors)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, run(nil, &out), "no command")
+	assert.Error(t, run([]string{"build"}, &out), "unknown command \"build\"")
+	assert.Nil(t, run([]string{"help"}, &out))
+	assert.True(t, strings.Contains(out.String(), "Usage: gs <command>"))
+}
+
+func TestNew(t *testing.T) {
+
+	dir := filepath.Join(t.TempDir(), "demo")
+	var out bytes.Buffer
+	err := run([]string{"new", "-dir", dir, "-starters", "gin, github.com/x/starter-y", "github.com/example/demo"}, &out)
+	assert.Nil(t, err)
+
+	s := parseFile(t, filepath.Join(dir, "main.go"))
+	assert.True(t, strings.Contains(s, `_ "github.com/go-spring/starter-gin"`))
+	assert.True(t, strings.Contains(s, `_ "github.com/x/starter-y"`))
+	assert.True(t, strings.Contains(s, `boot.Main(boot.Name("demo"))`))
+
+	parseFile(t, filepath.Join(dir, "modules/modules.go"))
+	parseFile(t, filepath.Join(dir, "controller/hello.go"))
+
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "module github.com/example/demo\n"))
+
+	// 不会覆盖已经存在的文件
+	err = run([]string{"new", "-dir", dir, "github.com/example/demo"}, &out)
+	assert.Error(t, err, "go.mod already exists")
+
+	err = run([]string{"new"}, &out)
+	assert.Error(t, err, "module path is required")
+}
+
+func TestGen(t *testing.T) {
+
+	dir := filepath.Join(t.TempDir(), "service")
+	var out bytes.Buffer
+
+	err := run([]string{"gen", "bean", "-dir", dir, "user-service"}, &out)
+	assert.Nil(t, err)
+	s := parseFile(t, filepath.Join(dir, "user_service.go"))
+	assert.True(t, strings.Contains(s, "package service\n"))
+	assert.True(t, strings.Contains(s, `gs.Provide(NewUserService, "${user-service}")`))
+
+	err = run([]string{"gen", "controller", "-dir", dir, "-path", "api/users/", "UserController"}, &out)
+	assert.Nil(t, err)
+	s = parseFile(t, filepath.Join(dir, "user_controller.go"))
+	assert.True(t, strings.Contains(s, `gs.GetMapping("/api/users/{id}", c.Get)`))
+	assert.True(t, strings.Contains(s, "type UserController struct"))
+
+	err = run([]string{"gen", "bean", "-dir", dir, "user-service"}, &out)
+	assert.Error(t, err, "user_service.go already exists")
+
+	assert.Error(t, run([]string{"gen"}, &out), "no generator")
+	assert.Error(t, run([]string{"gen", "model", "User"}, &out), "unknown generator \"model\"")
+}
+
+func TestCodegen(t *testing.T) {
+
+	dir := t.TempDir()
+	src := `package service
+
+import (
+	"context"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/go-spring/spring-core/gs"
+)
+
+func init() {
+	gs.Provide(NewService, "", "${service}")
+	gs.Provide(NewClients, "")
+	gs.Provide(func() int { return 1 })
+	gs.Object(NewIgnored())
+}
+
+type Config struct{}
+type Service struct{}
+
+func NewService(ctx context.Context, client *redis.Client, config Config) (*Service, error) {
+	return &Service{}, nil
+}
+
+func NewClients(clients ...*redis.Client) []*redis.Client {
+	return clients
+}
+
+func NewIgnored() *Config {
+	return &Config{}
+}
+`
+	err := os.WriteFile(filepath.Join(dir, "service.go"), []byte(src), 0644)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	err = run([]string{"codegen", "-dir", dir}, &out)
+	assert.Nil(t, err)
+
+	s := parseFile(t, filepath.Join(dir, "zz_gs_codegen.go"))
+	assert.True(t, strings.HasPrefix(s, generatedHeader))
+	assert.True(t, strings.Contains(s, `redis "github.com/go-redis/redis/v8"`))
+	assert.True(t, strings.Contains(s, "a1, _ := in[1].(*redis.Client)"))
+	assert.True(t, strings.Contains(s, "r0, r1 := NewService(a0, a1, a2)"))
+	assert.False(t, strings.Contains(s, "NewClients"))
+	assert.False(t, strings.Contains(s, "NewIgnored"))
+
+	// 可以覆盖生成的文件，但是不能覆盖用户的文件
+	err = run([]string{"codegen", "-dir", dir}, &out)
+	assert.Nil(t, err)
+	err = run([]string{"codegen", "-dir", dir, "-o", "service.go"}, &out)
+	assert.Error(t, err, "isn't generated by gs codegen")
+}
+
+func TestVet(t *testing.T) {
+
+	root, err := filepath.Abs("../..")
+	assert.Nil(t, err)
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/vet\n\ngo 1.18\n\n" +
+			"require github.com/go-spring/spring-core v0.0.0\n\n" +
+			"replace github.com/go-spring/spring-core => " + root + "\n",
+		"go.sum":                        string(sum),
+		"config/application.properties": "db.url=mysql://localhost\n",
+		"vet.go": `package vet
+
+import (
+	"fmt"
+
+	"github.com/go-spring/spring-core/gs"
+)
+
+type Store interface{ Get() string }
+
+type Repo struct {
+	URL  string ` + "`value:\"${db.url}\"`" + `
+	Host string ` + "`value:\"${db.host}\"`" + `
+	Port int    ` + "`value:\"{db.port}\"`" + `
+}
+
+type Service struct {
+	Store Store ` + "`autowire:\"\"`" + `
+	Repo  *Repo ` + "`autowire:\"\"`" + `
+	Main  *Repo ` + "`autowire:\"main\"`" + `
+	Other *Repo ` + "`autowire:\"other?\"`" + `
+}
+
+type Config struct{}
+
+func NewService(c Config) *Service { return &Service{} }
+
+func init() {
+	gs.Object(&Repo{}).Name("main")
+	gs.Object(&Repo{}).Export((*fmt.Stringer)(nil))
+	gs.Provide(NewService, "${service}")
+}
+`,
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.Nil(t, os.WriteFile(file, []byte(content), 0644))
+	}
+
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	var out bytes.Buffer
+	err = run([]string{"vet"}, &out)
+	assert.Error(t, err, "5 problems found")
+
+	s := out.String()
+	for _, msg := range []string{
+		`vet.go:13:14: property "db.host" not found in config and has no default value`,
+		`vet.go:14:14: "{db.port}" should be ${key} or ${key:=default}`,
+		`vet.go:18:2: can't find bean of type example.com/vet.Store for field Store`,
+		`vet.go:19:2: found 2 beans of type *example.com/vet.Repo for field Repo`,
+		`vet.go:30:28: *example.com/vet.Repo doesn't implement fmt.Stringer`,
+	} {
+		assert.True(t, strings.Contains(s, msg))
+	}
+	assert.False(t, strings.Contains(s, "db.url"))
+	assert.False(t, strings.Contains(s, "field Main"))
+	assert.False(t, strings.Contains(s, "field Other"))
+}
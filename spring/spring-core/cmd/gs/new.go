@@ -0,0 +1,158 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// project 项目骨架的模板数据。
+type project struct {
+	Module   string   // 模块路径
+	Name     string   // 应用名称
+	Starters []string // 引入的 starter 包
+}
+
+// runNew 创建项目骨架，包括 go.mod 、 main.go 、配置文件、模块和示例控制器。
+func runNew(args []string, out io.Writer) error {
+
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dir := fs.String("dir", "", "project directory, default is the last element of the module path")
+	starters := fs.String("starters", "", "comma separated starters, e.g. gin,go-redis")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: gs new [-dir dir] [-starters a,b] <module>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("module path is required")
+	}
+
+	p := project{Module: fs.Arg(0), Name: path.Base(fs.Arg(0))}
+	for _, s := range strings.Split(*starters, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			s = "github.com/go-spring/starter-" + strings.TrimPrefix(s, "starter-")
+		}
+		p.Starters = append(p.Starters, s)
+	}
+
+	root := *dir
+	if root == "" {
+		root = p.Name
+	}
+
+	files := []struct {
+		name string
+		text string
+	}{
+		{"go.mod", goModTemplate},
+		{"main.go", mainTemplate},
+		{"config/application.yaml", configTemplate},
+		{"modules/modules.go", moduleTemplate},
+		{"controller/hello.go", helloTemplate},
+	}
+	for _, f := range files {
+		if err := render(out, filepath.Join(root, f.name), f.text, p); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "\nproject created, run:\n\n  cd %s\n  go mod tidy\n  go run .\n", root)
+	return nil
+}
+
+const goModTemplate = `module {{.Module}}
+
+go 1.18
+`
+
+const mainTemplate = `package main
+
+import (
+	_ "{{.Module}}/controller"
+	_ "{{.Module}}/modules"
+
+	"github.com/go-spring/spring-core/gs/boot"
+{{- range .Starters}}
+	_ "{{.}}"
+{{- end}}
+)
+
+func main() {
+	boot.Main(boot.Name("{{.Name}}"))
+}
+`
+
+const configTemplate = `spring:
+  application:
+    name: {{.Name}}
+
+web:
+  server:
+    port: 8080
+`
+
+const moduleTemplate = `// Package modules 注册应用的 bean ，按照功能划分为多个模块。
+package modules
+
+import (
+	"github.com/go-spring/spring-core/gs"
+)
+
+func init() {
+	gs.Import(gs.ModuleFunc(func(c *gs.Container) {
+		// c.Provide(NewXxx, "${xxx}")
+	}))
+}
+`
+
+const helloTemplate = `package controller
+
+import (
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/web"
+)
+
+func init() {
+	gs.Object(new(HelloController)).Init(func(c *HelloController) {
+		gs.GetMapping("/hello", c.Hello)
+	})
+}
+
+// HelloController 示例控制器。
+type HelloController struct {
+	Name string ` + "`" + `value:"${spring.application.name}"` + "`" + `
+}
+
+// Hello 返回问候语。
+func (c *HelloController) Hello(ctx web.Context) {
+	ctx.String("hello from %s", c.Name)
+}
+`
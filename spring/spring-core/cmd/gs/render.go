@@ -0,0 +1,110 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// render 渲染模板并写入文件，文件已经存在时返回错误，避免覆盖用户的代码。
+func render(out io.Writer, file string, text string, data interface{}) error {
+
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s already exists", file)
+	}
+
+	t, err := template.New(filepath.Base(file)).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	b := buf.Bytes()
+	if strings.HasSuffix(file, ".go") {
+		if b, err = format.Source(b); err != nil {
+			return fmt.Errorf("format %s: %w", file, err)
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(file, b, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "create", file)
+	return nil
+}
+
+// exportedName 将名称转换为导出的 Go 标识符，例如 user-service 转换为 UserService 。
+func exportedName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '-' || r == '_' || r == ' ' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// packageName 返回目录对应的包名。
+func packageName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "main"
+	}
+	name := strings.ToLower(filepath.Base(abs))
+	name = strings.NewReplacer("-", "", "_", "", ".", "").Replace(name)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return "main"
+	}
+	return name
+}
+
+// fileName 返回名称对应的文件名，例如 UserService 转换为 user_service.go 。
+func fileName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + ".go"
+}
@@ -0,0 +1,520 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+const gsPath = "github.com/go-spring/spring-core/gs"
+
+// registration 通过 Object 或者 Provide 方法注册的 bean 。
+type registration struct {
+	pos     token.Pos
+	typ     types.Type
+	name    string // 为空表示名称不能在编译期确定
+	primary bool
+	cond    bool
+	exports []types.Type
+}
+
+type diagnostic struct {
+	pos token.Position
+	msg string
+}
+
+type vetter struct {
+	fset  *token.FileSet
+	pkgs  []*checkedPackage
+	regs  []*registration
+	props *conf.Properties // 为 nil 时不检查属性是否存在
+	diags []diagnostic
+}
+
+// runVet 在运行程序之前检查 bean 的注册和注入：无法满足的注入、有歧义的候选者、
+// 书写错误的属性引用以及错误的 Export 用法。
+func runVet(args []string, out io.Writer) error {
+
+	fs := flag.NewFlagSet("vet", flag.ContinueOnError)
+	fs.SetOutput(out)
+	config := fs.String("config", "config", "config directory used to check property references, skip if not exists")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: gs vet [-config dir] [packages]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	v := &vetter{fset: token.NewFileSet()}
+	props, err := loadConfig(*config)
+	if err != nil {
+		return err
+	}
+	v.props = props
+
+	if v.pkgs, err = loadPackages(v.fset, patterns); err != nil {
+		return err
+	}
+
+	v.collect()
+	v.checkStructs()
+
+	sort.Slice(v.diags, func(i, j int) bool {
+		a, b := v.diags[i].pos, v.diags[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	for _, d := range v.diags {
+		fmt.Fprintf(out, "%s: %s\n", d.pos, d.msg)
+	}
+	if n := len(v.diags); n > 0 {
+		return fmt.Errorf("%d problems found", n)
+	}
+	return nil
+}
+
+// loadConfig 加载配置目录下所有的配置文件，目录不存在时返回 nil 。
+func loadConfig(dir string) (*conf.Properties, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil
+	}
+	p := conf.New()
+	for _, ext := range []string{".properties", ".prop", ".yaml", ".yml", ".toml", ".tml"} {
+		files, err := filepath.Glob(filepath.Join(dir, "application*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err = p.Load(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func (v *vetter) report(pos token.Pos, format string, args ...interface{}) {
+	v.diags = append(v.diags, diagnostic{pos: v.fset.Position(pos), msg: fmt.Sprintf(format, args...)})
+}
+
+// gsFunc 返回 call 调用的 gs 包的函数或者方法，不是时返回 nil 。
+func gsFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch f := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		id = f.Sel
+	case *ast.Ident:
+		id = f
+	default:
+		return nil
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != gsPath {
+		return nil
+	}
+	return fn
+}
+
+// isBeanDefinitionMethod 判断函数是否是 *gs.BeanDefinition 的方法。
+func isBeanDefinitionMethod(fn *types.Func) bool {
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		return false
+	}
+	t := sig.Recv().Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "BeanDefinition"
+}
+
+// collect 收集所有注册的 bean 并检查 Export 的用法和 Provide 的属性引用。
+func (v *vetter) collect() {
+
+	for _, p := range v.pkgs {
+		calls := make(map[*ast.CallExpr]*registration)
+
+		for _, f := range p.files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) == 0 {
+					return true
+				}
+				fn := gsFunc(p.info, call)
+				if fn == nil || isBeanDefinitionMethod(fn) {
+					return true
+				}
+				var t types.Type
+				switch fn.Name() {
+				case "Object":
+					t = p.info.TypeOf(call.Args[0])
+				case "Provide":
+					sig, ok := p.info.TypeOf(call.Args[0]).Underlying().(*types.Signature)
+					if !ok || sig.Results().Len() == 0 {
+						return true
+					}
+					t = sig.Results().At(0).Type()
+					for i, arg := range call.Args[1:] {
+						var pt types.Type
+						if i < sig.Params().Len() {
+							pt = sig.Params().At(i).Type()
+						}
+						v.checkArg(p, arg, pt)
+					}
+				default:
+					return true
+				}
+				r := &registration{pos: call.Pos(), typ: t, name: beanName(t)}
+				calls[call] = r
+				v.regs = append(v.regs, r)
+				return true
+			})
+		}
+
+		for _, f := range p.files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fn := gsFunc(p.info, call)
+				if fn == nil || !isBeanDefinitionMethod(fn) {
+					return true
+				}
+				r := rootRegistration(call, calls)
+				if r == nil {
+					return true
+				}
+				switch fn.Name() {
+				case "Name":
+					r.name = ""
+					if len(call.Args) == 1 {
+						if s, ok := stringLit(call.Args[0]); ok {
+							r.name = s
+						}
+					}
+				case "Primary":
+					r.primary = true
+				case "On":
+					r.cond = true
+				case "Export":
+					for _, arg := range call.Args {
+						v.checkExport(p, r, arg)
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// rootRegistration 沿着链式调用找到注册 bean 的调用。
+func rootRegistration(call *ast.CallExpr, calls map[*ast.CallExpr]*registration) *registration {
+	for {
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		if call, ok = sel.X.(*ast.CallExpr); !ok {
+			return nil
+		}
+		if r, ok := calls[call]; ok {
+			return r
+		}
+	}
+}
+
+// beanName 返回 bean 的默认名称，即类型名称的最后一部分。
+func beanName(t types.Type) string {
+	s := strings.Split(types.TypeString(t, nil), ".")
+	return s[len(s)-1]
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// checkExport 检查 Export 的参数是否是 (*Interface)(nil) 形式，以及 bean 是否
+// 实现了该接口。
+func (v *vetter) checkExport(p *checkedPackage, r *registration, arg ast.Expr) {
+	t := p.info.TypeOf(arg)
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil &&
+		n.Obj().Pkg().Path() == "reflect" && n.Obj().Name() == "Type" {
+		return // 运行时才能确定的类型
+	}
+	ptr, ok := t.(*types.Pointer)
+	if ok {
+		if iface, ok := ptr.Elem().Underlying().(*types.Interface); ok {
+			if !types.Implements(r.typ, iface) {
+				v.report(arg.Pos(), "%s doesn't implement %s", r.typ, ptr.Elem())
+				return
+			}
+			r.exports = append(r.exports, ptr.Elem())
+			return
+		}
+	}
+	v.report(arg.Pos(), "Export argument should be (*Interface)(nil), got %s", t)
+}
+
+// checkArg 检查 Provide 的字符串参数中的属性引用，t 是参数对应的函数入参类型。
+func (v *vetter) checkArg(p *checkedPackage, arg ast.Expr, t types.Type) {
+	s, ok := stringLit(arg)
+	if !ok || !looksLikePlaceholder(s) {
+		return
+	}
+	key, hasDef, err := parsePlaceholder(s)
+	if err != nil {
+		v.report(arg.Pos(), "%s", err)
+		return
+	}
+	if !isStruct(t) {
+		v.checkProperty(arg.Pos(), key, hasDef)
+	}
+}
+
+func looksLikePlaceholder(s string) bool {
+	return strings.HasPrefix(s, "$") || strings.HasPrefix(s, "{")
+}
+
+// parsePlaceholder 解析 ${key} 或者 ${key:=default} 形式的属性引用。
+func parsePlaceholder(s string) (key string, hasDef bool, err error) {
+	if !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+		return "", false, fmt.Errorf("%q should be ${key} or ${key:=default}", s)
+	}
+	key = s[2 : len(s)-1]
+	if i := strings.Index(key, ":="); i >= 0 {
+		key, hasDef = key[:i], true
+	}
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune(".-_[]", c):
+		default:
+			return "", false, fmt.Errorf("%q has invalid character %q in key", s, c)
+		}
+	}
+	return key, hasDef, nil
+}
+
+// isStruct 判断类型是否是结构体，绑定结构体的属性前缀可以不存在，结构体的字段
+// 使用各自的默认值。
+func isStruct(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+// checkProperty 检查没有默认值的属性是否在配置文件中存在，key 也可以是前缀。
+func (v *vetter) checkProperty(pos token.Pos, key string, hasDef bool) {
+	if v.props == nil || hasDef || key == "" {
+		return
+	}
+	if v.props.Get(key) != nil {
+		return
+	}
+	for _, k := range v.props.Keys() {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return
+		}
+	}
+	v.report(pos, "property %q not found in config and has no default value", key)
+}
+
+// checkStructs 检查所有结构体字段的 value 标签以及 autowire 和 inject 标签。
+func (v *vetter) checkStructs() {
+	for _, p := range v.pkgs {
+		for _, f := range p.files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok {
+					return true
+				}
+				st, ok := spec.Type.(*ast.StructType)
+				if !ok {
+					return true
+				}
+				obj := p.info.Defs[spec.Name]
+				isBean := obj != nil && v.isBeanType(obj.Type())
+				for _, field := range st.Fields.List {
+					if field.Tag == nil {
+						continue
+					}
+					s, err := strconv.Unquote(field.Tag.Value)
+					if err != nil {
+						continue
+					}
+					tag := reflect.StructTag(s)
+					if value, ok := tag.Lookup("value"); ok {
+						key, hasDef, err := parsePlaceholder(value)
+						if err != nil {
+							v.report(field.Tag.Pos(), "%s", err)
+						} else if isBean && !isStruct(p.info.TypeOf(field.Type)) {
+							v.checkProperty(field.Tag.Pos(), key, hasDef)
+						}
+					}
+					wire, ok := tag.Lookup("autowire")
+					if !ok {
+						wire, ok = tag.Lookup("inject")
+					}
+					if ok {
+						v.checkWire(field, p.info.TypeOf(field.Type), wire)
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// isBeanType 判断 t 或者 *t 是否注册为 bean 。
+func (v *vetter) isBeanType(t types.Type) bool {
+	for _, r := range v.regs {
+		if types.Identical(r.typ, t) {
+			return true
+		}
+		if p, ok := r.typ.(*types.Pointer); ok && types.Identical(p.Elem(), t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocal 判断类型是否定义在被检查的包中，只有这些类型的注入可以确定无法满足。
+func (v *vetter) isLocal(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, ok := t.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	for _, p := range v.pkgs {
+		if p.pkg == n.Obj().Pkg() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWire 检查字段的注入是否能够满足以及是否存在歧义。
+func (v *vetter) checkWire(field *ast.Field, t types.Type, wire string) {
+
+	wire = strings.TrimSuffix(wire, ",lazy")
+	if strings.Contains(wire, "${") {
+		return // 运行时才能确定的选择器
+	}
+
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map, *types.Array:
+		return // 收集模式允许结果为空
+	}
+
+	nullable := strings.HasSuffix(wire, "?")
+	name := strings.TrimSuffix(wire, "?")
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var found []*registration
+	for _, r := range v.regs {
+		if name != "" {
+			if r.name == name && types.AssignableTo(r.typ, t) {
+				found = append(found, r)
+			}
+			continue
+		}
+		if types.Identical(r.typ, t) {
+			found = append(found, r)
+			continue
+		}
+		for _, e := range r.exports {
+			if types.Identical(e, t) {
+				found = append(found, r)
+				break
+			}
+		}
+	}
+
+	fieldName := "embedded field"
+	if len(field.Names) > 0 {
+		fieldName = field.Names[0].Name
+	}
+
+	if len(found) == 0 {
+		if !nullable && v.isLocal(t) {
+			if name != "" {
+				v.report(field.Pos(), "can't find bean %q of type %s for field %s", name, t, fieldName)
+			} else {
+				v.report(field.Pos(), "can't find bean of type %s for field %s", t, fieldName)
+			}
+		}
+		return
+	}
+
+	if name != "" || len(found) == 1 {
+		return
+	}
+
+	primary := 0
+	for _, r := range found {
+		if r.cond {
+			return // 带条件的 bean 可能互斥
+		}
+		if r.primary {
+			primary++
+		}
+	}
+	if primary == 1 {
+		return
+	}
+
+	var pos []string
+	for _, r := range found {
+		pos = append(pos, v.fset.Position(r.pos).String())
+	}
+	v.report(field.Pos(), "found %d beans of type %s for field %s, use a bean name or Primary(): %s",
+		len(found), t, fieldName, strings.Join(pos, ", "))
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
+	"github.com/go-spring/spring-core/expr"
 	"github.com/go-spring/spring-core/log"
 	"github.com/go-spring/spring-stl/cast"
 	"github.com/go-spring/spring-stl/util"
@@ -37,27 +39,82 @@ type bindOption struct {
 	path   string       // 绑定对象的路径
 	def    string       // 默认值
 	hasDef bool         // 是否具有默认值
+	beans  BeanFinder   // 表达式中 bean 引用的查找函数
+}
+
+// BindParam 属性绑定的参数。
+type BindParam struct {
+	Key    string // 完整的属性名
+	Path   string // 绑定对象的路径
+	Def    string // 默认值
+	HasDef bool   // 是否具有默认值
+}
+
+// Tag 返回 ${key:=def} 格式的属性绑定字符串。
+func (param BindParam) Tag() string {
+	if param.HasDef {
+		return "${" + param.Key + ":=" + param.Def + "}"
+	}
+	return "${" + param.Key + "}"
+}
+
+// Binder 可以由属性绑定的目标实现的接口，由目标自己完成属性的绑定，例如可以在
+// 运行时刷新的动态属性值。
+type Binder interface {
+	BindProperties(p *Properties, param BindParam) error
+}
+
+var binderType = reflect.TypeOf((*Binder)(nil)).Elem()
+
+// bindTag 预先解析的属性绑定字符串。
+type bindTag struct {
+	raw    string
+	expr   bool // 是否为 #{expr} 格式的表达式
+	valid  bool // 是否为 ${key:=def} 格式的字符串
+	key    string
+	def    string
+	hasDef bool
+}
+
+func parseBindTag(tag string) bindTag {
+	t := bindTag{raw: tag}
+	if isExprTag(tag) {
+		t.expr = true
+		return t
+	}
+	if validTag(tag) {
+		t.valid = true
+		t.key, t.def, t.hasDef = parseTag(tag)
+	}
+	return t
 }
 
 func bind(p *Properties, v reflect.Value, tag string, opt bindOption) error {
+	return bindParsed(p, v, parseBindTag(tag), opt)
+}
+
+func bindParsed(p *Properties, v reflect.Value, tag bindTag, opt bindOption) error {
 
 	if !util.IsValueType(opt.typ) {
 		return fmt.Errorf("%s 属性绑定的目标必须是值类型", opt.path)
 	}
 
-	if !validTag(tag) {
-		return fmt.Errorf("%s 属性绑定字符串 %q 语法错误", opt.path, tag)
+	if tag.expr {
+		return bindExpr(p, v, tag.raw[2:len(tag.raw)-1], opt)
+	}
+
+	if !tag.valid {
+		return fmt.Errorf("%s 属性绑定字符串 %q 语法错误", opt.path, tag.raw)
 	}
 
-	key, def, hasDef := parseTag(tag)
 	if opt.key == "" {
-		opt.key = key
-	} else if key != "" {
-		opt.key = opt.key + "." + key
+		opt.key = tag.key
+	} else if tag.key != "" {
+		opt.key = opt.key + "." + tag.key
 	}
 
-	opt.def = def
-	opt.hasDef = hasDef
+	opt.def = tag.def
+	opt.hasDef = tag.hasDef
 
 	return bindValue(p, v, opt)
 }
@@ -66,6 +123,11 @@ func bindValue(p *Properties, v reflect.Value, opt bindOption) error {
 
 	log.Tracef("::<>:: %#v", opt)
 
+	if v.CanAddr() && reflect.PtrTo(opt.typ).Implements(binderType) {
+		param := BindParam{Key: opt.key, Path: opt.path, Def: opt.def, HasDef: opt.hasDef}
+		return v.Addr().Interface().(Binder).BindProperties(p, param)
+	}
+
 	switch v.Kind() {
 	case reflect.Map:
 		return bindMap(p, v, opt)
@@ -75,9 +137,9 @@ func bindValue(p *Properties, v reflect.Value, opt bindOption) error {
 		return bindSlice(p, v, opt)
 	}
 
-	fn, _ := converters[opt.typ]
+	fn, hasFn := converters[opt.typ]
 	if v.Kind() == reflect.Struct {
-		if fn == nil {
+		if !hasFn {
 			return bindStruct(p, v, opt)
 		}
 	}
@@ -87,9 +149,8 @@ func bindValue(p *Properties, v reflect.Value, opt bindOption) error {
 		return fmt.Errorf("type %q bind error: %w", opt.typ, err)
 	}
 
-	if fn != nil {
-		fnValue := reflect.ValueOf(fn)
-		out := fnValue.Call([]reflect.Value{reflect.ValueOf(val)})
+	if hasFn {
+		out := fn.Call([]reflect.Value{reflect.ValueOf(val)})
 		if !out[1].IsNil() {
 			return out[1].Interface().(error)
 		}
@@ -149,9 +210,10 @@ func bindArray(p *Properties, v reflect.Value, opt bindOption) error {
 		subPath := fmt.Sprintf("%s[%d]", opt.path, i)
 
 		subOpt := bindOption{
-			typ:  subValue.Type(),
-			key:  subKey,
-			path: subPath,
+			typ:   subValue.Type(),
+			key:   subKey,
+			path:  subPath,
+			beans: opt.beans,
 		}
 
 		err := bindValue(p, subValue, subOpt)
@@ -181,7 +243,7 @@ func bindSlice(p *Properties, v reflect.Value, opt bindOption) error {
 
 		subKey := fmt.Sprintf("%s[%d]", opt.key, i)
 		subPath := fmt.Sprintf("%s[%d]", opt.path, i)
-		subOpt := bindOption{typ: et, key: subKey, path: subPath}
+		subOpt := bindOption{typ: et, key: subKey, path: subPath, beans: opt.beans}
 
 		e := reflect.New(et).Elem()
 		err := bindValue(p, e, subOpt)
@@ -221,7 +283,7 @@ func bindMap(p *Properties, v reflect.Value, opt bindOption) error {
 		}
 
 		if et.Kind() == reflect.Struct {
-			if fn, _ := converters[opt.typ]; fn == nil {
+			if _, ok := converters[opt.typ]; !ok {
 				subKey = strings.Split(subKey, ".")[0]
 			}
 		}
@@ -238,7 +300,7 @@ func bindMap(p *Properties, v reflect.Value, opt bindOption) error {
 		if opt.key != "" {
 			subKey = opt.key + "." + key
 		}
-		subOpt := bindOption{typ: et, key: subKey, path: opt.path}
+		subOpt := bindOption{typ: et, key: subKey, path: opt.path, beans: opt.beans}
 		err := bindValue(p, e, subOpt)
 		if err != nil {
 			return err
@@ -249,15 +311,46 @@ func bindMap(p *Properties, v reflect.Value, opt bindOption) error {
 	return nil
 }
 
+// structField 属性绑定时结构体字段的信息。
+type structField struct {
+	index  int
+	name   string
+	typ    reflect.Type
+	tag    bindTag
+	hasTag bool // 是否有 value 标签
+}
+
+var structPlans sync.Map // map[reflect.Type][]structField
+
+// structFields 返回结构体类型 t 中需要属性绑定或者递归处理的字段，结果只与类型
+// 有关，所以缓存起来避免每次都重新反射字段和解析标签。
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structPlans.Load(t); ok {
+		return fields.([]structField)
+	}
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		f := structField{index: i, name: ft.Name, typ: ft.Type}
+		if tag, ok := ft.Tag.Lookup("value"); ok {
+			f.tag, f.hasTag = parseBindTag(tag), true
+		} else if ft.Type.Kind() != reflect.Struct {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	actual, _ := structPlans.LoadOrStore(t, fields)
+	return actual.([]structField)
+}
+
 func bindStruct(p *Properties, v reflect.Value, opt bindOption) error {
 
 	if opt.hasDef && opt.def != "" {
 		return fmt.Errorf("%s struct 类型不能指定非空默认值", opt.path)
 	}
 
-	for i := 0; i < opt.typ.NumField(); i++ {
-		ft := opt.typ.Field(i)
-		fv := v.Field(i)
+	for _, f := range structFields(opt.typ) {
+		fv := v.Field(f.index)
 
 		if !fv.CanInterface() {
 			fv = util.PatchValue(fv)
@@ -267,13 +360,14 @@ func bindStruct(p *Properties, v reflect.Value, opt bindOption) error {
 		}
 
 		subOpt := bindOption{
-			typ:  ft.Type,
-			key:  opt.key,
-			path: opt.path + "." + ft.Name,
+			typ:   f.typ,
+			key:   opt.key,
+			path:  opt.path + "." + f.name,
+			beans: opt.beans,
 		}
 
-		if tag, ok := ft.Tag.Lookup("value"); ok {
-			err := bind(p, fv, tag, subOpt)
+		if f.hasTag {
+			err := bindParsed(p, fv, f.tag, subOpt)
 			if err != nil {
 				return err
 			}
@@ -281,16 +375,70 @@ func bindStruct(p *Properties, v reflect.Value, opt bindOption) error {
 		}
 
 		// 指针或者结构体类型可能出现无限递归的情况。
-		if ft.Type.Kind() == reflect.Struct {
-			err := bindStruct(p, fv, subOpt)
-			if err != nil {
-				return err
-			}
+		err := bindStruct(p, fv, subOpt)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// isExprTag 返回是否为 #{expr} 格式的表达式。
+func isExprTag(tag string) bool {
+	return strings.HasPrefix(tag, "#{") && strings.HasSuffix(tag, "}")
+}
+
+// exprEnv 表达式的执行环境。
+type exprEnv struct {
+	p     *Properties
+	beans BeanFinder
+}
+
+func (e exprEnv) Prop(key string) (string, bool) {
+	v := e.p.Get(key)
+	if v == nil {
+		return "", false
+	}
+	s, err := resolveString(e.p, v.(string))
+	if err != nil {
+		return v.(string), true
+	}
+	return s, true
+}
+
+func (e exprEnv) Bean(name string) (interface{}, bool, error) {
+	if e.beans == nil {
+		return nil, false, fmt.Errorf("bean reference @%s not supported", name)
+	}
+	return e.beans(name)
+}
+
+// bindExpr 计算表达式的值并绑定到 v 上，结果的类型可以赋值给 v 时直接赋值，否
+// 则将结果转换为字符串后按照属性值进行绑定。
+func bindExpr(p *Properties, v reflect.Value, s string, opt bindOption) error {
+
+	val, err := expr.Eval(s, exprEnv{p: p, beans: opt.beans})
+	if err != nil {
+		return fmt.Errorf("%s bind error: %w", opt.path, err)
+	}
+
+	if val != nil && reflect.TypeOf(val).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+
+	opt.key, opt.def, opt.hasDef = "", cast.ToString(val), true
+	switch v.Kind() {
+	case reflect.Map, reflect.Array, reflect.Slice:
+		return fmt.Errorf("%s can't bind %v to %s", opt.path, val, opt.typ)
+	case reflect.Struct:
+		if _, ok := converters[opt.typ]; !ok {
+			return fmt.Errorf("%s can't bind %v to %s", opt.path, val, opt.typ)
+		}
+	}
+	return bindValue(p, v, opt)
+}
+
 // validTag 返回是否为 ${key:=def} 格式的字符串。
 func validTag(tag string) bool {
 	return strings.HasPrefix(tag, "${") && strings.HasSuffix(tag, "}")
@@ -370,5 +518,9 @@ func resolve(p *Properties, opt bindOption) (string, error) {
 			return "", fmt.Errorf("property %q %w", opt.key, ErrNotExist)
 		}
 	}
-	return resolveString(p, val.(string))
+	str, err := resolveString(p, val.(string))
+	if err != nil {
+		return "", err
+	}
+	return resolveValue(str)
 }
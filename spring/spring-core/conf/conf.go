@@ -23,29 +23,89 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-stl/cast"
 )
 
 // Properties 提供创建和读取属性列表的方法。它使用扁平的 map[string]string 结
 // 构存储数据，属性的 key 可以是 a.b.c 或者 a[0].b 两种形式，a.b.c 表示从 map
 // 结构中获取属性值，a[0].b 表示从切片结构中获取属性值，并且 key 是大小写敏感的。
-type Properties struct{ m map[string]string }
+// 属性列表采用写时复制的方式实现，读操作访问的是一个发布之后就不再修改的快照，
+// 不需要加锁；写操作之间互斥，每次写操作复制当前的快照，修改之后原子地发布新的
+// 快照。因此属性列表可以在运行时被并发地读取和修改，例如热加载配置的同时在处理
+// 请求时读取属性。批量修改时应该使用 Merge 或者 Batch 方法，避免多次复制。
+type Properties struct {
+	mutex sync.Mutex        // 写操作之间的互斥
+	m     atomic.Value      // 当前的快照 map[string]string ，发布之后不再修改
+	batch map[string]string // Batch 期间尚未发布的副本，写操作直接修改它而不复制快照
+	w     Writer
+}
 
 // New 返回一个空的属性列表。
 func New() *Properties {
-	return &Properties{m: make(map[string]string)}
+	p := &Properties{}
+	p.m.Store(make(map[string]string))
+	return p
 }
 
 // Map 返回一个由 map 创建的属性列表。
 func Map(m map[string]interface{}) *Properties {
 	p := New()
-	for k, v := range m {
-		p.Set(k, v)
-	}
+	p.update(func(t map[string]string) {
+		for k, v := range m {
+			setValue(t, k, v)
+		}
+	})
 	return p
 }
 
+// snapshot 返回当前的快照，调用者不能修改。
+func (p *Properties) snapshot() map[string]string {
+	return p.m.Load().(map[string]string)
+}
+
+// update 复制当前的快照并使用 fn 修改，然后发布修改之后的快照。Batch 期间直接
+// 修改尚未发布的副本。
+func (p *Properties) update(fn func(m map[string]string)) {
+	if p.batch != nil {
+		fn(p.batch)
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	old := p.snapshot()
+	m := make(map[string]string, len(old))
+	for k, v := range old {
+		m[k] = v
+	}
+	fn(m)
+	p.m.Store(m)
+}
+
+// Batch 在一次写操作中执行 fn ，fn 对 q 的 Set、Merge 等修改都作用于同一个副本，
+// 在 fn 返回之后一起发布到 p 中，避免批量加载属性时每次修改都复制整个快照。q 只能
+// 在 fn 中使用，并且 fn 中不能修改 p 。
+func (p *Properties) Batch(fn func(q *Properties)) {
+	p.update(func(m map[string]string) {
+		q := &Properties{batch: m, w: p.w}
+		q.m.Store(m)
+		fn(q)
+	})
+}
+
+// Snapshot 返回当前属性的只读快照，之后对 p 的修改不会影响快照，适用于需要在
+// 一致的视图上读取多个属性的场景，例如属性绑定。快照不会复制属性，修改快照也不
+// 会影响 p 。
+func (p *Properties) Snapshot() *Properties {
+	s := &Properties{w: p.w}
+	s.m.Store(p.snapshot())
+	return s
+}
+
 // Load 返回一个由属性文件创建的属性列表，file 可以是绝对路径，也可以是相对路径。
 func Load(file string) (*Properties, error) {
 	p := New()
@@ -88,16 +148,29 @@ func (p *Properties) Read(b []byte, ext string) error {
 		return err
 	}
 
-	for k, v := range m {
-		p.Set(k, v)
-	}
+	p.update(func(t map[string]string) {
+		for k, v := range m {
+			setValue(t, k, v)
+		}
+	})
 	return nil
 }
 
+// Merge 使用 q 中的属性覆盖 p 中的同名属性，所有的修改在一个快照中发布。
+func (p *Properties) Merge(q *Properties) {
+	m := q.snapshot()
+	p.update(func(t map[string]string) {
+		for k, v := range m {
+			t[k] = v
+		}
+	})
+}
+
 // Keys 返回所有属性 key 的列表。
 func (p *Properties) Keys() []string {
-	keys := make([]string, 0, len(p.m))
-	for k := range p.m {
+	m := p.snapshot()
+	keys := make([]string, 0, len(m))
+	for k := range m {
 		keys = append(keys, k)
 	}
 	return keys
@@ -122,7 +195,7 @@ func Def(v interface{}) GetOption {
 // Get 方法的返回值是否为 nil 来判断 key 对应的属性值是否存在。
 func (p *Properties) Get(key string, opts ...GetOption) interface{} {
 
-	if val, ok := p.m[key]; ok {
+	if val, ok := p.snapshot()[key]; ok {
 		return val
 	}
 
@@ -143,32 +216,101 @@ func (p *Properties) Get(key string, opts ...GetOption) interface{} {
 // 成的属性值，其处理方式是将组合结构层层展开，可以将组合结构看成一棵树，那么叶子结
 // 点的路径就是属性的 key，叶子结点的值就是属性的值。
 func (p *Properties) Set(key string, val interface{}) {
+	p.update(func(m map[string]string) {
+		setValue(m, key, val)
+	})
+}
+
+// setValue 将 val 展开之后保存到 m 中，展开方式参见 Set 方法。
+func setValue(m map[string]string, key string, val interface{}) {
 	v := reflect.ValueOf(val)
 	switch v.Kind() {
 	case reflect.Map:
 		for _, k := range v.MapKeys() {
 			mapValue := v.MapIndex(k).Interface()
 			mapKey := cast.ToString(k.Interface())
-			p.Set(key+"."+mapKey, mapValue)
+			setValue(m, key+"."+mapKey, mapValue)
 		}
 	case reflect.Array, reflect.Slice:
 		for i := 0; i < v.Len(); i++ {
 			subKey := fmt.Sprintf("%s[%d]", key, i)
 			subValue := v.Index(i).Interface()
-			p.Set(subKey, subValue)
+			setValue(m, subKey, subValue)
 		}
 	default:
-		p.m[key] = cast.ToString(val)
+		m[key] = cast.ToString(val)
 	}
 }
 
+// ErrNotWritable 属性列表没有设置可写的属性源。
+var ErrNotWritable = errors.New("properties not writable")
+
+// SetWriter 设置保存运行时修改的属性的属性源，w 为 nil 时属性列表不可写。
+func (p *Properties) SetWriter(w Writer) {
+	p.w = w
+}
+
+// Writable 返回属性列表是否设置了可写的属性源。
+func (p *Properties) Writable() bool {
+	return p.w != nil
+}
+
+// Persist 修改 key 对应的属性值并写回可写的属性源，使修改在应用重启后仍然生效，
+// 例如通过 actuator 修改的日志级别和功能开关。val 的展开方式与 Set 方法相同，
+// val 为 nil 时删除 key 及其子属性。写回失败时属性列表保持不变，没有可写的属
+// 性源时返回 ErrNotWritable 。
+func (p *Properties) Persist(key string, val interface{}) error {
+
+	if p.w == nil {
+		return ErrNotWritable
+	}
+
+	values := make(map[string]string)
+	if val != nil {
+		setValue(values, key, val)
+	}
+
+	if err := p.w.Write(key, values); err != nil {
+		return err
+	}
+
+	p.update(func(m map[string]string) {
+		removeKey(m, key)
+		for k, v := range values {
+			m[k] = v
+		}
+	})
+	return nil
+}
+
+// removeKey 删除 key 及其子属性。
+func removeKey(m map[string]string, key string) {
+	for k := range m {
+		if isSubKey(k, key) {
+			delete(m, k)
+		}
+	}
+}
+
+// isSubKey 返回 k 是否为 key 本身或者其子属性，即 key.x 或者 key[i] 的形式。
+func isSubKey(k, key string) bool {
+	if !strings.HasPrefix(k, key) {
+		return false
+	}
+	if len(k) == len(key) {
+		return true
+	}
+	return k[len(key)] == '.' || k[len(key)] == '['
+}
+
 // Resolve 解析字符串中包含的所有属性引用即 ${key:=def} 的内容，并且支持递归引用。
 func (p *Properties) Resolve(s string) (string, error) {
-	return resolveString(p, s)
+	return resolveString(p.Snapshot(), s)
 }
 
 type bindArg struct {
-	tag string
+	tag   string
+	beans BeanFinder
 }
 
 type BindOption func(arg *bindArg)
@@ -187,6 +329,16 @@ func Tag(tag string) BindOption {
 	}
 }
 
+// BeanFinder 返回名称为 name 的 bean ，用于 #{...} 表达式中的 bean 引用。
+type BeanFinder func(name string) (interface{}, bool, error)
+
+// Beans 设置 #{...} 表达式中 bean 引用的查找函数。
+func Beans(fn BeanFinder) BindOption {
+	return func(arg *bindArg) {
+		arg.beans = fn
+	}
+}
+
 // Bind 将 key 对应的属性值绑定到某个数据类型的实例上。i 必须是一个指针，只有这
 // 样才能将修改传递出去。Bind 方法使用 tag 字符串对数据实例进行属性绑定，其语法
 // 为 value:"${a:=b}"，其中 value 表示属性绑定，${} 表示属性引用，a 表示属性
@@ -222,5 +374,12 @@ func (p *Properties) Bind(i interface{}, opts ...BindOption) error {
 		s = t.String()
 	}
 
-	return bind(p, v, arg.tag, bindOption{typ: t, path: s})
+	// 在同一个快照上完成绑定，避免绑定过程中属性被修改导致看到不一致的属性。
+	if err := bind(p.Snapshot(), v, arg.tag, bindOption{typ: t, path: s, beans: arg.beans}); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Struct {
+		return validator.Validate(v.Interface())
+	}
+	return nil
 }
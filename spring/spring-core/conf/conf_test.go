@@ -19,13 +19,17 @@ package conf_test
 import (
 	"container/list"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-spring/spring-core/conf"
 	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-stl/assert"
 	"github.com/go-spring/spring-stl/cast"
 )
@@ -485,6 +489,47 @@ func TestProperties_ReadToml(t *testing.T) {
 	})
 }
 
+func TestProperties_ReadDotenv(t *testing.T) {
+
+	t.Run("basic", func(t *testing.T) {
+		data := `
+# comment
+DB_URL=mysql://localhost:3306/db
+export APP_NAME = demo
+EMPTY=
+PLAIN=a b # trailing comment
+HASH=a#b
+SINGLE='raw \n ${HOME}'
+DOUBLE="line1\nline2 \"quoted\"" # comment
+MULTI="first
+second"
+spring.profiles.active=dev
+`
+		p, err := conf.Read([]byte(data), ".env")
+		assert.Nil(t, err)
+		assert.Equal(t, p.Get("DB_URL"), "mysql://localhost:3306/db")
+		assert.Equal(t, p.Get("APP_NAME"), "demo")
+		assert.Equal(t, p.Get("EMPTY"), "")
+		assert.Equal(t, p.Get("PLAIN"), "a b")
+		assert.Equal(t, p.Get("HASH"), "a#b")
+		assert.Equal(t, p.Get("DOUBLE"), "line1\nline2 \"quoted\"")
+		assert.Equal(t, p.Get("MULTI"), "first\nsecond")
+		assert.Equal(t, p.Get("spring.profiles.active"), "dev")
+		assert.Equal(t, p.Get("SINGLE"), `raw \n ${HOME}`)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := conf.Read([]byte("A=1\nB"), ".env")
+		assert.Error(t, err, "line 2: missing '=' in \"B\"")
+		_, err = conf.Read([]byte("1A=1"), ".env")
+		assert.Error(t, err, "line 1: invalid key \"1A\"")
+		_, err = conf.Read([]byte("A=\"1\nB=2"), ".env")
+		assert.Error(t, err, "line 1: unterminated quoted value")
+		_, err = conf.Read([]byte("A='1' 2"), ".env")
+		assert.Error(t, err, "line 1: unexpected \"2\" after quoted value")
+	})
+}
+
 func TestProperties_Get(t *testing.T) {
 
 	t.Run("base", func(t *testing.T) {
@@ -945,3 +990,230 @@ func TestInterpolate(t *testing.T) {
 	str, _ = p.Resolve("my name is ${name} my name is ${name}")
 	assert.Equal(t, str, "my name is Jim my name is Jim")
 }
+
+func TestBindValidate(t *testing.T) {
+
+	validator.Init(validator.NewEngine(validator.Config{Tag: "validate", Locale: "en"}))
+	defer validator.Init(nil)
+
+	type Server struct {
+		Host string `value:"${host:=}" validate:"required"`
+		Port int    `value:"${port:=8080}" validate:"max=65535"`
+	}
+
+	p := conf.New()
+	p.Set("server.host", "localhost")
+	var s Server
+	err := p.Bind(&s, conf.Key("server"))
+	assert.Nil(t, err)
+
+	p.Set("server.port", 70000)
+	err = p.Bind(&s, conf.Key("server"))
+	assert.Error(t, err, "Port must be at most 65535")
+}
+
+func TestProperties_Persist(t *testing.T) {
+
+	p := conf.New()
+	assert.Equal(t, p.Persist("a", 1), conf.ErrNotWritable)
+
+	_, err := conf.NewFileWriter("override.yaml")
+	assert.Error(t, err, "unsupported writable file type .yaml")
+
+	file := filepath.Join(t.TempDir(), "config", "override.properties")
+	w, err := conf.NewFileWriter(file)
+	assert.Nil(t, err)
+	p.SetWriter(w)
+	assert.True(t, p.Writable())
+
+	p.Set("logging.level.root", "info")
+	assert.Nil(t, p.Persist("logging.level.root", "debug"))
+	assert.Nil(t, p.Persist("feature.flags", map[string]interface{}{"a": true, "b": false}))
+	assert.Nil(t, p.Persist("hosts", []string{"a", "b"}))
+	assert.Nil(t, p.Persist("hosts", []string{"c"}))
+	assert.Nil(t, p.Persist("feature.flags.b", nil))
+	assert.Equal(t, p.Get("logging.level.root"), "debug")
+	assert.Nil(t, p.Get("hosts[1]"))
+	assert.Nil(t, p.Get("feature.flags.b"))
+
+	b, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "feature.flags.a = true\nhosts[0] = c\nlogging.level.root = debug\n")
+
+	f, err := conf.Load(file)
+	assert.Nil(t, err)
+	assert.Equal(t, f.Get("hosts[0]"), "c")
+}
+
+func TestBindExpr(t *testing.T) {
+
+	p := conf.Map(map[string]interface{}{
+		"pool.size": 8,
+		"profile":   "prod",
+	})
+
+	var s struct {
+		Size    int           `value:"#{${pool.size} * 2}"`
+		Ratio   float64       `value:"#{${pool.size} / 16.0}"`
+		Mode    string        `value:"#{${profile} == 'prod' ? 'release' : 'debug'}"`
+		Debug   bool          `value:"#{${profile} != 'prod'}"`
+		Timeout time.Duration `value:"#{${timeout:=3} + 's'}"`
+		Owner   string        `value:"#{@owner}"`
+	}
+
+	err := p.Bind(&s, conf.Beans(func(name string) (interface{}, bool, error) {
+		return "admin", name == "owner", nil
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, s.Size, 16)
+	assert.Equal(t, s.Ratio, 0.5)
+	assert.Equal(t, s.Mode, "release")
+	assert.False(t, s.Debug)
+	assert.Equal(t, s.Timeout, 3*time.Second)
+	assert.Equal(t, s.Owner, "admin")
+
+	var r struct {
+		Owner string `value:"#{@owner}"`
+	}
+	err = p.Bind(&r)
+	assert.Error(t, err, "bean reference @owner not supported")
+
+	var h struct {
+		Hosts []string `value:"#{'a'}"`
+	}
+	err = p.Bind(&h)
+	assert.Error(t, err, "can't bind a to \\[\\]string")
+}
+
+func TestProperties_Snapshot(t *testing.T) {
+
+	p := conf.Map(map[string]interface{}{"a": "1", "b": []string{"x", "y"}})
+	s := p.Snapshot()
+
+	p.Set("a", "2")
+	p.Merge(conf.Map(map[string]interface{}{"c": "3"}))
+	assert.Equal(t, p.Get("a"), "2")
+	assert.Equal(t, p.Get("c"), "3")
+
+	// 快照不受之后修改的影响
+	assert.Equal(t, s.Get("a"), "1")
+	assert.Nil(t, s.Get("c"))
+	assert.Equal(t, s.Get("b[1]"), "y")
+
+	// 修改快照也不影响原来的属性列表
+	s.Set("a", "3")
+	assert.Equal(t, s.Get("a"), "3")
+	assert.Equal(t, p.Get("a"), "2")
+}
+
+func TestProperties_Batch(t *testing.T) {
+
+	p := conf.Map(map[string]interface{}{"a": "1"})
+	s := p.Snapshot()
+
+	p.Batch(func(q *conf.Properties) {
+		q.Set("a", "2")
+		q.Set("b", []string{"x", "y"})
+		q.Merge(conf.Map(map[string]interface{}{"c": "3"}))
+		// 批量修改在 fn 返回之前只对 q 可见
+		assert.Equal(t, q.Get("a"), "2")
+		assert.Equal(t, p.Get("a"), "1")
+		assert.Nil(t, p.Get("b[0]"))
+	})
+
+	assert.Equal(t, p.Get("a"), "2")
+	assert.Equal(t, p.Get("b[1]"), "y")
+	assert.Equal(t, p.Get("c"), "3")
+	assert.Equal(t, s.Get("a"), "1")
+}
+
+// TestProperties_Concurrent 读写可以并发进行，需要使用 -race 运行。
+func TestProperties_Concurrent(t *testing.T) {
+
+	p := conf.Map(map[string]interface{}{"n": 0, "m": 0})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 100; i++ {
+			// n 和 m 在同一个快照中发布，读取的时候总是相等的
+			p.Merge(conf.Map(map[string]interface{}{"n": i, "m": i}))
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var s struct {
+					N int `value:"${n}"`
+					M int `value:"${m}"`
+				}
+				assert.Nil(t, p.Bind(&s))
+				assert.Equal(t, s.N, s.M)
+				assert.Equal(t, len(p.Keys()), 2)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, p.Get("n"), "100")
+}
+
+func BenchmarkProperties_Get(b *testing.B) {
+	m := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		m[fmt.Sprintf("key.%d", i)] = i
+	}
+	p := conf.Map(m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if p.Get("key.500") == nil {
+				b.Fatal("key.500 not found")
+			}
+		}
+	})
+}
+
+func BenchmarkProperties_Merge(b *testing.B) {
+	m := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		m[fmt.Sprintf("key.%d", i)] = i
+	}
+	q := conf.Map(m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conf.New().Merge(q)
+	}
+}
+
+// BenchmarkProperties_Load 逐个设置大量属性，比较每次 Set 都复制快照与在一次
+// Batch 中设置的开销。
+func BenchmarkProperties_Load(b *testing.B) {
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key.%d", i)
+	}
+	b.Run("set", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := conf.New()
+			for j, k := range keys {
+				p.Set(k, j)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			conf.New().Batch(func(p *conf.Properties) {
+				for j, k := range keys {
+					p.Set(k, j)
+				}
+			})
+		}
+	})
+}
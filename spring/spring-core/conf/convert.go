@@ -25,7 +25,8 @@ import (
 	"github.com/go-spring/spring-stl/util"
 )
 
-var converters = map[reflect.Type]interface{}{}
+// converters 类型转换器，保存转换函数的反射值避免每次转换时重新反射。
+var converters = map[reflect.Type]reflect.Value{}
 
 func init() {
 
@@ -51,5 +52,5 @@ func Convert(fn interface{}) {
 	if !validConverter(t) {
 		panic(errors.New("fn must be func(string)(type,error)"))
 	}
-	converters[t.Out(0)] = fn
+	converters[t.Out(0)] = reflect.ValueOf(fn)
 }
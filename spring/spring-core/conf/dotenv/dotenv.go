@@ -0,0 +1,151 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dotenv 解析 .env 格式的文件，每行一个 KEY=VALUE 形式的变量，支持
+// export 前缀、# 注释以及单引号和双引号包围的值。单引号中的内容原样保留，双引号
+// 中支持 \n、\t、\" 等转义字符，两者都可以跨越多行。
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Read 将 .env 格式的字节数组解析成 map 数据。
+func Read(b []byte) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	for line := 1; s != ""; {
+
+		var row string
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			row, s = s[:i], s[i+1:]
+		} else {
+			row, s = s, ""
+		}
+		start := line
+		line++
+
+		row = strings.TrimSpace(row)
+		if row == "" || row[0] == '#' {
+			continue
+		}
+		row = strings.TrimPrefix(row, "export ")
+
+		i := strings.IndexByte(row, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing '=' in %q", start, row)
+		}
+		key := strings.TrimSpace(row[:i])
+		if !validKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", start, key)
+		}
+
+		value := strings.TrimLeft(row[i+1:], " \t")
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			ret[key] = unquoted(value)
+			continue
+		}
+
+		// 引号中的值可能跨越多行，需要继续读取后面的行。
+		quote := value[0]
+		value = value[1:]
+		for {
+			v, rest, ok := quoted(value, quote)
+			if ok {
+				rest = strings.TrimSpace(rest)
+				if rest != "" && rest[0] != '#' {
+					return nil, fmt.Errorf("line %d: unexpected %q after quoted value", start, rest)
+				}
+				ret[key] = v
+				break
+			}
+			if s == "" {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+			}
+			if i := strings.IndexByte(s, '\n'); i >= 0 {
+				value, s = value+"\n"+s[:i], s[i+1:]
+			} else {
+				value, s = value+"\n"+s, ""
+			}
+			line++
+		}
+	}
+	return ret, nil
+}
+
+// validKey 变量名只能包含字母、数字、下划线、点和中划线，并且不能以数字开头。
+func validKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '.', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// unquoted 处理没有引号的值，空白之后的 # 开始的内容是注释。
+func unquoted(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// quoted 查找 quote 结束的值，返回值、结束引号后的内容以及是否找到了结束引号。
+func quoted(s string, quote byte) (string, string, bool) {
+	if quote == '\'' {
+		if i := strings.IndexByte(s, '\''); i >= 0 {
+			return s[:i], s[i+1:], true
+		}
+		return "", "", false
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' {
+			return sb.String(), s[i+1:], true
+		}
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$', '\'':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return "", "", false
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dotenv_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-core/conf/dotenv"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestRead(t *testing.T) {
+
+	data := `# 注释
+export APP_NAME=demo
+DB_URL = mysql://localhost # 行尾注释
+DB_PASS=pa#ss
+
+SINGLE='raw \n $HOME # not comment'
+DOUBLE="a\tb\n\"c\" \\ \$HOME"
+MULTI="line1
+line2" # 行尾注释
+EMPTY=
+`
+	m, err := dotenv.Read([]byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, m, map[string]interface{}{
+		"APP_NAME": "demo",
+		"DB_URL":   "mysql://localhost",
+		"DB_PASS":  "pa#ss",
+		"SINGLE":   `raw \n $HOME # not comment`,
+		"DOUBLE":   "a\tb\n\"c\" \\ $HOME",
+		"MULTI":    "line1\nline2",
+		"EMPTY":    "",
+	})
+
+	// Windows 换行符
+	m, err = dotenv.Read([]byte("A=1\r\nB='2'\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, m, map[string]interface{}{"A": "1", "B": "2"})
+}
+
+func TestRead_Error(t *testing.T) {
+
+	_, err := dotenv.Read([]byte("A=1\nB"))
+	assert.Error(t, err, "line 2: missing '=' in \"B\"")
+
+	_, err = dotenv.Read([]byte("1A=1"))
+	assert.Error(t, err, "line 1: invalid key \"1A\"")
+
+	_, err = dotenv.Read([]byte("A=\"1\n2\nB=3"))
+	assert.Error(t, err, "line 1: unterminated quoted value")
+
+	_, err = dotenv.Read([]byte("A='1' 2"))
+	assert.Error(t, err, "line 1: unexpected \"2\" after quoted value")
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kube 读取 Kubernetes 以卷的形式挂载的 ConfigMap 和 Secret 。挂载目录
+// 中每个文件对应一个 key，文件名是属性名，文件内容是属性值，名为 application.yaml
+// 、application.properties 等受支持格式的文件则作为配置文件解析。Kubernetes 更新挂载内容时
+// 先写入新的时间戳目录，然后原子地替换 ..data 符号链接，Watcher 通过检查该链接
+// 的指向发现变化，并将最新的属性交给回调函数，通常是动态属性的刷新函数。
+package kube
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/log"
+)
+
+// dataLink Kubernetes 原子替换的符号链接的名称。
+const dataLink = "..data"
+
+// Config 挂载目录的配置，通常绑定到 kube.config 前缀的属性上。
+type Config struct {
+	Paths    []string      `value:"${paths}"`         // ConfigMap 和 Secret 的挂载目录，后面的优先
+	Interval time.Duration `value:"${interval:=10s}"` // 检查挂载目录变化的间隔
+}
+
+// Watcher 读取挂载目录中的属性，并在挂载内容变化时调用回调函数。
+type Watcher struct {
+	config   *Config // 使用指针避免容器对其进行属性绑定
+	onChange func(p *conf.Properties) error
+	versions []string
+}
+
+// NewWatcher Watcher 的构造函数，onChange 在挂载内容变化时使用所有目录中的属性调用。
+func NewWatcher(config Config, onChange func(p *conf.Properties) error) *Watcher {
+	return &Watcher{config: &config, onChange: onChange}
+}
+
+// Load 读取所有挂载目录中的属性，不存在的目录被忽略。
+func (w *Watcher) Load() (*conf.Properties, error) {
+	for i := 0; ; i++ {
+		versions := w.fingerprint()
+		p, err := w.load()
+		if err != nil {
+			return nil, err
+		}
+		// 读取期间发生了替换时重新读取，保证属性来自同一个版本。
+		if equal(versions, w.fingerprint()) || i >= 3 {
+			w.versions = versions
+			return p, nil
+		}
+	}
+}
+
+func (w *Watcher) load() (*conf.Properties, error) {
+	p := conf.New()
+	for _, dir := range w.config.Paths {
+		if err := Read(p, dir); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Run 定期检查挂载目录，发现变化时重新读取属性并调用回调函数，直到 ctx 结束。
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if equal(w.versions, w.fingerprint()) {
+			continue
+		}
+		p, err := w.Load()
+		if err != nil {
+			log.Errorf("kube config reload error: %v", err)
+			continue
+		}
+		log.Infof("kube config changed, paths: %v", w.config.Paths)
+		if err = w.onChange(p); err != nil {
+			log.Errorf("kube config refresh error: %v", err)
+		}
+	}
+}
+
+// fingerprint 返回所有挂载目录的版本，优先使用 ..data 符号链接的指向，不是
+// Kubernetes 挂载的目录则使用文件的名称、大小和修改时间。
+func (w *Watcher) fingerprint() []string {
+	versions := make([]string, 0, len(w.config.Paths))
+	for _, dir := range w.config.Paths {
+		if target, err := os.Readlink(filepath.Join(dir, dataLink)); err == nil {
+			versions = append(versions, target)
+			continue
+		}
+		var sb strings.Builder
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil {
+				fmt.Fprintf(&sb, "%s:%d:%d;", e.Name(), info.Size(), info.ModTime().UnixNano())
+			}
+		}
+		versions = append(versions, sb.String())
+	}
+	return versions
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Read 读取挂载目录 dir 中的属性并保存到 p 中，忽略以 . 开头的文件和子目录，
+// dir 不存在时什么都不做。文件内容末尾的换行符会被去掉，例如 Secret 文件中的。
+func Read(p *conf.Properties, dir string) error {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	// 属性批量写入 p ，避免逐个写入时反复复制属性列表。
+	m := make(map[string]interface{})
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		info, err := os.Stat(file) // 文件通常是指向 ..data 的符号链接
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(name); name == "application"+ext && conf.Supported(ext) {
+			p.Merge(conf.Map(m))
+			m = make(map[string]interface{})
+			if err = p.Read(b, ext); err != nil {
+				return fmt.Errorf("read %s error: %w", file, err)
+			}
+			continue
+		}
+		m[name] = strings.TrimRight(string(b), "\r\n")
+	}
+	p.Merge(conf.Map(m))
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kube_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/conf/kube"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// mount 模拟 kubelet 更新挂载目录的过程：写入新的时间戳目录，然后原子地替换
+// ..data 符号链接，并确保每个 key 都有指向 ..data 的符号链接。
+func mount(t *testing.T, dir, version string, files map[string]string) {
+	ts := filepath.Join(dir, "..ts_"+version)
+	assert.Nil(t, os.MkdirAll(ts, 0755))
+	for name, data := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(ts, name), []byte(data), 0644))
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			assert.Nil(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	assert.Nil(t, os.Symlink(filepath.Base(ts), tmp))
+	assert.Nil(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestRead(t *testing.T) {
+
+	dir := t.TempDir()
+	mount(t, dir, "1", map[string]string{
+		"db.url":           "mysql://v1",
+		"application.yaml": "server:\n  port: 8080\n",
+		"app.env":          "prod",
+		"db.password":      "p1\n",
+	})
+
+	p := conf.New()
+	assert.Nil(t, kube.Read(p, dir))
+	assert.Equal(t, p.Get("db.url"), "mysql://v1")
+	assert.Equal(t, p.Get("server.port"), "8080")
+	assert.Equal(t, p.Get("app.env"), "prod")
+	assert.Equal(t, p.Get("db.password"), "p1")
+	assert.Equal(t, len(p.Keys()), 4)
+
+	// 不存在的目录被忽略
+	assert.Nil(t, kube.Read(p, filepath.Join(dir, "none")))
+}
+
+func TestWatcher(t *testing.T) {
+
+	configMap, secret := t.TempDir(), t.TempDir()
+	mount(t, configMap, "1", map[string]string{"db.url": "mysql://v1", "db.user": "app"})
+	mount(t, secret, "1", map[string]string{"db.password": "p1"})
+
+	ch := make(chan *conf.Properties, 1)
+	w := kube.NewWatcher(kube.Config{
+		Paths:    []string{configMap, secret},
+		Interval: 10 * time.Millisecond,
+	}, func(p *conf.Properties) error {
+		ch <- p
+		return nil
+	})
+
+	p, err := w.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, p.Get("db.url"), "mysql://v1")
+	assert.Equal(t, p.Get("db.password"), "p1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	mount(t, secret, "2", map[string]string{"db.password": "p2"})
+
+	select {
+	case p = <-ch:
+		assert.Equal(t, p.Get("db.url"), "mysql://v1")
+		assert.Equal(t, p.Get("db.password"), "p2")
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not detected")
+	}
+
+	// 没有变化时不会调用回调函数
+	select {
+	case <-ch:
+		t.Fatal("unexpected change")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -16,7 +16,12 @@
 
 package prop
 
-import "github.com/magiconair/properties"
+import (
+	"bytes"
+	"sort"
+
+	"github.com/magiconair/properties"
+)
 
 // Read 将 properties 格式的字节数组解析成 map 数据。
 func Read(b []byte) (map[string]interface{}, error) {
@@ -35,3 +40,27 @@ func Read(b []byte) (map[string]interface{}, error) {
 	}
 	return ret, nil
 }
+
+// Write 将 map 数据编码成 properties 格式的字节数组，属性按照 key 排序。
+func Write(m map[string]string) ([]byte, error) {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	for _, k := range keys {
+		if _, _, err := p.Set(k, m[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := p.Write(&buf, properties.UTF8); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
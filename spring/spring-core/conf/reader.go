@@ -17,6 +17,10 @@
 package conf
 
 import (
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-core/conf/dotenv"
 	"github.com/go-spring/spring-core/conf/prop"
 	"github.com/go-spring/spring-core/conf/toml"
 	"github.com/go-spring/spring-core/conf/yaml"
@@ -26,6 +30,7 @@ func init() {
 	NewReader(yaml.Read, ".yaml", ".yml")
 	NewReader(prop.Read, ".properties")
 	NewReader(toml.Read, ".toml")
+	NewReader(dotenv.Read, ".env")
 }
 
 var readers = make(map[string]Reader)
@@ -39,3 +44,45 @@ func NewReader(r Reader, ext ...string) {
 		readers[s] = r
 	}
 }
+
+// Supported 返回是否注册了扩展名为 ext 的属性列表解析器。
+func Supported(ext string) bool {
+	_, ok := readers[ext]
+	return ok
+}
+
+var (
+	resolversMutex sync.RWMutex
+	resolvers      = make(map[string]ValueResolver)
+)
+
+// ValueResolver 属性值解析器，在属性绑定时将 scheme://... 形式的属性值解析为真实
+// 的值，例如将 secret://db#password 解析为保存在密钥管理服务中的密码。
+type ValueResolver func(s string) (string, error)
+
+// NewResolver 注册属性值解析器，scheme 是解析器支持的属性值前缀，r 为 nil 时
+// 取消注册。
+func NewResolver(r ValueResolver, scheme string) {
+	resolversMutex.Lock()
+	defer resolversMutex.Unlock()
+	if r == nil {
+		delete(resolvers, scheme)
+		return
+	}
+	resolvers[scheme] = r
+}
+
+// resolveValue 使用注册的属性值解析器解析属性值，没有对应的解析器时原样返回。
+func resolveValue(s string) (string, error) {
+	i := strings.Index(s, "://")
+	if i <= 0 {
+		return s, nil
+	}
+	resolversMutex.RLock()
+	r, ok := resolvers[s[:i]]
+	resolversMutex.RUnlock()
+	if ok {
+		return r(s)
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-spring/spring-core/conf/prop"
+)
+
+// Writer 可写的属性源，例如本地的覆盖文件或者远程配置中心。
+type Writer interface {
+
+	// Write 使用 values 替换属性源中 key 及其子属性的值，values 是展开之后的
+	// 属性，为空时表示删除 key 及其子属性。
+	Write(key string, values map[string]string) error
+}
+
+// FileWriter 将运行时修改的属性保存到本地 properties 格式的覆盖文件中，应用启
+// 动时加载该文件并覆盖配置文件中的属性。
+type FileWriter struct {
+	file  string
+	mutex sync.Mutex
+}
+
+// NewFileWriter FileWriter 的构造函数，目前只支持 .properties 格式的文件。
+func NewFileWriter(file string) (*FileWriter, error) {
+	if ext := filepath.Ext(file); ext != ".properties" {
+		return nil, fmt.Errorf("unsupported writable file type %s", ext)
+	}
+	return &FileWriter{file: file}, nil
+}
+
+// File 返回覆盖文件的路径。
+func (w *FileWriter) File() string {
+	return w.file
+}
+
+// Write 修改覆盖文件中的属性，先写入临时文件再替换原文件，避免进程异常退出时
+// 覆盖文件不完整。
+func (w *FileWriter) Write(key string, values map[string]string) error {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	p := New()
+	if err := p.Load(w.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	p.update(func(m map[string]string) {
+		removeKey(m, key)
+		for k, v := range values {
+			m[k] = v
+		}
+	})
+
+	b, err := prop.Write(p.snapshot())
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(w.file); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := w.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.file)
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package connection 提供了 WebSocket 和 SSE 等长连接的注册表，负责连接的登记、
+// 元数据、广播、指标以及优雅关闭时的强制断开，应用只需要在处理函数中登记连接。
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/metrics"
+)
+
+// 连接的类型。
+const (
+	KindWebSocket = "websocket"
+	KindSSE       = "sse"
+)
+
+var (
+	ErrStopped  = errors.New("connection: registry stopped")
+	ErrTooMany  = errors.New("connection: too many connections")
+	ErrClosed   = errors.New("connection: connection closed")
+	ErrNotFound = errors.New("connection: connection not found")
+)
+
+// Conn 长连接的抽象，WebSocket 连接通常由应用使用的 WebSocket 库适配，SSE 连接
+// 可以使用 NewSSE 函数创建。
+type Conn interface {
+	Send(ctx context.Context, msg []byte) error // 发送一条消息
+	Close() error                               // 关闭连接
+}
+
+// Config 连接注册表配置，通常绑定到 web.connections 前缀的属性上。
+type Config struct {
+	MaxConnections int           `value:"${max-connections:=0}"` // 最大连接数，0 表示不限制
+	SendTimeout    time.Duration `value:"${send-timeout:=5s}"`   // 广播时发送一条消息的超时时间
+	Phase          int           `value:"${phase:=0}"`           // 容器关闭时断开连接的阶段
+}
+
+// Registry 长连接的注册表。容器关闭时首先拒绝新的连接，然后在所处的阶段断开所有
+// 的连接，使处理长连接的处理函数能够及时返回，避免阻塞 Web 服务器的关闭。
+type Registry struct {
+	Metrics *metrics.Registry `autowire:"?"`
+
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	seq     uint64
+	mutex   sync.RWMutex
+	entries map[string]*Entry
+	stopped bool
+
+	active *metrics.GaugeVec
+	sent   *metrics.CounterVec
+}
+
+// NewRegistry Registry 的构造函数。
+func NewRegistry(config Config) *Registry {
+	return &Registry{config: &config, entries: make(map[string]*Entry)}
+}
+
+// OnInit 注册连接的指标。
+func (r *Registry) OnInit() {
+	if r.Metrics != nil {
+		r.active = r.Metrics.Gauge("web_connections_active", "Number of active long-lived connections.", "kind")
+		r.sent = r.Metrics.Counter("web_connection_messages_total", "Total number of messages sent to long-lived connections.", "kind", "result")
+	}
+}
+
+// Register 登记 kind 类型的连接，meta 是连接的元数据，例如用户 ID 和订阅的主题。
+// 处理函数在连接结束时需要调用 Entry.Close 方法注销连接。
+func (r *Registry) Register(kind string, c Conn, meta map[string]string) (*Entry, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.stopped {
+		return nil, ErrStopped
+	}
+	if n := r.config.MaxConnections; n > 0 && len(r.entries) >= n {
+		return nil, ErrTooMany
+	}
+
+	e := &Entry{
+		ID:      strconv.FormatUint(atomic.AddUint64(&r.seq, 1), 10),
+		Kind:    kind,
+		Created: time.Now(),
+		conn:    c,
+		meta:    make(map[string]string, len(meta)),
+		done:    make(chan struct{}),
+		r:       r,
+	}
+	for k, v := range meta {
+		e.meta[k] = v
+	}
+	r.entries[e.ID] = e
+	if r.active != nil {
+		r.active.With(kind).Inc()
+	}
+	return e, nil
+}
+
+// remove 注销连接。
+func (r *Registry) remove(e *Entry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.entries[e.ID]; ok {
+		delete(r.entries, e.ID)
+		if r.active != nil {
+			r.active.With(e.Kind).Dec()
+		}
+	}
+}
+
+// Get 返回 id 对应的连接。
+func (r *Registry) Get(id string) (*Entry, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	e, ok := r.entries[id]
+	return e, ok
+}
+
+// Len 返回连接的数量。
+func (r *Registry) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.entries)
+}
+
+// Entries 返回满足 filter 的所有连接，filter 为 nil 时返回所有的连接。
+func (r *Registry) Entries(filter func(e *Entry) bool) []*Entry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var ret []*Entry
+	for _, e := range r.entries {
+		if filter == nil || filter(e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// Send 向 id 对应的连接发送一条消息。
+func (r *Registry) Send(ctx context.Context, id string, msg []byte) error {
+	e, ok := r.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return e.Send(ctx, msg)
+}
+
+// Broadcast 向满足 filter 的所有连接发送一条消息，返回发送成功的连接数。发送失败
+// 的连接被认为已经断开，会被关闭并注销，返回的 error 包含第一个发送失败的原因。
+func (r *Registry) Broadcast(ctx context.Context, msg []byte, filter func(e *Entry) bool) (int, error) {
+	var (
+		sent int
+		fail int
+		err  error
+	)
+	for _, e := range r.Entries(filter) {
+		if sendErr := e.Send(ctx, msg); sendErr != nil {
+			if err == nil {
+				err = sendErr
+			}
+			fail++
+			_ = e.Close()
+			continue
+		}
+		sent++
+	}
+	if err != nil {
+		return sent, fmt.Errorf("connection: %d sends failed: %w", fail, err)
+	}
+	return sent, nil
+}
+
+// Phase 返回容器关闭时断开连接的阶段。
+func (r *Registry) Phase() int {
+	return r.config.Phase
+}
+
+// Stop 拒绝新的连接。
+func (r *Registry) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stopped = true
+}
+
+// Drain 强制断开所有的连接。
+func (r *Registry) Drain(ctx context.Context) error {
+	r.Stop()
+	for _, e := range r.Entries(nil) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_ = e.Close()
+	}
+	return nil
+}
+
+// OnDestroy 没有被容器排空时断开所有的连接。
+func (r *Registry) OnDestroy() {
+	_ = r.Drain(context.Background())
+}
+
+// Health 返回连接的数量，停止接收新的连接之后返回 OUT_OF_SERVICE 。
+func (r *Registry) Health(ctx context.Context) health.Health {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	kinds := make(map[string]int)
+	for _, e := range r.entries {
+		kinds[e.Kind]++
+	}
+	h := health.Up()
+	if r.stopped {
+		h.Status = health.StatusOutOfService
+	}
+	h.Details = map[string]interface{}{"connections": len(r.entries), "kinds": kinds}
+	if r.config.MaxConnections > 0 {
+		h.Details["max"] = r.config.MaxConnections
+	}
+	return h
+}
+
+// Entry 登记在注册表中的连接。
+type Entry struct {
+	ID      string    // 连接的 ID
+	Kind    string    // 连接的类型
+	Created time.Time // 登记的时间
+
+	conn  Conn
+	mutex sync.RWMutex
+	meta  map[string]string
+	done  chan struct{}
+	once  sync.Once
+	r     *Registry
+}
+
+// Get 返回连接的元数据。
+func (e *Entry) Get(key string) string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.meta[key]
+}
+
+// Set 设置连接的元数据。
+func (e *Entry) Set(key string, value string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.meta[key] = value
+}
+
+// Meta 返回连接的所有元数据的副本。
+func (e *Entry) Meta() map[string]string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	m := make(map[string]string, len(e.meta))
+	for k, v := range e.meta {
+		m[k] = v
+	}
+	return m
+}
+
+// Send 向连接发送一条消息，超时时间为 SendTimeout 。
+func (e *Entry) Send(ctx context.Context, msg []byte) error {
+	select {
+	case <-e.done:
+		return ErrClosed
+	default:
+	}
+	if d := e.r.config.SendTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	err := e.conn.Send(ctx, msg)
+	if e.r.sent != nil {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		e.r.sent.With(e.Kind, result).Inc()
+	}
+	return err
+}
+
+// Done 返回连接被关闭时关闭的通道。
+func (e *Entry) Done() <-chan struct{} {
+	return e.done
+}
+
+// Wait 等待连接被关闭或者 ctx 结束，通常用于 SSE 处理函数等待连接结束。
+func (e *Entry) Wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-e.done:
+	}
+}
+
+// Close 关闭连接并从注册表中注销，可以重复调用。
+func (e *Entry) Close() error {
+	var err error
+	e.once.Do(func() {
+		e.r.remove(e)
+		err = e.conn.Close()
+		close(e.done)
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/connection"
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type memoryConn struct {
+	mutex  sync.Mutex
+	msgs   []string
+	fail   bool
+	closed bool
+}
+
+func (c *memoryConn) Send(ctx context.Context, msg []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fail {
+		return errors.New("broken pipe")
+	}
+	c.msgs = append(c.msgs, string(msg))
+	return nil
+}
+
+func (c *memoryConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+
+	r := connection.NewRegistry(connection.Config{MaxConnections: 3, SendTimeout: time.Second})
+	r.Metrics = metrics.NewRegistry()
+	r.OnInit()
+	ctx := context.Background()
+
+	c1, c2, c3 := &memoryConn{}, &memoryConn{}, &memoryConn{fail: true}
+	e1, err := r.Register(connection.KindWebSocket, c1, map[string]string{"topic": "order"})
+	assert.Nil(t, err)
+	e2, err := r.Register(connection.KindSSE, c2, map[string]string{"topic": "user"})
+	assert.Nil(t, err)
+	_, err = r.Register(connection.KindWebSocket, c3, map[string]string{"topic": "order"})
+	assert.Nil(t, err)
+	_, err = r.Register(connection.KindSSE, &memoryConn{}, nil)
+	assert.Equal(t, err, connection.ErrTooMany)
+
+	e2.Set("user", "jim")
+	assert.Equal(t, e2.Meta(), map[string]string{"topic": "user", "user": "jim"})
+
+	n, err := r.Broadcast(ctx, []byte("hello"), func(e *connection.Entry) bool {
+		return e.Get("topic") == "order"
+	})
+	assert.Error(t, err, "1 sends failed: broken pipe")
+	assert.Equal(t, n, 1)
+	assert.Equal(t, c1.msgs, []string{"hello"})
+	assert.True(t, c3.closed)
+	assert.Equal(t, r.Len(), 2)
+
+	assert.Nil(t, r.Send(ctx, e2.ID, []byte("hi")))
+	assert.Equal(t, r.Send(ctx, "none", nil), connection.ErrNotFound)
+
+	h := r.Health(ctx)
+	assert.Equal(t, h.Status, health.StatusUp)
+	assert.Equal(t, h.Details["connections"], 2)
+
+	r.Stop()
+	_, err = r.Register(connection.KindSSE, &memoryConn{}, nil)
+	assert.Equal(t, err, connection.ErrStopped)
+	assert.Equal(t, r.Health(ctx).Status, health.StatusOutOfService)
+
+	go func() { _ = r.Drain(ctx) }()
+	e1.Wait(ctx)
+	<-e2.Done()
+	assert.True(t, c1.closed)
+	assert.Equal(t, e1.Send(ctx, nil), connection.ErrClosed)
+	assert.Equal(t, r.Len(), 0)
+
+	for _, fam := range r.Metrics.Gather() {
+		if fam.Name == "web_connections_active" {
+			for _, s := range fam.Samples {
+				assert.Equal(t, s.Value, float64(0))
+			}
+		}
+	}
+}
+
+func TestSSE(t *testing.T) {
+
+	r := connection.NewRegistry(connection.Config{})
+	w := httptest.NewRecorder()
+	s, err := connection.NewSSE(w)
+	assert.Nil(t, err)
+	assert.Equal(t, w.Header().Get("Content-Type"), "text/event-stream")
+
+	e, err := r.Register(connection.KindSSE, s, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, e.Send(context.Background(), []byte("a\nb")))
+	assert.Nil(t, s.SendEvent("ping", []byte("1")))
+	assert.Equal(t, w.Body.String(), "data: a\ndata: b\n\nevent: ping\ndata: 1\n\n")
+
+	assert.Nil(t, e.Close())
+	assert.Equal(t, s.SendEvent("", nil), connection.ErrClosed)
+
+	_, err = connection.NewSSE(struct{ http.ResponseWriter }{w})
+	assert.Error(t, err, "streaming unsupported")
+}
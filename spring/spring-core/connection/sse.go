@@ -0,0 +1,87 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// SSE 基于 http.ResponseWriter 的 Server-Sent Events 连接。
+type SSE struct {
+	w      http.ResponseWriter
+	f      http.Flusher
+	mutex  sync.Mutex
+	closed bool
+}
+
+// NewSSE 写入 SSE 的响应头并返回 SSE 连接，w 必须实现 http.Flusher 接口。
+func NewSSE(w http.ResponseWriter) (*SSE, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("connection: streaming unsupported")
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &SSE{w: w, f: f}, nil
+}
+
+// Send 发送一条没有事件名称的消息。
+func (s *SSE) Send(ctx context.Context, msg []byte) error {
+	return s.SendEvent("", msg)
+}
+
+// SendEvent 发送一条 event 事件，多行数据被拆分为多个 data 字段。
+func (s *SSE) SendEvent(event string, data []byte) error {
+
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: " + event + "\n")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
+
+// Close 关闭连接，之后发送消息返回 ErrClosed ，处理函数返回后响应结束。
+func (s *SSE) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package correlation 为每个请求提供关联 ID 和 W3C baggage ，它们从请求头中
+// 提取或者自动生成，保存在请求的 knife 缓存中，并且随响应头、日志以及发出的
+// 客户端请求一起传递，从而能够把一次调用经过的所有服务串联起来。
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-spring/spring-stl/knife"
+)
+
+const (
+	idKey      = "::correlation-id::"
+	baggageKey = "::baggage::"
+)
+
+type ctxKey int
+
+const (
+	ctxIDKey = ctxKey(iota)
+	ctxBaggageKey
+)
+
+// ID 返回 ctx 中保存的关联 ID ，没有时返回空字符串。
+func ID(ctx context.Context) string {
+	if id, ok := knife.Get(ctx, idKey).(string); ok {
+		return id
+	}
+	id, _ := ctx.Value(ctxIDKey).(string)
+	return id
+}
+
+// WithID 返回保存了关联 ID 的 ctx ，用于在请求之外的场景，例如消费消息时，
+// 延续上游的关联 ID 。
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxIDKey, id)
+}
+
+// BaggageOf 返回 ctx 中保存的 baggage ，没有时返回 nil 。
+func BaggageOf(ctx context.Context) Baggage {
+	if b, ok := knife.Get(ctx, baggageKey).(Baggage); ok {
+		return b
+	}
+	b, _ := ctx.Value(ctxBaggageKey).(Baggage)
+	return b
+}
+
+// WithBaggage 返回保存了 baggage 的 ctx 。
+func WithBaggage(ctx context.Context, b Baggage) context.Context {
+	return context.WithValue(ctx, ctxBaggageKey, b)
+}
+
+// Baggage W3C baggage 的键值对，参见 https://www.w3.org/TR/baggage/ ，成员的
+// 属性部分在解析时被丢弃。
+type Baggage map[string]string
+
+// ParseBaggage 解析 baggage 请求头，忽略格式错误的成员。
+func ParseBaggage(s string) Baggage {
+	b := make(Baggage)
+	for _, member := range strings.Split(s, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		i := strings.IndexByte(member, '=')
+		if i <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(member[:i])
+		val, err := url.PathUnescape(strings.TrimSpace(member[i+1:]))
+		if key == "" || err != nil {
+			continue
+		}
+		b[key] = val
+	}
+	return b
+}
+
+// String 返回 baggage 请求头的格式，成员按照键排序。
+func (b Baggage) String() string {
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(url.PathEscape(b[k]))
+	}
+	return sb.String()
+}
+
+// Generator 关联 ID 的生成器，导出为该接口的 bean 会替换属性指定的生成策略。
+type Generator interface {
+	Generate() string
+}
+
+// GeneratorFunc 函数形式的 Generator 。
+type GeneratorFunc func() string
+
+func (f GeneratorFunc) Generate() string {
+	return f()
+}
+
+// UUID 生成随机的 UUID (版本 4) 形式的关联 ID 。
+var UUID = GeneratorFunc(func() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+})
+
+// Hex 生成 32 位十六进制的关联 ID ，与 W3C trace-id 的格式相同。
+var Hex = GeneratorFunc(func() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+})
+
+// generatorOf 返回名为 name 的内置生成器。
+func generatorOf(name string) (Generator, error) {
+	switch name {
+	case "uuid":
+		return UUID, nil
+	case "hex":
+		return Hex, nil
+	}
+	return nil, fmt.Errorf("unknown correlation id generator %q", name)
+}
+
+// valid 判断请求头中的关联 ID 是否可以直接使用，拒绝过长或者包含不可见字符
+// 的值以避免日志注入。
+func valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// traceID 返回 traceparent 请求头中的 trace-id ，格式错误时返回空字符串。
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	if parts[1] == "00000000000000000000000000000000" {
+		return ""
+	}
+	return parts[1]
+}
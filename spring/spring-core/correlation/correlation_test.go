@@ -0,0 +1,137 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package correlation_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-spring/spring-core/correlation"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+func newFilter(t *testing.T, config correlation.Config) *correlation.Filter {
+	f, err := correlation.NewFilter(config)
+	assert.Nil(t, err)
+	return f
+}
+
+func defaultConfig() correlation.Config {
+	return correlation.Config{
+		Header:         "X-Correlation-ID",
+		Generator:      "uuid",
+		ResponseHeader: true,
+		UseTraceparent: true,
+		BaggageHeader:  "baggage",
+		MaxBaggageSize: 8192,
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	b := correlation.ParseBaggage("userId=alice, isProduction=false;prop=1, bad, name=a%20b")
+	assert.Equal(t, b, correlation.Baggage{
+		"userId":       "alice",
+		"isProduction": "false",
+		"name":         "a b",
+	})
+	assert.Equal(t, b.String(), "isProduction=false,name=a%20b,userId=alice")
+}
+
+func TestGenerator(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	assert.True(t, uuid.MatchString(correlation.UUID.Generate()))
+	assert.True(t, regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(correlation.Hex.Generate()))
+
+	config := defaultConfig()
+	config.Generator = "snowflake"
+	_, err := correlation.NewFilter(config)
+	assert.Error(t, err, "unknown correlation id generator \"snowflake\"")
+}
+
+func TestFilter_Handler(t *testing.T) {
+
+	f := newFilter(t, defaultConfig())
+	f.Generator = correlation.GeneratorFunc(func() string { return "generated" })
+	f.OnInit()
+
+	var (
+		id      string
+		baggage correlation.Baggage
+	)
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = correlation.ID(r.Context())
+		baggage = correlation.BaggageOf(r.Context())
+	}))
+
+	serve := func(header http.Header, withKnife bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header = header
+		if withKnife {
+			r = r.WithContext(knife.New(r.Context()))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.Header{"X-Correlation-Id": {"abc"}, "Baggage": {"k=v"}}, true)
+	assert.Equal(t, id, "abc")
+	assert.Equal(t, baggage, correlation.Baggage{"k": "v"})
+	assert.Equal(t, w.Header().Get("X-Correlation-ID"), "abc")
+
+	w = serve(http.Header{}, false)
+	assert.Equal(t, id, "generated")
+	assert.True(t, baggage == nil)
+	assert.Equal(t, w.Header().Get("X-Correlation-ID"), "generated")
+
+	// 非法的关联 ID 被替换
+	serve(http.Header{"X-Correlation-Id": {"a\nb"}}, true)
+	assert.Equal(t, id, "generated")
+
+	serve(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, true)
+	assert.Equal(t, id, "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestFilter_Transport(t *testing.T) {
+
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer ts.Close()
+
+	f := newFilter(t, defaultConfig())
+	client := &http.Client{Transport: f.Transport(nil)}
+
+	ctx := correlation.WithID(context.Background(), "abc")
+	ctx = correlation.WithBaggage(ctx, correlation.Baggage{"tenant": "t1"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, header.Get("X-Correlation-ID"), "abc")
+	assert.Equal(t, header.Get("baggage"), "tenant=t1")
+	assert.Equal(t, req.Header.Get("X-Correlation-ID"), "")
+
+	assert.Equal(t, correlation.LogPrefix(ctx), "[abc] ")
+	assert.Equal(t, correlation.LogPrefix(context.Background()), "")
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package correlation
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+// Config 关联 ID 配置，通常绑定到 correlation 前缀的属性上。
+type Config struct {
+	Header         string `value:"${header:=X-Correlation-ID}"` // 关联 ID 的请求头和响应头
+	Generator      string `value:"${generator:=uuid}"`          // 生成策略，uuid 或者 hex
+	ResponseHeader bool   `value:"${response-header:=true}"`    // 是否在响应头中返回关联 ID
+	UseTraceparent bool   `value:"${use-traceparent:=true}"`    // 没有关联 ID 时是否使用 traceparent 中的 trace-id
+	BaggageHeader  string `value:"${baggage-header:=baggage}"`  // baggage 的请求头，为空时不处理 baggage
+	MaxBaggageSize int    `value:"${max-baggage-size:=8192}"`   // baggage 请求头的最大长度，超过时被忽略
+	Log            bool   `value:"${log:=true}"`                // 是否在日志前面输出关联 ID
+}
+
+// Filter 提取或者生成关联 ID 和 baggage 的过滤器，同时提供将它们传递给下游
+// 服务的 http.RoundTripper 。
+type Filter struct {
+	Generator Generator `autowire:"?"`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+	gen    Generator
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) (*Filter, error) {
+	gen, err := generatorOf(config.Generator)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{config: &config, gen: gen}, nil
+}
+
+// OnInit 使用自定义的生成器，并且设置日志前缀。
+func (f *Filter) OnInit() {
+	if f.Generator != nil {
+		f.gen = f.Generator
+	}
+	if f.config.Log {
+		log.SetCtxPrefix(LogPrefix)
+	}
+}
+
+// LogPrefix 返回 ctx 中的关联 ID 作为日志前缀。
+func LogPrefix(ctx context.Context) string {
+	if id := ID(ctx); id != "" {
+		return "[" + id + "] "
+	}
+	return ""
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	req, id := f.bind(ctx.Request())
+	if req != ctx.Request() {
+		ctx.SetRequest(req)
+	}
+	if f.config.ResponseHeader {
+		ctx.Header(f.config.Header, id)
+	}
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, id := f.bind(r)
+		if f.config.ResponseHeader {
+			w.Header().Set(f.config.Header, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bind 从请求头中提取关联 ID 和 baggage 并保存到请求的 knife 缓存中，请求没有
+// knife 缓存时返回携带了它们的新请求。
+func (f *Filter) bind(r *http.Request) (*http.Request, string) {
+
+	id := r.Header.Get(f.config.Header)
+	if !valid(id) {
+		id = ""
+		if f.config.UseTraceparent {
+			id = traceID(r.Header.Get("traceparent"))
+		}
+		if id == "" {
+			id = f.gen.Generate()
+		}
+	}
+
+	var b Baggage
+	if f.config.BaggageHeader != "" {
+		if s := r.Header.Get(f.config.BaggageHeader); s != "" && len(s) <= f.config.MaxBaggageSize {
+			b = ParseBaggage(s)
+		}
+	}
+
+	ctx := r.Context()
+	knife.Set(ctx, idKey, id)
+	if b != nil {
+		knife.Set(ctx, baggageKey, b)
+	}
+
+	if ID(ctx) != id { // 没有 knife 缓存
+		ctx = WithID(ctx, id)
+		if b != nil {
+			ctx = WithBaggage(ctx, b)
+		}
+		r = r.WithContext(ctx)
+	}
+	return r, id
+}
+
+// Inject 将 ctx 中的关联 ID 和 baggage 写入发出的请求的请求头。
+func (f *Filter) Inject(ctx context.Context, h http.Header) {
+	if id := ID(ctx); id != "" {
+		h.Set(f.config.Header, id)
+	}
+	if f.config.BaggageHeader != "" {
+		if b := BaggageOf(ctx); len(b) > 0 {
+			h.Set(f.config.BaggageHeader, b.String())
+		}
+	}
+}
+
+// Transport 返回将关联 ID 和 baggage 传递给下游服务的 http.RoundTripper 。
+func (f *Filter) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		if ID(r.Context()) != "" || len(BaggageOf(r.Context())) > 0 {
+			r = r.Clone(r.Context()) // RoundTripper 不能修改原始请求
+			f.Inject(r.Context(), r.Header)
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package db 提供了读写分离和多数据源路由：写操作发送到主库，读操作轮流发送到
+// 从库，只读事务可以使用从库，通过 Use 函数可以在 ctx 中选择命名的数据源。
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/sqllog"
+)
+
+// Primary 主库的名称，Use(ctx, Primary) 使读写操作都发送到主库。
+const Primary = "primary"
+
+// DataSourceConfig 数据源配置。
+type DataSourceConfig struct {
+	Driver string `value:"${driver}"` // 驱动名称，例如 mysql、postgres
+	URL    string `value:"${url}"`    // 数据源地址
+}
+
+// Config 路由数据源配置，通常绑定到 db.routing 前缀的属性上。
+type Config struct {
+	Primary  DataSourceConfig            `value:"${primary}"`  // 主库
+	Replicas []DataSourceConfig          `value:"${replicas}"` // 从库
+	Named    map[string]DataSourceConfig `value:"${named}"`    // 命名的数据源
+}
+
+type nameKey struct{}
+
+// Use 返回选择了命名数据源 name 的 ctx ，之后的读写操作都发送到该数据源。
+func Use(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, nameKey{}, name)
+}
+
+// UsePrimary 返回读操作也发送到主库的 ctx ，用于需要读取刚刚写入的数据的场景。
+func UsePrimary(ctx context.Context) context.Context {
+	return Use(ctx, Primary)
+}
+
+type txKey struct{}
+
+// WithTx 返回绑定了事务 tx 的 ctx ，Router 以及 repo.Repository 在 ctx 绑定了事务
+// 时使用该事务执行语句。
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom 返回 ctx 绑定的事务。
+func TxFrom(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// Router 路由数据源。
+type Router struct {
+	primary  *sql.DB
+	replicas []*sql.DB
+	named    map[string]*sql.DB
+	next     uint32
+}
+
+// New 使用已经打开的数据库连接创建路由数据源。
+func New(primary *sql.DB, replicas []*sql.DB, named map[string]*sql.DB) *Router {
+	return &Router{primary: primary, replicas: replicas, named: named}
+}
+
+// NewRouter 根据配置打开所有的数据源并创建路由数据源，i 不为空时拦截所有数据源
+// 的 SQL 语句。
+func NewRouter(config Config, i *sqllog.Interceptor) (_ *Router, err error) {
+
+	var opened []*sql.DB
+	open := func(c DataSourceConfig) (*sql.DB, error) {
+		db, err := sqllog.Open(c.Driver, c.URL, i)
+		if err != nil {
+			return nil, err
+		}
+		opened = append(opened, db)
+		return db, nil
+	}
+	defer func() {
+		if err != nil {
+			for _, db := range opened {
+				_ = db.Close()
+			}
+		}
+	}()
+
+	r := &Router{named: make(map[string]*sql.DB)}
+	if r.primary, err = open(config.Primary); err != nil {
+		return nil, fmt.Errorf("open primary datasource: %w", err)
+	}
+	for n, c := range config.Replicas {
+		db, err := open(c)
+		if err != nil {
+			return nil, fmt.Errorf("open replica datasource %d: %w", n, err)
+		}
+		r.replicas = append(r.replicas, db)
+	}
+	for name, c := range config.Named {
+		db, err := open(c)
+		if err != nil {
+			return nil, fmt.Errorf("open datasource %s: %w", name, err)
+		}
+		r.named[name] = db
+	}
+	return r, nil
+}
+
+// Primary 返回主库。
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// Named 返回名为 name 的数据源。
+func (r *Router) Named(name string) (*sql.DB, error) {
+	if name == Primary {
+		return r.primary, nil
+	}
+	if db, ok := r.named[name]; ok {
+		return db, nil
+	}
+	return nil, fmt.Errorf("unknown datasource %q", name)
+}
+
+// Writer 返回执行写操作的数据源，ctx 选择了命名数据源时返回该数据源，否则返回主库。
+func (r *Router) Writer(ctx context.Context) (*sql.DB, error) {
+	if name, ok := ctx.Value(nameKey{}).(string); ok {
+		return r.Named(name)
+	}
+	return r.primary, nil
+}
+
+// Reader 返回执行读操作的数据源，ctx 选择了命名数据源时返回该数据源，否则轮流
+// 返回从库，没有从库时返回主库。
+func (r *Router) Reader(ctx context.Context) (*sql.DB, error) {
+	if name, ok := ctx.Value(nameKey{}).(string); ok {
+		return r.Named(name)
+	}
+	if len(r.replicas) == 0 {
+		return r.primary, nil
+	}
+	n := atomic.AddUint32(&r.next, 1)
+	return r.replicas[int(n-1)%len(r.replicas)], nil
+}
+
+// ExecContext 在写数据源上执行语句，ctx 绑定了事务时在事务中执行。
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	db, err := r.Writer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext 在读数据源上执行查询，ctx 绑定了事务时在事务中执行。
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	db, err := r.Reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// BeginTx 开始事务，只读事务在读数据源上执行，其他事务在写数据源上执行。
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	get := r.Writer
+	if opts != nil && opts.ReadOnly {
+		get = r.Reader
+	}
+	db, err := get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.BeginTx(ctx, opts)
+}
+
+// RunInTx 在事务中执行 fn ，fn 的 ctx 绑定了该事务，fn 返回错误或者 panic 时
+// 回滚事务，否则提交事务。ctx 已经绑定了事务时直接在该事务中执行 fn 。
+func (r *Router) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+
+	if _, ok := TxFrom(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := r.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(WithTx(ctx, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// OnDestroy 关闭所有的数据源。
+func (r *Router) OnDestroy() {
+	dbs := append([]*sql.DB{r.primary}, r.replicas...)
+	for _, db := range r.named {
+		dbs = append(dbs, db)
+	}
+	for _, db := range dbs {
+		if err := db.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+}
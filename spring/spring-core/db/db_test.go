@@ -0,0 +1,160 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/db"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// recorder 记录每条语句由哪个数据源执行。
+var recorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func record(dsn string) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.calls = append(recorder.calls, dsn)
+}
+
+func calls() []string {
+	recorder.Lock()
+	defer recorder.Unlock()
+	ret := recorder.calls
+	recorder.calls = nil
+	return ret
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{dsn: name}, nil }
+
+type fakeConn struct{ dsn string }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+func (c fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	record(c.dsn)
+	return fakeTx{}, nil
+}
+
+type fakeStmt struct{ dsn string }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	record(s.dsn)
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	record(s.dsn)
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("db-fake", fakeDriver{})
+}
+
+func TestRouter(t *testing.T) {
+
+	p := conf.New()
+	p.Set("db.routing.primary.driver", "db-fake")
+	p.Set("db.routing.primary.url", "primary")
+	p.Set("db.routing.replicas[0].driver", "db-fake")
+	p.Set("db.routing.replicas[0].url", "replica-0")
+	p.Set("db.routing.replicas[1].driver", "db-fake")
+	p.Set("db.routing.replicas[1].url", "replica-1")
+	p.Set("db.routing.named.analytics.driver", "db-fake")
+	p.Set("db.routing.named.analytics.url", "analytics")
+
+	var config db.Config
+	err := p.Bind(&config, conf.Tag("${db.routing}"))
+	assert.Nil(t, err)
+
+	r, err := db.NewRouter(config, nil)
+	assert.Nil(t, err)
+	defer r.OnDestroy()
+
+	ctx := context.Background()
+	query := func(ctx context.Context) {
+		rows, err := r.QueryContext(ctx, "SELECT 1")
+		assert.Nil(t, err)
+		assert.Nil(t, rows.Close())
+	}
+
+	_, err = r.ExecContext(ctx, "UPDATE t SET a = 1")
+	assert.Nil(t, err)
+	query(ctx)
+	query(ctx)
+	query(ctx)
+	assert.Equal(t, calls(), []string{"primary", "replica-0", "replica-1", "replica-0"})
+
+	// 只读事务使用从库，其他事务使用主库
+	tx, err := r.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+	tx, err = r.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+	assert.Equal(t, calls(), []string{"replica-1", "primary"})
+
+	query(db.UsePrimary(ctx))
+	query(db.Use(ctx, "analytics"))
+	_, err = r.ExecContext(db.Use(ctx, "analytics"), "INSERT INTO t VALUES (1)")
+	assert.Nil(t, err)
+	assert.Equal(t, calls(), []string{"primary", "analytics", "analytics"})
+
+	_, err = r.QueryContext(db.Use(ctx, "report"), "SELECT 1")
+	assert.Error(t, err, "unknown datasource \"report\"")
+}
+
+func TestRouter_NoReplicas(t *testing.T) {
+
+	primary, err := sql.Open("db-fake", "primary")
+	assert.Nil(t, err)
+	r := db.New(primary, nil, nil)
+	defer r.OnDestroy()
+
+	rows, err := r.QueryContext(context.Background(), "SELECT 1")
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+	assert.Equal(t, calls(), []string{"primary"})
+}
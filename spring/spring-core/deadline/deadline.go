@@ -0,0 +1,193 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package deadline 在调用链上传递请求的截止时间，使下游的 HTTP、gRPC 和数据库
+// 调用只使用请求剩余的时间预算，避免超出调用方预算的调用和重试。服务端通过
+// Filter 从请求头中读取调用方的预算并设置到请求的 ctx 上，客户端通过 Transport
+// 将剩余的预算限制到单次调用上并通过请求头传递给下游服务。database/sql 和 gRPC
+// 本身就遵循 ctx 的截止时间，使用请求的 ctx 发起调用即可。
+package deadline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/web"
+)
+
+// ErrExhausted 请求的时间预算已经用完。
+var ErrExhausted = errors.New("deadline: request budget exhausted")
+
+// Header 传递请求时间预算的默认请求头，值为毫秒数，例如 1500 或者 1500ms 。
+const Header = "X-Request-Timeout"
+
+// Remaining 返回 ctx 剩余的时间预算，ctx 没有截止时间时 ok 为 false 。
+func Remaining(ctx context.Context) (d time.Duration, ok bool) {
+	t, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(t), true
+}
+
+// Allow 判断 ctx 剩余的时间预算是否大于 d ，ctx 没有截止时间时返回 true 。
+func Allow(ctx context.Context, d time.Duration) bool {
+	r, ok := Remaining(ctx)
+	return !ok || r > d
+}
+
+// Check 时间预算已经用完时返回 ErrExhausted 。
+func Check(ctx context.Context) error {
+	if !Allow(ctx, 0) {
+		return ErrExhausted
+	}
+	return nil
+}
+
+// WithTimeout 返回截止时间为 timeout 和剩余预算二者中较早者的 ctx ，timeout 为 0
+// 时只使用剩余的预算。
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Format 将时间预算格式化为请求头的值。
+func Format(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
+// Parse 解析请求头中的时间预算，支持毫秒数和带单位的时长。
+func Parse(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, ms > 0
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil && d > 0
+}
+
+// Transport 返回限制了单次请求超时时间的 http.RoundTripper ，超时时间为 timeout
+// 和请求 ctx 剩余预算二者中较小者，剩余预算通过 Header 请求头传递给下游服务。
+// 预算已经用完的请求直接返回 ErrExhausted 而不会发出。
+func Transport(next http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, timeout: timeout}
+}
+
+type transport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if err := Check(req.Context()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := WithTimeout(req.Context(), t.timeout)
+	r := req.Clone(ctx)
+	if d, ok := Remaining(ctx); ok {
+		r.Header.Set(Header, Format(d))
+	}
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &body{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// body 在响应体关闭时释放请求的 ctx 。
+type body struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Config 截止时间过滤器配置，通常绑定到 web.deadline 前缀的属性上。
+type Config struct {
+	Header  string        `value:"${header:=X-Request-Timeout}"` // 调用方传递时间预算的请求头
+	Default time.Duration `value:"${default:=0}"`                // 请求没有携带预算时使用的预算，0 表示不限制
+	Max     time.Duration `value:"${max:=0}"`                    // 预算的上限，0 表示不限制
+}
+
+// Filter 从请求头中读取调用方的时间预算并设置为请求 ctx 的截止时间。
+type Filter struct {
+	config *Config // 使用指针避免容器对其进行属性绑定
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	return &Filter{config: &config}
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	req, cancel := f.bind(ctx.Request())
+	defer cancel()
+	if req != ctx.Request() {
+		ctx.SetRequest(req)
+	}
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, cancel := f.bind(r)
+		defer cancel()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bind 返回 ctx 带有截止时间的新请求，没有时间预算时返回原请求。
+func (f *Filter) bind(r *http.Request) (*http.Request, context.CancelFunc) {
+	d, ok := Parse(r.Header.Get(f.config.Header))
+	if !ok {
+		d = f.config.Default
+	}
+	if f.config.Max > 0 && (d <= 0 || d > f.config.Max) {
+		d = f.config.Max
+	}
+	if d <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	return r.WithContext(ctx), cancel
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/deadline"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestParse(t *testing.T) {
+	testcases := []struct {
+		s  string
+		d  time.Duration
+		ok bool
+	}{
+		{"", 0, false},
+		{"1500", 1500 * time.Millisecond, true},
+		{"2s", 2 * time.Second, true},
+		{"0", 0, false},
+		{"-5ms", 0, false},
+		{"abc", 0, false},
+	}
+	for _, c := range testcases {
+		d, ok := deadline.Parse(c.s)
+		assert.Equal(t, ok, c.ok)
+		if ok {
+			assert.Equal(t, d, c.d)
+		}
+	}
+	assert.Equal(t, deadline.Format(1500*time.Millisecond), "1500")
+	assert.Equal(t, deadline.Format(time.Microsecond), "1")
+}
+
+func TestBudget(t *testing.T) {
+
+	_, ok := deadline.Remaining(context.Background())
+	assert.False(t, ok)
+	assert.True(t, deadline.Allow(context.Background(), time.Hour))
+	assert.Nil(t, deadline.Check(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.True(t, deadline.Allow(ctx, 100*time.Millisecond))
+	assert.False(t, deadline.Allow(ctx, 2*time.Second))
+
+	// 超时时间不会超过剩余的预算
+	c, cancel2 := deadline.WithTimeout(ctx, time.Hour)
+	defer cancel2()
+	d, ok := deadline.Remaining(c)
+	assert.True(t, ok)
+	assert.True(t, d <= time.Second)
+
+	c, cancel3 := deadline.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel3()
+	d, _ = deadline.Remaining(c)
+	assert.True(t, d <= 10*time.Millisecond)
+
+	expired, cancel4 := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel4()
+	assert.Equal(t, deadline.Check(expired), deadline.ErrExhausted)
+}
+
+func TestTransport(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(deadline.Header)))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: deadline.Transport(nil, 5*time.Second)}
+
+	get := func(ctx context.Context) (string, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	s, err := get(context.Background())
+	assert.Nil(t, err)
+	ms, _ := strconv.Atoi(s)
+	assert.True(t, ms > 4000 && ms <= 5000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s, err = get(ctx)
+	assert.Nil(t, err)
+	ms, _ = strconv.Atoi(s)
+	assert.True(t, ms > 0 && ms <= 1000)
+
+	expired, cancel2 := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel2()
+	_, err = get(expired)
+	assert.Error(t, err, "request budget exhausted")
+}
+
+func TestFilter(t *testing.T) {
+
+	serve := func(f *deadline.Filter, header string) (time.Duration, bool) {
+		var (
+			d  time.Duration
+			ok bool
+		)
+		h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok = deadline.Remaining(r.Context())
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(deadline.Header, header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return d, ok
+	}
+
+	f := deadline.NewFilter(deadline.Config{Header: deadline.Header})
+	_, ok := serve(f, "")
+	assert.False(t, ok)
+	d, ok := serve(f, "500")
+	assert.True(t, ok)
+	assert.True(t, d > 400*time.Millisecond && d <= 500*time.Millisecond)
+
+	f = deadline.NewFilter(deadline.Config{Header: deadline.Header, Default: time.Second, Max: 2 * time.Second})
+	d, ok = serve(f, "")
+	assert.True(t, ok)
+	assert.True(t, d > 900*time.Millisecond && d <= time.Second)
+	d, ok = serve(f, "1m")
+	assert.True(t, ok)
+	assert.True(t, d > 1900*time.Millisecond && d <= 2*time.Second)
+}
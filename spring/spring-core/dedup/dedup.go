@@ -0,0 +1,252 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dedup 提供了合并并发的相同 GET 请求的 Web 过滤器，同一时刻相同路径、
+// 查询参数和认证信息的请求只有一个会真正执行，其他请求等待并共享它的响应，适用
+// 于被大量客户端同时刷新的开销较大的接口，例如监控大盘。
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/web"
+)
+
+// Config 请求合并过滤器配置，通常绑定到 web.dedup 前缀的属性上。
+type Config struct {
+	Headers     []string `value:"${headers}"`                // 参与计算请求键的请求头，默认为 Authorization 和 Cookie
+	MaxBodySize int      `value:"${max-body-size:=1048576}"` // 能够共享的响应体的最大长度
+	URLPatterns []string `value:"${url-patterns}"`           // 过滤器作用的路由，默认为全部路由
+}
+
+// Filter 合并并发的相同 GET 请求的过滤器。只有能够被完整缓存的响应才会被共享，
+// 例如 web 包只缓存 JSON 和文本格式的响应体，无法共享响应或者首个请求发生
+// panic 时等待的请求各自执行。
+type Filter struct {
+	Metrics *metrics.Registry `autowire:"?"`
+
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	headers []string
+	mutex   sync.Mutex
+	calls   map[string]*call
+	shared  *metrics.CounterVec
+}
+
+// call 正在执行的请求。
+type call struct {
+	done   chan struct{}
+	ok     bool // 响应是否可以共享
+	status int
+	header http.Header
+	body   []byte
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = []string{"Authorization", "Cookie"}
+	}
+	return &Filter{config: &config, headers: headers, calls: make(map[string]*call)}
+}
+
+// OnInit 注册过滤器的指标。
+func (f *Filter) OnInit() {
+	if f.Metrics != nil {
+		f.shared = f.Metrics.Counter("web_dedup_shared_requests_total", "Total number of requests served by a shared response.")
+	}
+}
+
+// URLPatterns 返回过滤器作用的路由。
+func (f *Filter) URLPatterns() []string {
+	if len(f.config.URLPatterns) == 0 {
+		return []string{"/*"}
+	}
+	return f.config.URLPatterns
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+
+	r := ctx.Request()
+	if r.Method != http.MethodGet {
+		chain.Next(ctx)
+		return
+	}
+
+	key := f.key(r)
+	c, leader := f.join(key)
+	if !leader {
+		if f.wait(r.Context(), c) {
+			c.replay(ctx.ResponseWriter())
+			return
+		}
+		chain.Next(ctx)
+		return
+	}
+
+	defer f.leave(key, c)
+	chain.Next(ctx)
+
+	w := ctx.ResponseWriter()
+	if body := w.Body(); len(body) == w.Size() && len(body) <= f.config.MaxBodySize {
+		c.set(w.Status(), w.Header(), body)
+	}
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := f.key(r)
+		c, leader := f.join(key)
+		if !leader {
+			if f.wait(r.Context(), c) {
+				c.replay(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer f.leave(key, c)
+		cw := &captureWriter{ResponseWriter: w, max: f.config.MaxBodySize}
+		next.ServeHTTP(cw, r)
+		if !cw.overflow {
+			c.set(cw.status, w.Header(), cw.buf.Bytes())
+		}
+	})
+}
+
+// key 返回请求的合并键，由请求路径、排序后的查询参数以及认证相关的请求头组成。
+func (f *Filter) key(r *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(r.URL.Path)
+	sb.WriteString("?")
+	sb.WriteString(r.URL.Query().Encode())
+	for _, h := range f.headers {
+		sb.WriteString("\n")
+		sb.WriteString(r.Header.Get(h))
+	}
+	return sb.String()
+}
+
+// join 加入 key 对应的请求，没有正在执行的请求时成为首个请求。
+func (f *Filter) join(key string) (*call, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if c, ok := f.calls[key]; ok {
+		return c, false
+	}
+	c := &call{done: make(chan struct{})}
+	f.calls[key] = c
+	return c, true
+}
+
+// leave 首个请求执行结束，唤醒等待的请求。
+func (f *Filter) leave(key string, c *call) {
+	f.mutex.Lock()
+	delete(f.calls, key)
+	f.mutex.Unlock()
+	close(c.done)
+}
+
+// wait 等待首个请求执行结束，返回响应是否可以共享。
+func (f *Filter) wait(ctx context.Context, c *call) bool {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return false
+	}
+	if c.ok && f.shared != nil {
+		f.shared.With().Inc()
+	}
+	return c.ok
+}
+
+// sharedHeaders 等待的请求能够共享的响应头，只包含描述响应体的响应头， Set-Cookie
+// 和关联 ID 等属于首个请求的响应头不会被共享。
+var sharedHeaders = []string{
+	"Cache-Control",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Etag",
+	"Expires",
+	"Last-Modified",
+	"Vary",
+}
+
+func (c *call) set(status int, header http.Header, body []byte) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.ok = true
+	c.status = status
+	c.header = make(http.Header)
+	for _, k := range sharedHeaders {
+		if v, ok := header[k]; ok {
+			c.header[k] = append([]string(nil), v...)
+		}
+	}
+	c.body = append([]byte(nil), body...)
+}
+
+// replay 将共享的响应写入 w 。
+func (c *call) replay(w http.ResponseWriter) {
+	for k, v := range c.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(c.status)
+	_, _ = w.Write(c.body)
+}
+
+// captureWriter 缓存不超过 max 个字节的响应体。
+type captureWriter struct {
+	http.ResponseWriter
+	buf      bytes.Buffer
+	max      int
+	status   int
+	overflow bool
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.buf.Len()+len(b) > w.max {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
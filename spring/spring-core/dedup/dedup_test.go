@@ -0,0 +1,115 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dedup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dedup"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestFilter(t *testing.T) {
+
+	var config dedup.Config
+	p := conf.New()
+	p.Set("web.dedup.headers[0]", "X-Tenant")
+	err := p.Bind(&config, conf.Tag("${web.dedup}"))
+	assert.Nil(t, err)
+	assert.Equal(t, config.Headers, []string{"X-Tenant"})
+
+	f := dedup.NewFilter(config)
+	f.Metrics = metrics.NewRegistry()
+	f.OnInit()
+
+	var count int32
+	block := make(chan struct{})
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		if r.URL.Path == "/slow" {
+			<-block
+		}
+		w.Header().Set("Etag", string(rune('0'+n)))
+		w.Header().Set("Set-Cookie", "session="+string(rune('0'+n)))
+		w.Header().Set("X-Correlation-ID", string(rune('0'+n)))
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+
+	request := func(method, target, tenant string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("X-Tenant", tenant)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	var wg sync.WaitGroup
+	resp := make([]*httptest.ResponseRecorder, 3)
+	for i := range resp {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp[i] = request(http.MethodGet, "/slow?b=2&a=1", "t1")
+		}()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 不同的租户和不同的请求方法不会被合并
+	other := make(chan *httptest.ResponseRecorder, 2)
+	go func() { other <- request(http.MethodGet, "/slow?a=1&b=2", "t2") }()
+	go func() { other <- request(http.MethodPost, "/slow?a=1&b=2", "t1") }()
+	time.Sleep(10 * time.Millisecond)
+
+	close(block)
+	wg.Wait()
+	<-other
+	<-other
+
+	assert.Equal(t, atomic.LoadInt32(&count), int32(3))
+	for _, w := range resp {
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), "b=2&a=1")
+		assert.Equal(t, w.Header().Get("Etag"), resp[0].Header().Get("Etag"))
+	}
+
+	// 只有首个请求的响应带有 Set-Cookie 和关联 ID
+	assert.Equal(t, resp[0].Header().Get("Set-Cookie"), "session="+resp[0].Header().Get("Etag"))
+	for _, w := range resp[1:] {
+		assert.Equal(t, w.Header().Get("Set-Cookie"), "")
+		assert.Equal(t, w.Header().Get("X-Correlation-ID"), "")
+	}
+
+	var shared float64
+	for _, fam := range f.Metrics.Gather() {
+		if fam.Name == "web_dedup_shared_requests_total" {
+			shared = fam.Samples[0].Value
+		}
+	}
+	assert.Equal(t, shared, float64(2))
+
+	// 执行结束之后的请求重新执行
+	assert.Equal(t, request(http.MethodGet, "/fast", "t1").Code, http.StatusOK)
+	assert.Equal(t, atomic.LoadInt32(&count), int32(4))
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package delayqueue 实现了基于数据库的延迟队列：消息中间件不支持延迟投递时，
+// mq.Channel.SendAfter 将消息写入延迟队列表，由后台协程在消息到期后转发到
+// mq.Streams 绑定的消息中间件，消息至少被投递一次。
+//
+// 延迟队列表需要预先创建，以 MySQL 为例:
+//
+//	CREATE TABLE delay_queue (
+//		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+//		channel VARCHAR(255) NOT NULL,
+//		msg_id VARCHAR(255) NOT NULL,
+//		body BLOB NOT NULL,
+//		extra TEXT NOT NULL,
+//		due_at BIGINT NOT NULL,
+//		INDEX idx_due_at (due_at)
+//	)
+package delayqueue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/json"
+)
+
+// Config 延迟队列配置，通常绑定到 mq.delay-queue 前缀的属性上。
+type Config struct {
+	Table     string        `value:"${table:=delay_queue}"` // 延迟队列表
+	Dialect   string        `value:"${dialect:=mysql}"`     // 数据库方言，决定 SQL 占位符的格式
+	Interval  time.Duration `value:"${interval:=1s}"`       // 轮询间隔，决定消息投递的精度
+	BatchSize int           `value:"${batch-size:=100}"`    // 每次轮询转发的最大消息数
+}
+
+// Queue 基于数据库的延迟队列，实现了 mq.DelayQueue 接口。
+type Queue struct {
+	Streams *mq.Streams `autowire:""`
+
+	db     *sql.DB
+	config *Config // 使用指针避免容器对其进行属性绑定
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New Queue 的构造函数。
+func New(db *sql.DB, config Config) (*Queue, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("delayqueue: interval must be positive")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	return &Queue{db: db, config: &config}, nil
+}
+
+// Schedule 将消息写入延迟队列，消息在 due 时刻之后被转发到逻辑通道 channel 。
+func (q *Queue) Schedule(ctx context.Context, channel string, due time.Time, msg mq.Message) error {
+
+	extra, err := json.Marshal(msg.Extra())
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (channel, msg_id, body, extra, due_at) VALUES (%s)",
+		q.config.Table, q.placeholders(5))
+	_, err = q.db.ExecContext(ctx, query, channel, msg.ID(), msg.Body(), string(extra), due.UnixMilli())
+	return err
+}
+
+// OnInit 启动转发到期消息的后台协程。
+func (q *Queue) OnInit() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		ticker := time.NewTicker(q.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := q.Relay(ctx, time.Now()); err != nil {
+				log.Errorf("delay queue relay error: %v", err)
+			}
+		}
+	}()
+}
+
+// OnDestroy 停止转发消息的后台协程。
+func (q *Queue) OnDestroy() {
+	if q.cancel != nil {
+		q.cancel()
+		q.wg.Wait()
+	}
+}
+
+type record struct {
+	id      int64
+	channel string
+	msgID   string
+	body    []byte
+	extra   string
+}
+
+// Relay 转发一批在 now 时刻之前到期的消息，返回成功转发的消息数量。消息按照到期
+// 的顺序转发，遇到发送失败的消息时停止本次转发，等待下次重试。
+func (q *Queue) Relay(ctx context.Context, now time.Time) (int, error) {
+
+	records, err := q.fetch(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, r := range records {
+
+		c, err := q.Streams.Output(r.channel)
+		if err != nil {
+			return i, err
+		}
+
+		m := mq.NewMessage().WithID(r.msgID).WithBody(r.body)
+		var extra map[string]string
+		if err = json.Unmarshal([]byte(r.extra), &extra); err != nil {
+			return i, err
+		}
+		for k, v := range extra {
+			m.WithExtra(k, v)
+		}
+
+		if err = c.SendMessage(ctx, m); err != nil {
+			return i, err
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", q.config.Table, q.placeholders(1))
+		if _, err = q.db.ExecContext(ctx, query, r.id); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+func (q *Queue) fetch(ctx context.Context, now time.Time) ([]record, error) {
+
+	query := fmt.Sprintf("SELECT id, channel, msg_id, body, extra FROM %s WHERE due_at <= %s ORDER BY due_at, id LIMIT %d",
+		q.config.Table, q.placeholders(1), q.config.BatchSize)
+	rows, err := q.db.QueryContext(ctx, query, now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []record
+	for rows.Next() {
+		var r record
+		if err = rows.Scan(&r.id, &r.channel, &r.msgID, &r.body, &r.extra); err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, rows.Err()
+}
+
+// placeholders 返回 n 个逗号分隔的 SQL 占位符，postgres 使用 $1 格式，其他数据库
+// 使用 ? 格式。
+func (q *Queue) placeholders(n int) string {
+	s := make([]string, n)
+	for i := range s {
+		if q.config.Dialect == "postgres" {
+			s[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			s[i] = "?"
+		}
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package delayqueue_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/delayqueue"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// table 内存中的延迟队列表，只支持 Queue 使用的几条语句。每条记录依次保存 id、
+// channel、msg_id、body、extra 和 due_at 。
+type table struct {
+	mutex  sync.Mutex
+	nextID int64
+	rows   map[int64][]driver.Value
+}
+
+func (t *table) Connect(ctx context.Context) (driver.Conn, error) { return conn{t}, nil }
+
+func (t *table) Driver() driver.Driver { return nil }
+
+// ids 返回表中剩余记录的 id 。
+func (t *table) ids() []int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var ret []int64
+	for id := range t.rows {
+		ret = append(ret, id)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+type conn struct{ t *table }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.t, query}, nil }
+
+func (c conn) Close() error { return nil }
+
+func (c conn) Begin() (driver.Tx, error) { return nil, errors.New("unsupported") }
+
+type stmt struct {
+	t     *table
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.t.mutex.Lock()
+	defer s.t.mutex.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.t.nextID++
+		s.t.rows[s.t.nextID] = append([]driver.Value{s.t.nextID}, args...)
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.t.rows, args[0].(int64))
+	default:
+		return nil, errors.New("unsupported statement " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unsupported statement " + s.query)
+	}
+	var limit int
+	fmt.Sscanf(s.query[strings.LastIndex(s.query, "LIMIT"):], "LIMIT %d", &limit)
+
+	s.t.mutex.Lock()
+	defer s.t.mutex.Unlock()
+	var due [][]driver.Value
+	for _, row := range s.t.rows {
+		if row[5].(int64) <= args[0].(int64) {
+			due = append(due, row)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i][5].(int64) != due[j][5].(int64) {
+			return due[i][5].(int64) < due[j][5].(int64)
+		}
+		return due[i][0].(int64) < due[j][0].(int64)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return &rows{data: due}, nil
+}
+
+type rows struct{ data [][]driver.Value }
+
+func (r *rows) Columns() []string {
+	return []string{"id", "channel", "msg_id", "body", "extra"}
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.data[0][:5])
+	r.data = r.data[1:]
+	return nil
+}
+
+// publisher 记录发送的消息，fail 不为空时发送失败。
+type publisher struct {
+	mutex sync.Mutex
+	fail  error
+	sent  []mq.Message
+}
+
+func (p *publisher) BindConsumer(destination string, group string, c mq.Consumer) error {
+	return nil
+}
+
+func (p *publisher) BindProducer(destination string) (mq.Producer, error) {
+	return p, nil
+}
+
+func (p *publisher) SendMessage(ctx context.Context, msg mq.Message) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.fail != nil {
+		return p.fail
+	}
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+// bodies 返回已发送消息的 topic:body 列表。
+func (p *publisher) bodies() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var ret []string
+	for _, m := range p.sent {
+		ret = append(ret, m.Topic()+":"+string(m.Body()))
+	}
+	return ret
+}
+
+func newQueue(t *testing.T, config delayqueue.Config) (*delayqueue.Queue, *table, *publisher) {
+	tbl := &table{rows: make(map[int64][]driver.Value)}
+	db := sql.OpenDB(tbl)
+	t.Cleanup(func() { db.Close() })
+	q, err := delayqueue.New(db, config)
+	assert.Nil(t, err)
+	p := &publisher{}
+	q.Streams = &mq.Streams{
+		Binders: map[string]mq.Binder{"fake": p},
+		Config: mq.StreamConfig{
+			Bindings: map[string]mq.BindingConfig{
+				"orders": {Destination: "order-events"},
+			},
+		},
+	}
+	return q, tbl, p
+}
+
+// schedule 将消息 body 写入延迟队列，在 due 时刻到期。
+func schedule(t *testing.T, q *delayqueue.Queue, channel string, due time.Time, body string) {
+	msg := mq.NewMessage().WithID("id-"+body).WithBody([]byte(body)).WithExtra("source", "test")
+	assert.Nil(t, q.Schedule(context.Background(), channel, due, msg))
+}
+
+func TestNew(t *testing.T) {
+	_, err := delayqueue.New(nil, delayqueue.Config{})
+	assert.Error(t, err, "delayqueue: interval must be positive")
+	_, err = delayqueue.New(nil, delayqueue.Config{Interval: -time.Second})
+	assert.Error(t, err, "delayqueue: interval must be positive")
+}
+
+func TestQueue_Relay(t *testing.T) {
+
+	ctx := context.Background()
+	q, tbl, p := newQueue(t, delayqueue.Config{Table: "delay_queue", Interval: time.Second})
+
+	now := time.Now()
+	schedule(t, q, "orders", now.Add(2*time.Minute), "3")
+	schedule(t, q, "orders", now.Add(time.Minute), "2")
+	schedule(t, q, "audit", now.Add(time.Minute), "2a")
+	schedule(t, q, "orders", now.Add(-time.Second), "1")
+
+	// 只投递已经到期的消息。
+	n, err := q.Relay(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, p.bodies(), []string{"order-events:1"})
+	assert.Equal(t, p.sent[0].ID(), "id-1")
+	assert.Equal(t, p.sent[0].Extra(), map[string]string{"source": "test"})
+
+	// 按照到期时间投递，到期时间相同的按照写入顺序投递，投递成功的记录被删除。
+	n, err = q.Relay(ctx, now.Add(3*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, n, 3)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2", "audit:2a", "order-events:3"})
+	assert.Equal(t, len(tbl.ids()), 0)
+}
+
+func TestQueue_Redelivery(t *testing.T) {
+
+	ctx := context.Background()
+	q, tbl, p := newQueue(t, delayqueue.Config{Table: "delay_queue", Interval: time.Second})
+
+	now := time.Now()
+	schedule(t, q, "orders", now, "1")
+	schedule(t, q, "orders", now, "2")
+
+	// 发送失败时不删除记录，下次轮询时重新投递。
+	p.fail = errors.New("broker down")
+	n, err := q.Relay(ctx, now)
+	assert.Error(t, err, "broker down")
+	assert.Equal(t, n, 0)
+	assert.Equal(t, tbl.ids(), []int64{1, 2})
+
+	p.fail = nil
+	n, err = q.Relay(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2"})
+	assert.Equal(t, len(tbl.ids()), 0)
+}
+
+func TestQueue_BatchSize(t *testing.T) {
+
+	ctx := context.Background()
+	q, tbl, p := newQueue(t, delayqueue.Config{Table: "delay_queue", Interval: time.Second, BatchSize: 2})
+
+	now := time.Now()
+	for _, b := range []string{"1", "2", "3"} {
+		schedule(t, q, "orders", now, b)
+	}
+
+	n, err := q.Relay(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, tbl.ids(), []int64{3})
+
+	n, err = q.Relay(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2", "order-events:3"})
+}
+
+func TestQueue_OnInit(t *testing.T) {
+
+	q, tbl, p := newQueue(t, delayqueue.Config{Table: "delay_queue", Interval: 10 * time.Millisecond})
+	q.OnInit()
+	defer q.OnDestroy()
+
+	// 后台协程在消息到期后投递。
+	schedule(t, q, "orders", time.Now().Add(50*time.Millisecond), "1")
+	assert.Equal(t, p.bodies(), []string(nil))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tbl.ids()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, len(tbl.ids()), 0)
+	assert.Equal(t, p.bodies(), []string{"order-events:1"})
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Balancer 负载均衡器。
+type Balancer interface {
+
+	// Choose 从服务 service 的实例中选择一个协议为 scheme 的实例。
+	Choose(ctx context.Context, service string, scheme string) (*Instance, error)
+}
+
+// RoundRobin 轮询方式的负载均衡器。
+type RoundRobin struct {
+	client Client
+	mutex  sync.Mutex
+	next   map[string]*uint32
+}
+
+// NewRoundRobin RoundRobin 的构造函数。
+func NewRoundRobin(client Client) *RoundRobin {
+	return &RoundRobin{client: client, next: make(map[string]*uint32)}
+}
+
+// Choose 轮流选择服务的实例。
+func (b *RoundRobin) Choose(ctx context.Context, service string, scheme string) (*Instance, error) {
+
+	instances, err := b.client.Instances(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	instances = Filter(instances, scheme)
+	if len(instances) == 0 {
+		return nil, ErrNoInstance
+	}
+
+	b.mutex.Lock()
+	n, ok := b.next[service]
+	if !ok {
+		n = new(uint32)
+		b.next[service] = n
+	}
+	b.mutex.Unlock()
+
+	i := atomic.AddUint32(n, 1) - 1
+	return instances[int(i%uint32(len(instances)))], nil
+}
+
+// Transport 实现了 http.RoundTripper 接口，将请求地址中的主机名作为服务名称，
+// 通过负载均衡器替换为服务实例的地址，例如 http://order-service/orders 。
+type Transport struct {
+	Balancer Balancer
+	Base     http.RoundTripper // 为空时使用 http.DefaultTransport
+}
+
+// NewTransport Transport 的构造函数。
+func NewTransport(b Balancer) *Transport {
+	return &Transport{Balancer: b}
+}
+
+// RoundTrip 解析服务实例的地址后发送请求。
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	i, err := t.Balancer.Choose(req.Context(), req.URL.Hostname(), SchemeHTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	// 清空 Host 使请求头中的主机名与实例地址保持一致
+	r.URL.Host = i.Address()
+	r.Host = ""
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
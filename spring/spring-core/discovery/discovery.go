@@ -0,0 +1,110 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package discovery 提供了服务注册与发现的标准接口，可以灵活适配 Consul、etcd、
+// Nacos 等各种注册中心。应用启动时 Registrar 将 Web 服务器和 gRPC 服务器注册
+// 到注册中心，应用关闭时注销；客户端通过 Client 查询服务实例并进行负载均衡。
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ErrNoInstance 服务没有可用的实例。
+var ErrNoInstance = errors.New("discovery: no available instance")
+
+const (
+	SchemeHTTP = "http" // Web 服务实例
+	SchemeGrpc = "grpc" // gRPC 服务实例
+)
+
+// MetadataScheme 不支持协议字段的注册中心在元数据中保存实例协议时使用的键。
+const MetadataScheme = "scheme"
+
+// Instance 服务实例。
+type Instance struct {
+	ID          string            // 实例 ID，在注册中心内唯一
+	Service     string            // 服务名称
+	Scheme      string            // 实例提供的协议，例如 http、grpc
+	Host        string            // 主机地址
+	Port        int               // 端口
+	HealthCheck string            // 健康检查地址，为空时由注册中心决定检查方式
+	Metadata    map[string]string // 元数据
+}
+
+// Address 返回实例的 host:port 地址。
+func (i *Instance) Address() string {
+	return net.JoinHostPort(i.Host, strconv.Itoa(i.Port))
+}
+
+// Registry 服务注册接口。
+type Registry interface {
+
+	// Register 注册服务实例。
+	Register(ctx context.Context, i *Instance) error
+
+	// Deregister 注销服务实例。
+	Deregister(ctx context.Context, i *Instance) error
+}
+
+// Client 服务发现接口。
+type Client interface {
+
+	// Instances 返回服务 service 所有健康的实例。
+	Instances(ctx context.Context, service string) ([]*Instance, error)
+}
+
+// Filter 返回协议为 scheme 的实例，scheme 为空时返回全部实例。没有设置协议的
+// 实例被认为可以匹配任何协议。
+func Filter(instances []*Instance, scheme string) []*Instance {
+	if scheme == "" {
+		return instances
+	}
+	var ret []*Instance
+	for _, i := range instances {
+		if i.Scheme == "" || i.Scheme == scheme {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+// InstanceID 返回实例的默认 ID 。
+func InstanceID(service, scheme, host string, port int) string {
+	return fmt.Sprintf("%s-%s-%s-%d", service, scheme, host, port)
+}
+
+// LocalIP 返回本机第一个非回环的 IPv4 地址。
+func LocalIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			return ip.String(), nil
+		}
+	}
+	return "", errors.New("discovery: can't find local ip")
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/discovery"
+	"github.com/go-spring/spring-core/readiness"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type memoryClient map[string][]*discovery.Instance
+
+func (c memoryClient) Instances(ctx context.Context, service string) ([]*discovery.Instance, error) {
+	return c[service], nil
+}
+
+func TestRoundRobin(t *testing.T) {
+
+	client := memoryClient{
+		"order": {
+			{ID: "a", Scheme: discovery.SchemeHTTP, Host: "10.0.0.1", Port: 8080},
+			{ID: "b", Scheme: discovery.SchemeGrpc, Host: "10.0.0.1", Port: 9090},
+			{ID: "c", Scheme: discovery.SchemeHTTP, Host: "10.0.0.2", Port: 8080},
+		},
+	}
+
+	b := discovery.NewRoundRobin(client)
+	ctx := context.Background()
+
+	var ids []string
+	for j := 0; j < 4; j++ {
+		i, err := b.Choose(ctx, "order", discovery.SchemeHTTP)
+		assert.Nil(t, err)
+		ids = append(ids, i.ID)
+	}
+	assert.Equal(t, ids, []string{"a", "c", "a", "c"})
+
+	i, err := b.Choose(ctx, "order", discovery.SchemeGrpc)
+	assert.Nil(t, err)
+	assert.Equal(t, i.Address(), "10.0.0.1:9090")
+
+	_, err = b.Choose(ctx, "user", "")
+	assert.Equal(t, err, discovery.ErrNoInstance)
+}
+
+func TestTransport(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	host, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	assert.Nil(t, err)
+	p, err := strconv.Atoi(port)
+	assert.Nil(t, err)
+
+	client := memoryClient{
+		"order": {{ID: "a", Host: host, Port: p}},
+	}
+	c := &http.Client{Transport: discovery.NewTransport(discovery.NewRoundRobin(client))}
+
+	resp, err := c.Get("http://order/orders")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "/orders")
+
+	_, err = c.Get("http://user/users")
+	assert.Error(t, err, "no available instance")
+}
+
+type memoryRegistry struct {
+	mutex sync.Mutex
+	ids   []string
+}
+
+func (r *memoryRegistry) Register(ctx context.Context, i *discovery.Instance) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ids = append(r.ids, i.ID)
+	return nil
+}
+
+func (r *memoryRegistry) Deregister(ctx context.Context, i *discovery.Instance) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for j, id := range r.ids {
+		if id == i.ID {
+			r.ids = append(r.ids[:j], r.ids[j+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *memoryRegistry) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.ids)
+}
+
+func TestRegistrar_Gate(t *testing.T) {
+
+	config := discovery.Config{Service: "order", Host: "10.0.0.1", WebPort: 8080}
+
+	t.Run("ready", func(t *testing.T) {
+		gate, err := readiness.NewGate(readiness.Config{TimeoutPolicy: readiness.PolicyDown})
+		assert.Nil(t, err)
+		ch := make(chan struct{})
+		gate.Register("cache", func(ctx context.Context) error {
+			<-ch
+			return nil
+		})
+		registry := &memoryRegistry{}
+		r := discovery.NewRegistrar(registry, config)
+		r.Gate = gate
+		assert.Nil(t, r.OnInit())
+		go gate.Run(context.Background())
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, registry.Len(), 0)
+		close(ch)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, registry.Len(), 1)
+		r.OnDestroy()
+		assert.Equal(t, registry.Len(), 0)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		gate, err := readiness.NewGate(readiness.Config{TimeoutPolicy: readiness.PolicyDown})
+		assert.Nil(t, err)
+		gate.Register("conn", func(ctx context.Context) error { return io.EOF })
+		registry := &memoryRegistry{}
+		r := discovery.NewRegistrar(registry, config)
+		r.Gate = gate
+		assert.Nil(t, r.OnInit())
+		gate.Run(context.Background())
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, registry.Len(), 0)
+		r.OnDestroy()
+	})
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-spring/spring-core/grpc"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/readiness"
+)
+
+// Config 服务注册配置，通常绑定到 discovery 前缀的属性上。
+type Config struct {
+	Service     string            `value:"${service:=${spring.application.name:=}}"` // 服务名称
+	Host        string            `value:"${host:=}"`                                // 注册的主机地址，为空时使用本机 IP
+	WebPort     int               `value:"${web-port:=${web.server.port:=8080}}"`    // 注册的 Web 端口，0 表示不注册
+	GrpcPort    int               `value:"${grpc-port:=${grpc.server.port:=9090}}"`  // 注册的 gRPC 端口
+	HealthCheck string            `value:"${health-check:=}"`                        // Web 服务的健康检查路径，例如 /health
+	Metadata    map[string]string `value:"${metadata}"`                              // 实例的元数据
+}
+
+// Registrar 在容器刷新时将应用注册到注册中心，容器关闭时注销。存在 gRPC 服务
+// 时同时注册 gRPC 实例。存在就绪门控时等到应用就绪之后才注册。
+type Registrar struct {
+	GrpcServers map[string]*grpc.Server `autowire:""`
+	Gate        *readiness.Gate         `autowire:"?"`
+
+	registry  Registry
+	config    *Config // 使用指针避免容器对其进行属性绑定
+	instances []*Instance
+
+	mutex      sync.Mutex
+	registered []*Instance
+	destroyed  bool
+	stop       chan struct{}
+}
+
+// NewRegistrar Registrar 的构造函数。
+func NewRegistrar(registry Registry, config Config) *Registrar {
+	return &Registrar{registry: registry, config: &config}
+}
+
+// Instances 返回应用注册的所有实例。
+func (r *Registrar) Instances() []*Instance {
+	return r.instances
+}
+
+// OnInit 注册应用的服务实例。
+func (r *Registrar) OnInit() error {
+
+	if r.config.Service == "" {
+		return fmt.Errorf("discovery: service name is empty")
+	}
+
+	host := r.config.Host
+	if host == "" {
+		var err error
+		if host, err = LocalIP(); err != nil {
+			return err
+		}
+	}
+
+	if r.config.WebPort > 0 {
+		i := r.newInstance(SchemeHTTP, host, r.config.WebPort)
+		if r.config.HealthCheck != "" {
+			i.HealthCheck = fmt.Sprintf("http://%s%s", i.Address(), r.config.HealthCheck)
+		}
+		r.instances = append(r.instances, i)
+	}
+
+	if len(r.GrpcServers) > 0 && r.config.GrpcPort > 0 {
+		r.instances = append(r.instances, r.newInstance(SchemeGrpc, host, r.config.GrpcPort))
+	}
+
+	if r.Gate == nil {
+		return r.register()
+	}
+
+	r.stop = make(chan struct{})
+	go func() {
+		select {
+		case <-r.stop:
+			return
+		case <-r.Gate.Done():
+		}
+		if !r.Gate.Ready() {
+			log.Warn("application isn't ready, skip registering instances")
+			return
+		}
+		if err := r.register(); err != nil {
+			log.Error(err)
+		}
+	}()
+	return nil
+}
+
+// register 注册所有的服务实例，容器已经关闭时不再注册。
+func (r *Registrar) register() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.destroyed {
+		return nil
+	}
+	ctx := context.Background()
+	for _, i := range r.instances {
+		log.Infof("register %s instance %s", i.Scheme, i.ID)
+		if err := r.registry.Register(ctx, i); err != nil {
+			return err
+		}
+		r.registered = append(r.registered, i)
+	}
+	return nil
+}
+
+func (r *Registrar) newInstance(scheme, host string, port int) *Instance {
+	metadata := make(map[string]string)
+	for k, v := range r.config.Metadata {
+		metadata[k] = v
+	}
+	return &Instance{
+		ID:       InstanceID(r.config.Service, scheme, host, port),
+		Service:  r.config.Service,
+		Scheme:   scheme,
+		Host:     host,
+		Port:     port,
+		Metadata: metadata,
+	}
+}
+
+// OnDestroy 注销已经注册的服务实例。
+func (r *Registrar) OnDestroy() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.destroyed {
+		return
+	}
+	r.destroyed = true
+	if r.stop != nil {
+		close(r.stop)
+	}
+	ctx := context.Background()
+	for _, i := range r.registered {
+		log.Infof("deregister %s instance %s", i.Scheme, i.ID)
+		if err := r.registry.Deregister(ctx, i); err != nil {
+			log.Error(err)
+		}
+	}
+}
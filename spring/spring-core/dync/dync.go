@@ -0,0 +1,202 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dync 提供了可以在运行时刷新的动态属性值。动态属性值通过 value 标签
+// 绑定到属性上，属性刷新时原子地更新为新的值，校验函数可以拒绝不合法的新值，此
+// 时保留原来的值，变更监听函数在值发生变化后执行。
+package dync
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-stl/util"
+)
+
+// Refreshable 可以刷新的动态属性值。
+type Refreshable interface {
+	Refresh(p *conf.Properties) error
+}
+
+// 常用类型的动态属性值。
+type (
+	Int64    = Value[int64]
+	Float    = Value[float64]
+	String   = Value[string]
+	Bool     = Value[bool]
+	Duration = Value[time.Duration]
+)
+
+type box[T any] struct {
+	v T
+}
+
+// Value 类型为 T 的动态属性值，T 可以是任何支持属性绑定的类型。零值的 Value
+// 在绑定之前返回 T 的零值。
+type Value[T any] struct {
+	v atomic.Value // *box[T]
+
+	mutex      sync.Mutex
+	param      conf.BindParam
+	bound      bool
+	validators []func(v T) error
+	listeners  []func(old, new T)
+}
+
+// Value 返回当前的值。
+func (d *Value[T]) Value() T {
+	if b, ok := d.v.Load().(*box[T]); ok {
+		return b.v
+	}
+	var zero T
+	return zero
+}
+
+// OnValidate 添加校验函数，返回错误时拒绝新的值，需要在绑定之前调用才能校验初
+// 始值，例如在构造函数中调用。
+func (d *Value[T]) OnValidate(fn func(v T) error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.validators = append(d.validators, fn)
+}
+
+// OnChange 添加值发生变化后执行的监听函数。
+func (d *Value[T]) OnChange(fn func(old, new T)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.listeners = append(d.listeners, fn)
+}
+
+// BindProperties 实现 conf.Binder 接口，记录绑定的属性并设置初始值。
+func (d *Value[T]) BindProperties(p *conf.Properties, param conf.BindParam) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	v, err := d.resolve(p, param)
+	if err != nil {
+		return err
+	}
+	d.param, d.bound = param, true
+	d.v.Store(&box[T]{v})
+	return nil
+}
+
+// Refresh 从属性列表 p 获取新的值，校验通过并且与原来的值不同时更新并通知监
+// 听函数，未绑定的动态属性值不做任何处理。
+func (d *Value[T]) Refresh(p *conf.Properties) error {
+
+	d.mutex.Lock()
+	if !d.bound {
+		d.mutex.Unlock()
+		return nil
+	}
+	v, err := d.resolve(p, d.param)
+	if err != nil {
+		d.mutex.Unlock()
+		return err
+	}
+	old := d.Value()
+	if reflect.DeepEqual(old, v) {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.v.Store(&box[T]{v})
+	listeners := d.listeners
+	d.mutex.Unlock()
+
+	for _, fn := range listeners {
+		fn(old, v)
+	}
+	return nil
+}
+
+// resolve 获取并校验属性的值。
+func (d *Value[T]) resolve(p *conf.Properties, param conf.BindParam) (T, error) {
+	var v T
+	if err := p.Bind(&v, conf.Tag(param.Tag())); err != nil {
+		return v, fmt.Errorf("%s bind error: %w", param.Path, err)
+	}
+	for _, fn := range d.validators {
+		if err := fn(v); err != nil {
+			return v, fmt.Errorf("%s validate error: %w", param.Path, err)
+		}
+	}
+	return v, nil
+}
+
+// Manager 管理容器中所有绑定的动态属性值。
+type Manager struct {
+	mutex  sync.Mutex
+	values []Refreshable
+}
+
+// Add 添加需要刷新的动态属性值。
+func (m *Manager) Add(v Refreshable) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values = append(m.values, v)
+}
+
+var refreshableType = reflect.TypeOf((*Refreshable)(nil)).Elem()
+
+// Collect 收集结构体 v 中 (包括嵌套的结构体中) 所有的动态属性值。
+func (m *Manager) Collect(v reflect.Value) {
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		fv := v.Field(i)
+		if fv.Kind() != reflect.Struct {
+			continue
+		}
+		if !fv.CanInterface() {
+			fv = util.PatchValue(fv)
+		}
+		if fv.CanAddr() && fv.Addr().Type().Implements(refreshableType) {
+			m.Add(fv.Addr().Interface().(Refreshable))
+			continue
+		}
+		m.Collect(fv)
+	}
+}
+
+// Refresh 使用属性列表 p 刷新所有的动态属性值，校验失败的动态属性值保留原来的
+// 值，返回所有刷新失败的错误。
+func (m *Manager) Refresh(p *conf.Properties) error {
+
+	m.mutex.Lock()
+	values := append([]Refreshable(nil), m.values...)
+	m.mutex.Unlock()
+
+	var msgs []string
+	for _, v := range values {
+		if err := v.Refresh(p); err != nil {
+			log.Errorf("refresh dynamic value error: %v", err)
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("dync: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dync_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dync"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type Config struct {
+	Port    dync.Int64           `value:"${port:=8080}"`
+	Name    dync.String          `value:"${name}"`
+	Ratio   dync.Float           `value:"${ratio:=0.5}"`
+	Enabled dync.Bool            `value:"${enabled:=false}"`
+	Timeout dync.Duration        `value:"${timeout:=1s}"`
+	Hosts   dync.Value[[]string] `value:"${hosts}"`
+	Nested  struct {
+		Level dync.Int64 `value:"${level:=1}"`
+	} `value:"${nested}"`
+}
+
+func TestValue(t *testing.T) {
+
+	var c Config
+	c.Port.OnValidate(func(v int64) error {
+		if v <= 0 || v > 65535 {
+			return errors.New("invalid port")
+		}
+		return nil
+	})
+
+	var changes []int64
+	c.Port.OnChange(func(old, new int64) {
+		changes = append(changes, old, new)
+	})
+
+	p := conf.Map(map[string]interface{}{
+		"server.name":  "order",
+		"server.hosts": []string{"a", "b"},
+	})
+	err := p.Bind(&c, conf.Key("server"))
+	assert.Nil(t, err)
+	assert.Equal(t, c.Port.Value(), int64(8080))
+	assert.Equal(t, c.Name.Value(), "order")
+	assert.Equal(t, c.Ratio.Value(), 0.5)
+	assert.False(t, c.Enabled.Value())
+	assert.Equal(t, c.Timeout.Value(), time.Second)
+	assert.Equal(t, c.Hosts.Value(), []string{"a", "b"})
+	assert.Equal(t, c.Nested.Level.Value(), int64(1))
+
+	var m dync.Manager
+	m.Collect(reflect.ValueOf(&c).Elem())
+
+	p.Set("server.port", 9090)
+	p.Set("server.enabled", true)
+	p.Set("server.nested.level", 3)
+	assert.Nil(t, m.Refresh(p))
+	assert.Equal(t, c.Port.Value(), int64(9090))
+	assert.True(t, c.Enabled.Value())
+	assert.Equal(t, c.Nested.Level.Value(), int64(3))
+	assert.Equal(t, changes, []int64{8080, 9090})
+
+	// 校验失败时保留原来的值
+	p.Set("server.port", 70000)
+	p.Set("server.ratio", 0.8)
+	err = m.Refresh(p)
+	assert.Error(t, err, "Config.Port validate error: invalid port")
+	assert.Equal(t, c.Port.Value(), int64(9090))
+	assert.Equal(t, c.Ratio.Value(), 0.8)
+	assert.Equal(t, changes, []int64{8080, 9090})
+
+	p.Set("server.ratio", "abc")
+	assert.NotNil(t, m.Refresh(p))
+	assert.Equal(t, c.Ratio.Value(), 0.8)
+}
+
+func TestValue_Validate(t *testing.T) {
+
+	var c Config
+	c.Name.OnValidate(func(v string) error {
+		if v == "" {
+			return errors.New("empty name")
+		}
+		return nil
+	})
+
+	p := conf.Map(map[string]interface{}{"name": ""})
+	err := p.Bind(&c)
+	assert.Error(t, err, "Config.Name validate error: empty name")
+
+	var v dync.Int64
+	assert.Equal(t, v.Value(), int64(0))
+	assert.Nil(t, v.Refresh(p)) // 未绑定的动态属性值不做处理
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package events 提供了进程内的领域事件总线，以及在事务提交之后才发布领域事件
+// 的辅助函数，避免事务回滚时发布了实际上没有发生的事件。
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-spring/spring-core/db"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/util"
+)
+
+// Listener 领域事件的监听器，只接收可以赋值给参数类型的事件。
+type Listener interface {
+	Type() reflect.Type
+	OnEvent(ctx context.Context, e interface{}) error
+}
+
+type listener struct {
+	t reflect.Type
+	v reflect.Value
+}
+
+func (l *listener) Type() reflect.Type {
+	return l.t
+}
+
+func (l *listener) OnEvent(ctx context.Context, e interface{}) error {
+	out := l.v.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(e)})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+// On 创建领域事件的监听器，fn 的类型为 func(ctx,T)error ，例如
+// func(ctx context.Context, e *OrderCreated) error ，T 为接口类型时接收所有实现
+// 了该接口的事件。
+func On(fn interface{}) Listener {
+	t := reflect.TypeOf(fn)
+	if !util.IsFuncType(t) || !util.ReturnOnlyError(t) || t.NumIn() != 2 || !util.IsContextType(t.In(0)) {
+		panic(errors.New("fn should be func(ctx,T)error"))
+	}
+	return &listener{t: t.In(1), v: reflect.ValueOf(fn)}
+}
+
+// Remote 可以由领域事件实现的可选接口，返回事件需要转发到的 mq 逻辑通道，事件
+// 使用逻辑通道配置的编解码器编码。
+type Remote interface {
+	Channel() string
+}
+
+// Bus 进程内的领域事件总线。
+type Bus struct {
+	Listeners []Listener  `autowire:"?"`
+	Streams   *mq.Streams `autowire:"?"`
+
+	mutex     sync.RWMutex
+	listeners []Listener
+}
+
+// Default 默认的领域事件总线。
+var Default = NewBus()
+
+// NewBus Bus 的构造函数。
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe 添加领域事件的监听器，fn 的类型参见 On 函数。
+func (b *Bus) Subscribe(fn interface{}) {
+	l := On(fn)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.listeners = append(b.listeners, l)
+}
+
+// Publish 立即发布领域事件，依次同步调用所有匹配的监听器，事件实现了 Remote
+// 接口时再转发到 mq 逻辑通道，返回第一个错误。
+func (b *Bus) Publish(ctx context.Context, e interface{}) error {
+
+	b.mutex.RLock()
+	listeners := append(b.Listeners[:len(b.Listeners):len(b.Listeners)], b.listeners...)
+	b.mutex.RUnlock()
+
+	t := reflect.TypeOf(e)
+	for _, l := range listeners {
+		if t.AssignableTo(l.Type()) {
+			if err := l.OnEvent(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	r, ok := e.(Remote)
+	if !ok {
+		return nil
+	}
+	if b.Streams == nil {
+		return fmt.Errorf("events: no mq streams to forward %T to channel %q", e, r.Channel())
+	}
+	c, err := b.Streams.Output(r.Channel())
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, e)
+}
+
+type txKey struct{}
+
+// tx 事务期间缓存的领域事件，parent 不为空时表示嵌套的事务。
+type tx struct {
+	bus    *Bus
+	parent *tx
+	mutex  sync.Mutex
+	events []interface{}
+}
+
+func txFrom(ctx context.Context) *tx {
+	t, _ := ctx.Value(txKey{}).(*tx)
+	return t
+}
+
+// Begin 返回绑定了事件缓冲区的 ctx ，通常在事务开始时调用。ctx 中已经存在事件
+// 缓冲区时创建嵌套的缓冲区，提交时合并到外层的缓冲区中。
+func (b *Bus) Begin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txKey{}, &tx{bus: b, parent: txFrom(ctx)})
+}
+
+// PublishAfterCommit 将领域事件缓存到 ctx 的事件缓冲区中，在事务提交之后发布，
+// 事务回滚时丢弃。ctx 中没有事件缓冲区时使用默认的事件总线立即发布。
+func PublishAfterCommit(ctx context.Context, e interface{}) error {
+	t := txFrom(ctx)
+	if t == nil {
+		return Default.Publish(ctx, e)
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, e)
+	return nil
+}
+
+// Commit 在事务提交之后调用，嵌套的缓冲区将事件合并到外层的缓冲区，最外层的
+// 缓冲区按照缓存的顺序发布所有事件，返回第一个错误。
+func Commit(ctx context.Context) error {
+
+	t := txFrom(ctx)
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	events := t.events
+	t.events = nil
+	t.mutex.Unlock()
+
+	if p := t.parent; p != nil {
+		p.mutex.Lock()
+		p.events = append(p.events, events...)
+		p.mutex.Unlock()
+		return nil
+	}
+
+	// 监听器中再次发布的事件立即发布
+	ctx = context.WithValue(ctx, txKey{}, (*tx)(nil))
+	var ret error
+	for _, e := range events {
+		if err := t.bus.Publish(ctx, e); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// Rollback 在事务回滚之后调用，丢弃缓冲区中的所有事件。
+func Rollback(ctx context.Context) {
+	if t := txFrom(ctx); t != nil {
+		t.mutex.Lock()
+		t.events = nil
+		t.mutex.Unlock()
+	}
+}
+
+// RunInTx 在数据库事务中执行 fn ，fn 的 ctx 同时绑定了事务和事件缓冲区，fn 中
+// 通过 PublishAfterCommit 发布的事件在事务提交成功之后发布，fn 返回错误或者
+// panic 时回滚事务并丢弃事件。事务已经提交，因此发布事件的错误只会作为返回值，
+// 不会影响事务的结果。
+func (b *Bus) RunInTx(ctx context.Context, pool *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+
+	sqlTx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx = db.WithTx(b.Begin(ctx), sqlTx)
+	defer func() {
+		if r := recover(); r != nil {
+			Rollback(ctx)
+			_ = sqlTx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(ctx, sqlTx); err != nil {
+		Rollback(ctx)
+		_ = sqlTx.Rollback()
+		return err
+	}
+
+	if err = sqlTx.Commit(); err != nil {
+		Rollback(ctx)
+		return err
+	}
+	return Commit(ctx)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-core/events"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type OrderCreated struct {
+	ID string `json:"id"`
+}
+
+type OrderPaid struct {
+	ID string `json:"id"`
+}
+
+func (*OrderPaid) Channel() string {
+	return "payments"
+}
+
+type named interface {
+	Name() string
+}
+
+type UserCreated struct{}
+
+func (*UserCreated) Name() string {
+	return "user"
+}
+
+func TestBus_Publish(t *testing.T) {
+
+	var received []string
+	b := events.NewBus()
+	b.Listeners = []events.Listener{
+		events.On(func(ctx context.Context, e *OrderCreated) error {
+			received = append(received, "created:"+e.ID)
+			return nil
+		}),
+	}
+	b.Subscribe(func(ctx context.Context, e named) error {
+		received = append(received, "named:"+e.Name())
+		return nil
+	})
+
+	ctx := context.Background()
+	assert.Nil(t, b.Publish(ctx, &OrderCreated{ID: "1"}))
+	assert.Nil(t, b.Publish(ctx, &UserCreated{}))
+	assert.Equal(t, received, []string{"created:1", "named:user"})
+
+	err := b.Publish(ctx, &OrderPaid{ID: "1"})
+	assert.Error(t, err, "no mq streams to forward \\*events_test.OrderPaid to channel \"payments\"")
+
+	var forwarded []string
+	b.Streams = &mq.Streams{
+		Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+		Listeners: []mq.Listener{
+			mq.Handle("payments", func(ctx context.Context, e *OrderPaid) error {
+				forwarded = append(forwarded, e.ID)
+				return nil
+			}),
+		},
+	}
+	assert.Nil(t, b.Streams.OnInit())
+	assert.Nil(t, b.Publish(ctx, &OrderPaid{ID: "2"}))
+	assert.Equal(t, forwarded, []string{"2"})
+
+	assert.Panic(t, func() {
+		events.On(func(e *OrderCreated) {})
+	}, "fn should be func\\(ctx,T\\)error")
+}
+
+func TestPublishAfterCommit(t *testing.T) {
+
+	var received []string
+	b := events.NewBus()
+	b.Subscribe(func(ctx context.Context, e *OrderCreated) error {
+		received = append(received, e.ID)
+		if e.ID == "2" {
+			// 监听器中发布的事件立即发布
+			return events.PublishAfterCommit(ctx, &OrderCreated{ID: "3"})
+		}
+		return nil
+	})
+
+	defer func(bus *events.Bus) { events.Default = bus }(events.Default)
+	events.Default = b
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		received = nil
+		tx := b.Begin(ctx)
+		assert.Nil(t, events.PublishAfterCommit(tx, &OrderCreated{ID: "1"}))
+		assert.Nil(t, events.PublishAfterCommit(tx, &OrderCreated{ID: "2"}))
+		assert.Equal(t, len(received), 0)
+		assert.Nil(t, events.Commit(tx))
+		assert.Equal(t, received, []string{"1", "2", "3"})
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		received = nil
+		tx := b.Begin(ctx)
+		assert.Nil(t, events.PublishAfterCommit(tx, &OrderCreated{ID: "1"}))
+		events.Rollback(tx)
+		assert.Nil(t, events.Commit(tx))
+		assert.Equal(t, len(received), 0)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		received = nil
+		outer := b.Begin(ctx)
+		assert.Nil(t, events.PublishAfterCommit(outer, &OrderCreated{ID: "1"}))
+
+		inner := b.Begin(outer)
+		assert.Nil(t, events.PublishAfterCommit(inner, &OrderCreated{ID: "4"}))
+		events.Rollback(inner)
+
+		inner = b.Begin(outer)
+		assert.Nil(t, events.PublishAfterCommit(inner, &OrderCreated{ID: "5"}))
+		assert.Nil(t, events.Commit(inner))
+		assert.Equal(t, len(received), 0)
+
+		assert.Nil(t, events.Commit(outer))
+		assert.Equal(t, received, []string{"1", "5"})
+	})
+
+	t.Run("no transaction", func(t *testing.T) {
+		received = nil
+		assert.Nil(t, events.PublishAfterCommit(ctx, &OrderCreated{ID: "1"}))
+		assert.Equal(t, received, []string{"1"})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		b.Subscribe(func(ctx context.Context, e *OrderPaid) error {
+			return errors.New("listener error")
+		})
+		tx := b.Begin(ctx)
+		assert.Nil(t, events.PublishAfterCommit(tx, &OrderPaid{ID: "1"}))
+		assert.Error(t, events.Commit(tx), "listener error")
+	})
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package executor 提供了有界的异步任务线程池，用于替代直接使用 Container.Go
+// 执行大量的异步任务。线程池的队列有固定的容量，队列已满时按照拒绝策略处理新的
+// 任务，容器关闭时线程池停止接收任务并在限定时间内执行完队列中的任务。
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
+)
+
+var (
+	// ErrRejected 线程池的队列已满，任务被拒绝。
+	ErrRejected = errors.New("executor: task rejected")
+
+	// ErrClosed 线程池已经关闭。
+	ErrClosed = errors.New("executor: pool closed")
+)
+
+// 线程池的拒绝策略。
+const (
+	Abort         = "abort"          // 返回 ErrRejected
+	CallerRuns    = "caller-runs"    // 在提交任务的协程中执行任务
+	Discard       = "discard"        // 丢弃新的任务
+	DiscardOldest = "discard-oldest" // 丢弃队列中最早的任务
+)
+
+// Config 线程池配置，通常绑定到 executor 前缀的属性上。
+type Config struct {
+	Workers      int           `value:"${workers:=8}"`         // 工作协程的数量，0 表示与 GOMAXPROCS 相同
+	QueueSize    int           `value:"${queue-size:=1024}"`   // 队列的容量
+	Rejection    string        `value:"${rejection:=abort}"`   // 队列已满时的拒绝策略
+	DrainTimeout time.Duration `value:"${drain-timeout:=30s}"` // 关闭时等待队列中任务执行完的最长时间
+	Phase        int           `value:"${phase:=0}"`           // 容器关闭时排空线程池的阶段
+}
+
+type task struct {
+	ctx    context.Context
+	fn     func(ctx context.Context)
+	submit time.Time
+}
+
+// taskContext 任务的上下文，保留提交任务时 ctx 中的值 (包括 knife 中的值) 但是
+// 不受其取消的影响，任务只在线程池关闭超时后才被取消。
+type taskContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c taskContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// Pool 有界的异步任务线程池。
+type Pool struct {
+	Metrics *metrics.Registry `autowire:"?"`
+
+	name   string
+	config *Config // 使用指针避免容器对其进行属性绑定
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *task
+	wg     sync.WaitGroup
+	once   sync.Once
+
+	drain    sync.Once // 只排空一次，避免关闭阶段和销毁时重复等待
+	drainErr error
+
+	mu     sync.RWMutex
+	closed bool
+
+	tasks    *metrics.CounterVec
+	depth    *metrics.GaugeVec
+	active   *metrics.GaugeVec
+	waiting  *metrics.TimerVec
+	duration *metrics.TimerVec
+}
+
+// NewPool Pool 的构造函数，name 用于区分不同线程池的日志和指标。
+func NewPool(name string, config Config) (*Pool, error) {
+	if config.Workers < 0 {
+		return nil, fmt.Errorf("executor %s: workers must not be negative", name)
+	}
+	if config.Workers == 0 {
+		config.Workers = runtime.GOMAXPROCS(0)
+	}
+	if config.QueueSize < 0 {
+		return nil, fmt.Errorf("executor %s: queue-size must not be negative", name)
+	}
+	switch config.Rejection {
+	case Abort, CallerRuns, Discard, DiscardOldest:
+	default:
+		return nil, fmt.Errorf("executor %s: unknown rejection policy %q", name, config.Rejection)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		name:   name,
+		config: &config,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *task, config.QueueSize),
+	}, nil
+}
+
+// Name 返回线程池的名称。
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// OnInit 注册线程池的指标并启动工作协程。
+func (p *Pool) OnInit() {
+	if p.Metrics != nil {
+		p.tasks = p.Metrics.Counter("executor_tasks_total", "Total number of executor tasks by outcome.", "pool", "outcome")
+		p.depth = p.Metrics.Gauge("executor_queue_depth", "Number of tasks waiting in the executor queue.", "pool")
+		p.active = p.Metrics.Gauge("executor_active_workers", "Number of executor workers running a task.", "pool")
+		p.waiting = p.Metrics.Timer("executor_queue_seconds", "Time tasks spent in the executor queue in seconds.", "pool")
+		p.duration = p.Metrics.Timer("executor_task_seconds", "Executor task execution time in seconds.", "pool")
+	}
+	p.once.Do(func() {
+		for i := 0; i < p.config.Workers; i++ {
+			p.wg.Add(1)
+			go p.work()
+		}
+	})
+}
+
+// Phase 返回容器关闭时排空线程池的阶段。
+func (p *Pool) Phase() int {
+	return p.config.Phase
+}
+
+// Stop 停止接收新的任务，之后提交的任务返回 ErrClosed 。
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+}
+
+// Drain 等待队列中的任务执行完，ctx 结束或者超过 DrainTimeout 时取消所有任务的
+// 上下文。
+func (p *Pool) Drain(ctx context.Context) error {
+	p.drain.Do(func() { p.drainErr = p.doDrain(ctx) })
+	return p.drainErr
+}
+
+func (p *Pool) doDrain(ctx context.Context) error {
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.config.DrainTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-done:
+	case <-timer.C:
+		err = fmt.Errorf("executor %s: drain timeout, %d tasks left in queue", p.name, len(p.queue))
+	case <-ctx.Done():
+		err = fmt.Errorf("executor %s: drain canceled, %d tasks left in queue", p.name, len(p.queue))
+	}
+	p.cancel()
+	return err
+}
+
+// OnDestroy 停止接收任务并等待队列中的任务执行完，由容器管理时线程池已经在关闭
+// 阶段被排空，这里不会再次等待。
+func (p *Pool) OnDestroy() {
+	p.Stop()
+	if err := p.Drain(context.Background()); err != nil {
+		log.Warn(err)
+	}
+}
+
+// Submit 提交异步任务，fn 的 ctx 保留了 ctx 中的值但是不受其取消的影响。队列
+// 已满时按照拒绝策略处理，拒绝策略为 abort 时返回 ErrRejected 。线程池关闭后
+// 返回 ErrClosed 。
+func (p *Pool) Submit(ctx context.Context, fn func(ctx context.Context)) error {
+
+	t := &task{
+		ctx:    taskContext{Context: p.ctx, values: ctx},
+		fn:     fn,
+		submit: time.Now(),
+	}
+
+	ok, err := p.offer(t)
+	if err != nil || ok {
+		return err
+	}
+
+	switch p.config.Rejection {
+	case CallerRuns:
+		p.record("caller_runs")
+		p.run(t)
+		return nil
+	case Discard:
+		p.record("discarded")
+		return nil
+	}
+	p.record("rejected")
+	return ErrRejected
+}
+
+// offer 将任务放入队列，队列已满并且拒绝策略为 discard-oldest 时丢弃队列中最早
+// 的任务后再次尝试。
+func (p *Pool) offer(t *task) (bool, error) {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return false, ErrClosed
+	}
+
+	select {
+	case p.queue <- t:
+		p.gauge(p.depth, 1)
+		return true, nil
+	default:
+	}
+
+	if p.config.Rejection != DiscardOldest {
+		return false, nil
+	}
+
+	select {
+	case <-p.queue:
+		p.gauge(p.depth, -1)
+		p.record("discarded")
+	default:
+	}
+
+	select {
+	case p.queue <- t:
+		p.gauge(p.depth, 1)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for t := range p.queue {
+		p.gauge(p.depth, -1)
+		if p.waiting != nil {
+			p.waiting.With(p.name).Since(t.submit)
+		}
+		p.run(t)
+	}
+}
+
+// run 执行任务，任务中的 panic 被记录而不会向外传播。
+func (p *Pool) run(t *task) {
+	p.gauge(p.active, 1)
+	start := time.Now()
+	outcome := "completed"
+	defer func() {
+		if r := recover(); r != nil {
+			outcome = "panic"
+			log.Errorf("executor %s: task panic: %v\n%s", p.name, r, debug.Stack())
+		}
+		p.gauge(p.active, -1)
+		p.record(outcome)
+		if p.duration != nil {
+			p.duration.With(p.name).Since(start)
+		}
+	}()
+	t.fn(t.ctx)
+}
+
+func (p *Pool) record(outcome string) {
+	if p.tasks != nil {
+		p.tasks.With(p.name, outcome).Inc()
+	}
+}
+
+func (p *Pool) gauge(g *metrics.GaugeVec, delta float64) {
+	if g != nil {
+		g.With(p.name).Add(delta)
+	}
+}
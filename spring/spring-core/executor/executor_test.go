@@ -0,0 +1,200 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/executor"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+func newPool(t *testing.T, config executor.Config) *executor.Pool {
+	p, err := executor.NewPool("test", config)
+	assert.Nil(t, err)
+	return p
+}
+
+func TestNewPool(t *testing.T) {
+	_, err := executor.NewPool("test", executor.Config{Workers: -1, Rejection: executor.Abort})
+	assert.Error(t, err, "executor test: workers must not be negative")
+	_, err = executor.NewPool("test", executor.Config{Workers: 0, Rejection: executor.Abort})
+	assert.Nil(t, err)
+	_, err = executor.NewPool("test", executor.Config{Workers: 1, Rejection: "block"})
+	assert.Error(t, err, "executor test: unknown rejection policy \"block\"")
+}
+
+func TestPool_Submit(t *testing.T) {
+
+	p := newPool(t, executor.Config{Workers: 2, QueueSize: 10, Rejection: executor.Abort, DrainTimeout: time.Second})
+	p.Metrics = metrics.NewRegistry()
+	p.OnInit()
+
+	ctx, cancel := context.WithCancel(knife.New(context.Background()))
+	knife.Set(ctx, "trace", "abc")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	err := p.Submit(ctx, func(ctx context.Context) {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		// 提交任务的 ctx 被取消后任务仍然可以读取其中的值
+		assert.Nil(t, ctx.Err())
+		assert.Equal(t, knife.Get(ctx, "trace"), "abc")
+	})
+	assert.Nil(t, err)
+	cancel()
+
+	wg.Add(1)
+	err = p.Submit(context.Background(), func(ctx context.Context) {
+		defer wg.Done()
+		panic("boom")
+	})
+	assert.Nil(t, err)
+	wg.Wait()
+
+	p.OnDestroy()
+	assert.Equal(t, p.Submit(context.Background(), func(ctx context.Context) {}), executor.ErrClosed)
+
+	var completed, panics float64
+	for _, f := range p.Metrics.Gather() {
+		if f.Name != "executor_tasks_total" {
+			continue
+		}
+		for _, s := range f.Samples {
+			switch s.Values[1] {
+			case "completed":
+				completed = s.Value
+			case "panic":
+				panics = s.Value
+			}
+		}
+	}
+	assert.Equal(t, completed, float64(1))
+	assert.Equal(t, panics, float64(1))
+}
+
+func TestPool_Rejection(t *testing.T) {
+
+	block := func(p *executor.Pool) chan struct{} {
+		ch := make(chan struct{})
+		started := make(chan struct{})
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {
+			close(started)
+			<-ch
+		}))
+		<-started
+		return ch
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 1, Rejection: executor.Abort, DrainTimeout: time.Second})
+		p.OnInit()
+		ch := block(p)
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {}))
+		assert.Equal(t, p.Submit(context.Background(), func(ctx context.Context) {}), executor.ErrRejected)
+		close(ch)
+		p.OnDestroy()
+	})
+
+	t.Run("caller runs", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 1, Rejection: executor.CallerRuns, DrainTimeout: time.Second})
+		p.OnInit()
+		ch := block(p)
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {}))
+		ran := false
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) { ran = true }))
+		assert.True(t, ran)
+		close(ch)
+		p.OnDestroy()
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 1, Rejection: executor.Discard, DrainTimeout: time.Second})
+		p.OnInit()
+		ch := block(p)
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {}))
+		var n int32
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) { atomic.AddInt32(&n, 1) }))
+		close(ch)
+		p.OnDestroy()
+		assert.Equal(t, atomic.LoadInt32(&n), int32(0))
+	})
+
+	t.Run("discard oldest", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 1, Rejection: executor.DiscardOldest, DrainTimeout: time.Second})
+		p.OnInit()
+		ch := block(p)
+		var s []string
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) { s = append(s, "a") }))
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) { s = append(s, "b") }))
+		close(ch)
+		p.OnDestroy()
+		assert.Equal(t, s, []string{"b"})
+	})
+}
+
+func TestPool_Drain(t *testing.T) {
+
+	t.Run("drained", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 10, Rejection: executor.Abort, DrainTimeout: time.Second})
+		p.OnInit()
+		var n int32
+		for i := 0; i < 5; i++ {
+			assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&n, 1)
+			}))
+		}
+		p.OnDestroy()
+		assert.Equal(t, atomic.LoadInt32(&n), int32(5))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 10, Rejection: executor.Abort, DrainTimeout: 10 * time.Millisecond})
+		p.OnInit()
+		done := make(chan error, 1)
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) {
+			<-ctx.Done()
+			done <- ctx.Err()
+		}))
+		p.OnDestroy()
+		assert.Equal(t, <-done, context.Canceled)
+	})
+
+	t.Run("phase", func(t *testing.T) {
+		p := newPool(t, executor.Config{Workers: 1, QueueSize: 10, Rejection: executor.Abort, DrainTimeout: time.Second})
+		p.OnInit()
+		ch := make(chan struct{})
+		assert.Nil(t, p.Submit(context.Background(), func(ctx context.Context) { <-ch }))
+		p.Stop()
+		assert.Equal(t, p.Submit(context.Background(), func(ctx context.Context) {}), executor.ErrClosed)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Error(t, p.Drain(ctx), "executor test: drain canceled")
+		close(ch)
+		start := time.Now()
+		p.OnDestroy() // 已经排空过的线程池不会再次等待
+		assert.True(t, time.Since(start) < 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,760 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package expr 提供了用于条件和属性绑定的简单表达式语言，支持算术运算、比较运
+// 算、逻辑运算、三元运算以及属性和 bean 的引用，例如：
+//
+//	${server.port:=8080} + 1
+//	${cache.size} * 2 > 100 && @redisClient
+//	${spring.profiles.active} == 'prod' ? 'release' : 'debug'
+//	@config.Timeout
+//
+// ${key:=def} 引用属性的值，属性的值在参与算术和比较运算时如果是数字形式的字
+// 符串则按照数字处理。@name 引用名称为 name 的 bean ，可以通过 .Field 访问结构
+// 体的导出字段，在逻辑运算中 bean 存在时为 true 。
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Env 表达式的执行环境，提供属性和 bean 的引用。
+type Env interface {
+
+	// Prop 返回 key 对应的属性值，属性不存在时返回 false 。
+	Prop(key string) (string, bool)
+
+	// Bean 返回名称为 name 的 bean ，bean 不存在时返回 false 。
+	Bean(name string) (interface{}, bool, error)
+}
+
+// Expr 解析后的表达式。
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse 解析表达式。
+func Parse(s string) (*Expr, error) {
+	p := &parser{lexer: lexer{src: s}}
+	if err := p.next(); err != nil {
+		return nil, p.wrap(err)
+	}
+	root, err := p.parseTernary()
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.wrap(fmt.Errorf("unexpected %q", p.tok.text))
+	}
+	return &Expr{src: s, root: root}, nil
+}
+
+// String 返回表达式的源码。
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval 在执行环境 env 中计算表达式的值，结果可能是 int64、float64、string、
+// bool、nil 或者 bean 及其字段的值。
+func (e *Expr) Eval(env Env) (interface{}, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// Eval 解析并计算表达式。
+func Eval(s string, env Env) (interface{}, error) {
+	e, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(env)
+}
+
+// EvalBool 解析并计算结果为 bool 的表达式，例如条件表达式。
+func EvalBool(s string, env Env) (bool, error) {
+	v, err := Eval(s, env)
+	if err != nil {
+		return false, err
+	}
+	return Truth(v)
+}
+
+// Truth 返回 v 在逻辑运算中的值。nil 为 false ，bool 为其本身，字符串为 true
+// 或者 false 时按照 bool 处理，数字不为 0 时为 true ，其他类型 (例如 bean) 为
+// true 。
+func Truth(v interface{}) (bool, error) {
+	switch x := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return x, nil
+	case int64:
+		return x != 0, nil
+	case float64:
+		return x != 0, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, fmt.Errorf("can't use %q as bool", x)
+		}
+		return b, nil
+	}
+	return true, nil
+}
+
+/******************************** lexer ********************************/
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokProp
+	tokBean
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "(", ")", "?", ":", ".",
+}
+
+func isIdentChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+func (l *lexer) scan() (token, error) {
+
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+
+	start := l.pos
+	if start >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[start]
+	switch {
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], pos: start}, nil
+
+	case c == '\'' || c == '"':
+		var sb strings.Builder
+		for l.pos++; l.pos < len(l.src); l.pos++ {
+			ch := l.src[l.pos]
+			if ch == c {
+				l.pos++
+				return token{kind: tokString, text: sb.String(), pos: start}, nil
+			}
+			if ch == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+				ch = l.src[l.pos]
+			}
+			sb.WriteByte(ch)
+		}
+		return token{}, fmt.Errorf("unterminated string at %d", start)
+
+	case c == '$' && strings.HasPrefix(l.src[start:], "${"):
+		depth := 0
+		for ; l.pos < len(l.src); l.pos++ {
+			switch l.src[l.pos] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					l.pos++
+					return token{kind: tokProp, text: l.src[start+2 : l.pos-1], pos: start}, nil
+				}
+			}
+		}
+		return token{}, fmt.Errorf("unterminated property reference at %d", start)
+
+	case c == '@':
+		l.pos++
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos], false) || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if l.pos == start+1 {
+			return token{}, fmt.Errorf("missing bean name at %d", start)
+		}
+		return token{kind: tokBean, text: l.src[start+1 : l.pos], pos: start}, nil
+
+	case isIdentChar(c, true):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos], false) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[start:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+/******************************** parser *******************************/
+
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) wrap(err error) error {
+	return fmt.Errorf("expr %q: %w", p.src, err)
+}
+
+func (p *parser) next() error {
+	tok, err := p.scan()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		if p.tok.kind == tokEOF {
+			return fmt.Errorf("expect %q but got end of expression", op)
+		}
+		return fmt.Errorf("expect %q but got %q at %d", op, p.tok.text, p.tok.pos)
+	}
+	return p.next()
+}
+
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil || !p.isOp("?") {
+		return cond, err
+	}
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond: cond, then: then, els: els}, nil
+}
+
+// precedences 二元运算符的优先级，值越大优先级越高。
+var precedences = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedences) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(precedences[level]...) {
+		op := p.tok.text
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(".") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokIdent {
+			return nil, fmt.Errorf("expect field name at %d", p.tok.pos)
+		}
+		x = &fieldNode{x: x, name: p.tok.text}
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+
+	tok := p.tok
+	switch tok.kind {
+	case tokEOF:
+		return nil, errors.New("unexpected end of expression")
+	case tokNumber:
+		v, err := parseNumber(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", tok.text, tok.pos)
+		}
+		return &literalNode{v: v}, p.next()
+	case tokString:
+		return &literalNode{v: tok.text}, p.next()
+	case tokProp:
+		key, def, hasDef := tok.text, "", false
+		if i := strings.Index(key, ":="); i >= 0 {
+			key, def, hasDef = key[:i], key[i+2:], true
+		}
+		return &propNode{key: key, def: def, hasDef: hasDef}, p.next()
+	case tokBean:
+		return &beanNode{name: tok.text}, p.next()
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{v: true}, p.next()
+		case "false":
+			return &literalNode{v: false}, p.next()
+		case "nil":
+			return &literalNode{v: nil}, p.next()
+		}
+		return nil, fmt.Errorf("unknown identifier %q at %d", tok.text, tok.pos)
+	}
+
+	if p.isOp("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+}
+
+func parseNumber(s string) (interface{}, error) {
+	if strings.Contains(s, ".") {
+		return strconv.ParseFloat(s, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+/********************************* eval ********************************/
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct{ v interface{} }
+
+func (n *literalNode) eval(env Env) (interface{}, error) {
+	return n.v, nil
+}
+
+type propNode struct {
+	key    string
+	def    string
+	hasDef bool
+}
+
+func (n *propNode) eval(env Env) (interface{}, error) {
+	if v, ok := env.Prop(n.key); ok {
+		return v, nil
+	}
+	if n.hasDef {
+		return n.def, nil
+	}
+	return nil, fmt.Errorf("property %q not exist", n.key)
+}
+
+type beanNode struct{ name string }
+
+func (n *beanNode) eval(env Env) (interface{}, error) {
+	b, ok, err := env.Bean(n.name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return b, nil
+}
+
+type fieldNode struct {
+	x    node
+	name string
+}
+
+func (n *fieldNode) eval(env Env) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("can't access field %s of nil", n.name)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't access field %s of %s", n.name, v.Type())
+	}
+	f := v.FieldByName(n.name)
+	if !f.IsValid() || !f.CanInterface() {
+		return nil, fmt.Errorf("%s has no exported field %s", v.Type(), n.name)
+	}
+	return normalize(f.Interface()), nil
+}
+
+// normalize 将整数和浮点数统一转换为 int64 和 float64 类型，实现了 String 方法
+// 的整数类型 (例如 time.Duration) 保持原样。
+func normalize(x interface{}) interface{} {
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, ok := x.(interface{ String() string }); !ok {
+			return v.Int()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return x
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, err := Truth(x)
+		return !b, err
+	}
+	switch v := toNumber(x).(type) {
+	case int64:
+		return -v, nil
+	case float64:
+		return -v, nil
+	}
+	return nil, fmt.Errorf("can't negate %v", x)
+}
+
+type ternaryNode struct {
+	cond, then, els node
+}
+
+func (n *ternaryNode) eval(env Env) (interface{}, error) {
+	c, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Truth(c)
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return n.then.eval(env)
+	}
+	return n.els.eval(env)
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// 逻辑运算短路求值
+	if n.op == "&&" || n.op == "||" {
+		b, err := Truth(l)
+		if err != nil {
+			return nil, err
+		}
+		if b == (n.op == "||") {
+			return b, nil
+		}
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return Truth(r)
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		eq := equal(l, r)
+		return eq == (n.op == "=="), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	return arithmetic(n.op, l, r)
+}
+
+// toNumber 将数字和数字形式的字符串转换为 int64 或者 float64 ，否则原样返回。
+func toNumber(x interface{}) interface{} {
+	switch v := x.(type) {
+	case int64, float64:
+		return v
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return x
+}
+
+// numbers 返回 l 和 r 是否都是数字，其中一个是浮点数时都转换为浮点数。
+func numbers(l, r interface{}) (interface{}, interface{}, bool) {
+	l, r = toNumber(l), toNumber(r)
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		return li, ri, true
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if lok && rok {
+		return lf, rf, true
+	}
+	return l, r, false
+}
+
+func toFloat(x interface{}) (float64, bool) {
+	switch v := x.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func equal(l, r interface{}) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if ln, rn, ok := numbers(l, r); ok {
+		return ln == rn
+	}
+	lb, lok := l.(bool)
+	rb, rok := r.(bool)
+	if lok || rok {
+		if !lok {
+			b, err := Truth(l)
+			return err == nil && b == rb
+		}
+		if !rok {
+			b, err := Truth(r)
+			return err == nil && b == lb
+		}
+		return lb == rb
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return ls == rs
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func compare(l, r interface{}) (int, error) {
+	if ln, rn, ok := numbers(l, r); ok {
+		switch lv := ln.(type) {
+		case int64:
+			rv := rn.(int64)
+			return cmp(lv < rv, lv > rv), nil
+		case float64:
+			rv := rn.(float64)
+			return cmp(lv < rv, lv > rv), nil
+		}
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), nil
+	}
+	return 0, fmt.Errorf("can't compare %v and %v", l, r)
+}
+
+func cmp(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+
+	ln, rn, ok := numbers(l, r)
+	if !ok {
+		_, lok := l.(string)
+		_, rok := r.(string)
+		if op == "+" && (lok || rok) {
+			return fmt.Sprint(nilToEmpty(l)) + fmt.Sprint(nilToEmpty(r)), nil
+		}
+		return nil, fmt.Errorf("can't apply %s to %v and %v", op, l, r)
+	}
+
+	if li, ok := ln.(int64); ok {
+		ri := rn.(int64)
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, errors.New("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, rf := ln.(float64), rn.(float64)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return lf / rf, nil
+	}
+	return math.Mod(lf, rf), nil
+}
+
+func nilToEmpty(x interface{}) interface{} {
+	if x == nil {
+		return ""
+	}
+	return x
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expr_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/expr"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type server struct {
+	Port    int
+	Timeout time.Duration
+	Name    string
+}
+
+type env map[string]string
+
+func (e env) Prop(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func (e env) Bean(name string) (interface{}, bool, error) {
+	switch name {
+	case "server":
+		return &server{Port: 8080, Timeout: time.Second, Name: "order"}, true, nil
+	case "broken":
+		return nil, false, errors.New("broken bean")
+	}
+	return nil, false, nil
+}
+
+func TestEval(t *testing.T) {
+
+	e := env{
+		"server.port": "8080",
+		"ratio":       "0.5",
+		"profile":     "prod",
+		"enabled":     "true",
+	}
+
+	testcases := []struct {
+		expr   string
+		result interface{}
+	}{
+		{"1 + 2 * 3", int64(7)},
+		{"(1 + 2) * 3", int64(9)},
+		{"7 / 2", int64(3)},
+		{"7 % 4", int64(3)},
+		{"7 / 2.0", 3.5},
+		{"-2 * -3", int64(6)},
+		{"${server.port} + 1", int64(8081)},
+		{"${server.port:=80} == 8080", true},
+		{"${missing:=80} * 2", int64(160)},
+		{"${ratio} * 4", 2.0},
+		{"${profile} == 'prod'", true},
+		{`${profile} != "prod"`, false},
+		{"'a' < 'b'", true},
+		{"3 >= 3.0", true},
+		{"'v' + 1", "v1"},
+		{"${enabled} && 1 < 2", true},
+		{"!${enabled} || false", false},
+		{"${enabled} == true", true},
+		{"${profile} == 'prod' ? 'release' : 'debug'", "release"},
+		{"false ? 1 : true ? 2 : 3", int64(2)},
+		{"@server.Port * 2", int64(16160)},
+		{"@server.Timeout", time.Second},
+		{"@server.Name + '-svc'", "order-svc"},
+		{"@server && !@missing", true},
+		{"@missing == nil", true},
+		{"'it\\'s'", "it's"},
+	}
+
+	for _, c := range testcases {
+		v, err := expr.Eval(c.expr, e)
+		assert.Nil(t, err)
+		assert.Equal(t, v, c.result)
+	}
+}
+
+func TestEval_Error(t *testing.T) {
+
+	testcases := []struct {
+		expr string
+		err  string
+	}{
+		{"1 +", "expr \"1 \\+\": unexpected end of expression"},
+		{"(1 + 2", "expr \"\\(1 \\+ 2\": expect \"\\)\" but got end of expression"},
+		{"1 2", "expr \"1 2\": unexpected \"2\""},
+		{"abc", "unknown identifier \"abc\" at 0"},
+		{"${a", "unterminated property reference at 0"},
+		{"'a", "unterminated string at 0"},
+		{"1 # 2", "unexpected character '#' at 2"},
+		{"${a} + 1", "property \"a\" not exist"},
+		{"1 / 0", "division by zero"},
+		{"'a' - 1", "can't apply - to a and 1"},
+		{"'a' && true", "can't use \"a\" as bool"},
+		{"@server.Missing", "expr_test.server has no exported field Missing"},
+		{"@broken", "broken bean"},
+		{"true < 1", "can't compare true and 1"},
+	}
+
+	for _, c := range testcases {
+		_, err := expr.Eval(c.expr, env{})
+		assert.Error(t, err, c.err)
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	ok, err := expr.EvalBool("${a:=0} > 0 || @server", env{})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = expr.EvalBool("${a:=0}", env{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
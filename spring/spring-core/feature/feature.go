@@ -0,0 +1,224 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package feature 提供了功能开关，开关的值由 Provider 提供，例如 Unleash 或者
+// OpenFeature ，Provider 中不存在的开关使用 feature.flags 下的属性值。
+package feature
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-stl/cast"
+)
+
+// Target 计算开关值时使用的目标，例如当前用户，用于灰度发布等场景。
+type Target struct {
+	Key        string            // 目标的唯一标识，例如用户 ID
+	Attributes map[string]string // 目标的其他属性，例如地域、IP 地址
+}
+
+type targetKey struct{}
+
+// WithTarget 返回保存了目标的 ctx 。
+func WithTarget(ctx context.Context, t Target) context.Context {
+	return context.WithValue(ctx, targetKey{}, t)
+}
+
+// TargetOf 返回 ctx 中保存的目标。
+func TargetOf(ctx context.Context) Target {
+	t, _ := ctx.Value(targetKey{}).(Target)
+	return t
+}
+
+// Provider 开关值的提供者。
+type Provider interface {
+
+	// Evaluate 返回开关 flag 对于目标 t 的值，def 是调用方使用的默认值，其类型
+	// 表示期望的值类型，开关不存在时 ok 为 false 。
+	Evaluate(ctx context.Context, flag string, def interface{}, t Target) (value interface{}, ok bool, err error)
+}
+
+// Refresher 可以由 Provider 实现的可选接口，定时从远端拉取开关的最新配置。
+type Refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// Store 持久化开关在运行时覆盖的值，例如写回 feature.flags.{flag} 属性，使修
+// 改在应用重启后仍然生效。value 为 nil 表示删除该开关的值。
+type Store interface {
+	SaveFlag(flag string, value interface{}) error
+}
+
+// Config 功能开关配置，通常绑定到 feature 前缀的属性上。
+type Config struct {
+	Flags           map[string]string `value:"${flags}"`                 // 开关的默认值
+	RefreshInterval time.Duration     `value:"${refresh-interval:=30s}"` // Provider 的刷新间隔
+}
+
+// Flags 功能开关，依次从运行时覆盖的值、Provider 以及属性中获取开关的值。
+type Flags struct {
+	Provider Provider `autowire:"?"`
+	Store    Store    `autowire:"?"`
+
+	config    *Config // 使用指针避免容器对其进行属性绑定
+	overrides sync.Map
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewFlags Flags 的构造函数。
+func NewFlags(config Config) *Flags {
+	return &Flags{config: &config}
+}
+
+// OnInit Provider 实现了 Refresher 接口时先刷新一次，然后启动定时刷新的后台
+// 协程。首次刷新失败不影响应用启动，开关使用属性中的值。
+func (f *Flags) OnInit() {
+
+	r, ok := f.Provider.(Refresher)
+	if !ok || f.config.RefreshInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+
+	if err := r.Refresh(ctx); err != nil {
+		log.Errorf("feature flags refresh error: %v", err)
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.config.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := r.Refresh(ctx); err != nil {
+				log.Errorf("feature flags refresh error: %v", err)
+			}
+		}
+	}()
+}
+
+// OnDestroy 停止定时刷新的后台协程。
+func (f *Flags) OnDestroy() {
+	if f.cancel != nil {
+		f.cancel()
+		f.wg.Wait()
+	}
+}
+
+// Set 在运行时覆盖开关 flag 的值，优先级高于 Provider 和属性。
+func (f *Flags) Set(flag string, value interface{}) {
+	f.overrides.Store(flag, value)
+}
+
+// Reset 删除开关 flag 在运行时覆盖的值。
+func (f *Flags) Reset(flag string) {
+	f.overrides.Delete(flag)
+}
+
+// Save 在运行时覆盖开关 flag 的值并通过 Store 持久化，value 为 nil 时删除覆盖
+// 的值。持久化失败时不修改开关的值，没有 Store 时与 Set 和 Reset 相同。
+func (f *Flags) Save(flag string, value interface{}) error {
+	if f.Store != nil {
+		if err := f.Store.SaveFlag(flag, value); err != nil {
+			return err
+		}
+	}
+	if value == nil {
+		f.Reset(flag)
+	} else {
+		f.Set(flag, value)
+	}
+	return nil
+}
+
+// Value 返回开关 flag 的值，开关不存在时 ok 为 false 。
+func (f *Flags) Value(ctx context.Context, flag string, def interface{}) (value interface{}, ok bool) {
+
+	if v, ok := f.overrides.Load(flag); ok {
+		return v, true
+	}
+
+	if f.Provider != nil {
+		v, ok, err := f.Provider.Evaluate(ctx, flag, def, TargetOf(ctx))
+		if err != nil {
+			log.Ctx(ctx).Warnf("feature flag %s evaluate error: %v", flag, err)
+		} else if ok {
+			return v, true
+		}
+	}
+
+	if v, ok := f.config.Flags[flag]; ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// Enabled 返回开关 flag 是否打开，开关不存在时返回 false 。
+func (f *Flags) Enabled(ctx context.Context, flag string) bool {
+	return f.Bool(ctx, flag, false)
+}
+
+// Bool 返回 bool 类型的开关值，开关不存在或者类型不匹配时返回 def 。
+func (f *Flags) Bool(ctx context.Context, flag string, def bool) bool {
+	if v, ok := f.Value(ctx, flag, def); ok {
+		if b, err := cast.ToBoolE(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// String 返回 string 类型的开关值，开关不存在时返回 def 。
+func (f *Flags) String(ctx context.Context, flag string, def string) string {
+	if v, ok := f.Value(ctx, flag, def); ok {
+		if s, err := cast.ToStringE(v); err == nil {
+			return s
+		}
+	}
+	return def
+}
+
+// Int 返回 int64 类型的开关值，开关不存在或者类型不匹配时返回 def 。
+func (f *Flags) Int(ctx context.Context, flag string, def int64) int64 {
+	if v, ok := f.Value(ctx, flag, def); ok {
+		if i, err := cast.ToInt64E(v); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// Float 返回 float64 类型的开关值，开关不存在或者类型不匹配时返回 def 。
+func (f *Flags) Float(ctx context.Context, flag string, def float64) float64 {
+	if v, ok := f.Value(ctx, flag, def); ok {
+		if x, err := cast.ToFloat64E(v); err == nil {
+			return x
+		}
+	}
+	return def
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package feature_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/feature"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type provider struct {
+	flags     map[string]interface{}
+	refreshed int
+}
+
+func (p *provider) Evaluate(ctx context.Context, flag string, def interface{}, t feature.Target) (interface{}, bool, error) {
+	if flag == "broken" {
+		return nil, false, errors.New("broken")
+	}
+	if flag == "beta" {
+		return t.Key == "alice", true, nil
+	}
+	v, ok := p.flags[flag]
+	return v, ok, nil
+}
+
+func (p *provider) Refresh(ctx context.Context) error {
+	p.refreshed++
+	return nil
+}
+
+func TestFlags(t *testing.T) {
+
+	f := feature.NewFlags(feature.Config{
+		Flags: map[string]string{
+			"new-checkout": "true",
+			"page-size":    "20",
+			"ratio":        "0.5",
+			"theme":        "dark",
+			"broken":       "true",
+		},
+	})
+
+	ctx := context.Background()
+	assert.True(t, f.Enabled(ctx, "new-checkout"))
+	assert.False(t, f.Enabled(ctx, "missing"))
+	assert.True(t, f.Enabled(ctx, "broken"))
+	assert.Equal(t, f.Int(ctx, "page-size", 10), int64(20))
+	assert.Equal(t, f.Int(ctx, "theme", 10), int64(10))
+	assert.Equal(t, f.Float(ctx, "ratio", 0), 0.5)
+	assert.Equal(t, f.String(ctx, "theme", "light"), "dark")
+
+	// Provider 的值优先于属性
+	f.Provider = &provider{flags: map[string]interface{}{"new-checkout": false}}
+	assert.False(t, f.Enabled(ctx, "new-checkout"))
+	assert.Equal(t, f.Int(ctx, "page-size", 10), int64(20))
+	assert.True(t, f.Enabled(ctx, "broken"))
+
+	assert.True(t, f.Enabled(feature.WithTarget(ctx, feature.Target{Key: "alice"}), "beta"))
+	assert.False(t, f.Enabled(feature.WithTarget(ctx, feature.Target{Key: "bob"}), "beta"))
+
+	// 运行时覆盖的值优先级最高
+	f.Set("new-checkout", true)
+	assert.True(t, f.Enabled(ctx, "new-checkout"))
+	f.Reset("new-checkout")
+	assert.False(t, f.Enabled(ctx, "new-checkout"))
+}
+
+func TestFlags_Refresh(t *testing.T) {
+
+	p := &provider{}
+	f := feature.NewFlags(feature.Config{RefreshInterval: 10 * time.Millisecond})
+	f.Provider = p
+	f.OnInit()
+	time.Sleep(35 * time.Millisecond)
+	f.OnDestroy()
+
+	n := p.refreshed
+	assert.True(t, n >= 2)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, p.refreshed, n)
+}
+
+type store map[string]interface{}
+
+func (s store) SaveFlag(flag string, value interface{}) error {
+	if flag == "readonly" {
+		return errors.New("readonly")
+	}
+	if value == nil {
+		delete(s, flag)
+	} else {
+		s[flag] = value
+	}
+	return nil
+}
+
+func TestFlags_Save(t *testing.T) {
+
+	ctx := context.Background()
+	s := store{}
+	f := feature.NewFlags(feature.Config{Flags: map[string]string{"beta": "false"}})
+	f.Store = s
+
+	assert.Nil(t, f.Save("beta", true))
+	assert.True(t, f.Enabled(ctx, "beta"))
+	assert.Equal(t, s, store{"beta": true})
+
+	assert.Nil(t, f.Save("beta", nil))
+	assert.False(t, f.Enabled(ctx, "beta"))
+	assert.Equal(t, s, store{})
+
+	assert.Error(t, f.Save("readonly", true), "readonly")
+	assert.False(t, f.Enabled(ctx, "readonly"))
+}
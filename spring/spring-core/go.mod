@@ -1,14 +1,23 @@
 module github.com/go-spring/spring-core
 
-go 1.14
+go 1.18
 
 require (
 	github.com/go-spring/spring-stl v1.1.0-alpha
 	github.com/magiconair/properties v1.8.1
 	github.com/pelletier/go-toml v1.2.0
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v2 v2.2.4
 )
 
+require (
+	github.com/spf13/cast v1.3.1 // indirect
+	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2 // indirect
+	golang.org/x/text v0.3.6 // indirect
+)
+
 //replace (
 //	github.com/go-spring/spring-stl => ../spring-stl
 //)
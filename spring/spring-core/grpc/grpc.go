@@ -20,3 +20,9 @@ type Server struct {
 	Register interface{} // 服务注册函数
 	Service  interface{} // 服务提供者
 }
+
+// Gateway gRPC-Gateway 的 HTTP 处理器，用于将 REST 请求转换为对同名 gRPC 服务
+// 的进程内调用。
+type Gateway struct {
+	Register interface{} // 网关注册函数，grpc-gateway 生成的 RegisterXxxHandlerServer 函数
+}
@@ -18,8 +18,10 @@ package gs
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
@@ -27,15 +29,49 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/go-spring/spring-core/actuator"
+	"github.com/go-spring/spring-core/bodylimit"
+	"github.com/go-spring/spring-core/bodylog"
+	"github.com/go-spring/spring-core/buildinfo"
+	"github.com/go-spring/spring-core/cache"
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/conf/kube"
+	"github.com/go-spring/spring-core/connection"
+	"github.com/go-spring/spring-core/correlation"
+	"github.com/go-spring/spring-core/deadline"
+	"github.com/go-spring/spring-core/dedup"
+	"github.com/go-spring/spring-core/delayqueue"
+	"github.com/go-spring/spring-core/events"
+	"github.com/go-spring/spring-core/executor"
+	"github.com/go-spring/spring-core/feature"
 	"github.com/go-spring/spring-core/grpc"
 	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/gs/cond"
 	"github.com/go-spring/spring-core/gs/environ"
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/lock"
 	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mapping"
+	"github.com/go-spring/spring-core/metrics"
 	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-core/overload"
+	"github.com/go-spring/spring-core/ratelimit"
+	"github.com/go-spring/spring-core/readiness"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-core/resilience"
+	"github.com/go-spring/spring-core/secrets"
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/security/authz"
+	"github.com/go-spring/spring-core/security/csrf"
+	"github.com/go-spring/spring-core/tenancy"
+	"github.com/go-spring/spring-core/tuning"
+	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-core/web/acme"
 	"github.com/go-spring/spring-stl/cast"
 	"github.com/go-spring/spring-stl/util"
 )
@@ -78,6 +114,10 @@ type App struct {
 	consumers *Consumers
 
 	exitChan chan struct{}
+	exitOnce sync.Once
+
+	// 属性的来源
+	origins map[string]string
 
 	// 属性列表解析完成后的回调
 	mapOfOnProperty map[string]interface{}
@@ -99,13 +139,20 @@ func (c *Consumers) ForEach(fn func(mq.Consumer)) {
 
 // NewApp application 的构造函数
 func NewApp() *App {
-	return &App{
+	app := &App{
 		c:               New(),
 		mapOfOnProperty: make(map[string]interface{}),
 		exitChan:        make(chan struct{}),
+		origins:         make(map[string]string),
 		router:          web.NewRouter(),
 		consumers:       new(Consumers),
 	}
+	app.c.onGoError = func(err error) {
+		if cast.ToBool(app.c.p.Get(environ.SpringShutdownOnGoroutineError)) {
+			app.ShutDown(fmt.Errorf("goroutine error: %w", err))
+		}
+	}
+	return app
 }
 
 // Banner 自定义 banner 字符串。
@@ -115,65 +162,280 @@ func (app *App) Banner(banner string) {
 
 func (app *App) Run() error {
 
-	// 响应控制台的 Ctrl+C 及 kill 命令。
-	go func() {
-		ch := make(chan os.Signal, 1)
-		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-		sig := <-ch
-		app.ShutDown(fmt.Errorf("signal %v", sig))
-	}()
-
 	if err := app.start(); err != nil {
 		return err
 	}
 
-	<-app.exitChan
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if s := cast.ToString(app.c.p.Get(environ.SpringShutdownSignals)); s != "" {
+		var err error
+		if signals, err = parseSignals(s); err != nil {
+			app.c.Close()
+			return err
+		}
+	}
+
+	// 响应控制台的 Ctrl+C 及 kill 命令。
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	defer signal.Stop(ch)
+
+	select {
+	case sig := <-ch:
+		app.ShutDown(fmt.Errorf("signal %v", sig))
+	case <-app.exitChan:
+	}
 
 	app.c.Close()
 	log.Info("application exited")
-	return nil
+	return app.c.Err()
+}
+
+// OnShutdown 注册程序关闭时执行的钩子函数。
+func (app *App) OnShutdown(fn func(ctx context.Context) error) *ShutdownHook {
+	return app.c.OnShutdown(fn)
 }
 
 func (app *App) start() error {
 
 	app.Object(app.router).Export(WebRouter)
 	app.Object(app.consumers)
-
+	app.Object(new(mq.Streams))
+	app.Object(events.Default)
+	app.Provide(mq.NewSchemaRegistry, "${mq.schema-registry}").
+		On(cond.OnProperty("mq.schema-registry.enabled", cond.HavingValue("true")))
+	app.Provide(mq.NewAvroCodec).
+		Name("avro").
+		On(cond.OnBean((*mq.SchemaRegistry)(nil)).OnBean((*mq.AvroSerde)(nil)))
+	app.Provide(delayqueue.New, "", "${mq.delay-queue}").
+		Export((*mq.DelayQueue)(nil)).
+		On(cond.OnBean((*sql.DB)(nil)).
+			OnProperty("mq.delay-queue.enabled", cond.HavingValue("true")))
+
+	app.Object(metrics.Default)
+	app.Object(new(metrics.Endpoint)).
+		On(cond.OnProperty("metrics.prometheus.enabled", cond.HavingValue("true")))
+	app.Object(metrics.NewWebFilter(metrics.Default)).
+		Export(WebFilter).
+		On(cond.OnProperty("metrics.web.enabled", cond.HavingValue("true")))
+	app.Provide(metrics.NewStatsD, "", "${metrics.export.statsd}").
+		Name("metrics-statsd").
+		On(cond.OnProperty("metrics.export.statsd.enabled", cond.HavingValue("true")))
+	app.Provide(metrics.NewOTLP, "", "${metrics.export.otlp}").
+		Name("metrics-otlp").
+		On(cond.OnProperty("metrics.export.otlp.enabled", cond.HavingValue("true")))
+
+	app.Provide(correlation.NewFilter, "${correlation}").
+		Export(WebFilter).
+		On(cond.OnProperty("correlation.enabled", cond.HavingValue("true")))
+	app.Provide(deadline.NewFilter, "${web.deadline}").
+		Export(WebFilter).
+		On(cond.OnProperty("web.deadline.enabled", cond.HavingValue("true")))
+	app.Provide(bodylimit.NewFilter, "${web.body-limit}").
+		Export(WebFilter).
+		On(cond.OnProperty("web.body-limit.enabled", cond.HavingValue("true")))
+	app.Provide(overload.NewFilter, "${web.overload}").
+		Export(WebFilter).
+		On(cond.OnProperty("web.overload.enabled", cond.HavingValue("true")))
+	app.Provide(bodylog.NewFilter, "${web.body-log}").
+		Export(WebFilter).
+		On(cond.OnProperty("web.body-log.enabled", cond.HavingValue("true")))
+	app.Provide(dedup.NewFilter, "${web.dedup}").
+		Export(WebFilter).
+		On(cond.OnProperty("web.dedup.enabled", cond.HavingValue("true")))
+	app.Provide(connection.NewRegistry, "${web.connections}").
+		Name("connections").
+		Export((*health.Indicator)(nil)).
+		On(cond.OnProperty("web.connections.enabled", cond.HavingValue("true")))
+
+	app.Provide(security.NewFilter, "${security}").
+		Export(WebFilter).
+		On(cond.OnProperty("security.enabled", cond.HavingValue("true")))
+	app.Provide(authz.NewFilter, "${security.authz}").
+		Export(WebFilter).
+		Order(1). // 授权需要在认证之后进行
+		On(cond.OnProperty("security.authz.enabled", cond.HavingValue("true")))
+	app.Provide(csrf.NewFilter, "${security.csrf}").
+		Export(WebFilter).
+		On(cond.OnProperty("security.csrf.enabled", cond.HavingValue("true")))
+	app.Provide(security.NewJWTProvider, "${security.jwt}").
+		Name("jwt").
+		Export((*security.AuthenticationProvider)(nil)).
+		On(cond.OnProperty("security.jwt.enabled", cond.HavingValue("true")))
+	app.Provide(security.NewAPIKeyProvider, "${security.api-key}").
+		Name("api-key").
+		Export((*security.AuthenticationProvider)(nil)).
+		On(cond.OnProperty("security.api-key.enabled", cond.HavingValue("true")))
+	app.Provide(security.NewBasicProvider, "${security.basic}").
+		Name("basic").
+		Export((*security.AuthenticationProvider)(nil)).
+		On(cond.OnProperty("security.basic.enabled", cond.HavingValue("true")))
+
+	app.Provide(tenancy.NewFilter, "${tenancy}").
+		Export(WebFilter).
+		Order(1). // 通过 claim 识别租户需要在认证之后进行
+		On(cond.OnProperty("tenancy.enabled", cond.HavingValue("true")))
+	app.Object(tenancy.NewOverlay(app.c.p)).
+		On(cond.OnProperty("tenancy.enabled", cond.HavingValue("true")))
+
+	app.Object(mapping.Default())
+	app.Provide(web.NewJSONCodec, "${web.json}").
+		On(cond.OnMatches(missingWebCodec))
+	app.Provide(web.NewTemplateEngine, "${web.template}").
+		Export((*web.TemplateEngine)(nil)).
+		On(cond.OnProperty("web.template.enabled", cond.HavingValue("true")))
+	app.Provide(acme.New, "${web.server.acme}").
+		On(cond.OnProperty("web.server.acme.enabled", cond.HavingValue("true")))
+
+	app.Provide(health.NewChecker, "${health}").Export((*mq.HealthProbe)(nil))
+	app.Object(new(health.Endpoint)).
+		On(cond.OnProperty("health.enabled", cond.HavingValue("true")))
+	app.Object(new(health.PingIndicator)).Name("ping").Export((*health.Indicator)(nil))
+	app.Provide(health.NewDBIndicator, "").Name("db").On(cond.OnBean((*sql.DB)(nil)))
+	app.Provide(health.NewRedisIndicator, "").Name("redis").On(cond.OnBean((*redis.Client)(nil)))
+	app.Provide(lock.NewRedisLocker, "").On(cond.OnBean((*redis.Client)(nil)))
+	app.Provide(redis.NewScripts, "").On(cond.OnBean((*redis.Client)(nil)))
+	app.Provide(redis.NewBloom, "", "${redis.bloom}").On(cond.OnBean((*redis.Client)(nil)))
+	app.Provide(ratelimit.NewSlidingWindow, "", "${ratelimit.sliding-window}").On(cond.OnBean((*redis.Scripts)(nil)))
+	app.Provide(ratelimit.NewTokenBucket, "", "${ratelimit.token-bucket}").On(cond.OnBean((*redis.Scripts)(nil)))
+	app.Provide(health.NewDiskIndicator, "${health.disk.path:=.}", "${health.disk.threshold:=10485760}").
+		Name("disk").
+		On(cond.OnProperty("health.disk.enabled", cond.HavingValue("true")))
+	app.Provide(readiness.NewGate, "${readiness}").
+		Name("warmup").
+		Export((*health.Indicator)(nil))
+
+	app.Object(cache.Default)
+	app.Provide(buildinfo.Read, "${spring.application.name:=}")
+	app.Provide(resilience.NewRegistry, "${resilience}")
+	app.Provide(feature.NewFlags, "${feature}")
+	app.Object(&propertyStore{p: app.c.p}).
+		Export((*actuator.LevelStore)(nil), (*feature.Store)(nil)).
+		On(cond.OnMissingBean((*actuator.LevelStore)(nil)).OnMissingBean((*feature.Store)(nil)))
+	app.Provide(tuning.New, "${runtime}").
+		Export((*actuator.Endpoint)(nil)).
+		On(cond.OnProperty("runtime.enabled", cond.HavingValue("true")))
+	app.Provide(executor.NewPool, arg.Value("executor"), "${executor}").
+		Name("executor").
+		DependsOn((*tuning.Tuner)(nil)). // 工作协程的数量可能依赖调整后的 GOMAXPROCS
+		On(cond.OnProperty("executor.enabled", cond.HavingValue("true")))
+
+	ins := &inspector{app: app}
+	app.Object(ins).Export((*actuator.Inspector)(nil))
+	app.Provide(actuator.NewServer, "${management}").
+		On(cond.OnProperty("management.enabled", cond.HavingValue("true")))
+
+	start := time.Now()
 	e := newEnvironment()
 	if err := e.prepare(); err != nil {
 		return err
 	}
+	app.c.step("prepare-environment", start)
+
+	// 启动相关的属性优先从环境变量和命令行参数获取，其次是通过代码设置的属性。
+	envGet := func(key string, def interface{}) interface{} {
+		return e.Get(key, conf.Def(app.c.p.Get(key, conf.Def(def))))
+	}
 
 	configLocations := func() []string {
-		s := e.Get(environ.SpringConfigLocations, conf.Def("config/"))
+		s := envGet(environ.SpringConfigLocations, "config/")
 		return strings.Split(cast.ToString(s), ",")
 	}()
 
-	showBanner := cast.ToBool(e.Get(environ.SpringBannerVisible))
+	showBanner := cast.ToBool(envGet(environ.SpringBannerVisible, nil))
 	if showBanner {
-		PrintBanner(app.getBanner(configLocations))
+		bannerText := cast.ToString(envGet(environ.SpringBannerText, nil))
+		bannerFile := cast.ToString(envGet(environ.SpringBannerLocation, nil))
+		PrintBanner(app.getBanner(bannerText, bannerFile, configLocations))
 	}
 
 	configExtensions := func() []string {
 		extensions := ".properties,.prop,.yaml,.yml,.toml,.tml"
-		s := e.Get(environ.SpringConfigExtensions, conf.Def(extensions))
+		s := envGet(environ.SpringConfigExtensions, extensions)
 		return strings.Split(cast.ToString(s), ",")
 	}()
 
-	profile := cast.ToString(e.Get(environ.SpringProfilesActive))
+	configStart := time.Now()
+	profile := cast.ToString(envGet(environ.SpringProfilesActive, nil))
 	p, err := app.profile(configLocations, configExtensions, profile)
 	if err != nil {
 		return err
 	}
+	app.c.step("load-config", configStart)
+
+	// 通过代码设置的属性
+	for _, k := range app.c.p.Keys() {
+		if _, ok := app.origins[k]; !ok {
+			app.origins[k] = OriginCode
+		}
+	}
 
 	// 保存从配置文件加载的属性
-	for _, k := range p.Keys() {
-		app.c.p.Set(k, p.Get(k))
+	app.c.p.Merge(p)
+
+	// 加载保存运行时修改的覆盖文件，其中的属性优先于配置文件中的属性
+	if file := cast.ToString(envGet(environ.SpringConfigWritableLocation, nil)); file != "" {
+		w, err := conf.NewFileWriter(file)
+		if err != nil {
+			return err
+		}
+		f := conf.New()
+		if err = f.Load(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		app.c.p.Merge(f)
+		for _, k := range f.Keys() {
+			app.origins[k] = file
+		}
+		app.c.p.SetWriter(w)
 	}
 
 	// 保存从环境变量和命令行解析的属性
+	app.c.p.Merge(e.p)
 	for _, k := range e.p.Keys() {
-		app.c.p.Set(k, e.p.Get(k))
+		app.origins[k] = e.origins[k]
+	}
+
+	if err = app.setLogLevels(); err != nil {
+		return err
+	}
+
+	appName := cast.ToString(app.c.p.Get(environ.SpringApplicationName))
+	log.Infof("build info: %s", buildinfo.Read(appName))
+
+	// 加载 Kubernetes 挂载的 ConfigMap 和 Secret，挂载内容变化时刷新动态属性
+	if cast.ToBool(app.c.p.Get("kube.config.enabled")) {
+		if err = app.watchKubeConfig(e.p); err != nil {
+			return err
+		}
+	}
+
+	// 统计 Web 服务器的每个监听器接受的连接数
+	if cast.ToBool(app.c.p.Get("metrics.web.enabled")) {
+		web.OnAccept = metrics.NewAcceptCounter(metrics.Default)
+	}
+
+	// 在属性绑定之前注册密钥引用的解析器
+	if cast.ToBool(app.c.p.Get("secrets.enabled")) {
+		store, err := secrets.Setup(app.c.p)
+		if err != nil {
+			return err
+		}
+		app.Object(store).Destroy(func(*secrets.Store) {
+			conf.NewResolver(nil, secrets.Scheme) // 解析器是进程级的，应用退出时取消注册
+		})
+		app.c.Go(store.Run)
+	}
+
+	// 在属性绑定之前初始化参数校验器，用户通过 validator.Init 设置的除外
+	if _, ok := validator.Default().(*validator.Engine); ok || validator.Default() == nil {
+		var config validator.Config
+		if err = app.c.p.Bind(&config, conf.Key("validator")); err != nil {
+			return err
+		}
+		engine := validator.NewEngine(config)
+		validator.Init(engine)
+		app.Object(engine)
 	}
 
 	for key, f := range app.mapOfOnProperty {
@@ -189,9 +451,35 @@ func (app *App) start() error {
 	if err = app.c.refresh(); err != nil {
 		return err
 	}
+	ins.snapshot()
 
 	ctx := &pandora{app.c}
 
+	// 用户注册的 JSON 编解码器优先于按照 web.json 配置创建的编解码器
+	var codec web.Codec
+	if err = ctx.Get(&codec); err != nil {
+		return err
+	}
+	web.SetJSONCodec(codec)
+
+	// 使用容器中的模板引擎渲染视图，例如通过 embed.FS 加载模板的引擎
+	var engines []web.TemplateEngine
+	if err = ctx.Get(&engines); err != nil {
+		return err
+	}
+	if len(engines) > 0 {
+		web.SetTemplateEngine(engines[0])
+	}
+
+	// 容器中的可写属性源 (例如远程配置中心) 优先于本地的覆盖文件
+	var writers []conf.Writer
+	if err = ctx.Get(&writers); err != nil {
+		return err
+	}
+	if len(writers) > 0 {
+		app.c.p.SetWriter(writers[0])
+	}
+
 	// TODO 增加根据配置获取。
 	var runners []appRunner
 	if err = ctx.Get(&runners); err != nil {
@@ -199,9 +487,11 @@ func (app *App) start() error {
 	}
 
 	// 执行命令行启动器
+	runStart := time.Now()
 	for _, r := range runners {
 		r.Run(ctx)
 	}
+	app.c.step("run-runners", runStart)
 
 	// TODO 增加根据配置获取。
 	var events []appEvent
@@ -210,9 +500,18 @@ func (app *App) start() error {
 	}
 
 	// 通知应用启动事件
+	eventStart := time.Now()
 	for _, e := range events {
 		e.OnStartApp(ctx)
 	}
+	app.c.step("start-app-events", eventStart)
+
+	// 应用启动之后执行预热任务，预热结束之前应用不会就绪
+	var gate *readiness.Gate
+	if err = ctx.Get(&gate); err != nil {
+		return err
+	}
+	app.c.Go(gate.Run)
 
 	// 通知应用停止事件
 	app.Go(func(c context.Context) {
@@ -228,14 +527,41 @@ func (app *App) start() error {
 		app.c.clearCache()
 	}
 
-	log.Info("application started successfully")
+	log.Infof("application started successfully in %s", time.Since(start))
 	return err
 }
 
-func (app *App) getBanner(configLocations []string) string {
+// missingWebCodec 返回容器中是否没有用户注册的 JSON 编解码器。消息编解码器的
+// 方法集包含 web.Codec 的方法，但是不能作为 JSON 编解码器使用，所以需要排除。
+func missingWebCodec(ctx cond.Context) (bool, error) {
+	beans, err := ctx.Find((*web.Codec)(nil))
+	if err != nil {
+		return false, err
+	}
+	mqCodec := reflect.TypeOf((*mq.Codec)(nil)).Elem()
+	for _, b := range beans {
+		if !b.Type().AssignableTo(mqCodec) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getBanner 按照代码设置、 banner 属性、 banner 文件、配置目录下的 banner.txt
+// 文件的顺序获取 banner 字符串，都没有时返回默认的 banner 。
+func (app *App) getBanner(text string, file string, configLocations []string) string {
 	if app.banner != "" {
 		return app.banner
 	}
+	if text != "" {
+		return text
+	}
+	if file != "" {
+		if b, err := ioutil.ReadFile(file); err == nil {
+			return string(b)
+		}
+		log.Warnf("can't read banner file %q", file)
+	}
 	for _, configLocation := range configLocations {
 		file := path.Join(configLocation, "banner.txt")
 		if b, err := ioutil.ReadFile(file); err == nil {
@@ -260,6 +586,48 @@ func (app *App) profile(locations []string, extensions []string, profile string)
 	return p, nil
 }
 
+// watchKubeConfig 加载 Kubernetes 挂载目录中的属性并监视其变化，挂载目录中的
+// 属性优先于配置文件，但是不会覆盖 env 中来自环境变量和命令行参数的属性。
+func (app *App) watchKubeConfig(env *conf.Properties) error {
+
+	var config kube.Config
+	if err := app.c.p.Bind(&config, conf.Key("kube.config")); err != nil {
+		return err
+	}
+
+	skip := make(map[string]bool)
+	for _, k := range env.Keys() {
+		skip[k] = true
+	}
+	filter := func(p *conf.Properties) *conf.Properties {
+		m := make(map[string]interface{})
+		for _, k := range p.Keys() {
+			if !skip[k] {
+				m[k] = p.Get(k)
+			}
+		}
+		return conf.Map(m)
+	}
+
+	w := kube.NewWatcher(config, func(p *conf.Properties) error {
+		return app.c.RefreshProperties(filter(p))
+	})
+
+	p, err := w.Load()
+	if err != nil {
+		return err
+	}
+	p = filter(p)
+	app.c.p.Merge(p)
+	for _, k := range p.Keys() {
+		app.origins[k] = OriginKube
+	}
+
+	app.Object(w)
+	app.c.Go(w.Run)
+	return nil
+}
+
 func (app *App) loadConfigFile(p *conf.Properties, locations []string, extensions []string, profile string) error {
 
 	filename := "application"
@@ -269,10 +637,44 @@ func (app *App) loadConfigFile(p *conf.Properties, locations []string, extension
 
 	for _, loc := range locations {
 		for _, ext := range extensions {
-			err := p.Load(filepath.Join(loc, filename+ext))
-			if err != nil && !os.IsNotExist(err) {
+			file := filepath.Join(loc, filename+ext)
+			f, err := conf.Load(file)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
 				return err
 			}
+			p.Merge(f)
+			for _, k := range f.Keys() {
+				app.origins[k] = file
+			}
+		}
+	}
+	return nil
+}
+
+// Startup 返回应用的启动耗时报告。
+func (app *App) Startup() StartupReport {
+	return app.c.Startup()
+}
+
+// setLogLevels 根据 logging.level 前缀的属性设置日志的输出级别，其中
+// logging.level.root 设置全局级别，其他属性设置对应名称的日志的级别。
+func (app *App) setLogLevels() error {
+	const prefix = "logging.level."
+	for _, k := range app.c.p.Keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		level, err := log.ParseLevel(cast.ToString(app.c.p.Get(k)))
+		if err != nil {
+			return fmt.Errorf("property %s: %v", k, err)
+		}
+		if name := strings.TrimPrefix(k, prefix); name == "root" {
+			log.SetLevel(level)
+		} else {
+			log.SetLoggerLevel(name, level)
 		}
 	}
 	return nil
@@ -281,12 +683,8 @@ func (app *App) loadConfigFile(p *conf.Properties, locations []string, extension
 // ShutDown 关闭执行器
 func (app *App) ShutDown(err error) {
 	log.Infof("program will exit %s", err.Error())
-	select {
-	case <-app.exitChan:
-		// chan 已关闭，无需再次关闭。
-	default:
-		close(app.exitChan)
-	}
+	// 多个 goroutine 可能同时出错并调用 ShutDown ，只能关闭一次 chan 。
+	app.exitOnce.Do(func() { close(app.exitChan) })
 }
 
 // OnProperty 当 key 对应的属性值准备好后发送一个通知。
@@ -310,6 +708,18 @@ func (app *App) Property(key string, value interface{}) {
 	app.c.Property(key, value)
 }
 
+// MergeProperties 使用 p 中的属性覆盖应用的同名属性，所有的属性在一次修改中设置，
+// 批量设置属性时比逐个调用 Property 高效。
+func (app *App) MergeProperties(p *conf.Properties) {
+	app.c.MergeProperties(p)
+}
+
+// RefreshProperties 使用 p 中的属性覆盖应用的同名属性，然后刷新所有绑定到属性
+// 上的动态属性值，例如在配置中心推送新的配置时调用。
+func (app *App) RefreshProperties(p *conf.Properties) error {
+	return app.c.RefreshProperties(p)
+}
+
 // Object 注册对象形式的 bean ，需要注意的是该方法在注入开始后就不能再调用了。
 func (app *App) Object(i interface{}) *BeanDefinition {
 	return app.c.register(NewBean(reflect.ValueOf(i)))
@@ -320,6 +730,21 @@ func (app *App) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return app.c.register(NewBean(ctor, args...))
 }
 
+// Import 导入模块，模块注册的 bean 只有在 conds 全部满足时才会生效。
+func (app *App) Import(m Module, conds ...cond.Condition) {
+	app.c.Import(m, conds...)
+}
+
+// GroupRegister 注册一组由属性决定的 bean ，fn 在容器刷新时调用。
+func (app *App) GroupRegister(fn GroupFunc) {
+	app.c.GroupRegister(fn)
+}
+
+// ExportGraph 以 format 格式导出 bean 的依赖图，必须在应用启动之后调用。
+func (app *App) ExportGraph(w io.Writer, format GraphFormat) error {
+	return app.c.ExportGraph(w, format)
+}
+
 // Go 创建安全可等待的 goroutine，fn 要求的 ctx 对象由 IoC 容器提供，当 IoC 容
 // 器关闭时 ctx会 发出 Done 信号， fn 在接收到此信号后应当立即退出。
 func (app *App) Go(fn func(ctx context.Context)) {
@@ -418,3 +843,11 @@ func (app *App) GrpcServer(serviceName string, fn interface{}, service interface
 	s := &grpc.Server{Service: service, Register: fn}
 	return app.c.register(NewBean(s)).Name(serviceName)
 }
+
+// GrpcGateway 注册 gRPC 服务的 HTTP 网关，fn 是 grpc-gateway 自动生成的
+// RegisterXxxHandlerServer 函数，serviceName 必须和 GrpcServer 注册时的服务
+// 名称一致。
+func (app *App) GrpcGateway(serviceName string, fn interface{}) *BeanDefinition {
+	g := &grpc.Gateway{Register: fn}
+	return app.c.register(NewBean(g)).Name(serviceName)
+}
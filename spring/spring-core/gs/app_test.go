@@ -17,19 +17,77 @@
 package gs_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-spring/spring-core/actuator"
+	"github.com/go-spring/spring-core/bodylog"
+	"github.com/go-spring/spring-core/buildinfo"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/feature"
 	"github.com/go-spring/spring-core/gs"
 	"github.com/go-spring/spring-core/gs/environ"
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mapping"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-core/ratelimit"
+	"github.com/go-spring/spring-core/readiness"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-core/secrets"
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/security/authz"
+	"github.com/go-spring/spring-core/tenancy"
+	"github.com/go-spring/spring-core/validator"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-core/web/acme"
 	"github.com/go-spring/spring-stl/assert"
 )
 
-func startApplication(cfgLocation string) (*gs.App, gs.Pandora) {
+// readyEvent 应用启动完成时关闭 ready 通道。
+type readyEvent struct {
+	ready chan struct{}
+}
+
+func (e *readyEvent) OnStartApp(ctx gs.AppContext) { close(e.ready) }
+
+func (e *readyEvent) OnStopApp(ctx gs.AppContext) {}
+
+// runApp 启动应用并等待应用启动完成，返回的函数关闭应用并等待应用退出。
+func runApp(t *testing.T, app *gs.App) (stop func()) {
+	t.Helper()
+
+	e := &readyEvent{ready: make(chan struct{})}
+	app.Object(e).Export(gs.AppEvent)
+
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+
+	select {
+	case <-e.ready:
+	case err := <-done:
+		t.Fatalf("application exited before started: %v", err)
+	}
+
+	return func() {
+		app.ShutDown(errors.New("run test end"))
+		<-done
+	}
+}
+
+func startApplication(t *testing.T, cfgLocation string) (gs.Pandora, func()) {
 
 	app := gs.NewApp()
 	gs.Setenv("SPRING_BANNER_VISIBLE", "true")
@@ -43,9 +101,8 @@ func startApplication(cfgLocation string) (*gs.App, gs.Pandora) {
 		return PandoraAware{}
 	})
 
-	go app.Run()
-	time.Sleep(100 * time.Millisecond)
-	return app, p
+	stop := runApp(t, app)
+	return p, stop
 }
 
 func TestConfig(t *testing.T) {
@@ -53,16 +110,16 @@ func TestConfig(t *testing.T) {
 	t.Run("config via env", func(t *testing.T) {
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app, p := startApplication("testdata/config/")
-		defer app.ShutDown(errors.New("run test end"))
+		p, stop := startApplication(t, "testdata/config/")
+		defer stop()
 		assert.Equal(t, p.Prop(environ.SpringProfilesActive), "dev")
 	})
 
 	t.Run("config via env 2", func(t *testing.T) {
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app, p := startApplication("testdata/config/")
-		defer app.ShutDown(errors.New("run test end"))
+		p, stop := startApplication(t, "testdata/config/")
+		defer stop()
 		assert.Equal(t, p.Prop(environ.SpringProfilesActive), "dev")
 	})
 
@@ -70,8 +127,8 @@ func TestConfig(t *testing.T) {
 
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app, p := startApplication("testdata/config/")
-		defer app.ShutDown(errors.New("run test end"))
+		p, stop := startApplication(t, "testdata/config/")
+		defer stop()
 		assert.Equal(t, p.Prop(environ.SpringProfilesActive), "dev")
 
 		var m map[string]string
@@ -84,6 +141,63 @@ func TestConfig(t *testing.T) {
 	})
 }
 
+type inspectConfig struct {
+	Name     string `value:"${name:=x}"`
+	Password string `value:"${password:=}"`
+}
+
+type inspectService struct {
+	Router web.Router `autowire:""`
+	config inspectConfig
+}
+
+func TestInspector(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("inspect.name", "abc")
+
+	var p gs.Pandora
+	app.Provide(func(b gs.Pandora, c inspectConfig) *inspectService {
+		p = b
+		return &inspectService{config: c}
+	}, "", "${inspect}")
+
+	defer runApp(t, app)()
+
+	var i actuator.Inspector
+	err := p.Get(&i)
+	assert.Nil(t, err)
+
+	found := false
+	for _, b := range i.Beans() {
+		if b.Type == "*gs_test.inspectService" {
+			found = true
+			assert.Equal(t, len(b.Dependencies), 2)
+		}
+	}
+	assert.True(t, found)
+
+	found = false
+	for _, c := range i.ConfigProps() {
+		if c.Prefix == "inspect" {
+			found = true
+			assert.Equal(t, c.Value, inspectConfig{Name: "abc"})
+		}
+	}
+	assert.True(t, found)
+
+	origins := make(map[string]string)
+	for _, prop := range i.Properties() {
+		origins[prop.Key] = prop.Origin
+	}
+	assert.Equal(t, origins["inspect.name"], gs.OriginCode)
+	assert.Equal(t, origins["spring.config.locations"], gs.OriginSystemEnv)
+	assert.Equal(t, origins["spring.application.name"], "testdata/config/application.properties")
+}
+
 func sortedKeys(m map[string]string) (keys []string) {
 	for k := range m {
 		keys = append(keys, k)
@@ -91,3 +205,571 @@ func sortedKeys(m map[string]string) (keys []string) {
 	sort.Strings(keys)
 	return
 }
+
+func TestBuildInfo(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property(environ.SpringBannerVisible, true)
+	app.Property(environ.SpringBannerText, "build info test")
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	var info *buildinfo.Info
+	err := p.Get(&info)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Name, "test")
+	assert.Equal(t, info.GoVersion, runtime.Version())
+}
+
+func TestDotenv(t *testing.T) {
+	os.Clearenv()
+
+	file := filepath.Join(t.TempDir(), ".env")
+	data := "# dotenv\nexport GS_DOTENV_VALUE=\"from dotenv\"\nGS_OVERRIDE_VALUE=dotenv\n"
+	assert.Nil(t, os.WriteFile(file, []byte(data), 0644))
+	gs.Setenv(environ.DotenvLocation, file)
+	gs.Setenv("GS_OVERRIDE_VALUE", "env")
+
+	p, stop := startApplication(t, "testdata/config/")
+	defer stop()
+
+	assert.Equal(t, p.Prop("dotenv.value"), "from dotenv")
+	assert.Equal(t, p.Prop("override.value"), "env")
+}
+
+func TestKubeConfig(t *testing.T) {
+	os.Clearenv()
+
+	dir := t.TempDir()
+	write := func(name, data string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+	write("kube.value", "v1")
+	write("kube.env", "kube")
+	gs.Setenv("GS_KUBE_ENV", "env")
+
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("kube.config.enabled", true)
+	app.Property("kube.config.paths[0]", dir)
+	app.Property("kube.config.interval", "10ms")
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	assert.Equal(t, p.Prop("kube.value"), "v1")
+	assert.Equal(t, p.Prop("kube.env"), "env")
+
+	write("kube.value", "v2")
+	write("kube.env", "kube2")
+	for i := 0; i < 100 && p.Prop("kube.value") != "v2"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, p.Prop("kube.value"), "v2")
+	assert.Equal(t, p.Prop("kube.env"), "env")
+}
+
+func TestShutdownSignals(t *testing.T) {
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.SpringShutdownSignals, "SIGTERM,SIGFOO")
+	err := app.Run()
+	assert.Error(t, err, "unsupported signal \"SIGFOO\"")
+}
+
+func TestShutdownOnGoroutineError(t *testing.T) {
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.SpringShutdownOnGoroutineError, true)
+	app.Go(func(ctx context.Context) { panic("boom") })
+
+	ch := make(chan error, 1)
+	go func() { ch <- app.Run() }()
+
+	select {
+	case err := <-ch:
+		assert.Error(t, err, "goroutine panic: boom")
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't shut down on goroutine error")
+	}
+}
+
+// TestShutdownOnConcurrentGoroutineErrors 多个 goroutine 同时出错时只关闭一次程序，
+// 并且所有的错误都会返回。
+func TestShutdownOnConcurrentGoroutineErrors(t *testing.T) {
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.SpringShutdownOnGoroutineError, true)
+	start := make(chan struct{})
+	app.Go(func(ctx context.Context) { <-start; panic("boom") })
+	app.Go(func(ctx context.Context) { <-start; panic("bang") })
+	app.Go(func(ctx context.Context) { close(start) })
+
+	ch := make(chan error, 1)
+	go func() { ch <- app.Run() }()
+
+	select {
+	case err := <-ch:
+		assert.Error(t, err, "2 goroutine errors occurred:")
+		assert.Error(t, err, "goroutine panic: boom")
+		assert.Error(t, err, "goroutine panic: bang")
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't shut down on goroutine error")
+	}
+}
+
+func TestSecurity(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("security.enabled", true)
+	app.Property("security.jwt.enabled", true)
+	app.Property("security.jwt.secret", "secret")
+	app.Property("security.api-key.enabled", true)
+	app.Property("security.api-key.keys[0].key", "k1")
+	app.Property("security.api-key.keys[0].name", "svc")
+	app.Property("security.api-key.keys[0].roles", []string{"service", "ops"})
+	app.Property("security.authz.enabled", true)
+	app.Property("security.authz.routes[0].pattern", "/admin/**")
+	app.Property("security.authz.routes[0].access", "hasRole('admin') or @ops")
+	app.Object(authz.RuleFunc(func(ctx context.Context, p *security.Principal) bool {
+		return p != nil && p.Name == "ops"
+	})).Name("ops").Export((*authz.Rule)(nil))
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	var filter *security.Filter
+	err := p.Get(&filter)
+	assert.Nil(t, err)
+	assert.Equal(t, len(filter.Providers), 2)
+
+	var apiKey *security.APIKeyProvider
+	err = p.Get(&apiKey)
+	assert.Nil(t, err)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", "k1")
+	a, err := apiKey.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Principal.Roles, []string{"service", "ops"})
+
+	var authzFilter *authz.Filter
+	err = p.Get(&authzFilter)
+	assert.Nil(t, err)
+	assert.Equal(t, len(authzFilter.Rules), 1)
+
+	h := authzFilter.Handler(http.NotFoundHandler())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	ctx := security.WithAuthentication(context.Background(), &security.Authentication{
+		Principal: &security.Principal{Name: "ops"},
+	})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil).WithContext(ctx))
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+type secretProvider map[string]string
+
+func (p secretProvider) Get(ctx context.Context, path string) (*secrets.Secret, error) {
+	return &secrets.Secret{Data: p}, nil
+}
+
+func TestSecrets(t *testing.T) {
+
+	secrets.Register("test", func(*conf.Properties) (secrets.Provider, error) {
+		return secretProvider{"password": "p@ss"}, nil
+	})
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("secrets.enabled", true)
+	app.Property("secrets.provider", "test")
+	app.Property("db.password", "secret://db#password")
+
+	var password string
+	app.Provide(func(s string) bool {
+		password = s
+		return true
+	}, "${db.password}")
+
+	stop := runApp(t, app)
+	assert.Equal(t, password, "p@ss")
+	stop()
+
+	// 应用退出后取消注册 secret:// 属性值的解析器。
+	p := conf.New()
+	p.Set("db.password", "secret://db#password")
+	var s string
+	assert.Nil(t, p.Bind(&s, conf.Key("db.password")))
+	assert.Equal(t, s, "secret://db#password")
+}
+
+type tenantDB struct{ URL string }
+
+func TestTenancy(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("tenancy.enabled", true)
+	app.Property("tenancy.resolvers", []string{"header"})
+	app.Property("db.url", "mysql://shared")
+	app.Property("tenants.acme.db.url", "mysql://acme")
+
+	app.Object(&tenantDB{URL: "mysql://shared"}).Name("db")
+	app.Object(&tenantDB{URL: "mysql://acme"}).Name(tenancy.BeanName("db", "acme"))
+
+	var (
+		overlay *tenancy.Overlay
+		router  *tenancy.Router[*tenantDB]
+	)
+	app.Provide(func(o *tenancy.Overlay, m map[string]*tenantDB) bool {
+		overlay, router = o, tenancy.NewRouter("db", m)
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	ctx := tenancy.WithID(context.Background(), "acme")
+	assert.Equal(t, overlay.Prop(ctx, "db.url"), "mysql://acme")
+	db, err := router.Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, db.URL, "mysql://acme")
+}
+
+type validatedConfig struct {
+	Host string `value:"${host:=}" validate:"required" label:"主机"`
+}
+
+func TestValidator(t *testing.T) {
+	defer validator.Init(nil)
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("validator.locale", "zh")
+	app.Provide(func(c validatedConfig) bool { return true }, "${server}")
+	err := app.Run()
+	assert.Error(t, err, "主机不能为空")
+	_, ok := validator.Default().(*validator.Engine)
+	assert.True(t, ok)
+}
+
+type celsius float64
+
+type weatherDTO struct {
+	Temp string
+}
+
+func TestMapping(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Object(mapping.Func(func(c celsius) (string, error) {
+		return fmt.Sprintf("%.1f°C", c), nil
+	})).Export((*mapping.Converter)(nil))
+
+	var m *mapping.Mapper
+	app.Provide(func(b *mapping.Mapper) bool {
+		m = b
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	assert.Equal(t, m, mapping.Default())
+	w, err := mapping.Copy[weatherDTO](struct{ Temp celsius }{Temp: 21.5})
+	assert.Nil(t, err)
+	assert.Equal(t, w.Temp, "21.5°C")
+}
+
+type upperCodec struct{ web.Codec }
+
+func TestJSONCodec(t *testing.T) {
+	defer web.SetJSONCodec(nil)
+
+	t.Run("config", func(t *testing.T) {
+		os.Clearenv()
+		app := gs.NewApp()
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+		app.Property("web.json.naming", web.NamingSnake)
+		defer runApp(t, app)()
+		b, err := web.MarshalJSON(struct{ UserID int }{1})
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), `{"user_id":1}`)
+	})
+
+	t.Run("bean", func(t *testing.T) {
+		os.Clearenv()
+		app := gs.NewApp()
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+		app.Property("web.json.naming", web.NamingSnake)
+		c := &upperCodec{Codec: web.JSONCodec()}
+		app.Object(c).Export((*web.Codec)(nil))
+		defer runApp(t, app)()
+		assert.Equal(t, web.JSONCodec(), web.Codec(c))
+	})
+}
+
+func TestTemplateEngine(t *testing.T) {
+	defer web.SetTemplateEngine(nil)
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(`hello {{.}}`), 0644)
+	assert.Nil(t, err)
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("web.template.enabled", true)
+	app.Property("web.template.dir", dir)
+
+	var e *web.HTMLTemplates
+	app.Provide(func(b *web.HTMLTemplates) bool {
+		e = b
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	var buf bytes.Buffer
+	err = e.Render(&buf, "index", "jim", "")
+	assert.Nil(t, err)
+	assert.Equal(t, buf.String(), "hello jim")
+}
+
+func TestBodyLog(t *testing.T) {
+
+	var msgs []string
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {
+		if strings.HasPrefix(e.GetMsg(), "http payload") {
+			msgs = append(msgs, e.GetMsg())
+		}
+	})
+	defer log.Reset()
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("web.body-log.enabled", true)
+	app.Property("web.body-log.redact", []string{"password"})
+
+	var f *bodylog.Filter
+	app.Provide(func(b *bodylog.Filter) bool {
+		f = b
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"password":"p"}`))
+	r.Header.Set("Content-Type", "application/json")
+	f.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, len(msgs), 1)
+	assert.True(t, strings.Contains(msgs[0], `request="{\"password\":\"******\"}"`))
+}
+
+func TestReadiness(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("health.cache-ttl", "0")
+
+	ch := make(chan struct{})
+	app.Object(readiness.WarmupFunc(func(ctx context.Context) error {
+		<-ch
+		return nil
+	})).Name("cache").Export((*readiness.Warmup)(nil))
+
+	var c *health.Checker
+	app.Provide(func(b *health.Checker) bool {
+		c = b
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	h := c.Check(context.Background(), health.GroupReadiness)
+	assert.Equal(t, h.Status, health.StatusOutOfService)
+	assert.Equal(t, c.Check(context.Background(), health.GroupLiveness).Status, health.StatusUp)
+
+	close(ch)
+	time.Sleep(10 * time.Millisecond)
+	h = c.Check(context.Background(), health.GroupReadiness)
+	assert.Equal(t, h.Status, health.StatusUp)
+}
+
+func TestPersist(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "override.properties")
+	err := os.WriteFile(file, []byte("feature.flags.beta=true\n"), 0644)
+	assert.Nil(t, err)
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property("spring.config.writable-location", file)
+
+	var (
+		f *feature.Flags
+		s actuator.LevelStore
+	)
+	app.Provide(func(b *feature.Flags, store actuator.LevelStore) bool {
+		f, s = b, store
+		return true
+	})
+
+	defer runApp(t, app)()
+
+	ctx := context.Background()
+	assert.True(t, f.Enabled(ctx, "beta"))
+	assert.Nil(t, f.Save("beta", false))
+	assert.Nil(t, s.SaveLevel("web", "debug"))
+
+	p, err := conf.Load(file)
+	assert.Nil(t, err)
+	assert.Equal(t, p.Get("feature.flags.beta"), "false")
+	assert.Equal(t, p.Get("logging.level.web"), "debug")
+}
+
+type nopAvroSerde struct{}
+
+func (nopAvroSerde) Marshal(schema string, v interface{}) ([]byte, error) {
+	return nil, nil
+}
+
+func (nopAvroSerde) Unmarshal(schema string, data []byte, v interface{}) error {
+	return nil
+}
+
+func TestMessagingCodec(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("mq.schema-registry.enabled", true)
+	app.Property("mq.schema-registry.url", "http://localhost:8081")
+	app.Property("mq.bindings.orders.content-type", "avro")
+	app.Object(mq.NewMemoryBinder()).Name("memory").Export((*mq.Binder)(nil))
+	app.Object(&nopAvroSerde{}).Export((*mq.AvroSerde)(nil))
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	var s *mq.Streams
+	err := p.Get(&s)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Config.Bindings["orders"].ContentType, "avro")
+	_, ok := s.Codecs["avro"]
+	assert.True(t, ok)
+}
+
+type nopRedis struct {
+	redis.Client
+}
+
+func TestRedisPrimitives(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("ratelimit.token-bucket.rate", 100)
+	app.Object(&nopRedis{}).Export((*redis.Client)(nil))
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	var s *redis.Scripts
+	assert.Nil(t, p.Get(&s))
+	_, ok := s.Script("ratelimit.sliding-window")
+	assert.True(t, ok)
+	_, ok = s.Script("ratelimit.token-bucket")
+	assert.True(t, ok)
+
+	var b *redis.Bloom
+	assert.Nil(t, p.Get(&b))
+	var w *ratelimit.SlidingWindow
+	assert.Nil(t, p.Get(&w))
+	var tb *ratelimit.TokenBucket
+	assert.Nil(t, p.Get(&tb))
+}
+
+func TestACME(t *testing.T) {
+
+	os.Clearenv()
+	app := gs.NewApp()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	app.Property(environ.EnablePandora, true)
+	app.Property("web.server.acme.enabled", true)
+	app.Property("web.server.acme.domains[0]", "example.com")
+	app.Property("web.server.acme.cache-dir", t.TempDir())
+	app.Property("web.server.acme.http-challenge.addr", "127.0.0.1:0")
+	app.Property("web.server.acme.tls-alpn-challenge.enabled", false)
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	app.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	defer runApp(t, app)()
+
+	var m *acme.Manager
+	assert.Nil(t, p.Get(&m))
+
+	var cfg web.ContainerConfig
+	m.Configure(&cfg)
+	assert.True(t, cfg.EnableSSL)
+	assert.Equal(t, cfg.TLSConfig.NextProtos, []string{"h2", "http/1.1"})
+
+	_, err := cfg.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.com"})
+	assert.Error(t, err, "not configured in HostWhitelist")
+}
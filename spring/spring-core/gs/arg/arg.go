@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sync"
 
 	"github.com/go-spring/spring-core/gs/bean"
 	"github.com/go-spring/spring-core/gs/cond"
@@ -40,12 +41,20 @@ type Context interface {
 
 	// Wire 根据 tag 的内容对 v 进行依赖注入。
 	Wire(v reflect.Value, tag string) error
+
+	// WireFields 根据结构体 v 的字段上的 autowire 或者 inject 标签进行依赖注入。
+	WireFields(v reflect.Value) error
+
+	// Supply 返回容器为 t 类型的参数自动提供的值，例如 context.Context 。
+	Supply(t reflect.Type) (reflect.Value, bool)
 }
 
 // Arg 用于为函数参数提供绑定值。可以是 bean.Selector 类型，表示注入 bean ；
 // 可以是 ${X:=Y} 形式的字符串，表示属性绑定或者注入 bean ；可以是 ValueArg
 // 类型，表示不从 IoC 容器获取而是用户传入的普通值；可以是 IndexArg 类型，表示
-// 带有下标的参数绑定；可以是 *optionArg 类型，用于为 Option 方法提供参数绑定。
+// 带有下标的参数绑定；可以是 NameArg 类型，表示按照参数名称的参数绑定；可以是
+// StructArg 类型，表示对结构体参数的字段分别进行绑定；可以是 *optionArg 类型，
+// 用于为 Option 方法提供参数绑定。
 type Arg interface{}
 
 // IndexArg 包含下标的参数绑定。
@@ -80,6 +89,92 @@ func R5(arg Arg) IndexArg { return Index(6, arg) }
 // R6 返回下标为 6 的参数绑定。
 func R6(arg Arg) IndexArg { return Index(7, arg) }
 
+// NameArg 按照参数名称的参数绑定。
+type NameArg struct {
+	name string
+	arg  Arg
+}
+
+// Name 返回按照参数名称的参数绑定，反射无法获取函数的参数名称，所以函数的参数
+// 名称需要事先通过 ParamNames 进行注册。按照名称的参数绑定最终转换为按照下标的
+// 参数绑定，因此可以和 IndexArg 混用，但是不能和普通的参数绑定混用。
+func Name(name string, arg Arg) NameArg {
+	return NameArg{name: name, arg: arg}
+}
+
+// paramNames 函数的参数名称，key 为函数的地址。
+var paramNames sync.Map
+
+// ParamNames 注册函数的参数名称，方法表达式的第一个参数是接收者。通常在定义函数
+// 的文件中调用或者由代码生成工具生成，例如:
+//
+//	func init() {
+//		arg.ParamNames(NewServer, "addr", "timeout", "logger")
+//	}
+func ParamNames(fn interface{}, names ...string) {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		panic(errors.New("fn should be func type"))
+	}
+	if len(names) != t.NumIn() {
+		panic(fmt.Errorf("func has %d params but %d names", t.NumIn(), len(names)))
+	}
+	paramNames.Store(reflect.ValueOf(fn).Pointer(), names)
+}
+
+// resolveNames 将按照名称的参数绑定转换为按照下标的参数绑定。
+func resolveNames(fn interface{}, args []Arg) ([]Arg, error) {
+
+	var names []string
+	result := make([]Arg, len(args))
+	for i, a := range args {
+		na, ok := a.(NameArg)
+		if !ok {
+			result[i] = a
+			continue
+		}
+		if names == nil {
+			v, ok := paramNames.Load(reflect.ValueOf(fn).Pointer())
+			if !ok {
+				return nil, fmt.Errorf("参数名称 %q 无法绑定，函数没有注册参数名称", na.name)
+			}
+			names = v.([]string)
+		}
+		n := -1
+		for j, name := range names {
+			if name == na.name {
+				n = j
+				break
+			}
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("函数没有名为 %q 的参数", na.name)
+		}
+		result[i] = Index(n+1, na.arg)
+	}
+	return result, nil
+}
+
+// StructArg 对结构体参数的字段分别进行绑定，带有 value 标签的字段进行属性绑定，
+// 带有 autowire 或者 inject 标签的字段进行依赖注入。
+type StructArg struct {
+	tag string
+}
+
+// Struct 返回对结构体参数的字段分别进行绑定的参数绑定，参数可以是结构体或者结构
+// 体指针，tag 为 ${key} 形式的属性前缀，为空时表示从根属性开始绑定。适用于参数
+// 较多的构造函数，例如:
+//
+//	type ServerOptions struct {
+//		Addr   string        `value:"${addr:=:8080}"`
+//		Logger *log.Logger   `autowire:""`
+//	}
+//
+//	gs.Provide(NewServer, arg.Struct("${server}"))
+func Struct(tag string) StructArg {
+	return StructArg{tag: tag}
+}
+
 // ValueArg 包含具体值的参数绑定。
 type ValueArg struct {
 	v interface{}
@@ -175,9 +270,19 @@ func newArgList(fnType reflect.Type, args []Arg) (*argList, error) {
 		}
 	}
 
+	// 没有传入可变参数时收集所有元素类型的 bean 作为可变参数。
+	if fnType.IsVariadic() && len(fnArgs) == fixedArgCount {
+		if util.IsBeanType(fnType.In(fixedArgCount).Elem()) {
+			fnArgs = append(fnArgs, collectArg{})
+		}
+	}
+
 	return &argList{fnType: fnType, args: fnArgs}, nil
 }
 
+// collectArg 收集所有元素类型的 bean 作为可变参数，bean 按照 Order 排序。
+type collectArg struct{}
+
 // get 返回所有绑定参数的真实值，fileLine 是函数定义所在的文件信息。
 func (r *argList) get(ctx Context, fileLine string) ([]reflect.Value, error) {
 
@@ -188,6 +293,17 @@ func (r *argList) get(ctx Context, fileLine string) ([]reflect.Value, error) {
 
 	for idx, arg := range r.args {
 
+		if _, ok := arg.(collectArg); ok {
+			v := reflect.New(fnType.In(numIn - 1)).Elem()
+			if err := ctx.Wire(v, ""); err != nil {
+				return nil, err
+			}
+			for i := 0; i < v.Len(); i++ {
+				result = append(result, v.Index(i))
+			}
+			continue
+		}
+
 		var t reflect.Type
 		if variadic && idx >= numIn-1 {
 			t = fnType.In(numIn - 1).Elem()
@@ -228,6 +344,8 @@ func (r *argList) getArg(ctx Context, arg Arg, t reflect.Type, fileLine string)
 	switch g := arg.(type) {
 	case ValueArg:
 		return reflect.ValueOf(g.v), nil
+	case StructArg:
+		return g.get(ctx, t)
 	case *optionArg:
 		return g.call(ctx)
 	case bean.Definition:
@@ -238,6 +356,13 @@ func (r *argList) getArg(ctx Context, arg Arg, t reflect.Type, fileLine string)
 		tag = util.TypeName(g) + ":"
 	}
 
+	// 未绑定的参数优先使用容器自动提供的值
+	if tag == "" {
+		if v, ok := ctx.Supply(t); ok {
+			return v, nil
+		}
+	}
+
 	v := reflect.New(t).Elem()
 
 	// 处理 bean 类型
@@ -258,6 +383,34 @@ func (r *argList) getArg(ctx Context, arg Arg, t reflect.Type, fileLine string)
 	return v, nil
 }
 
+func (arg StructArg) get(ctx Context, t reflect.Type) (reflect.Value, error) {
+
+	et := t
+	if t.Kind() == reflect.Ptr {
+		et = t.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s 不是结构体类型", t.String())
+	}
+
+	tag := arg.tag
+	if tag == "" {
+		tag = "${}"
+	}
+
+	v := reflect.New(et)
+	if err := ctx.Bind(v.Elem(), tag); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := ctx.WireFields(v.Elem()); err != nil {
+		return reflect.Value{}, err
+	}
+	if t.Kind() == reflect.Ptr {
+		return v, nil
+	}
+	return v.Elem(), nil
+}
+
 // optionArg Option 函数的参数绑定。
 type optionArg struct {
 	r *Callable
@@ -319,12 +472,28 @@ func (arg *optionArg) call(ctx Context) (reflect.Value, error) {
 // Callable 绑定函数及其参数，然后通过 Call 方法获取绑定函数的执行结果。
 type Callable struct {
 	fn       interface{}
+	invoker  Invoker
 	argList  *argList
 	fileLine string
 }
 
 // Bind 绑定函数及其参数，skip 是相对于当前方法需要跳过的调用栈层数。
 func Bind(fn interface{}, args []Arg, skip int) (*Callable, error) {
+	return bind(fn, nil, args, skip+1)
+}
+
+// BindInvoker 绑定函数及其参数，并且使用 invoker 作为函数的静态调用器，适用于
+// 函数字面量等无法通过 RegisterInvoker 注册静态调用器的场景。
+func BindInvoker(fn interface{}, invoker Invoker, args []Arg, skip int) (*Callable, error) {
+	return bind(fn, invoker, args, skip+1)
+}
+
+func bind(fn interface{}, invoker Invoker, args []Arg, skip int) (*Callable, error) {
+
+	args, err := resolveNames(fn, args)
+	if err != nil {
+		return nil, err
+	}
 
 	fnType := reflect.TypeOf(fn)
 	argList, err := newArgList(fnType, args)
@@ -335,13 +504,15 @@ func Bind(fn interface{}, args []Arg, skip int) (*Callable, error) {
 	_, file, line, _ := runtime.Caller(skip + 1)
 	r := &Callable{
 		fn:       fn,
+		invoker:  invoker,
 		argList:  argList,
 		fileLine: fmt.Sprintf("%s:%d", file, line),
 	}
 	return r, nil
 }
 
-// Call 通过反射机制获取函数的绑定参数并执行函数，最后返回函数的执行结果。
+// Call 获取函数的绑定参数并执行函数，最后返回函数的执行结果。函数注册了静态调用
+// 器时使用静态调用器执行，否则通过反射机制执行。
 func (r *Callable) Call(ctx Context) ([]reflect.Value, error) {
 
 	in, err := r.argList.get(ctx, r.fileLine)
@@ -349,7 +520,7 @@ func (r *Callable) Call(ctx Context) ([]reflect.Value, error) {
 		return nil, err
 	}
 
-	out := reflect.ValueOf(r.fn).Call(in)
+	out := call(r.fn, r.invoker, in)
 	n := len(out)
 	if n == 0 {
 		return out, nil
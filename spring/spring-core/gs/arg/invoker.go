@@ -0,0 +1,70 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arg
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Invoker 函数的静态调用器，通常由 gs codegen 命令生成。容器调用注册了静态调用
+// 器的函数时不再通过反射进行调用，in 的顺序和类型与函数的参数一致，返回值的顺序
+// 和类型与函数的返回值一致。
+type Invoker func(in []interface{}) []interface{}
+
+var invokers sync.Map // map[uintptr]Invoker
+
+// RegisterInvoker 注册函数的静态调用器，通常由生成的代码在 init 函数中调用。注意
+// 同一个函数字面量生成的闭包共享同一个函数地址，因此 fn 应该是包级别的函数。可变
+// 参数函数总是通过反射进行调用。
+func RegisterInvoker(fn interface{}, invoker Invoker) {
+	invokers.Store(reflect.ValueOf(fn).Pointer(), invoker)
+}
+
+// call 调用函数 fn ，优先使用绑定的静态调用器，其次是注册的静态调用器，都没有时
+// 通过反射进行调用。
+func call(fn interface{}, invoker Invoker, in []reflect.Value) []reflect.Value {
+
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+	if invoker == nil {
+		if fnType.IsVariadic() {
+			return fnValue.Call(in)
+		}
+		i, ok := invokers.Load(fnValue.Pointer())
+		if !ok {
+			return fnValue.Call(in)
+		}
+		invoker = i.(Invoker)
+	}
+
+	args := make([]interface{}, len(in))
+	for j, v := range in {
+		args[j] = v.Interface()
+	}
+
+	ret := invoker(args)
+	out := make([]reflect.Value, len(ret))
+	for j, r := range ret {
+		v := reflect.New(fnType.Out(j)).Elem()
+		if r != nil {
+			v.Set(reflect.ValueOf(r))
+		}
+		out[j] = v
+	}
+	return out
+}
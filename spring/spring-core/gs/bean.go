@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-spring/spring-core/gs/arg"
 	"github.com/go-spring/spring-core/gs/bean"
@@ -68,7 +69,16 @@ type BeanDefinition struct {
 	destroy   interface{}     // 销毁函数
 	dependsOn []bean.Selector // 间接依赖项
 
+	dependencies []*BeanDefinition // 注入的其他 bean
+	bindings     []configBinding   // 绑定到属性上的配置结构体
+	properties   []string          // 引用的属性
+
+	wiringTime time.Duration // 注入的耗时
+	nestedTime time.Duration // 注入依赖项的耗时
+
 	exports map[reflect.Type]struct{} // 导出的接口
+
+	bridged string // 桥接来源容器的名称，非空时 bean 由来源容器注入和销毁
 }
 
 // Type 返回 bean 的类型。
@@ -111,8 +121,48 @@ func (d *BeanDefinition) FileLine() string {
 	return fmt.Sprintf("%s:%d", d.file, d.line)
 }
 
+// addProperty 记录 bean 引用的属性，tag 是 ${key:=def} 形式的属性引用。
+func (d *BeanDefinition) addProperty(tag string) {
+	key := propertyKey(tag)
+	if key == "" {
+		return
+	}
+	for _, k := range d.properties {
+		if k == key {
+			return
+		}
+	}
+	d.properties = append(d.properties, key)
+}
+
+// propertyKey 返回 ${key:=def} 形式的属性引用中的 key ，#{...} 形式的表达式
+// 返回空字符串。
+func propertyKey(tag string) string {
+	if strings.HasPrefix(tag, "#{") {
+		return ""
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(tag, "${"), "}")
+	if n := strings.Index(key, ":="); n >= 0 {
+		key = key[:n]
+	}
+	return key
+}
+
+// addDependency 记录注入到当前 bean 的其他 bean 。
+func (d *BeanDefinition) addDependency(b *BeanDefinition) {
+	for _, v := range d.dependencies {
+		if v == b {
+			return
+		}
+	}
+	d.dependencies = append(d.dependencies, b)
+}
+
 // getClass 返回 bean 的类型描述。
 func (d *BeanDefinition) getClass() string {
+	if d.bridged != "" {
+		return "bridged bean"
+	}
 	if d.f == nil {
 		return "object bean"
 	}
@@ -231,6 +281,12 @@ func (d *BeanDefinition) export(exports ...interface{}) error {
 
 // NewBean 普通函数注册时需要使用 reflect.ValueOf(fn) 形式以避免和构造函数发生冲突。
 func NewBean(objOrCtor interface{}, ctorArgs ...arg.Arg) *BeanDefinition {
+	return newBean(objOrCtor, nil, ctorArgs, 2)
+}
+
+// newBean 创建 bean 的元数据，invoker 是构造函数的静态调用器，skip 是相对于调用
+// newBean 的方法需要跳过的调用栈层数。
+func newBean(objOrCtor interface{}, invoker arg.Invoker, ctorArgs []arg.Arg, skip int) *BeanDefinition {
 
 	var v reflect.Value
 	var fromValue bool
@@ -247,9 +303,8 @@ func NewBean(objOrCtor interface{}, ctorArgs ...arg.Arg) *BeanDefinition {
 		panic(errors.New("bean can't be nil"))
 	}
 
-	const skip = 2
 	var f *arg.Callable
-	_, file, line, _ := runtime.Caller(skip)
+	_, file, line, _ := runtime.Caller(skip + 1)
 
 	// 以 reflect.ValueOf(fn) 形式注册的函数被视为函数对象 bean 。
 	if t := v.Type(); !fromValue && t.Kind() == reflect.Func {
@@ -261,7 +316,7 @@ func NewBean(objOrCtor interface{}, ctorArgs ...arg.Arg) *BeanDefinition {
 		}
 
 		var err error
-		f, err = arg.Bind(objOrCtor, ctorArgs, skip)
+		f, err = arg.BindInvoker(objOrCtor, invoker, ctorArgs, skip+1)
 		util.Panic(err).When(err != nil)
 
 		out0 := t.Out(0)
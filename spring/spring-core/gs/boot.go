@@ -21,7 +21,9 @@ import (
 	"os"
 	"reflect"
 
+	"github.com/go-spring/spring-core/conf"
 	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/gs/cond"
 	"github.com/go-spring/spring-core/web"
 	"github.com/go-spring/spring-stl/util"
 )
@@ -63,6 +65,18 @@ func Property(key string, value interface{}) {
 	app.Property(key, value)
 }
 
+// MergeProperties 使用 p 中的属性覆盖应用的同名属性，所有的属性在一次修改中设置，
+// 批量设置属性时比逐个调用 Property 高效。
+func MergeProperties(p *conf.Properties) {
+	app.MergeProperties(p)
+}
+
+// RefreshProperties 使用 p 中的属性覆盖应用的同名属性，然后刷新所有绑定到属性
+// 上的动态属性值，例如在配置中心推送新的配置时调用。
+func RefreshProperties(p *conf.Properties) error {
+	return app.RefreshProperties(p)
+}
+
 // Object 注册对象形式的 bean ，需要注意的是该方法在注入开始后就不能再调用了。
 func Object(i interface{}) *BeanDefinition {
 	return app.c.register(NewBean(reflect.ValueOf(i)))
@@ -73,6 +87,23 @@ func Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return app.c.register(NewBean(ctor, args...))
 }
 
+// Import 导入模块，通常在 starter 包的 init 函数中调用，模块注册的 bean 只有在
+// conds 全部满足时才会生效。
+func Import(m Module, conds ...cond.Condition) {
+	app.Import(m, conds...)
+}
+
+// GroupRegister 注册一组由属性决定的 bean ，通常在 starter 包的 init 函数中调用，
+// 例如为每个配置的服务端点创建一个客户端 bean 。
+func GroupRegister(fn GroupFunc) {
+	app.GroupRegister(fn)
+}
+
+// OnShutdown 注册程序关闭时执行的钩子函数，可以通过 Phase 方法设置执行阶段。
+func OnShutdown(fn func(ctx context.Context) error) *ShutdownHook {
+	return app.OnShutdown(fn)
+}
+
 // Go 创建安全可等待的 goroutine，fn 要求的 ctx 对象由 IoC 容器提供，当 IoC 容
 // 器关闭时 ctx会 发出 Done 信号， fn 在接收到此信号后应当立即退出。
 func Go(fn func(ctx context.Context)) {
@@ -170,3 +201,10 @@ func GrpcClient(fn interface{}, endpoint string) *BeanDefinition {
 func GrpcServer(serviceName string, fn interface{}, service interface{}) *BeanDefinition {
 	return app.GrpcServer(serviceName, fn, service)
 }
+
+// GrpcGateway 注册 gRPC 服务的 HTTP 网关，fn 是 grpc-gateway 自动生成的
+// RegisterXxxHandlerServer 函数，serviceName 必须和 GrpcServer 注册时的服务
+// 名称一致。
+func GrpcGateway(serviceName string, fn interface{}) *BeanDefinition {
+	return app.GrpcGateway(serviceName, fn)
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package boot 提供开箱即用的程序入口，按照约定加载配置、打印 banner 、响应
+// 退出信号，并阻塞直到程序退出，使 main 函数只需要一行代码。
+//
+//	func main() {
+//		boot.Main(boot.Name("demo"), boot.Profile("dev"))
+//	}
+package boot
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/environ"
+	"github.com/go-spring/spring-core/log"
+)
+
+// Option 程序启动选项。
+type Option func(o *options)
+
+type options struct {
+	name       string
+	profile    string
+	locations  []string
+	banner     string
+	showBanner bool
+	props      map[string]interface{}
+}
+
+// Name 设置应用的名称，即 spring.application.name 属性。
+func Name(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Profile 设置激活的 profile ，即 spring.profiles.active 属性。
+func Profile(profile string) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// ConfigLocations 设置配置文件的位置，默认为 config/ 目录。
+func ConfigLocations(locations ...string) Option {
+	return func(o *options) {
+		o.locations = append(o.locations, locations...)
+	}
+}
+
+// Banner 设置自定义的 banner 字符串。
+func Banner(banner string) Option {
+	return func(o *options) {
+		o.banner = banner
+	}
+}
+
+// NoBanner 不打印 banner 。
+func NoBanner() Option {
+	return func(o *options) {
+		o.showBanner = false
+	}
+}
+
+// Property 设置属性值，配置文件、环境变量和命令行参数中的同名属性会覆盖该值。
+func Property(key string, value interface{}) Option {
+	return func(o *options) {
+		o.props[key] = value
+	}
+}
+
+// Run 按照约定启动程序：设置启动选项，加载配置文件，打印 banner ，收到 SIGINT
+// 或者 SIGTERM 信号时关闭程序，然后阻塞直到程序退出。以上启动选项都可以通过
+// 环境变量或者命令行参数覆盖。
+func Run(opts ...Option) error {
+	o := &options{showBanner: true, props: make(map[string]interface{})}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.name != "" {
+		o.props[environ.SpringApplicationName] = o.name
+	}
+	if o.profile != "" {
+		o.props[environ.SpringProfilesActive] = o.profile
+	}
+	if len(o.locations) > 0 {
+		o.props[environ.SpringConfigLocations] = strings.Join(o.locations, ",")
+	}
+	if o.banner != "" {
+		gs.Banner(o.banner)
+	}
+	o.props[environ.SpringBannerVisible] = o.showBanner
+	gs.MergeProperties(conf.Map(o.props))
+	return gs.Run()
+}
+
+// Main 调用 Run 启动程序，启动失败时打印错误并以非零状态码退出进程。
+func Main(opts ...Option) {
+	if err := Run(opts...); err != nil {
+		log.Errorf("application failed to start: %v", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package boot_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/boot"
+	"github.com/go-spring/spring-core/gs/environ"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestRun(t *testing.T) {
+
+	var p gs.Pandora
+	type PandoraAware struct{}
+	gs.Provide(func(b gs.Pandora) PandoraAware {
+		p = b
+		return PandoraAware{}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- boot.Run(
+			boot.ConfigLocations("../testdata/config/"),
+			boot.Profile("test"),
+			boot.NoBanner(),
+			boot.Property("boot.enabled", true),
+			boot.Property(environ.EnablePandora, true),
+		)
+	}()
+	select {
+	case err := <-done:
+		t.Fatal(err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, p.Prop("spring.application.name"), "test.yaml")
+	assert.Equal(t, p.Prop("spring.profiles.active"), "test")
+	assert.Equal(t, p.Prop("boot.enabled"), "true")
+
+	gs.ShutDown(errors.New("run test end"))
+	assert.Nil(t, <-done)
+}
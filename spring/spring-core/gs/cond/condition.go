@@ -24,10 +24,10 @@ import (
 	"strings"
 
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/expr"
 	"github.com/go-spring/spring-core/gs/bean"
 	"github.com/go-spring/spring-core/gs/environ"
 	"github.com/go-spring/spring-stl/cast"
-	"github.com/go-spring/spring-stl/util"
 )
 
 // Context IoC 容器对 cond 模块提供的最小功能集。
@@ -127,7 +127,27 @@ func (c *onSingleCandidate) Matches(ctx Context) (bool, error) {
 type onExpression struct{ expression string }
 
 func (c *onExpression) Matches(ctx Context) (bool, error) {
-	return false, util.UnimplementedMethod
+	return expr.EvalBool(c.expression, exprEnv{ctx})
+}
+
+// exprEnv 条件表达式的执行环境，bean 引用返回 bean 的元数据，因为此时 bean 可
+// 能还没有完成注入。
+type exprEnv struct{ ctx Context }
+
+func (e exprEnv) Prop(key string) (string, bool) {
+	v := e.ctx.Prop(key)
+	if v == nil {
+		return "", false
+	}
+	return v.(string), true
+}
+
+func (e exprEnv) Bean(name string) (interface{}, bool, error) {
+	beans, err := e.ctx.Find(name)
+	if err != nil || len(beans) == 0 {
+		return nil, false, err
+	}
+	return beans[0], true, nil
 }
 
 // Operator 条件操作符，包含 Or、And、None 三种。
@@ -339,7 +359,8 @@ func (c *conditional) OnSingleCandidate(selector bean.Selector) *conditional {
 	return c.On(&onSingleCandidate{selector: selector})
 }
 
-// OnExpression 返回一个以 onExpression 为开始条件的计算式。
+// OnExpression 返回一个以 onExpression 为开始条件的计算式，表达式的语法参见
+// expr 包，例如 ${cache.size:=0} > 100 && @redisClient 。
 func OnExpression(expression string) *conditional {
 	return New().OnExpression(expression)
 }
@@ -368,3 +389,14 @@ func OnProfile(profile string) *conditional {
 func (c *conditional) OnProfile(profile string) *conditional {
 	return c.OnProperty(environ.SpringProfilesActive, HavingValue(profile))
 }
+
+// OnFeature 返回一个以功能开关 feature.flags.{name} 的属性值是否为 true 为开始
+// 条件的计算式。bean 的注册条件在容器刷新时计算，此时只能使用属性中的开关值。
+func OnFeature(name string) *conditional {
+	return New().OnFeature(name)
+}
+
+// OnFeature 添加一个功能开关 feature.flags.{name} 的属性值是否为 true 的条件。
+func (c *conditional) OnFeature(name string) *conditional {
+	return c.OnProperty("feature.flags."+name, HavingValue("true"))
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/gs/bean"
+)
+
+// Context 容器为构造函数、Option 函数以及 Invoke 函数自动提供的上下文，函数只需
+// 要声明 Context 类型的参数即可获得，不需要开启 Pandora 。Context 本身也是容器
+// 的 context.Context ，在容器关闭时发出 Done 信号。通过 Get 方法可以在容器刷新
+// 之后延迟获取 bean 。
+type Context interface {
+	context.Context
+	Go(fn func(ctx context.Context))
+	Prop(key string, opts ...conf.GetOption) interface{}
+	Get(i interface{}, selectors ...bean.Selector) error
+}
+
+type containerContext struct {
+	context.Context
+	p *pandora
+}
+
+func (c *containerContext) Go(fn func(ctx context.Context)) {
+	c.p.Go(fn)
+}
+
+func (c *containerContext) Prop(key string, opts ...conf.GetOption) interface{} {
+	return c.p.Prop(key, opts...)
+}
+
+func (c *containerContext) Get(i interface{}, selectors ...bean.Selector) error {
+	return c.p.Get(i, selectors...)
+}
+
+var (
+	stdContextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	contextType    = reflect.TypeOf((*Context)(nil)).Elem()
+)
+
+// supply 返回容器为 t 类型的参数自动提供的值。
+func (c *Container) supply(t reflect.Type) (reflect.Value, bool) {
+	switch t {
+	case stdContextType:
+		return reflect.ValueOf(&c.ctx).Elem(), true
+	case contextType:
+		// 延迟获取 bean 需要保留容器的缓存，刷新之后容器不再修改自身的状态。
+		if c.state != Refreshed {
+			c.keepCache = true
+		}
+		v := reflect.New(t).Elem()
+		v.Set(reflect.ValueOf(&containerContext{Context: c.ctx, p: &pandora{c}}))
+		return v, true
+	}
+	return reflect.Value{}, false
+}
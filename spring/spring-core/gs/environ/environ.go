@@ -29,9 +29,16 @@ const IncludeEnvPatterns = "INCLUDE_ENV_PATTERNS"
 // ExcludeEnvPatterns 排除符合条件的环境变量。
 const ExcludeEnvPatterns = "EXCLUDE_ENV_PATTERNS"
 
+// DotenvLocation .env 文件的位置，默认为工作目录下的 .env 文件，文件中的变量
+// 与环境变量的处理方式相同，但是优先级低于真正的环境变量。
+const DotenvLocation = "DOTENV_LOCATION"
+
 // EnablePandora 是否允许 gs.Pandora 接口。
 const EnablePandora = "enable-pandora"
 
+// AllowBeanOverride 是否允许后注册的 bean 覆盖先注册的同 ID 的 bean 。
+const AllowBeanOverride = "spring.main.allow-bean-override"
+
 // SpringPidFile 保存进程 ID 的文件。
 const SpringPidFile = "spring.pid.file"
 
@@ -41,9 +48,33 @@ const SpringConfigLocations = "spring.config.locations"
 // SpringConfigExtensions 配置文件的扩展名，支持逗号分隔。
 const SpringConfigExtensions = "spring.config.extensions"
 
+// SpringConfigWritableLocation 保存运行时修改的属性的覆盖文件，例如
+// config/override.properties ，该文件中的属性优先于配置文件中的属性。
+const SpringConfigWritableLocation = "spring.config.writable-location"
+
 // SpringBannerVisible 是否显示 banner。
 const SpringBannerVisible = "spring.banner.visible"
 
+// SpringBannerText 自定义的 banner 字符串。
+const SpringBannerText = "spring.banner.text"
+
+// SpringBannerLocation 自定义的 banner 文件。
+const SpringBannerLocation = "spring.banner.location"
+
+// SpringShutdownTimeout 关闭宽限期，例如 30s 。
+const SpringShutdownTimeout = "spring.shutdown.timeout"
+
+// SpringShutdownPhaseTimeout 每个关闭阶段排空在途任务的最长时间，例如 10s ，
+// 默认只受关闭宽限期的限制。
+const SpringShutdownPhaseTimeout = "spring.shutdown.phase-timeout"
+
+// SpringShutdownSignals 触发程序关闭的信号，支持逗号分隔，默认为 SIGINT,SIGTERM 。
+const SpringShutdownSignals = "spring.shutdown.signals"
+
+// SpringShutdownOnGoroutineError 容器管理的 goroutine 返回错误或者发生 panic 时
+// 是否关闭程序，默认只记录错误并在程序退出时返回。
+const SpringShutdownOnGoroutineError = "spring.shutdown.on-goroutine-error"
+
 // SpringProfilesActive 当前应用的 profile 配置。
 const SpringProfilesActive = "spring.profiles.active"
 
@@ -17,11 +17,14 @@
 package gs
 
 import (
+	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/conf/dotenv"
 	"github.com/go-spring/spring-core/gs/environ"
 )
 
@@ -33,40 +36,84 @@ type Environment interface {
 	Get(key string, opts ...conf.GetOption) interface{}
 }
 
+const (
+	OriginSystemEnv = "systemEnvironment" // 来自环境变量的属性
+	OriginDotenv    = "dotenv"            // 来自 .env 文件的属性
+	OriginKube      = "kubernetes"        // 来自 Kubernetes 挂载的 ConfigMap 和 Secret 的属性
+	OriginCmdArgs   = "commandLineArgs"   // 来自命令行参数的属性
+	OriginCode      = "code"              // 通过代码设置的属性
+)
+
 type environment struct {
-	p *conf.Properties
+	p       *conf.Properties
+	origins map[string]string // 属性的来源
 }
 
 func newEnvironment() *environment {
-	return &environment{p: conf.New()}
+	return &environment{p: conf.New(), origins: make(map[string]string)}
 }
 
 // loadCmdArgs 加载 -name value 形式的命令行参数。
 func loadCmdArgs(p *conf.Properties) {
-	for i := 0; i < len(os.Args); i++ {
+	p.Batch(func(p *conf.Properties) {
+		for i := 0; i < len(os.Args); i++ {
 
-		s := os.Args[i]
-		if !strings.HasPrefix(s, "-") {
-			continue
-		}
+			s := os.Args[i]
+			if !strings.HasPrefix(s, "-") {
+				continue
+			}
 
-		k, v := s[1:], ""
-		if i >= len(os.Args)-1 {
-			p.Set(k, v)
-			break
-		}
+			k, v := s[1:], ""
+			if i >= len(os.Args)-1 {
+				p.Set(k, v)
+				break
+			}
 
-		if !strings.HasPrefix(os.Args[i+1], "-") {
-			v = os.Args[i+1]
-			i++
+			if !strings.HasPrefix(os.Args[i+1], "-") {
+				v = os.Args[i+1]
+				i++
+			}
+			p.Set(k, v)
 		}
-		p.Set(k, v)
-	}
+	})
 }
 
 // loadSystemEnv 添加符合 includes 条件的环境变量，排除符合 excludes 条件的
 // 环境变量。如果发现存在允许通过环境变量覆盖的属性名，那么保存时转换成真正的属性名。
 func loadSystemEnv(p *conf.Properties) error {
+	return loadEnv(p, os.Environ())
+}
+
+// loadDotenv 加载 .env 文件中的变量，处理方式与环境变量相同，文件不存在时忽略。
+func loadDotenv(p *conf.Properties) error {
+
+	file := ".env"
+	if s, ok := os.LookupEnv(environ.DotenvLocation); ok {
+		file = s
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	m, err := dotenv.Read(b)
+	if err != nil {
+		return fmt.Errorf("load %s error: %w", file, err)
+	}
+
+	env := make([]string, 0, len(m))
+	for k, v := range m {
+		env = append(env, k+"="+v.(string))
+	}
+	sort.Strings(env)
+	return loadEnv(p, env)
+}
+
+// loadEnv 加载 KEY=VALUE 形式的环境变量。
+func loadEnv(p *conf.Properties, env []string) error {
 
 	toRex := func(patterns []string) ([]*regexp.Regexp, error) {
 		var rex []*regexp.Regexp
@@ -107,39 +154,58 @@ func loadSystemEnv(p *conf.Properties) error {
 		return false
 	}
 
-	for _, env := range os.Environ() {
+	// 在一次批量修改中设置所有的环境变量，避免每次设置都复制整个属性列表
+	p.Batch(func(p *conf.Properties) {
+		for _, s := range env {
 
-		kv := strings.SplitN(env, "=", 2)
-		if len(kv) == 1 {
-			continue
-		}
+			kv := strings.SplitN(s, "=", 2)
+			if len(kv) == 1 {
+				continue
+			}
 
-		k, v := kv[0], kv[1]
-		if k == "" || v == "" {
-			continue
-		}
+			k, v := kv[0], kv[1]
+			if k == "" || v == "" {
+				continue
+			}
 
-		if strings.HasPrefix(k, EnvPrefix) {
-			propKey := strings.TrimPrefix(k, EnvPrefix)
-			propKey = strings.ReplaceAll(propKey, "_", ".")
-			p.Set(strings.ToLower(propKey), v)
-			continue
-		}
+			if strings.HasPrefix(k, EnvPrefix) {
+				propKey := strings.TrimPrefix(k, EnvPrefix)
+				propKey = strings.ReplaceAll(propKey, "_", ".")
+				p.Set(strings.ToLower(propKey), v)
+				continue
+			}
 
-		if matches(excludeRex, k) || !matches(includeRex, k) {
-			continue
+			if matches(excludeRex, k) || !matches(includeRex, k) {
+				continue
+			}
+			p.Set(k, v)
 		}
-		p.Set(k, v)
-	}
+	})
 	return nil
 }
 
 func (e *environment) prepare() error {
-	err := loadSystemEnv(e.p)
+	err := loadDotenv(e.p)
 	if err != nil {
 		return err
 	}
-	loadCmdArgs(e.p)
+	for _, k := range e.p.Keys() {
+		e.origins[k] = OriginDotenv
+	}
+	env := conf.New()
+	if err = loadSystemEnv(env); err != nil {
+		return err
+	}
+	e.p.Merge(env)
+	for _, k := range env.Keys() {
+		e.origins[k] = OriginSystemEnv
+	}
+	args := conf.New()
+	loadCmdArgs(args)
+	e.p.Merge(args)
+	for _, k := range args.Keys() {
+		e.origins[k] = OriginCmdArgs
+	}
 	return nil
 }
 
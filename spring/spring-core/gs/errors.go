@@ -0,0 +1,143 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 错误码，便于程序根据错误类型进行处理，也方便在日志和文档中检索。
+const (
+	CodeBeanNotFound      = "GS-001"
+	CodeTooManyCandidates = "GS-002"
+	CodeConditionFailed   = "GS-003"
+)
+
+// ErrBeanNotFound 没有找到符合条件的 bean 。
+type ErrBeanNotFound struct {
+	Selector string       // 选择器
+	Type     reflect.Type // 接收者类型
+	Hint     string       // 修复建议
+}
+
+// Code 返回错误码。
+func (e *ErrBeanNotFound) Code() string {
+	return CodeBeanNotFound
+}
+
+func (e *ErrBeanNotFound) Error() string {
+	msg := fmt.Sprintf("can't find bean, bean:%q type:%q", e.Selector, e.Type)
+	return withHint(e.Code(), msg, e.Hint)
+}
+
+// ErrTooManyCandidates 找到多个符合条件的 bean ，无法确定使用哪一个。
+type ErrTooManyCandidates struct {
+	Selector   string       // 选择器
+	Type       reflect.Type // 接收者类型
+	Primary    bool         // 是否是多个主版本的 bean 之间发生冲突
+	Candidates []string     // 候选 bean 的描述
+	Hint       string       // 修复建议
+}
+
+// Code 返回错误码。
+func (e *ErrTooManyCandidates) Code() string {
+	return CodeTooManyCandidates
+}
+
+func (e *ErrTooManyCandidates) Error() string {
+	var kind string
+	if e.Primary {
+		kind = "primary "
+	}
+	msg := fmt.Sprintf("found %d %sbeans, bean:%q type:%q [( %s )]", len(e.Candidates),
+		kind, e.Selector, e.Type, strings.Join(e.Candidates, " ), ( "))
+	return withHint(e.Code(), msg, e.Hint)
+}
+
+// ErrConditionFailed 符合条件的 bean 都因为条件不满足而被排除了。
+type ErrConditionFailed struct {
+	Selector   string       // 选择器
+	Type       reflect.Type // 接收者类型
+	Candidates []string     // 被排除的 bean 的描述
+	Hint       string       // 修复建议
+}
+
+// Code 返回错误码。
+func (e *ErrConditionFailed) Code() string {
+	return CodeConditionFailed
+}
+
+func (e *ErrConditionFailed) Error() string {
+	msg := fmt.Sprintf("can't find bean, bean:%q type:%q, condition failed [( %s )]",
+		e.Selector, e.Type, strings.Join(e.Candidates, " ), ( "))
+	return withHint(e.Code(), msg, e.Hint)
+}
+
+func withHint(code, msg, hint string) string {
+	if hint == "" {
+		return fmt.Sprintf("%s (%s)", msg, code)
+	}
+	return fmt.Sprintf("%s (%s) hint: %s", msg, code, hint)
+}
+
+func beanStrings(beans []*BeanDefinition) []string {
+	var s []string
+	for _, b := range beans {
+		s = append(s, b.String())
+	}
+	return s
+}
+
+// notFound 返回没有找到 bean 时的错误，如果存在因为条件不满足而被排除的 bean
+// 则返回 ErrConditionFailed 错误，便于使用者定位问题。
+func (c *Container) notFound(tag wireTag, t reflect.Type) error {
+	var excluded []*BeanDefinition
+	for _, b := range c.excluded {
+		if b.Type().AssignableTo(t) && b.Match(tag.typeName, tag.beanName) {
+			excluded = append(excluded, b)
+		}
+	}
+	if len(excluded) > 0 {
+		return &ErrConditionFailed{
+			Selector:   tag.String(),
+			Type:       t,
+			Candidates: beanStrings(excluded),
+			Hint:       "check the conditions of the candidate beans, e.g. missing properties or beans",
+		}
+	}
+	hint := "register a bean of this type, or mark the receiver as nullable with '?'"
+	if t.Kind() == reflect.Interface {
+		hint = "register a bean that implements this interface and call Export() on it"
+	}
+	return &ErrBeanNotFound{Selector: tag.String(), Type: t, Hint: hint}
+}
+
+func tooManyCandidates(tag wireTag, t reflect.Type, primary bool, beans []*BeanDefinition) error {
+	hint := "use a selector to choose one of them, or mark one as Primary()"
+	if primary {
+		hint = "only one of the candidate beans can be marked as Primary()"
+	}
+	return &ErrTooManyCandidates{
+		Selector:   tag.String(),
+		Type:       t,
+		Primary:    primary,
+		Candidates: beanStrings(beans),
+		Hint:       hint,
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GraphFormat 依赖图的导出格式。
+type GraphFormat string
+
+const (
+	GraphDOT     = GraphFormat("dot")     // Graphviz DOT 格式
+	GraphMermaid = GraphFormat("mermaid") // Mermaid 流程图格式
+	GraphJSON    = GraphFormat("json")    // JSON 格式
+)
+
+// bean 的条件状态。
+const (
+	ConditionNone    = "none"    // 没有设置条件
+	ConditionMatched = "matched" // 条件满足
+	ConditionFailed  = "failed"  // 条件不满足，bean 被排除
+)
+
+// GraphNode 依赖图中的 bean 结点。
+type GraphNode struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Source     string   `json:"source"`
+	Condition  string   `json:"condition"`
+	Properties []string `json:"properties,omitempty"`
+}
+
+// GraphEdge 依赖图中的边，表示 From 依赖 To 。
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph bean 的依赖图。
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// snapshotGraph 保存依赖图的快照，因为容器刷新后可能会清空 bean 的缓存。
+func (c *Container) snapshotGraph() {
+
+	g := &Graph{}
+
+	addNode := func(b *BeanDefinition, condition string) {
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:         b.ID(),
+			Name:       b.BeanName(),
+			Type:       b.Type().String(),
+			Source:     b.FileLine(),
+			Condition:  condition,
+			Properties: append([]string(nil), b.properties...),
+		})
+	}
+
+	for _, b := range c.beans {
+		if b.status == Deleted {
+			continue
+		}
+		condition := ConditionNone
+		if b.cond != nil {
+			condition = ConditionMatched
+		}
+		addNode(b, condition)
+		for _, d := range b.dependencies {
+			g.Edges = append(g.Edges, GraphEdge{From: b.ID(), To: d.ID()})
+		}
+	}
+
+	for _, b := range c.excluded {
+		addNode(b, ConditionFailed)
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From == g.Edges[j].From {
+			return g.Edges[i].To < g.Edges[j].To
+		}
+		return g.Edges[i].From < g.Edges[j].From
+	})
+	c.graph = g
+}
+
+// ExportGraph 以 format 格式导出 bean 的依赖图，包括 bean 之间的依赖关系、bean
+// 的条件状态以及 bean 引用的属性，必须在容器刷新之后调用。
+func (c *Container) ExportGraph(w io.Writer, format GraphFormat) error {
+	if c.graph == nil {
+		return errors.New("container not refreshed")
+	}
+	switch format {
+	case GraphDOT:
+		return writeDOT(w, c.graph)
+	case GraphMermaid:
+		return writeMermaid(w, c.graph)
+	case GraphJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(c.graph)
+	default:
+		return fmt.Errorf("unsupported graph format %q", format)
+	}
+}
+
+// properties 返回依赖图中引用的所有属性，已排序。
+func (g *Graph) properties() []string {
+	m := make(map[string]struct{})
+	for _, n := range g.Nodes {
+		for _, p := range n.Properties {
+			m[p] = struct{}{}
+		}
+	}
+	var ret []string
+	for p := range m {
+		ret = append(ret, p)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func writeDOT(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	sb.WriteString("digraph beans {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		label := n.Name + "\\n" + n.Type
+		switch n.Condition {
+		case ConditionFailed:
+			fmt.Fprintf(&sb, "  %q [label=%q, style=dashed, color=gray];\n", n.ID, label)
+		case ConditionMatched:
+			fmt.Fprintf(&sb, "  %q [label=%q, color=blue];\n", n.ID, label)
+		default:
+			fmt.Fprintf(&sb, "  %q [label=%q];\n", n.ID, label)
+		}
+	}
+	for _, p := range g.properties() {
+		fmt.Fprintf(&sb, "  %q [label=%q, shape=note];\n", "${"+p+"}", p)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %q -> %q;\n", e.From, e.To)
+	}
+	for _, n := range g.Nodes {
+		for _, p := range n.Properties {
+			fmt.Fprintf(&sb, "  %q -> %q [style=dotted];\n", n.ID, "${"+p+"}")
+		}
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeMermaid(w io.Writer, g *Graph) error {
+
+	// Mermaid 的结点 ID 不能包含特殊字符，所以使用序号作为结点 ID 。
+	ids := make(map[string]string)
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	props := make(map[string]string)
+	for i, p := range g.properties() {
+		props[p] = fmt.Sprintf("p%d", i)
+	}
+
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "  %s[%s]\n", ids[n.ID], quote(n.Name+"<br/>"+n.Type))
+	}
+	for _, p := range g.properties() {
+		fmt.Fprintf(&sb, "  %s>%s]\n", props[p], quote(p))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	for _, n := range g.Nodes {
+		for _, p := range n.Properties {
+			fmt.Fprintf(&sb, "  %s -.-> %s\n", ids[n.ID], props[p])
+		}
+	}
+	sb.WriteString("  classDef failed stroke-dasharray: 5 5,color:#999\n")
+	sb.WriteString("  classDef matched stroke:#00f\n")
+	for _, n := range g.Nodes {
+		switch n.Condition {
+		case ConditionFailed, ConditionMatched:
+			fmt.Fprintf(&sb, "  class %s %s\n", ids[n.ID], n.Condition)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -29,13 +29,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dync"
 	"github.com/go-spring/spring-core/gs/arg"
 	"github.com/go-spring/spring-core/gs/bean"
 	"github.com/go-spring/spring-core/gs/cond"
 	"github.com/go-spring/spring-core/gs/environ"
 	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
 	"github.com/go-spring/spring-stl/cast"
 	"github.com/go-spring/spring-stl/util"
 )
@@ -56,7 +59,8 @@ const (
 // go-spring 严格区分了这两种概念，在描述对 bean 的处理时要么单独使用依赖注入或属
 // 性绑定，要么同时使用依赖注入和属性绑定。
 type Container struct {
-	p *conf.Properties
+	name string // 容器的名称，具名容器注册在进程内的注册表中
+	p    *conf.Properties
 
 	state refreshState
 
@@ -64,18 +68,42 @@ type Container struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	beans       []*BeanDefinition
+	goMutex   sync.Mutex
+	goErrors  []error         // 容器管理的 goroutine 返回的错误及发生的 panic
+	onGoError func(err error) // goroutine 出错时的回调，例如触发程序关闭
+
+	beans       []*BeanDefinition // 按注册顺序排列，保证遍历 bean 的顺序是确定的
 	beansById   map[string]*BeanDefinition
 	beansByName map[string][]*BeanDefinition
 	beansByType map[reflect.Type][]*BeanDefinition
+	excluded    []*BeanDefinition // 因为条件不满足而被排除的 bean
+	graph       *Graph            // 刷新后的依赖图快照
 
 	destroyers []func() // 使用函数闭包来避免引入新的类型。
+
+	modules []importedModule // 导入的模块
+	groups  []GroupFunc      // 由属性决定的 bean 组
+	bridges []bridge         // 从其他容器导入的 bean
+	hooks   []*ShutdownHook  // 关闭钩子
+
+	lifecycles []SmartLifecycle // 关闭时需要排空在途任务的 bean
+
+	dync dync.Manager // 绑定到属性上的动态属性值
+
+	keepCache bool // 有 bean 依赖 Context 时刷新后保留缓存
+
+	allowOverride bool // 允许后注册的 bean 覆盖先注册的同 ID 的 bean
+
+	steps       []StartupStep // 启动过程中各个阶段的耗时
+	beanTimings []BeanTiming  // 各个 bean 的注入耗时
+
+	profile *profiler // 开启性能分析时收集刷新过程的性能数据
 }
 
 // New 创建 IoC 容器。
-func New() *Container {
+func New(opts ...Option) *Container {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Container{
+	c := &Container{
 		p:           conf.New(),
 		ctx:         ctx,
 		cancel:      cancel,
@@ -83,6 +111,13 @@ func New() *Container {
 		beansByName: make(map[string][]*BeanDefinition),
 		beansByType: make(map[reflect.Type][]*BeanDefinition),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.name != "" {
+		registerContainer(c)
+	}
+	return c
 }
 
 // Load 从属性文件加载属性列表，file 可以是绝对路径，也可以是相对路径。该方法会覆
@@ -100,6 +135,19 @@ func (c *Container) Property(key string, value interface{}) {
 	c.p.Set(key, value)
 }
 
+// MergeProperties 使用 p 中的属性覆盖容器中的同名属性，所有的属性在一次修改中
+// 设置，批量设置属性时比逐个调用 Property 高效。
+func (c *Container) MergeProperties(p *conf.Properties) {
+	c.p.Merge(p)
+}
+
+// RefreshProperties 使用 p 中的属性覆盖容器中的同名属性，然后刷新所有绑定到属
+// 性上的动态属性值，校验失败的动态属性值保留原来的值并返回错误。
+func (c *Container) RefreshProperties(p *conf.Properties) error {
+	c.p.Merge(p)
+	return c.dync.Refresh(c.p.Snapshot())
+}
+
 func (c *Container) register(b *BeanDefinition) *BeanDefinition {
 	if c.state != Unrefreshed {
 		panic(errors.New("should call before Refresh"))
@@ -114,26 +162,76 @@ func (c *Container) Object(i interface{}) *BeanDefinition {
 }
 
 // Provide 注册构造函数形式的 bean ，需要注意的是该方法在注入开始后就不能再调用了。
+// 构造函数以接口形式返回结构体或者结构体指针时，容器对接口的动态值进行属性绑定和
+// 依赖注入，与 Object 方式注册的 bean 的行为一致。
 func (c *Container) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return c.register(NewBean(ctor, args...))
 }
 
 // Go 创建安全可等待的 goroutine，fn 要求的 ctx 对象由 IoC 容器提供，当 IoC 容
-// 器关闭时 ctx会 发出 Done 信号， fn 在接收到此信号后应当立即退出。
+// 器关闭时 ctx会 发出 Done 信号， fn 在接收到此信号后应当立即退出。fn 中发生的
+// panic 会被记录下来，可以通过 Err 获取。
 func (c *Container) Go(fn func(ctx context.Context)) {
+	c.GoE(func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	})
+}
+
+// GoE 与 Go 相同，区别是 fn 可以返回错误，返回的错误与发生的 panic 一样被记录
+// 下来，可以通过 Err 获取。
+func (c *Container) GoE(fn func(ctx context.Context) error) {
 
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-
-		defer func() {
-			if r := recover(); r != nil {
-				log.Errorf("%v, %s", r, debug.Stack())
+		if err := c.runGo(fn); err != nil {
+			log.Error(err)
+			c.goMutex.Lock()
+			c.goErrors = append(c.goErrors, err)
+			c.goMutex.Unlock()
+			if c.onGoError != nil {
+				c.onGoError(err)
 			}
-		}()
+		}
+	}()
+}
 
-		fn(c.ctx)
+func (c *Container) runGo(fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("goroutine panic: %v, %s", r, debug.Stack())
+		}
 	}()
+	return fn(c.ctx)
+}
+
+// goroutineErrors 容器管理的 goroutine 产生的所有错误。容器的 goroutine 管理与
+// util.Group 的行为一致，spring-stl 发布新版本后改为直接使用 util.Group 。
+type goroutineErrors []error
+
+func (e goroutineErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d goroutine errors occurred:", len(e))
+	for _, err := range e {
+		sb.WriteString("\n\t* ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Err 返回容器管理的 goroutine 返回的错误及发生的 panic ，没有错误时返回 nil 。
+// 通常在 Close 之后调用，此时所有的 goroutine 都已经结束(或者超过了宽限期)。
+func (c *Container) Err() error {
+	c.goMutex.Lock()
+	defer c.goMutex.Unlock()
+	if len(c.goErrors) == 0 {
+		return nil
+	}
+	return append(goroutineErrors(nil), c.goErrors...)
 }
 
 // destroyer 保存具有销毁函数的 bean 以及销毁函数的调用顺序。
@@ -178,6 +276,12 @@ type lazyField struct {
 	tag  string
 }
 
+// configBinding 绑定到属性上的配置结构体。
+type configBinding struct {
+	tag string
+	v   reflect.Value
+}
+
 // wiringStack 记录 bean 的注入路径。
 type wiringStack struct {
 	beans        []*BeanDefinition
@@ -207,6 +311,14 @@ func (s *wiringStack) popBack() {
 	log.Tracef("wired %s", b)
 }
 
+// current 返回正在注入的 bean ，没有时返回 nil 。
+func (s *wiringStack) current() *BeanDefinition {
+	if n := len(s.beans); n > 0 {
+		return s.beans[n-1]
+	}
+	return nil
+}
+
 // path 返回 bean 的注入路径。
 func (s *wiringStack) path() (path string) {
 	for _, b := range s.beans {
@@ -261,6 +373,7 @@ func (c *Container) clearCache() {
 	c.beansById = nil
 	c.beansByName = nil
 	c.beansByType = nil
+	c.excluded = nil
 }
 
 func (c *Container) enablePandora() bool {
@@ -272,35 +385,53 @@ func (c *Container) Refresh() error {
 	if err := c.refresh(); err != nil {
 		return err
 	}
-	if !c.enablePandora() {
+	if !c.enablePandora() && !c.keepCache {
 		c.clearCache()
 	}
 	return nil
 }
 
-func (c *Container) refresh() error {
+func (c *Container) refresh() (err error) {
 
 	if c.state != Unrefreshed {
 		return errors.New("container already refreshed")
 	}
 
+	if c.profile != nil {
+		c.profile.startRefresh()
+		defer func() { c.profile.stopRefresh(c.countBeans(), err == nil) }()
+	}
+
+	c.configureModules()
+	c.registerGroups()
+
+	if err = c.registerBridges(); err != nil {
+		return err
+	}
+
 	if c.enablePandora() {
 		c.Object(&pandora{c}).Export((*Pandora)(nil))
 	}
 
 	c.state = Refreshing
+	start := time.Now()
 
 	for _, b := range c.beans {
 		if err := c.registerBean(b); err != nil {
 			return err
 		}
 	}
+	c.step("register-beans", start)
 
-	for _, b := range c.beansById {
+	// 按照注册顺序决议和注入 bean，而不是遍历 map，这样每次启动的决议结果、注入
+	// 顺序和错误信息都是一样的。
+	resolveStart := time.Now()
+	for _, b := range c.beans {
 		if err := c.resolveBean(b); err != nil {
 			return err
 		}
 	}
+	c.step("resolve-beans", resolveStart)
 
 	stack := newWiringStack()
 
@@ -310,7 +441,11 @@ func (c *Container) refresh() error {
 		}
 	}()
 
-	for _, b := range c.beansById {
+	wireStart := time.Now()
+	for _, b := range c.beans {
+		if b.status == Deleted {
+			continue
+		}
 		if err := c.wireBean(b, stack); err != nil {
 			return err
 		}
@@ -320,20 +455,56 @@ func (c *Container) refresh() error {
 	for _, f := range stack.lazyFields {
 		tag := strings.TrimSuffix(f.tag, ",lazy")
 		if err := c.wireByTag(f.v, tag, stack); err != nil {
-			return fmt.Errorf("%q wired error: %s", f.name, err.Error())
+			return fmt.Errorf("%q wired error: %w", f.name, err)
 		}
 	}
+	c.step("wire-beans", wireStart)
+	c.saveBeanTimings()
 
 	c.destroyers = stack.sortDestroyers()
+	c.lifecycles = c.collectLifecycles()
 	c.state = Refreshed
+	c.snapshotGraph()
+
+	metrics.Default.Gauge("spring_container_beans", "Number of beans in the container.").With().Set(float64(c.countBeans()))
+	metrics.Default.Gauge("spring_container_refresh_seconds", "Duration of the last container refresh in seconds.").With().Set(time.Since(start).Seconds())
 
 	log.Info("container refreshed successfully")
 	return nil
 }
 
+// countBeans 返回有效的 bean 的数量。
+func (c *Container) countBeans() int {
+	count := 0
+	for _, b := range c.beans {
+		if b.status != Deleted {
+			count++
+		}
+	}
+	return count
+}
+
+// AllowBeanOverride 允许后注册的 bean 覆盖先注册的同 ID 的 bean，覆盖时输出警告
+// 日志，便于迁移大型项目时逐步消除重复注册。也可以通过 spring.main.allow-bean-override
+// 属性开启。
+func AllowBeanOverride() Option {
+	return func(c *Container) {
+		c.allowOverride = true
+	}
+}
+
+func (c *Container) allowBeanOverride() bool {
+	return c.allowOverride || cast.ToBool(c.p.Get(environ.AllowBeanOverride))
+}
+
 func (c *Container) registerBean(b *BeanDefinition) error {
 	if d, ok := c.beansById[b.ID()]; ok {
-		return fmt.Errorf("found duplicate beans [%s] [%s]", b, d)
+		if !c.allowBeanOverride() {
+			return fmt.Errorf("found duplicate beans %q registered at %s and %s, "+
+				"use Name() to give them different names", b.ID(), d.FileLine(), b.FileLine())
+		}
+		log.Warnf("bean %q registered at %s is overridden by %s", b.ID(), d.FileLine(), b.FileLine())
+		d.status = Deleted
 	}
 	c.beansById[b.ID()] = b
 	return nil
@@ -353,6 +524,7 @@ func (c *Container) resolveBean(b *BeanDefinition) error {
 			return err
 		} else if !ok {
 			delete(c.beansById, b.ID())
+			c.excluded = append(c.excluded, b)
 			b.status = Deleted
 			return nil
 		}
@@ -381,7 +553,15 @@ type wireTag struct {
 	nullable bool
 }
 
-func parseWireTag(str string) (tag wireTag) {
+// parseWireTag 解析 str 对应的 tag 分解式，解析结果按照 str 缓存在整个进程中。
+func parseWireTag(str string) wireTag {
+	if tag, ok := wireTagCache.Load(str); ok {
+		return tag.(wireTag)
+	}
+	return storeTag(&wireTagCache, &wireTagCount, str, splitWireTag(str)).(wireTag)
+}
+
+func splitWireTag(str string) (tag wireTag) {
 
 	if str == "" {
 		return
@@ -425,7 +605,13 @@ func toWireTag(selector bean.Selector) wireTag {
 	case *BeanDefinition:
 		return parseWireTag(s.ID())
 	default:
-		return parseWireTag(util.TypeName(s) + ":")
+		t := util.TypeOf(s) // reflect.Type 和 reflect.Value 形式的选择器需要按照它们表示的类型缓存
+		if tag, ok := typeTagCache.Load(t); ok {
+			return tag.(wireTag)
+		}
+		tag := parseWireTag(util.TypeName(s) + ":")
+		typeTagCache.Store(t, tag)
+		return tag
 	}
 }
 
@@ -435,8 +621,8 @@ func (c *Container) findBean(selector bean.Selector) ([]*BeanDefinition, error)
 
 	finder := func(fn func(*BeanDefinition) bool) ([]*BeanDefinition, error) {
 		var result []*BeanDefinition
-		for _, b := range c.beansById {
-			if b.status == Resolving || !fn(b) {
+		for _, b := range c.beans {
+			if b.status == Resolving || b.status == Deleted || !fn(b) {
 				continue
 			}
 			if err := c.resolveBean(b); err != nil {
@@ -469,8 +655,10 @@ func (c *Container) findBean(selector bean.Selector) ([]*BeanDefinition, error)
 		if b.Type().AssignableTo(t) {
 			return true
 		}
+		// 具体类型的选择器只匹配可以赋值给该类型的 bean ，否则按照具体类型查找时会匹
+		// 配到所有的 bean 。接口类型的选择器还可以匹配导出了该接口的 bean 。
 		if t.Kind() != reflect.Interface {
-			return true
+			return false
 		}
 		_, ok := b.exports[t]
 		return ok
@@ -486,15 +674,19 @@ func (c *Container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 		return fmt.Errorf("bean:%q have been deleted", b.ID())
 	}
 
+	if d := stack.current(); d != nil {
+		d.addDependency(b)
+	}
+
 	if c.state == Refreshed && b.status == Wired {
 		return nil
 	}
 
-	defer func() {
-		if b.destroy != nil {
-			stack.destroyers.Remove(stack.destroyers.Back())
-		}
-	}()
+	// 桥接的 bean 已经在来源容器中完成注入，并且由来源容器负责销毁。
+	if b.bridged != "" {
+		b.status = Wired
+		return nil
+	}
 
 	// 记录注入路径上的销毁函数及其执行的先后顺序。
 	if _, ok := b.Interface().(interface{ OnDestroy() }); ok || b.destroy != nil {
@@ -502,7 +694,8 @@ func (c *Container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 		if i := stack.destroyers.Back(); i != nil {
 			d.after(i.Value.(*BeanDefinition))
 		}
-		stack.destroyers.PushBack(b)
+		e := stack.destroyers.PushBack(b)
+		defer stack.destroyers.Remove(e)
 	}
 
 	if b.status == Wired {
@@ -519,6 +712,7 @@ func (c *Container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 	}
 
 	b.status = Wiring
+	start := time.Now()
 
 	// 对当前 bean 的间接依赖项进行注入。
 	for _, s := range b.dependsOn {
@@ -545,6 +739,7 @@ func (c *Container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 	}
 
 	if b.init != nil {
+		c.count(CallInit)
 		fnValue := reflect.ValueOf(b.init)
 		out := fnValue.Call([]reflect.Value{b.Value()})
 		if len(out) > 0 && !out[0].IsNil() {
@@ -564,6 +759,11 @@ func (c *Container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 
 	b.status = Wired
 	stack.popBack()
+
+	b.wiringTime = time.Since(start)
+	if d := stack.current(); d != nil {
+		d.nestedTime += b.wiringTime
+	}
 	return nil
 }
 
@@ -577,13 +777,31 @@ func (a *argContext) Matches(c cond.Condition) (bool, error) {
 }
 
 func (a *argContext) Bind(v reflect.Value, tag string) error {
-	return a.c.p.Bind(v, conf.Tag(tag))
+	a.c.count(CallBind)
+	if err := a.c.p.Bind(v, conf.Tag(tag), conf.Beans(a.c.exprBeans(a.stack))); err != nil {
+		return err
+	}
+	if b := a.stack.current(); b != nil {
+		b.addProperty(tag)
+		if v.Kind() == reflect.Struct {
+			b.bindings = append(b.bindings, configBinding{tag: tag, v: v})
+		}
+	}
+	return nil
 }
 
 func (a *argContext) Wire(v reflect.Value, tag string) error {
 	return a.c.wireByTag(v, tag, a.stack)
 }
 
+func (a *argContext) WireFields(v reflect.Value) error {
+	return a.c.wireStruct(v, a.stack)
+}
+
+func (a *argContext) Supply(t reflect.Type) (reflect.Value, bool) {
+	return a.c.supply(t)
+}
+
 // getBeanValue 获取 bean 的值，如果是构造函数 bean 则执行其构造函数然后返回执行结果。
 func (c *Container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect.Value, error) {
 
@@ -591,6 +809,7 @@ func (c *Container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 		return b.Value(), nil
 	}
 
+	c.count(CallConstructor)
 	out, err := b.f.Call(&argContext{c: c, stack: stack})
 	if err != nil {
 		return reflect.Value{}, fmt.Errorf("%s:%q return error: %v", b.getClass(), b.FileLine(), err)
@@ -622,6 +841,24 @@ func (c *Container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 	return v, nil
 }
 
+// exprBeans 返回 #{...} 表达式中 bean 引用的查找函数，被引用的 bean 会先完成
+// 注入。
+func (c *Container) exprBeans(stack *wiringStack) conf.BeanFinder {
+	return func(name string) (interface{}, bool, error) {
+		beans, err := c.findBean(name)
+		if err != nil || len(beans) == 0 {
+			return nil, false, err
+		}
+		if len(beans) > 1 {
+			return nil, false, fmt.Errorf("found %d beans for @%s", len(beans), name)
+		}
+		if err = c.wireBean(beans[0], stack); err != nil {
+			return nil, false, err
+		}
+		return beans[0].Interface(), true, nil
+	}
+}
+
 // wireBeanValue 对 v 进行属性绑定和依赖注入，v 在传入时应该是一个已经初始化的值。
 func (c *Container) wireBeanValue(v reflect.Value, stack *wiringStack) error {
 
@@ -636,10 +873,26 @@ func (c *Container) wireBeanValue(v reflect.Value, stack *wiringStack) error {
 		return nil
 	}
 
-	err := c.p.Bind(ev)
+	c.count(CallBind)
+	err := c.p.Bind(ev, conf.Beans(c.exprBeans(stack)))
 	if err != nil {
 		return err
 	}
+	c.dync.Collect(ev)
+
+	// 记录通过 value 标签引用的属性以及绑定的配置结构体。
+	if b := stack.current(); b != nil {
+		for _, f := range planOf(ev.Type()).values {
+			b.addProperty(f.tag)
+			if f.config {
+				fv := ev.Field(f.index)
+				if !fv.CanInterface() {
+					fv = util.PatchValue(fv)
+				}
+				b.bindings = append(b.bindings, configBinding{tag: f.tag, v: fv})
+			}
+		}
+	}
 
 	return c.wireStruct(ev, stack)
 }
@@ -647,40 +900,32 @@ func (c *Container) wireBeanValue(v reflect.Value, stack *wiringStack) error {
 // wireStruct 对结构体进行依赖注入，需要注意的是这里不需要进行属性绑定。
 func (c *Container) wireStruct(v reflect.Value, stack *wiringStack) error {
 
-	t := v.Type()
-	typeName := t.Name()
-	if typeName == "" { // 简单类型没有名字
-		typeName = t.String()
-	}
-
-	for i := 0; i < t.NumField(); i++ {
-
-		ft := t.Field(i)
-		fv := v.Field(i)
+	for _, f := range planOf(v.Type()).fields {
 
+		fv := v.Field(f.index)
 		if !fv.CanInterface() {
 			fv = util.PatchValue(fv)
 		}
 
-		// 支持 autowire 和 inject 标签，支持 lazy 注入。
-		tag, ok := ft.Tag.Lookup("autowire")
-		if !ok {
-			tag, ok = ft.Tag.Lookup("inject")
-		}
-		if ok {
-			if strings.HasSuffix(tag, ",lazy") {
-				fieldName := typeName + "." + ft.Name
-				f := lazyField{v: fv, name: fieldName, tag: tag}
-				stack.lazyFields = append(stack.lazyFields, f)
+		if f.wire {
+			if f.lazy {
+				lf := lazyField{v: fv, name: f.name, tag: f.tag}
+				stack.lazyFields = append(stack.lazyFields, lf)
 			} else {
-				if err := c.wireByTag(fv, tag, stack); err != nil {
-					fieldName := typeName + "." + ft.Name
-					return fmt.Errorf("%q wired error: %s", fieldName, err.Error())
+				var err error
+				if f.expr {
+					err = c.wireByTag(fv, f.tag, stack)
+				} else {
+					c.count(CallAutowire)
+					err = c.autowire(fv, f.tags, stack)
+				}
+				if err != nil {
+					return fmt.Errorf("%q wired error: %w", f.name, err)
 				}
 			}
 		}
 
-		if ft.Type.Kind() != reflect.Struct {
+		if !f.nested {
 			continue
 		}
 		// 递归处理结构体字段，指针字段不可以因为可能出现无限循环。
@@ -692,6 +937,7 @@ func (c *Container) wireStruct(v reflect.Value, stack *wiringStack) error {
 }
 
 func (c *Container) wireByTag(v reflect.Value, tag string, stack *wiringStack) error {
+	c.count(CallAutowire)
 
 	// tag 预处理，可能通过属性值进行指定。
 	if strings.HasPrefix(tag, "${") {
@@ -702,15 +948,7 @@ func (c *Container) wireByTag(v reflect.Value, tag string, stack *wiringStack) e
 		tag = s
 	}
 
-	if tag == "" {
-		return c.autowire(v, nil, stack)
-	}
-
-	var tags []wireTag
-	for _, s := range strings.Split(tag, ",") {
-		tags = append(tags, toWireTag(s))
-	}
-	return c.autowire(v, tags, stack)
+	return c.autowire(v, parseWireTags(tag), stack)
 }
 
 func (c *Container) autowire(v reflect.Value, tags []wireTag, stack *wiringStack) error {
@@ -773,7 +1011,7 @@ func (c *Container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 		if tag.nullable {
 			return nil
 		}
-		return fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
+		return c.notFound(tag, t)
 	}
 
 	// 优先使用设置成主版本的 bean
@@ -786,21 +1024,11 @@ func (c *Container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 	}
 
 	if len(primaryBeans) > 1 {
-		msg := fmt.Sprintf("found %d primary beans, bean:%q type:%q [", len(primaryBeans), tag, t)
-		for _, b := range primaryBeans {
-			msg += "( " + b.String() + " ), "
-		}
-		msg = msg[:len(msg)-2] + "]"
-		return errors.New(msg)
+		return tooManyCandidates(tag, t, true, primaryBeans)
 	}
 
 	if len(primaryBeans) == 0 && len(foundBeans) > 1 {
-		msg := fmt.Sprintf("found %d beans, bean:%q type:%q [", len(foundBeans), tag, t)
-		for _, b := range foundBeans {
-			msg += "( " + b.String() + " ), "
-		}
-		msg = msg[:len(msg)-2] + "]"
-		return errors.New(msg)
+		return tooManyCandidates(tag, t, false, foundBeans)
 	}
 
 	var result *BeanDefinition
@@ -821,7 +1049,7 @@ func (c *Container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 }
 
 // filterBean 返回 tag 对应的 bean 在数组中的索引，找不到返回 -1。
-func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, error) {
+func (c *Container) filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, error) {
 
 	var found []int
 	for i, b := range beans {
@@ -831,12 +1059,11 @@ func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, erro
 	}
 
 	if len(found) > 1 {
-		msg := fmt.Sprintf("found %d beans, bean:%q type:%q [", len(found), tag, t)
+		var candidates []*BeanDefinition
 		for _, i := range found {
-			msg += "( " + beans[i].String() + " ), "
+			candidates = append(candidates, beans[i])
 		}
-		msg = msg[:len(msg)-2] + "]"
-		return -1, errors.New(msg)
+		return -1, tooManyCandidates(tag, t, false, candidates)
 	}
 
 	if len(found) > 0 {
@@ -848,7 +1075,7 @@ func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, erro
 		return -1, nil
 	}
 
-	return -1, fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
+	return -1, c.notFound(tag, t)
 }
 
 type byOrder []*BeanDefinition
@@ -869,7 +1096,9 @@ func (c *Container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 		return fmt.Errorf("%s is not valid receiver type", t.String())
 	}
 
-	beans := c.beansByType[et]
+	// 下面会对结果进行过滤和排序，复制一份避免修改容器的索引，刷新之后的查找可能
+	// 是并发进行的。
+	beans := append([]*BeanDefinition(nil), c.beansByType[et]...)
 	if len(tags) > 0 {
 
 		var (
@@ -890,7 +1119,7 @@ func (c *Container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 				continue
 			}
 
-			index, err := filterBean(beans, item, et)
+			index, err := c.filterBean(beans, item, et)
 			if err != nil {
 				return err
 			}
@@ -937,7 +1166,7 @@ func (c *Container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 	var ret reflect.Value
 	switch t.Kind() {
 	case reflect.Slice:
-		sort.Sort(byOrder(beans))
+		sort.Stable(byOrder(beans))
 		ret = reflect.MakeSlice(t, 0, 0)
 		for _, b := range beans {
 			ret = reflect.Append(ret, b.Value())
@@ -952,18 +1181,39 @@ func (c *Container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 	return nil
 }
 
-// Close 关闭容器，此方法必须在 Refresh 之后调用。该方法会触发 ctx 的 Done 信
-// 号，然后等待所有 goroutine 结束，最后按照被依赖先销毁的原则执行所有的销毁函数。
+// Close 关闭容器，此方法必须在 Refresh 之后调用。该方法首先让所有 SmartLifecycle
+// 停止接收新的任务，然后按照阶段顺序执行关闭钩子并排空 SmartLifecycle 的在途任务，
+// 之后触发 ctx 的 Done 信号并等待所有 goroutine 结束，最后按照被依赖先销毁的原则
+// 执行所有的销毁函数。排空任务和等待 goroutine 结束共享同一个宽限期。
 func (c *Container) Close() {
 
+	ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout())
+	defer cancel()
+
+	c.stopLifecycles()
+	c.runPhases(ctx)
 	c.cancel()
-	c.wg.Wait()
 
-	log.Info("goroutines exited")
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("goroutines exited")
+	case <-ctx.Done():
+		log.Warn("goroutines didn't exit before shutdown timeout")
+	}
 
 	for _, f := range c.destroyers {
 		f()
 	}
 
+	if c.name != "" {
+		unregisterContainer(c)
+	}
+
 	log.Info("container closed")
 }
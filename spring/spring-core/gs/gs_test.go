@@ -17,18 +17,22 @@
 package gs_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"image"
+	"io"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dync"
 	"github.com/go-spring/spring-core/gs"
 	"github.com/go-spring/spring-core/gs/arg"
 	"github.com/go-spring/spring-core/gs/bean"
@@ -2608,6 +2612,26 @@ func TestDefaultSpringContext(t *testing.T) {
 		assert.Error(t, err, "can't find bean, bean:\"\"")
 	})
 
+	t.Run("bean:feature", func(t *testing.T) {
+
+		c, ch := container()
+		c.Property("feature.flags.new-checkout", true)
+		c.Object(&BeanZero{5}).On(cond.OnFeature("new-checkout"))
+		c.Object(&BeanOne{}).On(cond.OnFeature("old-checkout"))
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+
+		var b *BeanZero
+		err = p.Get(&b)
+		assert.Nil(t, err)
+
+		var o *BeanOne
+		err = p.Get(&o)
+		assert.Error(t, err, "can't find bean, bean:\"\"")
+	})
+
 	t.Run("option withClassName Condition", func(t *testing.T) {
 
 		c, ch := container()
@@ -3052,3 +3076,1239 @@ func TestLazy(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+type slowRepository struct{}
+
+type slowService struct {
+	Repository *slowRepository `autowire:""`
+}
+
+func TestContainer_Startup(t *testing.T) {
+
+	c := gs.New()
+	c.Provide(func() *slowRepository {
+		time.Sleep(20 * time.Millisecond)
+		return new(slowRepository)
+	})
+	c.Provide(func() *slowService {
+		time.Sleep(40 * time.Millisecond)
+		return new(slowService)
+	})
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	r := c.Startup()
+
+	var steps []string
+	for _, s := range r.Steps {
+		steps = append(steps, s.Name)
+	}
+	assert.Equal(t, steps, []string{"register-beans", "resolve-beans", "wire-beans"})
+
+	assert.Equal(t, len(r.Beans), 2)
+	assert.True(t, strings.HasSuffix(r.Beans[0].ID, "gs_test.slowService:slowService"))
+	assert.True(t, r.Beans[0].Self >= 40*time.Millisecond)
+	assert.True(t, r.Beans[0].Self <= r.Beans[0].Total)
+	assert.True(t, r.Beans[1].Self >= 20*time.Millisecond)
+}
+
+func TestContainer_Profile(t *testing.T) {
+
+	c := gs.New()
+	c.Object(new(slowRepository))
+	assert.Nil(t, c.Refresh())
+	assert.True(t, c.Profile() == nil)
+
+	c = gs.New(gs.WithProfiling())
+	c.Provide(func() *slowRepository { return new(slowRepository) })
+	c.Object(new(slowService)).Init(func(*slowService) {})
+	c.Property("a", "b")
+	c.Provide(func(s string) *string { return &s }, "${a}")
+	assert.True(t, c.Profile() == nil)
+	assert.Nil(t, c.Refresh())
+
+	p := c.Profile()
+	assert.NotNil(t, p)
+	assert.Equal(t, p.Beans, 3)
+	assert.True(t, p.Duration > 0)
+	assert.True(t, p.Allocs > 0)
+
+	var phases []string
+	for _, s := range p.Phases {
+		phases = append(phases, s.Name)
+	}
+	assert.Equal(t, phases, []string{"register-beans", "resolve-beans", "wire-beans"})
+
+	assert.Equal(t, p.Calls[gs.CallConstructor], int64(2))
+	assert.Equal(t, p.Calls[gs.CallInit], int64(1))
+	assert.Equal(t, p.Calls[gs.CallAutowire], int64(1))
+	assert.True(t, p.Calls[gs.CallBind] >= 1)
+
+	var buf bytes.Buffer
+	assert.Nil(t, p.WriteJSON(&buf))
+	var r gs.Profile
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &r))
+	assert.Equal(t, r.Beans, 3)
+	assert.Equal(t, r.Calls, p.Calls)
+
+	buf.Reset()
+	if err := p.WritePprof(&buf); err == nil {
+		assert.True(t, buf.Len() > 0)
+	} else {
+		assert.Error(t, err, "cpu profile not captured")
+	}
+}
+
+type planRepository struct {
+	Name string `value:"${name}"`
+}
+
+type planService struct {
+	Repository *planRepository `autowire:"${repository}"`
+	Nested     struct {
+		Repository *planRepository `autowire:"primary"`
+		Timeout    time.Duration   `value:"${timeout:=1s}"`
+	}
+}
+
+// TestContainer_WiringPlan 同一类型在多个容器中注入时复用注入计划，但是通过属性
+// 指定的注入标签和属性值仍然在每个容器中单独解析。
+func TestContainer_WiringPlan(t *testing.T) {
+	for _, name := range []string{"primary", "secondary"} {
+		c := gs.New()
+		c.Property("name", name)
+		c.Property("repository", name)
+		c.Property("timeout", "3s")
+		primary := &planRepository{}
+		secondary := &planRepository{}
+		c.Object(primary).Name("primary")
+		c.Object(secondary).Name("secondary")
+		s := new(planService)
+		c.Object(s)
+		assert.Nil(t, c.Refresh())
+		assert.Equal(t, s.Repository.Name, name)
+		assert.True(t, s.Nested.Repository == primary)
+		assert.True(t, s.Repository == primary == (name == "primary"))
+		assert.Equal(t, s.Nested.Timeout, 3*time.Second)
+	}
+}
+
+// TestContainer_TagCache 注入标签的解析结果在进程中共享，并发查找时仍然返回
+// 各自的结果。
+func TestContainer_TagCache(t *testing.T) {
+
+	c, ch := container()
+	c.Property("name", "repository")
+	primary := &planRepository{}
+	secondary := &planRepository{}
+	c.Object(primary).Name("primary")
+	c.Object(secondary).Name("secondary")
+	assert.Nil(t, c.Refresh())
+	p := <-ch
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var r *planRepository
+				assert.Nil(t, p.Get(&r, "secondary"))
+				assert.True(t, r == secondary)
+				assert.Nil(t, p.Get(&r, "github.com/go-spring/spring-core/gs_test/gs_test.planRepository:primary"))
+				assert.True(t, r == primary)
+				r = nil
+				assert.Nil(t, p.GetE(&r, gs.Nullable(), gs.Select("missing")))
+				assert.True(t, r == nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestPandora_Concurrent 刷新之后可以并发地查找和注入 bean 以及刷新属性，需要
+// 使用 -race 运行。
+func TestPandora_Concurrent(t *testing.T) {
+
+	c, ch := container()
+	c.Property("name", "repository")
+	c.Property("repository", "primary")
+	c.Object(&planRepository{}).Name("primary").Order(2)
+	c.Object(&planRepository{}).Name("secondary").Order(1)
+	assert.Nil(t, c.Refresh())
+	p := <-ch
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var rs []*planRepository
+				assert.Nil(t, p.Get(&rs))
+				assert.Equal(t, len(rs), 2)
+				rs = nil
+				assert.Nil(t, p.Get(&rs, "secondary", "*"))
+				assert.Equal(t, len(rs), 2)
+				beans, err := p.Find("secondary")
+				assert.Nil(t, err)
+				assert.Equal(t, len(beans), 1)
+				v, err := p.Wire(new(planService))
+				assert.Nil(t, err)
+				assert.NotNil(t, v.(*planService).Nested.Repository)
+				assert.Equal(t, p.Prop("name"), "repository")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			assert.Nil(t, c.RefreshProperties(conf.Map(map[string]interface{}{"timeout": "2s"})))
+		}
+	}()
+	wg.Wait()
+
+	// 查找时不会修改容器的索引，自动模式仍然按照 order 排序。
+	var rs []*planRepository
+	assert.Nil(t, p.Get(&rs))
+	var names []string
+	for _, b := range []string{"primary", "secondary"} {
+		var r *planRepository
+		assert.Nil(t, p.Get(&r, b))
+		for i := range rs {
+			if rs[i] == r {
+				names = append(names, b+strconv.Itoa(i))
+			}
+		}
+	}
+	assert.Equal(t, names, []string{"primary1", "secondary0"})
+}
+
+// BenchmarkContainer_Refresh 跟踪注入引擎的性能，除了耗时还输出每次刷新的反射
+// 调用次数。性能分析本身有开销，所以只在计时之前开启一次。
+func BenchmarkContainer_Refresh(b *testing.B) {
+
+	newContainer := func(opts ...gs.Option) *gs.Container {
+		c := gs.New(opts...)
+		c.Provide(func() *slowRepository { return new(slowRepository) })
+		c.Object(new(slowService))
+		return c
+	}
+
+	c := newContainer(gs.WithProfiling())
+	if err := c.Refresh(); err != nil {
+		b.Fatal(err)
+	}
+	var calls int64
+	for _, n := range c.Profile().Calls {
+		calls += n
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := newContainer().Refresh(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(calls), "calls/op")
+}
+
+type NamedArgServer struct {
+	addr    string
+	timeout time.Duration
+	filter  filter
+}
+
+func NewNamedArgServer(addr string, timeout time.Duration, f filter) *NamedArgServer {
+	return &NamedArgServer{addr: addr, timeout: timeout, filter: f}
+}
+
+func init() {
+	arg.ParamNames(NewNamedArgServer, "addr", "timeout", "filter")
+}
+
+func TestApplicationContext_NameArg(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		c, ch := container()
+		c.Property("server.addr", ":9090")
+		c.Object(&filterImpl{}).Export((*filter)(nil))
+		c.Provide(NewNamedArgServer,
+			arg.Name("timeout", "${server.timeout:=3s}"),
+			arg.Name("addr", "${server.addr}"),
+			arg.Index(3, ""),
+		)
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var s *NamedArgServer
+		err = p.Get(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.addr, ":9090")
+		assert.Equal(t, s.timeout, 3*time.Second)
+		assert.NotNil(t, s.filter)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		_, err := arg.Bind(NewNamedArgServer, []arg.Arg{arg.Name("port", "${port}")}, 0)
+		assert.Error(t, err, "函数没有名为 \"port\" 的参数")
+	})
+
+	t.Run("unregistered", func(t *testing.T) {
+		fn := func(addr string) *NamedArgServer { return nil }
+		_, err := arg.Bind(fn, []arg.Arg{arg.Name("addr", "${addr}")}, 0)
+		assert.Error(t, err, "函数没有注册参数名称")
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		_, err := arg.Bind(NewNamedArgServer, []arg.Arg{arg.Name("addr", "${addr}"), "${timeout}"}, 0)
+		assert.Error(t, err, "所有参数必须都有或者都没有索引")
+	})
+}
+
+type StructArgOptions struct {
+	Addr    string        `value:"${addr:=:8080}"`
+	Timeout time.Duration `value:"${timeout:=1s}"`
+	Filter  filter        `autowire:""`
+	Cache   *int          `autowire:"?"`
+}
+
+// StructArgServer 使用指针保存选项，避免容器对选项再次进行属性绑定和依赖注入。
+type StructArgServer struct {
+	opts *StructArgOptions
+}
+
+func TestApplicationContext_StructArg(t *testing.T) {
+
+	t.Run("struct", func(t *testing.T) {
+		c, ch := container()
+		c.Property("server.timeout", "5s")
+		c.Object(&filterImpl{}).Export((*filter)(nil))
+		c.Provide(func(opts StructArgOptions) *StructArgServer {
+			return &StructArgServer{opts: &opts}
+		}, arg.Struct("${server}"))
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var s *StructArgServer
+		err = p.Get(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.opts.Addr, ":8080")
+		assert.Equal(t, s.opts.Timeout, 5*time.Second)
+		assert.NotNil(t, s.opts.Filter)
+		assert.True(t, s.opts.Cache == nil)
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		c, ch := container()
+		c.Property("addr", ":7070")
+		c.Object(&filterImpl{}).Export((*filter)(nil))
+		c.Provide(func(opts *StructArgOptions) *StructArgServer {
+			return &StructArgServer{opts: opts}
+		}, arg.Struct(""))
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var s *StructArgServer
+		err = p.Get(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.opts.Addr, ":7070")
+		assert.NotNil(t, s.opts.Filter)
+	})
+
+	t.Run("missing bean", func(t *testing.T) {
+		c := gs.New()
+		c.Provide(func(opts StructArgOptions) *StructArgServer {
+			return &StructArgServer{opts: &opts}
+		}, arg.Struct("${server}"))
+		err := c.Refresh()
+		assert.Error(t, err, "\"StructArgOptions.Filter\" wired error")
+	})
+}
+
+func TestApplicationContext_VariadicArg(t *testing.T) {
+
+	t.Run("collect", func(t *testing.T) {
+		c, ch := container()
+		c.Property("var.obj", "description")
+		c.Provide(func() VarOptionFunc {
+			return func(opt *VarOption) { opt.v = append(opt.v, &Var{"v2"}) }
+		}).Name("o2").Order(2)
+		c.Provide(func() VarOptionFunc {
+			return func(opt *VarOption) { opt.v = append(opt.v, &Var{"v1"}) }
+		}).Name("o1").Order(1)
+		c.Provide(NewVarObj, "${var.obj}")
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var obj *VarObj
+		err = p.Get(&obj)
+		assert.Nil(t, err)
+		assert.Equal(t, len(obj.v), 2)
+		assert.Equal(t, obj.v[0].name, "v1")
+		assert.Equal(t, obj.v[1].name, "v2")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		c, ch := container()
+		c.Provide(NewVarObj, arg.Value("description"))
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var obj *VarObj
+		err = p.Get(&obj)
+		assert.Nil(t, err)
+		assert.Equal(t, len(obj.v), 0)
+	})
+
+	t.Run("option", func(t *testing.T) {
+		c, ch := container()
+		c.Object(&Var{"v1"}).Name("v1")
+		c.Object(&Var{"v2"}).Name("v2")
+		c.Provide(NewVarObj, arg.Value("description"), arg.Option(withVar))
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		p := <-ch
+		var obj *VarObj
+		err = p.Get(&obj)
+		assert.Nil(t, err)
+		assert.Equal(t, len(obj.v), 2)
+	})
+}
+
+func TestApplicationContext_ContextArg(t *testing.T) {
+
+	var (
+		stdCtx context.Context
+		gsCtx  gs.Context
+	)
+
+	c := gs.New()
+	c.Property("service.name", "ctx")
+	c.Object(&filterImpl{}).Export((*filter)(nil))
+	c.Provide(func(ctx context.Context, gctx gs.Context) bool {
+		stdCtx, gsCtx = ctx, gctx
+		return true
+	})
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	// 没有开启 Pandora 时也可以在刷新之后获取 bean
+	var f filter
+	err = gsCtx.Get(&f)
+	assert.Nil(t, err)
+	assert.NotNil(t, f)
+	assert.Equal(t, gsCtx.Prop("service.name"), "ctx")
+
+	c.Close()
+	select {
+	case <-stdCtx.Done():
+	default:
+		t.Fatal("ctx should be done")
+	}
+	assert.NotNil(t, gsCtx.Err())
+}
+
+type InterfaceResult interface {
+	Name() string
+}
+
+type interfaceResultImpl struct {
+	name   string `value:"${result.name:=default}"`
+	Filter filter `autowire:""`
+}
+
+func (r *interfaceResultImpl) Name() string { return r.name }
+
+type interfaceResultValue struct {
+	Name_ string `value:"${result.name:=default}"`
+}
+
+func (r interfaceResultValue) Name() string { return r.Name_ }
+
+// 构造函数以接口形式返回结构体指针时，容器仍然对其动态值进行属性绑定和依赖注入。
+func TestApplicationContext_InterfaceResult(t *testing.T) {
+
+	t.Run("pointer", func(t *testing.T) {
+		var r InterfaceResult
+		c := gs.New()
+		c.Property("result.name", "ptr")
+		c.Object(&filterImpl{}).Export((*filter)(nil))
+		c.Provide(func() InterfaceResult { return &interfaceResultImpl{} })
+		c.Provide(func(i InterfaceResult) bool { r = i; return true })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, r.Name(), "ptr")
+		assert.NotNil(t, r.(*interfaceResultImpl).Filter)
+	})
+
+	t.Run("value", func(t *testing.T) {
+		var r InterfaceResult
+		c := gs.New()
+		c.Property("result.name", "value")
+		c.Provide(func() InterfaceResult { return interfaceResultValue{} })
+		c.Provide(func(i InterfaceResult) bool { r = i; return true })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, r.Name(), "value")
+	})
+}
+
+func TestPandora_GetE(t *testing.T) {
+
+	c, ch := container()
+	f1 := float32(11.0)
+	c.Object(&f1).Name("float_ptr_1")
+	f2 := float32(12.0)
+	c.Object(&f2).Name("float_ptr_2")
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	p := <-ch
+
+	t.Run("select", func(t *testing.T) {
+		var f *float32
+		err = p.GetE(&f, gs.Select("float_ptr_2"))
+		assert.Nil(t, err)
+		assert.Equal(t, f, &f2)
+		var ff []*float32
+		err = p.GetE(&ff, gs.Select("float_ptr_2", "float_ptr_1"))
+		assert.Nil(t, err)
+		assert.Equal(t, ff, []*float32{&f2, &f1})
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var i *int
+		err = p.GetE(&i)
+		assert.Error(t, err, "can't find bean")
+	})
+
+	t.Run("nullable", func(t *testing.T) {
+		var i *int
+		err = p.GetE(&i, gs.Nullable())
+		assert.Nil(t, err)
+		assert.True(t, i == nil)
+		var f *float32
+		err = p.GetE(&f, gs.Select("float_ptr_3"), gs.Nullable())
+		assert.Nil(t, err)
+		assert.True(t, f == nil)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		err = p.GetE(nil)
+		assert.Error(t, err, "i can't be nil")
+		var f float32
+		err = p.GetE(f)
+		assert.Error(t, err, "i must be pointer")
+	})
+
+	t.Run("find", func(t *testing.T) {
+		beans, err := p.Find((*float32)(nil))
+		assert.Nil(t, err)
+		assert.Equal(t, len(beans), 2)
+	})
+}
+
+func TestPandora_Lazy(t *testing.T) {
+
+	c, ch := container()
+	f := float32(1.0)
+	c.Object(&f)
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	p := <-ch
+
+	get := gs.Lazy[*float32](p)
+	v, err := get()
+	assert.Nil(t, err)
+	assert.Equal(t, v, &f)
+
+	missing := gs.Lazy[*int](p)
+	_, err = missing()
+	assert.Error(t, err, "can't find bean")
+
+	nullable := gs.Lazy[*int](p, gs.Nullable())
+	i, err := nullable()
+	assert.Nil(t, err)
+	assert.True(t, i == nil)
+}
+
+func TestApplicationContext_WiringErrors(t *testing.T) {
+
+	type IntHolder struct {
+		I *int `autowire:""`
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&IntHolder{})
+		err := c.Refresh()
+		var e *gs.ErrBeanNotFound
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Code(), gs.CodeBeanNotFound)
+		assert.Equal(t, e.Type, reflect.TypeOf((*int)(nil)))
+		assert.Error(t, err, "can't find bean, bean:\"\" type:\"\\*int\" \\(GS-001\\) hint: ")
+	})
+
+	t.Run("too many candidates", func(t *testing.T) {
+		c := gs.New()
+		i1, i2 := 1, 2
+		c.Object(&i1).Name("i1")
+		c.Object(&i2).Name("i2")
+		c.Object(&IntHolder{})
+		err := c.Refresh()
+		var e *gs.ErrTooManyCandidates
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Code(), gs.CodeTooManyCandidates)
+		assert.Equal(t, len(e.Candidates), 2)
+		assert.False(t, e.Primary)
+	})
+
+	t.Run("condition failed", func(t *testing.T) {
+		c := gs.New()
+		i := 1
+		c.Object(&i).On(cond.OnProperty("int.enabled"))
+		c.Object(&IntHolder{})
+		err := c.Refresh()
+		var e *gs.ErrConditionFailed
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Code(), gs.CodeConditionFailed)
+		assert.Equal(t, len(e.Candidates), 1)
+		assert.NotEqual(t, e.Hint, "")
+	})
+}
+
+type moduleService struct {
+	Name string `value:"${module.name:=default}"`
+}
+
+type moduleClient struct {
+	Service *moduleService `autowire:"?"`
+}
+
+func TestApplicationContext_Import(t *testing.T) {
+
+	module := gs.ModuleFunc(func(c *gs.Container) {
+		c.Object(&moduleService{})
+		c.Object(new(int)).On(cond.OnProperty("module.int"))
+	})
+
+	t.Run("no condition", func(t *testing.T) {
+		c := gs.New()
+		c.Property("module.name", "svc")
+		c.Import(module)
+		client := &moduleClient{}
+		c.Object(client)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.NotNil(t, client.Service)
+		assert.Equal(t, client.Service.Name, "svc")
+	})
+
+	t.Run("condition failed", func(t *testing.T) {
+		c := gs.New()
+		c.Import(module, cond.OnProperty("module.enabled", cond.HavingValue("true")))
+		client := &moduleClient{}
+		c.Object(client)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.True(t, client.Service == nil)
+	})
+
+	t.Run("condition matched", func(t *testing.T) {
+		c, ch := container()
+		c.Property("module.enabled", true)
+		c.Import(module, cond.OnProperty("module.enabled", cond.HavingValue("true")), cond.OnBean((*moduleClient)(nil)))
+		client := &moduleClient{}
+		c.Object(client)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.NotNil(t, client.Service)
+		// 模块的条件和 bean 自身的条件同时生效
+		var i *int
+		err = (<-ch).GetE(&i, gs.Nullable())
+		assert.Nil(t, err)
+		assert.True(t, i == nil)
+	})
+}
+
+func TestApplicationContext_OnShutdown(t *testing.T) {
+
+	c := gs.New()
+	c.Property(environ.SpringShutdownTimeout, "50ms")
+
+	var order []string
+	c.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "default")
+		return errors.New("error")
+	})
+	c.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "flush")
+		panic("boom")
+	}).Phase(gs.PhaseFlush)
+	c.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "stop-traffic")
+		return nil
+	}).Name("stop-traffic").Phase(gs.PhaseStopTraffic)
+	c.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		order = append(order, "timeout")
+		return ctx.Err()
+	}).Phase(gs.PhaseFlush + 1)
+	c.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "skipped")
+		return nil
+	}).Phase(gs.PhaseFlush + 2)
+
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	start := time.Now()
+	c.Close()
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, order, []string{"stop-traffic", "default", "flush", "timeout"})
+}
+
+type lifecycleRecorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *lifecycleRecorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, s)
+}
+
+type lifecycleBean struct {
+	name  string
+	phase int
+	block bool
+	r     *lifecycleRecorder
+}
+
+func (b *lifecycleBean) Phase() int { return b.phase }
+
+func (b *lifecycleBean) Stop() { b.r.add("stop:" + b.name) }
+
+func (b *lifecycleBean) Drain(ctx context.Context) error {
+	if b.block {
+		<-ctx.Done()
+		b.r.add("timeout:" + b.name)
+		return ctx.Err()
+	}
+	b.r.add("drain:" + b.name)
+	return nil
+}
+
+func TestApplicationContext_SmartLifecycle(t *testing.T) {
+
+	c := gs.New()
+	c.Property(environ.SpringShutdownTimeout, "1s")
+	c.Property(environ.SpringShutdownPhaseTimeout, "50ms")
+
+	r := new(lifecycleRecorder)
+	c.Object(&lifecycleBean{name: "scheduler", phase: 10, r: r}).Name("scheduler")
+	c.Object(&lifecycleBean{name: "consumer", phase: gs.PhaseDefault, block: true, r: r}).Name("consumer")
+	c.Object(&lifecycleBean{name: "disabled", r: r}).Name("disabled").On(cond.OnProperty("x"))
+	c.OnShutdown(func(ctx context.Context) error {
+		r.add("hook:stop-traffic")
+		return nil
+	}).Phase(gs.PhaseStopTraffic)
+	c.OnShutdown(func(ctx context.Context) error {
+		r.add("hook:flush")
+		return nil
+	}).Phase(gs.PhaseFlush)
+
+	var goroutineCanceled bool
+	c.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		r.mu.Lock()
+		goroutineCanceled = len(r.order) == 6
+		r.mu.Unlock()
+	})
+
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	start := time.Now()
+	c.Close()
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Equal(t, r.order, []string{
+		"stop:consumer", "stop:scheduler",
+		"hook:stop-traffic",
+		"timeout:consumer",
+		"drain:scheduler",
+		"hook:flush",
+	})
+	assert.True(t, goroutineCanceled)
+}
+
+type groupClient struct {
+	Endpoint string
+}
+
+func TestApplicationContext_GroupRegister(t *testing.T) {
+
+	c := gs.New()
+	c.Property("group.endpoints", []string{"order", "user"})
+	c.GroupRegister(func(p *conf.Properties) []*gs.BeanDefinition {
+		var endpoints []string
+		if err := p.Bind(&endpoints, conf.Key("group.endpoints")); err != nil {
+			return nil
+		}
+		var beans []*gs.BeanDefinition
+		for _, e := range endpoints {
+			beans = append(beans, gs.NewBean(&groupClient{Endpoint: e}).Name(e))
+		}
+		return beans
+	})
+
+	var s struct {
+		Clients map[string]*groupClient `autowire:""`
+	}
+	c.Object(&s)
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, len(s.Clients), 2)
+	assert.Equal(t, s.Clients["order"].Endpoint, "order")
+	assert.Equal(t, s.Clients["user"].Endpoint, "user")
+
+	assert.Panic(t, func() {
+		c.GroupRegister(func(p *conf.Properties) []*gs.BeanDefinition { return nil })
+	}, "should call before Refresh")
+}
+
+type invokerBean struct {
+	Name string
+}
+
+func newInvokerBean(name string, i *int) (*invokerBean, error) {
+	if i != nil {
+		return nil, errors.New("i should be nil")
+	}
+	return &invokerBean{Name: name}, nil
+}
+
+func TestApplicationContext_Invoker(t *testing.T) {
+
+	calls := 0
+	arg.RegisterInvoker(newInvokerBean, func(in []interface{}) []interface{} {
+		calls++
+		a0, _ := in[0].(string)
+		a1, _ := in[1].(*int)
+		r0, r1 := newInvokerBean(a0, a1)
+		return []interface{}{r0, r1}
+	})
+
+	var b *invokerBean
+	c := gs.New()
+	c.Property("invoker.name", "static")
+	c.Provide(newInvokerBean, "${invoker.name}", "?")
+	c.Provide(func(i *invokerBean) bool { b = i; return true })
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, b.Name, "static")
+}
+
+type sizeBean struct {
+	Size int
+}
+
+func TestProvideN(t *testing.T) {
+
+	var (
+		b    *invokerBean
+		n    int
+		name string
+	)
+	c := gs.New()
+	c.Property("invoker.name", "generic")
+	c.Property("invoker.size", 3)
+	d := gs.ProvideE2(c, newInvokerBean, "${invoker.name}", "?")
+	assert.Matches(t, d.FileLine(), "gs_test.go:")
+	gs.Provide1(c, func(size int) *sizeBean { return &sizeBean{Size: size} }, "${invoker.size}")
+	gs.Provide2(c, func(i *invokerBean, s *sizeBean) bool {
+		b, n = i, s.Size
+		return true
+	})
+	gs.Provide0(c, func() *strings.Builder { return new(strings.Builder) })
+	gs.Provide1(c, func(sb *strings.Builder) string { name = "builder"; return "x" })
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, b.Name, "generic")
+	assert.Equal(t, n, 3)
+	assert.Equal(t, name, "builder")
+
+	c = gs.New()
+	gs.ProvideE0(c, func() (*invokerBean, error) { return nil, errors.New("provide error") })
+	gs.Provide1(c, func(i *invokerBean) bool { return true })
+	err = c.Refresh()
+	assert.Error(t, err, "provide error")
+}
+
+type graphDB struct {
+	URL string `value:"${graph.db.url:=mem}"`
+}
+
+type graphService struct {
+	DB *graphDB `autowire:""`
+}
+
+func TestApplicationContext_ExportGraph(t *testing.T) {
+
+	c := gs.New()
+	err := c.ExportGraph(io.Discard, gs.GraphJSON)
+	assert.Error(t, err, "container not refreshed")
+
+	c.Object(new(graphDB))
+	c.Object(new(graphService))
+	c.Object(new(BeanZero)).On(cond.OnProperty("graph.zero"))
+	err = c.Refresh()
+	assert.Nil(t, err)
+
+	var g gs.Graph
+	buf := bytes.NewBuffer(nil)
+	err = c.ExportGraph(buf, gs.GraphJSON)
+	assert.Nil(t, err)
+	err = json.Unmarshal(buf.Bytes(), &g)
+	assert.Nil(t, err)
+
+	nodes := make(map[string]gs.GraphNode)
+	for _, n := range g.Nodes {
+		nodes[n.Name] = n
+	}
+	assert.Equal(t, nodes["graphDB"].Condition, gs.ConditionNone)
+	assert.Equal(t, nodes["graphDB"].Properties, []string{"graph.db.url"})
+	assert.Equal(t, nodes["BeanZero"].Condition, gs.ConditionFailed)
+	assert.Equal(t, g.Edges, []gs.GraphEdge{{From: nodes["graphService"].ID, To: nodes["graphDB"].ID}})
+
+	buf.Reset()
+	err = c.ExportGraph(buf, gs.GraphDOT)
+	assert.Nil(t, err)
+	s := buf.String()
+	assert.True(t, strings.HasPrefix(s, "digraph beans {"))
+	assert.True(t, strings.Contains(s, fmt.Sprintf("%q -> %q;", nodes["graphService"].ID, nodes["graphDB"].ID)))
+	assert.True(t, strings.Contains(s, "style=dashed"))
+	assert.True(t, strings.Contains(s, `[label="graph.db.url", shape=note]`))
+
+	buf.Reset()
+	err = c.ExportGraph(buf, gs.GraphMermaid)
+	assert.Nil(t, err)
+	s = buf.String()
+	assert.True(t, strings.HasPrefix(s, "graph LR\n"))
+	assert.True(t, strings.Contains(s, `>"graph.db.url"]`))
+	assert.True(t, strings.Contains(s, " failed\n"))
+
+	err = c.ExportGraph(buf, "svg")
+	assert.Error(t, err, "unsupported graph format")
+}
+
+type dyncServer struct {
+	Port    dync.Int64  `value:"${server.port:=8080}"`
+	Name    string      `value:"${server.name:=order}"`
+	Limit   dync.Int64  `value:"${server.limit:=100}"`
+	Timeout dync.String `value:"${server.timeout:=1s}"`
+}
+
+func TestApplicationContext_RefreshProperties(t *testing.T) {
+
+	c := gs.New()
+	c.Property("server.port", 9090)
+
+	s := new(dyncServer)
+	s.Limit.OnValidate(func(v int64) error {
+		if v <= 0 {
+			return errors.New("limit must be positive")
+		}
+		return nil
+	})
+	c.Object(s)
+
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, s.Port.Value(), int64(9090))
+	assert.Equal(t, s.Limit.Value(), int64(100))
+
+	err = c.RefreshProperties(conf.Map(map[string]interface{}{
+		"server.port":    9091,
+		"server.name":    "user",
+		"server.limit":   -1,
+		"server.timeout": "3s",
+	}))
+	assert.Error(t, err, "dyncServer.Limit validate error: limit must be positive")
+	assert.Equal(t, s.Port.Value(), int64(9091))
+	assert.Equal(t, s.Name, "order") // 普通字段不会刷新
+	assert.Equal(t, s.Limit.Value(), int64(100))
+	assert.Equal(t, s.Timeout.Value(), "3s")
+}
+
+type exprPool struct {
+	Size int `value:"${pool.size:=4}"`
+}
+
+type exprService struct {
+	Workers int             `value:"#{@pool.Size * 2}"`
+	Mode    string          `value:"#{${spring.profiles.active:=dev} == 'prod' ? 'release' : 'debug'}"`
+	Flags   map[string]*int `autowire:""`
+}
+
+func TestApplicationContext_Expression(t *testing.T) {
+
+	c := gs.New()
+	c.Property("pool.size", 8)
+	c.Property("cache.size", 200)
+	c.Property("spring.profiles.active", "prod")
+
+	s := new(exprService)
+	c.Object(s)
+	c.Object(new(exprPool)).Name("pool")
+	c.Object(new(int)).Name("big").On(cond.OnExpression("${cache.size} * 2 > 300 && @pool"))
+	c.Object(new(int)).Name("small").On(cond.OnExpression("${cache.size} < 100"))
+	c.Object(new(int)).Name("missing").On(cond.OnExpression("!@nothing"))
+
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, s.Workers, 16)
+	assert.Equal(t, s.Mode, "release")
+
+	var names []string
+	for name := range s.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, names, []string{"big", "missing"})
+}
+
+type orderedBean struct {
+	Name string
+}
+
+type orderedService struct {
+	Beans []*orderedBean `autowire:""`
+}
+
+// TestContainer_DeterministicOrder bean 按照注册顺序决议和注入，多次刷新的结果
+// 完全相同，相同 Order 的 bean 在收集时也保持注册顺序。
+func TestContainer_DeterministicOrder(t *testing.T) {
+
+	var names []string
+	for i := 0; i < 16; i++ {
+		names = append(names, fmt.Sprintf("b%02d", i))
+	}
+
+	for n := 0; n < 10; n++ {
+		var inits []string
+		c := gs.New()
+		for _, name := range names {
+			c.Object(&orderedBean{Name: name}).Name(name).Init(func(b *orderedBean) {
+				inits = append(inits, b.Name)
+			})
+		}
+		s := new(orderedService)
+		c.Object(s)
+		assert.Nil(t, c.Refresh())
+		assert.Equal(t, inits, names)
+
+		var collected []string
+		for _, b := range s.Beans {
+			collected = append(collected, b.Name)
+		}
+		assert.Equal(t, collected, names)
+	}
+}
+
+// TestContainer_DuplicateBean 重复注册时错误信息包含两处注册位置，开启覆盖模式
+// 后后注册的 bean 生效。
+func TestContainer_DuplicateBean(t *testing.T) {
+
+	t.Run("error", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&BeanZero{5})
+		c.Object(&BeanZero{6})
+		err := c.Refresh()
+		assert.Error(t, err, `found duplicate beans ".*/gs_test.BeanZero:BeanZero" registered at .*gs_test.go:\d+ and .*gs_test.go:\d+, use Name\(\) to give them different names`)
+	})
+
+	t.Run("option", func(t *testing.T) {
+		c := gs.New(gs.AllowBeanOverride())
+		c.Object(&BeanZero{5})
+		c.Object(&BeanZero{6})
+		b := new(BeanOne)
+		c.Object(b)
+		assert.Nil(t, c.Refresh())
+		assert.Equal(t, b.Zero.Int, 6)
+	})
+
+	t.Run("property", func(t *testing.T) {
+		c := gs.New()
+		c.Property(environ.AllowBeanOverride, true)
+		c.Object(&BeanZero{5})
+		c.Object(&BeanZero{6})
+		b := new(BeanOne)
+		c.Object(b)
+		assert.Nil(t, c.Refresh())
+		assert.Equal(t, b.Zero.Int, 6)
+	})
+}
+
+// TestContainer_GoErrors 容器管理的 goroutine 返回的错误及发生的 panic 在关闭容器
+// 后可以通过 Err 获取。
+func TestContainer_GoErrors(t *testing.T) {
+
+	c := gs.New()
+	assert.Nil(t, c.Refresh())
+
+	c.Go(func(ctx context.Context) { <-ctx.Done() })
+	c.Go(func(ctx context.Context) { panic("boom") })
+	c.GoE(func(ctx context.Context) error { return errors.New("failed") })
+	c.GoE(func(ctx context.Context) error { return nil })
+
+	c.Close()
+	err := c.Err()
+	assert.Error(t, err, "2 goroutine errors occurred:")
+	assert.Error(t, err, "goroutine panic: boom")
+	assert.Error(t, err, "\\* failed")
+
+	c = gs.New()
+	assert.Nil(t, c.Refresh())
+	c.Go(func(ctx context.Context) {})
+	c.Close()
+	assert.Nil(t, c.Err())
+}
+
+type bridgedDB struct {
+	URL       string `value:"${db.url:=}"`
+	destroyed bool
+}
+
+func (db *bridgedDB) OnDestroy() { db.destroyed = true }
+
+type bridgedJob struct {
+	DB   *bridgedDB `autowire:""`
+	Name string     `value:"${name}"`
+}
+
+// TestContainer_Registry 具名容器注册在进程内的注册表中，容器之间互相隔离，只有
+// 通过 Bridge 声明的 bean 可以跨容器注入，并且由来源容器负责注入和销毁。
+func TestContainer_Registry(t *testing.T) {
+
+	main := gs.New(gs.WithName("main"))
+	main.Property("db.url", "mysql://main")
+	main.Property("name", "main")
+	db := new(bridgedDB)
+	main.Object(db)
+
+	jobs := gs.New(gs.WithName("jobs"))
+	jobs.Property("name", "jobs")
+	jobs.Bridge("main", (*bridgedDB)(nil))
+	job := new(bridgedJob)
+	jobs.Object(job)
+
+	assert.True(t, gs.Lookup("main") == main)
+	assert.True(t, gs.Lookup("jobs") == jobs)
+	assert.True(t, gs.Lookup("none") == nil)
+	assert.Equal(t, gs.Names(), []string{"jobs", "main"})
+	assert.Equal(t, jobs.Name(), "jobs")
+
+	assert.Panic(t, func() { gs.New(gs.WithName("main")) }, `container "main" already exists`)
+	assert.Panic(t, func() { jobs.Bridge("none", (*bridgedDB)(nil)) }, `container "none" not found`)
+
+	err := jobs.Refresh()
+	assert.Error(t, err, `container "main" should be refreshed before "jobs"`)
+	jobs.Close()
+	assert.True(t, gs.Lookup("jobs") == nil)
+
+	jobs = gs.New(gs.WithName("jobs2"))
+	jobs.Property("name", "jobs")
+	jobs.Bridge("main", (*bridgedDB)(nil))
+	job = new(bridgedJob)
+	jobs.Object(job)
+
+	assert.Nil(t, main.Refresh())
+	assert.Nil(t, jobs.Refresh())
+	assert.True(t, job.DB == db)
+	assert.Equal(t, job.Name, "jobs")
+	assert.Equal(t, db.URL, "mysql://main")
+
+	// 桥接的 bean 由来源容器销毁。
+	jobs.Close()
+	assert.False(t, db.destroyed)
+	assert.True(t, gs.Lookup("jobs2") == nil)
+	main.Close()
+	assert.True(t, db.destroyed)
+	assert.True(t, gs.Lookup("main") == nil)
+
+	// 只能匹配到一个 bean 。
+	src := gs.New(gs.WithName("src"))
+	src.Object(&bridgedDB{}).Name("a")
+	src.Object(&bridgedDB{}).Name("b")
+	dst := gs.New(gs.WithName("dst"))
+	dst.Bridge("src", (*bridgedDB)(nil))
+	assert.Nil(t, src.Refresh())
+	assert.Error(t, dst.Refresh(), `found 2 beans for ".*bridgedDB:" in container "src"`)
+	src.Close()
+	dst.Close()
+}
+
+// TestPandora_FindByConcreteType 具体类型的选择器只匹配该类型的 bean 。
+func TestPandora_FindByConcreteType(t *testing.T) {
+
+	c, ch := container()
+	i := 3
+	f := float32(1.0)
+	c.Object(&i)
+	c.Object(&f)
+	c.Object(&struct{}{})
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	p := <-ch
+
+	// 具体类型的选择器只能匹配到该类型的 bean ，不能匹配到其他类型的 bean 。
+	beans, err := p.Find((*float32)(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, len(beans), 1)
+	assert.Equal(t, beans[0].Type(), reflect.TypeOf(&f))
+
+	beans, err = p.Find((*string)(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, len(beans), 0)
+}
+
+type typeSelectorX struct{}
+type typeSelectorY struct{}
+
+// TestPandora_GetByReflectType 不同的 reflect.Type 选择器查找到各自类型的 bean 。
+func TestPandora_GetByReflectType(t *testing.T) {
+
+	c, ch := container()
+	x, y := &typeSelectorX{}, &typeSelectorY{}
+	c.Object(x)
+	c.Object(y)
+	assert.Nil(t, c.Refresh())
+
+	p := <-ch
+
+	var rx *typeSelectorX
+	assert.Nil(t, p.GetE(&rx, gs.Select(reflect.TypeOf(x))))
+	assert.True(t, rx == x)
+
+	var ry *typeSelectorY
+	assert.Nil(t, p.GetE(&ry, gs.Select(reflect.TypeOf(y))))
+	assert.True(t, ry == y)
+
+	ry = nil
+	assert.Nil(t, p.GetE(&ry, gs.Select(reflect.ValueOf(y))))
+	assert.True(t, ry == y)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/actuator"
+)
+
+// inspector 实现 actuator.Inspector 接口。因为没有开启 Pandora 时容器在启动
+// 完成后会清空 bean 的缓存，所以需要在容器刷新之后保存 bean 的快照。
+type inspector struct {
+	app         *App
+	mutex       sync.RWMutex
+	beans       []actuator.Bean
+	configProps []actuator.ConfigProp
+}
+
+// snapshot 保存 bean 及其配置结构体的快照。
+func (i *inspector) snapshot() {
+
+	var (
+		beans       []actuator.Bean
+		configProps []actuator.ConfigProp
+	)
+
+	for _, b := range i.app.c.beans {
+		if b.status == Deleted {
+			continue
+		}
+		var deps []string
+		for _, d := range b.dependencies {
+			deps = append(deps, d.ID())
+		}
+		beans = append(beans, actuator.Bean{
+			ID:           b.ID(),
+			Name:         b.BeanName(),
+			Type:         b.Type().String(),
+			Source:       b.FileLine(),
+			Dependencies: deps,
+		})
+		for _, c := range b.bindings {
+			prefix := propertyKey(c.tag)
+			configProps = append(configProps, actuator.ConfigProp{
+				Bean:   b.ID(),
+				Prefix: prefix,
+				Type:   c.v.Type().String(),
+				Value:  c.v.Interface(),
+			})
+		}
+	}
+
+	sort.Slice(beans, func(m, n int) bool { return beans[m].ID < beans[n].ID })
+	sort.SliceStable(configProps, func(m, n int) bool { return configProps[m].Bean < configProps[n].Bean })
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.beans = beans
+	i.configProps = configProps
+}
+
+// Beans 返回所有有效的 bean 。
+func (i *inspector) Beans() []actuator.Bean {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.beans
+}
+
+// ConfigProps 返回所有绑定到属性上的配置结构体。
+func (i *inspector) ConfigProps() []actuator.ConfigProp {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.configProps
+}
+
+// Properties 返回按照 key 排序的属性列表及其来源。
+func (i *inspector) Properties() []actuator.Property {
+	p := i.app.c.p
+	keys := p.Keys()
+	sort.Strings(keys)
+	ret := make([]actuator.Property, 0, len(keys))
+	for _, k := range keys {
+		ret = append(ret, actuator.Property{
+			Key:    k,
+			Value:  p.Get(k).(string),
+			Origin: i.app.origins[k],
+		})
+	}
+	return ret
+}
+
+// Startup 返回启动耗时报告。
+func (i *inspector) Startup() actuator.Startup {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	r := i.app.Startup()
+	ret := actuator.Startup{
+		Steps: make([]actuator.StartupStep, 0, len(r.Steps)),
+		Beans: make([]actuator.BeanStartup, 0, len(r.Beans)),
+	}
+	for _, s := range r.Steps {
+		ret.Steps = append(ret.Steps, actuator.StartupStep{
+			Name:      s.Name,
+			StartTime: s.Start,
+			Duration:  ms(s.Duration),
+		})
+	}
+	for _, b := range r.Beans {
+		ret.Beans = append(ret.Beans, actuator.BeanStartup{
+			ID:     b.ID,
+			Source: b.Source,
+			Total:  ms(b.Total),
+			Self:   ms(b.Self),
+		})
+	}
+	return ret
+}
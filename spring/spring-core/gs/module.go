@@ -0,0 +1,120 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"errors"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/gs/cond"
+)
+
+// Module 模块，用于向容器批量注册 bean ，类似于 Spring Boot 的自动配置。starter
+// 包通常在 init 函数中通过 Import 导入自己的模块，应用只需要引入 starter 包即可。
+type Module interface {
+	Configure(c *Container)
+}
+
+// ModuleFunc 函数形式的 Module 。
+type ModuleFunc func(c *Container)
+
+func (f ModuleFunc) Configure(c *Container) {
+	f(c)
+}
+
+type importedModule struct {
+	m    Module
+	cond cond.Condition
+}
+
+// onceCondition 模块的条件只计算一次，保证模块的 bean 同时生效或者同时失效。
+type onceCondition struct {
+	cond cond.Condition
+	done bool
+	ok   bool
+	err  error
+}
+
+func (c *onceCondition) Matches(ctx cond.Context) (bool, error) {
+	if !c.done {
+		c.done = true
+		c.ok, c.err = c.cond.Matches(ctx)
+	}
+	return c.ok, c.err
+}
+
+// Import 导入模块，模块的 Configure 方法在容器刷新时调用。当设置了 conds 时只有
+// 所有条件都满足时模块注册的 bean 才会生效，注意模块的条件不应该依赖模块自身注册
+// 的 bean 。需要注意的是该方法在注入开始后就不能再调用了。
+func (c *Container) Import(m Module, conds ...cond.Condition) {
+	if c.state != Unrefreshed {
+		panic(errors.New("should call before Refresh"))
+	}
+	var mc cond.Condition
+	switch len(conds) {
+	case 0:
+	case 1:
+		mc = &onceCondition{cond: conds[0]}
+	default:
+		mc = &onceCondition{cond: cond.Group(cond.And, conds...)}
+	}
+	c.modules = append(c.modules, importedModule{m: m, cond: mc})
+}
+
+// configureModules 调用模块的 Configure 方法，并为模块注册的 bean 附加模块的条件。
+func (c *Container) configureModules() {
+	for _, im := range c.modules {
+		n := len(c.beans)
+		im.m.Configure(c)
+		if im.cond == nil {
+			continue
+		}
+		for _, b := range c.beans[n:] {
+			if b.cond == nil {
+				b.cond = im.cond
+			} else {
+				b.cond = cond.Group(cond.And, im.cond, b.cond)
+			}
+		}
+	}
+	c.modules = nil
+}
+
+// GroupFunc 根据属性计算需要注册的一组 bean ，例如为每个配置的服务端点创建一个
+// 客户端 bean ，返回的 bean 通常使用 NewBean 函数创建。
+type GroupFunc func(p *conf.Properties) []*BeanDefinition
+
+// GroupRegister 注册一组由属性决定的 bean ，fn 在容器刷新时模块配置完成之后调用，
+// 此时所有的属性都已经准备好。需要注意的是该方法在注入开始后就不能再调用了。
+func (c *Container) GroupRegister(fn GroupFunc) {
+	if c.state != Unrefreshed {
+		panic(errors.New("should call before Refresh"))
+	}
+	c.groups = append(c.groups, fn)
+}
+
+// registerGroups 调用 GroupRegister 注册的函数并注册它们返回的 bean 。
+func (c *Container) registerGroups() {
+	for _, fn := range c.groups {
+		for _, b := range fn(c.p) {
+			if b != nil {
+				c.register(b)
+			}
+		}
+	}
+	c.groups = nil
+}
@@ -19,7 +19,9 @@ package gs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 
 	"github.com/go-spring/spring-core/conf"
 	"github.com/go-spring/spring-core/gs/arg"
@@ -36,15 +38,64 @@ import (
 // 出一个可共用的接口来，也就是说，无论程序是 Container 方式启动还是 App 方式启动，
 // 都可以在需要使用这些方法的地方注入一个 Pandora 对象而不是 Container 对象或者
 // App 对象，从而实现使用方式的统一。
+// 容器刷新之后 bean 的索引不再变化，查找过程也不会修改索引，所以 Get 、GetE 、
+// Find 、Wire 和 Invoke 不需要加锁，可以在处理请求时被大量的协程并发调用。
 type Pandora interface {
 	Go(fn func(ctx context.Context))
 	Prop(key string, opts ...conf.GetOption) interface{}
 	Bind(i interface{}, opts ...conf.BindOption) error
 	Get(i interface{}, selectors ...bean.Selector) error
+	GetE(i interface{}, opts ...GetOption) error
+	Find(selector bean.Selector) ([]bean.Definition, error)
 	Wire(objOrCtor interface{}, ctorArgs ...arg.Arg) (interface{}, error)
 	Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, error)
 }
 
+// GetOption Pandora.GetE 方法的选项。
+type GetOption func(opts *getOptions)
+
+type getOptions struct {
+	selectors []bean.Selector
+	nullable  bool
+}
+
+// Select 设置获取 bean 时使用的选择器列表，获取集合时按照选择器的顺序排序。
+func Select(selectors ...bean.Selector) GetOption {
+	return func(opts *getOptions) {
+		opts.selectors = append(opts.selectors, selectors...)
+	}
+}
+
+// Nullable 没有找到符合条件的 bean 时不返回错误，接收者保持零值。
+func Nullable() GetOption {
+	return func(opts *getOptions) {
+		opts.nullable = true
+	}
+}
+
+// Lazy 返回延迟获取 bean 的函数，第一次调用时才从容器中获取 bean ，获取成功后
+// 缓存结果。适用于在启动阶段还无法确定是否需要某个 bean 的场景。
+func Lazy[T any](p Pandora, opts ...GetOption) func() (T, error) {
+	var (
+		mu   sync.Mutex
+		done bool
+		v    T
+	)
+	return func() (T, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return v, nil
+		}
+		var r T
+		if err := p.GetE(&r, opts...); err != nil {
+			return r, err
+		}
+		v, done = r, true
+		return v, nil
+	}
+}
+
 type pandora struct{ c *Container }
 
 // Go 创建安全可等待的 goroutine，fn 要求的 ctx 对象由 IoC 容器提供，当 IoC 容
@@ -102,6 +153,54 @@ func (p *pandora) Get(i interface{}, selectors ...bean.Selector) error {
 	return p.c.autowire(v.Elem(), tags, stack)
 }
 
+// GetE 根据选项获取符合条件的 bean 对象，和 Get 方法一样保证返回的 bean 对象都
+// 已经完成属性绑定和依赖注入。获取过程中发生的 panic 也会转换为 error 返回，因此
+// 可以在运行时安全地调用。
+func (p *pandora) GetE(i interface{}, opts ...GetOption) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("get bean panic: %v", r)
+		}
+	}()
+
+	var o getOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if !o.nullable {
+		return p.Get(i, o.selectors...)
+	}
+
+	if i == nil {
+		return errors.New("i can't be nil")
+	}
+
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("i must be pointer")
+	}
+
+	var tags []wireTag
+	for _, s := range o.selectors {
+		tag := toWireTag(s)
+		tag.nullable = true
+		tags = append(tags, tag)
+	}
+
+	switch v.Elem().Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		if len(tags) == 0 {
+			tags = append(tags, wireTag{nullable: true})
+		}
+	}
+
+	stack := newWiringStack()
+	return p.c.autowire(v.Elem(), tags, stack)
+}
+
 // Find 查找符合条件的 bean 对象，注意该函数只能保证返回的 bean 是有效的，即未被
 // 标记为删除的，而不能保证已经完成属性绑定和依赖注入。
 func (p *pandora) Find(selector bean.Selector) ([]bean.Definition, error) {
@@ -137,6 +236,8 @@ func (p *pandora) Wire(objOrCtor interface{}, ctorArgs ...arg.Arg) (interface{},
 	return b.Interface(), nil
 }
 
+// Invoke 调用函数 fn ，函数的参数由容器根据 args 进行属性绑定和依赖注入，返回
+// 函数除 error 之外的返回值。
 func (p *pandora) Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, error) {
 
 	if !util.IsFuncType(reflect.TypeOf(fn)) {
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"github.com/go-spring/spring-core/conf"
+)
+
+// propertyStore 将通过 actuator 修改的日志级别和功能开关写回可写的属性源，属性
+// 列表不可写时修改只在运行期间有效。
+type propertyStore struct {
+	p *conf.Properties
+}
+
+func (s *propertyStore) persist(key string, val interface{}) error {
+	if !s.p.Writable() {
+		return nil
+	}
+	return s.p.Persist(key, val)
+}
+
+// SaveLevel 保存日志的级别到 logging.level.{name} 属性。
+func (s *propertyStore) SaveLevel(name string, level string) error {
+	if level == "" {
+		return s.persist("logging.level."+name, nil)
+	}
+	return s.persist("logging.level."+name, level)
+}
+
+// SaveFlag 保存功能开关的值到 feature.flags.{flag} 属性。
+func (s *propertyStore) SaveFlag(flag string, value interface{}) error {
+	return s.persist("feature.flags."+flag, value)
+}
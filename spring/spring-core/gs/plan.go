@@ -0,0 +1,153 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// wiringPlan 结构体类型的注入计划，记录了需要依赖注入的字段、预先解析好的注入
+// 标签以及需要递归处理的结构体字段。注入计划只与类型有关，所以在第一次注入某个
+// 类型之后缓存起来，同一类型的其他实例 (包括测试中反复创建的容器) 直接复用，不
+// 必每次都重新解析标签和反射字段信息。
+type wiringPlan struct {
+	fields []plannedField
+	values []valueField
+}
+
+// plannedField 需要依赖注入或者递归处理的字段。
+type plannedField struct {
+	index  int
+	name   string    // 类型名.字段名，用于错误信息
+	tag    string    // 原始的注入标签
+	wire   bool      // 是否需要依赖注入
+	lazy   bool      // 是否延迟注入
+	expr   bool      // 标签以 ${ 开头，需要在注入时解析属性
+	tags   []wireTag // 预先解析的注入标签
+	nested bool      // 结构体字段，需要递归处理
+}
+
+// valueField 带有 value 标签的字段，用于记录 bean 引用的属性以及绑定的配置结构体。
+type valueField struct {
+	index  int
+	tag    string
+	config bool // 是否为结构体字段
+}
+
+var wiringPlans sync.Map // map[reflect.Type]*wiringPlan
+
+// 注入标签的解析缓存，缓存的结果是共享的，使用者不能修改。类型的数量是有限的，
+// 但是字符串形式的注入标签可能来自运行时传入的选择器和刷新后的属性值，所以字符
+// 串缓存的条目数达到 maxTagCacheSize 之后不再缓存新的标签。
+var (
+	wireTagCache  sync.Map // map[string]wireTag
+	wireTagsCache sync.Map // map[string][]wireTag
+	typeTagCache  sync.Map // map[reflect.Type]wireTag
+
+	wireTagCount  int32
+	wireTagsCount int32
+)
+
+// maxTagCacheSize 字符串注入标签缓存的最大条目数。
+const maxTagCacheSize = 4096
+
+// storeTag 在缓存未满时保存 key 对应的解析结果，返回缓存中实际的结果。
+func storeTag(m *sync.Map, count *int32, key string, value interface{}) interface{} {
+	if atomic.LoadInt32(count) >= maxTagCacheSize {
+		return value
+	}
+	actual, loaded := m.LoadOrStore(key, value)
+	if !loaded {
+		atomic.AddInt32(count, 1)
+	}
+	return actual
+}
+
+// planOf 返回结构体类型 t 的注入计划。
+func planOf(t reflect.Type) *wiringPlan {
+	if p, ok := wiringPlans.Load(t); ok {
+		return p.(*wiringPlan)
+	}
+	p, _ := wiringPlans.LoadOrStore(t, newWiringPlan(t))
+	return p.(*wiringPlan)
+}
+
+func newWiringPlan(t reflect.Type) *wiringPlan {
+
+	typeName := t.Name()
+	if typeName == "" { // 简单类型没有名字
+		typeName = t.String()
+	}
+
+	p := &wiringPlan{}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+
+		if tag, ok := ft.Tag.Lookup("value"); ok {
+			p.values = append(p.values, valueField{
+				index:  i,
+				tag:    tag,
+				config: ft.Type.Kind() == reflect.Struct,
+			})
+		}
+
+		f := plannedField{
+			index:  i,
+			name:   typeName + "." + ft.Name,
+			nested: ft.Type.Kind() == reflect.Struct,
+		}
+
+		// 支持 autowire 和 inject 标签，支持 lazy 注入。
+		tag, ok := ft.Tag.Lookup("autowire")
+		if !ok {
+			tag, ok = ft.Tag.Lookup("inject")
+		}
+		if ok {
+			f.wire = true
+			f.tag = tag
+			f.lazy = strings.HasSuffix(tag, ",lazy")
+			f.expr = strings.HasPrefix(tag, "${")
+			if !f.lazy && !f.expr {
+				f.tags = parseWireTags(tag)
+			}
+		}
+
+		if f.wire || f.nested {
+			p.fields = append(p.fields, f)
+		}
+	}
+	return p
+}
+
+// parseWireTags 解析以逗号分隔的注入标签，空字符串返回 nil 。返回的切片是共享
+// 的，使用者不能修改。
+func parseWireTags(tag string) []wireTag {
+	if tag == "" {
+		return nil
+	}
+	if tags, ok := wireTagsCache.Load(tag); ok {
+		return tags.([]wireTag)
+	}
+	var tags []wireTag
+	for _, s := range strings.Split(tag, ",") {
+		tags = append(tags, parseWireTag(s))
+	}
+	return storeTag(&wireTagsCache, &wireTagsCount, tag, tags).([]wireTag)
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// Option 创建容器时的选项。
+type Option func(c *Container)
+
+// WithProfiling 开启容器刷新过程的性能分析，记录各个阶段的耗时和内存分配次数、
+// 各类反射调用的次数，并在刷新期间采集 CPU profile 。性能分析会在每个阶段结束
+// 时读取内存统计信息 (短暂地暂停所有协程) ，所以只应在基准测试或者排查问题时开启，
+// 可以在 CI 中据此跟踪注入引擎的性能退化。
+func WithProfiling() Option {
+	return func(c *Container) {
+		c.profile = &profiler{calls: make(map[string]int64)}
+		c.profile.mark()
+	}
+}
+
+// 反射调用的种类。
+const (
+	CallConstructor = "constructor" // 调用构造函数
+	CallInit        = "init"        // 调用初始化函数
+	CallBind        = "bind"        // 属性绑定
+	CallAutowire    = "autowire"    // 字段或者参数的依赖注入
+)
+
+// PhaseProfile 一个阶段的性能数据，分配次数统计的是从上一个阶段结束 (或者开启
+// 性能分析) 到本阶段结束之间的内存分配。
+type PhaseProfile struct {
+	Name       string        `json:"name"`
+	Duration   time.Duration `json:"duration"`
+	Allocs     uint64        `json:"allocs"`
+	AllocBytes uint64        `json:"allocBytes"`
+}
+
+// Profile 容器刷新过程的性能分析报告。
+type Profile struct {
+	Duration   time.Duration    `json:"duration"`   // Refresh 的总耗时
+	Allocs     uint64           `json:"allocs"`     // Refresh 期间的内存分配次数
+	AllocBytes uint64           `json:"allocBytes"` // Refresh 期间分配的字节数
+	Beans      int              `json:"beans"`      // 刷新后有效的 bean 的数量
+	Phases     []PhaseProfile   `json:"phases"`
+	Calls      map[string]int64 `json:"calls"` // 各类反射调用的次数
+
+	cpu []byte // 刷新期间采集的 CPU profile
+}
+
+// WriteJSON 以 JSON 格式输出报告。
+func (p *Profile) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WritePprof 输出刷新期间采集的 CPU profile ，可以使用 go tool pprof 查看。进程
+// 中已经有其他的 CPU profile 在采集时 (例如 go test -cpuprofile) 无法采集。
+func (p *Profile) WritePprof(w io.Writer) error {
+	if len(p.cpu) == 0 {
+		return errors.New("cpu profile not captured")
+	}
+	_, err := w.Write(p.cpu)
+	return err
+}
+
+// profiler 刷新过程中收集性能数据，刷新是单协程进行的所以不需要加锁。
+type profiler struct {
+	calls  map[string]int64
+	phases []PhaseProfile
+
+	last  runtime.MemStats // 上一个阶段结束时的内存统计信息
+	begin runtime.MemStats // 刷新开始时的内存统计信息
+	start time.Time
+
+	cpu       bytes.Buffer
+	capturing bool
+
+	refreshed  bool
+	duration   time.Duration
+	allocs     uint64
+	allocBytes uint64
+	beans      int
+}
+
+func (p *profiler) mark() {
+	runtime.ReadMemStats(&p.last)
+}
+
+func (p *profiler) phase(name string, d time.Duration) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	p.phases = append(p.phases, PhaseProfile{
+		Name:       name,
+		Duration:   d,
+		Allocs:     m.Mallocs - p.last.Mallocs,
+		AllocBytes: m.TotalAlloc - p.last.TotalAlloc,
+	})
+	p.last = m
+}
+
+func (p *profiler) startRefresh() {
+	p.start = time.Now()
+	runtime.ReadMemStats(&p.begin)
+	if err := pprof.StartCPUProfile(&p.cpu); err != nil {
+		log.Warnf("profiling: cpu profile not captured: %v", err)
+		return
+	}
+	p.capturing = true
+}
+
+// stopRefresh 结束刷新过程的性能分析，刷新失败时也需要调用以停止采集 CPU profile 。
+func (p *profiler) stopRefresh(beans int, ok bool) {
+	if p.capturing {
+		pprof.StopCPUProfile()
+		p.capturing = false
+	}
+	if !ok {
+		return
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	p.refreshed = true
+	p.duration = time.Since(p.start)
+	p.allocs = m.Mallocs - p.begin.Mallocs
+	p.allocBytes = m.TotalAlloc - p.begin.TotalAlloc
+	p.beans = beans
+}
+
+// count 记录一次 kind 类型的反射调用，没有开启性能分析时什么也不做。只统计刷新
+// 过程中的调用，刷新之后通过 Pandora 进行的注入可能是并发的。
+func (c *Container) count(kind string) {
+	if c.profile != nil && c.state == Refreshing {
+		c.profile.calls[kind]++
+	}
+}
+
+// Profile 返回容器刷新过程的性能分析报告，Phases 包含截止到调用时记录的所有阶段。
+// 没有开启性能分析或者还没有成功刷新时返回 nil 。
+func (c *Container) Profile() *Profile {
+	p := c.profile
+	if p == nil || !p.refreshed {
+		return nil
+	}
+	calls := make(map[string]int64, len(p.calls))
+	for k, v := range p.calls {
+		calls[k] = v
+	}
+	phases := make([]PhaseProfile, len(p.phases))
+	copy(phases, p.phases)
+	return &Profile{
+		Duration:   p.duration,
+		Allocs:     p.allocs,
+		AllocBytes: p.allocBytes,
+		Beans:      p.beans,
+		Phases:     phases,
+		Calls:      calls,
+		cpu:        p.cpu.Bytes(),
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// Registry 可以注册 bean 的对象，*Container 和 *App 实现了该接口。
+type Registry interface {
+	register(b *BeanDefinition) *BeanDefinition
+}
+
+func (app *App) register(b *BeanDefinition) *BeanDefinition {
+	return app.c.register(b)
+}
+
+// provide 使用静态调用器注册构造函数形式的 bean ，r 为 nil 时注册到全局的 App
+// 对象上。
+func provide(r Registry, ctor interface{}, invoker arg.Invoker, args []arg.Arg) *BeanDefinition {
+	if r == nil {
+		r = app
+	}
+	return r.register(newBean(ctor, invoker, args, 2))
+}
+
+// as 将静态调用器的参数转换为 T 类型，未注入的接口或者指针参数为 nil 。
+func as[T any](i interface{}) T {
+	v, _ := i.(T)
+	return v
+}
+
+// Provide0 注册无参数的构造函数形式的 bean ，容器直接调用构造函数而不通过反射，
+// r 为 nil 时注册到全局的 App 对象上。
+func Provide0[T any](r Registry, fn func() T) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn()}
+	}, nil)
+}
+
+// Provide1 注册一个参数的构造函数形式的 bean ，参数类型在编译期确定，容器直接
+// 调用构造函数而不通过反射，args 的用法与 Provide 方法一致。
+func Provide1[T, A1 any](r Registry, fn func(A1) T, args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn(as[A1](in[0]))}
+	}, args)
+}
+
+// Provide2 注册两个参数的构造函数形式的 bean ，参见 Provide1 。
+func Provide2[T, A1, A2 any](r Registry, fn func(A1, A2) T, args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn(as[A1](in[0]), as[A2](in[1]))}
+	}, args)
+}
+
+// Provide3 注册三个参数的构造函数形式的 bean ，参见 Provide1 。
+func Provide3[T, A1, A2, A3 any](r Registry, fn func(A1, A2, A3) T, args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]))}
+	}, args)
+}
+
+// Provide4 注册四个参数的构造函数形式的 bean ，参见 Provide1 。
+func Provide4[T, A1, A2, A3, A4 any](r Registry, fn func(A1, A2, A3, A4) T, args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]), as[A4](in[3]))}
+	}, args)
+}
+
+// Provide5 注册五个参数的构造函数形式的 bean ，参见 Provide1 。
+func Provide5[T, A1, A2, A3, A4, A5 any](r Registry, fn func(A1, A2, A3, A4, A5) T, args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		return []interface{}{fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]), as[A4](in[3]), as[A5](in[4]))}
+	}, args)
+}
+
+// ProvideE0 注册返回 error 的无参数构造函数形式的 bean ，参见 Provide0 。
+func ProvideE0[T any](r Registry, fn func() (T, error)) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn()
+		return []interface{}{r0, err}
+	}, nil)
+}
+
+// ProvideE1 注册返回 error 的一个参数的构造函数形式的 bean ，参见 Provide1 。
+func ProvideE1[T, A1 any](r Registry, fn func(A1) (T, error), args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn(as[A1](in[0]))
+		return []interface{}{r0, err}
+	}, args)
+}
+
+// ProvideE2 注册返回 error 的两个参数的构造函数形式的 bean ，参见 Provide1 。
+func ProvideE2[T, A1, A2 any](r Registry, fn func(A1, A2) (T, error), args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn(as[A1](in[0]), as[A2](in[1]))
+		return []interface{}{r0, err}
+	}, args)
+}
+
+// ProvideE3 注册返回 error 的三个参数的构造函数形式的 bean ，参见 Provide1 。
+func ProvideE3[T, A1, A2, A3 any](r Registry, fn func(A1, A2, A3) (T, error), args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]))
+		return []interface{}{r0, err}
+	}, args)
+}
+
+// ProvideE4 注册返回 error 的四个参数的构造函数形式的 bean ，参见 Provide1 。
+func ProvideE4[T, A1, A2, A3, A4 any](r Registry, fn func(A1, A2, A3, A4) (T, error), args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]), as[A4](in[3]))
+		return []interface{}{r0, err}
+	}, args)
+}
+
+// ProvideE5 注册返回 error 的五个参数的构造函数形式的 bean ，参见 Provide1 。
+func ProvideE5[T, A1, A2, A3, A4, A5 any](r Registry, fn func(A1, A2, A3, A4, A5) (T, error), args ...arg.Arg) *BeanDefinition {
+	return provide(r, fn, func(in []interface{}) []interface{} {
+		r0, err := fn(as[A1](in[0]), as[A2](in[1]), as[A3](in[2]), as[A4](in[3]), as[A5](in[4]))
+		return []interface{}{r0, err}
+	}, args)
+}
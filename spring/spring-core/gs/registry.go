@@ -0,0 +1,151 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/go-spring/spring-core/gs/bean"
+)
+
+// registry 进程内具名容器的注册表，用于在一个进程中运行多个逻辑上独立的应用。
+var registry = struct {
+	sync.RWMutex
+	m map[string]*Container
+}{m: make(map[string]*Container)}
+
+// WithName 为容器命名并将其注册到进程内的注册表中，之后可以通过 Lookup 获取，
+// 容器关闭时自动注销。名称重复时 New 会 panic 。具名容器之间除了通过 Bridge
+// 显式声明的 bean 之外互相隔离，各自拥有独立的属性、bean 和生命周期。
+func WithName(name string) Option {
+	return func(c *Container) {
+		c.name = name
+	}
+}
+
+// Lookup 返回名为 name 的容器，不存在时返回 nil 。
+func Lookup(name string) *Container {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.m[name]
+}
+
+// Names 返回所有具名容器的名称，按字典序排列。
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.m))
+	for name := range registry.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func registerContainer(c *Container) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.m[c.name]; ok {
+		panic(fmt.Errorf("container %q already exists", c.name))
+	}
+	registry.m[c.name] = c
+}
+
+func unregisterContainer(c *Container) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.m[c.name] == c {
+		delete(registry.m, c.name)
+	}
+}
+
+// Name 返回容器的名称，匿名容器返回空字符串。
+func (c *Container) Name() string {
+	return c.name
+}
+
+// bridge 从其他容器导入 bean 的声明。
+type bridge struct {
+	source   *Container
+	selector bean.Selector
+	file     string
+	line     int
+}
+
+// Bridge 声明从名为 source 的容器中导入一个符合 selector 的 bean，导入的 bean
+// 可以像本容器的 bean 一样被注入，但是它的属性绑定、依赖注入和销毁都由来源容器
+// 负责。来源容器必须先于本容器刷新，并且只能匹配到一个 bean 。
+func (c *Container) Bridge(source string, selector bean.Selector) {
+	if c.state != Unrefreshed {
+		panic(errors.New("should call before Refresh"))
+	}
+	s := Lookup(source)
+	if s == nil {
+		panic(fmt.Errorf("container %q not found", source))
+	}
+	if s == c {
+		panic(errors.New("can't bridge beans from itself"))
+	}
+	if s.state == Refreshed && s.beansById == nil {
+		panic(fmt.Errorf("container %q has released its beans", source))
+	}
+	s.keepCache = true // 来源容器刷新后保留 bean 以便查找
+	_, file, line, _ := runtime.Caller(1)
+	c.bridges = append(c.bridges, bridge{s, selector, file, line})
+}
+
+// registerBridges 从来源容器中查找桥接的 bean 并注册到本容器中。
+func (c *Container) registerBridges() error {
+	for _, br := range c.bridges {
+		if br.source.state != Refreshed {
+			return fmt.Errorf("container %q should be refreshed before %q", br.source.name, c.name)
+		}
+		beans, err := br.source.findBean(br.selector)
+		if err != nil {
+			return err
+		}
+		if len(beans) != 1 {
+			return fmt.Errorf("bridge %s:%d found %d beans for %q in container %q",
+				br.file, br.line, len(beans), toWireTag(br.selector), br.source.name)
+		}
+		b := beans[0]
+		d := &BeanDefinition{
+			t:        b.t,
+			v:        b.v,
+			name:     b.name,
+			typeName: b.typeName,
+			status:   Default,
+			primary:  b.primary,
+			order:    b.order,
+			file:     br.file,
+			line:     br.line,
+			bridged:  br.source.name,
+			exports:  make(map[reflect.Type]struct{}, len(b.exports)),
+		}
+		for t := range b.exports {
+			d.exports[t] = struct{}{}
+		}
+		c.beans = append(c.beans, d)
+	}
+	c.bridges = nil
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-spring/spring-core/gs/environ"
+	"github.com/go-spring/spring-core/log"
+)
+
+// 关闭钩子的内置阶段，阶段值越小越先执行，同一阶段内按照注册的顺序执行。
+const (
+	PhaseStopTraffic = -100 // 停止接收流量，例如从注册中心下线、停止 Web 服务器
+	PhaseDefault     = 0    // 默认阶段
+	PhaseFlush       = 100  // 刷新缓冲数据，例如上报指标、刷新日志
+)
+
+// DefaultShutdownTimeout 默认的关闭宽限期。
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownHook 容器关闭时执行的钩子函数。
+type ShutdownHook struct {
+	name  string
+	phase int
+	fn    func(ctx context.Context) error
+}
+
+// Name 设置钩子的名称，用于日志输出。
+func (h *ShutdownHook) Name(name string) *ShutdownHook {
+	h.name = name
+	return h
+}
+
+// Phase 设置钩子的执行阶段，阶段值越小越先执行。
+func (h *ShutdownHook) Phase(phase int) *ShutdownHook {
+	h.phase = phase
+	return h
+}
+
+// OnShutdown 注册容器关闭时执行的钩子函数。钩子函数在所有 goroutine 退出以及
+// bean 销毁之前执行，ctx 在关闭宽限期结束时取消。
+func (c *Container) OnShutdown(fn func(ctx context.Context) error) *ShutdownHook {
+	h := &ShutdownHook{fn: fn, name: fmt.Sprintf("hook#%d", len(c.hooks))}
+	c.hooks = append(c.hooks, h)
+	return h
+}
+
+// shutdownTimeout 返回关闭宽限期，可以通过 spring.shutdown.timeout 属性设置。
+func (c *Container) shutdownTimeout() time.Duration {
+	s, ok := c.p.Get(environ.SpringShutdownTimeout).(string)
+	if !ok || s == "" {
+		return DefaultShutdownTimeout
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Warnf("invalid %s %q, use default %s", environ.SpringShutdownTimeout, s, DefaultShutdownTimeout)
+		return DefaultShutdownTimeout
+	}
+	return d
+}
+
+// SmartLifecycle 关闭时需要排空在途任务的组件，例如线程池、消息消费者和定时任务，
+// 实现该接口的 bean 会被自动管理。容器关闭时首先按照阶段顺序调用所有组件的 Stop
+// 方法停止接收新的任务，然后按照阶段顺序执行关闭钩子和 Drain 方法，同一阶段先执行
+// 关闭钩子，再并发地排空所有组件，ctx 在阶段超时或者宽限期结束时取消。
+type SmartLifecycle interface {
+	Phase() int                      // 所处的关闭阶段，阶段值越小越先排空
+	Stop()                           // 停止接收新的任务，不能阻塞
+	Drain(ctx context.Context) error // 等待在途任务完成
+}
+
+// collectLifecycles 按照注册顺序收集实现了 SmartLifecycle 接口的 bean 。
+func (c *Container) collectLifecycles() []SmartLifecycle {
+	var ret []SmartLifecycle
+	for _, b := range c.beans {
+		if b.status != Wired {
+			continue
+		}
+		if l, ok := b.Interface().(SmartLifecycle); ok {
+			ret = append(ret, l)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Phase() < ret[j].Phase()
+	})
+	return ret
+}
+
+// stopLifecycles 让所有的 SmartLifecycle 停止接收新的任务。
+func (c *Container) stopLifecycles() {
+	for _, l := range c.lifecycles {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("stop %T panic: %v", l, r)
+				}
+			}()
+			l.Stop()
+		}()
+	}
+}
+
+// phaseTimeout 返回每个阶段的超时时间，可以通过 spring.shutdown.phase-timeout
+// 属性设置，返回 0 表示只受宽限期的限制。
+func (c *Container) phaseTimeout() time.Duration {
+	s, ok := c.p.Get(environ.SpringShutdownPhaseTimeout).(string)
+	if !ok || s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Warnf("invalid %s %q, ignored", environ.SpringShutdownPhaseTimeout, s)
+		return 0
+	}
+	return d
+}
+
+// runPhases 按照阶段顺序执行关闭钩子并排空 SmartLifecycle ，宽限期结束后剩余的
+// 钩子不再执行，剩余的组件也不再等待。
+func (c *Container) runPhases(ctx context.Context) {
+
+	hooks := make([]*ShutdownHook, len(c.hooks))
+	copy(hooks, c.hooks)
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].phase < hooks[j].phase
+	})
+
+	var phases []int
+	for _, h := range hooks {
+		phases = append(phases, h.phase)
+	}
+	for _, l := range c.lifecycles {
+		phases = append(phases, l.Phase())
+	}
+	sort.Ints(phases)
+
+	timeout := c.phaseTimeout()
+	for i, phase := range phases {
+		if i > 0 && phases[i-1] == phase {
+			continue
+		}
+		for _, h := range hooks {
+			if h.phase != phase {
+				continue
+			}
+			if ctx.Err() != nil {
+				log.Warnf("shutdown timeout, skip hook %s", h.name)
+				continue
+			}
+			if err := runHook(ctx, h); err != nil {
+				log.Errorf("shutdown hook %s error: %v", h.name, err)
+			}
+		}
+		c.drainPhase(ctx, phase, timeout)
+	}
+}
+
+// drainPhase 并发地排空同一阶段的所有 SmartLifecycle 。
+func (c *Container) drainPhase(ctx context.Context, phase int, timeout time.Duration) {
+
+	var lifecycles []SmartLifecycle
+	for _, l := range c.lifecycles {
+		if l.Phase() == phase {
+			lifecycles = append(lifecycles, l)
+		}
+	}
+	if len(lifecycles) == 0 {
+		return
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for _, l := range lifecycles {
+		wg.Add(1)
+		go func(l SmartLifecycle) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("drain %T panic: %v", l, r)
+				}
+			}()
+			if err := l.Drain(ctx); err != nil {
+				log.Errorf("drain %T error: %v", l, err)
+			}
+		}(l)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warnf("shutdown phase %d didn't drain in time", phase)
+	}
+}
+
+func runHook(ctx context.Context, h *ShutdownHook) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.fn(ctx)
+}
+
+var signalNames = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// parseSignals 解析逗号分隔的信号名称，名称可以省略 SIG 前缀。
+func parseSignals(s string) ([]os.Signal, error) {
+	var signals []os.Signal
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		sig, ok := signalNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported signal %q", name)
+		}
+		signals = append(signals, sig)
+	}
+	return signals, nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"sort"
+	"time"
+)
+
+// StartupStep 启动过程中一个阶段的耗时。
+type StartupStep struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// BeanTiming bean 注入的耗时，Total 包含注入其依赖项的耗时，Self 不包含。
+type BeanTiming struct {
+	ID     string
+	Source string
+	Total  time.Duration
+	Self   time.Duration
+}
+
+// StartupReport 启动耗时报告，Beans 按照 Self 从大到小排列，可以据此找出拖慢
+// 启动速度的 bean 。
+type StartupReport struct {
+	Steps []StartupStep
+	Beans []BeanTiming
+}
+
+// step 记录从 start 开始的名为 name 的阶段的耗时。
+func (c *Container) step(name string, start time.Time) {
+	d := time.Since(start)
+	c.steps = append(c.steps, StartupStep{
+		Name:     name,
+		Start:    start,
+		Duration: d,
+	})
+	if c.profile != nil {
+		c.profile.phase(name, d)
+	}
+}
+
+// saveBeanTimings 保存所有 bean 的注入耗时，因为容器可能会清空 bean 的缓存。
+func (c *Container) saveBeanTimings() {
+	var timings []BeanTiming
+	for _, b := range c.beans {
+		if b.status != Wired {
+			continue
+		}
+		timings = append(timings, BeanTiming{
+			ID:     b.ID(),
+			Source: b.FileLine(),
+			Total:  b.wiringTime,
+			Self:   b.wiringTime - b.nestedTime,
+		})
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Self == timings[j].Self {
+			return timings[i].ID < timings[j].ID
+		}
+		return timings[i].Self > timings[j].Self
+	})
+	c.beanTimings = timings
+}
+
+// Startup 返回容器的启动耗时报告。
+func (c *Container) Startup() StartupReport {
+	return StartupReport{Steps: c.steps, Beans: c.beanTimings}
+}
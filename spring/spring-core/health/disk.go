@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"context"
+	"syscall"
+)
+
+// NewDiskIndicator 创建检查 path 所在磁盘剩余空间的指示器，剩余空间小于
+// threshold 字节时返回异常状态。
+func NewDiskIndicator(path string, threshold int64) Indicator {
+	return IndicatorFunc(func(ctx context.Context) Health {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return Down(err)
+		}
+		free := int64(st.Bavail) * int64(st.Bsize)
+		h := Health{
+			Status: StatusUp,
+			Details: map[string]interface{}{
+				"total":     int64(st.Blocks) * int64(st.Bsize),
+				"free":      free,
+				"threshold": threshold,
+			},
+		}
+		if free < threshold {
+			h.Status = StatusDown
+		}
+		return h
+	})
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"context"
+)
+
+// NewDiskIndicator 创建检查磁盘剩余空间的指示器，当前平台不支持，总是返回
+// 未知状态。
+func NewDiskIndicator(path string, threshold int64) Indicator {
+	return IndicatorFunc(func(ctx context.Context) Health {
+		return Health{Status: StatusUnknown}
+	})
+}
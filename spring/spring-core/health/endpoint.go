@@ -0,0 +1,49 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"strings"
+
+	"github.com/go-spring/spring-core/web"
+)
+
+// Endpoint 将健康检查挂载到 Web 服务器上，提供 {path}、{path}/liveness 和
+// {path}/readiness 三个端点，响应码由汇总的健康状态决定。
+type Endpoint struct {
+	Router  web.Router `autowire:""`
+	Checker *Checker   `autowire:""`
+}
+
+// OnInit 挂载健康检查的端点。
+func (e *Endpoint) OnInit() {
+	path := strings.TrimSuffix(e.Checker.config.Path, "/")
+	e.Router.GetMapping(path, e.handler(""))
+	e.Router.GetMapping(path+"/"+GroupLiveness, e.handler(GroupLiveness))
+	e.Router.GetMapping(path+"/"+GroupReadiness, e.handler(GroupReadiness))
+}
+
+func (e *Endpoint) handler(group string) web.HandlerFunc {
+	return func(ctx web.Context) {
+		h := e.Checker.Check(ctx.Context(), group)
+		if !e.Checker.config.ShowDetails {
+			h = Health{Status: h.Status}
+		}
+		ctx.Status(h.Status.HTTPStatus())
+		ctx.JSON(h)
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package health 提供了应用的健康检查，汇总所有 Indicator 的检查结果，通过
+// liveness 和 readiness 两个端点供 Kubernetes 等平台进行探活。
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+)
+
+// Status 健康状态。
+type Status string
+
+const (
+	StatusUp           = Status("UP")             // 正常
+	StatusDown         = Status("DOWN")           // 异常
+	StatusOutOfService = Status("OUT_OF_SERVICE") // 停止服务
+	StatusUnknown      = Status("UNKNOWN")        // 未知
+)
+
+// severity 返回状态的严重程度，汇总时取最严重的状态。
+func (s Status) severity() int {
+	switch s {
+	case StatusDown:
+		return 3
+	case StatusOutOfService:
+		return 2
+	case StatusUp:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// HTTPStatus 返回健康状态对应的 HTTP 响应码。
+func (s Status) HTTPStatus() int {
+	switch s {
+	case StatusDown, StatusOutOfService:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusOK
+	}
+}
+
+const (
+	GroupLiveness  = "liveness"  // 存活检查，失败时平台会重启应用
+	GroupReadiness = "readiness" // 就绪检查，失败时平台不再转发流量
+)
+
+// Health 健康检查的结果。
+type Health struct {
+	Status     Status                 `json:"status"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	Components map[string]Health      `json:"components,omitempty"`
+}
+
+// Up 返回正常状态的检查结果。
+func Up() Health {
+	return Health{Status: StatusUp}
+}
+
+// Down 返回异常状态的检查结果，err 被记录在 details 中。
+func Down(err error) Health {
+	h := Health{Status: StatusDown}
+	if err != nil {
+		h.Details = map[string]interface{}{"error": err.Error()}
+	}
+	return h
+}
+
+// Indicator 健康检查指示器，导出为该接口的 bean 以 bean 名称作为组件名称参与
+// 健康检查，默认只属于 readiness 分组，可以通过实现 Grouped 接口指定分组。
+type Indicator interface {
+	Health(ctx context.Context) Health
+}
+
+// Grouped 可以由 Indicator 实现的可选接口，返回其所属的分组。
+type Grouped interface {
+	Groups() []string
+}
+
+// IndicatorFunc 函数形式的 Indicator 。
+type IndicatorFunc func(ctx context.Context) Health
+
+func (f IndicatorFunc) Health(ctx context.Context) Health {
+	return f(ctx)
+}
+
+// PingIndicator 总是返回正常状态，属于 liveness 和 readiness 分组，表示应用
+// 进程可以响应请求。
+type PingIndicator struct{}
+
+func (PingIndicator) Health(ctx context.Context) Health {
+	return Up()
+}
+
+func (PingIndicator) Groups() []string {
+	return []string{GroupLiveness, GroupReadiness}
+}
+
+// NewDBIndicator 创建检查数据库连接的指示器。
+func NewDBIndicator(db *sql.DB) Indicator {
+	return IndicatorFunc(func(ctx context.Context) Health {
+		if err := db.PingContext(ctx); err != nil {
+			return Down(err)
+		}
+		return Up()
+	})
+}
+
+// NewRedisIndicator 创建检查 Redis 连接的指示器。
+func NewRedisIndicator(client redis.Client) Indicator {
+	return IndicatorFunc(func(ctx context.Context) Health {
+		if err := client.Ping(ctx); err != nil {
+			return Down(err)
+		}
+		return Up()
+	})
+}
+
+// Config 健康检查配置，通常绑定到 health 前缀的属性上。
+type Config struct {
+	Path        string        `value:"${path:=/actuator/health}"` // 端点的路径前缀
+	Timeout     time.Duration `value:"${timeout:=3s}"`            // 单个指示器的超时时间
+	CacheTTL    time.Duration `value:"${cache-ttl:=1s}"`          // 检查结果的缓存时间，0 表示不缓存
+	ShowDetails bool          `value:"${show-details:=true}"`     // 是否返回各个组件的检查结果
+}
+
+type cached struct {
+	h        Health
+	expireAt time.Time
+}
+
+// Checker 汇总所有指示器的检查结果。
+type Checker struct {
+	Indicators map[string]Indicator `autowire:""`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+	mutex  sync.Mutex
+	cache  map[string]cached
+}
+
+// NewChecker Checker 的构造函数。
+func NewChecker(config Config) *Checker {
+	return &Checker{config: &config, cache: make(map[string]cached)}
+}
+
+// indicators 返回属于分组 group 的指示器，group 为空时返回全部指示器。
+func (c *Checker) indicators(group string) map[string]Indicator {
+	if group == "" {
+		return c.Indicators
+	}
+	ret := make(map[string]Indicator)
+	for name, i := range c.Indicators {
+		groups := []string{GroupReadiness}
+		if g, ok := i.(Grouped); ok {
+			groups = g.Groups()
+		}
+		for _, s := range groups {
+			if s == group {
+				ret[name] = i
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Check 并发执行分组 group 的所有指示器并汇总结果，超时的指示器被认为是异常
+// 状态，在缓存时间内重复检查时直接返回上次的结果。
+func (c *Checker) Check(ctx context.Context, group string) Health {
+
+	c.mutex.Lock()
+	if r, ok := c.cache[group]; ok && time.Now().Before(r.expireAt) {
+		c.mutex.Unlock()
+		return r.h
+	}
+	c.mutex.Unlock()
+
+	indicators := c.indicators(group)
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	components := make(map[string]Health, len(indicators))
+	for name, i := range indicators {
+		wg.Add(1)
+		go func(name string, i Indicator) {
+			defer wg.Done()
+			h := c.checkOne(ctx, i)
+			mutex.Lock()
+			components[name] = h
+			mutex.Unlock()
+		}(name, i)
+	}
+	wg.Wait()
+
+	h := Health{Status: StatusUnknown, Components: components}
+	if len(components) == 0 {
+		h.Status = StatusUp
+	}
+	for _, r := range components {
+		if r.Status.severity() > h.Status.severity() {
+			h.Status = r.Status
+		}
+	}
+
+	if c.config.CacheTTL > 0 {
+		c.mutex.Lock()
+		c.cache[group] = cached{h: h, expireAt: time.Now().Add(c.config.CacheTTL)}
+		c.mutex.Unlock()
+	}
+	return h
+}
+
+// Healthy 返回名为 name 的分组或者指示器是否健康，name 为空时检查全部指示器，
+// 指示器不存在时认为是健康的。
+func (c *Checker) Healthy(ctx context.Context, name string) bool {
+	var h Health
+	if i, ok := c.Indicators[name]; ok {
+		h = c.checkOne(ctx, i)
+	} else {
+		h = c.Check(ctx, name)
+	}
+	return h.Status.HTTPStatus() == http.StatusOK
+}
+
+// checkOne 执行单个指示器，超时或者 panic 时返回异常状态。
+func (c *Checker) checkOne(ctx context.Context, i Indicator) Health {
+
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	ch := make(chan Health, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- Health{Status: StatusDown, Details: map[string]interface{}{"error": r}}
+			}
+		}()
+		ch <- i.Health(ctx)
+	}()
+
+	select {
+	case h := <-ch:
+		return h
+	case <-ctx.Done():
+		return Down(ctx.Err())
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestStatus_HTTPStatus(t *testing.T) {
+	assert.Equal(t, health.StatusUp.HTTPStatus(), http.StatusOK)
+	assert.Equal(t, health.StatusUnknown.HTTPStatus(), http.StatusOK)
+	assert.Equal(t, health.StatusDown.HTTPStatus(), http.StatusServiceUnavailable)
+	assert.Equal(t, health.StatusOutOfService.HTTPStatus(), http.StatusServiceUnavailable)
+}
+
+func TestChecker_Check(t *testing.T) {
+
+	c := health.NewChecker(health.Config{Timeout: 50 * time.Millisecond})
+	c.Indicators = map[string]health.Indicator{
+		"ping": health.PingIndicator{},
+		"up": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			return health.Up()
+		}),
+		"down": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			return health.Down(errors.New("connection refused"))
+		}),
+		"slow": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return health.Up()
+		}),
+		"panic": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			panic("boom")
+		}),
+	}
+
+	h := c.Check(context.Background(), "")
+	assert.Equal(t, h.Status, health.StatusDown)
+	assert.Equal(t, len(h.Components), 5)
+	assert.Equal(t, h.Components["up"].Status, health.StatusUp)
+	assert.Equal(t, h.Components["down"].Status, health.StatusDown)
+	assert.Equal(t, h.Components["down"].Details["error"], "connection refused")
+	assert.Equal(t, h.Components["slow"].Status, health.StatusDown)
+	assert.Equal(t, h.Components["panic"].Status, health.StatusDown)
+
+	h = c.Check(context.Background(), health.GroupLiveness)
+	assert.Equal(t, h.Status, health.StatusUp)
+	assert.Equal(t, len(h.Components), 1)
+	assert.Equal(t, h.Components["ping"].Status, health.StatusUp)
+
+	h = c.Check(context.Background(), health.GroupReadiness)
+	assert.Equal(t, h.Status, health.StatusDown)
+	assert.Equal(t, len(h.Components), 5)
+}
+
+func TestChecker_Cache(t *testing.T) {
+
+	var count int32
+	c := health.NewChecker(health.Config{CacheTTL: 50 * time.Millisecond})
+	c.Indicators = map[string]health.Indicator{
+		"count": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			atomic.AddInt32(&count, 1)
+			return health.Up()
+		}),
+	}
+
+	c.Check(context.Background(), "")
+	c.Check(context.Background(), "")
+	assert.Equal(t, atomic.LoadInt32(&count), int32(1))
+
+	time.Sleep(60 * time.Millisecond)
+	c.Check(context.Background(), "")
+	assert.Equal(t, atomic.LoadInt32(&count), int32(2))
+}
+
+func TestChecker_Empty(t *testing.T) {
+	c := health.NewChecker(health.Config{})
+	h := c.Check(context.Background(), health.GroupLiveness)
+	assert.Equal(t, h.Status, health.StatusUp)
+}
+
+func TestChecker_Healthy(t *testing.T) {
+	c := health.NewChecker(health.Config{})
+	c.Indicators = map[string]health.Indicator{
+		"ping": health.PingIndicator{},
+		"down": health.IndicatorFunc(func(ctx context.Context) health.Health {
+			return health.Down(errors.New("connection refused"))
+		}),
+	}
+	ctx := context.Background()
+	assert.True(t, c.Healthy(ctx, "ping"))
+	assert.False(t, c.Healthy(ctx, "down"))
+	assert.True(t, c.Healthy(ctx, health.GroupLiveness))
+	assert.False(t, c.Healthy(ctx, health.GroupReadiness))
+	assert.True(t, c.Healthy(ctx, "unknown"))
+}
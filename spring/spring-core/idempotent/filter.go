@@ -0,0 +1,131 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotent
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+// Config Web 幂等过滤器配置，通常绑定到 web.idempotent 前缀的属性上。
+type Config struct {
+	Header      string        `value:"${header:=Idempotency-Key}"` // 幂等键所在的请求头
+	TTL         time.Duration `value:"${ttl:=24h}"`                // 幂等记录的有效期
+	Required    bool          `value:"${required:=false}"`         // 请求是否必须携带幂等键
+	Methods     []string      `value:"${methods}"`                 // 需要去重的请求方法，默认为 POST 和 PATCH
+	URLPatterns []string      `value:"${url-patterns}"`            // 过滤器作用的路由，默认为全部路由
+}
+
+// Filter Web 请求的幂等过滤器，对携带相同幂等键的重试请求直接返回首次处理的
+// 响应，首次请求仍在处理时返回 409 。只有 JSON、XML 和文本格式的响应体能够被
+// 保存，响应码为 5xx 的请求允许客户端重新处理。
+type Filter struct {
+	store   Store
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	methods map[string]bool
+}
+
+// NewFilter Filter 的构造函数，通过不同的 URLPatterns 配置可以为不同的路由
+// 创建多个过滤器。
+func NewFilter(store Store, config Config) *Filter {
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	m := make(map[string]bool)
+	for _, method := range methods {
+		m[strings.ToUpper(method)] = true
+	}
+	return &Filter{store: store, config: &config, methods: m}
+}
+
+// URLPatterns 返回过滤器作用的路由。
+func (f *Filter) URLPatterns() []string {
+	if len(f.config.URLPatterns) == 0 {
+		return []string{"/*"}
+	}
+	return f.config.URLPatterns
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+
+	req := ctx.Request()
+	if !f.methods[req.Method] {
+		chain.Next(ctx)
+		return
+	}
+
+	key := ctx.GetHeader(f.config.Header)
+	if key == "" {
+		if f.config.Required {
+			web.ErrorHandler(ctx, web.NewHttpError(http.StatusBadRequest, "missing header "+f.config.Header))
+			return
+		}
+		chain.Next(ctx)
+		return
+	}
+
+	key = req.Method + ":" + req.URL.Path + ":" + key
+	r, err := f.store.Acquire(ctx.Context(), key, f.config.TTL)
+	if err != nil {
+		web.ErrorHandler(ctx, web.NewHttpError(http.StatusInternalServerError).SetInternal(err))
+		return
+	}
+
+	if r != nil {
+		if !r.Done {
+			web.ErrorHandler(ctx, web.NewHttpError(http.StatusConflict, "request is being processed"))
+			return
+		}
+		w := ctx.ResponseWriter()
+		for k, v := range r.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(r.Status)
+		if _, err = w.Write(r.Body); err != nil {
+			log.Ctx(ctx.Context()).Error(err)
+		}
+		return
+	}
+
+	done := false
+	defer func() {
+		if !done {
+			if err := f.store.Release(ctx.Context(), key); err != nil {
+				log.Ctx(ctx.Context()).Error(err)
+			}
+		}
+	}()
+
+	chain.Next(ctx)
+
+	w := ctx.ResponseWriter()
+	if w.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	r = &Record{Done: true, Status: w.Status(), Header: w.Header().Clone(), Body: w.Body()}
+	if err = f.store.Save(ctx.Context(), key, r, f.config.TTL); err != nil {
+		log.Ctx(ctx.Context()).Error(err)
+		return
+	}
+	done = true
+}
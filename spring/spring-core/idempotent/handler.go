@@ -0,0 +1,63 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// ErrProcessing 相同幂等键的消息正在被处理。
+var ErrProcessing = errors.New("idempotent: message is being processed")
+
+// Handle 包装消息处理函数，以消息的主题和 ID 作为幂等键，已经处理过的消息被
+// 直接确认，正在处理的消息返回 ErrProcessing 以便稍后重试，处理失败的消息可以
+// 被重新处理。可以为每个订阅的主题单独包装，例如:
+//
+//	kafka.Subscribe("order", idempotent.Handle(store, time.Hour, fn))
+func Handle(store Store, ttl time.Duration, fn func(ctx context.Context, msg mq.Message) error) func(ctx context.Context, msg mq.Message) error {
+	return func(ctx context.Context, msg mq.Message) error {
+
+		if msg.ID() == "" {
+			return fn(ctx, msg)
+		}
+
+		key := msg.Topic() + ":" + msg.ID()
+		r, err := store.Acquire(ctx, key, ttl)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			if r.Done {
+				return nil
+			}
+			return ErrProcessing
+		}
+
+		if err = fn(ctx, msg); err != nil {
+			if e := store.Release(ctx, key); e != nil {
+				log.Ctx(ctx).Error(e)
+			}
+			return err
+		}
+		return store.Save(ctx, key, &Record{Done: true}, ttl)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/idempotent"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+
+	ctx := context.Background()
+	s := idempotent.NewMemoryStore()
+
+	r, err := s.Acquire(ctx, "a", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, r == nil)
+
+	r, err = s.Acquire(ctx, "a", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, r.Done)
+
+	err = s.Save(ctx, "a", &idempotent.Record{Done: true, Status: 201}, time.Minute)
+	assert.Nil(t, err)
+	r, err = s.Acquire(ctx, "a", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Status, 201)
+
+	r, err = s.Acquire(ctx, "b", time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, r == nil)
+	time.Sleep(5 * time.Millisecond)
+	r, err = s.Acquire(ctx, "b", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, r == nil)
+}
+
+func TestHandle(t *testing.T) {
+
+	ctx := context.Background()
+	s := idempotent.NewMemoryStore()
+
+	count := 0
+	fail := true
+	fn := idempotent.Handle(s, time.Minute, func(ctx context.Context, msg mq.Message) error {
+		count++
+		if fail {
+			return errors.New("fail")
+		}
+		return nil
+	})
+
+	msg := mq.NewMessage().WithTopic("order").WithID("1")
+	assert.Error(t, fn(ctx, msg), "fail")
+
+	fail = false
+	assert.Nil(t, fn(ctx, msg))
+	assert.Nil(t, fn(ctx, msg))
+	assert.Equal(t, count, 2)
+
+	_, err := s.Acquire(ctx, "order:2", time.Minute)
+	assert.Nil(t, err)
+	err = fn(ctx, mq.NewMessage().WithTopic("order").WithID("2"))
+	assert.Equal(t, err, idempotent.ErrProcessing)
+}
+
+func TestConfig_Bind(t *testing.T) {
+	p := conf.New()
+	p.Set("web.idempotent.methods[0]", "PUT")
+	p.Set("web.idempotent.url-patterns[0]", "/orders/*")
+	var c idempotent.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("web.idempotent")))
+	assert.Equal(t, c.Methods, []string{"PUT"})
+	assert.Equal(t, c.URLPatterns, []string{"/orders/*"})
+	assert.Equal(t, idempotent.NewFilter(idempotent.NewMemoryStore(), c).URLPatterns(), []string{"/orders/*"})
+}
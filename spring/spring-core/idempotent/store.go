@@ -0,0 +1,237 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package idempotent 提供了基于幂等键的请求和消息去重，Web 请求通过 Filter
+// 去重并对重试请求返回首次处理的响应，消息通过 Handle 包装的处理函数去重。
+// 幂等记录保存在可替换的 Store 中，内置了内存、Redis 和数据库三种实现。
+package idempotent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/json"
+)
+
+// Record 幂等键对应的处理记录。
+type Record struct {
+	Done   bool        // 是否已经处理完成，为 false 时表示正在处理
+	Status int         // Web 请求的响应码
+	Header http.Header // Web 请求的响应头
+	Body   []byte      // Web 请求的响应体
+}
+
+// Store 幂等记录的存储接口。
+type Store interface {
+
+	// Acquire 尝试占用幂等键 key，占用成功时返回 nil，否则返回该键当前的记录。
+	// 占用的键在 ttl 之后过期，防止处理过程异常退出后该键永远无法再被处理。
+	Acquire(ctx context.Context, key string, ttl time.Duration) (*Record, error)
+
+	// Save 保存幂等键 key 的处理结果，结果在 ttl 之后过期。
+	Save(ctx context.Context, key string, r *Record, ttl time.Duration) error
+
+	// Release 释放幂等键 key，处理失败时调用，使之可以被重新处理。
+	Release(ctx context.Context, key string) error
+}
+
+type memoryRecord struct {
+	r        *Record
+	expireAt time.Time
+}
+
+// MemoryStore 基于内存的 Store 实现，只适用于单实例部署。
+type MemoryStore struct {
+	mutex   sync.Mutex
+	records map[string]*memoryRecord
+}
+
+// NewMemoryStore MemoryStore 的构造函数。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord)}
+}
+
+func (s *MemoryStore) Acquire(ctx context.Context, key string, ttl time.Duration) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	if m, ok := s.records[key]; ok && now.Before(m.expireAt) {
+		return m.r, nil
+	}
+	s.records[key] = &memoryRecord{r: &Record{}, expireAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, r *Record, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[key] = &memoryRecord{r: r, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// RedisStore 基于 Redis 的 Store 实现，记录以 JSON 格式保存。
+type RedisStore struct {
+	client redis.Client
+	prefix string
+}
+
+// NewRedisStore RedisStore 的构造函数，prefix 为幂等键的前缀。
+func NewRedisStore(client redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Acquire(ctx context.Context, key string, ttl time.Duration) (*Record, error) {
+	b, err := json.Marshal(&Record{})
+	if err != nil {
+		return nil, err
+	}
+	// 记录可能在 SetNX 和 Get 之间过期，此时重新尝试占用。
+	for {
+		ok, err := s.client.SetNX(ctx, s.prefix+key, string(b), ttl)
+		if err != nil || ok {
+			return nil, err
+		}
+		str, err := s.client.Get(ctx, s.prefix+key)
+		if errors.Is(err, redis.ErrNil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r := new(Record)
+		if err = json.Unmarshal([]byte(str), r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+func (s *RedisStore) Save(ctx context.Context, key string, r *Record, ttl time.Duration) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, string(b), ttl)
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	_, err := s.client.Del(ctx, s.prefix+key)
+	return err
+}
+
+// SQLStore 基于数据库的 Store 实现，幂等记录表需要预先创建，以 MySQL 为例:
+//
+//	CREATE TABLE idempotency (
+//		id VARCHAR(255) NOT NULL PRIMARY KEY,
+//		record BLOB NOT NULL,
+//		expire_at BIGINT NOT NULL
+//	)
+type SQLStore struct {
+	db      *sql.DB
+	table   string
+	dialect string
+}
+
+// NewSQLStore SQLStore 的构造函数，dialect 为 postgres 时使用 $n 格式的占位符，
+// 否则使用 ? 格式的占位符。
+func NewSQLStore(db *sql.DB, table string, dialect string) *SQLStore {
+	return &SQLStore{db: db, table: table, dialect: dialect}
+}
+
+// bind 将 SQL 语句中的 ? 占位符替换为数据库方言支持的格式。
+func (s *SQLStore) bind(query string) string {
+	if s.dialect != "postgres" {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (s *SQLStore) Acquire(ctx context.Context, key string, ttl time.Duration) (*Record, error) {
+
+	b, err := json.Marshal(&Record{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	query := s.bind(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND expire_at < ?", s.table))
+	if _, err = s.db.ExecContext(ctx, query, key, now.UnixNano()); err != nil {
+		return nil, err
+	}
+
+	query = s.bind(fmt.Sprintf("INSERT INTO %s (id, record, expire_at) VALUES (?, ?, ?)", s.table))
+	_, insertErr := s.db.ExecContext(ctx, query, key, b, now.Add(ttl).UnixNano())
+	if insertErr == nil {
+		return nil, nil
+	}
+
+	// 插入失败时如果记录存在说明键已经被占用，否则返回插入时的错误。
+	var data []byte
+	query = s.bind(fmt.Sprintf("SELECT record FROM %s WHERE id = ?", s.table))
+	err = s.db.QueryRowContext(ctx, query, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, insertErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(Record)
+	if err = json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *SQLStore) Save(ctx context.Context, key string, r *Record, ttl time.Duration) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	query := s.bind(fmt.Sprintf("UPDATE %s SET record = ?, expire_at = ? WHERE id = ?", s.table))
+	_, err = s.db.ExecContext(ctx, query, b, time.Now().Add(ttl).UnixNano(), key)
+	return err
+}
+
+func (s *SQLStore) Release(ctx context.Context, key string) error {
+	query := s.bind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table))
+	_, err := s.db.ExecContext(ctx, query, key)
+	return err
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lock 提供了分布式锁，用于保证多个实例中同一时刻只有一个实例执行某个
+// 操作，例如定时任务。
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+)
+
+// Locker 分布式锁接口。
+type Locker interface {
+
+	// TryLock 尝试获取名为 key 的锁，锁在 ttl 之后自动释放。获取成功时 ok 为
+	// true ，返回的 unlock 函数用于提前释放锁。
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(ctx context.Context) error, ok bool, err error)
+}
+
+// token 返回随机的锁持有者标识，释放锁时用于确认锁仍然属于自己。
+func token() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// unlockScript 锁仍然属于持有者时才删除锁，比较和删除在服务器上原子地执行。
+var unlockScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// redisLocker 基于 Redis SET NX 的 Locker 实现。
+type redisLocker struct {
+	client  redis.Client
+	scripts *redis.Scripts
+}
+
+// NewRedisLocker 创建基于 Redis 的 Locker 。释放锁时通过 Lua 脚本原子地比较持有
+// 者并删除锁，所以 client 需要实现 redis.Scripter 接口，否则释放锁时返回
+// redis.ErrNotSupported ，锁只能等待 ttl 之后过期。
+func NewRedisLocker(client redis.Client) Locker {
+	return &redisLocker{client: client, scripts: redis.NewScripts(client)}
+}
+
+func (l *redisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(ctx context.Context) error, bool, error) {
+	t := token()
+	ok, err := l.client.SetNX(ctx, key, t, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	unlock := func(ctx context.Context) error {
+		_, err := l.scripts.Exec(ctx, unlockScript, []string{key}, t)
+		return err
+	}
+	return unlock, true, nil
+}
+
+// memoryLocker 基于内存的 Locker 实现，只在单个进程内有效，主要用于测试。
+type memoryLocker struct {
+	mutex sync.Mutex
+	locks map[string]memoryLock
+}
+
+type memoryLock struct {
+	token    string
+	expireAt time.Time
+}
+
+// NewMemoryLocker 创建基于内存的 Locker 。
+func NewMemoryLocker() Locker {
+	return &memoryLocker{locks: make(map[string]memoryLock)}
+}
+
+func (l *memoryLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(ctx context.Context) error, bool, error) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if m, ok := l.locks[key]; ok && now.Before(m.expireAt) {
+		return nil, false, nil
+	}
+
+	t := token()
+	l.locks[key] = memoryLock{token: t, expireAt: now.Add(ttl)}
+	unlock := func(ctx context.Context) error {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if m, ok := l.locks[key]; ok && m.token == t {
+			delete(l.locks, key)
+		}
+		return nil
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/lock"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// mapClient 只实现了 Locker 用到的命令，脚本按照释放锁的语义执行。
+type mapClient struct {
+	redis.Client
+	m map[string]string
+}
+
+func (c *mapClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if _, ok := c.m[key]; ok {
+		return false, nil
+	}
+	c.m[key] = fmt.Sprint(value)
+	return true, nil
+}
+
+func (c *mapClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if v, ok := c.m[keys[0]]; ok && v == fmt.Sprint(args[0]) {
+		delete(c.m, keys[0])
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func (c *mapClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.Eval(ctx, "", keys, args...)
+}
+
+func (c *mapClient) ScriptLoad(ctx context.Context, script string) (string, error) {
+	return redis.NewScript(script).Hash(), nil
+}
+
+// noScriptClient 没有实现 redis.Scripter 接口。
+type noScriptClient struct {
+	redis.Client
+}
+
+func (c *noScriptClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return true, nil
+}
+
+func testLocker(t *testing.T, l lock.Locker) {
+
+	ctx := context.Background()
+	unlock, ok, err := l.TryLock(ctx, "job", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, ok, err = l.TryLock(ctx, "job", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, unlock(ctx))
+	_, ok, err = l.TryLock(ctx, "job", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 释放已经不属于自己的锁不会产生影响
+	assert.Nil(t, unlock(ctx))
+	_, ok, _ = l.TryLock(ctx, "job", time.Minute)
+	assert.False(t, ok)
+}
+
+func TestRedisLocker(t *testing.T) {
+	testLocker(t, lock.NewRedisLocker(&mapClient{m: make(map[string]string)}))
+
+	l := lock.NewRedisLocker(&noScriptClient{})
+	unlock, ok, err := l.TryLock(context.Background(), "job", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, errors.Is(unlock(context.Background()), redis.ErrNotSupported))
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, lock.NewMemoryLocker())
+
+	l := lock.NewMemoryLocker()
+	_, ok, _ := l.TryLock(context.Background(), "job", 10*time.Millisecond)
+	assert.True(t, ok)
+	time.Sleep(20 * time.Millisecond)
+	_, ok, _ = l.TryLock(context.Background(), "job", 10*time.Millisecond)
+	assert.True(t, ok)
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event 结构化日志事件，通过 Check 方法获取。日志级别被禁用时 Check 返回 nil，
+// Event 的所有方法都可以在 nil 上调用并且什么都不做，因此禁用级别的日志不会发生
+// 任何内存分配，例如：
+//
+//	log.Check(log.DebugLevel).Str("user", name).Int("age", age).Msg("login")
+//
+// 注意 Event 会被复用，调用 Msg 或 Msgf 之后不能再使用。
+type Event struct {
+	e     Entry
+	level Level
+	buf   []byte
+}
+
+var eventPool = sync.Pool{
+	New: func() interface{} { return &Event{buf: make([]byte, 0, 256)} },
+}
+
+// maxEventSize 复用时保留的缓冲区的最大容量。
+const maxEventSize = 64 * 1024
+
+// Check 返回 level 级别的 Event，级别被禁用时返回 nil 。
+func Check(level Level) *Event {
+	return empty.Check(level)
+}
+
+// Check 返回 level 级别的 Event，级别被禁用时返回 nil 。
+func (e Entry) Check(level Level) *Event {
+	if levelOf(e.tag) > level {
+		return nil
+	}
+	ev := eventPool.Get().(*Event)
+	ev.e = e
+	ev.level = level
+	ev.buf = ev.buf[:0]
+	return ev
+}
+
+// Enabled 返回 Event 是否会被输出。
+func (ev *Event) Enabled() bool {
+	return ev != nil
+}
+
+func (ev *Event) key(k string) {
+	ev.buf = append(ev.buf, ' ')
+	ev.buf = append(ev.buf, k...)
+	ev.buf = append(ev.buf, '=')
+}
+
+// Str 添加字符串类型的字段，包含空白或特殊字符时加引号输出。
+func (ev *Event) Str(k, v string) *Event {
+	if ev == nil {
+		return ev
+	}
+	ev.key(k)
+	if v == "" || strings.ContainsAny(v, " \t\r\n=\"") {
+		ev.buf = strconv.AppendQuote(ev.buf, v)
+	} else {
+		ev.buf = append(ev.buf, v...)
+	}
+	return ev
+}
+
+// Int 添加 int 类型的字段。
+func (ev *Event) Int(k string, v int) *Event {
+	return ev.Int64(k, int64(v))
+}
+
+// Int64 添加 int64 类型的字段。
+func (ev *Event) Int64(k string, v int64) *Event {
+	if ev == nil {
+		return ev
+	}
+	ev.key(k)
+	ev.buf = strconv.AppendInt(ev.buf, v, 10)
+	return ev
+}
+
+// Uint64 添加 uint64 类型的字段。
+func (ev *Event) Uint64(k string, v uint64) *Event {
+	if ev == nil {
+		return ev
+	}
+	ev.key(k)
+	ev.buf = strconv.AppendUint(ev.buf, v, 10)
+	return ev
+}
+
+// Float64 添加 float64 类型的字段。
+func (ev *Event) Float64(k string, v float64) *Event {
+	if ev == nil {
+		return ev
+	}
+	ev.key(k)
+	ev.buf = strconv.AppendFloat(ev.buf, v, 'g', -1, 64)
+	return ev
+}
+
+// Bool 添加 bool 类型的字段。
+func (ev *Event) Bool(k string, v bool) *Event {
+	if ev == nil {
+		return ev
+	}
+	ev.key(k)
+	ev.buf = strconv.AppendBool(ev.buf, v)
+	return ev
+}
+
+// Dur 添加 time.Duration 类型的字段。
+func (ev *Event) Dur(k string, v time.Duration) *Event {
+	if ev == nil {
+		return ev
+	}
+	return ev.Str(k, v.String())
+}
+
+// Err 添加名为 error 的字段，err 为 nil 时什么都不做。
+func (ev *Event) Err(err error) *Event {
+	if ev == nil || err == nil {
+		return ev
+	}
+	return ev.Str("error", err.Error())
+}
+
+// Any 添加任意类型的字段，使用 fmt 格式化，值在传入时已经被装箱，因此只应该在
+// 没有对应类型化方法时使用。
+func (ev *Event) Any(k string, v interface{}) *Event {
+	if ev == nil {
+		return ev
+	}
+	return ev.Str(k, fmt.Sprint(v))
+}
+
+// Msg 输出日志，字段以 key=value 的形式追加在 msg 后面。
+func (ev *Event) Msg(msg string) {
+	if ev == nil {
+		return
+	}
+	ev.output(msg)
+}
+
+// Msgf 输出格式化的日志，字段以 key=value 的形式追加在消息后面。
+func (ev *Event) Msgf(format string, args ...interface{}) {
+	if ev == nil {
+		return
+	}
+	ev.output(fmt.Sprintf(format, args...))
+}
+
+func (ev *Event) output(msg string) {
+	fields := ev.buf
+	if msg == "" && len(fields) > 0 {
+		fields = fields[1:]
+	}
+	e := ev.e
+	e.msg = e.prefix() + msg + string(fields)
+	level := ev.level
+	ev.e = Entry{}
+	if cap(ev.buf) <= maxEventSize {
+		eventPool.Put(ev)
+	}
+	config.output(2, level, &e)
+}
@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -59,6 +60,16 @@ func (level Level) String() string {
 	return ""
 }
 
+// ParseLevel 将字符串转换为日志输出级别，不区分大小写。
+func ParseLevel(s string) (Level, error) {
+	for level := TraceLevel; level <= FatalLevel; level++ {
+		if strings.EqualFold(s, level.String()) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
 var empty = Entry{}
 
 // Ctx 创建包含 context.Context 对象的 Entry 。
@@ -101,15 +112,26 @@ func (e Entry) Ctx(ctx context.Context) Entry {
 }
 
 func (e Entry) print(a ...interface{}) *Entry {
-	e.msg = fmt.Sprint(a...)
+	e.msg = e.prefix() + fmt.Sprint(a...)
 	return &e
 }
 
 func (e Entry) printf(format string, a ...interface{}) *Entry {
-	e.msg = fmt.Sprintf(format, a...)
+	e.msg = e.prefix() + fmt.Sprintf(format, a...)
 	return &e
 }
 
+// prefix 返回 context.Context 对象中需要输出到日志内容前面的信息。
+func (e *Entry) prefix() string {
+	if e.ctx == nil {
+		return ""
+	}
+	if fn, _ := ctxPrefix.Load().(func(ctx context.Context) string); fn != nil {
+		return fn(e.ctx)
+	}
+	return ""
+}
+
 // Output 定制日志的输出格式，skip 是相对于当前函数的调用深度。
 type Output func(skip int, level Level, e *Entry)
 
@@ -146,8 +168,40 @@ var config = struct {
 // T 将可变参数转换成切片。
 func T(a ...interface{}) []interface{} { return a }
 
+// ctxPrefix 从 context.Context 对象中提取日志前缀的函数。
+var ctxPrefix atomic.Value
+
+// SetCtxPrefix 设置从 context.Context 对象中提取日志前缀的函数，例如将请求的
+// 关联 ID 输出到每条日志中，只对通过 Ctx 方法设置了上下文的日志生效。
+func SetCtxPrefix(fn func(ctx context.Context) string) {
+	ctxPrefix.Store(fn)
+}
+
+// loggers 具名日志的输出级别，写时复制，存储的是 map[string]Level 类型。
+var loggers atomic.Value
+
+// levelOf 返回 tag 对应的输出级别。tag 即日志的名称，使用 . 分隔层级，没有设
+// 置级别时使用上一层级的级别，都没有设置时使用全局级别。
+func levelOf(tag string) Level {
+	if tag != "" {
+		if m, _ := loggers.Load().(map[string]Level); len(m) > 0 {
+			for name := tag; ; {
+				if level, ok := m[name]; ok {
+					return level
+				}
+				i := strings.LastIndexByte(name, '.')
+				if i < 0 {
+					break
+				}
+				name = name[:i]
+			}
+		}
+	}
+	return config.level
+}
+
 func output(level Level, e Entry, args ...interface{}) {
-	if config.level <= level {
+	if levelOf(e.tag) <= level {
 		if len(args) == 1 {
 			if fn, ok := args[0].(func() []interface{}); ok {
 				args = fn()
@@ -158,7 +212,7 @@ func output(level Level, e Entry, args ...interface{}) {
 }
 
 func outputf(level Level, e Entry, format string, args ...interface{}) {
-	if config.level <= level {
+	if levelOf(e.tag) <= level {
 		if len(args) == 1 {
 			if fn, ok := args[0].(func() []interface{}); ok {
 				args = fn()
@@ -174,6 +228,8 @@ func Reset() {
 	defer config.mutex.Unlock()
 	config.level = InfoLevel
 	config.output = Console
+	loggers.Store(map[string]Level{})
+	ctxPrefix.Store((func(ctx context.Context) string)(nil))
 }
 
 // SetLevel 设置日志输出的级别。
@@ -183,6 +239,51 @@ func SetLevel(level Level) {
 	config.level = level
 }
 
+// GetLevel 返回日志输出的级别。
+func GetLevel() Level {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+	return config.level
+}
+
+// SetLoggerLevel 设置名为 name 的日志的输出级别，name 是通过 Tag 方法设置的
+// 日志名称，同时对 name 的所有下级日志生效。
+func SetLoggerLevel(name string, level Level) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+	m := copyLoggers()
+	m[name] = level
+	loggers.Store(m)
+}
+
+// ResetLoggerLevel 删除名为 name 的日志的输出级别，恢复使用上一层级的级别。
+func ResetLoggerLevel(name string) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+	m := copyLoggers()
+	delete(m, name)
+	loggers.Store(m)
+}
+
+// Loggers 返回所有设置过输出级别的日志名称及其级别。
+func Loggers() map[string]Level {
+	return copyLoggers()
+}
+
+// LoggerLevel 返回名为 name 的日志实际生效的输出级别。
+func LoggerLevel(name string) Level {
+	return levelOf(name)
+}
+
+func copyLoggers() map[string]Level {
+	m, _ := loggers.Load().(map[string]Level)
+	r := make(map[string]Level, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
+
 // SetOutput 设置日志的输出格式。
 func SetOutput(output Output) {
 	config.mutex.Lock()
@@ -23,8 +23,10 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-stl/assert"
 )
 
 func TestDefault(t *testing.T) {
@@ -171,3 +173,130 @@ func TestEntry(t *testing.T) {
 	logger.Ctx(ctx).Fatal("level:", "fatal")
 	logger.Ctx(ctx).Fatalf("level:%s", "fatal")
 }
+
+func TestLoggerLevel(t *testing.T) {
+
+	var tags []string
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {
+		tags = append(tags, e.GetTag())
+	})
+	defer log.Reset()
+
+	log.SetLoggerLevel("mq", log.DebugLevel)
+	log.SetLoggerLevel("mq.kafka", log.ErrorLevel)
+
+	log.Debug("root")
+	log.Tag("web").Debug("web")
+	log.Tag("mq").Debug("mq")
+	log.Tag("mq.rabbit").Debug("mq.rabbit")
+	log.Tag("mq.kafka").Warn("mq.kafka")
+	log.Tag("mq.kafka.consumer").Error("mq.kafka.consumer")
+	assert.Equal(t, tags, []string{"mq", "mq.rabbit", "mq.kafka.consumer"})
+
+	assert.Equal(t, log.LoggerLevel("mq.rabbit"), log.DebugLevel)
+	assert.Equal(t, log.LoggerLevel("web"), log.InfoLevel)
+	assert.Equal(t, log.Loggers(), map[string]log.Level{
+		"mq":       log.DebugLevel,
+		"mq.kafka": log.ErrorLevel,
+	})
+
+	log.ResetLoggerLevel("mq.kafka")
+	assert.Equal(t, log.LoggerLevel("mq.kafka"), log.DebugLevel)
+
+	level, err := log.ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.Equal(t, level, log.WarnLevel)
+
+	_, err = log.ParseLevel("verbose")
+	assert.Error(t, err, "unknown log level \"verbose\"")
+}
+
+type ctxKey struct{}
+
+func TestSetCtxPrefix(t *testing.T) {
+
+	var msgs []string
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {
+		msgs = append(msgs, e.GetMsg())
+	})
+	defer log.Reset()
+
+	log.SetCtxPrefix(func(ctx context.Context) string {
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return "[" + id + "] "
+		}
+		return ""
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "abc")
+	log.Ctx(ctx).Info("hello")
+	log.Ctx(context.Background()).Infof("a=%d", 1)
+	log.Info("world")
+	assert.Equal(t, msgs, []string{"[abc] hello", "a=1", "world"})
+}
+
+func TestCheck(t *testing.T) {
+	defer log.Reset()
+
+	var msgs []string
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {
+		_, file, _, _ := runtime.Caller(skip + 1)
+		assert.True(t, strings.HasSuffix(file, "log_test.go"))
+		msgs = append(msgs, level.String()+" "+e.GetTag()+" "+e.GetMsg())
+	})
+
+	log.SetLevel(log.InfoLevel)
+	log.SetLoggerLevel("sql", log.DebugLevel)
+
+	assert.False(t, log.Check(log.DebugLevel).Enabled())
+	log.Check(log.DebugLevel).Str("k", "v").Msg("disabled")
+
+	log.Check(log.InfoLevel).
+		Str("user", "jim").
+		Str("note", "a b").
+		Int("age", 3).
+		Bool("ok", true).
+		Float64("rate", 0.5).
+		Dur("cost", time.Second).
+		Err(errors.New("boom")).
+		Err(nil).
+		Msg("login")
+
+	log.Tag("sql.conn").Check(log.DebugLevel).Uint64("id", 7).Msgf("open %d", 1)
+	log.Check(log.WarnLevel).Any("v", []int{1}).Msg("")
+
+	assert.Equal(t, msgs, []string{
+		`info  login user=jim note="a b" age=3 ok=true rate=0.5 cost=1s error=boom`,
+		`debug sql.conn open 1 id=7`,
+		`warn  v=[1]`,
+	})
+}
+
+func BenchmarkCheck(b *testing.B) {
+	defer log.Reset()
+	log.SetLevel(log.InfoLevel)
+	log.SetOutput(func(skip int, level log.Level, e *log.Entry) {})
+
+	names := []string{"jim", "tom"}
+
+	b.Run("disabled-printf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			log.Debugf("login user=%s age=%d", names[i&1], i)
+		}
+	})
+
+	b.Run("disabled-check", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			log.Check(log.DebugLevel).Str("user", names[i&1]).Int("age", i).Msg("login")
+		}
+	})
+
+	b.Run("enabled-check", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			log.Check(log.InfoLevel).Str("user", names[i&1]).Int("age", i).Msg("login")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mail_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-spring/spring-core/mail"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+
+	msg := &mail.Message{
+		From:    "a@example.com",
+		To:      []string{"b@example.com"},
+		Bcc:     []string{"c@example.com"},
+		Subject: "你好",
+		Text:    "hello",
+		HTML:    "<b>hello</b>",
+	}
+	msg.Attach("a.txt", []byte("attachment"))
+	assert.Equal(t, msg.Recipients(), []string{"b@example.com", "c@example.com"})
+
+	b, err := msg.Bytes()
+	assert.Nil(t, err)
+
+	m, err := netmail.ReadMessage(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, m.Header.Get("Bcc"), "")
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	assert.Nil(t, err)
+	assert.Equal(t, subject, "你好")
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, mediaType, "multipart/mixed")
+
+	r := multipart.NewReader(m.Body, params["boundary"])
+
+	p, err := r.NextPart()
+	assert.Nil(t, err)
+	mediaType, _, err = mime.ParseMediaType(p.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, mediaType, "multipart/alternative")
+
+	p, err = r.NextPart()
+	assert.Nil(t, err)
+	assert.Equal(t, p.FileName(), "a.txt")
+	data, err := io.ReadAll(p)
+	assert.Nil(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, string(decoded), "attachment")
+
+	_, err = (&mail.Message{From: "a@example.com"}).Bytes()
+	assert.Error(t, err, "mail: no recipients")
+}
+
+type translator map[string]string
+
+func (t translator) Translate(locale string, key string, args ...interface{}) string {
+	return t[locale+"."+key]
+}
+
+func TestTemplates_Render(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"templates/welcome.html": {Data: []byte(`{{t "welcome"}}, {{.Name}}`)},
+	}
+
+	ts, err := mail.NewTemplates(fsys, "templates/*.html")
+	assert.Nil(t, err)
+
+	s, err := ts.Render("welcome.html", map[string]string{"Name": "<jim>"}, "zh", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, s, "welcome, &lt;jim&gt;")
+
+	tr := translator{"zh.welcome": "欢迎", "en.welcome": "Welcome"}
+	s, err = ts.Render("welcome.html", map[string]string{"Name": "jim"}, "zh", tr)
+	assert.Nil(t, err)
+	assert.Equal(t, s, "欢迎, jim")
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mail 提供了基于 SMTP 协议的邮件发送功能，支持 TLS、连接池、模板渲染、
+// 附件以及异步发送。
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Attachment 邮件附件。
+type Attachment struct {
+	Name        string // 文件名
+	ContentType string // 内容类型，为空时根据文件名推断
+	Data        []byte // 文件内容
+}
+
+// Message 邮件。
+type Message struct {
+	From        string       // 发件人，为空时使用 mail.smtp.from 属性的值
+	To          []string     // 收件人
+	Cc          []string     // 抄送
+	Bcc         []string     // 密送，不会出现在邮件头中
+	Subject     string       // 主题
+	Text        string       // 纯文本正文
+	HTML        string       // HTML 正文
+	Locale      string       // 模板渲染时使用的语言
+	Attachments []Attachment // 附件
+}
+
+// Attach 添加附件。
+func (m *Message) Attach(name string, data []byte) *Message {
+	m.Attachments = append(m.Attachments, Attachment{Name: name, Data: data})
+	return m
+}
+
+// Recipients 返回所有的收件人，包括抄送和密送。
+func (m *Message) Recipients() []string {
+	var ret []string
+	ret = append(ret, m.To...)
+	ret = append(ret, m.Cc...)
+	ret = append(ret, m.Bcc...)
+	return ret
+}
+
+// Bytes 返回 MIME 格式的邮件内容。
+func (m *Message) Bytes() ([]byte, error) {
+
+	if m.From == "" {
+		return nil, errors.New("mail: from can't be empty")
+	}
+	if len(m.Recipients()) == 0 {
+		return nil, errors.New("mail: no recipients")
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", m.From)
+	writeHeader(&buf, "To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", strings.Join(m.Cc, ", "))
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	header, body, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		for _, k := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			if v := header.Get(k); v != "" {
+				writeHeader(&buf, k, v)
+			}
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	w := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", "multipart/mixed; boundary="+w.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(body); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Attachments {
+		if err = writeAttachment(w, a); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// body 返回邮件正文及其 MIME 头，同时存在纯文本和 HTML 正文时使用
+// multipart/alternative 格式。
+func (m *Message) body() (textproto.MIMEHeader, []byte, error) {
+
+	var buf bytes.Buffer
+
+	if m.Text != "" && m.HTML != "" {
+		w := multipart.NewWriter(&buf)
+		for _, p := range []struct{ contentType, s string }{
+			{"text/plain; charset=utf-8", m.Text},
+			{"text/html; charset=utf-8", m.HTML},
+		} {
+			part, err := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {p.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			if err = writeQuotedPrintable(part, p.s); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, nil, err
+		}
+		header := textproto.MIMEHeader{
+			"Content-Type": {"multipart/alternative; boundary=" + w.Boundary()},
+		}
+		return header, buf.Bytes(), nil
+	}
+
+	contentType, s := "text/plain; charset=utf-8", m.Text
+	if m.HTML != "" {
+		contentType, s = "text/html; charset=utf-8", m.HTML
+	}
+	if err := writeQuotedPrintable(&buf, s); err != nil {
+		return nil, nil, err
+	}
+	header := textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+	return header, buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func writeAttachment(w *multipart.Writer, a Attachment) error {
+
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	name := mime.QEncoding.Encode("utf-8", a.Name)
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("%s; name=%q", contentType, name)},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 按照 RFC 2045 的要求每 76 个字符换行。
+	s := base64.StdEncoding.EncodeToString(a.Data)
+	for len(s) > 76 {
+		if _, err = part.Write([]byte(s[:76] + "\r\n")); err != nil {
+			return err
+		}
+		s = s[76:]
+	}
+	_, err = part.Write([]byte(s + "\r\n"))
+	return err
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// ErrQueueClosed 发送队列已经关闭。
+var ErrQueueClosed = errors.New("mail: queue closed")
+
+// ErrQueueFull 发送队列已满。
+var ErrQueueFull = errors.New("mail: queue full")
+
+// Config SMTP 配置，通常绑定到 mail.smtp 前缀的属性上。
+type Config struct {
+	Host      string        `value:"${host}"`            // 服务器地址
+	Port      int           `value:"${port:=587}"`       // 服务器端口
+	Username  string        `value:"${username:=}"`      // 用户名，为空时不进行认证
+	Password  string        `value:"${password:=}"`      // 密码
+	From      string        `value:"${from:=}"`          // 默认发件人
+	TLS       string        `value:"${tls:=starttls}"`   // 加密方式，none、starttls 或 tls
+	Timeout   time.Duration `value:"${timeout:=10s}"`    // 连接超时
+	PoolSize  int           `value:"${pool-size:=2}"`    // 空闲连接的最大数量
+	Workers   int           `value:"${workers:=1}"`      // 异步发送的协程数量
+	QueueSize int           `value:"${queue-size:=100}"` // 异步发送队列的长度
+}
+
+// Sender 邮件发送器，维护一个 SMTP 连接池，异步发送的邮件在 bean 销毁前全部
+// 发送完成。
+type Sender struct {
+	Templates  *Templates `autowire:"?"`
+	Translator Translator `autowire:"?"`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+	pool   chan *smtp.Client
+	queue  chan *Message
+	mutex  sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewSender Sender 的构造函数。
+func NewSender(config Config) *Sender {
+	if config.PoolSize <= 0 {
+		config.PoolSize = 1
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	return &Sender{
+		config: &config,
+		pool:   make(chan *smtp.Client, config.PoolSize),
+		queue:  make(chan *Message, config.QueueSize),
+	}
+}
+
+// OnInit 启动异步发送协程。
+func (s *Sender) OnInit() {
+	for i := 0; i < s.config.Workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for msg := range s.queue {
+				if err := s.Send(context.Background(), msg); err != nil {
+					log.Errorf("send mail %q to %v error: %v", msg.Subject, msg.To, err)
+				}
+			}
+		}()
+	}
+}
+
+// OnDestroy 等待异步发送队列中的邮件全部发送完成，然后关闭所有连接。
+func (s *Sender) OnDestroy() {
+
+	s.mutex.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+
+	for {
+		select {
+		case c := <-s.pool:
+			_ = c.Quit()
+			continue
+		default:
+		}
+		break
+	}
+}
+
+// SendAsync 将邮件放入异步发送队列，发送失败时只打印日志。
+func (s *Sender) SendAsync(msg *Message) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// SendTemplate 使用名为 name 的模板渲染 HTML 正文后发送邮件。
+func (s *Sender) SendTemplate(ctx context.Context, msg *Message, name string, data interface{}) error {
+	if s.Templates == nil {
+		return errors.New("mail: no templates")
+	}
+	html, err := s.Templates.Render(name, data, msg.Locale, s.Translator)
+	if err != nil {
+		return err
+	}
+	msg.HTML = html
+	return s.Send(ctx, msg)
+}
+
+// Send 同步发送邮件。
+func (s *Sender) Send(ctx context.Context, msg *Message) (err error) {
+
+	if msg.From == "" {
+		msg.From = s.config.From
+	}
+
+	b, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { s.put(c, err) }()
+
+	if err = c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, addr := range msg.Recipients() {
+		if err = c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(b); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// get 从连接池中获取一个可用的连接，连接池为空时创建新的连接。
+func (s *Sender) get(ctx context.Context) (*smtp.Client, error) {
+	for {
+		select {
+		case c := <-s.pool:
+			if c.Noop() == nil {
+				return c, nil
+			}
+			_ = c.Close()
+			continue
+		default:
+		}
+		return s.dial(ctx)
+	}
+}
+
+// put 将连接放回连接池，出错的连接或者连接池已满时关闭该连接。
+func (s *Sender) put(c *smtp.Client, err error) {
+	if err == nil && c.Reset() == nil {
+		select {
+		case s.pool <- c:
+			return
+		default:
+		}
+	}
+	_ = c.Close()
+}
+
+func (s *Sender) dial(ctx context.Context) (*smtp.Client, error) {
+
+	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch s.config.TLS {
+	case "tls":
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	case "starttls", "none":
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("mail: unsupported tls mode %q", s.config.TLS)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if s.config.TLS == "starttls" {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err = c.Auth(auth); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
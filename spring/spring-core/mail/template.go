@@ -0,0 +1,69 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mail
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+)
+
+// Translator 国际化消息的翻译接口，模板中可以通过 t 函数获取翻译后的消息。
+type Translator interface {
+	Translate(locale string, key string, args ...interface{}) string
+}
+
+// Templates 邮件模板集合，模板名称即模板文件名。
+type Templates struct {
+	t *template.Template
+}
+
+// NewTemplates 从 fsys 中加载符合 patterns 的所有 html/template 模板。
+func NewTemplates(fsys fs.FS, patterns ...string) (*Templates, error) {
+	t := template.New("").Funcs(template.FuncMap{
+		"t": func(key string, args ...interface{}) string { return key },
+	})
+	t, err := t.ParseFS(fsys, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{t: t}, nil
+}
+
+// Render 渲染名为 name 的模板，translator 不为空时模板中的 t 函数返回 locale
+// 对应语言的翻译结果，否则原样返回消息的 key 。
+func (ts *Templates) Render(name string, data interface{}, locale string, translator Translator) (string, error) {
+
+	t, err := ts.t.Clone()
+	if err != nil {
+		return "", err
+	}
+
+	if translator != nil {
+		t = t.Funcs(template.FuncMap{
+			"t": func(key string, args ...interface{}) string {
+				return translator.Translate(locale, key, args...)
+			},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err = t.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
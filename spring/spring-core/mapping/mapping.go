@@ -0,0 +1,352 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mapping 提供对象之间的映射工具，用于简化请求参数、领域对象和数据库实体
+// 之间的相互转换。默认按照字段名称(不区分大小写)进行映射，也可以通过 mapping
+// 标签指定源对象的字段路径，例如 mapping:"Address.City"，mapping:"-" 表示忽略
+// 该字段。嵌套的结构体、指针、切片和 map 会被递归映射，类型不兼容时可以注册自定
+// 义的类型转换器。
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Converter 自定义的类型转换器。
+type Converter interface {
+	Types() (src, dst reflect.Type)
+	Convert(src reflect.Value) (reflect.Value, error)
+}
+
+type funcConverter[S, D any] func(S) (D, error)
+
+func (f funcConverter[S, D]) Types() (src, dst reflect.Type) {
+	return reflect.TypeOf((*S)(nil)).Elem(), reflect.TypeOf((*D)(nil)).Elem()
+}
+
+func (f funcConverter[S, D]) Convert(src reflect.Value) (reflect.Value, error) {
+	d, err := f(src.Interface().(S))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(&d).Elem(), nil
+}
+
+// Func 使用函数创建从 S 类型到 D 类型的转换器。
+func Func[S, D any](fn func(S) (D, error)) Converter {
+	return funcConverter[S, D](fn)
+}
+
+type typePair struct{ src, dst reflect.Type }
+
+// field 目标结构体字段的映射规则。
+type field struct {
+	dst  []int    // 目标字段的索引
+	path []string // 源对象的字段路径
+}
+
+// Mapper 对象映射器，通过 Converters 字段可以注入类型转换器 bean 。
+type Mapper struct {
+	Converters []Converter `autowire:""`
+
+	mutex      sync.RWMutex
+	converters map[typePair]Converter
+	fields     sync.Map // reflect.Type -> []field
+}
+
+// New 创建对象映射器。
+func New() *Mapper {
+	return &Mapper{converters: make(map[typePair]Converter)}
+}
+
+// OnInit 注册容器注入的类型转换器。
+func (m *Mapper) OnInit() {
+	for _, c := range m.Converters {
+		m.Register(c)
+	}
+}
+
+// Register 注册类型转换器，相同类型的转换器后注册的生效。
+func (m *Mapper) Register(c Converter) {
+	src, dst := c.Types()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.converters[typePair{src, dst}] = c
+}
+
+func (m *Mapper) converter(src, dst reflect.Type) Converter {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.converters[typePair{src, dst}]
+}
+
+// Map 将 src 映射到 dst 上，dst 必须是一个非空指针。
+func (m *Mapper) Map(dst, src interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("mapping: dst should be a non-nil pointer")
+	}
+	if src == nil {
+		return nil
+	}
+	return m.assign(v.Elem(), reflect.ValueOf(src), v.Elem().Type().String())
+}
+
+var defaultMapper = New()
+
+// Default 返回默认的对象映射器。
+func Default() *Mapper {
+	return defaultMapper
+}
+
+// Register 在默认的对象映射器上注册类型转换器。
+func Register(c Converter) {
+	defaultMapper.Register(c)
+}
+
+// Copy 使用默认的对象映射器将 src 映射为 T 类型的对象。
+func Copy[T any](src interface{}) (T, error) {
+	return CopyWith[T](defaultMapper, src)
+}
+
+// CopyWith 使用指定的对象映射器将 src 映射为 T 类型的对象。
+func CopyWith[T any](m *Mapper, src interface{}) (T, error) {
+	var t T
+	if err := m.Map(&t, src); err != nil {
+		var zero T
+		return zero, err
+	}
+	return t, nil
+}
+
+// CopySlice 使用默认的对象映射器将 src 中的每个元素映射为 T 类型的对象。
+func CopySlice[T, S any](src []S) ([]T, error) {
+	return CopyWith[[]T](defaultMapper, src)
+}
+
+// assign 将 src 的值赋给 dst ，path 用于错误信息。
+func (m *Mapper) assign(dst, src reflect.Value, path string) error {
+
+	if !src.IsValid() {
+		return nil
+	}
+
+	if c := m.converter(src.Type(), dst.Type()); c != nil {
+		v, err := c.Convert(src)
+		if err != nil {
+			return fmt.Errorf("mapping: %s: %w", path, err)
+		}
+		dst.Set(v)
+		return nil
+	}
+
+	// 解引用源对象的指针和接口
+	for src.Kind() == reflect.Ptr || src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			return nil
+		}
+		if c := m.converter(src.Type(), dst.Type()); c != nil {
+			return m.assign(dst, src, path)
+		}
+		src = src.Elem()
+	}
+
+	if src.Type().AssignableTo(dst.Type()) && !deep(src.Kind()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		v := reflect.New(dst.Type().Elem())
+		if err := m.assign(v.Elem(), src, path); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	case reflect.Interface:
+		if src.Type().AssignableTo(dst.Type()) {
+			dst.Set(src)
+			return nil
+		}
+	case reflect.Struct:
+		if src.Kind() == reflect.Struct {
+			return m.mapStruct(dst, src, path)
+		}
+	case reflect.Slice:
+		if src.Kind() == reflect.Slice || src.Kind() == reflect.Array {
+			if src.Kind() == reflect.Slice && src.IsNil() {
+				return nil
+			}
+			v := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				if err := m.assign(v.Index(i), src.Index(i), p); err != nil {
+					return err
+				}
+			}
+			dst.Set(v)
+			return nil
+		}
+	case reflect.Array:
+		if src.Kind() == reflect.Slice || src.Kind() == reflect.Array {
+			for i := 0; i < src.Len() && i < dst.Len(); i++ {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				if err := m.assign(dst.Index(i), src.Index(i), p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if src.Kind() == reflect.Map {
+			if src.IsNil() {
+				return nil
+			}
+			t := dst.Type()
+			v := reflect.MakeMapWithSize(t, src.Len())
+			iter := src.MapRange()
+			for iter.Next() {
+				p := fmt.Sprintf("%s[%v]", path, iter.Key().Interface())
+				key := reflect.New(t.Key()).Elem()
+				if err := m.assign(key, iter.Key(), p); err != nil {
+					return err
+				}
+				val := reflect.New(t.Elem()).Elem()
+				if err := m.assign(val, iter.Value(), p); err != nil {
+					return err
+				}
+				v.SetMapIndex(key, val)
+			}
+			dst.Set(v)
+			return nil
+		}
+	default:
+		if convertible(src.Type(), dst.Type()) {
+			dst.Set(src.Convert(dst.Type()))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("mapping: %s: can't convert %s to %s", path, src.Type(), dst.Type())
+}
+
+// deep 切片和 map 需要深拷贝，避免映射前后的对象共享底层数据。
+func deep(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Map
+}
+
+// convertible 只允许同类基础类型之间的转换，例如 int32 到 int64 ，不允许
+// 数字到字符串这种语义不明确的转换。
+func convertible(src, dst reflect.Type) bool {
+	if !src.ConvertibleTo(dst) {
+		return false
+	}
+	return class(src.Kind()) != 0 && class(src.Kind()) == class(dst.Kind())
+}
+
+func class(k reflect.Kind) int {
+	switch k {
+	case reflect.Bool:
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 2
+	case reflect.String:
+		return 3
+	}
+	return 0
+}
+
+func (m *Mapper) mapStruct(dst, src reflect.Value, path string) error {
+	for _, f := range m.structFields(dst.Type()) {
+		v, ok := lookup(src, f.path)
+		if !ok {
+			continue
+		}
+		fv := dst.FieldByIndex(f.dst)
+		if err := m.assign(fv, v, path+"."+strings.Join(f.path, ".")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structFields 返回目标结构体的字段映射规则，匿名嵌入的结构体字段会被展开。
+func (m *Mapper) structFields(t reflect.Type) []field {
+	if v, ok := m.fields.Load(t); ok {
+		return v.([]field)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag, ok := f.Tag.Lookup("mapping")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && !ok && f.Type.Kind() == reflect.Struct {
+			for _, sub := range m.structFields(f.Type) {
+				sub.dst = append([]int{i}, sub.dst...)
+				fields = append(fields, sub)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		p := []string{f.Name}
+		if tag != "" {
+			p = strings.Split(tag, ".")
+		}
+		fields = append(fields, field{dst: []int{i}, path: p})
+	}
+	m.fields.Store(t, fields)
+	return fields
+}
+
+// lookup 按照字段路径查找源对象的字段值，字段名不区分大小写。
+func lookup(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		f, ok := v.Type().FieldByNameFunc(func(s string) bool {
+			return strings.EqualFold(s, name)
+		})
+		if !ok || f.PkgPath != "" {
+			return reflect.Value{}, false
+		}
+		var err error
+		if v, err = v.FieldByIndexErr(f.Index); err != nil {
+			return reflect.Value{}, false // 嵌入的结构体指针为空
+		}
+	}
+	return v, true
+}
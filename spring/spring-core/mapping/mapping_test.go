@@ -0,0 +1,158 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapping_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/mapping"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type Base struct {
+	ID      int64
+	Created time.Time
+}
+
+type addressEntity struct {
+	City   string
+	Street string
+}
+
+type userEntity struct {
+	Base
+	Name     string
+	Age      int32
+	Status   int
+	Password string
+	Address  *addressEntity
+	Tags     []string
+	Orders   []orderEntity
+	Extra    map[string]int
+}
+
+type orderEntity struct {
+	No    string
+	Price int64
+}
+
+type orderDTO struct {
+	No    string
+	Price string
+}
+
+type userDTO struct {
+	ID       int64
+	Created  time.Time
+	Name     string
+	Age      int64
+	Status   string
+	Password string `mapping:"-"`
+	City     string `mapping:"Address.City"`
+	Address  addressDTO
+	Tags     []string
+	Orders   []orderDTO
+	Extra    map[string]int64
+}
+
+type addressDTO struct {
+	City string
+}
+
+func TestCopy(t *testing.T) {
+
+	m := mapping.New()
+	m.Register(mapping.Func(func(i int64) (string, error) {
+		return strconv.FormatInt(i, 10), nil
+	}))
+	m.Register(mapping.Func(func(i int) (string, error) {
+		return map[int]string{0: "inactive", 1: "active"}[i], nil
+	}))
+
+	now := time.Now()
+	src := &userEntity{
+		Base:     Base{ID: 1, Created: now},
+		Name:     "jim",
+		Age:      20,
+		Status:   1,
+		Password: "secret",
+		Address:  &addressEntity{City: "bj", Street: "x"},
+		Tags:     []string{"a", "b"},
+		Orders:   []orderEntity{{No: "o1", Price: 100}},
+		Extra:    map[string]int{"k": 1},
+	}
+
+	dto, err := mapping.CopyWith[userDTO](m, src)
+	assert.Nil(t, err)
+	assert.Equal(t, dto, userDTO{
+		ID:      1,
+		Created: now,
+		Name:    "jim",
+		Age:     20,
+		Status:  "active",
+		City:    "bj",
+		Address: addressDTO{City: "bj"},
+		Tags:    []string{"a", "b"},
+		Orders:  []orderDTO{{No: "o1", Price: "100"}},
+		Extra:   map[string]int64{"k": 1},
+	})
+
+	// 切片需要深拷贝
+	dto.Tags[0] = "c"
+	assert.Equal(t, src.Tags[0], "a")
+
+	t.Run("reverse", func(t *testing.T) {
+		e, err := mapping.CopyWith[*userEntity](m, dto)
+		assert.Error(t, err, "mapping: \\*mapping_test.userEntity.Status: can't convert string to int")
+		assert.True(t, e == nil)
+		m.Register(mapping.Func(func(s string) (int, error) {
+			return map[string]int{"inactive": 0, "active": 1}[s], nil
+		}))
+		e, err = mapping.CopyWith[*userEntity](m, dto)
+		assert.Error(t, err, "Orders\\[0\\].Price: can't convert string to int64")
+		m.Register(mapping.Func(func(s string) (int64, error) {
+			return strconv.ParseInt(s, 10, 64)
+		}))
+		e, err = mapping.CopyWith[*userEntity](m, dto)
+		assert.Nil(t, err)
+		assert.Equal(t, e.ID, int64(1))
+		assert.Equal(t, e.Status, 1)
+		assert.Equal(t, e.Password, "")
+		assert.Equal(t, e.Address, &addressEntity{City: "bj"})
+		assert.Equal(t, e.Orders, []orderEntity{{No: "o1", Price: 100}})
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var p *userEntity
+		dto, err := mapping.CopyWith[userDTO](m, p)
+		assert.Nil(t, err)
+		assert.Equal(t, dto, userDTO{})
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s, err := mapping.CopySlice[addressDTO]([]*addressEntity{{City: "bj"}, {City: "sh"}})
+		assert.Nil(t, err)
+		assert.Equal(t, s, []addressDTO{{City: "bj"}, {City: "sh"}})
+	})
+
+	t.Run("dst", func(t *testing.T) {
+		err := m.Map(addressDTO{}, src)
+		assert.Error(t, err, "dst should be a non-nil pointer")
+	})
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// Family 某一时刻一个指标及其所有子指标的快照。
+type Family struct {
+	Name    string
+	Help    string
+	Type    string    // TypeCounter、TypeGauge 或者 TypeHistogram
+	Labels  []string  // 标签名称
+	Buckets []float64 // 直方图的桶边界
+	Samples []Sample
+}
+
+// Sample 一个子指标的快照。
+type Sample struct {
+	Values []string // 标签值，顺序与 Family.Labels 一致
+	Value  float64  // 计数器和仪表盘的值
+	Count  uint64   // 直方图的观测值个数
+	Sum    float64  // 直方图的观测值总和
+	Counts []uint64 // 直方图落在每个桶中的观测值个数，不是累计值
+}
+
+// Gather 返回所有指标的快照，按照指标名称和标签值排序。
+func (r *Registry) Gather() []Family {
+
+	r.mutex.Lock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	families := make([]Family, 0, len(metrics))
+	for _, m := range metrics {
+		families = append(families, m.gather())
+	}
+	return families
+}
+
+func (m *metric) gather() Family {
+
+	f := Family{
+		Name:    m.name,
+		Help:    m.help,
+		Type:    m.typ,
+		Labels:  m.labels,
+		Buckets: m.buckets,
+	}
+
+	if m.fn != nil {
+		f.Samples = []Sample{{Value: m.fn()}}
+		return f
+	}
+
+	m.mutex.RLock()
+	keys := make([]string, 0, len(m.children))
+	for k := range m.children {
+		keys = append(keys, k)
+	}
+	m.mutex.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		m.mutex.RLock()
+		c, values := m.children[k], m.values[k]
+		m.mutex.RUnlock()
+		s := Sample{Values: values}
+		switch v := c.(type) {
+		case *Counter:
+			s.Value = v.Value()
+		case *Gauge:
+			s.Value = v.Value()
+		case *Histogram:
+			s.Counts = make([]uint64, len(v.counts))
+			for i := range v.counts {
+				s.Counts[i] = atomic.LoadUint64(&v.counts[i])
+			}
+			s.Count = v.Count()
+			s.Sum = v.Sum()
+		}
+		f.Samples = append(f.Samples, s)
+	}
+	return f
+}
+
+// Exporter 将指标推送到外部监控系统。
+type Exporter interface {
+	Export(ctx context.Context, families []Family) error
+}
+
+// Pusher 按照固定的间隔将注册中心的指标推送给 Exporter ，停止时再推送一次，
+// 避免丢失最后一个间隔内的数据。
+type Pusher struct {
+	name     string
+	registry *Registry
+	exporter Exporter
+	step     time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPusher Pusher 的构造函数，name 用于输出日志。
+func NewPusher(name string, r *Registry, e Exporter, step time.Duration) *Pusher {
+	if step <= 0 {
+		step = time.Minute
+	}
+	return &Pusher{name: name, registry: r, exporter: e, step: step}
+}
+
+// Push 立即推送一次指标。
+func (p *Pusher) Push(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.step)
+	defer cancel()
+	return p.exporter.Export(ctx, p.registry.Gather())
+}
+
+// OnInit 启动推送指标的后台协程。
+func (p *Pusher) OnInit() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.step)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := p.Push(ctx); err != nil {
+				log.Errorf("metrics export %s error: %v", p.name, err)
+			}
+		}
+	}()
+}
+
+// OnDestroy 停止后台协程并推送最后一次指标，Exporter 实现了 io.Closer 接口
+// 时将其关闭。
+func (p *Pusher) OnDestroy() {
+	if p.cancel != nil {
+		p.cancel()
+		p.wg.Wait()
+		if err := p.Push(context.Background()); err != nil {
+			log.Errorf("metrics export %s error: %v", p.name, err)
+		}
+	}
+	if c, ok := p.exporter.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
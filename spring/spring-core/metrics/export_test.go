@@ -0,0 +1,171 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func newTestRegistry() *metrics.Registry {
+	r := metrics.NewRegistry()
+	r.Counter("requests_total", "Total requests.", "method").With("GET").Add(3)
+	r.Gauge("temperature", "", "room").With("cellar").Set(-2)
+	r.Histogram("latency", "", []float64{0.1, 1}, "path").With("/a").Observe(0.05)
+	r.Histogram("latency", "", []float64{0.1, 1}, "path").With("/a").Observe(5)
+	return r
+}
+
+func TestRegistry_Gather(t *testing.T) {
+	families := newTestRegistry().Gather()
+	assert.Equal(t, len(families), 3)
+	assert.Equal(t, families[0].Name, "latency")
+	assert.Equal(t, families[0].Samples, []metrics.Sample{
+		{Values: []string{"/a"}, Count: 2, Sum: 5.05, Counts: []uint64{1, 0}},
+	})
+	assert.Equal(t, families[1].Type, metrics.TypeCounter)
+	assert.Equal(t, families[1].Samples, []metrics.Sample{{Values: []string{"GET"}, Value: 3}})
+}
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	return string(buf[:n])
+}
+
+func TestStatsD(t *testing.T) {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	r := newTestRegistry()
+	p, err := metrics.NewStatsD(r, metrics.StatsDConfig{
+		Address:       conn.LocalAddr().String(),
+		Flavor:        "datadog",
+		Prefix:        "app.",
+		Tags:          []string{"env:test"},
+		Step:          time.Minute,
+		MaxPacketSize: 1432,
+	})
+	assert.Nil(t, err)
+	defer p.OnDestroy()
+
+	assert.Nil(t, p.Push(context.Background()))
+	assert.Equal(t, readPacket(t, conn), strings.Join([]string{
+		"app.latency.count:2|c|#path:/a,env:test",
+		"app.latency.sum:5.05|c|#path:/a,env:test",
+		"app.requests_total:3|c|#method:GET,env:test",
+		"app.temperature:0|g|#room:cellar,env:test",
+		"app.temperature:-2|g|#room:cellar,env:test",
+	}, "\n"))
+
+	// 计数器只推送增量
+	r.Counter("requests_total", "Total requests.", "method").With("GET").Inc()
+	assert.Nil(t, p.Push(context.Background()))
+	assert.Equal(t, readPacket(t, conn), strings.Join([]string{
+		"app.requests_total:1|c|#method:GET,env:test",
+		"app.temperature:0|g|#room:cellar,env:test",
+		"app.temperature:-2|g|#room:cellar,env:test",
+	}, "\n"))
+
+	_, err = metrics.NewStatsD(r, metrics.StatsDConfig{Flavor: "graphite"})
+	assert.Error(t, err, "unknown statsd flavor \"graphite\"")
+}
+
+func TestStatsD_Plain(t *testing.T) {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	r := metrics.NewRegistry()
+	r.Counter("requests_total", "", "method", "path").With("GET", "/v1.0").Inc()
+	p, err := metrics.NewStatsD(r, metrics.StatsDConfig{
+		Address: conn.LocalAddr().String(),
+		Flavor:  "statsd",
+	})
+	assert.Nil(t, err)
+	defer p.OnDestroy()
+
+	assert.Nil(t, p.Push(context.Background()))
+	assert.Equal(t, readPacket(t, conn), "requests_total.GET./v1_0:1|c")
+}
+
+func TestOTLP(t *testing.T) {
+
+	var (
+		header http.Header
+		body   map[string]interface{}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer ts.Close()
+
+	p := metrics.NewOTLP(newTestRegistry(), metrics.OTLPConfig{
+		Endpoint:    ts.URL,
+		Headers:     map[string]string{"Authorization": "Bearer x"},
+		ServiceName: "demo",
+		Step:        time.Minute,
+	})
+	assert.Nil(t, p.Push(context.Background()))
+	assert.Equal(t, header.Get("Authorization"), "Bearer x")
+	assert.Equal(t, header.Get("Content-Type"), "application/json")
+
+	rm := body["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	sm := rm["scopeMetrics"].([]interface{})[0].(map[string]interface{})
+	ms := sm["metrics"].([]interface{})
+	assert.Equal(t, len(ms), 3)
+
+	h := ms[0].(map[string]interface{})["histogram"].(map[string]interface{})
+	dp := h["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, dp["count"], "2")
+	assert.Equal(t, dp["bucketCounts"], []interface{}{"1", "0", "1"})
+
+	sum := ms[1].(map[string]interface{})["sum"].(map[string]interface{})
+	assert.Equal(t, sum["isMonotonic"], true)
+	assert.Equal(t, sum["aggregationTemporality"], float64(2))
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("bad"))
+	})
+	err := p.Push(context.Background())
+	assert.Error(t, err, "otlp export returns 400 Bad Request: bad")
+}
+
+func TestStatsDConfig_Bind(t *testing.T) {
+	p := conf.New()
+	p.Set("metrics.export.statsd.tags[0]", "env:prod")
+	var c metrics.StatsDConfig
+	assert.Nil(t, p.Bind(&c, conf.Key("metrics.export.statsd")))
+	assert.Equal(t, c.Tags, []string{"env:prod"})
+}
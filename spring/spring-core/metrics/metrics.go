@@ -0,0 +1,287 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics 提供了计数器、仪表盘、直方图和计时器四种监控指标，指标注册
+// 到 Registry 中并以 Prometheus 文本格式对外暴露，也可以通过 Exporter 定时推送
+// 到 StatsD、Datadog 或者 OTLP 等监控系统。
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default 默认的指标注册中心，框架内置的指标都注册在这里，同时也是容器中的
+// *Registry 类型的 bean 。
+var Default = NewRegistry()
+
+// DefaultBuckets 计时器默认的桶边界，单位为秒。
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// 指标的类型。
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// value 可以原子操作的 float64 。
+type value struct {
+	bits uint64
+}
+
+func (v *value) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+func (v *value) Store(f float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(f))
+}
+
+func (v *value) Add(f float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		n := math.Float64bits(math.Float64frombits(old) + f)
+		if atomic.CompareAndSwapUint64(&v.bits, old, n) {
+			return
+		}
+	}
+}
+
+// Counter 只增不减的计数器。
+type Counter struct{ v value }
+
+// Inc 计数加 1 。
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add 计数加 f，f 不能为负数。
+func (c *Counter) Add(f float64) {
+	if f < 0 {
+		panic(errors.New("counter can't decrease"))
+	}
+	c.v.Add(f)
+}
+
+// Value 返回当前的计数。
+func (c *Counter) Value() float64 { return c.v.Load() }
+
+// Gauge 可以任意设置的仪表盘。
+type Gauge struct{ v value }
+
+// Set 设置当前值。
+func (g *Gauge) Set(f float64) { g.v.Store(f) }
+
+// Add 当前值加 f 。
+func (g *Gauge) Add(f float64) { g.v.Add(f) }
+
+// Inc 当前值加 1 。
+func (g *Gauge) Inc() { g.v.Add(1) }
+
+// Dec 当前值减 1 。
+func (g *Gauge) Dec() { g.v.Add(-1) }
+
+// Value 返回当前值。
+func (g *Gauge) Value() float64 { return g.v.Load() }
+
+// Histogram 统计观测值分布的直方图。
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     value
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe 记录一个观测值。
+func (h *Histogram) Observe(f float64) {
+	if i := sort.SearchFloat64s(h.buckets, f); i < len(h.buckets) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.Add(f)
+}
+
+// Count 返回观测值的个数。
+func (h *Histogram) Count() uint64 { return atomic.LoadUint64(&h.count) }
+
+// Sum 返回观测值的总和。
+func (h *Histogram) Sum() float64 { return h.sum.Load() }
+
+// Timer 以秒为单位统计耗时分布的计时器。
+type Timer struct{ h *Histogram }
+
+// Observe 记录一次耗时。
+func (t *Timer) Observe(d time.Duration) { t.h.Observe(d.Seconds()) }
+
+// Since 记录从 start 到现在的耗时。
+func (t *Timer) Since(start time.Time) { t.Observe(time.Since(start)) }
+
+// Time 执行 fn 并记录其耗时。
+func (t *Timer) Time(fn func()) {
+	start := time.Now()
+	defer t.Since(start)
+	fn()
+}
+
+// Count 返回记录的次数。
+func (t *Timer) Count() uint64 { return t.h.Count() }
+
+// metric 一个指标及其按照标签值区分的所有子指标。
+type metric struct {
+	name     string
+	help     string
+	typ      string
+	labels   []string
+	buckets  []float64
+	fn       func() float64
+	mutex    sync.RWMutex
+	children map[string]interface{}
+	values   map[string][]string
+}
+
+func (m *metric) child(values []string) interface{} {
+
+	if len(values) != len(m.labels) {
+		panic(fmt.Errorf("metric %s expects %d label values but got %d", m.name, len(m.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	m.mutex.RLock()
+	c, ok := m.children[key]
+	m.mutex.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if c, ok = m.children[key]; ok {
+		return c
+	}
+	switch m.typ {
+	case TypeCounter:
+		c = new(Counter)
+	case TypeGauge:
+		c = new(Gauge)
+	case TypeHistogram:
+		c = newHistogram(m.buckets)
+	}
+	m.children[key] = c
+	m.values[key] = append([]string(nil), values...)
+	return c
+}
+
+// CounterVec 按照标签值区分的一组计数器。
+type CounterVec struct{ m *metric }
+
+// With 返回标签值为 values 的计数器，values 的顺序与注册时的标签一致。
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.m.child(values).(*Counter)
+}
+
+// GaugeVec 按照标签值区分的一组仪表盘。
+type GaugeVec struct{ m *metric }
+
+// With 返回标签值为 values 的仪表盘。
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.m.child(values).(*Gauge)
+}
+
+// HistogramVec 按照标签值区分的一组直方图。
+type HistogramVec struct{ m *metric }
+
+// With 返回标签值为 values 的直方图。
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.m.child(values).(*Histogram)
+}
+
+// TimerVec 按照标签值区分的一组计时器。
+type TimerVec struct{ m *metric }
+
+// With 返回标签值为 values 的计时器。
+func (v *TimerVec) With(values ...string) *Timer {
+	return &Timer{h: v.m.child(values).(*Histogram)}
+}
+
+// Registry 指标注册中心。
+type Registry struct {
+	mutex   sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewRegistry Registry 的构造函数。
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// register 注册指标，同名指标已经存在时返回已有的指标，但是两者的类型和标签
+// 必须一致。
+func (r *Registry) register(m *metric) *metric {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if old, ok := r.metrics[m.name]; ok {
+		if old.typ != m.typ || strings.Join(old.labels, ",") != strings.Join(m.labels, ",") {
+			panic(fmt.Errorf("metric %s already registered as %s%v", m.name, old.typ, old.labels))
+		}
+		return old
+	}
+
+	m.children = make(map[string]interface{})
+	m.values = make(map[string][]string)
+	r.metrics[m.name] = m
+	return m
+}
+
+// Counter 注册计数器。
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	m := r.register(&metric{name: name, help: help, typ: TypeCounter, labels: labels})
+	return &CounterVec{m: m}
+}
+
+// Gauge 注册仪表盘。
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	m := r.register(&metric{name: name, help: help, typ: TypeGauge, labels: labels})
+	return &GaugeVec{m: m}
+}
+
+// GaugeFunc 注册在暴露时通过 fn 获取当前值的仪表盘。
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(&metric{name: name, help: help, typ: TypeGauge, fn: fn})
+}
+
+// Histogram 注册直方图，buckets 为升序排列的桶边界。
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	m := r.register(&metric{name: name, help: help, typ: TypeHistogram, labels: labels, buckets: buckets})
+	return &HistogramVec{m: m}
+}
+
+// Timer 注册以秒为单位、使用 DefaultBuckets 的计时器。
+func (r *Registry) Timer(name, help string, labels ...string) *TimerVec {
+	return &TimerVec{m: r.Histogram(name, help, DefaultBuckets, labels...).m}
+}
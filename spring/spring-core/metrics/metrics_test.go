@@ -0,0 +1,105 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestRegistry(t *testing.T) {
+
+	r := metrics.NewRegistry()
+
+	c := r.Counter("requests_total", "Total requests.", "method")
+	c.With("GET").Inc()
+	c.With("GET").Add(2)
+	c.With("POST").Inc()
+	assert.Equal(t, c.With("GET").Value(), float64(3))
+
+	// 重复注册时返回已有的指标
+	assert.Equal(t, r.Counter("requests_total", "Total requests.", "method").With("GET").Value(), float64(3))
+
+	g := r.Gauge("queue_size", "")
+	g.With().Set(5)
+	g.With().Dec()
+
+	r.GaugeFunc("up", "Whether the app is up.", func() float64 { return 1 })
+
+	h := r.Histogram("size_bytes", "", []float64{100, 10})
+	h.With().Observe(5)
+	h.With().Observe(50)
+	h.With().Observe(500)
+
+	tm := r.Timer("latency_seconds", "")
+	tm.With().Observe(20 * time.Millisecond)
+	assert.Equal(t, tm.With().Count(), uint64(1))
+
+	var buf bytes.Buffer
+	assert.Nil(t, r.WritePrometheus(&buf))
+
+	expect := `# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.005"} 0
+latency_seconds_bucket{le="0.01"} 0
+latency_seconds_bucket{le="0.025"} 1
+latency_seconds_bucket{le="0.05"} 1
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="0.25"} 1
+latency_seconds_bucket{le="0.5"} 1
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="2.5"} 1
+latency_seconds_bucket{le="5"} 1
+latency_seconds_bucket{le="10"} 1
+latency_seconds_bucket{le="+Inf"} 1
+latency_seconds_sum 0.02
+latency_seconds_count 1
+# TYPE queue_size gauge
+queue_size 4
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET"} 3
+requests_total{method="POST"} 1
+# TYPE size_bytes histogram
+size_bytes_bucket{le="10"} 1
+size_bytes_bucket{le="100"} 2
+size_bytes_bucket{le="+Inf"} 3
+size_bytes_sum 555
+size_bytes_count 3
+# HELP up Whether the app is up.
+# TYPE up gauge
+up 1
+`
+	assert.Equal(t, buf.String(), expect)
+
+	assert.Panic(t, func() { r.Gauge("requests_total", "") }, "metric requests_total already registered as counter")
+	assert.Panic(t, func() { c.With() }, "metric requests_total expects 1 label values but got 0")
+}
+
+func TestAcceptCounter(t *testing.T) {
+	r := metrics.NewRegistry()
+	onAccept := metrics.NewAcceptCounter(r)
+	onAccept("tcp://127.0.0.1:8080#0")
+	onAccept("tcp://127.0.0.1:8080#0")
+	onAccept("tcp://127.0.0.1:8080#1")
+	c := r.Counter("http_server_accepted_connections_total", "Total number of connections accepted by each listener.", "listener")
+	assert.Equal(t, c.With("tcp://127.0.0.1:8080#0").Value(), float64(2))
+	assert.Equal(t, c.With("tcp://127.0.0.1:8080#1").Value(), float64(1))
+}
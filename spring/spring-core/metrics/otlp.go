@@ -0,0 +1,223 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OTLPConfig OTLP 推送配置，通常绑定到 metrics.export.otlp 前缀的属性上。
+type OTLPConfig struct {
+	Endpoint    string            `value:"${endpoint:=http://localhost:4318/v1/metrics}"` // OTLP/HTTP 的接收地址
+	Headers     map[string]string `value:"${headers}"`                                    // 附加的请求头，例如认证信息
+	ServiceName string            `value:"${service-name:=go-spring}"`                    // 资源属性 service.name
+	Step        time.Duration     `value:"${step:=1m}"`                                   // 推送间隔
+}
+
+// otlpExporter 以 OTLP/HTTP JSON 协议推送指标，所有的指标都使用累计值。
+type otlpExporter struct {
+	config *OTLPConfig
+	client *http.Client
+	start  time.Time
+}
+
+// NewOTLP 创建以 OTLP/HTTP 协议推送注册中心 r 中的指标的 Pusher 。
+func NewOTLP(r *Registry, config OTLPConfig) *Pusher {
+	e := &otlpExporter{config: &config, client: &http.Client{}, start: time.Now()}
+	return NewPusher("otlp", r, e, config.Step)
+}
+
+// OTLP JSON 编码中 64 位整数使用字符串表示。
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Sum         *otlpSum       `json:"sum,omitempty"`
+		Gauge       *otlpGauge     `json:"gauge,omitempty"`
+		Histogram   *otlpHistogram `json:"histogram,omitempty"`
+	}
+	otlpSum struct {
+		DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+		AggregationTemporality int                   `json:"aggregationTemporality"`
+		IsMonotonic            bool                  `json:"isMonotonic"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+	}
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+	}
+	otlpNumberDataPoint struct {
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		AsDouble          float64        `json:"asDouble"`
+	}
+	otlpHistogramDataPoint struct {
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		Count             string         `json:"count"`
+		Sum               float64        `json:"sum"`
+		BucketCounts      []string       `json:"bucketCounts"`
+		ExplicitBounds    []float64      `json:"explicitBounds"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// aggregationTemporalityCumulative OTLP 中表示累计值的枚举值。
+const aggregationTemporalityCumulative = 2
+
+func (e *otlpExporter) Export(ctx context.Context, families []Family) error {
+
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	start := strconv.FormatInt(e.start.UnixNano(), 10)
+
+	metrics := make([]otlpMetric, 0, len(families))
+	for _, f := range families {
+		m := otlpMetric{Name: f.Name, Description: f.Help}
+		switch f.Type {
+		case TypeCounter, TypeGauge:
+			points := make([]otlpNumberDataPoint, 0, len(f.Samples))
+			for _, s := range f.Samples {
+				p := otlpNumberDataPoint{
+					Attributes:   attributes(f.Labels, s.Values),
+					TimeUnixNano: now,
+					AsDouble:     s.Value,
+				}
+				if f.Type == TypeCounter {
+					p.StartTimeUnixNano = start
+				}
+				points = append(points, p)
+			}
+			if f.Type == TypeCounter {
+				m.Sum = &otlpSum{
+					DataPoints:             points,
+					AggregationTemporality: aggregationTemporalityCumulative,
+					IsMonotonic:            true,
+				}
+			} else {
+				m.Gauge = &otlpGauge{DataPoints: points}
+			}
+		case TypeHistogram:
+			points := make([]otlpHistogramDataPoint, 0, len(f.Samples))
+			for _, s := range f.Samples {
+				// OTLP 的桶数量比边界数量多一个，最后一个桶表示大于所有边界的观测值
+				counts := make([]string, 0, len(s.Counts)+1)
+				var n uint64
+				for _, c := range s.Counts {
+					counts = append(counts, strconv.FormatUint(c, 10))
+					n += c
+				}
+				counts = append(counts, strconv.FormatUint(s.Count-n, 10))
+				points = append(points, otlpHistogramDataPoint{
+					Attributes:        attributes(f.Labels, s.Values),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      now,
+					Count:             strconv.FormatUint(s.Count, 10),
+					Sum:               s.Sum,
+					BucketCounts:      counts,
+					ExplicitBounds:    f.Buckets,
+				})
+			}
+			m.Histogram = &otlpHistogram{
+				DataPoints:             points,
+				AggregationTemporality: aggregationTemporalityCumulative,
+			}
+		}
+		metrics = append(metrics, m)
+	}
+
+	body, err := json.Marshal(otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: e.config.ServiceName}}},
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: "github.com/go-spring/spring-core/metrics"},
+				Metrics: metrics,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export returns %s: %s", resp.Status, b)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func attributes(labels, values []string) []otlpKeyValue {
+	if len(labels) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, len(labels))
+	for i, l := range labels {
+		kvs[i] = otlpKeyValue{Key: l, Value: otlpValue{StringValue: values[i]}}
+	}
+	return kvs
+}
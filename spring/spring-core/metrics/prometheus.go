@@ -0,0 +1,128 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ContentType Prometheus 文本格式的内容类型。
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WritePrometheus 以 Prometheus 文本格式输出所有的指标。
+func (r *Registry) WritePrometheus(w io.Writer) error {
+
+	r.mutex.Lock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 返回输出 Prometheus 文本格式指标的 http.Handler 。
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WritePrometheus(w)
+	})
+}
+
+func (m *metric) write(w *bufio.Writer) {
+
+	if m.help != "" {
+		w.WriteString("# HELP " + m.name + " " + escape(m.help, false) + "\n")
+	}
+	w.WriteString("# TYPE " + m.name + " " + m.typ + "\n")
+
+	if m.fn != nil {
+		writeSample(w, m.name, nil, nil, m.fn())
+		return
+	}
+
+	m.mutex.RLock()
+	keys := make([]string, 0, len(m.children))
+	for k := range m.children {
+		keys = append(keys, k)
+	}
+	m.mutex.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		m.mutex.RLock()
+		c, values := m.children[k], m.values[k]
+		m.mutex.RUnlock()
+		switch v := c.(type) {
+		case *Counter:
+			writeSample(w, m.name, m.labels, values, v.Value())
+		case *Gauge:
+			writeSample(w, m.name, m.labels, values, v.Value())
+		case *Histogram:
+			labels := append(append([]string(nil), m.labels...), "le")
+			var cumulative uint64
+			for i, b := range v.buckets {
+				cumulative += atomic.LoadUint64(&v.counts[i])
+				le := strconv.FormatFloat(b, 'g', -1, 64)
+				writeSample(w, m.name+"_bucket", labels, append(values, le), float64(cumulative))
+			}
+			count := v.Count()
+			writeSample(w, m.name+"_bucket", labels, append(values, "+Inf"), float64(count))
+			writeSample(w, m.name+"_sum", m.labels, values, v.Sum())
+			writeSample(w, m.name+"_count", m.labels, values, float64(count))
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, f float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l + `="` + escape(values[i], true) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	w.WriteByte('\n')
+}
+
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
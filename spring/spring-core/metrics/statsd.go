@@ -0,0 +1,174 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDConfig StatsD 推送配置，通常绑定到 metrics.export.statsd 前缀的属性上。
+type StatsDConfig struct {
+	Address       string        `value:"${address:=127.0.0.1:8125}"` // UDP 地址
+	Flavor        string        `value:"${flavor:=statsd}"`          // statsd 或者 datadog
+	Prefix        string        `value:"${prefix:=}"`                // 指标名称的前缀
+	Tags          []string      `value:"${tags}"`                    // datadog 格式下附加到所有指标的标签，形如 env:prod
+	Step          time.Duration `value:"${step:=10s}"`               // 推送间隔
+	MaxPacketSize int           `value:"${max-packet-size:=1432}"`   // 单个 UDP 包的最大字节数
+}
+
+// statsdExporter 以 StatsD 协议推送指标。计数器推送两次推送之间的增量，直方图
+// 推送观测值个数和总和的增量，statsd 格式下标签值作为名称的后缀，datadog 格式
+// 下标签以 DogStatsD 的 tags 扩展推送。
+type statsdExporter struct {
+	config *StatsDConfig
+	conn   net.Conn
+
+	mutex sync.Mutex
+	last  map[string]float64 // 计数器上次推送时的值
+}
+
+// NewStatsD 创建以 StatsD 协议推送注册中心 r 中的指标的 Pusher 。
+func NewStatsD(r *Registry, config StatsDConfig) (*Pusher, error) {
+	if config.Flavor != "statsd" && config.Flavor != "datadog" {
+		return nil, fmt.Errorf("unknown statsd flavor %q", config.Flavor)
+	}
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = 1432
+	}
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	e := &statsdExporter{config: &config, conn: conn, last: make(map[string]float64)}
+	return NewPusher("statsd", r, e, config.Step), nil
+}
+
+func (e *statsdExporter) Export(ctx context.Context, families []Family) error {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var (
+		buf   bytes.Buffer
+		lines []string
+	)
+	for _, f := range families {
+		for _, s := range f.Samples {
+			switch f.Type {
+			case TypeCounter:
+				lines = e.appendDelta(lines, f, s, "", s.Value)
+			case TypeGauge:
+				if s.Value < 0 { // 带符号的值会被当作增量，需要先归零
+					lines = append(lines, e.line(f, s, "", 0, "g"))
+				}
+				lines = append(lines, e.line(f, s, "", s.Value, "g"))
+			case TypeHistogram:
+				lines = e.appendDelta(lines, f, s, ".count", float64(s.Count))
+				lines = e.appendDelta(lines, f, s, ".sum", s.Sum)
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > e.config.MaxPacketSize {
+			if _, err := e.conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		if _, err := e.conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendDelta 计算累计值 v 相对上次推送的增量，增量为 0 时不推送。
+func (e *statsdExporter) appendDelta(lines []string, f Family, s Sample, suffix string, v float64) []string {
+	key := f.Name + suffix + "\xff" + strings.Join(s.Values, "\xff")
+	delta := v - e.last[key]
+	if delta < 0 { // 指标被重置
+		delta = v
+	}
+	e.last[key] = v
+	if delta == 0 {
+		return lines
+	}
+	return append(lines, e.line(f, s, suffix, delta, "c"))
+}
+
+func (e *statsdExporter) line(f Family, s Sample, suffix string, v float64, typ string) string {
+	var sb strings.Builder
+	sb.WriteString(e.config.Prefix)
+	sb.WriteString(sanitize(f.Name))
+	if e.config.Flavor == "statsd" {
+		for _, value := range s.Values {
+			sb.WriteByte('.')
+			sb.WriteString(strings.ReplaceAll(sanitize(value), ".", "_"))
+		}
+	}
+	sb.WriteString(suffix)
+	sb.WriteByte(':')
+	sb.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	sb.WriteByte('|')
+	sb.WriteString(typ)
+	if e.config.Flavor == "datadog" && (len(f.Labels) > 0 || len(e.config.Tags) > 0) {
+		sb.WriteString("|#")
+		for i, l := range f.Labels {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(sanitize(l) + ":" + sanitize(s.Values[i]))
+		}
+		for i, tag := range e.config.Tags {
+			if i > 0 || len(f.Labels) > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(tag)
+		}
+	}
+	return sb.String()
+}
+
+// sanitize 将 StatsD 协议中有特殊含义的字符替换为下划线。
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Close 关闭 UDP 连接。
+func (e *statsdExporter) Close() error {
+	return e.conn.Close()
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-spring/spring-core/web"
+)
+
+// Endpoint 将 Prometheus 格式的指标挂载到 Web 服务器上。
+type Endpoint struct {
+	Router   web.Router `autowire:""`
+	Registry *Registry  `autowire:""`
+	Path     string     `value:"${metrics.prometheus.path:=/actuator/prometheus}"`
+}
+
+// OnInit 挂载指标的访问路径。
+func (e *Endpoint) OnInit() {
+	e.Router.HandleGet(e.Path, web.WrapH(e.Registry.Handler()))
+}
+
+// webFilter 统计 Web 请求的数量和耗时。
+type webFilter struct {
+	requests *CounterVec
+	latency  *TimerVec
+}
+
+// NewWebFilter 创建统计 Web 请求的数量和耗时的过滤器，指标的标签为请求方法、
+// 路由、处理函数名称和响应码，其中路由为规范化的路径模板以避免标签值过多，处理
+// 函数的名称可以通过 Mapper.Name 设置。
+func NewWebFilter(r *Registry) web.Filter {
+	return &webFilter{
+		requests: r.Counter("http_server_requests_total", "Total number of HTTP requests.", "method", "path", "handler", "status"),
+		latency:  r.Timer("http_server_request_seconds", "HTTP request latency in seconds.", "method", "path", "handler", "status"),
+	}
+}
+
+func (f *webFilter) Invoke(ctx web.Context, chain web.FilterChain) {
+
+	start := time.Now()
+	method := ctx.Request().Method
+
+	defer func() {
+		status := ctx.ResponseWriter().Status()
+		r := recover()
+		if r != nil {
+			status = http.StatusInternalServerError
+		}
+		s := strconv.Itoa(status)
+		path := web.RouteTemplate(ctx.Path())
+		handler := web.HandlerName(ctx.Handler())
+		f.requests.With(method, path, handler, s).Inc()
+		f.latency.With(method, path, handler, s).Since(start)
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	chain.Next(ctx)
+}
+
+// NewAcceptCounter 返回统计每个监听器接受的连接数的回调，通常赋值给
+// web.OnAccept ，开启 SO_REUSEPORT 时可以观察连接在多个监听器之间的分布。
+func NewAcceptCounter(r *Registry) func(listener string) {
+	accepted := r.Counter("http_server_accepted_connections_total", "Total number of connections accepted by each listener.", "listener")
+	return func(listener string) {
+		accepted.With(listener).Inc()
+	}
+}
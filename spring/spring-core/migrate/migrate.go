@@ -0,0 +1,231 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package migrate 提供基于版本号的 SQL 数据库迁移功能，迁移脚本可以来自于
+// embed.FS 等任意 fs.FS 实现，在 IoC 容器刷新阶段执行。
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// Config 数据库迁移配置，通常绑定到 db.migrate 前缀的属性上。
+type Config struct {
+	Enabled bool   `value:"${enabled:=true}"`            // 是否执行迁移
+	DryRun  bool   `value:"${dry-run:=false}"`           // 只打印不执行
+	Dir     string `value:"${dir:=migrations}"`          // 脚本所在目录
+	Table   string `value:"${table:=schema_migrations}"` // 版本记录表
+}
+
+// Migration 一个版本的迁移脚本。
+type Migration struct {
+	Version int64  // 版本号
+	Name    string // 脚本名称
+	SQL     string // 脚本内容
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Source 迁移脚本的来源，通常是一个 embed.FS 对象。
+type Source struct{ fs.FS }
+
+// FS 返回由 fs.FS 对象创建的迁移脚本来源。
+func FS(fsys fs.FS) *Source {
+	return &Source{FS: fsys}
+}
+
+// 迁移脚本的文件名必须符合 {version}_{name}.sql 或者 {version}_{name}.up.sql
+// 格式，.down.sql 结尾的回滚脚本会被忽略。
+var fileRegexp = regexp.MustCompile(`^(\d+)_(.+?)(\.up)?\.sql$`)
+
+// Load 从 fsys 的 dir 目录加载所有迁移脚本，返回的结果按照版本号升序排列。
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Migration
+	versions := make(map[int64]string)
+	for _, e := range entries {
+
+		if e.IsDir() || strings.HasSuffix(e.Name(), ".down.sql") {
+			continue
+		}
+
+		ss := fileRegexp.FindStringSubmatch(e.Name())
+		if ss == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(ss[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if s, ok := versions[version]; ok {
+			return nil, fmt.Errorf("found duplicate migration version %d [%s] [%s]", version, s, e.Name())
+		}
+		versions[version] = e.Name()
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, Migration{Version: version, Name: ss[2], SQL: string(b)})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Version < ret[j].Version })
+	return ret, nil
+}
+
+// Migrator 数据库迁移执行器，bean 初始化时按版本号顺序执行尚未执行过的迁移脚本，
+// 依赖迁移结果的 bean 可以通过 DependsOn 保证在迁移完成后再进行初始化。
+type Migrator struct {
+	db     *sql.DB
+	source *Source
+	config *Config // 使用指针避免容器对其进行属性绑定
+}
+
+// New Migrator 的构造函数。
+func New(db *sql.DB, source *Source, config Config) *Migrator {
+	return &Migrator{db: db, source: source, config: &config}
+}
+
+// OnInit 在 bean 初始化时执行数据库迁移。
+func (m *Migrator) OnInit() error {
+	if !m.config.Enabled {
+		return nil
+	}
+	_, err := m.Migrate(context.Background())
+	return err
+}
+
+// Migrate 执行尚未执行过的迁移脚本，返回本次执行(dry-run 模式下为将要执行)的迁移脚本。
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+
+	if m.source == nil || m.source.FS == nil {
+		return nil, errors.New("migration source can't be nil")
+	}
+
+	migrations, err := Load(m.source.FS, m.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.createTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+
+	for _, mig := range pending {
+		if m.config.DryRun {
+			log.Infof("[dry-run] migrate %s\n%s", mig, mig.SQL)
+			continue
+		}
+		log.Infof("migrate %s", mig)
+		if err = m.apply(ctx, mig); err != nil {
+			return nil, fmt.Errorf("migrate %s error: %w", mig, err)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	if m.config.DryRun {
+		return nil
+	}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`, m.config.Table)
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+// appliedVersions 返回已经执行过的迁移脚本的版本号，dry-run 模式下版本记录表可能
+// 不存在，此时视为没有执行过任何迁移脚本。
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]struct{}, error) {
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version FROM "+m.config.Table)
+	if err != nil {
+		if m.config.DryRun {
+			return map[int64]struct{}{}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make(map[int64]struct{})
+	for rows.Next() {
+		var v int64
+		if err = rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		ret[v] = struct{}{}
+	}
+	return ret, rows.Err()
+}
+
+// apply 在同一个事务中执行迁移脚本并记录版本号，不同数据库的占位符语法不同，因此
+// 版本记录使用字面值的方式写入。
+func (m *Migrator) apply(ctx context.Context, mig Migration) error {
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, mig.SQL); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	name := strings.ReplaceAll(mig.Name, "'", "''")
+	query := fmt.Sprintf("INSERT INTO %s (version, name) VALUES (%d, '%s')", m.config.Table, mig.Version, name)
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
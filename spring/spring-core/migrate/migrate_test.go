@@ -0,0 +1,53 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-spring/spring-core/migrate"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestLoad(t *testing.T) {
+
+	t.Run("sorted", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0010_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON user(name)")},
+			"migrations/0010_add_index.down.sql": {Data: []byte("DROP INDEX idx")},
+			"migrations/0002_create_user.sql":    {Data: []byte("CREATE TABLE user(id INT)")},
+			"migrations/README.md":               {Data: []byte("readme")},
+		}
+		ms, err := migrate.Load(fsys, "migrations")
+		assert.Nil(t, err)
+		assert.Equal(t, len(ms), 2)
+		assert.Equal(t, ms[0].Version, int64(2))
+		assert.Equal(t, ms[0].Name, "create_user")
+		assert.Equal(t, ms[1].String(), "10_add_index")
+		assert.Equal(t, ms[1].SQL, "CREATE INDEX idx ON user(name)")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/1_a.sql":    {Data: []byte("")},
+			"migrations/0001_b.sql": {Data: []byte("")},
+		}
+		_, err := migrate.Load(fsys, "migrations")
+		assert.Error(t, err, "found duplicate migration version 1")
+	})
+}
@@ -0,0 +1,392 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// Binder 将逻辑通道绑定到具体的消息中间件，例如 Kafka、RabbitMQ 或者内存。
+type Binder interface {
+
+	// BindConsumer 将消费者绑定到目的地 destination，相同 group 的消费者
+	// 共同消费目的地的消息，group 为空时使用 Binder 自身的默认值。
+	BindConsumer(destination string, group string, c Consumer) error
+
+	// BindProducer 返回发送到目的地 destination 的消息生产者。
+	BindProducer(destination string) (Producer, error)
+}
+
+// Listener 绑定到逻辑通道的消息处理器，与 Consumer 不同的是它不关心消息来自
+// 哪个中间件的哪个主题，具体的绑定关系由属性决定。
+type Listener interface {
+	Channel() string
+	Consume(ctx context.Context, msg Message) error
+}
+
+type listener struct {
+	channel string
+	fn      func(ctx context.Context, msg Message) error
+}
+
+func (l *listener) Channel() string {
+	return l.channel
+}
+
+func (l *listener) Consume(ctx context.Context, msg Message) error {
+	return l.fn(ctx, msg)
+}
+
+// Listen 创建绑定到逻辑通道 channel 的消息处理器。
+func Listen(channel string, fn func(ctx context.Context, msg Message) error) Listener {
+	return &listener{channel: channel, fn: fn}
+}
+
+// BindingConfig 逻辑通道的绑定配置。
+type BindingConfig struct {
+	Destination string `value:"${destination:=}"`      // 目的地，为空时使用通道名称
+	Group       string `value:"${group:=}"`            // 消费组
+	Binder      string `value:"${binder:=}"`           // Binder 的 bean 名称
+	ContentType string `value:"${content-type:=json}"` // 编解码器的名称
+
+	Consumer ConsumerConfig `value:"${consumer}"`
+}
+
+// StreamConfig 消息绑定配置，通常绑定到 mq 前缀的属性上。
+type StreamConfig struct {
+	DefaultBinder string                   `value:"${default-binder:=}"`
+	Bindings      map[string]BindingConfig `value:"${bindings}"`
+}
+
+// Channel 绑定到具体目的地的逻辑通道，发送消息时消息的主题被替换为目的地。
+type Channel struct {
+	name        string
+	destination string
+	producer    Producer
+	queue       DelayQueue
+	codec       Codec
+}
+
+// Name 返回逻辑通道的名称。
+func (c *Channel) Name() string {
+	return c.name
+}
+
+// Destination 返回逻辑通道绑定的目的地。
+func (c *Channel) Destination() string {
+	return c.destination
+}
+
+// SendMessage 发送消息到逻辑通道绑定的目的地。
+func (c *Channel) SendMessage(ctx context.Context, msg Message) error {
+	return c.producer.SendMessage(ctx, c.message(msg))
+}
+
+// Send 使用逻辑通道配置的编解码器编码 v 并发送到逻辑通道绑定的目的地。
+func (c *Channel) Send(ctx context.Context, v interface{}) error {
+	b, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	msg := NewMessage().WithBody(b).WithExtra(ExtraContentType, c.codec.ContentType())
+	return c.SendMessage(ctx, msg)
+}
+
+// SendAfter 在 d 时间之后发送消息到逻辑通道绑定的目的地。消息中间件原生支持延迟
+// 投递时直接使用中间件的能力，否则先将消息保存到延迟队列，到期后再发送。
+func (c *Channel) SendAfter(ctx context.Context, d time.Duration, msg Message) error {
+	if d <= 0 {
+		return c.SendMessage(ctx, msg)
+	}
+	if p, ok := c.producer.(DelayedProducer); ok {
+		return p.SendMessageAfter(ctx, d, c.message(msg))
+	}
+	if c.queue == nil {
+		return fmt.Errorf("%w: channel %q", ErrDelayNotSupported, c.name)
+	}
+	return c.queue.Schedule(ctx, c.name, time.Now().Add(d), msg)
+}
+
+// message 返回主题被替换为目的地的消息副本。
+func (c *Channel) message(msg Message) Message {
+	m := NewMessage().WithTopic(c.destination).WithID(msg.ID()).WithBody(msg.Body())
+	for k, v := range msg.Extra() {
+		m.WithExtra(k, v)
+	}
+	return m
+}
+
+// Streams 根据属性将消息处理器和逻辑通道绑定到 Binder 上，应用中只需要面向
+// 逻辑通道编程，切换消息中间件时只需要修改属性。
+type Streams struct {
+	Binders    map[string]Binder `autowire:""`
+	Listeners  []Listener        `autowire:""`
+	DelayQueue DelayQueue        `autowire:"?"`
+	Probe      HealthProbe       `autowire:"?"`
+	Codecs     map[string]Codec  `autowire:"?"`
+	Config     StreamConfig      `value:"${mq}"`
+
+	mutex     sync.Mutex
+	channels  map[string]*Channel
+	pipelines map[string][]*pipeline
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// OnInit 将所有的消息处理器绑定到对应的 Binder 上，配置了下游健康检查的通道
+// 启动后台协程检查下游的健康状态。
+func (s *Streams) OnInit() error {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.pipelines = make(map[string][]*pipeline)
+
+	for _, l := range s.Listeners {
+		b, binding, err := s.binding(l.Channel())
+		if err != nil {
+			return err
+		}
+		if t, ok := l.(TypedListener); ok {
+			var c Codec
+			if c, err = s.codec(binding); err != nil {
+				return err
+			}
+			l = t.WithCodec(c)
+		}
+		p := newPipeline(binding.Destination, l, binding.Consumer)
+		if err = b.BindConsumer(binding.Destination, binding.Group, p); err != nil {
+			return err
+		}
+		s.pipelines[l.Channel()] = append(s.pipelines[l.Channel()], p)
+		if binding.Consumer.PauseWhenDown != "" && s.Probe != nil {
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				p.watch(ctx, s.Probe)
+			}()
+		}
+	}
+	return nil
+}
+
+// OnDestroy 停止检查下游健康状态的后台协程并恢复所有暂停的通道。
+func (s *Streams) OnDestroy() {
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+	}
+	for _, pipelines := range s.pipelines {
+		for _, p := range pipelines {
+			p.setDown(false)
+			p.Resume()
+		}
+	}
+}
+
+// Pause 暂停逻辑通道 channel 的所有消息处理器，之后到达的消息阻塞直到恢复消费。
+func (s *Streams) Pause(channel string) {
+	for _, p := range s.pipelines[channel] {
+		p.Pause()
+	}
+}
+
+// Resume 恢复逻辑通道 channel 的所有消息处理器，下游不健康的处理器仍然保持暂停。
+func (s *Streams) Resume(channel string) {
+	for _, p := range s.pipelines[channel] {
+		p.Resume()
+	}
+}
+
+// Output 返回名为 name 的逻辑通道，首次调用时创建绑定关系。
+func (s *Streams) Output(name string) (*Channel, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c, ok := s.channels[name]; ok {
+		return c, nil
+	}
+
+	b, binding, err := s.binding(name)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := s.codec(binding)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := b.BindProducer(binding.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Channel{name: name, destination: binding.Destination, producer: p, queue: s.DelayQueue, codec: codec}
+	if s.channels == nil {
+		s.channels = make(map[string]*Channel)
+	}
+	s.channels[name] = c
+	return c, nil
+}
+
+// codec 返回逻辑通道配置的编解码器，优先使用同名的 Codec bean 。
+func (s *Streams) codec(binding BindingConfig) (Codec, error) {
+	name := binding.ContentType
+	if name == "" {
+		name = "json"
+	}
+	if c, ok := s.Codecs[name]; ok {
+		return c, nil
+	}
+	if c, ok := LookupCodec(name); ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("can't find codec %q for destination %q", name, binding.Destination)
+}
+
+// binding 返回逻辑通道的绑定配置以及对应的 Binder 。
+func (s *Streams) binding(name string) (Binder, BindingConfig, error) {
+
+	binding := s.Config.Bindings[name]
+	if binding.Destination == "" {
+		binding.Destination = name
+	}
+
+	binderName := binding.Binder
+	if binderName == "" {
+		binderName = s.Config.DefaultBinder
+	}
+
+	if binderName == "" {
+		switch len(s.Binders) {
+		case 0:
+			return nil, binding, errors.New("no binder found")
+		case 1:
+			for _, b := range s.Binders {
+				return b, binding, nil
+			}
+		default:
+			var names []string
+			for k := range s.Binders {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return nil, binding, fmt.Errorf("found binders %v for channel %q but no binder specified", names, name)
+		}
+	}
+
+	b, ok := s.Binders[binderName]
+	if !ok {
+		return nil, binding, fmt.Errorf("can't find binder %q for channel %q", binderName, name)
+	}
+	return b, binding, nil
+}
+
+// MemoryBinder 基于内存的 Binder 实现，消息同步投递，延迟消息通过定时器投递，
+// 主要用于测试。同一目的地的每个消费组都会收到消息，消费组内的消费者轮流消费。
+type MemoryBinder struct {
+	mutex  sync.Mutex
+	groups map[string]map[string]*memoryGroup
+}
+
+type memoryGroup struct {
+	next      int
+	consumers []Consumer
+}
+
+// NewMemoryBinder MemoryBinder 的构造函数。
+func NewMemoryBinder() *MemoryBinder {
+	return &MemoryBinder{groups: make(map[string]map[string]*memoryGroup)}
+}
+
+// BindConsumer 将消费者绑定到目的地 destination 。
+func (b *MemoryBinder) BindConsumer(destination string, group string, c Consumer) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	groups, ok := b.groups[destination]
+	if !ok {
+		groups = make(map[string]*memoryGroup)
+		b.groups[destination] = groups
+	}
+
+	// 没有指定消费组的消费者独占一个匿名消费组
+	if group == "" {
+		group = fmt.Sprintf("anonymous.%d", len(groups))
+	}
+
+	g, ok := groups[group]
+	if !ok {
+		g = &memoryGroup{}
+		groups[group] = g
+	}
+	g.consumers = append(g.consumers, c)
+	return nil
+}
+
+// BindProducer 返回发送到目的地 destination 的消息生产者。
+func (b *MemoryBinder) BindProducer(destination string) (Producer, error) {
+	return &memoryProducer{b: b, destination: destination}, nil
+}
+
+// dispatch 将消息投递给目的地的每个消费组，返回第一个消费错误。
+func (b *MemoryBinder) dispatch(ctx context.Context, destination string, msg Message) error {
+
+	var consumers []Consumer
+	b.mutex.Lock()
+	for _, g := range b.groups[destination] {
+		consumers = append(consumers, g.consumers[g.next%len(g.consumers)])
+		g.next++
+	}
+	b.mutex.Unlock()
+
+	var ret error
+	for _, c := range consumers {
+		if err := c.Consume(ctx, msg); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+type memoryProducer struct {
+	b           *MemoryBinder
+	destination string
+}
+
+func (p *memoryProducer) SendMessage(ctx context.Context, msg Message) error {
+	return p.b.dispatch(ctx, p.destination, msg)
+}
+
+// SendMessageAfter 在 d 时间之后投递消息，投递时使用新的 context ，消费错误只记录日志。
+func (p *memoryProducer) SendMessageAfter(ctx context.Context, d time.Duration, msg Message) error {
+	time.AfterFunc(d, func() {
+		if err := p.b.dispatch(context.Background(), p.destination, msg); err != nil {
+			log.Errorf("mq: delayed message to %s error: %v", p.destination, err)
+		}
+	})
+	return nil
+}
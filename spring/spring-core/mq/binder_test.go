@@ -0,0 +1,295 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestStreams(t *testing.T) {
+
+	var received []string
+	record := func(prefix string) func(ctx context.Context, msg mq.Message) error {
+		return func(ctx context.Context, msg mq.Message) error {
+			received = append(received, prefix+":"+msg.Topic()+":"+string(msg.Body()))
+			return nil
+		}
+	}
+
+	s := &mq.Streams{
+		Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+		Listeners: []mq.Listener{
+			mq.Listen("input", record("a")),
+			mq.Listen("input", record("b")),
+			mq.Listen("audit", record("c")),
+		},
+		Config: mq.StreamConfig{
+			Bindings: map[string]mq.BindingConfig{
+				"input":  {Destination: "orders", Group: "g"},
+				"output": {Destination: "orders"},
+				"audit":  {Destination: "orders"},
+			},
+		},
+	}
+	assert.Nil(t, s.OnInit())
+
+	c, err := s.Output("output")
+	assert.Nil(t, err)
+	assert.Equal(t, c.Destination(), "orders")
+
+	ctx := context.Background()
+	for _, body := range []string{"1", "2"} {
+		err = c.SendMessage(ctx, mq.NewMessage().WithTopic("output").WithBody([]byte(body)))
+		assert.Nil(t, err)
+	}
+
+	count := map[string]int{}
+	for _, r := range received {
+		count[r[:1]]++
+	}
+	assert.Equal(t, count, map[string]int{"a": 1, "b": 1, "c": 2})
+
+	s.Binders["other"] = mq.NewMemoryBinder()
+	_, err = s.Output("another")
+	assert.Error(t, err, "found binders \\[memory other\\] for channel \"another\" but no binder specified")
+}
+
+type delayQueue struct {
+	channel string
+	due     time.Time
+	msg     mq.Message
+}
+
+func (q *delayQueue) Schedule(ctx context.Context, channel string, due time.Time, msg mq.Message) error {
+	q.channel, q.due, q.msg = channel, due, msg
+	return nil
+}
+
+type producer struct{}
+
+func (producer) SendMessage(ctx context.Context, msg mq.Message) error {
+	return nil
+}
+
+type binder struct{}
+
+func (binder) BindConsumer(destination string, group string, c mq.Consumer) error {
+	return nil
+}
+
+func (binder) BindProducer(destination string) (mq.Producer, error) {
+	return producer{}, nil
+}
+
+func TestChannel_SendAfter(t *testing.T) {
+
+	ctx := context.Background()
+
+	t.Run("native", func(t *testing.T) {
+		ch := make(chan string, 1)
+		s := &mq.Streams{
+			Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+			Listeners: []mq.Listener{
+				mq.Listen("orders", func(ctx context.Context, msg mq.Message) error {
+					ch <- string(msg.Body())
+					return nil
+				}),
+			},
+		}
+		assert.Nil(t, s.OnInit())
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+		start := time.Now()
+		err = c.SendAfter(ctx, 20*time.Millisecond, mq.NewMessage().WithBody([]byte("1")))
+		assert.Nil(t, err)
+		select {
+		case body := <-ch:
+			assert.Equal(t, body, "1")
+			assert.True(t, time.Since(start) >= 20*time.Millisecond)
+		case <-time.After(time.Second):
+			t.Fatal("delayed message not delivered")
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		q := &delayQueue{}
+		s := &mq.Streams{
+			Binders:    map[string]mq.Binder{"b": binder{}},
+			DelayQueue: q,
+		}
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+		before := time.Now()
+		err = c.SendAfter(ctx, time.Minute, mq.NewMessage().WithBody([]byte("1")))
+		assert.Nil(t, err)
+		assert.Equal(t, q.channel, "orders")
+		assert.Equal(t, string(q.msg.Body()), "1")
+		assert.True(t, !q.due.Before(before.Add(time.Minute)))
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		s := &mq.Streams{Binders: map[string]mq.Binder{"b": binder{}}}
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+		err = c.SendAfter(ctx, time.Minute, mq.NewMessage())
+		assert.True(t, errors.Is(err, mq.ErrDelayNotSupported))
+		assert.Nil(t, c.SendAfter(ctx, 0, mq.NewMessage()))
+	})
+}
+
+type probe struct {
+	healthy int32
+}
+
+func (p *probe) Healthy(ctx context.Context, name string) bool {
+	return atomic.LoadInt32(&p.healthy) == 1
+}
+
+func TestStreams_Consumer(t *testing.T) {
+
+	ctx := context.Background()
+
+	t.Run("max in flight", func(t *testing.T) {
+		var current, max int32
+		s := &mq.Streams{
+			Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+			Listeners: []mq.Listener{
+				mq.Listen("orders", func(ctx context.Context, msg mq.Message) error {
+					n := atomic.AddInt32(&current, 1)
+					defer atomic.AddInt32(&current, -1)
+					for {
+						m := atomic.LoadInt32(&max)
+						if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					return nil
+				}),
+			},
+			Config: mq.StreamConfig{
+				Bindings: map[string]mq.BindingConfig{
+					"orders": {Consumer: mq.ConsumerConfig{MaxInFlight: 2}},
+				},
+			},
+		}
+		assert.Nil(t, s.OnInit())
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, c.SendMessage(ctx, mq.NewMessage()))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, atomic.LoadInt32(&max), int32(2))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		var mutex sync.Mutex
+		var sizes []int
+		s := &mq.Streams{
+			Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+			Listeners: []mq.Listener{
+				mq.ListenBatch("orders", func(ctx context.Context, msgs []mq.Message) error {
+					mutex.Lock()
+					sizes = append(sizes, len(msgs))
+					mutex.Unlock()
+					return errors.New("batch error")
+				}),
+			},
+			Config: mq.StreamConfig{
+				Bindings: map[string]mq.BindingConfig{
+					"orders": {Consumer: mq.ConsumerConfig{BatchSize: 3, BatchLinger: 20 * time.Millisecond}},
+				},
+			},
+		}
+		assert.Nil(t, s.OnInit())
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Error(t, c.SendMessage(ctx, mq.NewMessage()), "batch error")
+			}()
+		}
+		wg.Wait()
+		sort.Ints(sizes)
+		assert.Equal(t, sizes, []int{1, 3})
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		p := &probe{}
+		var count int32
+		s := &mq.Streams{
+			Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+			Listeners: []mq.Listener{
+				mq.Listen("orders", func(ctx context.Context, msg mq.Message) error {
+					atomic.AddInt32(&count, 1)
+					return nil
+				}),
+			},
+			Probe: p,
+			Config: mq.StreamConfig{
+				Bindings: map[string]mq.BindingConfig{
+					"orders": {Consumer: mq.ConsumerConfig{PauseWhenDown: "db", CheckInterval: 10 * time.Millisecond}},
+				},
+			},
+		}
+		assert.Nil(t, s.OnInit())
+		defer s.OnDestroy()
+		c, err := s.Output("orders")
+		assert.Nil(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			assert.Nil(t, c.SendMessage(ctx, mq.NewMessage()))
+		}()
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, atomic.LoadInt32(&count), int32(0))
+
+		atomic.StoreInt32(&p.healthy, 1)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("consumer not resumed")
+		}
+		assert.Equal(t, atomic.LoadInt32(&count), int32(1))
+
+		s.Pause("orders")
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.Error(t, c.SendMessage(ctx, mq.NewMessage()), "context deadline exceeded")
+		s.Resume("orders")
+	})
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-spring/spring-stl/json"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExtraContentType 保存消息内容类型的额外信息的键。
+const ExtraContentType = "content-type"
+
+// Codec 消息内容的编解码器，通过逻辑通道的 content-type 属性按照名称选择。
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("mq: %T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("mq: %T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+var (
+	JSON     Codec = jsonCodec{}     // 基于 json 的编解码器
+	Protobuf Codec = protobufCodec{} // 基于 protobuf 的编解码器
+)
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{
+	m: map[string]Codec{"json": JSON, "protobuf": Protobuf},
+}
+
+// RegisterCodec 注册名为 name 的编解码器，导出为 Codec 接口的 bean 以 bean
+// 名称注册，不需要调用该函数。
+func RegisterCodec(name string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[name] = c
+}
+
+// LookupCodec 返回名为 name 的编解码器。
+func LookupCodec(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.m[name]
+	return c, ok
+}
+
+// TypedListener 处理函数的参数类型确定的消息处理器，消息内容由逻辑通道配置
+// 的编解码器解码为参数类型的对象。
+type TypedListener interface {
+	Listener
+	WithCodec(c Codec) Listener
+}
+
+// typedListener Handle 方式的消息处理器。
+type typedListener struct {
+	channel string
+	codec   Codec
+	v       reflect.Value
+	e       reflect.Type
+}
+
+// Handle 创建绑定到逻辑通道 channel 的消息处理器，fn 的类型为
+// func(ctx,*struct)error ，例如 func(ctx context.Context, e *OrderCreated) error 。
+func Handle(channel string, fn interface{}) TypedListener {
+	t := reflect.TypeOf(fn)
+	if !validBindFn(t) {
+		panic(errors.New("fn should be func(ctx,*struct)error"))
+	}
+	return &typedListener{channel: channel, codec: JSON, v: reflect.ValueOf(fn), e: t.In(1)}
+}
+
+func (l *typedListener) Channel() string {
+	return l.channel
+}
+
+// WithCodec 返回使用编解码器 c 的消息处理器副本。
+func (l *typedListener) WithCodec(c Codec) Listener {
+	r := *l
+	r.codec = c
+	return &r
+}
+
+func (l *typedListener) Consume(ctx context.Context, msg Message) error {
+	e := reflect.New(l.e.Elem())
+	if err := l.codec.Unmarshal(msg.Body(), e.Interface()); err != nil {
+		return fmt.Errorf("mq: decode %s message error: %w", l.codec.ContentType(), err)
+	}
+	out := l.v.Call([]reflect.Value{reflect.ValueOf(ctx), e})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type OrderCreated struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func (*OrderCreated) Schema() string {
+	return `{"type":"record","name":"OrderCreated","namespace":"shop","fields":[{"name":"id","type":"string"},{"name":"total","type":"int"}]}`
+}
+
+// jsonSerde 测试用的 AvroSerde ，使用 json 代替 Avro 二进制格式。
+type jsonSerde struct{}
+
+func (jsonSerde) Marshal(schema string, v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerde) Unmarshal(schema string, data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestStreams_Codec(t *testing.T) {
+
+	var (
+		orders []*OrderCreated
+		names  []string
+	)
+
+	s := &mq.Streams{
+		Binders: map[string]mq.Binder{"memory": mq.NewMemoryBinder()},
+		Listeners: []mq.Listener{
+			mq.Handle("orders", func(ctx context.Context, e *OrderCreated) error {
+				orders = append(orders, e)
+				return nil
+			}),
+			mq.Handle("names", func(ctx context.Context, e *wrapperspb.StringValue) error {
+				names = append(names, e.GetValue())
+				return nil
+			}),
+		},
+		Config: mq.StreamConfig{
+			Bindings: map[string]mq.BindingConfig{
+				"names": {ContentType: "protobuf"},
+			},
+		},
+	}
+	assert.Nil(t, s.OnInit())
+
+	ctx := context.Background()
+
+	c, err := s.Output("orders")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Send(ctx, &OrderCreated{ID: "1", Total: 100}))
+	assert.Equal(t, orders, []*OrderCreated{{ID: "1", Total: 100}})
+
+	c, err = s.Output("names")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Send(ctx, wrapperspb.String("go-spring")))
+	assert.Equal(t, names, []string{"go-spring"})
+	assert.Error(t, c.Send(ctx, &OrderCreated{}), "is not a protobuf message")
+
+	s.Config.Bindings["other"] = mq.BindingConfig{ContentType: "xml"}
+	_, err = s.Output("other")
+	assert.Error(t, err, "can't find codec \"xml\" for destination \"other\"")
+}
+
+func TestAvroCodec(t *testing.T) {
+
+	var subjects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			subjects = append(subjects, r.URL.Path)
+			_, _ = w.Write([]byte(`{"id":7}`))
+		case r.URL.Path == "/schemas/ids/7":
+			b, _ := json.Marshal(map[string]string{"schema": (*OrderCreated)(nil).Schema()})
+			_, _ = w.Write(b)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	registry := mq.NewSchemaRegistry(mq.SchemaRegistryConfig{URL: srv.URL})
+	codec := mq.NewAvroCodec(registry, jsonSerde{})
+
+	b, err := codec.Marshal(&OrderCreated{ID: "1", Total: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, b[0], byte(0))
+	assert.Equal(t, binary.BigEndian.Uint32(b[1:5]), uint32(7))
+
+	_, err = codec.Marshal(&OrderCreated{ID: "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, subjects, []string{"/subjects/shop.OrderCreated/versions"})
+
+	var e OrderCreated
+	assert.Nil(t, codec.Unmarshal(b, &e))
+	assert.Equal(t, e, OrderCreated{ID: "1", Total: 100})
+
+	assert.Error(t, codec.Unmarshal([]byte{1}, &e), "invalid confluent avro message")
+	assert.Error(t, codec.Unmarshal([]byte{0, 0, 0, 0, 1}, &e), "404 Not Found")
+	_, err = codec.Marshal(&struct{}{})
+	assert.Error(t, err, "is not an avro record")
+}
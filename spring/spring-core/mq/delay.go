@@ -0,0 +1,38 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDelayNotSupported 消息中间件不支持延迟投递并且没有配置延迟队列时返回的错误。
+var ErrDelayNotSupported = errors.New("mq: delayed delivery not supported")
+
+// DelayedProducer 可以由 Producer 实现的可选接口，表示消息中间件原生支持延迟
+// 投递，例如 RabbitMQ 的延迟交换机、RocketMQ 的定时消息。
+type DelayedProducer interface {
+	SendMessageAfter(ctx context.Context, d time.Duration, msg Message) error
+}
+
+// DelayQueue 延迟队列，消息中间件不支持延迟投递时延迟消息先保存在延迟队列中，
+// 到期后再由延迟队列发送到逻辑通道 channel 。
+type DelayQueue interface {
+	Schedule(ctx context.Context, channel string, due time.Time, msg Message) error
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// ConsumerConfig 逻辑通道消费者的并发、批量和背压配置。
+type ConsumerConfig struct {
+	Concurrency   int           `value:"${concurrency:=1}"`      // 并发消费的协程数，由支持并发消费的 Binder 使用
+	MaxInFlight   int           `value:"${max-in-flight:=0}"`    // 同时处理中的最大消息数，达到上限时阻塞 Binder ，0 表示不限制
+	BatchSize     int           `value:"${batch-size:=1}"`       // 批量消费的最大消息数，大于 1 时只对 BatchListener 生效
+	BatchLinger   time.Duration `value:"${batch-linger:=100ms}"` // 批次未满时的最长等待时间
+	PauseWhenDown string        `value:"${pause-when-down:=}"`   // 下游健康检查的名称，检查失败时暂停消费
+	CheckInterval time.Duration `value:"${check-interval:=5s}"`  // 下游健康检查的间隔
+}
+
+// Concurrent 可以由 Consumer 实现的可选接口，支持并发消费的 Binder 据此决定
+// 消费协程的数量。
+type Concurrent interface {
+	Concurrency() int
+}
+
+// BatchListener 支持批量消费的消息处理器，批次内的所有消息共享处理结果。
+type BatchListener interface {
+	Listener
+	ConsumeBatch(ctx context.Context, msgs []Message) error
+}
+
+type batchListener struct {
+	channel string
+	fn      func(ctx context.Context, msgs []Message) error
+}
+
+func (l *batchListener) Channel() string {
+	return l.channel
+}
+
+func (l *batchListener) Consume(ctx context.Context, msg Message) error {
+	return l.fn(ctx, []Message{msg})
+}
+
+func (l *batchListener) ConsumeBatch(ctx context.Context, msgs []Message) error {
+	return l.fn(ctx, msgs)
+}
+
+// ListenBatch 创建绑定到逻辑通道 channel 的批量消息处理器。
+func ListenBatch(channel string, fn func(ctx context.Context, msgs []Message) error) BatchListener {
+	return &batchListener{channel: channel, fn: fn}
+}
+
+// HealthProbe 下游健康检查，name 为健康检查的名称，返回 false 时暂停消费。
+type HealthProbe interface {
+	Healthy(ctx context.Context, name string) bool
+}
+
+// batch 正在收集的一批消息，done 关闭之后 err 为批次的处理结果。
+type batch struct {
+	msgs    []Message
+	timer   *time.Timer
+	flushed bool
+	done    chan struct{}
+	err     error
+}
+
+// pipeline 按照 ConsumerConfig 控制消息处理器的并发、批量和暂停，消息处理完成之后
+// Consume 才返回，因此 Binder 的确认语义保持不变。
+type pipeline struct {
+	destination string
+	l           Listener
+	config      ConsumerConfig
+	sem         chan struct{}
+
+	mutex  sync.Mutex
+	batch  *batch
+	manual bool          // 是否被 Pause 暂停
+	down   bool          // 下游是否不健康
+	resume chan struct{} // 暂停时不为空，恢复时关闭
+}
+
+func newPipeline(destination string, l Listener, config ConsumerConfig) *pipeline {
+	p := &pipeline{destination: destination, l: l, config: config}
+	if config.MaxInFlight > 0 {
+		p.sem = make(chan struct{}, config.MaxInFlight)
+	}
+	return p
+}
+
+func (p *pipeline) Topics() []string {
+	return []string{p.destination}
+}
+
+func (p *pipeline) Concurrency() int {
+	return p.config.Concurrency
+}
+
+// Pause 暂停消费，之后到达的消息阻塞直到恢复消费。
+func (p *pipeline) Pause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.manual = true
+	p.update()
+}
+
+// Resume 恢复被 Pause 暂停的消费，下游不健康时仍然保持暂停。
+func (p *pipeline) Resume() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.manual = false
+	p.update()
+}
+
+// setDown 设置下游是否不健康，下游不健康时暂停消费。
+func (p *pipeline) setDown(down bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.down = down
+	p.update()
+}
+
+// update 根据暂停状态创建或者关闭 resume ，调用时需要持有锁。
+func (p *pipeline) update() {
+	if p.manual || p.down {
+		if p.resume == nil {
+			p.resume = make(chan struct{})
+		}
+	} else if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+func (p *pipeline) Consume(ctx context.Context, msg Message) error {
+
+	p.mutex.Lock()
+	resume := p.resume
+	p.mutex.Unlock()
+	if resume != nil {
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if p.sem != nil {
+		select {
+		case p.sem <- struct{}{}:
+			defer func() { <-p.sem }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if bl, ok := p.l.(BatchListener); ok && p.config.BatchSize > 1 {
+		return p.consumeBatch(ctx, bl, msg)
+	}
+	return p.l.Consume(ctx, msg)
+}
+
+// consumeBatch 将消息加入当前批次并等待批次的处理结果，批次在消息数量达到
+// BatchSize 或者等待时间达到 BatchLinger 时被处理。
+func (p *pipeline) consumeBatch(ctx context.Context, bl BatchListener, msg Message) error {
+
+	p.mutex.Lock()
+	b := p.batch
+	if b == nil {
+		b = &batch{done: make(chan struct{})}
+		b.timer = time.AfterFunc(p.config.BatchLinger, func() {
+			p.flush(context.Background(), bl, b)
+		})
+		p.batch = b
+	}
+	b.msgs = append(b.msgs, msg)
+	full := len(b.msgs) >= p.config.BatchSize
+	p.mutex.Unlock()
+
+	if full {
+		p.flush(ctx, bl, b)
+	}
+	<-b.done
+	return b.err
+}
+
+// flush 处理批次 b ，同一批次只会被处理一次。
+func (p *pipeline) flush(ctx context.Context, bl BatchListener, b *batch) {
+
+	p.mutex.Lock()
+	if b.flushed {
+		p.mutex.Unlock()
+		return
+	}
+	b.flushed = true
+	b.timer.Stop()
+	if p.batch == b {
+		p.batch = nil
+	}
+	p.mutex.Unlock()
+
+	b.err = bl.ConsumeBatch(ctx, b.msgs)
+	close(b.done)
+}
+
+// watch 按照固定的间隔检查下游的健康状态，不健康时暂停消费，恢复健康时恢复消费。
+func (p *pipeline) watch(ctx context.Context, probe HealthProbe) {
+	ticker := time.NewTicker(p.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		down := !probe.Healthy(ctx, p.config.PauseWhenDown)
+		p.mutex.Lock()
+		changed := down != p.down
+		p.mutex.Unlock()
+		if changed {
+			if down {
+				log.Warnf("mq: pause consuming %s, %s is unhealthy", p.destination, p.config.PauseWhenDown)
+			} else {
+				log.Infof("mq: resume consuming %s, %s is healthy", p.destination, p.config.PauseWhenDown)
+			}
+			p.setDown(down)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
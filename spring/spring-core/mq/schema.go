@@ -0,0 +1,239 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mq
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-stl/json"
+)
+
+// SchemaRegistryConfig Confluent Schema Registry 的配置，通常绑定到
+// mq.schema-registry 前缀的属性上。
+type SchemaRegistryConfig struct {
+	URL      string        `value:"${url}"`         // 服务地址，例如 http://localhost:8081
+	Username string        `value:"${username:=}"`  // 基本认证的用户名
+	Password string        `value:"${password:=}"`  // 基本认证的密码
+	Timeout  time.Duration `value:"${timeout:=5s}"` // 请求超时时间
+}
+
+// SchemaRegistry Confluent Schema Registry 的客户端，注册和查询的结果都会被缓存。
+type SchemaRegistry struct {
+	config *SchemaRegistryConfig // 使用指针避免容器对其进行属性绑定
+	client *http.Client
+
+	mutex   sync.RWMutex
+	ids     map[string]int // subject+schema -> id
+	schemas map[int]string // id -> schema
+}
+
+// NewSchemaRegistry SchemaRegistry 的构造函数。
+func NewSchemaRegistry(config SchemaRegistryConfig) *SchemaRegistry {
+	return &SchemaRegistry{
+		config:  &config,
+		client:  &http.Client{Timeout: config.Timeout},
+		ids:     make(map[string]int),
+		schemas: make(map[int]string),
+	}
+}
+
+type schemaPayload struct {
+	ID     int    `json:"id,omitempty"`
+	Schema string `json:"schema,omitempty"`
+}
+
+// Register 在主题 subject 下注册 schema 并返回其 ID ，已经注册过的 schema
+// 返回原有的 ID 。
+func (r *SchemaRegistry) Register(ctx context.Context, subject, schema string) (int, error) {
+
+	key := subject + "\n" + schema
+	r.mutex.RLock()
+	id, ok := r.ids[key]
+	r.mutex.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	var resp schemaPayload
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := r.do(ctx, http.MethodPost, path, schemaPayload{Schema: schema}, &resp); err != nil {
+		return 0, err
+	}
+
+	r.mutex.Lock()
+	r.ids[key] = resp.ID
+	r.schemas[resp.ID] = schema
+	r.mutex.Unlock()
+	return resp.ID, nil
+}
+
+// Schema 返回 ID 对应的 schema 。
+func (r *SchemaRegistry) Schema(ctx context.Context, id int) (string, error) {
+
+	r.mutex.RLock()
+	schema, ok := r.schemas[id]
+	r.mutex.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaPayload
+	if err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return "", err
+	}
+
+	r.mutex.Lock()
+	r.schemas[id] = resp.Schema
+	r.mutex.Unlock()
+	return resp.Schema, nil
+}
+
+func (r *SchemaRegistry) do(ctx context.Context, method, path string, in, out interface{}) error {
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.config.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if r.config.Username != "" {
+		req.SetBasicAuth(r.config.Username, r.config.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("schema registry %s %s: %s %s", method, path, resp.Status, b)
+	}
+	return json.Unmarshal(b, out)
+}
+
+// AvroSerde Avro 二进制格式的编解码实现，由具体的 Avro 库适配，导出为该接口的
+// bean 存在并且开启了 mq.schema-registry.enabled 时自动注册名为 avro 的编解码器。
+type AvroSerde interface {
+	Marshal(schema string, v interface{}) ([]byte, error)
+	Unmarshal(schema string, data []byte, v interface{}) error
+}
+
+// AvroRecord 可以使用 Avro 编解码器的对象，返回对象的 Avro schema 。
+type AvroRecord interface {
+	Schema() string
+}
+
+// avroCodec 使用 Confluent 线路格式的 Avro 编解码器：1 字节的魔数 0 ，4 字节
+// 大端序的 schema ID ，之后是 Avro 二进制数据。schema 以其全名作为主题注册，
+// 即 RecordNameStrategy 策略。
+type avroCodec struct {
+	registry *SchemaRegistry
+	serde    AvroSerde
+}
+
+// NewAvroCodec 创建基于 Confluent Schema Registry 的 Avro 编解码器。
+func NewAvroCodec(registry *SchemaRegistry, serde AvroSerde) Codec {
+	return &avroCodec{registry: registry, serde: serde}
+}
+
+func (c *avroCodec) ContentType() string {
+	return "application/vnd.confluent.avro"
+}
+
+func (c *avroCodec) Marshal(v interface{}) ([]byte, error) {
+
+	r, ok := v.(AvroRecord)
+	if !ok {
+		return nil, fmt.Errorf("mq: %T is not an avro record", v)
+	}
+
+	schema := r.Schema()
+	subject, err := schemaName(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := c.registry.Register(context.Background(), subject, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.serde.Marshal(schema, v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	return append(buf, b...), nil
+}
+
+func (c *avroCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) < 5 || data[0] != 0 {
+		return errors.New("mq: invalid confluent avro message")
+	}
+	id := int(binary.BigEndian.Uint32(data[1:5]))
+	schema, err := c.registry.Schema(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return c.serde.Unmarshal(schema, data[5:], v)
+}
+
+// schemaName 返回 Avro schema 的全名。
+func schemaName(schema string) (string, error) {
+	var s struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return "", fmt.Errorf("mq: invalid avro schema: %w", err)
+	}
+	if s.Name == "" {
+		return "", errors.New("mq: avro schema has no name")
+	}
+	if s.Namespace == "" || strings.Contains(s.Name, ".") {
+		return s.Name, nil
+	}
+	return s.Namespace + "." + s.Name, nil
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package outbox 实现了事务性发件箱模式：在数据库事务中发布的消息先写入发件箱
+// 表，事务提交后由后台协程转发到 mq.Streams 绑定的消息中间件，从而保证消息的发送
+// 和数据库的提交结果一致，消息至少被投递一次。
+//
+// 发件箱表需要预先创建，以 MySQL 为例:
+//
+//	CREATE TABLE outbox (
+//		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+//		channel VARCHAR(255) NOT NULL,
+//		msg_id VARCHAR(255) NOT NULL,
+//		body BLOB NOT NULL,
+//		extra TEXT NOT NULL,
+//		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+//	)
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/json"
+)
+
+// Config 发件箱配置，通常绑定到 outbox 前缀的属性上。
+type Config struct {
+	Table     string        `value:"${table:=outbox}"`   // 发件箱表
+	Dialect   string        `value:"${dialect:=mysql}"`  // 数据库方言，决定 SQL 占位符的格式
+	Interval  time.Duration `value:"${interval:=1s}"`    // 轮询间隔
+	BatchSize int           `value:"${batch-size:=100}"` // 每次轮询转发的最大消息数
+}
+
+// Outbox 事务性发件箱。
+type Outbox struct {
+	Streams *mq.Streams `autowire:""`
+
+	db     *sql.DB
+	config *Config // 使用指针避免容器对其进行属性绑定
+	wakeup chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New Outbox 的构造函数。
+func New(db *sql.DB, config Config) (*Outbox, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("outbox: interval must be positive")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	return &Outbox{
+		db:     db,
+		config: &config,
+		wakeup: make(chan struct{}, 1),
+	}, nil
+}
+
+// Publish 在事务 tx 中将消息写入发件箱，消息在事务提交后被转发到逻辑通道 channel 。
+func (o *Outbox) Publish(ctx context.Context, tx *sql.Tx, channel string, msg mq.Message) error {
+
+	extra, err := json.Marshal(msg.Extra())
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (channel, msg_id, body, extra) VALUES (%s)",
+		o.config.Table, o.placeholders(4))
+	_, err = tx.ExecContext(ctx, query, channel, msg.ID(), msg.Body(), string(extra))
+	return err
+}
+
+// Notify 通知后台协程立即转发消息，通常在事务提交之后调用以降低消息延迟。
+func (o *Outbox) Notify() {
+	select {
+	case o.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// OnInit 启动转发消息的后台协程。
+func (o *Outbox) OnInit() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		ticker := time.NewTicker(o.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-o.wakeup:
+			}
+			if _, err := o.Relay(ctx); err != nil {
+				log.Errorf("outbox relay error: %v", err)
+			}
+		}
+	}()
+}
+
+// OnDestroy 停止转发消息的后台协程。
+func (o *Outbox) OnDestroy() {
+	if o.cancel != nil {
+		o.cancel()
+		o.wg.Wait()
+	}
+}
+
+type record struct {
+	id      int64
+	channel string
+	msgID   string
+	body    []byte
+	extra   string
+}
+
+// Relay 转发一批发件箱中的消息，返回成功转发的消息数量。消息按照写入的顺序转发，
+// 遇到发送失败的消息时停止本次转发，等待下次重试。
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+
+	records, err := o.fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, r := range records {
+
+		c, err := o.Streams.Output(r.channel)
+		if err != nil {
+			return i, err
+		}
+
+		m := mq.NewMessage().WithID(r.msgID).WithBody(r.body)
+		var extra map[string]string
+		if err = json.Unmarshal([]byte(r.extra), &extra); err != nil {
+			return i, err
+		}
+		for k, v := range extra {
+			m.WithExtra(k, v)
+		}
+
+		if err = c.SendMessage(ctx, m); err != nil {
+			return i, err
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", o.config.Table, o.placeholders(1))
+		if _, err = o.db.ExecContext(ctx, query, r.id); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+func (o *Outbox) fetch(ctx context.Context) ([]record, error) {
+
+	query := fmt.Sprintf("SELECT id, channel, msg_id, body, extra FROM %s ORDER BY id LIMIT %d",
+		o.config.Table, o.config.BatchSize)
+	rows, err := o.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []record
+	for rows.Next() {
+		var r record
+		if err = rows.Scan(&r.id, &r.channel, &r.msgID, &r.body, &r.extra); err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, rows.Err()
+}
+
+// placeholders 返回 n 个逗号分隔的 SQL 占位符，postgres 使用 $1 格式，其他数据库
+// 使用 ? 格式。
+func (o *Outbox) placeholders(n int) string {
+	s := make([]string, n)
+	for i := range s {
+		if o.config.Dialect == "postgres" {
+			s[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			s[i] = "?"
+		}
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-core/outbox"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// table 内存中的发件箱表，只支持 Outbox 使用的几条语句。
+type table struct {
+	mutex  sync.Mutex
+	nextID int64
+	rows   map[int64][]driver.Value
+}
+
+func (t *table) Connect(ctx context.Context) (driver.Conn, error) { return conn{t}, nil }
+
+func (t *table) Driver() driver.Driver { return nil }
+
+// ids 返回表中剩余记录的 id 。
+func (t *table) ids() []int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var ret []int64
+	for id := range t.rows {
+		ret = append(ret, id)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+type conn struct{ t *table }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.t, query}, nil }
+
+func (c conn) Close() error { return nil }
+
+func (c conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	t     *table
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.t.mutex.Lock()
+	defer s.t.mutex.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.t.nextID++
+		s.t.rows[s.t.nextID] = append([]driver.Value{s.t.nextID}, args...)
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.t.rows, args[0].(int64))
+	default:
+		return nil, errors.New("unsupported statement " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unsupported statement " + s.query)
+	}
+	var limit int
+	fmt.Sscanf(s.query[strings.LastIndex(s.query, "LIMIT"):], "LIMIT %d", &limit)
+	r := &rows{}
+	for _, id := range s.t.ids() {
+		if len(r.data) == limit {
+			break
+		}
+		s.t.mutex.Lock()
+		r.data = append(r.data, s.t.rows[id])
+		s.t.mutex.Unlock()
+	}
+	return r, nil
+}
+
+type rows struct{ data [][]driver.Value }
+
+func (r *rows) Columns() []string {
+	return []string{"id", "channel", "msg_id", "body", "extra"}
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.data[0])
+	r.data = r.data[1:]
+	return nil
+}
+
+// publisher 记录发送的消息，fail 不为空时发送失败。
+type publisher struct {
+	mutex sync.Mutex
+	fail  error
+	sent  []mq.Message
+}
+
+func (p *publisher) BindConsumer(destination string, group string, c mq.Consumer) error {
+	return nil
+}
+
+func (p *publisher) BindProducer(destination string) (mq.Producer, error) {
+	return p, nil
+}
+
+func (p *publisher) SendMessage(ctx context.Context, msg mq.Message) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.fail != nil {
+		return p.fail
+	}
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+// bodies 返回已发送消息的 topic:body 列表。
+func (p *publisher) bodies() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var ret []string
+	for _, m := range p.sent {
+		ret = append(ret, m.Topic()+":"+string(m.Body()))
+	}
+	return ret
+}
+
+func newOutbox(t *testing.T, config outbox.Config) (*outbox.Outbox, *sql.DB, *table, *publisher) {
+	tbl := &table{rows: make(map[int64][]driver.Value)}
+	db := sql.OpenDB(tbl)
+	t.Cleanup(func() { db.Close() })
+	o, err := outbox.New(db, config)
+	assert.Nil(t, err)
+	p := &publisher{}
+	o.Streams = &mq.Streams{
+		Binders: map[string]mq.Binder{"fake": p},
+		Config: mq.StreamConfig{
+			Bindings: map[string]mq.BindingConfig{
+				"orders": {Destination: "order-events"},
+			},
+		},
+	}
+	return o, db, tbl, p
+}
+
+// publish 在同一个事务中将 bodies 写入发件箱。
+func publish(t *testing.T, o *outbox.Outbox, db *sql.DB, channel string, bodies ...string) {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	for _, b := range bodies {
+		msg := mq.NewMessage().WithID("id-"+b).WithBody([]byte(b)).WithExtra("source", "test")
+		assert.Nil(t, o.Publish(ctx, tx, channel, msg))
+	}
+	assert.Nil(t, tx.Commit())
+}
+
+func TestNew(t *testing.T) {
+	_, err := outbox.New(nil, outbox.Config{})
+	assert.Error(t, err, "outbox: interval must be positive")
+	_, err = outbox.New(nil, outbox.Config{Interval: -time.Second})
+	assert.Error(t, err, "outbox: interval must be positive")
+}
+
+func TestOutbox_Relay(t *testing.T) {
+
+	ctx := context.Background()
+	o, db, tbl, p := newOutbox(t, outbox.Config{Table: "outbox", Interval: time.Second})
+
+	// 消息按照写入的顺序转发，转发成功的记录从发件箱中删除。
+	publish(t, o, db, "orders", "1", "2")
+	publish(t, o, db, "audit", "3")
+	assert.Equal(t, tbl.ids(), []int64{1, 2, 3})
+
+	n, err := o.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 3)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2", "audit:3"})
+	assert.Equal(t, p.sent[0].ID(), "id-1")
+	assert.Equal(t, p.sent[0].Extra(), map[string]string{"source": "test"})
+	assert.Equal(t, len(tbl.ids()), 0)
+
+	n, err = o.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 0)
+}
+
+func TestOutbox_RelayRetry(t *testing.T) {
+
+	ctx := context.Background()
+	o, db, tbl, p := newOutbox(t, outbox.Config{Table: "outbox", Interval: time.Second})
+	publish(t, o, db, "orders", "1", "2")
+
+	// 发送失败时停止转发，记录保留在发件箱中等待下次重试。
+	p.fail = errors.New("broker down")
+	n, err := o.Relay(ctx)
+	assert.Error(t, err, "broker down")
+	assert.Equal(t, n, 0)
+	assert.Equal(t, tbl.ids(), []int64{1, 2})
+
+	p.fail = nil
+	n, err = o.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2"})
+	assert.Equal(t, len(tbl.ids()), 0)
+}
+
+func TestOutbox_BatchSize(t *testing.T) {
+
+	ctx := context.Background()
+	o, db, tbl, p := newOutbox(t, outbox.Config{Table: "outbox", Interval: time.Second, BatchSize: 2})
+	publish(t, o, db, "orders", "1", "2", "3")
+
+	n, err := o.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, tbl.ids(), []int64{3})
+
+	n, err = o.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, p.bodies(), []string{"order-events:1", "order-events:2", "order-events:3"})
+}
+
+func TestOutbox_Notify(t *testing.T) {
+
+	o, db, tbl, p := newOutbox(t, outbox.Config{Table: "outbox", Interval: time.Hour})
+	o.OnInit()
+	defer o.OnDestroy()
+
+	// 轮询间隔很长，Notify 触发立即转发。
+	publish(t, o, db, "orders", "1")
+	o.Notify()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tbl.ids()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, len(tbl.ids()), 0)
+	assert.Equal(t, p.bodies(), []string{"order-events:1"})
+}
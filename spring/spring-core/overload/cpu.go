@@ -0,0 +1,68 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package overload
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// processSampler 基于 getrusage 采集当前进程的 CPU 使用率。
+type processSampler struct {
+	mu   sync.Mutex
+	cpu  time.Duration
+	wall time.Time
+}
+
+func newProcessSampler() CPUSampler {
+	s := &processSampler{wall: time.Now()}
+	s.cpu, _ = cpuTime()
+	return s
+}
+
+func cpuTime() (time.Duration, bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, false
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), true
+}
+
+func (s *processSampler) CPUUsage() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cpu, ok := cpuTime()
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+	wall := now.Sub(s.wall) * time.Duration(runtime.GOMAXPROCS(0))
+	usage := 0.0
+	if wall > 0 {
+		usage = float64(cpu-s.cpu) / float64(wall) * 100
+	}
+	s.cpu, s.wall = cpu, now
+	if usage > 100 {
+		usage = 100
+	}
+	return usage
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package overload 提供了 Web 服务的过载保护过滤器，在并发数、排队时间或者 CPU
+// 使用率超过阈值时尽早以 429 拒绝多余的请求，避免请求在服务内部堆积导致所有
+// 请求都超时。
+package overload
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/web"
+)
+
+// 请求被拒绝的原因。
+const (
+	ReasonConcurrency = "concurrency" // 并发数达到上限
+	ReasonQueue       = "queue"       // 排队时间超过上限
+	ReasonCPU         = "cpu"         // CPU 使用率超过阈值
+)
+
+// CPUSampler 采集 CPU 使用率，默认采集当前进程的 CPU 使用率，在容器中运行时
+// 可以提供基于 cgroup 配额的实现。
+type CPUSampler interface {
+	CPUUsage() float64 // 返回上次调用以来的 CPU 使用率，取值范围为 0-100
+}
+
+// Config 过载保护过滤器配置，通常绑定到 web.overload 前缀的属性上。
+type Config struct {
+	MaxConcurrent int           `value:"${max-concurrent:=0}"`   // 最大并发数，0 表示不限制
+	QueueTimeout  time.Duration `value:"${queue-timeout:=0}"`    // 并发数达到上限时请求最长的排队时间，0 表示立即拒绝
+	CPUThreshold  float64       `value:"${cpu-threshold:=0}"`    // CPU 使用率阈值，取值范围为 0-100，0 表示不检测
+	CPUInterval   time.Duration `value:"${cpu-interval:=250ms}"` // CPU 使用率的采样间隔
+	RetryAfter    time.Duration `value:"${retry-after:=1s}"`     // 拒绝请求时 Retry-After 响应头的值
+	URLPatterns   []string      `value:"${url-patterns}"`        // 过滤器作用的路由，默认为全部路由
+}
+
+// windowSize 估算服务处理能力时使用的采样周期数。
+const windowSize = 10
+
+// Filter 过载保护过滤器。CPU 使用率超过阈值时根据最近一段时间内的最大吞吐量和
+// 最小响应时间估算服务的处理能力，即最大吞吐量与最小响应时间的乘积，只拒绝超出
+// 处理能力的并发请求，从而在保护服务的同时维持尽可能高的吞吐量。
+type Filter struct {
+	Metrics *metrics.Registry `autowire:"?"`
+	Sampler CPUSampler        `autowire:"?"`
+
+	config   *Config // 使用指针避免容器对其进行属性绑定
+	sem      chan struct{}
+	inflight int64
+
+	cpu    uint64 // 平滑后的 CPU 使用率，float64 的位表示
+	limit  int64  // CPU 超过阈值时允许的并发数
+	passed int64  // 当前采样周期内完成的请求数
+	rtSum  int64  // 当前采样周期内完成的请求的总耗时，单位为纳秒
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	shed    *metrics.CounterVec
+	current *metrics.GaugeVec
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	f := &Filter{config: &config, limit: 1}
+	if config.MaxConcurrent > 0 {
+		f.sem = make(chan struct{}, config.MaxConcurrent)
+	}
+	return f
+}
+
+// OnInit 注册过滤器的指标并启动 CPU 使用率的采样。
+func (f *Filter) OnInit() {
+	if f.Metrics != nil {
+		f.shed = f.Metrics.Counter("web_shed_requests_total", "Total number of requests rejected by load shedding.", "reason")
+		f.current = f.Metrics.Gauge("web_inflight_requests", "Number of requests being processed.")
+	}
+	if f.config.CPUThreshold <= 0 {
+		return
+	}
+	if f.Sampler == nil {
+		f.Sampler = newProcessSampler()
+	}
+	var ctx context.Context
+	ctx, f.cancel = context.WithCancel(context.Background())
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.sample(ctx)
+	}()
+}
+
+// OnDestroy 停止 CPU 使用率的采样。
+func (f *Filter) OnDestroy() {
+	if f.cancel != nil {
+		f.cancel()
+		f.wg.Wait()
+	}
+}
+
+// CPU 返回平滑后的 CPU 使用率。
+func (f *Filter) CPU() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.cpu))
+}
+
+// sample 定期采集 CPU 使用率并估算服务的处理能力。
+func (f *Filter) sample(ctx context.Context) {
+
+	ticker := time.NewTicker(f.config.CPUInterval)
+	defer ticker.Stop()
+
+	var (
+		rates [windowSize]float64       // 每个周期的吞吐量，单位为请求数每秒
+		rts   [windowSize]time.Duration // 每个周期的平均响应时间
+		n     int
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		const decay = 0.8
+		cpu := f.CPU()*decay + f.Sampler.CPUUsage()*(1-decay)
+		atomic.StoreUint64(&f.cpu, math.Float64bits(cpu))
+
+		passed := atomic.SwapInt64(&f.passed, 0)
+		rtSum := atomic.SwapInt64(&f.rtSum, 0)
+		i := n % windowSize
+		rates[i], rts[i] = float64(passed)/f.config.CPUInterval.Seconds(), 0
+		if passed > 0 {
+			rts[i] = time.Duration(rtSum / passed)
+		}
+		n++
+
+		var (
+			maxRate float64
+			minRT   time.Duration
+		)
+		for j := 0; j < windowSize && j < n; j++ {
+			if rates[j] > maxRate {
+				maxRate = rates[j]
+			}
+			if rts[j] > 0 && (minRT == 0 || rts[j] < minRT) {
+				minRT = rts[j]
+			}
+		}
+
+		limit := int64(math.Ceil(maxRate * minRT.Seconds()))
+		if limit < 1 {
+			limit = 1
+		}
+		atomic.StoreInt64(&f.limit, limit)
+	}
+}
+
+// URLPatterns 返回过滤器作用的路由。
+func (f *Filter) URLPatterns() []string {
+	if len(f.config.URLPatterns) == 0 {
+		return []string{"/*"}
+	}
+	return f.config.URLPatterns
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	release, reason := f.acquire(ctx.Request().Context())
+	if reason != "" {
+		f.reject(ctx.ResponseWriter(), reason)
+		return
+	}
+	defer release()
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, reason := f.acquire(r.Context())
+		if reason != "" {
+			f.reject(w, reason)
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire 为请求申请处理的资格，被拒绝时返回拒绝的原因。
+func (f *Filter) acquire(ctx context.Context) (release func(), reason string) {
+
+	if f.config.CPUThreshold > 0 && f.CPU() > f.config.CPUThreshold {
+		if atomic.LoadInt64(&f.inflight) >= atomic.LoadInt64(&f.limit) {
+			return nil, ReasonCPU
+		}
+	}
+
+	if f.sem != nil {
+		select {
+		case f.sem <- struct{}{}:
+		default:
+			if f.config.QueueTimeout <= 0 {
+				return nil, ReasonConcurrency
+			}
+			timer := time.NewTimer(f.config.QueueTimeout)
+			select {
+			case f.sem <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				return nil, ReasonQueue
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ReasonQueue
+			}
+		}
+	}
+
+	start := time.Now()
+	atomic.AddInt64(&f.inflight, 1)
+	f.gauge(1)
+	return func() {
+		atomic.AddInt64(&f.inflight, -1)
+		atomic.AddInt64(&f.passed, 1)
+		atomic.AddInt64(&f.rtSum, int64(time.Since(start)))
+		f.gauge(-1)
+		if f.sem != nil {
+			<-f.sem
+		}
+	}, ""
+}
+
+func (f *Filter) reject(w http.ResponseWriter, reason string) {
+	if f.shed != nil {
+		f.shed.With(reason).Inc()
+	}
+	if f.config.RetryAfter > 0 {
+		secs := int64(math.Ceil(f.config.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+func (f *Filter) gauge(delta float64) {
+	if f.current != nil {
+		f.current.With().Add(delta)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package overload_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/overload"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type fixedSampler float64
+
+func (s fixedSampler) CPUUsage() float64 { return float64(s) }
+
+// newHandler 返回经过过滤器的 handler ，访问 /block 的请求在后台阻塞直到 block
+// 被关闭。
+func newHandler(f *overload.Filter) (http.Handler, chan struct{}, *sync.WaitGroup) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 16)
+	var wg sync.WaitGroup
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("block") != "" {
+			started <- struct{}{}
+			<-block
+		}
+	}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			r.Header.Set("block", "true")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(httptest.NewRecorder(), r)
+			}()
+			<-started
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), block, &wg
+}
+
+func request(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestFilter_Concurrency(t *testing.T) {
+
+	f := overload.NewFilter(overload.Config{MaxConcurrent: 1, RetryAfter: 1500 * time.Millisecond})
+	f.Metrics = metrics.NewRegistry()
+	f.OnInit()
+	defer f.OnDestroy()
+
+	h, block, wg := newHandler(f)
+	request(h, "/block")
+
+	w := request(h, "/")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+	assert.Equal(t, w.Header().Get("Retry-After"), "2")
+
+	close(block)
+	wg.Wait()
+	assert.Equal(t, request(h, "/").Code, http.StatusOK)
+
+	var shed float64
+	for _, fam := range f.Metrics.Gather() {
+		if fam.Name == "web_shed_requests_total" {
+			shed = fam.Samples[0].Value
+			assert.Equal(t, fam.Samples[0].Values, []string{overload.ReasonConcurrency})
+		}
+	}
+	assert.Equal(t, shed, float64(1))
+}
+
+func TestFilter_Queue(t *testing.T) {
+
+	f := overload.NewFilter(overload.Config{MaxConcurrent: 1, QueueTimeout: 20 * time.Millisecond})
+	f.OnInit()
+	defer f.OnDestroy()
+
+	h, block, wg := newHandler(f)
+	request(h, "/block")
+
+	start := time.Now()
+	assert.Equal(t, request(h, "/").Code, http.StatusTooManyRequests)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	// 排队期间释放的资格可以被等待的请求获得
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		close(block)
+	}()
+	assert.Equal(t, request(h, "/").Code, http.StatusOK)
+	wg.Wait()
+}
+
+func TestFilter_CPU(t *testing.T) {
+
+	f := overload.NewFilter(overload.Config{CPUThreshold: 80, CPUInterval: time.Millisecond})
+	f.Sampler = fixedSampler(100)
+	f.OnInit()
+	defer f.OnDestroy()
+
+	for f.CPU() <= 80 {
+		time.Sleep(time.Millisecond)
+	}
+
+	h, block, wg := newHandler(f)
+	request(h, "/block")
+	assert.Equal(t, request(h, "/").Code, http.StatusTooManyRequests)
+	close(block)
+	wg.Wait()
+	assert.Equal(t, request(h, "/").Code, http.StatusOK)
+}
+
+func TestConfig_Bind(t *testing.T) {
+	p := conf.New()
+	p.Set("web.overload.url-patterns[0]", "/api/*")
+	var c overload.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("web.overload")))
+	assert.Equal(t, c.URLPatterns, []string{"/api/*"})
+	assert.Equal(t, overload.NewFilter(c).URLPatterns(), []string{"/api/*"})
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ratelimit 提供了基于 Redis Lua 脚本的分布式限流器，包括滑动窗口和
+// 令牌桶两种算法。限流器使用调用方的时钟，多个实例之间的时钟偏差会影响限流的
+// 精度。
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+)
+
+// Result 限流的结果。
+type Result struct {
+	Allowed    bool          // 是否允许通过
+	Remaining  int64         // 剩余的配额
+	RetryAfter time.Duration // 不允许通过时建议的重试间隔
+}
+
+// Limiter 限流器接口。
+type Limiter interface {
+
+	// Allow 判断 key 对应的一次请求是否允许通过。
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+func result(reply interface{}, err error) (Result, error) {
+	if err != nil {
+		return Result{}, err
+	}
+	arr, err := redis.Int64s(reply)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(arr) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", arr)
+	}
+	return Result{
+		Allowed:    arr[0] == 1,
+		Remaining:  arr[1],
+		RetryAfter: time.Duration(arr[2]) * time.Millisecond,
+	}, nil
+}
+
+func millis(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// slidingWindowScript 使用有序集合记录窗口内的请求，返回 {是否通过, 剩余配额, 重试间隔毫秒}。
+const slidingWindowScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`
+
+// SlidingWindowConfig 滑动窗口限流器的配置。
+type SlidingWindowConfig struct {
+	Limit     int64         `value:"${limit:=100}"`                    // 窗口内允许通过的请求数
+	Window    time.Duration `value:"${window:=1s}"`                    // 窗口的长度
+	KeyPrefix string        `value:"${key-prefix:=ratelimit:window:}"` // Redis key 的前缀
+}
+
+// SlidingWindow 基于滑动窗口日志的限流器，任意 Window 长度的时间内最多允许 Limit
+// 个请求通过。
+type SlidingWindow struct {
+	scripts *redis.Scripts
+	script  *redis.Script
+	config  *SlidingWindowConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewSlidingWindow SlidingWindow 的构造函数。
+func NewSlidingWindow(scripts *redis.Scripts, config SlidingWindowConfig) *SlidingWindow {
+	return &SlidingWindow{
+		scripts: scripts,
+		script:  scripts.Register("ratelimit.sliding-window", slidingWindowScript),
+		config:  &config,
+	}
+}
+
+// Allow 判断 key 对应的一次请求是否允许通过。
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	now := time.Now()
+	window := strconv.FormatInt(int64(l.config.Window/time.Millisecond), 10)
+	member := millis(now) + "-" + hex.EncodeToString(b[:])
+	return result(l.scripts.Exec(ctx, l.script, []string{l.config.KeyPrefix + key},
+		millis(now), window, l.config.Limit, member))
+}
+
+// tokenBucketScript 使用哈希表记录令牌数和上次更新时间，返回 {是否通过, 剩余令牌数, 重试间隔毫秒}。
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate))
+return {allowed, math.floor(tokens), wait}
+`
+
+// TokenBucketConfig 令牌桶限流器的配置。
+type TokenBucketConfig struct {
+	Rate      float64 `value:"${rate:=10}"`                      // 每秒生成的令牌数
+	Burst     int64   `value:"${burst:=20}"`                     // 桶的容量，即允许的突发请求数
+	KeyPrefix string  `value:"${key-prefix:=ratelimit:bucket:}"` // Redis key 的前缀
+}
+
+// TokenBucket 基于令牌桶的限流器，平均每秒允许 Rate 个请求通过，最多允许 Burst
+// 个突发请求。
+type TokenBucket struct {
+	scripts *redis.Scripts
+	script  *redis.Script
+	config  *TokenBucketConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewTokenBucket TokenBucket 的构造函数。
+func NewTokenBucket(scripts *redis.Scripts, config TokenBucketConfig) *TokenBucket {
+	return &TokenBucket{
+		scripts: scripts,
+		script:  scripts.Register("ratelimit.token-bucket", tokenBucketScript),
+		config:  &config,
+	}
+}
+
+// Allow 判断 key 对应的一次请求是否允许通过。
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	rate := strconv.FormatFloat(l.config.Rate/1000, 'f', -1, 64) // 每毫秒生成的令牌数
+	return result(l.scripts.Exec(ctx, l.script, []string{l.config.KeyPrefix + key},
+		rate, l.config.Burst, millis(time.Now())))
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/ratelimit"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// scriptClient 记录脚本的参数并返回预先设置的结果。
+type scriptClient struct {
+	redis.Client
+	keys  []string
+	args  []interface{}
+	reply interface{}
+}
+
+func (c *scriptClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.EvalSha(ctx, "", keys, args...)
+}
+
+func (c *scriptClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	c.keys, c.args = keys, args
+	return c.reply, nil
+}
+
+func (c *scriptClient) ScriptLoad(ctx context.Context, script string) (string, error) {
+	return redis.NewScript(script).Hash(), nil
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	c := &scriptClient{reply: []interface{}{int64(1), int64(4), int64(0)}}
+	var l ratelimit.Limiter = ratelimit.NewSlidingWindow(redis.NewScripts(c), ratelimit.SlidingWindowConfig{
+		Limit: 5, Window: time.Second, KeyPrefix: "rl:",
+	})
+
+	r, err := l.Allow(ctx, "user:1")
+	assert.Nil(t, err)
+	assert.Equal(t, r, ratelimit.Result{Allowed: true, Remaining: 4})
+	assert.Equal(t, c.keys, []string{"rl:user:1"})
+	assert.Equal(t, c.args[1:3], []interface{}{"1000", int64(5)})
+
+	c.reply = []interface{}{int64(0), int64(0), int64(250)}
+	r, err = l.Allow(ctx, "user:1")
+	assert.Nil(t, err)
+	assert.Equal(t, r, ratelimit.Result{RetryAfter: 250 * time.Millisecond})
+
+	c.reply = []interface{}{int64(0)}
+	_, err = l.Allow(ctx, "user:1")
+	assert.Error(t, err, "unexpected reply \\[0\\]")
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	c := &scriptClient{reply: []interface{}{int64(1), int64(19), int64(0)}}
+	var l ratelimit.Limiter = ratelimit.NewTokenBucket(redis.NewScripts(c), ratelimit.TokenBucketConfig{
+		Rate: 10, Burst: 20, KeyPrefix: "tb:",
+	})
+
+	r, err := l.Allow(ctx, "api")
+	assert.Nil(t, err)
+	assert.Equal(t, r, ratelimit.Result{Allowed: true, Remaining: 19})
+	assert.Equal(t, c.keys, []string{"tb:api"})
+	assert.Equal(t, c.args[:2], []interface{}{"0.01", int64(20)})
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package readiness 提供了应用的就绪门控，bean 可以注册缓存预热、连接检查等预热
+// 任务，所有任务完成之前 readiness 健康检查返回 OUT_OF_SERVICE 并且不会注册服务
+// 实例，超过限定时间仍未完成时按照超时策略处理。
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/log"
+)
+
+// 预热超时后的处理策略。
+const (
+	PolicyUp   = "up"   // 忽略未完成的任务，应用变为就绪状态
+	PolicyDown = "down" // 应用保持不可用状态
+)
+
+// Warmup 预热任务，导出为该接口的 bean 以 bean 名称作为任务名称。
+type Warmup interface {
+	Warmup(ctx context.Context) error
+}
+
+// WarmupFunc 函数形式的 Warmup 。
+type WarmupFunc func(ctx context.Context) error
+
+func (f WarmupFunc) Warmup(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Config 就绪门控配置，通常绑定到 readiness 前缀的属性上。
+type Config struct {
+	Timeout       time.Duration `value:"${timeout:=1m}"`          // 等待预热任务完成的最长时间，0 表示一直等待
+	TimeoutPolicy string        `value:"${timeout-policy:=down}"` // 预热超时后的处理策略
+}
+
+// Gate 就绪门控，在应用启动后并发执行所有的预热任务，任务全部成功后应用变为就
+// 绪状态。Gate 同时是 readiness 分组的健康检查指示器。
+type Gate struct {
+	Warmups map[string]Warmup `autowire:""`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+	done   chan struct{}
+	start  sync.Once
+
+	mutex   sync.Mutex
+	tasks   map[string]Warmup
+	pending map[string]struct{}
+	status  health.Status
+	errs    map[string]interface{}
+}
+
+// NewGate Gate 的构造函数。
+func NewGate(config Config) (*Gate, error) {
+	switch config.TimeoutPolicy {
+	case PolicyUp, PolicyDown:
+	default:
+		return nil, fmt.Errorf("readiness: unknown timeout policy %q", config.TimeoutPolicy)
+	}
+	return &Gate{
+		config: &config,
+		done:   make(chan struct{}),
+		tasks:  make(map[string]Warmup),
+		status: health.StatusOutOfService,
+	}, nil
+}
+
+// Register 注册预热任务，需要在应用启动之前调用，例如在 bean 的初始化函数中。
+func (g *Gate) Register(name string, fn WarmupFunc) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.tasks[name] = fn
+}
+
+// Run 并发执行所有的预热任务并等待其完成、超时或者 ctx 结束，只有第一次调用有效。
+func (g *Gate) Run(ctx context.Context) {
+	g.start.Do(func() { g.run(ctx) })
+}
+
+func (g *Gate) run(ctx context.Context) {
+
+	g.mutex.Lock()
+	tasks := make(map[string]Warmup, len(g.Warmups)+len(g.tasks))
+	for name, w := range g.Warmups {
+		tasks[name] = w
+	}
+	for name, w := range g.tasks {
+		tasks[name] = w
+	}
+	g.pending = make(map[string]struct{}, len(tasks))
+	for name := range tasks {
+		g.pending[name] = struct{}{}
+	}
+	g.mutex.Unlock()
+
+	if g.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.config.Timeout)
+		defer cancel()
+	}
+
+	ch := make(chan struct{})
+	var wg sync.WaitGroup
+	for name, w := range tasks {
+		wg.Add(1)
+		go func(name string, w Warmup) {
+			defer wg.Done()
+			g.finish(name, g.warmup(ctx, name, w))
+		}(name, w)
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	select {
+	case <-ch:
+		g.complete(false)
+	case <-ctx.Done():
+		g.complete(true)
+	}
+}
+
+// warmup 执行单个预热任务，任务中的 panic 被当作错误返回。
+func (g *Gate) warmup(ctx context.Context, name string, w Warmup) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("readiness: warmup %s panic: %v\n%s", name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	start := time.Now()
+	if err = w.Warmup(ctx); err == nil {
+		log.Infof("readiness: warmup %s completed in %s", name, time.Since(start))
+	}
+	return err
+}
+
+func (g *Gate) finish(name string, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.pending, name)
+	if err != nil {
+		log.Errorf("readiness: warmup %s failed: %v", name, err)
+		if g.errs == nil {
+			g.errs = make(map[string]interface{})
+		}
+		g.errs[name] = err.Error()
+	}
+}
+
+// complete 根据预热任务的结果确定应用的状态，timeout 表示存在未完成的任务。
+func (g *Gate) complete(timeout bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	switch {
+	case len(g.errs) > 0:
+		g.status = health.StatusDown
+	case timeout && len(g.pending) > 0:
+		log.Warnf("readiness: warmup %v didn't complete in time", g.pendingNames())
+		if g.config.TimeoutPolicy == PolicyUp {
+			g.status = health.StatusUp
+		} else {
+			g.status = health.StatusDown
+		}
+	default:
+		g.status = health.StatusUp
+	}
+	if g.status == health.StatusUp {
+		log.Info("readiness: application is ready")
+	}
+	close(g.done)
+}
+
+func (g *Gate) pendingNames() []string {
+	names := make([]string, 0, len(g.pending))
+	for name := range g.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Done 返回在预热结束 (完成、失败或者超时) 时关闭的通道。
+func (g *Gate) Done() <-chan struct{} {
+	return g.done
+}
+
+// Ready 返回应用是否已经就绪。
+func (g *Gate) Ready() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.status == health.StatusUp
+}
+
+// Health 预热结束之前返回 OUT_OF_SERVICE ，失败或者超时时返回 DOWN 。
+func (g *Gate) Health(ctx context.Context) health.Health {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	h := health.Health{Status: g.status}
+	if len(g.pending) > 0 {
+		h.Details = map[string]interface{}{"pending": g.pendingNames()}
+	}
+	if len(g.errs) > 0 {
+		if h.Details == nil {
+			h.Details = make(map[string]interface{})
+		}
+		errs := make(map[string]interface{}, len(g.errs))
+		for name, err := range g.errs {
+			errs[name] = err
+		}
+		h.Details["errors"] = errs
+	}
+	return h
+}
+
+// Groups 门控只属于 readiness 分组，预热期间应用仍然是存活的。
+func (g *Gate) Groups() []string {
+	return []string{health.GroupReadiness}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package readiness_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/health"
+	"github.com/go-spring/spring-core/readiness"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func newGate(t *testing.T, config readiness.Config) *readiness.Gate {
+	g, err := readiness.NewGate(config)
+	assert.Nil(t, err)
+	return g
+}
+
+func TestNewGate(t *testing.T) {
+	_, err := readiness.NewGate(readiness.Config{TimeoutPolicy: "crash"})
+	assert.Error(t, err, "readiness: unknown timeout policy \"crash\"")
+}
+
+func TestGate(t *testing.T) {
+
+	t.Run("ready", func(t *testing.T) {
+		g := newGate(t, readiness.Config{Timeout: time.Second, TimeoutPolicy: readiness.PolicyDown})
+		ch := make(chan struct{})
+		g.Warmups = map[string]readiness.Warmup{
+			"cache": readiness.WarmupFunc(func(ctx context.Context) error {
+				<-ch
+				return nil
+			}),
+		}
+		g.Register("conn", func(ctx context.Context) error { return nil })
+		go g.Run(context.Background())
+
+		time.Sleep(10 * time.Millisecond)
+		h := g.Health(context.Background())
+		assert.Equal(t, h.Status, health.StatusOutOfService)
+		assert.Equal(t, h.Details["pending"], []string{"cache"})
+		assert.False(t, g.Ready())
+
+		close(ch)
+		<-g.Done()
+		assert.True(t, g.Ready())
+		assert.Equal(t, g.Health(context.Background()), health.Up())
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		g := newGate(t, readiness.Config{TimeoutPolicy: readiness.PolicyUp})
+		g.Register("conn", func(ctx context.Context) error { return errors.New("refused") })
+		g.Register("panic", func(ctx context.Context) error { panic("boom") })
+		g.Run(context.Background())
+		assert.False(t, g.Ready())
+		h := g.Health(context.Background())
+		assert.Equal(t, h.Status, health.StatusDown)
+		assert.Equal(t, h.Details["errors"], map[string]interface{}{
+			"conn":  "refused",
+			"panic": "panic: boom",
+		})
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		for policy, status := range map[string]health.Status{
+			readiness.PolicyUp:   health.StatusUp,
+			readiness.PolicyDown: health.StatusDown,
+		} {
+			g := newGate(t, readiness.Config{Timeout: 10 * time.Millisecond, TimeoutPolicy: policy})
+			g.Register("slow", func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			})
+			g.Run(context.Background())
+			h := g.Health(context.Background())
+			assert.Equal(t, h.Status, status)
+			assert.Equal(t, h.Details["pending"], []string{"slow"})
+		}
+	})
+}
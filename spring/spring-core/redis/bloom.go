@@ -0,0 +1,115 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Doer 执行任意命令的接口，是 Redis 客户端可选实现的接口，用于执行 Client 没有
+// 包含的命令以及模块提供的命令。
+type Doer interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// BloomConfig 布隆过滤器的配置。
+type BloomConfig struct {
+	ErrorRate float64 `value:"${error-rate:=0.01}"`  // 创建过滤器时使用的误判率
+	Capacity  int64   `value:"${capacity:=1000000}"` // 创建过滤器时使用的容量
+}
+
+// Bloom 基于 RedisBloom 模块的布隆过滤器，需要 Redis 客户端实现 Doer 接口。
+type Bloom struct {
+	client Client
+	config *BloomConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewBloom Bloom 的构造函数。
+func NewBloom(client Client, config BloomConfig) *Bloom {
+	return &Bloom{client: client, config: &config}
+}
+
+func (b *Bloom) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	c, ok := b.client.(Doer)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return c.Do(ctx, args...)
+}
+
+// Reserve 按照配置的误判率和容量创建名为 key 的过滤器，过滤器已经存在时不做任何
+// 操作。不调用 Reserve 时 Add 使用服务器默认的参数创建过滤器。
+func (b *Bloom) Reserve(ctx context.Context, key string) error {
+	_, err := b.do(ctx, "BF.RESERVE", key, b.config.ErrorRate, b.config.Capacity)
+	if err != nil && strings.Contains(err.Error(), "exists") {
+		return nil
+	}
+	return err
+}
+
+// Add 添加元素，元素之前可能不存在时返回 true 。
+func (b *Bloom) Add(ctx context.Context, key string, item string) (bool, error) {
+	r, err := b.do(ctx, "BF.ADD", key, item)
+	if err != nil {
+		return false, err
+	}
+	return r == int64(1), nil
+}
+
+// Exists 判断元素是否可能存在，返回 false 时元素一定不存在。
+func (b *Bloom) Exists(ctx context.Context, key string, item string) (bool, error) {
+	r, err := b.do(ctx, "BF.EXISTS", key, item)
+	if err != nil {
+		return false, err
+	}
+	return r == int64(1), nil
+}
+
+// MAdd 批量添加元素。
+func (b *Bloom) MAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return b.multi(ctx, "BF.MADD", key, items)
+}
+
+// MExists 批量判断元素是否可能存在。
+func (b *Bloom) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return b.multi(ctx, "BF.MEXISTS", key, items)
+}
+
+func (b *Bloom) multi(ctx context.Context, cmd string, key string, items []string) ([]bool, error) {
+	args := []interface{}{cmd, key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	r, err := b.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	arr, err := Int64s(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(arr) != len(items) {
+		return nil, fmt.Errorf("redis: %s returned %d results for %d items", cmd, len(arr), len(items))
+	}
+	ret := make([]bool, len(arr))
+	for i, n := range arr {
+		ret[i] = n == 1
+	}
+	return ret, nil
+}
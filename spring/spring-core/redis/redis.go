@@ -14,4 +14,111 @@
  * limitations under the License.
  */
 
+// Package redis 提供了标准的 Redis 操作接口，可以灵活适配各种 Redis 客户端，
+// 同时提供了基于泛型的类型化辅助函数。
 package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spring/spring-stl/json"
+)
+
+// ErrNil key 不存在时返回的错误。
+var ErrNil = errors.New("redis: nil")
+
+// Client Redis 客户端接口，只包含最常用的命令，其他命令请直接使用底层客户端。
+type Client interface {
+
+	// Ping 检查与服务器的连接是否正常。
+	Ping(ctx context.Context) error
+
+	// Get 获取 key 对应的值，key 不存在时返回 ErrNil 。
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set 设置 key 对应的值，expiration 为 0 时表示永不过期。
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+
+	// SetNX 只在 key 不存在时设置 key 对应的值，设置成功返回 true 。
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+
+	// Del 删除 key，返回实际删除的 key 的数量。
+	Del(ctx context.Context, keys ...string) (int64, error)
+
+	// Exists 返回存在的 key 的数量。
+	Exists(ctx context.Context, keys ...string) (int64, error)
+
+	// Expire 设置 key 的过期时间，key 不存在时返回 false 。
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+
+	// Incr 将 key 对应的整数值加一并返回加一之后的结果。
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// PubSub 发布订阅接口，是 Redis 客户端可选实现的接口，缓存等组件通过它在多个
+// 实例之间广播消息。
+type PubSub interface {
+
+	// Publish 向 channel 发布消息。
+	Publish(ctx context.Context, channel string, message string) error
+
+	// Subscribe 订阅 channel 上的消息，每条消息都会在同一个 goroutine 中调用 fn ，
+	// 调用返回的函数取消订阅。
+	Subscribe(ctx context.Context, channel string, fn func(message string)) (func(), error)
+}
+
+// Codec 类型化辅助函数使用的序列化接口。
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// JSON 基于 json 的序列化实现。
+var JSON Codec = jsonCodec{}
+
+// DefaultCodec 类型化辅助函数默认使用的序列化实现。
+var DefaultCodec = JSON
+
+// Get 获取 key 对应的值并反序列化为 T 类型，key 不存在时返回 ErrNil 。
+func Get[T any](ctx context.Context, c Client, key string) (T, error) {
+	var v T
+	s, err := c.Get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	err = DefaultCodec.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+// Set 将 T 类型的值序列化后保存到 key 中，expiration 为 0 时表示永不过期。
+func Set[T any](ctx context.Context, c Client, key string, v T, expiration time.Duration) error {
+	b, err := DefaultCodec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, string(b), expiration)
+}
+
+// GetOrLoad 获取 key 对应的值，key 不存在时调用 loader 获取值并回写到 key 中。
+func GetOrLoad[T any](ctx context.Context, c Client, key string, expiration time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	v, err := Get[T](ctx, c, key)
+	if err == nil || !errors.Is(err, ErrNil) {
+		return v, err
+	}
+	if v, err = loader(ctx); err != nil {
+		return v, err
+	}
+	return v, Set(ctx, c, key, v, expiration)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type mapClient struct {
+	m map[string]string
+}
+
+func newMapClient() *mapClient {
+	return &mapClient{m: make(map[string]string)}
+}
+
+func (c *mapClient) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := c.m[key]; ok {
+		return v, nil
+	}
+	return "", redis.ErrNil
+}
+
+func (c *mapClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.m[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *mapClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if _, ok := c.m[key]; ok {
+		return false, nil
+	}
+	c.m[key] = fmt.Sprint(value)
+	return true, nil
+}
+
+func (c *mapClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	var n int64
+	for _, k := range keys {
+		if _, ok := c.m[k]; ok {
+			delete(c.m, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *mapClient) Exists(ctx context.Context, keys ...string) (int64, error) {
+	var n int64
+	for _, k := range keys {
+		if _, ok := c.m[k]; ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *mapClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	_, ok := c.m[key]
+	return ok, nil
+}
+
+func (c *mapClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *mapClient) Incr(ctx context.Context, key string) (int64, error) {
+	return 0, nil
+}
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestTyped(t *testing.T) {
+	ctx := context.Background()
+	c := newMapClient()
+
+	_, err := redis.Get[user](ctx, c, "user:1")
+	assert.Equal(t, err, redis.ErrNil)
+
+	err = redis.Set(ctx, c, "user:1", user{Name: "jim", Age: 3}, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, c.m["user:1"], `{"name":"jim","age":3}`)
+
+	u, err := redis.Get[user](ctx, c, "user:1")
+	assert.Nil(t, err)
+	assert.Equal(t, u, user{Name: "jim", Age: 3})
+
+	count := 0
+	loader := func(ctx context.Context) ([]int, error) {
+		count++
+		return []int{1, 2, 3}, nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := redis.GetOrLoad(ctx, c, "ints", time.Minute, loader)
+		assert.Nil(t, err)
+		assert.Equal(t, v, []int{1, 2, 3})
+	}
+	assert.Equal(t, count, 1)
+}
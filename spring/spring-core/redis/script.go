@@ -0,0 +1,153 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrNoScript EVALSHA 指定的脚本不在服务器的脚本缓存中时返回的错误。
+	ErrNoScript = errors.New("redis: NOSCRIPT")
+
+	// ErrNotSupported Redis 客户端没有实现所需的可选接口时返回的错误。
+	ErrNotSupported = errors.New("redis: command not supported by client")
+)
+
+// Scripter 执行 Lua 脚本的接口，是 Redis 客户端可选实现的接口。
+type Scripter interface {
+
+	// Eval 执行脚本 script 。
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+
+	// EvalSha 执行摘要为 sha1 的脚本，脚本不在服务器的脚本缓存中时返回 ErrNoScript 。
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error)
+
+	// ScriptLoad 将脚本加载到服务器的脚本缓存中，返回脚本的摘要。
+	ScriptLoad(ctx context.Context, script string) (string, error)
+}
+
+// Script 可以通过 EVALSHA 执行的 Lua 脚本。
+type Script struct {
+	src    string
+	sha    string
+	loaded int32
+}
+
+// NewScript 创建 Lua 脚本。
+func NewScript(src string) *Script {
+	h := sha1.Sum([]byte(src))
+	return &Script{src: src, sha: hex.EncodeToString(h[:])}
+}
+
+// Source 返回脚本的源码。
+func (s *Script) Source() string {
+	return s.src
+}
+
+// Hash 返回脚本的 SHA1 摘要。
+func (s *Script) Hash() string {
+	return s.sha
+}
+
+// Run 执行脚本。第一次执行时先通过 SCRIPT LOAD 加载脚本，之后通过 EVALSHA 执行，
+// 服务器重启或者清空脚本缓存导致 EVALSHA 失败时通过 EVAL 执行并在下次执行时重新
+// 加载脚本。
+func (s *Script) Run(ctx context.Context, c Scripter, keys []string, args ...interface{}) (interface{}, error) {
+	if atomic.LoadInt32(&s.loaded) == 0 {
+		if _, err := c.ScriptLoad(ctx, s.src); err != nil {
+			return nil, err
+		}
+		atomic.StoreInt32(&s.loaded, 1)
+	}
+	r, err := c.EvalSha(ctx, s.sha, keys, args...)
+	if !errors.Is(err, ErrNoScript) {
+		return r, err
+	}
+	atomic.StoreInt32(&s.loaded, 0)
+	return c.Eval(ctx, s.src, keys, args...)
+}
+
+// Scripts 按照名称管理 Lua 脚本。
+type Scripts struct {
+	client  Client
+	mutex   sync.RWMutex
+	scripts map[string]*Script
+}
+
+// NewScripts 创建脚本注册表，client 需要实现 Scripter 接口才能执行脚本。
+func NewScripts(client Client) *Scripts {
+	return &Scripts{client: client, scripts: make(map[string]*Script)}
+}
+
+// Register 注册名为 name 的脚本，同名的脚本会被覆盖。
+func (s *Scripts) Register(name, src string) *Script {
+	script := NewScript(src)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.scripts[name] = script
+	return script
+}
+
+// Script 返回名为 name 的脚本。
+func (s *Scripts) Script(name string) (*Script, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	script, ok := s.scripts[name]
+	return script, ok
+}
+
+// Exec 执行脚本 script ，script 不需要注册。
+func (s *Scripts) Exec(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	c, ok := s.client.(Scripter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return script.Run(ctx, c, keys, args...)
+}
+
+// Run 执行名为 name 的脚本。
+func (s *Scripts) Run(ctx context.Context, name string, keys []string, args ...interface{}) (interface{}, error) {
+	script, ok := s.Script(name)
+	if !ok {
+		return nil, fmt.Errorf("redis: script %q not registered", name)
+	}
+	return s.Exec(ctx, script, keys, args...)
+}
+
+// Int64s 将脚本返回的整数数组转换为 []int64 。
+func Int64s(reply interface{}) ([]int64, error) {
+	arr, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply type %T", reply)
+	}
+	ret := make([]int64, len(arr))
+	for i, v := range arr {
+		n, ok := v.(int64)
+		if !ok {
+			return nil, fmt.Errorf("redis: unexpected reply element type %T", v)
+		}
+		ret[i] = n
+	}
+	return ret, nil
+}
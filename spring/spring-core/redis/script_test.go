@@ -0,0 +1,134 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// scriptClient 记录执行的命令，模拟服务器的脚本缓存。
+type scriptClient struct {
+	redis.Client
+	cache map[string]bool
+	calls []string
+	reply interface{}
+	err   error
+}
+
+func newScriptClient() *scriptClient {
+	return &scriptClient{cache: make(map[string]bool), reply: int64(1)}
+}
+
+func (c *scriptClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	c.calls = append(c.calls, "EVAL")
+	return c.reply, nil
+}
+
+func (c *scriptClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	c.calls = append(c.calls, "EVALSHA")
+	if !c.cache[sha1] {
+		return nil, redis.ErrNoScript
+	}
+	return c.reply, nil
+}
+
+func (c *scriptClient) ScriptLoad(ctx context.Context, script string) (string, error) {
+	c.calls = append(c.calls, "SCRIPT LOAD")
+	s := redis.NewScript(script)
+	c.cache[s.Hash()] = true
+	return s.Hash(), nil
+}
+
+func (c *scriptClient) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	c.calls = append(c.calls, strings.TrimSpace(fmt.Sprintln(args...)))
+	return c.reply, c.err
+}
+
+func TestScripts(t *testing.T) {
+	ctx := context.Background()
+	c := newScriptClient()
+	s := redis.NewScripts(c)
+
+	script := s.Register("incr", "return redis.call('INCR', KEYS[1])")
+	assert.Equal(t, len(script.Hash()), 40)
+
+	for i := 0; i < 2; i++ {
+		r, err := s.Run(ctx, "incr", []string{"n"})
+		assert.Nil(t, err)
+		assert.Equal(t, r, int64(1))
+	}
+	assert.Equal(t, c.calls, []string{"SCRIPT LOAD", "EVALSHA", "EVALSHA"})
+
+	// 服务器清空脚本缓存之后通过 EVAL 执行，下次执行时重新加载
+	c.calls, c.cache = nil, make(map[string]bool)
+	_, err := s.Run(ctx, "incr", []string{"n"})
+	assert.Nil(t, err)
+	_, err = s.Run(ctx, "incr", []string{"n"})
+	assert.Nil(t, err)
+	assert.Equal(t, c.calls, []string{"EVALSHA", "EVAL", "SCRIPT LOAD", "EVALSHA"})
+
+	_, err = s.Run(ctx, "decr", nil)
+	assert.Error(t, err, "script \"decr\" not registered")
+
+	s = redis.NewScripts(newMapClient())
+	s.Register("incr", "return 1")
+	_, err = s.Run(ctx, "incr", nil)
+	assert.Equal(t, err, redis.ErrNotSupported)
+}
+
+func TestBloom(t *testing.T) {
+	ctx := context.Background()
+	c := newScriptClient()
+	b := redis.NewBloom(c, redis.BloomConfig{ErrorRate: 0.01, Capacity: 1000})
+
+	assert.Nil(t, b.Reserve(ctx, "users"))
+	ok, err := b.Add(ctx, "users", "tom")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = b.Exists(ctx, "users", "tom")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	c.reply = []interface{}{int64(1), int64(0)}
+	r, err := b.MExists(ctx, "users", "tom", "jerry")
+	assert.Nil(t, err)
+	assert.Equal(t, r, []bool{true, false})
+	_, err = b.MAdd(ctx, "users", "spike")
+	assert.Error(t, err, "BF.MADD returned 2 results for 1 items")
+
+	assert.Equal(t, c.calls, []string{
+		"BF.RESERVE users 0.01 1000",
+		"BF.ADD users tom",
+		"BF.EXISTS users tom",
+		"BF.MEXISTS users tom jerry",
+		"BF.MADD users spike",
+	})
+
+	c.err = errors.New("ERR item exists")
+	assert.Nil(t, b.Reserve(ctx, "users"))
+
+	b = redis.NewBloom(newMapClient(), redis.BloomConfig{})
+	_, err = b.Add(ctx, "users", "tom")
+	assert.Equal(t, err, redis.ErrNotSupported)
+}
@@ -0,0 +1,410 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package repo 提供了基于泛型的轻量级仓储，根据结构体的 db 标签生成常用的增删改查
+// 语句，ctx 绑定了事务 (参见 db.WithTx) 时在事务中执行。
+//
+// 字段的 db 标签格式为 "列名,选项"，列名为空时使用字段名的蛇形命名，"-" 表示忽略
+// 该字段，选项 pk 表示主键，auto 表示主键由数据库自动生成。没有字段标记为主键时
+// 使用名为 ID 的字段作为主键。表名默认为类型名的蛇形命名，可以通过实现 Table 接口
+// 指定。
+//
+//	type User struct {
+//		ID        int64     `db:"id,pk,auto"`
+//		Name      string    `db:"name"`
+//		CreatedAt time.Time `db:"created_at"`
+//	}
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-spring/spring-core/db"
+)
+
+// ErrNotFound 记录不存在时返回的错误。
+var ErrNotFound = errors.New("repo: record not found")
+
+// DataSource 执行 SQL 语句的数据源，*sql.DB 、*sql.Tx 和 *db.Router 都实现了
+// 该接口。
+type DataSource interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Table 可以由实体实现的可选接口，返回实体对应的表名。
+type Table interface {
+	TableName() string
+}
+
+// Option 仓储的选项。
+type Option func(*options)
+
+type options struct {
+	table   string
+	dialect string
+}
+
+// WithTable 指定表名。
+func WithTable(table string) Option {
+	return func(o *options) { o.table = table }
+}
+
+// WithDialect 指定数据库方言，决定 SQL 占位符的格式以及自动生成主键的获取方式，
+// postgres 使用 $1 格式的占位符和 RETURNING 子句，其他数据库使用 ? 格式的占位符
+// 和 LastInsertId 。
+func WithDialect(dialect string) Option {
+	return func(o *options) { o.dialect = dialect }
+}
+
+type column struct {
+	name  string
+	index []int
+}
+
+// Query 分页查询的条件。
+type Query struct {
+	Where   string        // 查询条件，例如 "age > ? AND status = ?"，为空时查询全部记录
+	Args    []interface{} // 查询条件的参数
+	OrderBy string        // 排序方式，例如 "id DESC"
+	Page    int           // 页码，从 1 开始
+	Size    int           // 每页的记录数，小于等于 0 时不分页
+}
+
+// Page 分页查询的结果。
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Size  int `json:"size"`
+}
+
+// Repository 实体 T 的仓储，ID 为主键的类型。
+type Repository[T any, ID any] struct {
+	source  DataSource
+	table   string
+	dialect string
+	pk      column
+	auto    bool
+	columns []column // 包括主键在内的所有列
+}
+
+// New 创建实体 T 的仓储，T 必须是结构体类型。
+func New[T any, ID any](ds DataSource, opts ...Option) *Repository[T, ID] {
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("repo: %s should be a struct", t))
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.table == "" {
+		if v, ok := reflect.New(t).Interface().(Table); ok {
+			o.table = v.TableName()
+		} else {
+			o.table = snakeCase(t.Name())
+		}
+	}
+
+	r := &Repository[T, ID]{source: ds, table: o.table, dialect: o.dialect}
+	var byName *column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("db"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		c := column{name: tag[0], index: f.Index}
+		if c.name == "" {
+			c.name = snakeCase(f.Name)
+		}
+		for _, s := range tag[1:] {
+			switch s {
+			case "pk":
+				r.pk = c
+			case "auto":
+				r.auto = true
+			}
+		}
+		if f.Name == "ID" {
+			byName = &c
+		}
+		r.columns = append(r.columns, c)
+	}
+
+	if r.pk.name == "" {
+		if byName == nil {
+			panic(fmt.Errorf("repo: %s has no primary key", t))
+		}
+		r.pk = *byName
+	}
+	return r
+}
+
+// snakeCase 返回蛇形命名，例如 UserID 返回 user_id 。
+func snakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, c := range runes {
+		if unicode.IsUpper(c) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// ds 返回执行语句的数据源，ctx 绑定了事务时返回该事务。
+func (r *Repository[T, ID]) ds(ctx context.Context) DataSource {
+	if tx, ok := db.TxFrom(ctx); ok {
+		return tx
+	}
+	return r.source
+}
+
+// placeholder 返回第 n 个 SQL 占位符，n 从 1 开始。
+func (r *Repository[T, ID]) placeholder(n int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (r *Repository[T, ID]) columnNames() string {
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// scan 将查询结果的所有行转换为实体。
+func (r *Repository[T, ID]) scan(rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+	var ret []T
+	for rows.Next() {
+		var e T
+		v := reflect.ValueOf(&e).Elem()
+		dest := make([]interface{}, len(r.columns))
+		for i, c := range r.columns {
+			dest[i] = v.FieldByIndex(c.index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, rows.Err()
+}
+
+// Get 返回主键为 id 的实体，不存在时返回 ErrNotFound 。
+func (r *Repository[T, ID]) Get(ctx context.Context, id ID) (*T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		r.columnNames(), r.table, r.pk.name, r.placeholder(1))
+	rows, err := r.ds(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := r.scan(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return nil, ErrNotFound
+	}
+	return &ret[0], nil
+}
+
+// List 返回符合条件的实体，q.Size 大于 0 时分页查询并返回总记录数。
+func (r *Repository[T, ID]) List(ctx context.Context, q Query) (*Page[T], error) {
+
+	where := ""
+	if q.Where != "" {
+		where = " WHERE " + q.Where
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s", r.columnNames(), r.table, where)
+	if q.OrderBy != "" {
+		query += " ORDER BY " + q.OrderBy
+	}
+
+	page := &Page[T]{Page: q.Page, Size: q.Size}
+	if q.Size > 0 {
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", q.Size, (page.Page-1)*q.Size)
+	}
+
+	ds := r.ds(ctx)
+	rows, err := ds.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	if page.Items, err = r.scan(rows); err != nil {
+		return nil, err
+	}
+
+	if q.Size <= 0 {
+		page.Total = len(page.Items)
+		return page, nil
+	}
+
+	query = fmt.Sprintf("SELECT COUNT(*) FROM %s%s", r.table, where)
+	rows, err = ds.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err = rows.Scan(&page.Total); err != nil {
+			return nil, err
+		}
+	}
+	return page, rows.Err()
+}
+
+// Save 保存实体，主键为零值时插入记录，否则更新记录。主键由数据库自动生成时
+// 插入之后回填主键。
+func (r *Repository[T, ID]) Save(ctx context.Context, e *T) error {
+	if reflect.ValueOf(e).Elem().FieldByIndex(r.pk.index).IsZero() {
+		return r.Insert(ctx, e)
+	}
+	return r.Update(ctx, e)
+}
+
+// Insert 插入记录，主键由数据库自动生成时插入之后回填主键。
+func (r *Repository[T, ID]) Insert(ctx context.Context, e *T) error {
+
+	v := reflect.ValueOf(e).Elem()
+	var (
+		names  []string
+		marks  []string
+		values []interface{}
+	)
+	for _, c := range r.columns {
+		if r.auto && c.name == r.pk.name {
+			continue
+		}
+		names = append(names, c.name)
+		values = append(values, v.FieldByIndex(c.index).Interface())
+		marks = append(marks, r.placeholder(len(values)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		r.table, strings.Join(names, ", "), strings.Join(marks, ", "))
+	pk := v.FieldByIndex(r.pk.index)
+	ds := r.ds(ctx)
+
+	if !r.auto {
+		_, err := ds.ExecContext(ctx, query, values...)
+		return err
+	}
+
+	if r.dialect == "postgres" {
+		rows, err := ds.QueryContext(ctx, query+" RETURNING "+r.pk.name, values...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if rows.Next() {
+			if err = rows.Scan(pk.Addr().Interface()); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+
+	result, err := ds.ExecContext(ctx, query, values...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	switch pk.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		pk.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		pk.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("repo: can't set auto generated id to %s", pk.Type())
+	}
+	return nil
+}
+
+// Update 根据主键更新记录的所有列，记录不存在时返回 ErrNotFound 。
+func (r *Repository[T, ID]) Update(ctx context.Context, e *T) error {
+
+	v := reflect.ValueOf(e).Elem()
+	var (
+		sets   []string
+		values []interface{}
+	)
+	for _, c := range r.columns {
+		if c.name == r.pk.name {
+			continue
+		}
+		values = append(values, v.FieldByIndex(c.index).Interface())
+		sets = append(sets, c.name+" = "+r.placeholder(len(values)))
+	}
+	values = append(values, v.FieldByIndex(r.pk.index).Interface())
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		r.table, strings.Join(sets, ", "), r.pk.name, r.placeholder(len(values)))
+	result, err := r.ds(ctx).ExecContext(ctx, query, values...)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// Delete 删除主键为 id 的记录，记录不存在时返回 ErrNotFound 。
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", r.table, r.pk.name, r.placeholder(1))
+	result, err := r.ds(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/go-spring/spring-core/db"
+	"github.com/go-spring/spring-core/repo"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+// fake 记录执行的语句，并返回预先设置的查询结果。
+var fake struct {
+	stmts []string
+	rows  [][][]driver.Value
+	tx    int
+}
+
+func stmts() []string {
+	ret := fake.stmts
+	fake.stmts = nil
+	return ret
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	fake.tx++
+	return fakeTx{}, nil
+}
+
+type fakeStmt struct{ query string }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	fake.stmts = append(fake.stmts, fmt.Sprint(s.query, args))
+	return fakeResult{}, nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	fake.stmts = append(fake.stmts, fmt.Sprint(s.query, args))
+	var rows [][]driver.Value
+	if len(fake.rows) > 0 {
+		rows, fake.rows = fake.rows[0], fake.rows[1:]
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 42, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 1 && len(r.rows[0]) == 1 {
+		return []string{"n"}
+	}
+	return []string{"id", "name", "age"}
+}
+
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("repo-fake", fakeDriver{})
+}
+
+type User struct {
+	ID      int64  `db:"id,pk,auto"`
+	Name    string `db:"name"`
+	Age     int
+	Ignored string `db:"-"`
+}
+
+func (User) TableName() string { return "users" }
+
+type OrderItem struct {
+	ID  string
+	SKU string `db:"sku"`
+}
+
+func TestRepository(t *testing.T) {
+
+	d, err := sql.Open("repo-fake", "")
+	assert.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	r := repo.New[User, int64](d)
+
+	u := &User{Name: "jim", Age: 18}
+	assert.Nil(t, r.Save(ctx, u))
+	assert.Equal(t, u.ID, int64(42))
+	assert.Nil(t, r.Save(ctx, u))
+	assert.Nil(t, r.Delete(ctx, u.ID))
+	assert.Equal(t, stmts(), []string{
+		"INSERT INTO users (name, age) VALUES (?, ?)[jim 18]",
+		"UPDATE users SET name = ?, age = ? WHERE id = ?[jim 18 42]",
+		"DELETE FROM users WHERE id = ?[42]",
+	})
+
+	fake.rows = [][][]driver.Value{{{int64(42), "jim", int64(18)}}, nil}
+	u, err = r.Get(ctx, 42)
+	assert.Nil(t, err)
+	assert.Equal(t, *u, User{ID: 42, Name: "jim", Age: 18})
+	_, err = r.Get(ctx, 43)
+	assert.Equal(t, err, repo.ErrNotFound)
+	assert.Equal(t, stmts(), []string{
+		"SELECT id, name, age FROM users WHERE id = ?[42]",
+		"SELECT id, name, age FROM users WHERE id = ?[43]",
+	})
+
+	fake.rows = [][][]driver.Value{
+		{{int64(3), "tom", int64(20)}, {int64(4), "lily", int64(21)}},
+		{{int64(12)}},
+	}
+	p, err := r.List(ctx, repo.Query{Where: "age > ?", Args: []interface{}{18}, OrderBy: "id", Page: 2, Size: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, p, &repo.Page[User]{
+		Items: []User{{ID: 3, Name: "tom", Age: 20}, {ID: 4, Name: "lily", Age: 21}},
+		Total: 12, Page: 2, Size: 2,
+	})
+	assert.Equal(t, stmts(), []string{
+		"SELECT id, name, age FROM users WHERE age > ? ORDER BY id LIMIT 2 OFFSET 2[18]",
+		"SELECT COUNT(*) FROM users WHERE age > ?[18]",
+	})
+}
+
+func TestRepository_Postgres(t *testing.T) {
+
+	d, err := sql.Open("repo-fake", "")
+	assert.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	r := repo.New[User, int64](d, repo.WithDialect("postgres"), repo.WithTable("app.users"))
+
+	fake.rows = [][][]driver.Value{{{int64(7)}}}
+	u := &User{Name: "jim", Age: 18}
+	assert.Nil(t, r.Save(ctx, u))
+	assert.Equal(t, u.ID, int64(7))
+	assert.Nil(t, r.Save(ctx, u))
+	assert.Equal(t, stmts(), []string{
+		"INSERT INTO app.users (name, age) VALUES ($1, $2) RETURNING id[jim 18]",
+		"UPDATE app.users SET name = $1, age = $2 WHERE id = $3[jim 18 7]",
+	})
+}
+
+func TestRepository_Tx(t *testing.T) {
+
+	d, err := sql.Open("repo-fake", "")
+	assert.Nil(t, err)
+	defer d.Close()
+
+	// 没有 db 标签的字段使用蛇形命名，没有标记主键时使用 ID 字段
+	r := repo.New[OrderItem, string](d)
+
+	tx, err := d.Begin()
+	assert.Nil(t, err)
+	ctx := db.WithTx(context.Background(), tx)
+	assert.Nil(t, r.Save(ctx, &OrderItem{SKU: "A-1"}))
+	assert.Nil(t, tx.Commit())
+	assert.Equal(t, fake.tx, 1)
+	assert.Equal(t, stmts(), []string{
+		"INSERT INTO order_item (id, sku) VALUES (?, ?)[ A-1]",
+	})
+
+	assert.Panic(t, func() { repo.New[int, int](d) }, "repo: int should be a struct")
+}
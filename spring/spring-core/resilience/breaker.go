@@ -0,0 +1,219 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOpen 断路器处于打开状态时拒绝调用返回的错误。
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State 断路器的状态。
+type State int32
+
+const (
+	StateClosed   = State(0) // 关闭，正常调用
+	StateOpen     = State(1) // 打开，拒绝所有调用
+	StateHalfOpen = State(2) // 半开，允许少量调用以探测后端是否恢复
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return ""
+}
+
+// BreakerConfig 断路器配置。
+type BreakerConfig struct {
+	Enabled              bool          `value:"${enabled:=true}"`                // 是否开启断路器
+	FailureRateThreshold float64       `value:"${failure-rate-threshold:=50}"`   // 失败率阈值，百分比
+	SlidingWindowSize    int           `value:"${sliding-window-size:=100}"`     // 统计失败率的最近调用次数
+	MinimumCalls         int           `value:"${minimum-calls:=10}"`            // 计算失败率所需的最少调用次数
+	WaitDurationInOpen   time.Duration `value:"${wait-duration-in-open:=30s}"`   // 打开状态的持续时间，之后进入半开状态
+	HalfOpenCalls        int           `value:"${permitted-half-open-calls:=5}"` // 半开状态允许的调用次数
+}
+
+// Breaker 基于滑动窗口统计失败率的断路器。关闭状态下最近的调用失败率达到阈值
+// 时打开断路器，打开状态持续一段时间后进入半开状态，半开状态下允许的调用全部成
+// 功时关闭断路器，有任何一次失败则重新打开断路器。
+type Breaker struct {
+	name   string
+	config *BreakerConfig // 使用指针避免容器对其进行属性绑定
+
+	mutex    sync.Mutex
+	state    State
+	gen      uint64 // 每次状态变化时递增，用于忽略状态变化之前发起的调用的结果
+	openedAt time.Time
+
+	window   []bool // 环形缓冲区，true 表示调用失败
+	pos      int
+	count    int
+	failures int
+
+	halfOpenCalls   int // 半开状态下已经允许的调用次数
+	halfOpenSuccess int // 半开状态下成功的调用次数
+
+	onStateChange func(name string, from, to State)
+}
+
+// NewBreaker 创建名为 name 的断路器。
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	if config.SlidingWindowSize <= 0 {
+		config.SlidingWindowSize = 100
+	}
+	if config.MinimumCalls > config.SlidingWindowSize {
+		config.MinimumCalls = config.SlidingWindowSize
+	}
+	if config.HalfOpenCalls <= 0 {
+		config.HalfOpenCalls = 1
+	}
+	return &Breaker{
+		name:   name,
+		config: &config,
+		window: make([]bool, config.SlidingWindowSize),
+	}
+}
+
+// OnStateChange 设置状态变化时的回调函数，回调函数在持有锁的情况下执行，不
+// 能再调用断路器的方法。
+func (b *Breaker) OnStateChange(fn func(name string, from, to State)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.onStateChange = fn
+}
+
+// State 返回断路器当前的状态。
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.checkOpen()
+	return b.state
+}
+
+// Allow 判断是否允许调用，允许时返回的 done 函数必须在调用结束后以调用结果
+// 执行，不允许时返回 ErrOpen 。
+func (b *Breaker) Allow() (done func(err error), err error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.checkOpen()
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenCalls >= b.config.HalfOpenCalls {
+			return nil, ErrOpen
+		}
+		b.halfOpenCalls++
+	}
+
+	gen := b.gen
+	return func(err error) { b.done(gen, err) }, nil
+}
+
+// Execute 在断路器的保护下执行 fn 。fn 发生 panic 时记录为一次失败，然后继续
+// 抛出 panic 。
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			done(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// checkOpen 打开状态持续足够长的时间后进入半开状态。
+func (b *Breaker) checkOpen() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.config.WaitDurationInOpen {
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) done(gen uint64, err error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if gen != b.gen {
+		return
+	}
+
+	failed := isFailure(err)
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen)
+			return
+		}
+		if b.halfOpenSuccess++; b.halfOpenSuccess >= b.config.HalfOpenCalls {
+			b.transition(StateClosed)
+		}
+	case StateClosed:
+		if b.count == len(b.window) {
+			if b.window[b.pos] {
+				b.failures--
+			}
+		} else {
+			b.count++
+		}
+		b.window[b.pos] = failed
+		b.pos = (b.pos + 1) % len(b.window)
+		if failed {
+			b.failures++
+		}
+		if b.count >= b.config.MinimumCalls &&
+			float64(b.failures)*100 >= b.config.FailureRateThreshold*float64(b.count) {
+			b.transition(StateOpen)
+		}
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.gen++
+	b.pos, b.count, b.failures = 0, 0, 0
+	b.halfOpenCalls, b.halfOpenSuccess = 0, 0
+	if to == StateOpen {
+		b.openedAt = time.Now()
+	}
+	if b.onStateChange != nil {
+		b.onStateChange(b.name, from, to)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBulkheadFull 并发调用数达到上限时拒绝调用返回的错误。
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// BulkheadConfig 并发隔离配置。
+type BulkheadConfig struct {
+	MaxConcurrent int           `value:"${max-concurrent:=0}"` // 最大并发调用数，0 表示不限制
+	MaxWait       time.Duration `value:"${max-wait:=0}"`       // 达到上限时的最长等待时间
+}
+
+// Bulkhead 限制对同一后端的并发调用数，避免一个缓慢的后端耗尽所有的资源。
+type Bulkhead struct {
+	sem     chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead 创建并发隔离器，最大并发调用数不大于 0 时返回 nil ，此时不做限制。
+func NewBulkhead(config BulkheadConfig) *Bulkhead {
+	if config.MaxConcurrent <= 0 {
+		return nil
+	}
+	return &Bulkhead{
+		sem:     make(chan struct{}, config.MaxConcurrent),
+		maxWait: config.MaxWait,
+	}
+}
+
+// Acquire 获取一个调用许可，成功时返回的 release 函数必须在调用结束后执行。
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+
+	release = func() { <-b.sem }
+
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if b.maxWait <= 0 {
+		return nil, ErrBulkheadFull
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Execute 在获取调用许可之后执行 fn 。
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resilience 提供了断路器、重试和并发隔离三种容错手段，按照后端名称
+// 通过属性进行配置，可以作为 HTTP 客户端和 gRPC 客户端的拦截器使用，也可以直
+// 接包装任意的调用。
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
+)
+
+// ignored 包装不应该被认为是后端故障的错误，例如参数错误。
+type ignored struct {
+	err error
+}
+
+func (e *ignored) Error() string { return e.err.Error() }
+
+func (e *ignored) Unwrap() error { return e.err }
+
+// Ignore 标记 err 不是后端故障，该错误既不计入断路器的失败次数也不会被重试。
+func Ignore(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ignored{err: err}
+}
+
+// unwrapIgnored 去掉 Ignore 添加的包装。
+func unwrapIgnored(err error) error {
+	if e, ok := err.(*ignored); ok {
+		return e.err
+	}
+	return err
+}
+
+// isFailure 判断调用结果是否是后端故障。
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *ignored
+	return !errors.As(err, &e)
+}
+
+// retryable 判断调用结果是否可以重试。
+func retryable(err error) bool {
+	if !isFailure(err) {
+		return false
+	}
+	return !errors.Is(err, ErrOpen) &&
+		!errors.Is(err, ErrBulkheadFull) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// BackendConfig 单个后端的容错配置。
+type BackendConfig struct {
+	Breaker  BreakerConfig  `value:"${breaker}"`
+	Retry    RetryConfig    `value:"${retry}"`
+	Bulkhead BulkheadConfig `value:"${bulkhead}"`
+}
+
+// Config 容错配置，通常绑定到 resilience 前缀的属性上，backends 下按照后端名
+// 称进行配置，没有配置的后端使用 default 的配置。
+type Config struct {
+	Default  BackendConfig            `value:"${default}"`
+	Backends map[string]BackendConfig `value:"${backends}"`
+}
+
+// Backend 组合了断路器、重试和并发隔离的后端，各个组件都可以为 nil 。
+type Backend struct {
+	Name     string
+	Breaker  *Breaker
+	Retry    *Retry
+	Bulkhead *Bulkhead
+
+	calls   *metrics.CounterVec
+	retries *metrics.Counter
+}
+
+// NewBackend 根据配置创建名为 name 的后端。
+func NewBackend(name string, config BackendConfig) *Backend {
+	b := &Backend{
+		Name:     name,
+		Retry:    NewRetry(config.Retry),
+		Bulkhead: NewBulkhead(config.Bulkhead),
+	}
+	if config.Breaker.Enabled {
+		b.Breaker = NewBreaker(name, config.Breaker)
+	}
+	b.Retry.OnRetry(func(attempt int, err error) {
+		log.Debugf("resilience backend %s retry %d error: %v", name, attempt, err)
+		if b.retries != nil {
+			b.retries.Inc()
+		}
+	})
+	return b
+}
+
+// Execute 在容错保护下执行 fn ，每次重试都要重新经过并发隔离和断路器。通过
+// Ignore 包装的错误在返回之前会去掉包装。
+func (b *Backend) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := b.Retry.Do(ctx, func(ctx context.Context) error {
+		return b.Bulkhead.Execute(ctx, func(ctx context.Context) error {
+			err := b.Breaker.Execute(ctx, fn)
+			b.record(err)
+			return err
+		})
+	})
+	if errors.Is(err, ErrBulkheadFull) {
+		b.record(err)
+	}
+	return unwrapIgnored(err)
+}
+
+// record 记录调用结果。
+func (b *Backend) record(err error) {
+	if b.calls == nil {
+		return
+	}
+	outcome := "success"
+	if errors.Is(err, ErrOpen) || errors.Is(err, ErrBulkheadFull) {
+		outcome = "rejected"
+	} else if isFailure(err) {
+		outcome = "failure"
+	}
+	b.calls.With(b.Name, outcome).Inc()
+}
+
+// statusError 表示后端返回了 5xx 响应。
+type statusError struct {
+	resp *http.Response
+}
+
+func (e *statusError) Error() string { return e.resp.Status }
+
+// Transport 返回带有容错保护的 http.RoundTripper ，5xx 响应被认为是后端故障，
+// 重试全部失败时返回最后一次的响应。只有幂等的请求才会重试，请求体通过
+// GetBody 重新获取。
+func (b *Backend) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{b: b, next: next}
+}
+
+type transport struct {
+	b    *Backend
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	retry := t.b.Retry
+	if !idempotent(req) {
+		retry = nil
+	}
+
+	var (
+		resp    *http.Response
+		attempt int
+	)
+
+	fn := func(ctx context.Context) error {
+		if resp != nil { // 丢弃上一次失败的响应
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			resp = nil
+		}
+		r := req
+		if attempt++; attempt > 1 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return Ignore(err)
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+		var err error
+		resp, err = t.next.RoundTrip(r)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &statusError{resp: resp}
+		}
+		return nil
+	}
+
+	err := retry.Do(req.Context(), func(ctx context.Context) error {
+		return t.b.Bulkhead.Execute(ctx, func(ctx context.Context) error {
+			err := t.b.Breaker.Execute(ctx, fn)
+			t.b.record(err)
+			return err
+		})
+	})
+
+	var e *statusError
+	if errors.As(err, &e) {
+		return e.resp, nil
+	}
+	if err != nil {
+		if resp != nil { // 重试被拒绝时之前失败的响应
+			_ = resp.Body.Close()
+		}
+		if errors.Is(err, ErrBulkheadFull) {
+			t.b.record(err)
+		}
+		return nil, unwrapIgnored(err)
+	}
+	return resp, nil
+}
+
+// idempotent 判断请求是否可以安全地重试。
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return req.Body == nil || req.GetBody != nil
+	}
+	return false
+}
+
+// StateListener 断路器状态变化的监听器，导出为该接口的 bean 会被自动注册。
+type StateListener interface {
+	OnStateChange(backend string, from, to State)
+}
+
+// Registry 按照名称管理所有的后端，后端在首次使用时创建。
+type Registry struct {
+	Metrics   *metrics.Registry `autowire:"?"`
+	Listeners []StateListener   `autowire:""`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+
+	mutex    sync.Mutex
+	backends map[string]*Backend
+	calls    *metrics.CounterVec
+	retries  *metrics.CounterVec
+	state    *metrics.GaugeVec
+}
+
+// NewRegistry Registry 的构造函数。
+func NewRegistry(config Config) *Registry {
+	return &Registry{config: &config, backends: make(map[string]*Backend)}
+}
+
+// OnInit 注册容错相关的指标。
+func (r *Registry) OnInit() {
+	if r.Metrics == nil {
+		return
+	}
+	r.calls = r.Metrics.Counter("resilience_calls_total", "Total number of calls by outcome.", "backend", "outcome")
+	r.retries = r.Metrics.Counter("resilience_retries_total", "Total number of retries.", "backend")
+	r.state = r.Metrics.Gauge("resilience_breaker_state", "Circuit breaker state, 0 closed, 1 open, 2 half-open.", "backend")
+}
+
+// Backend 返回名为 name 的后端，没有配置时使用默认配置。
+func (r *Registry) Backend(name string) *Backend {
+	c, ok := r.config.Backends[name]
+	if !ok {
+		c = r.config.Default
+	}
+	return r.backend(name, c)
+}
+
+// Lookup 返回名为 name 并且在 backends 下有配置的后端。
+func (r *Registry) Lookup(name string) (*Backend, bool) {
+	c, ok := r.config.Backends[name]
+	if !ok {
+		return nil, false
+	}
+	return r.backend(name, c), true
+}
+
+// Execute 在名为 name 的后端的容错保护下执行 fn 。
+func (r *Registry) Execute(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return r.Backend(name).Execute(ctx, fn)
+}
+
+func (r *Registry) backend(name string, c BackendConfig) *Backend {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if b, ok := r.backends[name]; ok {
+		return b
+	}
+
+	b := NewBackend(name, c)
+	if r.calls != nil {
+		b.calls = r.calls
+		b.retries = r.retries.With(name)
+		r.state.With(name).Set(float64(StateClosed))
+	}
+	if b.Breaker != nil {
+		b.Breaker.OnStateChange(r.onStateChange)
+	}
+	r.backends[name] = b
+	return b
+}
+
+func (r *Registry) onStateChange(name string, from, to State) {
+	log.Warnf("resilience backend %s circuit breaker %s -> %s", name, from, to)
+	if r.state != nil {
+		r.state.With(name).Set(float64(to))
+	}
+	for _, l := range r.Listeners {
+		l.OnStateChange(name, from, to)
+	}
+}
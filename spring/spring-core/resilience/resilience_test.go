@@ -0,0 +1,342 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/resilience"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+var errBackend = errors.New("backend error")
+
+func fail(ctx context.Context) error { return errBackend }
+
+func succeed(ctx context.Context) error { return nil }
+
+func TestBreaker(t *testing.T) {
+
+	var transitions []string
+	b := resilience.NewBreaker("test", resilience.BreakerConfig{
+		FailureRateThreshold: 50,
+		SlidingWindowSize:    4,
+		MinimumCalls:         4,
+		WaitDurationInOpen:   20 * time.Millisecond,
+		HalfOpenCalls:        2,
+	})
+	b.OnStateChange(func(name string, from, to resilience.State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	ctx := context.Background()
+	_ = b.Execute(ctx, succeed)
+	_ = b.Execute(ctx, succeed)
+	_ = b.Execute(ctx, fail)
+	assert.Equal(t, b.State(), resilience.StateClosed)
+	_ = b.Execute(ctx, fail)
+	assert.Equal(t, b.State(), resilience.StateOpen)
+
+	err := b.Execute(ctx, succeed)
+	assert.True(t, errors.Is(err, resilience.ErrOpen))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, b.State(), resilience.StateHalfOpen)
+
+	// 半开状态下只允许有限的调用
+	done1, err := b.Allow()
+	assert.Nil(t, err)
+	done2, err := b.Allow()
+	assert.Nil(t, err)
+	_, err = b.Allow()
+	assert.True(t, errors.Is(err, resilience.ErrOpen))
+
+	done1(nil)
+	done2(nil)
+	assert.Equal(t, b.State(), resilience.StateClosed)
+
+	assert.Equal(t, transitions, []string{"closed->open", "open->half-open", "half-open->closed"})
+}
+
+func TestBreaker_HalfOpenFailure(t *testing.T) {
+
+	b := resilience.NewBreaker("test", resilience.BreakerConfig{
+		FailureRateThreshold: 100,
+		SlidingWindowSize:    1,
+		MinimumCalls:         1,
+		WaitDurationInOpen:   10 * time.Millisecond,
+		HalfOpenCalls:        1,
+	})
+
+	ctx := context.Background()
+	_ = b.Execute(ctx, fail)
+	assert.Equal(t, b.State(), resilience.StateOpen)
+
+	time.Sleep(20 * time.Millisecond)
+	_ = b.Execute(ctx, fail)
+	assert.Equal(t, b.State(), resilience.StateOpen)
+}
+
+func TestBreaker_Panic(t *testing.T) {
+
+	b := resilience.NewBreaker("test", resilience.BreakerConfig{
+		FailureRateThreshold: 100,
+		SlidingWindowSize:    1,
+		MinimumCalls:         1,
+		WaitDurationInOpen:   10 * time.Millisecond,
+		HalfOpenCalls:        1,
+	})
+
+	ctx := context.Background()
+	boom := func(ctx context.Context) error { panic("boom") }
+
+	// panic 被记录为失败并继续抛出
+	assert.Panic(t, func() { _ = b.Execute(ctx, boom) }, "boom")
+	assert.Equal(t, b.State(), resilience.StateOpen)
+
+	// 半开状态下的 panic 不会一直占用调用名额
+	time.Sleep(20 * time.Millisecond)
+	assert.Panic(t, func() { _ = b.Execute(ctx, boom) }, "boom")
+	assert.Equal(t, b.State(), resilience.StateOpen)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, b.Execute(ctx, succeed))
+	assert.Equal(t, b.State(), resilience.StateClosed)
+}
+
+func TestBreaker_Ignore(t *testing.T) {
+
+	b := resilience.NewBreaker("test", resilience.BreakerConfig{
+		FailureRateThreshold: 50,
+		SlidingWindowSize:    2,
+		MinimumCalls:         2,
+		WaitDurationInOpen:   time.Minute,
+		HalfOpenCalls:        1,
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_ = b.Execute(ctx, func(ctx context.Context) error {
+			return resilience.Ignore(errBackend)
+		})
+	}
+	assert.Equal(t, b.State(), resilience.StateClosed)
+}
+
+func TestRetry(t *testing.T) {
+
+	r := resilience.NewRetry(resilience.RetryConfig{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Multiplier:  2,
+	})
+
+	var n int
+	err := r.Do(context.Background(), func(ctx context.Context) error {
+		if n++; n < 3 {
+			return errBackend
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, n, 3)
+
+	n = 0
+	err = r.Do(context.Background(), func(ctx context.Context) error {
+		n++
+		return errBackend
+	})
+	assert.Equal(t, err, errBackend)
+	assert.Equal(t, n, 3)
+
+	n = 0
+	err = r.Do(context.Background(), func(ctx context.Context) error {
+		n++
+		return resilience.Ignore(errBackend)
+	})
+	assert.True(t, errors.Is(err, errBackend))
+	assert.Equal(t, n, 1)
+
+	// 剩余的时间预算不足以等待退避时间
+	r = resilience.NewRetry(resilience.RetryConfig{MaxAttempts: 3, Backoff: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	n = 0
+	err = r.Do(ctx, func(ctx context.Context) error {
+		n++
+		return errBackend
+	})
+	assert.Equal(t, err, errBackend)
+	assert.Equal(t, n, 1)
+}
+
+func TestBulkhead(t *testing.T) {
+
+	b := resilience.NewBulkhead(resilience.BulkheadConfig{MaxConcurrent: 1})
+
+	release, err := b.Acquire(context.Background())
+	assert.Nil(t, err)
+
+	_, err = b.Acquire(context.Background())
+	assert.Equal(t, err, resilience.ErrBulkheadFull)
+
+	release()
+	err = b.Execute(context.Background(), succeed)
+	assert.Nil(t, err)
+
+	assert.True(t, resilience.NewBulkhead(resilience.BulkheadConfig{}) == nil)
+}
+
+func TestBulkhead_MaxWait(t *testing.T) {
+
+	b := resilience.NewBulkhead(resilience.BulkheadConfig{
+		MaxConcurrent: 1,
+		MaxWait:       time.Second,
+	})
+
+	release, err := b.Acquire(context.Background())
+	assert.Nil(t, err)
+	time.AfterFunc(10*time.Millisecond, release)
+
+	err = b.Execute(context.Background(), succeed)
+	assert.Nil(t, err)
+}
+
+type listener struct {
+	states []resilience.State
+}
+
+func (l *listener) OnStateChange(backend string, from, to resilience.State) {
+	l.states = append(l.states, to)
+}
+
+func newRegistry() (*resilience.Registry, *listener) {
+	r := resilience.NewRegistry(resilience.Config{
+		Backends: map[string]resilience.BackendConfig{
+			"user": {
+				Breaker: resilience.BreakerConfig{
+					Enabled:              true,
+					FailureRateThreshold: 50,
+					SlidingWindowSize:    2,
+					MinimumCalls:         2,
+					WaitDurationInOpen:   time.Minute,
+					HalfOpenCalls:        1,
+				},
+				Retry: resilience.RetryConfig{
+					MaxAttempts: 2,
+					Backoff:     time.Millisecond,
+				},
+			},
+		},
+	})
+	l := &listener{}
+	r.Metrics = metrics.NewRegistry()
+	r.Listeners = []resilience.StateListener{l}
+	r.OnInit()
+	return r, l
+}
+
+func TestRegistry(t *testing.T) {
+
+	r, l := newRegistry()
+
+	_, ok := r.Lookup("order")
+	assert.False(t, ok)
+	assert.True(t, r.Backend("order").Breaker == nil)
+
+	b, ok := r.Lookup("user")
+	assert.True(t, ok)
+	assert.True(t, b == r.Backend("user"))
+
+	var n int
+	err := r.Execute(context.Background(), "user", func(ctx context.Context) error {
+		n++
+		return errBackend
+	})
+	assert.Equal(t, err, errBackend)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, b.Breaker.State(), resilience.StateOpen)
+	assert.Equal(t, l.states, []resilience.State{resilience.StateOpen})
+
+	err = r.Execute(context.Background(), "user", succeed)
+	assert.Equal(t, err, resilience.ErrOpen)
+
+	var buf strings.Builder
+	_ = r.Metrics.WritePrometheus(&buf)
+	s := buf.String()
+	assert.True(t, strings.Contains(s, `resilience_calls_total{backend="user",outcome="failure"} 2`))
+	assert.True(t, strings.Contains(s, `resilience_calls_total{backend="user",outcome="rejected"} 1`))
+	assert.True(t, strings.Contains(s, `resilience_retries_total{backend="user"} 1`))
+	assert.True(t, strings.Contains(s, `resilience_breaker_state{backend="user"} 1`))
+}
+
+func TestBackend_Transport(t *testing.T) {
+
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	b := resilience.NewBackend("test", resilience.BackendConfig{
+		Breaker: resilience.BreakerConfig{
+			Enabled:              true,
+			FailureRateThreshold: 100,
+			SlidingWindowSize:    2,
+			MinimumCalls:         2,
+			WaitDurationInOpen:   time.Minute,
+			HalfOpenCalls:        1,
+		},
+		Retry: resilience.RetryConfig{
+			MaxAttempts: 2,
+			Backoff:     time.Millisecond,
+		},
+	})
+	client := &http.Client{Transport: b.Transport(nil)}
+
+	resp, err := client.Get(ts.URL)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, atomic.LoadInt32(&n), int32(2))
+
+	// 非幂等的请求不会重试，失败的响应原样返回
+	for i := 0; i < 2; i++ {
+		atomic.StoreInt32(&n, 0)
+		resp, err = client.Post(ts.URL, "text/plain", strings.NewReader("body"))
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+		assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+	}
+
+	// 断路器已经打开
+	_, err = client.Get(ts.URL)
+	assert.True(t, errors.Is(err, resilience.ErrOpen))
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spring/spring-core/deadline"
+)
+
+// RetryConfig 重试配置。
+type RetryConfig struct {
+	MaxAttempts int           `value:"${max-attempts:=1}"` // 最大调用次数，包含首次调用，1 表示不重试
+	Backoff     time.Duration `value:"${backoff:=100ms}"`  // 首次重试的等待时间
+	Multiplier  float64       `value:"${multiplier:=2}"`   // 每次重试等待时间的倍数
+	MaxBackoff  time.Duration `value:"${max-backoff:=2s}"` // 重试等待时间的上限
+}
+
+// Retry 按照指数退避的方式重试失败的调用，通过 Ignore 包装的错误、ErrOpen、
+// ErrBulkheadFull 以及 ctx 结束时的错误不会重试，ctx 剩余的时间预算不足以等待
+// 退避时间时也不再重试。
+type Retry struct {
+	config  *RetryConfig // 使用指针避免容器对其进行属性绑定
+	onRetry func(attempt int, err error)
+}
+
+// NewRetry Retry 的构造函数。
+func NewRetry(config RetryConfig) *Retry {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	if config.Multiplier < 1 {
+		config.Multiplier = 1
+	}
+	return &Retry{config: &config}
+}
+
+// OnRetry 设置每次重试之前的回调函数，attempt 为即将进行的调用的序号。
+func (r *Retry) OnRetry(fn func(attempt int, err error)) {
+	r.onRetry = fn
+}
+
+// Do 执行 fn ，失败时进行重试，返回最后一次调用的错误。
+func (r *Retry) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+
+	if r == nil {
+		return fn(ctx)
+	}
+
+	backoff := r.config.Backoff
+	for attempt := 1; ; attempt++ {
+
+		err := fn(ctx)
+		if err == nil || attempt >= r.config.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		// 剩余的时间预算不足以等到下一次调用
+		if !deadline.Allow(ctx, backoff) {
+			return err
+		}
+
+		if r.onRetry != nil {
+			r.onRetry(attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff = time.Duration(float64(backoff) * r.config.Multiplier)
+		if r.config.MaxBackoff > 0 && backoff > r.config.MaxBackoff {
+			backoff = r.config.MaxBackoff
+		}
+	}
+}
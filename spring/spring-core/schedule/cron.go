@@ -0,0 +1,290 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 计算任务的下一次执行时间。
+type Schedule interface {
+
+	// Next 返回晚于 t 的下一次执行时间。
+	Next(t time.Time) time.Time
+}
+
+// every 固定间隔的 Schedule 实现。
+type every struct {
+	d time.Duration
+}
+
+func (s every) Next(t time.Time) time.Time {
+	return t.Add(s.d)
+}
+
+// field cron 表达式中一个字段的取值范围。
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = field{min: 0, max: 59}
+	minutes = field{min: 0, max: 59}
+	hours   = field{min: 0, max: 23}
+	doms    = field{min: 1, max: 31}
+	months  = field{min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	dows = field{min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// cron 基于 cron 表达式的 Schedule 实现，每个字段使用位图表示允许的取值。
+type cron struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// 日期和星期都有限制时只要满足其中一个即可，否则两者都要满足。
+	domStar, dowStar bool
+
+	loc   *time.Location
+	tzSet bool // 表达式是否指定了时区
+}
+
+// in 返回使用时区 loc 的表达式，表达式指定了时区时返回自身。
+func (c *cron) in(loc *time.Location) *cron {
+	if c.tzSet {
+		return c
+	}
+	r := *c
+	r.loc = loc
+	return &r
+}
+
+// ParseCron 解析 cron 表达式，支持带秒的六个字段 (秒 分 时 日 月 星期) 或者不
+// 带秒的五个字段，字段支持 * ? , - / 以及月份和星期的英文缩写。此外还支持
+// @hourly、@daily 等预定义表达式以及 @every 1m30s 形式的固定间隔，表达式前面
+// 可以使用 CRON_TZ=Asia/Shanghai 指定时区，默认使用本地时区。
+func ParseCron(spec string) (Schedule, error) {
+
+	spec = strings.TrimSpace(spec)
+	loc, tzSet := time.Local, false
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexByte(spec, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid cron spec %q", spec)
+		}
+		var err error
+		tz := spec[strings.IndexByte(spec, '=')+1 : i]
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %v", spec, err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+		tzSet = true
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid cron spec %q", spec)
+		}
+		return every{d: d}, nil
+	}
+
+	if s, ok := macros[spec]; ok {
+		spec = s
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 or 6 fields but got %d", spec, len(fields))
+	}
+
+	c := &cron{loc: loc, tzSet: tzSet}
+	var err error
+	parse := func(s string, f field) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		if bits, err = parseField(s, f); err != nil {
+			err = fmt.Errorf("invalid cron spec %q: %v", spec, err)
+		}
+		return bits
+	}
+
+	c.second = parse(fields[0], seconds)
+	c.minute = parse(fields[1], minutes)
+	c.hour = parse(fields[2], hours)
+	c.dom = parse(fields[3], doms)
+	c.month = parse(fields[4], months)
+	c.dow = parse(fields[5], dows)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dow&(1<<7) != 0 { // 7 也表示星期日
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domStar = fields[3] == "*" || fields[3] == "?"
+	c.dowStar = fields[5] == "*" || fields[5] == "?"
+	return c, nil
+}
+
+// parseField 解析一个字段，返回允许的取值的位图。
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		var lo, hi int
+		switch {
+		case part == "*" || part == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(part, "-"):
+			i := strings.IndexByte(part, '-')
+			var err error
+			if lo, err = f.value(part[:i]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(part[i+1:]); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if lo, err = f.value(part); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if step > 1 { // a/n 表示从 a 开始直到最大值
+				hi = f.max
+			}
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// wall 返回 t 在其时区下的挂钟时间，用于比较夏令时切换时重复出现的时间。
+func wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// Next 返回晚于 t 的下一次执行时间，表达式永远不会匹配时返回零值。夏令时结束时
+// 重复出现的挂钟时间只匹配一次。
+func (c *cron) Next(t time.Time) time.Time {
+
+	origLoc := t.Location()
+	from := wall(t.In(c.loc))
+	t = t.In(c.loc).Add(time.Second - time.Duration(t.Nanosecond()))
+
+	// 例如 2 月 30 日这样的表达式永远不会匹配，五年之内找不到时放弃。
+	yearLimit := t.Year() + 5
+
+	for t.Year() <= yearLimit {
+
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+
+		if c.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+
+		if !wall(t).After(from) {
+			t = t.Add(time.Second)
+			continue
+		}
+
+		return t.In(origLoc)
+	}
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// taskView scheduledtasks 端点返回的任务信息。
+type taskView struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	Paused       bool       `json:"paused"`
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration float64    `json:"lastDurationMs"`
+	LastOutcome  string     `json:"lastOutcome,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	NextRun      *time.Time `json:"nextRun,omitempty"`
+	Runs         int64      `json:"runs"`
+	Failures     int64      `json:"failures"`
+	Missed       int64      `json:"missed"`
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Endpoint 查看和管理定时任务的管理端点。GET 请求返回所有任务的调度方式和运行
+// 状态；POST 请求对 name 参数指定的任务执行 action 参数指定的操作，可选 trigger 、
+// pause 和 resume 。
+type Endpoint struct {
+	Scheduler *Scheduler `autowire:""`
+}
+
+// ID 返回端点的 ID 。
+func (e *Endpoint) ID() string {
+	return "scheduledtasks"
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		e.get(w)
+	case http.MethodPost:
+		e.post(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (e *Endpoint) get(w http.ResponseWriter) {
+	tasks := make([]taskView, 0, len(e.Scheduler.Tasks))
+	for _, t := range e.Scheduler.Tasks {
+		st := t.State()
+		tasks = append(tasks, taskView{
+			Name:         st.Name,
+			Schedule:     t.Spec(),
+			Running:      t.Running(),
+			Paused:       st.Paused,
+			LastRun:      timeOrNil(st.LastRun),
+			LastDuration: float64(st.LastDuration) / float64(time.Millisecond),
+			LastOutcome:  st.LastOutcome,
+			LastError:    st.LastError,
+			NextRun:      timeOrNil(st.NextRun),
+			Runs:         st.Runs,
+			Failures:     st.Failures,
+			Missed:       st.Missed,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(map[string]interface{}{"tasks": tasks}); err != nil {
+		log.Error(err)
+	}
+}
+
+func (e *Endpoint) post(w http.ResponseWriter, r *http.Request) {
+
+	var fn func(name string) error
+	switch action := r.URL.Query().Get("action"); action {
+	case "trigger":
+		fn = e.Scheduler.Trigger
+	case "pause":
+		fn = e.Scheduler.Pause
+	case "resume":
+		fn = e.Scheduler.Resume
+	default:
+		http.Error(w, "unknown action "+action, http.StatusBadRequest)
+		return
+	}
+
+	if err := fn(r.URL.Query().Get("name")); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
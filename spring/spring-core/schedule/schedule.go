@@ -0,0 +1,704 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package schedule 提供了由容器管理的定时任务，支持 cron 表达式、固定频率和固
+// 定间隔三种调度方式。任务在应用启动后开始调度，在容器关闭时取消，单次执行的
+// panic 不会影响后续调度，上一次执行尚未结束时跳过本次执行，设置了分布式锁的任
+// 务在多个实例中同一时刻只会有一个实例执行。
+//
+// 任务可以在运行时手动触发、暂停和恢复，任务的运行状态通过 scheduledtasks 管理
+// 端点查看。设置了 Store 时任务的运行状态被持久化，应用重启之后恢复任务的暂停
+// 状态并检测应用停止期间错过的调度。错过的调度按照任务的 MisfirePolicy 处理，
+// 调度时间可以随机推迟一段时间，避免多个实例在同一时刻执行任务。
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/actuator"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/lock"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-stl/util"
+)
+
+func init() {
+	gs.Provide(NewScheduler, "${schedule}").Export(gs.AppEvent)
+	gs.Provide(NewSQLStore, "", "${schedule.store}").
+		On(cond.OnProperty("schedule.store.enabled", cond.HavingValue("true")).OnBean((*sql.DB)(nil)))
+	gs.Object(new(Endpoint)).Export((*actuator.Endpoint)(nil))
+}
+
+// ErrNotFound 任务不存在时返回的错误。
+var ErrNotFound = errors.New("schedule: task not found")
+
+// MisfirePolicy 错过调度的处理策略。应用停止期间或者进程暂停导致调度时间已经
+// 过去时称为错过调度，执行时间过长导致跳过的调度不属于错过调度。
+type MisfirePolicy string
+
+const (
+	MisfireSkip     MisfirePolicy = "skip"      // 跳过错过的调度，等待下一次调度
+	MisfireFireOnce MisfirePolicy = "fire-once" // 立即执行一次，然后恢复正常调度
+	MisfireCatchUp  MisfirePolicy = "catch-up"  // 依次补执行错过的调度，最多补执行 max-catch-up 次
+)
+
+func (p MisfirePolicy) valid() bool {
+	return p == MisfireSkip || p == MisfireFireOnce || p == MisfireCatchUp
+}
+
+// Func 定时任务执行的函数，ctx 在容器关闭时结束。
+type Func func(ctx context.Context) error
+
+// State 任务的运行状态。
+type State struct {
+	Name         string
+	Paused       bool
+	LastRun      time.Time     // 最近一次执行的开始时间
+	LastDuration time.Duration // 最近一次执行的耗时
+	LastOutcome  string        // 最近一次执行的结果，success、failure 或者 panic
+	LastError    string        // 最近一次执行失败的原因
+	NextRun      time.Time     // 下一次调度的时间
+	Runs         int64         // 执行的次数
+	Failures     int64         // 执行失败的次数，包括 panic
+	Missed       int64         // 应用停止期间错过的调度次数
+}
+
+// Task 定时任务。
+type Task struct {
+	name         string
+	spec         string // 调度方式的描述
+	fn           Func
+	schedule     Schedule
+	delay        time.Duration // 大于 0 时表示固定间隔调度
+	initialDelay time.Duration
+	lockTTL      time.Duration
+	misfire      MisfirePolicy
+	jitter       time.Duration
+	jitterSet    bool
+	missed       int64 // 应用停止期间错过的调度次数，在调度开始时处理
+	running      int32
+	paused       int32
+	trigger      chan struct{} // 手动触发的信号
+
+	mu    sync.Mutex
+	state State
+}
+
+// Option 定时任务的选项。
+type Option func(t *Task)
+
+// Name 设置任务的名称，默认使用函数名，同时也是任务的 bean 名称和锁的名称。
+func Name(name string) Option {
+	return func(t *Task) { t.name = name }
+}
+
+// InitialDelay 设置应用启动后首次调度之前的等待时间。
+func InitialDelay(d time.Duration) Option {
+	return func(t *Task) { t.initialDelay = d }
+}
+
+// Lock 使用分布式锁保证多个实例中同一时刻只有一个实例执行任务，需要容器中存在
+// lock.Locker 类型的 bean 。锁在执行结束后不会释放而是在 ttl 之后过期，从而避免
+// 时钟偏差导致其他实例重复执行同一次调度，ttl 应该大于执行时间和时钟偏差并且
+// 小于调度间隔。
+func Lock(ttl time.Duration) Option {
+	return func(t *Task) { t.lockTTL = ttl }
+}
+
+// Misfire 设置错过调度的处理策略，默认使用 schedule.misfire 属性的值。检测应用
+// 停止期间错过的调度需要容器中存在 Store 类型的 bean 。
+func Misfire(p MisfirePolicy) Option {
+	return func(t *Task) { t.misfire = p }
+}
+
+// Jitter 每次调度随机推迟 [0, d) 的时间，包括应用启动后的首次调度，默认使用
+// schedule.jitter 属性的值。d 应该明显小于调度间隔。
+func Jitter(d time.Duration) Option {
+	return func(t *Task) { t.jitter, t.jitterSet = d, true }
+}
+
+func newTask(spec string, fn Func, opts []Option) *Task {
+	_, _, name := util.FileLine(fn)
+	t := &Task{name: name, spec: spec, fn: fn, trigger: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.state.Name = t.name
+	return t
+}
+
+// NewCron 创建按照 cron 表达式调度的任务，表达式的格式参见 ParseCron 。
+func NewCron(spec string, fn Func, opts ...Option) (*Task, error) {
+	s, err := ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	t := newTask("cron "+spec, fn, opts)
+	t.schedule = s
+	return t, nil
+}
+
+// NewFixedRate 创建按照固定频率调度的任务，应用启动后立即执行第一次。
+func NewFixedRate(d time.Duration, fn Func, opts ...Option) *Task {
+	t := newTask("fixed-rate "+d.String(), fn, opts)
+	t.schedule = every{d: d}
+	return t
+}
+
+// NewFixedDelay 创建在上一次执行结束后等待固定时间再次执行的任务，应用启动后
+// 立即执行第一次。
+func NewFixedDelay(d time.Duration, fn Func, opts ...Option) *Task {
+	t := newTask("fixed-delay "+d.String(), fn, opts)
+	t.delay = d
+	return t
+}
+
+// Cron 注册按照 cron 表达式调度的任务，表达式错误时 panic 。
+func Cron(spec string, fn Func, opts ...Option) *Task {
+	t, err := NewCron(spec, fn, opts...)
+	if err != nil {
+		panic(err)
+	}
+	gs.Object(t).Name(t.name)
+	return t
+}
+
+// FixedRate 注册按照固定频率调度的任务。
+func FixedRate(d time.Duration, fn Func, opts ...Option) *Task {
+	t := NewFixedRate(d, fn, opts...)
+	gs.Object(t).Name(t.name)
+	return t
+}
+
+// FixedDelay 注册按照固定间隔调度的任务。
+func FixedDelay(d time.Duration, fn Func, opts ...Option) *Task {
+	t := NewFixedDelay(d, fn, opts...)
+	gs.Object(t).Name(t.name)
+	return t
+}
+
+// GetName 返回任务的名称。
+func (t *Task) GetName() string {
+	return t.name
+}
+
+// Spec 返回调度方式的描述，例如 "cron 0 0 * * * *"、"fixed-rate 1m0s" 。
+func (t *Task) Spec() string {
+	return t.spec
+}
+
+// Running 返回任务是否正在执行。
+func (t *Task) Running() bool {
+	return atomic.LoadInt32(&t.running) == 1
+}
+
+// Paused 返回任务是否已经暂停。
+func (t *Task) Paused() bool {
+	return atomic.LoadInt32(&t.paused) == 1
+}
+
+// State 返回任务运行状态的快照。
+func (t *Task) State() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.state
+	st.Paused = t.Paused()
+	return st
+}
+
+func (t *Task) update(fn func(st *State)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.state)
+}
+
+// restore 恢复应用停止之前保存的运行状态并计算停止期间错过的调度次数，错过的
+// 调度在调度开始时按照任务的策略处理。
+func (t *Task) restore(st State, now time.Time) {
+	var missed int64
+	if next := st.NextRun; !next.IsZero() && next.Before(now) {
+		missed = 1
+		if t.schedule != nil { // 固定间隔的任务最多错过一次调度
+			for next = t.schedule.Next(next); !next.IsZero() && next.Before(now) && missed < 1000; missed++ {
+				next = t.schedule.Next(next)
+			}
+		}
+	}
+	if st.Paused {
+		atomic.StoreInt32(&t.paused, 1)
+	}
+	t.update(func(s *State) {
+		st.Name, st.Paused, st.NextRun = t.name, false, time.Time{}
+		*s = st
+	})
+	t.missed = missed
+}
+
+// first 返回应用启动后首次调度的时间。
+func (t *Task) first(now time.Time) time.Time {
+	now = now.Add(t.initialDelay)
+	if _, ok := t.schedule.(*cron); ok {
+		return t.schedule.Next(now)
+	}
+	return now
+}
+
+// Config 定时任务配置，通常绑定到 schedule 前缀的属性上。
+type Config struct {
+	LockPrefix string        `value:"${lock-prefix:=schedule:}"` // 分布式锁名称的前缀
+	Phase      int           `value:"${phase:=0}"`               // 容器关闭时等待任务执行完的阶段
+	Misfire    string        `value:"${misfire:=skip}"`          // 错过调度的默认处理策略，可选 skip、fire-once、catch-up
+	MaxCatchUp int64         `value:"${max-catch-up:=10}"`       // catch-up 策略最多补执行的次数
+	Jitter     time.Duration `value:"${jitter:=0}"`              // 每次调度随机推迟的默认最长时间
+	Timezone   string        `value:"${timezone:=}"`             // 没有指定时区的 cron 表达式使用的时区，默认为本地时区
+}
+
+// jitterRand 计算随机推迟时间的随机数生成器，使用时间作为种子保证多个实例的随机
+// 序列不同。
+var jitterRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// Store 保存任务的运行状态。
+type Store interface {
+
+	// Load 返回名为 name 的任务保存的运行状态，ok 为 false 时表示没有保存过。
+	Load(ctx context.Context, name string) (st State, ok bool, err error)
+
+	// Save 保存任务的运行状态。
+	Save(ctx context.Context, st State) error
+}
+
+// Scheduler 调度容器中所有的定时任务。
+type Scheduler struct {
+	Tasks   []*Task           `autowire:""`
+	Locker  lock.Locker       `autowire:"?"`
+	Metrics *metrics.Registry `autowire:"?"`
+	Store   Store             `autowire:"?"`
+
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	runs    *metrics.CounterVec
+	latency *metrics.TimerVec
+
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	running sync.WaitGroup // 正在执行的任务
+}
+
+// NewScheduler Scheduler 的构造函数。
+func NewScheduler(config Config) *Scheduler {
+	return &Scheduler{config: &config}
+}
+
+// OnInit 检查任务的配置，注册任务的指标并恢复任务的运行状态。
+func (s *Scheduler) OnInit() error {
+	s.stop = make(chan struct{})
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	if s.config.Misfire == "" {
+		s.config.Misfire = string(MisfireSkip)
+	}
+	if !MisfirePolicy(s.config.Misfire).valid() {
+		return fmt.Errorf("schedule has unknown misfire policy %q", s.config.Misfire)
+	}
+	var loc *time.Location
+	if s.config.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.config.Timezone); err != nil {
+			return fmt.Errorf("schedule has invalid timezone %q: %w", s.config.Timezone, err)
+		}
+	}
+	for _, t := range s.Tasks {
+		if t.lockTTL > 0 && s.Locker == nil {
+			return fmt.Errorf("schedule task %s requires a lock.Locker bean", t.name)
+		}
+		if t.misfire != "" && !t.misfire.valid() {
+			return fmt.Errorf("schedule task %s has unknown misfire policy %q", t.name, t.misfire)
+		}
+		if c, ok := t.schedule.(*cron); ok && loc != nil {
+			t.schedule = c.in(loc)
+		}
+	}
+	if s.Metrics != nil {
+		s.runs = s.Metrics.Counter("schedule_task_runs_total", "Total number of scheduled task runs by outcome.", "task", "outcome")
+		s.latency = s.Metrics.Timer("schedule_task_seconds", "Scheduled task execution time in seconds.", "task")
+	}
+	if s.Store == nil {
+		return nil
+	}
+	now := time.Now()
+	for _, t := range s.Tasks {
+		st, ok, err := s.Store.Load(context.Background(), t.name)
+		if err != nil {
+			return fmt.Errorf("schedule task %s load state error: %w", t.name, err)
+		}
+		if !ok {
+			continue
+		}
+		t.restore(st, now)
+	}
+	return nil
+}
+
+// policy 返回任务错过调度的处理策略。
+func (s *Scheduler) policy(t *Task) MisfirePolicy {
+	if t.misfire != "" {
+		return t.misfire
+	}
+	return MisfirePolicy(s.config.Misfire)
+}
+
+// jitter 返回任务本次调度随机推迟的时间。
+func (s *Scheduler) jitter(t *Task) time.Duration {
+	d := s.config.Jitter
+	if t.jitterSet {
+		d = t.jitter
+	}
+	if d <= 0 {
+		return 0
+	}
+	jitterRand.Lock()
+	defer jitterRand.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(d)))
+}
+
+// Task 返回名为 name 的任务。
+func (s *Scheduler) Task(name string) (*Task, bool) {
+	for _, t := range s.Tasks {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Scheduler) task(name string) (*Task, error) {
+	if t, ok := s.Task(name); ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Trigger 立即执行一次名为 name 的任务，不影响任务原来的调度，暂停的任务也可以
+// 手动触发。上一次手动触发尚未开始执行时忽略本次触发。
+func (s *Scheduler) Trigger(name string) error {
+	t, err := s.task(name)
+	if err != nil {
+		return err
+	}
+	select {
+	case t.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pause 暂停名为 name 的任务，暂停期间到期的调度被跳过。
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, 1)
+}
+
+// Resume 恢复名为 name 的任务的调度。
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, 0)
+}
+
+func (s *Scheduler) setPaused(name string, paused int32) error {
+	t, err := s.task(name)
+	if err != nil {
+		return err
+	}
+	if atomic.SwapInt32(&t.paused, paused) != paused {
+		log.Infof("schedule task %s paused: %v", name, paused == 1)
+		s.save(t)
+	}
+	return nil
+}
+
+// save 保存任务的运行状态，保存失败时只记录日志。
+func (s *Scheduler) save(t *Task) {
+	if s.Store == nil {
+		return
+	}
+	if err := s.Store.Save(context.Background(), t.State()); err != nil {
+		log.Errorf("schedule task %s save state error: %v", t.name, err)
+	}
+}
+
+// OnStartApp 应用启动后开始调度所有的任务。
+func (s *Scheduler) OnStartApp(ctx gs.AppContext) {
+	for _, t := range s.Tasks {
+		t := t
+		ctx.Go(func(ctx context.Context) { s.run(ctx, t) })
+	}
+}
+
+// OnStopApp 任务的调度随着容器的关闭而结束，容器会等待正在执行的任务返回。
+func (s *Scheduler) OnStopApp(ctx gs.AppContext) {}
+
+// Phase 返回容器关闭时等待任务执行完的阶段。
+func (s *Scheduler) Phase() int {
+	return s.config.Phase
+}
+
+// Stop 停止调度新的任务执行。
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+}
+
+// begin 开始执行一次任务，已经停止调度时返回 false 。
+func (s *Scheduler) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.running.Add(1)
+	return true
+}
+
+// Drain 等待正在执行的任务返回，任务的 ctx 在排空之后才会结束。
+func (s *Scheduler) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("schedule: tasks still running: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, t *Task) {
+
+	var wg sync.WaitGroup // 容器在 ctx 结束后还会等待正在执行的任务
+	defer wg.Wait()
+
+	if t.missed > 0 && !s.misfire(ctx, t, t.missed, &wg) {
+		return
+	}
+
+	next := t.first(time.Now())
+	for {
+		if next.IsZero() {
+			log.Warnf("schedule task %s has no next execution time", t.name)
+			return
+		}
+
+		t.update(func(st *State) { st.NextRun = next })
+		s.save(t)
+
+		manual := false
+		timer := time.NewTimer(time.Until(next) + s.jitter(t))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-t.trigger:
+			timer.Stop()
+			manual = true
+		case <-timer.C:
+		}
+
+		if !manual && t.Paused() {
+			s.record(t, "paused")
+		} else if !s.fire(ctx, t, &wg) {
+			return
+		}
+
+		if manual {
+			continue
+		}
+
+		if t.delay > 0 {
+			next = time.Now().Add(t.delay)
+			continue
+		}
+
+		// 执行时间过长导致跳过的调度已经在上面处理，这里只会是进程暂停等原因导致
+		// 错过的调度
+		now := time.Now()
+		var missed int64
+		for next = t.schedule.Next(next); !next.IsZero() && !next.After(now); missed++ {
+			next = t.schedule.Next(next)
+		}
+		if missed > 0 && !s.misfire(ctx, t, missed, &wg) {
+			return
+		}
+	}
+}
+
+// misfire 按照任务的策略处理错过的 n 次调度，需要补执行时先等待正在执行的任务
+// 结束再依次同步执行，暂停的任务不会补执行。已经停止调度时返回 false 。
+func (s *Scheduler) misfire(ctx context.Context, t *Task, n int64, wg *sync.WaitGroup) bool {
+
+	t.update(func(st *State) { st.Missed += n })
+	if s.runs != nil {
+		s.runs.With(t.name, "missed").Add(float64(n))
+	}
+
+	var runs int64
+	switch s.policy(t) {
+	case MisfireFireOnce:
+		runs = 1
+	case MisfireCatchUp:
+		runs = n
+		if max := s.config.MaxCatchUp; max > 0 && runs > max {
+			runs = max
+		}
+	}
+	if t.Paused() {
+		runs = 0
+	}
+	log.Warnf("schedule task %s missed %d runs, %d will be fired", t.name, n, runs)
+
+	if runs > 0 {
+		wg.Wait()
+	}
+	for i := int64(0); i < runs; i++ {
+		if !s.execSync(ctx, t) {
+			return false
+		}
+	}
+	s.save(t)
+	return true
+}
+
+// execSync 在当前协程中执行一次任务，已经停止调度时返回 false 。
+func (s *Scheduler) execSync(ctx context.Context, t *Task) bool {
+	if !s.begin() {
+		return false
+	}
+	atomic.StoreInt32(&t.running, 1)
+	s.execute(ctx, t)
+	atomic.StoreInt32(&t.running, 0)
+	s.running.Done()
+	return true
+}
+
+// fire 执行一次任务，固定间隔的任务同步执行，其他任务异步执行并且跳过上一次
+// 执行尚未结束的调度。已经停止调度时返回 false 。
+func (s *Scheduler) fire(ctx context.Context, t *Task, wg *sync.WaitGroup) bool {
+
+	if t.delay > 0 {
+		return s.execSync(ctx, t)
+	}
+
+	if !s.begin() {
+		return false
+	}
+
+	if atomic.CompareAndSwapInt32(&t.running, 0, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.running.Done()
+			defer atomic.StoreInt32(&t.running, 0)
+			s.execute(ctx, t)
+		}()
+	} else {
+		log.Warnf("schedule task %s is still running, skipped", t.name)
+		s.record(t, "skipped")
+		s.running.Done()
+	}
+	return true
+}
+
+// execute 执行一次任务，执行过程中的 panic 被记录而不会向外传播。
+func (s *Scheduler) execute(ctx context.Context, t *Task) {
+
+	start := time.Now()
+	if t.lockTTL > 0 {
+		_, ok, err := s.Locker.TryLock(ctx, s.config.LockPrefix+t.name, t.lockTTL)
+		if err != nil {
+			log.Errorf("schedule task %s lock error: %v", t.name, err)
+			s.record(t, "failure")
+			s.finish(t, start, "failure", err)
+			return
+		}
+		if !ok {
+			s.record(t, "locked")
+			return
+		}
+	}
+
+	outcome := "success"
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			outcome, err = "panic", fmt.Errorf("%v", r)
+			log.Errorf("schedule task %s panic: %v\n%s", t.name, r, debug.Stack())
+		}
+		s.record(t, outcome)
+		if s.latency != nil {
+			s.latency.With(t.name).Since(start)
+		}
+		s.finish(t, start, outcome, err)
+	}()
+
+	if err = t.fn(ctx); err != nil {
+		outcome = "failure"
+		log.Errorf("schedule task %s error: %v", t.name, err)
+	}
+}
+
+// finish 更新并保存任务最近一次执行的结果。
+func (s *Scheduler) finish(t *Task, start time.Time, outcome string, err error) {
+	t.update(func(st *State) {
+		st.LastRun = start
+		st.LastDuration = time.Since(start)
+		st.LastOutcome = outcome
+		st.LastError = ""
+		st.Runs++
+		if err != nil {
+			st.LastError = err.Error()
+		}
+		if outcome != "success" {
+			st.Failures++
+		}
+	})
+	s.save(t)
+}
+
+func (s *Scheduler) record(t *Task, outcome string) {
+	if s.runs != nil {
+		s.runs.With(t.name, outcome).Inc()
+	}
+}
@@ -0,0 +1,455 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/go-spring/spring-core/lock"
+	"github.com/go-spring/spring-core/metrics"
+	"github.com/go-spring/spring-core/schedule"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestParseCron(t *testing.T) {
+
+	date := func(s string) time.Time {
+		r, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+
+	testcases := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{"0 */5 * * * *", "2021-01-01 00:02:30", "2021-01-01 00:05:00"},
+		{"*/5 * * * *", "2021-01-01 00:05:00", "2021-01-01 00:10:00"},
+		{"0 0 1 * *", "2021-01-15 10:00:00", "2021-02-01 00:00:00"},
+		{"0 0 9 * * MON-FRI", "2021-01-02 10:00:00", "2021-01-04 09:00:00"},
+		{"0 0 0 * * 7", "2021-01-01 00:00:00", "2021-01-03 00:00:00"},
+		{"0 0 0 13 * FRI", "2021-01-01 00:00:00", "2021-01-08 00:00:00"},
+		{"0 0 0 29 FEB ?", "2021-01-01 00:00:00", "2024-02-29 00:00:00"},
+		{"0 30 8,20 * * *", "2021-01-01 09:00:00", "2021-01-01 20:30:00"},
+		{"0 0 10-12/2 * * *", "2021-01-01 10:00:00", "2021-01-01 12:00:00"},
+		{"@daily", "2021-01-01 12:00:00", "2021-01-02 00:00:00"},
+		{"@hourly", "2021-01-01 12:00:00", "2021-01-01 13:00:00"},
+		{"@weekly", "2021-01-01 12:00:00", "2021-01-03 00:00:00"},
+		{"@monthly", "2021-01-01 12:00:00", "2021-02-01 00:00:00"},
+		{"@yearly", "2021-01-01 12:00:00", "2022-01-01 00:00:00"},
+		{"@every 90s", "2021-01-01 12:00:00", "2021-01-01 12:01:30"},
+	}
+
+	for _, c := range testcases {
+		s, err := schedule.ParseCron(c.spec)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Next(date(c.from)), date(c.next))
+	}
+
+	s, err := schedule.ParseCron("TZ=UTC 0 0 8 * * *")
+	assert.Nil(t, err)
+	from := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, s.Next(from), time.Date(2021, 1, 2, 8, 0, 0, 0, time.UTC))
+
+	// 夏令时结束时 01:30 出现两次，只匹配第一次
+	ny, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	s, err = schedule.ParseCron("CRON_TZ=America/New_York 0 30 1 * * *")
+	assert.Nil(t, err)
+	first := time.Date(2021, 11, 7, 5, 30, 0, 0, time.UTC) // 01:30 EDT
+	assert.Equal(t, s.Next(first.Add(-time.Minute)), first)
+	assert.True(t, s.Next(first).Equal(time.Date(2021, 11, 8, 1, 30, 0, 0, ny)))
+
+	s, err = schedule.ParseCron("0 0 0 30 2 *")
+	assert.Nil(t, err)
+	assert.True(t, s.Next(date("2021-01-01 00:00:00")).IsZero())
+
+	for _, spec := range []string{"", "* * * *", "61 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@every 0s", "@often"} {
+		_, err = schedule.ParseCron(spec)
+		assert.NotNil(t, err)
+	}
+}
+
+type appContext struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+func (c *appContext) Go(fn func(ctx context.Context)) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn(c.ctx)
+	}()
+}
+
+// start 启动调度器，返回的函数结束调度并等待所有任务返回。
+func start(t *testing.T, s *schedule.Scheduler) func() {
+	assert.Nil(t, s.OnInit())
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &appContext{ctx: ctx}
+	s.OnStartApp(c)
+	return func() {
+		cancel()
+		c.wg.Wait()
+	}
+}
+
+func runs(r *metrics.Registry, task, outcome string) float64 {
+	for _, f := range r.Gather() {
+		if f.Name != "schedule_task_runs_total" {
+			continue
+		}
+		for _, s := range f.Samples {
+			if s.Values[0] == task && s.Values[1] == outcome {
+				return s.Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestScheduler(t *testing.T) {
+
+	t.Run("panic", func(t *testing.T) {
+		var n int32
+		task := schedule.NewFixedRate(5*time.Millisecond, func(ctx context.Context) error {
+			if atomic.AddInt32(&n, 1)%2 == 1 {
+				panic("boom")
+			}
+			return errors.New("error")
+		}, schedule.Name("panic"))
+		r := metrics.NewRegistry()
+		stop := start(t, &schedule.Scheduler{Tasks: []*schedule.Task{task}, Metrics: r})
+		time.Sleep(50 * time.Millisecond)
+		stop()
+		assert.True(t, atomic.LoadInt32(&n) >= 2)
+		assert.True(t, runs(r, "panic", "panic") >= 1)
+		assert.True(t, runs(r, "panic", "failure") >= 1)
+	})
+
+	t.Run("overlap", func(t *testing.T) {
+		var running, max int32
+		task := schedule.NewFixedRate(5*time.Millisecond, func(ctx context.Context) error {
+			if v := atomic.AddInt32(&running, 1); v > atomic.LoadInt32(&max) {
+				atomic.StoreInt32(&max, v)
+			}
+			defer atomic.AddInt32(&running, -1)
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}, schedule.Name("overlap"))
+		r := metrics.NewRegistry()
+		stop := start(t, &schedule.Scheduler{Tasks: []*schedule.Task{task}, Metrics: r})
+		time.Sleep(60 * time.Millisecond)
+		stop()
+		assert.Equal(t, atomic.LoadInt32(&max), int32(1))
+		assert.True(t, runs(r, "overlap", "success") >= 1)
+		assert.True(t, runs(r, "overlap", "skipped") >= 1)
+	})
+
+	t.Run("fixed delay", func(t *testing.T) {
+		var times []time.Time
+		task := schedule.NewFixedDelay(10*time.Millisecond, func(ctx context.Context) error {
+			times = append(times, time.Now())
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+		stop := start(t, &schedule.Scheduler{Tasks: []*schedule.Task{task}})
+		time.Sleep(70 * time.Millisecond)
+		stop()
+		assert.True(t, len(times) >= 2)
+		for i := 1; i < len(times); i++ {
+			assert.True(t, times[i].Sub(times[i-1]) >= 20*time.Millisecond)
+		}
+	})
+
+	t.Run("initial delay", func(t *testing.T) {
+		var n int32
+		task := schedule.NewFixedRate(time.Millisecond, func(ctx context.Context) error {
+			atomic.AddInt32(&n, 1)
+			return nil
+		}, schedule.InitialDelay(time.Hour))
+		stop := start(t, &schedule.Scheduler{Tasks: []*schedule.Task{task}})
+		time.Sleep(20 * time.Millisecond)
+		stop()
+		assert.Equal(t, atomic.LoadInt32(&n), int32(0))
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		var n int32
+		fn := func(ctx context.Context) error {
+			atomic.AddInt32(&n, 1)
+			return nil
+		}
+		locker := lock.NewMemoryLocker()
+		r := metrics.NewRegistry()
+		var stops []func()
+		for i := 0; i < 2; i++ {
+			task := schedule.NewFixedRate(5*time.Millisecond, fn, schedule.Name("sync"), schedule.Lock(time.Hour))
+			s := schedule.NewScheduler(schedule.Config{LockPrefix: "schedule:"})
+			s.Tasks, s.Locker, s.Metrics = []*schedule.Task{task}, locker, r
+			stops = append(stops, start(t, s))
+		}
+		time.Sleep(40 * time.Millisecond)
+		for _, stop := range stops {
+			stop()
+		}
+		assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+		assert.True(t, runs(r, "sync", "locked") >= 1)
+	})
+
+	t.Run("no locker", func(t *testing.T) {
+		task := schedule.NewFixedRate(time.Second, func(ctx context.Context) error { return nil },
+			schedule.Name("sync"), schedule.Lock(time.Minute))
+		s := &schedule.Scheduler{Tasks: []*schedule.Task{task}}
+		assert.Error(t, s.OnInit(), "schedule task sync requires a lock.Locker bean")
+	})
+}
+
+func TestScheduler_Drain(t *testing.T) {
+
+	var started, finished int32
+	task := schedule.NewFixedRate(5*time.Millisecond, func(ctx context.Context) error {
+		atomic.AddInt32(&started, 1)
+		time.Sleep(30 * time.Millisecond)
+		assert.Nil(t, ctx.Err()) // 排空期间任务的 ctx 不会结束
+		atomic.AddInt32(&finished, 1)
+		return nil
+	}, schedule.Name("drain"))
+
+	s := schedule.NewScheduler(schedule.Config{})
+	s.Tasks = []*schedule.Task{task}
+	stop := start(t, s)
+	time.Sleep(10 * time.Millisecond)
+
+	s.Stop()
+	err := s.Drain(context.Background())
+	assert.Nil(t, err)
+	n := atomic.LoadInt32(&started)
+	assert.Equal(t, atomic.LoadInt32(&finished), n)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, atomic.LoadInt32(&started), n)
+	stop()
+}
+
+func TestScheduler_Manage(t *testing.T) {
+
+	var n int32
+	task := schedule.NewFixedRate(time.Hour, func(ctx context.Context) error {
+		if atomic.AddInt32(&n, 1) == 2 {
+			return errors.New("boom")
+		}
+		return nil
+	}, schedule.Name("report"), schedule.InitialDelay(time.Hour))
+
+	store := schedule.NewMemoryStore()
+	s := &schedule.Scheduler{Tasks: []*schedule.Task{task}, Store: store}
+	stop := start(t, s)
+	defer stop()
+
+	assert.Error(t, s.Trigger("unknown"), "schedule: task not found: unknown")
+	assert.Nil(t, s.Trigger("report"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+
+	// 暂停的任务也可以手动触发
+	assert.Nil(t, s.Pause("report"))
+	assert.Nil(t, s.Trigger("report"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, atomic.LoadInt32(&n), int32(2))
+
+	st := task.State()
+	assert.True(t, st.Paused)
+	assert.Equal(t, st.Runs, int64(2))
+	assert.Equal(t, st.Failures, int64(1))
+	assert.Equal(t, st.LastOutcome, "failure")
+	assert.Equal(t, st.LastError, "boom")
+	assert.True(t, st.NextRun.After(time.Now().Add(50*time.Minute)))
+
+	saved, ok, err := store.Load(context.Background(), "report")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, saved, st)
+
+	assert.Nil(t, s.Resume("report"))
+	assert.False(t, task.State().Paused)
+}
+
+func TestScheduler_Restore(t *testing.T) {
+
+	testcases := []struct {
+		name   string
+		paused bool
+		opts   []schedule.Option
+		config schedule.Config
+		runs   int32
+	}{
+		{name: "skip", runs: 0},
+		{name: "fire once", opts: []schedule.Option{schedule.Misfire(schedule.MisfireFireOnce)}, runs: 1},
+		{name: "catch up", config: schedule.Config{Misfire: "catch-up", MaxCatchUp: 2}, runs: 2},
+		{name: "paused", paused: true, opts: []schedule.Option{schedule.Misfire(schedule.MisfireCatchUp)}, runs: 0},
+	}
+
+	for _, c := range testcases {
+		t.Run(c.name, func(t *testing.T) {
+
+			ctx := context.Background()
+			store := schedule.NewMemoryStore()
+			err := store.Save(ctx, schedule.State{
+				Name:    "sync",
+				Paused:  c.paused,
+				NextRun: time.Now().Add(-25 * time.Millisecond),
+				Runs:    3,
+			})
+			assert.Nil(t, err)
+
+			var n int32
+			opts := append([]schedule.Option{schedule.Name("sync"), schedule.InitialDelay(time.Hour)}, c.opts...)
+			task := schedule.NewFixedRate(10*time.Millisecond, func(ctx context.Context) error {
+				atomic.AddInt32(&n, 1)
+				return nil
+			}, opts...)
+
+			r := metrics.NewRegistry()
+			s := schedule.NewScheduler(c.config)
+			s.Tasks, s.Store, s.Metrics = []*schedule.Task{task}, store, r
+			stop := start(t, s)
+			time.Sleep(20 * time.Millisecond)
+			stop()
+
+			// 错过了 -25ms、-15ms、-5ms 三次调度
+			st := task.State()
+			assert.Equal(t, st.Paused, c.paused)
+			assert.Equal(t, st.Missed, int64(3))
+			assert.Equal(t, st.Runs, int64(3+c.runs))
+			assert.Equal(t, atomic.LoadInt32(&n), c.runs)
+			assert.Equal(t, runs(r, "sync", "missed"), float64(3))
+		})
+	}
+
+	s := schedule.NewScheduler(schedule.Config{Misfire: "never"})
+	assert.Error(t, s.OnInit(), "schedule has unknown misfire policy \"never\"")
+	s = schedule.NewScheduler(schedule.Config{Timezone: "Mars/Olympus"})
+	assert.Error(t, s.OnInit(), "schedule has invalid timezone \"Mars/Olympus\"")
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+
+	var (
+		mu     sync.Mutex
+		offset []time.Duration
+		tasks  []*schedule.Task
+	)
+	begin := time.Now()
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, schedule.NewFixedRate(time.Hour, func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			offset = append(offset, time.Since(begin))
+			return nil
+		}, schedule.Jitter(30*time.Millisecond)))
+	}
+
+	stop := start(t, &schedule.Scheduler{Tasks: tasks})
+	time.Sleep(60 * time.Millisecond)
+	stop()
+
+	// 首次调度随机推迟 [0, 30ms)，多个任务不会在同一时刻执行
+	assert.Equal(t, len(offset), 5)
+	min, max := offset[0], offset[0]
+	for _, d := range offset {
+		assert.True(t, d < 40*time.Millisecond)
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	assert.True(t, max-min > time.Millisecond)
+}
+
+func TestScheduler_Timezone(t *testing.T) {
+
+	var n int32
+	task, err := schedule.NewCron("0 0 8 * * *", func(ctx context.Context) error {
+		atomic.AddInt32(&n, 1)
+		return nil
+	}, schedule.Name("morning"))
+	assert.Nil(t, err)
+	explicit, err := schedule.NewCron("TZ=Asia/Tokyo 0 0 8 * * *", func(ctx context.Context) error {
+		return nil
+	}, schedule.Name("tokyo"))
+	assert.Nil(t, err)
+
+	s := schedule.NewScheduler(schedule.Config{Timezone: "UTC"})
+	s.Tasks = []*schedule.Task{task, explicit}
+	stop := start(t, s)
+	time.Sleep(10 * time.Millisecond)
+	stop()
+
+	next := task.State().NextRun.In(time.UTC)
+	assert.Equal(t, next.Hour(), 8)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.Nil(t, err)
+	assert.Equal(t, explicit.State().NextRun.In(tokyo).Hour(), 8)
+}
+
+func TestEndpoint(t *testing.T) {
+
+	task, err := schedule.NewCron("0 0 * * * *", func(ctx context.Context) error {
+		return nil
+	}, schedule.Name("hourly"))
+	assert.Nil(t, err)
+	s := &schedule.Scheduler{Tasks: []*schedule.Task{task}}
+	stop := start(t, s)
+	defer stop()
+	e := &schedule.Endpoint{Scheduler: s}
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	assert.Equal(t, serve(http.MethodPost, "/?name=hourly&action=pause").Code, http.StatusNoContent)
+	assert.Equal(t, serve(http.MethodPost, "/?name=daily&action=pause").Code, http.StatusNotFound)
+	assert.Equal(t, serve(http.MethodPost, "/?name=hourly&action=stop").Code, http.StatusBadRequest)
+
+	w := serve(http.MethodGet, "/")
+	assert.Equal(t, w.Code, http.StatusOK)
+	var resp struct {
+		Tasks []map[string]interface{} `json:"tasks"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, len(resp.Tasks), 1)
+	assert.Equal(t, resp.Tasks[0]["name"], "hourly")
+	assert.Equal(t, resp.Tasks[0]["schedule"], "cron 0 0 * * * *")
+	assert.Equal(t, resp.Tasks[0]["paused"], true)
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore 基于内存的 Store 实现，只在单个进程内有效，主要用于测试。
+type memoryStore struct {
+	mutex  sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStore 创建基于内存的 Store 。
+func NewMemoryStore() Store {
+	return &memoryStore{states: make(map[string]State)}
+}
+
+func (s *memoryStore) Load(ctx context.Context, name string) (State, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	st, ok := s.states[name]
+	return st, ok, nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, st State) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[st.Name] = st
+	return nil
+}
+
+// StoreConfig 数据库存储的配置，通常绑定到 schedule.store 前缀的属性上。
+type StoreConfig struct {
+	Table   string `value:"${table:=schedule_task_state}"` // 任务状态表
+	Dialect string `value:"${dialect:=mysql}"`             // 数据库方言，决定 SQL 占位符的格式
+}
+
+// sqlStore 基于数据库的 Store 实现，时间以毫秒时间戳保存。任务状态表需要预先
+// 创建，以 MySQL 为例:
+//
+//	CREATE TABLE schedule_task_state (
+//		name VARCHAR(255) NOT NULL PRIMARY KEY,
+//		paused INT NOT NULL,
+//		last_run BIGINT NOT NULL,
+//		last_duration BIGINT NOT NULL,
+//		last_outcome VARCHAR(32) NOT NULL,
+//		last_error TEXT NOT NULL,
+//		next_run BIGINT NOT NULL,
+//		runs BIGINT NOT NULL,
+//		failures BIGINT NOT NULL,
+//		missed BIGINT NOT NULL
+//	)
+type sqlStore struct {
+	db     *sql.DB
+	config *StoreConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewSQLStore 创建基于数据库的 Store 。
+func NewSQLStore(db *sql.DB, config StoreConfig) Store {
+	return &sqlStore{db: db, config: &config}
+}
+
+const stateColumns = "paused, last_run, last_duration, last_outcome, last_error, next_run, runs, failures, missed"
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func (s *sqlStore) placeholder(n int) string {
+	if s.config.Dialect == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (s *sqlStore) Load(ctx context.Context, name string) (State, bool, error) {
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE name = %s", stateColumns, s.config.Table, s.placeholder(1))
+	var (
+		st                             State
+		paused                         int
+		lastRun, lastDuration, nextRun int64
+	)
+	err := s.db.QueryRowContext(ctx, query, name).Scan(&paused, &lastRun, &lastDuration,
+		&st.LastOutcome, &st.LastError, &nextRun, &st.Runs, &st.Failures, &st.Missed)
+	if err == sql.ErrNoRows {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, err
+	}
+
+	st.Name = name
+	st.Paused = paused == 1
+	st.LastRun = fromMillis(lastRun)
+	st.LastDuration = time.Duration(lastDuration) * time.Millisecond
+	st.NextRun = fromMillis(nextRun)
+	return st, true, nil
+}
+
+// Save 先更新任务的状态，记录不存在时再插入。
+func (s *sqlStore) Save(ctx context.Context, st State) error {
+
+	paused := 0
+	if st.Paused {
+		paused = 1
+	}
+	args := []interface{}{paused, millis(st.LastRun), st.LastDuration.Milliseconds(),
+		st.LastOutcome, st.LastError, millis(st.NextRun), st.Runs, st.Failures, st.Missed, st.Name}
+
+	columns := strings.Split(stateColumns, ", ")
+	sets := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = c + " = " + s.placeholder(i+1)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE name = %s",
+		s.config.Table, strings.Join(sets, ", "), s.placeholder(len(args)))
+	r, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := r.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	marks := make([]string, len(args))
+	for i := range marks {
+		marks[i] = s.placeholder(i + 1)
+	}
+	query = fmt.Sprintf("INSERT INTO %s (%s, name) VALUES (%s)",
+		s.config.Table, stateColumns, strings.Join(marks, ", "))
+	if _, err = s.db.ExecContext(ctx, query, args...); err != nil {
+		// MySQL 在更新的值没有变化时返回的影响行数为 0 ，此时记录已经存在
+		if _, ok, e := s.Load(ctx, st.Name); e == nil && ok {
+			return nil
+		}
+	}
+	return err
+}
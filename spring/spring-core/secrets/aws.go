@@ -0,0 +1,144 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// AWSConfig AWS Secrets Manager 配置，通常绑定到 secrets.aws 前缀的属性上。访问
+// 凭证为空时使用 AWS_ACCESS_KEY_ID 等标准环境变量。
+type AWSConfig struct {
+	Region          string `value:"${region:=}"`            // 区域，为空时使用 AWS_REGION 环境变量
+	AccessKeyID     string `value:"${access-key-id:=}"`     // 访问密钥 ID
+	SecretAccessKey string `value:"${secret-access-key:=}"` // 访问密钥
+	SessionToken    string `value:"${session-token:=}"`     // 临时凭证的会话令牌
+	Endpoint        string `value:"${endpoint:=}"`          // 服务地址，为空时根据区域生成
+}
+
+// AWS 通过 HTTP API 访问 AWS Secrets Manager 的 Provider ，请求使用 SigV4 签名。
+type AWS struct {
+	config *AWSConfig
+	client *http.Client
+}
+
+// NewAWS AWS 的构造函数。
+func NewAWS(config AWSConfig) (*AWS, error) {
+	env := func(s *string, key string) {
+		if *s == "" {
+			*s = os.Getenv(key)
+		}
+	}
+	env(&config.Region, "AWS_REGION")
+	env(&config.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	env(&config.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	env(&config.SessionToken, "AWS_SESSION_TOKEN")
+	if config.Region == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("secrets: aws region and credentials required")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://secretsmanager." + config.Region + ".amazonaws.com"
+	}
+	return &AWS{config: &config, client: &http.Client{}}, nil
+}
+
+func newAWS(p *conf.Properties) (Provider, error) {
+	var config AWSConfig
+	if err := p.Bind(&config, conf.Key("secrets.aws")); err != nil {
+		return nil, err
+	}
+	return NewAWS(config)
+}
+
+func (a *AWS) Get(ctx context.Context, path string) (*Secret, error) {
+
+	body, _ := json.Marshal(map[string]string{"SecretId": path})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, body)
+
+	var resp struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err = doJSON(a.client, req, &resp); err != nil {
+		return nil, err
+	}
+	return &Secret{Data: parsePayload(resp.SecretString)}, nil
+}
+
+// sign 使用 AWS Signature Version 4 对请求进行签名。
+func (a *AWS) sign(req *http.Request, body []byte) {
+
+	const service = "secretsmanager"
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	if a.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.config.SessionToken)
+		signedHeaders = "content-type;host;x-amz-date;x-amz-security-token;x-amz-target"
+	}
+
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	if a.config.SessionToken != "" {
+		canonicalHeaders += "x-amz-security-token:" + a.config.SessionToken + "\n"
+	}
+	canonicalHeaders += "x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+
+	canonicalRequest := req.Method + "\n/\n\n" + canonicalHeaders + "\n" + signedHeaders + "\n" + sha256Hex(body)
+	scope := date + "/" + a.config.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.config.SecretAccessKey), date)
+	key = hmacSHA256(key, a.config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+a.config.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(s))
+	return m.Sum(nil)
+}
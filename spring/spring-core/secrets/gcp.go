@@ -0,0 +1,131 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// GCPConfig GCP Secret Manager 配置，通常绑定到 secrets.gcp 前缀的属性上。
+type GCPConfig struct {
+	Project     string `value:"${project}"`                                                          // 项目 ID
+	AccessToken string `value:"${access-token:=}"`                                                   // 访问令牌，为空时从元数据服务获取
+	Endpoint    string `value:"${endpoint:=https://secretmanager.googleapis.com}"`                   // 服务地址
+	MetadataURL string `value:"${metadata-url:=http://metadata.google.internal/computeMetadata/v1}"` // 元数据服务地址
+}
+
+// GCP 通过 HTTP API 访问 GCP Secret Manager 的 Provider ，path 可以使用
+// name@version 的形式指定版本，默认使用最新版本。
+type GCP struct {
+	config *GCPConfig
+	client *http.Client
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewGCP GCP 的构造函数。
+func NewGCP(config GCPConfig) (*GCP, error) {
+	if config.Project == "" {
+		return nil, fmt.Errorf("secrets: gcp project required")
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &GCP{config: &config, client: &http.Client{}}, nil
+}
+
+func newGCP(p *conf.Properties) (Provider, error) {
+	var config GCPConfig
+	if err := p.Bind(&config, conf.Key("secrets.gcp")); err != nil {
+		return nil, err
+	}
+	return NewGCP(config)
+}
+
+func (g *GCP) Get(ctx context.Context, path string) (*Secret, error) {
+
+	name, version := path, "latest"
+	if i := strings.LastIndexByte(path, '@'); i >= 0 {
+		name, version = path[:i], path[i+1:]
+	}
+
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/%s:access", g.config.Endpoint, g.config.Project, name, version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err = doJSON(g.client, req, &resp); err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{Data: parsePayload(string(b))}, nil
+}
+
+// accessToken 返回访问令牌，没有配置时从元数据服务获取并缓存到过期前一分钟。
+func (g *GCP) accessToken(ctx context.Context) (string, error) {
+
+	if g.config.AccessToken != "" {
+		return g.config.AccessToken, nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+
+	url := g.config.MetadataURL + "/instance/service-accounts/default/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = doJSON(g.client, req, &resp); err != nil {
+		return "", fmt.Errorf("get gcp access token error: %w", err)
+	}
+	g.token = resp.AccessToken
+	g.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return g.token, nil
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secrets 在属性绑定时把 secret://path#key 形式的属性值解析为保存在密钥
+// 管理服务中的值，使数据库密码等敏感信息不需要出现在配置文件或者环境变量中。解析
+// 的结果会被缓存，有租期的密钥会在到期之前自动续租。内置了 Vault 、AWS Secrets
+// Manager 和 GCP Secret Manager 三种 Provider ，也可以通过 Register 方法注册
+// 自定义的 Provider 。
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/log"
+)
+
+// Scheme 密钥引用的前缀。
+const Scheme = "secret"
+
+// Secret 从密钥管理服务中获取的密钥。
+type Secret struct {
+	Data      map[string]string // 密钥的内容，不是键值对形式的密钥保存在空字符串键下
+	LeaseID   string            // 租约 ID ，用于续租
+	Lease     time.Duration     // 租期，0 表示使用缓存的有效期
+	Renewable bool              // 是否可以续租
+}
+
+// Provider 密钥管理服务。
+type Provider interface {
+	Get(ctx context.Context, path string) (*Secret, error)
+}
+
+// Renewer 可以由 Provider 实现，对可以续租的密钥进行续租，返回续租后的密钥。
+type Renewer interface {
+	Renew(ctx context.Context, s *Secret) (*Secret, error)
+}
+
+// Factory 使用属性列表创建 Provider 。
+type Factory func(p *conf.Properties) (Provider, error)
+
+var factories = map[string]Factory{
+	"vault": newVault,
+	"aws":   newAWS,
+	"gcp":   newGCP,
+}
+
+// Register 注册名为 name 的 Provider ，通过 secrets.provider 属性选择使用。
+func Register(name string, f Factory) {
+	factories[name] = f
+}
+
+// ParseRef 解析 secret://path#key 形式的密钥引用。
+func ParseRef(ref string) (path, key string, err error) {
+	s := strings.TrimPrefix(ref, Scheme+"://")
+	if s == ref {
+		return "", "", fmt.Errorf("secrets: invalid reference %q", ref)
+	}
+	if i := strings.LastIndexByte(s, '#'); i >= 0 {
+		s, key = s[:i], s[i+1:]
+	}
+	if s == "" {
+		return "", "", fmt.Errorf("secrets: invalid reference %q", ref)
+	}
+	return s, key, nil
+}
+
+// Config 密钥解析配置，通常绑定到 secrets 前缀的属性上。
+type Config struct {
+	Provider      string        `value:"${provider}"`            // 使用的 Provider
+	CacheTTL      time.Duration `value:"${cache-ttl:=5m}"`       // 没有租期的密钥的缓存有效期
+	RenewInterval time.Duration `value:"${renew-interval:=30s}"` // 检查租期的时间间隔
+	Timeout       time.Duration `value:"${timeout:=10s}"`        // 获取密钥的超时时间
+}
+
+// Store 带有缓存的密钥解析器。
+type Store struct {
+	provider Provider
+	config   *Config
+
+	mutex sync.Mutex
+	cache map[string]*entry
+}
+
+type entry struct {
+	secret  *Secret
+	expires time.Time
+}
+
+// NewStore Store 的构造函数。
+func NewStore(provider Provider, config Config) *Store {
+	return &Store{
+		provider: provider,
+		config:   &config,
+		cache:    make(map[string]*entry),
+	}
+}
+
+// Setup 使用 secrets 前缀的属性创建 Store ，并将其注册为 secret:// 属性值的解析
+// 器。
+func Setup(p *conf.Properties) (*Store, error) {
+	var config Config
+	if err := p.Bind(&config, conf.Key("secrets")); err != nil {
+		return nil, err
+	}
+	f, ok := factories[config.Provider]
+	if !ok {
+		return nil, fmt.Errorf("secrets: unknown provider %q", config.Provider)
+	}
+	provider, err := f(p)
+	if err != nil {
+		return nil, err
+	}
+	s := NewStore(provider, config)
+	conf.NewResolver(s.Resolve, Scheme)
+	return s, nil
+}
+
+// Resolve 返回密钥引用对应的值，实现了 conf.ValueResolver 。
+func (s *Store) Resolve(ref string) (string, error) {
+	path, key, err := ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	secret, err := s.Get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	v, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secrets: key %q not found in %q", key, path)
+	}
+	return v, nil
+}
+
+// Get 返回 path 对应的密钥，优先使用缓存。
+func (s *Store) Get(ctx context.Context, path string) (*Secret, error) {
+
+	s.mutex.Lock()
+	e, ok := s.cache[path]
+	s.mutex.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.secret, nil
+	}
+
+	secret, err := s.provider.Get(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: get %q error: %w", path, err)
+	}
+	s.put(path, secret)
+	return secret, nil
+}
+
+func (s *Store) put(path string, secret *Secret) {
+	ttl := s.config.CacheTTL
+	if secret.Lease > 0 {
+		ttl = secret.Lease
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cache[path] = &entry{secret: secret, expires: time.Now().Add(ttl)}
+}
+
+// Renew 对剩余租期不足三分之一的密钥进行续租，Provider 不支持续租或者续租失败的
+// 密钥被移出缓存，下次使用时重新获取。
+func (s *Store) Renew(ctx context.Context) {
+
+	s.mutex.Lock()
+	var paths []string
+	now := time.Now()
+	for path, e := range s.cache {
+		if e.secret.Lease <= 0 || e.expires.Sub(now) > e.secret.Lease/3 {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	s.mutex.Unlock()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		s.mutex.Lock()
+		e := s.cache[path]
+		s.mutex.Unlock()
+		if e == nil {
+			continue
+		}
+		r, ok := s.provider.(Renewer)
+		if !ok || !e.secret.Renewable {
+			s.evict(path)
+			continue
+		}
+		secret, err := r.Renew(ctx, e.secret)
+		if err != nil {
+			log.Errorf("secrets: renew %q error: %v", path, err)
+			s.evict(path)
+			continue
+		}
+		s.put(path, secret)
+	}
+}
+
+func (s *Store) evict(path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.cache, path)
+}
+
+// Run 定期对密钥进行续租，直到 ctx 结束。
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Renew(ctx)
+		}
+	}
+}
+
+// errStatus 返回密钥管理服务的错误响应。
+func errStatus(status int, body []byte) error {
+	if len(body) > 256 {
+		body = body[:256]
+	}
+	return fmt.Errorf("status %d: %s", status, strings.TrimSpace(string(body)))
+}
+
+// ErrNotFound 密钥不存在。
+var ErrNotFound = errors.New("secrets: not found")
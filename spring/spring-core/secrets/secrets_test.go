@@ -0,0 +1,251 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/secrets"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type fakeProvider struct {
+	calls   int
+	renews  int
+	secrets map[string]*secrets.Secret
+}
+
+func (p *fakeProvider) Get(ctx context.Context, path string) (*secrets.Secret, error) {
+	p.calls++
+	if s, ok := p.secrets[path]; ok {
+		return s, nil
+	}
+	return nil, secrets.ErrNotFound
+}
+
+func (p *fakeProvider) Renew(ctx context.Context, s *secrets.Secret) (*secrets.Secret, error) {
+	p.renews++
+	return &secrets.Secret{Data: s.Data, LeaseID: s.LeaseID, Lease: time.Hour, Renewable: true}, nil
+}
+
+func config() secrets.Config {
+	return secrets.Config{CacheTTL: time.Minute, RenewInterval: time.Second, Timeout: time.Second}
+}
+
+func TestParseRef(t *testing.T) {
+	path, key, err := secrets.ParseRef("secret://db/prod#password")
+	assert.Nil(t, err)
+	assert.Equal(t, path, "db/prod")
+	assert.Equal(t, key, "password")
+
+	path, key, err = secrets.ParseRef("secret://api-token")
+	assert.Nil(t, err)
+	assert.Equal(t, path, "api-token")
+	assert.Equal(t, key, "")
+
+	_, _, err = secrets.ParseRef("vault://db")
+	assert.Error(t, err, "invalid reference")
+	_, _, err = secrets.ParseRef("secret://#password")
+	assert.Error(t, err, "invalid reference")
+}
+
+func TestStore(t *testing.T) {
+
+	p := &fakeProvider{secrets: map[string]*secrets.Secret{
+		"db":      {Data: map[string]string{"password": "p@ss"}},
+		"dynamic": {Data: map[string]string{"username": "v-123"}, LeaseID: "l1", Lease: 30 * time.Millisecond, Renewable: true},
+		"static":  {Data: map[string]string{"": "token"}, Lease: 30 * time.Millisecond},
+	}}
+	s := secrets.NewStore(p, config())
+
+	v, err := s.Resolve("secret://db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, v, "p@ss")
+	_, err = s.Resolve("secret://db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, p.calls, 1)
+
+	_, err = s.Resolve("secret://db#user")
+	assert.Error(t, err, "secrets: key \"user\" not found in \"db\"")
+
+	_, err = s.Resolve("secret://missing#user")
+	assert.True(t, errors.Is(err, secrets.ErrNotFound))
+
+	v, err = s.Resolve("secret://static")
+	assert.Nil(t, err)
+	assert.Equal(t, v, "token")
+	_, err = s.Resolve("secret://dynamic#username")
+	assert.Nil(t, err)
+	assert.Equal(t, p.calls, 4)
+
+	time.Sleep(25 * time.Millisecond)
+	s.Renew(context.Background())
+	assert.Equal(t, p.renews, 1)
+
+	// 续租后的密钥使用缓存，不能续租的密钥被移出缓存后重新获取
+	_, err = s.Resolve("secret://dynamic#username")
+	assert.Nil(t, err)
+	_, err = s.Resolve("secret://static")
+	assert.Nil(t, err)
+	assert.Equal(t, p.calls, 5)
+}
+
+type dbConfig struct {
+	URL      string `value:"${url}"`
+	Password string `value:"${password}"`
+}
+
+func TestSetup(t *testing.T) {
+
+	p := &fakeProvider{secrets: map[string]*secrets.Secret{
+		"db": {Data: map[string]string{"password": "p@ss"}},
+	}}
+	secrets.Register("fake", func(*conf.Properties) (secrets.Provider, error) {
+		return p, nil
+	})
+
+	props := conf.New()
+	props.Set("secrets.provider", "fake")
+	props.Set("db.url", "postgres://localhost/app")
+	props.Set("db.password", "secret://db#password")
+
+	_, err := secrets.Setup(props)
+	assert.Nil(t, err)
+	defer conf.NewResolver(nil, secrets.Scheme)
+
+	var c dbConfig
+	err = props.Bind(&c, conf.Key("db"))
+	assert.Nil(t, err)
+	assert.Equal(t, c, dbConfig{URL: "postgres://localhost/app", Password: "p@ss"})
+
+	props.Set("secrets.provider", "unknown")
+	_, err = secrets.Setup(props)
+	assert.Error(t, err, "secrets: unknown provider \"unknown\"")
+}
+
+func TestVault(t *testing.T) {
+
+	var renewed map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("X-Vault-Token"), "root")
+		switch r.URL.Path {
+		case "/v1/secret/data/db/prod":
+			w.Write([]byte(`{"data":{"data":{"password":"p@ss","port":5432}}}`))
+		case "/v1/database/creds/app":
+			w.Write([]byte(`{"lease_id":"database/creds/app/l1","lease_duration":60,"renewable":true,"data":{"username":"v-app"}}`))
+		case "/v1/sys/leases/renew":
+			_ = json.NewDecoder(r.Body).Decode(&renewed)
+			w.Write([]byte(`{"lease_id":"database/creds/app/l1","lease_duration":120,"renewable":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	_, err := secrets.NewVault(secrets.VaultConfig{Address: srv.URL})
+	assert.Error(t, err, "vault token required")
+
+	v, err := secrets.NewVault(secrets.VaultConfig{Address: srv.URL, Token: "root", Mount: "secret", KVVersion: 2})
+	assert.Nil(t, err)
+	s, err := v.Get(context.Background(), "db/prod")
+	assert.Nil(t, err)
+	assert.Equal(t, s.Data, map[string]string{"password": "p@ss", "port": "5432"})
+
+	_, err = v.Get(context.Background(), "db/dev")
+	assert.True(t, errors.Is(err, secrets.ErrNotFound))
+
+	v, err = secrets.NewVault(secrets.VaultConfig{Address: srv.URL, Token: "root"})
+	assert.Nil(t, err)
+	s, err = v.Get(context.Background(), "database/creds/app")
+	assert.Nil(t, err)
+	assert.Equal(t, s.Lease, time.Minute)
+	assert.True(t, s.Renewable)
+
+	s, err = v.Renew(context.Background(), s)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Lease, 2*time.Minute)
+	assert.Equal(t, s.Data["username"], "v-app")
+	assert.Equal(t, renewed["lease_id"], "database/creds/app/l1")
+}
+
+func TestAWS(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.True(t, strings.Contains(auth, "/us-east-1/secretsmanager/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature="))
+		assert.Equal(t, r.Header.Get("X-Amz-Target"), "secretsmanager.GetSecretValue")
+		assert.Equal(t, r.Header.Get("X-Amz-Security-Token"), "session")
+		b, _ := io.ReadAll(r.Body)
+		assert.Equal(t, string(b), `{"SecretId":"prod/db"}`)
+		w.Write([]byte(`{"SecretString":"{\"password\":\"p@ss\"}"}`))
+	}))
+	defer srv.Close()
+
+	a, err := secrets.NewAWS(secrets.AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SessionToken:    "session",
+		Endpoint:        srv.URL,
+	})
+	assert.Nil(t, err)
+	s, err := a.Get(context.Background(), "prod/db")
+	assert.Nil(t, err)
+	assert.Equal(t, s.Data["password"], "p@ss")
+	assert.Equal(t, s.Data[""], `{"password":"p@ss"}`)
+}
+
+func TestGCP(t *testing.T) {
+
+	tokens := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/instance/service-accounts/default/token":
+			tokens++
+			assert.Equal(t, r.Header.Get("Metadata-Flavor"), "Google")
+			w.Write([]byte(`{"access_token":"ya29","expires_in":3600}`))
+		case "/v1/projects/demo/secrets/api-key/versions/latest:access",
+			"/v1/projects/demo/secrets/api-key/versions/3:access":
+			assert.Equal(t, r.Header.Get("Authorization"), "Bearer ya29")
+			data := base64.StdEncoding.EncodeToString([]byte("k-123"))
+			w.Write([]byte(`{"payload":{"data":"` + data + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := secrets.NewGCP(secrets.GCPConfig{Project: "demo", Endpoint: srv.URL, MetadataURL: srv.URL + "/metadata"})
+	assert.Nil(t, err)
+	s, err := g.Get(context.Background(), "api-key")
+	assert.Nil(t, err)
+	assert.Equal(t, s.Data, map[string]string{"": "k-123"})
+	_, err = g.Get(context.Background(), "api-key@3")
+	assert.Nil(t, err)
+	assert.Equal(t, tokens, 1)
+}
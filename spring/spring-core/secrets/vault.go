@@ -0,0 +1,188 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// VaultConfig Vault 配置，通常绑定到 secrets.vault 前缀的属性上。
+type VaultConfig struct {
+	Address   string `value:"${address:=http://127.0.0.1:8200}"` // Vault 服务的地址
+	Token     string `value:"${token:=}"`                        // 访问令牌，为空时使用 VAULT_TOKEN 环境变量
+	TokenFile string `value:"${token-file:=}"`                   // 保存访问令牌的文件，例如 Vault Agent 输出的令牌
+	Mount     string `value:"${mount:=secret}"`                  // KV 引擎的挂载路径，为空时直接访问 path ，用于数据库等动态密钥
+	KVVersion int    `value:"${kv-version:=2}"`                  // KV 引擎的版本
+}
+
+// Vault 通过 HTTP API 访问 HashiCorp Vault 的 Provider 。
+type Vault struct {
+	config *VaultConfig
+	client *http.Client
+}
+
+// NewVault Vault 的构造函数。
+func NewVault(config VaultConfig) (*Vault, error) {
+	if config.TokenFile != "" {
+		b, err := ioutil.ReadFile(config.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Token = strings.TrimSpace(string(b))
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("secrets: vault token required")
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	return &Vault{config: &config, client: &http.Client{}}, nil
+}
+
+func newVault(p *conf.Properties) (Provider, error) {
+	var config VaultConfig
+	if err := p.Bind(&config, conf.Key("secrets.vault")); err != nil {
+		return nil, err
+	}
+	return NewVault(config)
+}
+
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int64                  `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+func (v *Vault) Get(ctx context.Context, path string) (*Secret, error) {
+
+	url := v.config.Address + "/v1/"
+	switch {
+	case v.config.Mount == "":
+		url += path
+	case v.config.KVVersion == 2:
+		url += v.config.Mount + "/data/" + path
+	default:
+		url += v.config.Mount + "/" + path
+	}
+
+	var resp vaultResponse
+	if err := v.do(ctx, http.MethodGet, url, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	data := resp.Data
+	if v.config.Mount != "" && v.config.KVVersion == 2 {
+		data, _ = data["data"].(map[string]interface{})
+	}
+
+	s := &Secret{
+		Data:      make(map[string]string),
+		LeaseID:   resp.LeaseID,
+		Lease:     time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+	}
+	for k, val := range data {
+		s.Data[k] = toString(val)
+	}
+	return s, nil
+}
+
+// Renew 对动态密钥的租约进行续租。
+func (v *Vault) Renew(ctx context.Context, s *Secret) (*Secret, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"lease_id":  s.LeaseID,
+		"increment": int64(s.Lease / time.Second),
+	})
+	var resp vaultResponse
+	err := v.do(ctx, http.MethodPut, v.config.Address+"/v1/sys/leases/renew", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{
+		Data:      s.Data,
+		LeaseID:   resp.LeaseID,
+		Lease:     time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+	}, nil
+}
+
+func (v *Vault) do(ctx context.Context, method, url string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.config.Token)
+	return doJSON(v.client, req, out)
+}
+
+// doJSON 发送请求并将 JSON 格式的响应解析到 out 中。
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return errStatus(resp.StatusCode, b)
+	}
+	return json.Unmarshal(b, out)
+}
+
+// toString 将 JSON 值转换为字符串，对象和数组保持 JSON 格式。
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// parsePayload 解析 AWS 和 GCP 保存的密钥内容，JSON 对象按照键值对保存，其他内
+// 容保存在空字符串键下。
+func parsePayload(payload string) map[string]string {
+	data := map[string]string{"": payload}
+	var m map[string]interface{}
+	if json.Unmarshal([]byte(payload), &m) == nil {
+		for k, v := range m {
+			data[k] = toString(v)
+		}
+	}
+	return data
+}
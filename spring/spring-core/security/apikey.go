@@ -0,0 +1,102 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// APIKey 静态配置的 API key 。
+type APIKey struct {
+	Key         string   `value:"${key}"`
+	Name        string   `value:"${name}"`
+	Roles       []string `value:"${roles}"`
+	Permissions []string `value:"${permissions}"`
+}
+
+// APIKeyConfig API key 认证配置，通常绑定到 security.api-key 前缀的属性上。
+type APIKeyConfig struct {
+	Header string   `value:"${header:=X-API-Key}"` // 携带 API key 的请求头
+	Query  string   `value:"${query:=}"`           // 携带 API key 的查询参数，为空时不使用
+	Keys   []APIKey `value:"${keys}"`              // 静态配置的 API key
+}
+
+// APIKeyStore 查询 API key 对应的用户，key 无效时返回 nil, nil 。导出为该接口
+// 的 bean 会替换静态配置的 API key 。
+type APIKeyStore interface {
+	Lookup(ctx context.Context, key string) (*Principal, error)
+}
+
+// APIKeyProvider 使用请求头或者查询参数中的 API key 进行认证。
+type APIKeyProvider struct {
+	Store APIKeyStore `autowire:"?"`
+
+	config *APIKeyConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewAPIKeyProvider APIKeyProvider 的构造函数。
+func NewAPIKeyProvider(config APIKeyConfig) *APIKeyProvider {
+	return &APIKeyProvider{config: &config}
+}
+
+func (p *APIKeyProvider) Authenticate(r *http.Request) (*Authentication, error) {
+
+	key := r.Header.Get(p.config.Header)
+	if key == "" && p.config.Query != "" {
+		key = r.URL.Query().Get(p.config.Query)
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	principal, err := p.lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrBadCredentials
+	}
+	return &Authentication{Principal: principal, Scheme: "apikey"}, nil
+}
+
+func (p *APIKeyProvider) lookup(ctx context.Context, key string) (*Principal, error) {
+	if p.Store != nil {
+		return p.Store.Lookup(ctx, key)
+	}
+	for _, k := range p.config.Keys {
+		if secretEqual(k.Key, key) {
+			return &Principal{Name: k.Name, Roles: k.Roles, Permissions: k.Permissions}, nil
+		}
+	}
+	return nil, nil
+}
+
+// secretEqual 以固定时间比较配置的密钥和请求携带的密钥，配置的密钥可以使用
+// {sha256} 前缀加十六进制摘要的形式，避免在配置文件中保存明文。
+func secretEqual(configured, given string) bool {
+	if s := strings.TrimPrefix(configured, "{sha256}"); s != configured {
+		sum := sha256.Sum256([]byte(given))
+		given = hex.EncodeToString(sum[:])
+		configured = strings.ToLower(s)
+	}
+	return subtle.ConstantTimeCompare([]byte(configured), []byte(given)) == 1
+}
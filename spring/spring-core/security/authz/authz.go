@@ -0,0 +1,217 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package authz 基于角色和权限的授权机制。Filter 根据请求的路径和方法匹配授权
+// 规则，规则可以使用 hasRole('admin') or @owner 这样的表达式配置，其中 @ 引用的
+// 是导出为 Rule 接口的 bean 。在 service 中可以通过 Check 和 Require 方法进行
+// 编程式的授权。所有的授权决策都会记录到日志中。
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/security"
+)
+
+// ErrForbidden 当前用户没有访问权限。
+var ErrForbidden = errors.New("authz: access denied")
+
+// Rule 授权规则，p 为 nil 表示匿名访问。
+type Rule interface {
+	Evaluate(ctx context.Context, p *security.Principal) bool
+}
+
+// RuleFunc 函数形式的 Rule 。
+type RuleFunc func(ctx context.Context, p *security.Principal) bool
+
+func (f RuleFunc) Evaluate(ctx context.Context, p *security.Principal) bool {
+	return f(ctx, p)
+}
+
+// rule 带有描述的 Rule ，描述用于记录授权决策。
+type rule struct {
+	desc string
+	fn   func(ctx context.Context, p *security.Principal) bool
+}
+
+func (r *rule) Evaluate(ctx context.Context, p *security.Principal) bool {
+	return r.fn(ctx, p)
+}
+
+func (r *rule) String() string {
+	return r.desc
+}
+
+// describe 返回规则的描述。
+func describe(r Rule) string {
+	if s, ok := r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", r)
+}
+
+func quote(a []string) string {
+	s := make([]string, len(a))
+	for i, v := range a {
+		s[i] = "'" + v + "'"
+	}
+	return strings.Join(s, ", ")
+}
+
+// PermitAll 允许所有访问。
+func PermitAll() Rule {
+	return &rule{"permitAll", func(ctx context.Context, p *security.Principal) bool {
+		return true
+	}}
+}
+
+// DenyAll 拒绝所有访问。
+func DenyAll() Rule {
+	return &rule{"denyAll", func(ctx context.Context, p *security.Principal) bool {
+		return false
+	}}
+}
+
+// Authenticated 只允许经过认证的用户访问。
+func Authenticated() Rule {
+	return &rule{"authenticated", func(ctx context.Context, p *security.Principal) bool {
+		return p != nil
+	}}
+}
+
+// HasRole 只允许拥有角色 role 的用户访问。
+func HasRole(role string) Rule {
+	return HasAnyRole(role)
+}
+
+// HasAnyRole 只允许拥有任一角色的用户访问。
+func HasAnyRole(roles ...string) Rule {
+	desc := "hasAnyRole(" + quote(roles) + ")"
+	if len(roles) == 1 {
+		desc = "hasRole(" + quote(roles) + ")"
+	}
+	return &rule{desc, func(ctx context.Context, p *security.Principal) bool {
+		if p == nil {
+			return false
+		}
+		for _, r := range roles {
+			if p.HasRole(r) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// HasPermission 只允许拥有权限 perm 的用户访问。
+func HasPermission(perm string) Rule {
+	return HasAnyPermission(perm)
+}
+
+// HasAnyPermission 只允许拥有任一权限的用户访问。
+func HasAnyPermission(perms ...string) Rule {
+	desc := "hasAnyPermission(" + quote(perms) + ")"
+	if len(perms) == 1 {
+		desc = "hasPermission(" + quote(perms) + ")"
+	}
+	return &rule{desc, func(ctx context.Context, p *security.Principal) bool {
+		if p == nil {
+			return false
+		}
+		for _, perm := range perms {
+			if p.HasPermission(perm) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// HasAny 满足任一规则时允许访问。
+func HasAny(rules ...Rule) Rule {
+	return join(" or ", rules, func(ctx context.Context, p *security.Principal) bool {
+		for _, r := range rules {
+			if r.Evaluate(ctx, p) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// HasAll 满足所有规则时允许访问。
+func HasAll(rules ...Rule) Rule {
+	return join(" and ", rules, func(ctx context.Context, p *security.Principal) bool {
+		for _, r := range rules {
+			if !r.Evaluate(ctx, p) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Not 不满足规则 r 时允许访问。
+func Not(r Rule) Rule {
+	return &rule{"not " + describe(r), func(ctx context.Context, p *security.Principal) bool {
+		return !r.Evaluate(ctx, p)
+	}}
+}
+
+func join(sep string, rules []Rule, fn func(ctx context.Context, p *security.Principal) bool) Rule {
+	s := make([]string, len(rules))
+	for i, r := range rules {
+		s[i] = describe(r)
+	}
+	return &rule{"(" + strings.Join(s, sep) + ")", fn}
+}
+
+// Check 判断 ctx 中的当前用户是否拥有权限 perm ，没有经过认证时返回
+// security.ErrUnauthenticated ，没有权限时返回 ErrForbidden 。
+func Check(ctx context.Context, perm string) error {
+	return decide(ctx, perm, HasPermission(perm))
+}
+
+// Require 判断 ctx 中的当前用户是否满足规则 r ，返回值同 Check 方法。
+func Require(ctx context.Context, r Rule) error {
+	return decide(ctx, "", r)
+}
+
+// decide 对 resource 的访问进行授权并记录授权决策。
+func decide(ctx context.Context, resource string, r Rule) error {
+
+	p := security.CurrentUser(ctx)
+	granted := r.Evaluate(ctx, p)
+
+	user := "<anonymous>"
+	if p != nil {
+		user = p.Name
+	}
+	if granted {
+		log.Ctx(ctx).Debugf("authz granted user=%q resource=%q rule=%s", user, resource, describe(r))
+		return nil
+	}
+	log.Ctx(ctx).Infof("authz denied user=%q resource=%q rule=%s", user, resource, describe(r))
+
+	if p == nil {
+		return security.ErrUnauthenticated
+	}
+	return ErrForbidden
+}
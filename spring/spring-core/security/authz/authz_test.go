@@ -0,0 +1,143 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/security/authz"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func withUser(ctx context.Context, name string, roles, perms []string) context.Context {
+	return security.WithAuthentication(ctx, &security.Authentication{
+		Principal: &security.Principal{Name: name, Roles: roles, Permissions: perms},
+	})
+}
+
+var owner = authz.RuleFunc(func(ctx context.Context, p *security.Principal) bool {
+	return p != nil && p.Name == "alice"
+})
+
+func TestParse(t *testing.T) {
+
+	admin := withUser(context.Background(), "bob", []string{"admin"}, nil)
+	alice := withUser(context.Background(), "alice", []string{"user"}, []string{"order:read"})
+	anonymous := context.Background()
+
+	testcases := []struct {
+		expr   string
+		desc   string
+		expect [3]bool // admin, alice, anonymous
+	}{
+		{"permitAll", "permitAll", [3]bool{true, true, true}},
+		{"denyAll", "denyAll", [3]bool{false, false, false}},
+		{"authenticated", "authenticated", [3]bool{true, true, false}},
+		{"hasRole('admin')", "hasRole('admin')", [3]bool{true, false, false}},
+		{`hasAnyRole("admin", "user")`, "hasAnyRole('admin', 'user')", [3]bool{true, true, false}},
+		{"hasPermission('order:read')", "hasPermission('order:read')", [3]bool{false, true, false}},
+		{"hasRole('admin') or @owner", "(hasRole('admin') or @owner)", [3]bool{true, true, false}},
+		{"authenticated and not (hasRole('admin'))", "(authenticated and not hasRole('admin'))", [3]bool{false, true, false}},
+	}
+	for _, c := range testcases {
+		r, err := authz.Parse(c.expr, map[string]authz.Rule{"owner": owner})
+		assert.Nil(t, err)
+		assert.Equal(t, r.(interface{ String() string }).String(), c.desc)
+		for i, ctx := range []context.Context{admin, alice, anonymous} {
+			assert.Equal(t, r.Evaluate(ctx, security.CurrentUser(ctx)), c.expect[i])
+		}
+	}
+
+	errs := []struct {
+		expr string
+		err  string
+	}{
+		{"hasRole(", "expects string arguments"},
+		{"hasRole()", "hasRole expects arguments"},
+		{"permitAll('x')", "permitAll expects no arguments"},
+		{"@missing", "rule @missing not found"},
+		{"isAdmin", "unknown function isAdmin"},
+		{"(authenticated", "expect \"\\)\""},
+		{"authenticated authenticated", "unexpected \"authenticated\""},
+		{"hasRole('admin) ", "unterminated string"},
+		{"a == b", "unexpected '='"},
+	}
+	for _, c := range errs {
+		_, err := authz.Parse(c.expr, nil)
+		assert.Error(t, err, c.err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	ctx := context.Background()
+	err := authz.Check(ctx, "order:read")
+	assert.True(t, errors.Is(err, security.ErrUnauthenticated))
+
+	ctx = withUser(ctx, "alice", nil, []string{"order:read"})
+	assert.Nil(t, authz.Check(ctx, "order:read"))
+	assert.Equal(t, authz.Check(ctx, "order:write"), authz.ErrForbidden)
+
+	assert.Nil(t, authz.Require(ctx, authz.HasAny(authz.HasRole("admin"), owner)))
+	assert.Equal(t, authz.Require(ctx, authz.HasAll(authz.Authenticated(), authz.Not(owner))), authz.ErrForbidden)
+}
+
+func TestFilter_Handler(t *testing.T) {
+
+	f := authz.NewFilter(authz.Config{
+		Routes: []authz.RouteConfig{
+			{Pattern: "/admin/**", Access: "hasRole('admin')"},
+			{Pattern: "/orders/*", Methods: []string{"DELETE"}, Access: "hasRole('admin') or @owner"},
+			{Pattern: "/orders/*", Access: "authenticated"},
+		},
+		Default: "permitAll",
+	})
+	f.Rules = map[string]authz.Rule{"owner": owner}
+	err := f.OnInit()
+	assert.Nil(t, err)
+	f.Route("/internal", authz.DenyAll())
+
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(method, path string, ctx context.Context) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil).WithContext(ctx))
+		return w.Code
+	}
+
+	anonymous := context.Background()
+	bob := withUser(anonymous, "bob", nil, nil)
+	alice := withUser(anonymous, "alice", nil, nil)
+	admin := withUser(anonymous, "root", []string{"admin"}, nil)
+
+	assert.Equal(t, serve(http.MethodGet, "/public", anonymous), http.StatusOK)
+	assert.Equal(t, serve(http.MethodGet, "/admin/users/1", anonymous), http.StatusUnauthorized)
+	assert.Equal(t, serve(http.MethodGet, "/admin/users/1", bob), http.StatusForbidden)
+	assert.Equal(t, serve(http.MethodGet, "/admin", admin), http.StatusOK)
+	assert.Equal(t, serve(http.MethodGet, "/orders/1", bob), http.StatusOK)
+	assert.Equal(t, serve(http.MethodGet, "/orders/1/items", anonymous), http.StatusOK)
+	assert.Equal(t, serve(http.MethodDelete, "/orders/1", bob), http.StatusForbidden)
+	assert.Equal(t, serve(http.MethodDelete, "/orders/1", alice), http.StatusOK)
+	assert.Equal(t, serve(http.MethodGet, "/internal", admin), http.StatusForbidden)
+
+	f = authz.NewFilter(authz.Config{Default: "@missing"})
+	assert.Error(t, f.OnInit(), "rule @missing not found")
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-spring/spring-core/security"
+)
+
+// Parse 解析授权规则表达式，例如 hasRole('admin') or (authenticated and @owner)。
+// 支持的函数有 permitAll 、denyAll 、authenticated 、hasRole 、hasAnyRole 、
+// hasPermission 和 hasAnyPermission ，支持 and 、or 、not 运算符和括号，@name
+// 引用 named 中名为 name 的规则。
+func Parse(expr string, named map[string]Rule) (Rule, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: expr, toks: toks, named: named}
+	r, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].s)
+	}
+	return r, nil
+}
+
+type tokenKind int
+
+const (
+	tokIdent = tokenKind(iota)
+	tokString
+	tokRef
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	s    string
+}
+
+func tokenize(expr string) ([]token, error) {
+	var toks []token
+	s := []rune(expr)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			toks = append(toks, token{tokPunct, string(c)})
+			i++
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("authz: unterminated string in %q", expr)
+			}
+			toks = append(toks, token{tokString, string(s[i+1 : j])})
+			i = j + 1
+		case c == '@' || isIdent(c):
+			j := i + 1
+			for j < len(s) && isIdent(s[j]) {
+				j++
+			}
+			if c == '@' {
+				if j == i+1 {
+					return nil, fmt.Errorf("authz: empty reference in %q", expr)
+				}
+				toks = append(toks, token{tokRef, string(s[i+1 : j])})
+			} else {
+				toks = append(toks, token{tokIdent, string(s[i:j])})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("authz: unexpected %q in %q", c, expr)
+		}
+	}
+	return toks, nil
+}
+
+func isIdent(c rune) bool {
+	return c == '_' || c == '-' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+type parser struct {
+	expr  string
+	toks  []token
+	pos   int
+	named map[string]Rule
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("authz: %s in %q", fmt.Sprintf(format, args...), p.expr)
+}
+
+func (p *parser) peek(kind tokenKind, s string) bool {
+	return p.pos < len(p.toks) && p.toks[p.pos].kind == kind && p.toks[p.pos].s == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.peek(tokPunct, s) {
+		return p.errorf("expect %q", s)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) parseOr() (Rule, error) {
+	r, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{r}
+	for p.peek(tokIdent, "or") {
+		p.pos++
+		if r, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 1 {
+		return rules[0], nil
+	}
+	return HasAny(rules...), nil
+}
+
+func (p *parser) parseAnd() (Rule, error) {
+	r, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{r}
+	for p.peek(tokIdent, "and") {
+		p.pos++
+		if r, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 1 {
+		return rules[0], nil
+	}
+	return HasAll(rules...), nil
+}
+
+func (p *parser) parseUnary() (Rule, error) {
+	if p.peek(tokIdent, "not") {
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(r), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Rule, error) {
+
+	if p.pos >= len(p.toks) {
+		return nil, p.errorf("unexpected end")
+	}
+
+	t := p.toks[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokPunct:
+		if t.s != "(" {
+			return nil, p.errorf("unexpected %q", t.s)
+		}
+		r, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return r, nil
+	case tokRef:
+		r, ok := p.named[t.s]
+		if !ok {
+			return nil, p.errorf("rule @%s not found", t.s)
+		}
+		return &rule{"@" + t.s, func(ctx context.Context, principal *security.Principal) bool {
+			return r.Evaluate(ctx, principal)
+		}}, nil
+	case tokIdent:
+		return p.parseFunc(t.s)
+	default:
+		return nil, p.errorf("unexpected %q", t.s)
+	}
+}
+
+func (p *parser) parseFunc(name string) (Rule, error) {
+
+	var args []string
+	if p.peek(tokPunct, "(") {
+		p.pos++
+		for !p.peek(tokPunct, ")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokString {
+				return nil, p.errorf("%s expects string arguments", name)
+			}
+			args = append(args, p.toks[p.pos].s)
+			p.pos++
+		}
+		p.pos++
+	}
+
+	switch strings.ToLower(name) {
+	case "permitall":
+		return PermitAll(), p.noArgs(name, args)
+	case "denyall":
+		return DenyAll(), p.noArgs(name, args)
+	case "authenticated":
+		return Authenticated(), p.noArgs(name, args)
+	case "hasrole", "hasanyrole":
+		if len(args) == 0 {
+			return nil, p.errorf("%s expects arguments", name)
+		}
+		return HasAnyRole(args...), nil
+	case "haspermission", "hasanypermission":
+		if len(args) == 0 {
+			return nil, p.errorf("%s expects arguments", name)
+		}
+		return HasAnyPermission(args...), nil
+	}
+	return nil, p.errorf("unknown function %s", name)
+}
+
+func (p *parser) noArgs(name string, args []string) error {
+	if len(args) > 0 {
+		return p.errorf("%s expects no arguments", name)
+	}
+	return nil
+}
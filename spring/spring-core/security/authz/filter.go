@@ -0,0 +1,156 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/web"
+)
+
+// RouteConfig 路由的授权规则配置。
+type RouteConfig struct {
+	Pattern string   `value:"${pattern}"` // 路径模式，* 匹配一段路径，** 匹配剩余的所有路径
+	Methods []string `value:"${methods}"` // 请求方法，为空时匹配所有方法
+	Access  string   `value:"${access}"`  // 授权规则表达式
+}
+
+// Config 授权过滤器配置，通常绑定到 security.authz 前缀的属性上。
+type Config struct {
+	Routes  []RouteConfig `value:"${routes}"`             // 按照顺序匹配的路由规则
+	Default string        `value:"${default:=permitAll}"` // 没有匹配的路由时使用的规则表达式
+}
+
+// route 路由的授权规则。
+type route struct {
+	pattern []string
+	methods []string
+	rule    Rule
+}
+
+func (r *route) match(method, path string) bool {
+	if len(r.methods) > 0 && !containsFold(r.methods, method) {
+		return false
+	}
+	return matchPath(r.pattern, split(path))
+}
+
+func containsFold(a []string, s string) bool {
+	for _, v := range a {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchPath 按段匹配路径，* 匹配一段路径，** 匹配剩余的所有路径。
+func matchPath(pattern, path []string) bool {
+	for i, s := range pattern {
+		if s == "**" {
+			return true
+		}
+		if i >= len(path) || (s != "*" && s != path[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// Filter 根据请求的路径和方法进行授权的过滤器，需要在 security.Filter 之后执
+// 行。匿名访问被拒绝时返回 401 ，经过认证的用户被拒绝时返回 403 。
+type Filter struct {
+	Rules map[string]Rule `autowire:""` // 表达式中可以通过 @name 引用的规则
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+	routes []*route
+	def    Rule
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	return &Filter{config: &config}
+}
+
+// OnInit 解析配置的路由规则。
+func (f *Filter) OnInit() error {
+	for _, c := range f.config.Routes {
+		r, err := Parse(c.Access, f.Rules)
+		if err != nil {
+			return err
+		}
+		f.Route(c.Pattern, r, c.Methods...)
+	}
+	def, err := Parse(f.config.Default, f.Rules)
+	if err != nil {
+		return err
+	}
+	f.def = def
+	return nil
+}
+
+// Route 在配置的路由规则之后添加一条路由规则。
+func (f *Filter) Route(pattern string, r Rule, methods ...string) {
+	f.routes = append(f.routes, &route{pattern: split(pattern), methods: methods, rule: r})
+}
+
+// ruleOf 返回第一条与请求匹配的路由规则。
+func (f *Filter) ruleOf(method, path string) Rule {
+	for _, r := range f.routes {
+		if r.match(method, path) {
+			return r.rule
+		}
+	}
+	return f.def
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	if f.authorize(ctx.ResponseWriter(), ctx.Request()) {
+		chain.Next(ctx)
+	}
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.authorize(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// authorize 对请求进行授权，拒绝时返回错误响应。
+func (f *Filter) authorize(w http.ResponseWriter, r *http.Request) bool {
+	resource := r.Method + " " + r.URL.Path
+	err := decide(r.Context(), resource, f.ruleOf(r.Method, r.URL.Path))
+	if err == nil {
+		return true
+	}
+	code := http.StatusForbidden
+	if errors.Is(err, security.ErrUnauthenticated) {
+		code = http.StatusUnauthorized
+	}
+	http.Error(w, http.StatusText(code), code)
+	return false
+}
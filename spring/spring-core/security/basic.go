@@ -0,0 +1,89 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// User 静态配置的 basic 认证用户。
+type User struct {
+	Name        string   `value:"${name}"`
+	Password    string   `value:"${password}"` // 明文或者 {sha256} 前缀加十六进制摘要
+	Roles       []string `value:"${roles}"`
+	Permissions []string `value:"${permissions}"`
+}
+
+// BasicConfig HTTP basic 认证配置，通常绑定到 security.basic 前缀的属性上。
+type BasicConfig struct {
+	Realm string `value:"${realm:=go-spring}"` // WWW-Authenticate 响应头中的 realm
+	Users []User `value:"${users}"`            // 静态配置的用户
+}
+
+// UserStore 校验用户名和密码，校验失败时返回 nil, nil 。导出为该接口的 bean 会
+// 替换静态配置的用户。
+type UserStore interface {
+	Authenticate(ctx context.Context, name, password string) (*Principal, error)
+}
+
+// BasicProvider HTTP basic 认证方式。
+type BasicProvider struct {
+	Store UserStore `autowire:"?"`
+
+	config *BasicConfig // 使用指针避免容器对其进行属性绑定
+}
+
+// NewBasicProvider BasicProvider 的构造函数。
+func NewBasicProvider(config BasicConfig) *BasicProvider {
+	return &BasicProvider{config: &config}
+}
+
+// Challenge 返回认证失败时 WWW-Authenticate 响应头的值。
+func (p *BasicProvider) Challenge() string {
+	return "Basic realm=" + strconv.Quote(p.config.Realm)
+}
+
+func (p *BasicProvider) Authenticate(r *http.Request) (*Authentication, error) {
+
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	principal, err := p.authenticate(r.Context(), name, password)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrBadCredentials
+	}
+	return &Authentication{Principal: principal, Scheme: "basic"}, nil
+}
+
+func (p *BasicProvider) authenticate(ctx context.Context, name, password string) (*Principal, error) {
+	if p.Store != nil {
+		return p.Store.Authenticate(ctx, name, password)
+	}
+	for _, u := range p.config.Users {
+		if u.Name == name && secretEqual(u.Password, password) {
+			return &Principal{Name: u.Name, Roles: u.Roles, Permissions: u.Permissions}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package csrf 为使用会话 cookie 的应用提供 CSRF 防护。支持两种策略：
+// double-submit 策略把令牌保存在 cookie 中，要求请求通过请求头或者表单字段再提交
+// 一次；synchronizer 策略把令牌保存在服务端并与会话绑定。令牌保存在请求的 ctx
+// 中，可以通过 Token 和 TemplateField 方法输出到模板，也可以通过响应头返回给
+// 前端。
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"sync"
+	"time"
+)
+
+const (
+	DoubleSubmit = "double-submit" // 令牌保存在 cookie 中
+	Synchronizer = "synchronizer"  // 令牌保存在服务端
+)
+
+type ctxKey int
+
+const (
+	ctxTokenKey = ctxKey(iota)
+	ctxFieldKey
+)
+
+// Token 返回 ctx 中保存的令牌，没有时返回空字符串。
+func Token(ctx context.Context) string {
+	t, _ := ctx.Value(ctxTokenKey).(string)
+	return t
+}
+
+// TemplateField 返回携带令牌的隐藏表单字段，用于在模板中输出。
+func TemplateField(ctx context.Context) template.HTML {
+	name, _ := ctx.Value(ctxFieldKey).(string)
+	if name == "" {
+		name = "_csrf"
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(Token(ctx)) + `">`)
+}
+
+// newToken 生成随机的令牌。
+func newToken() string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// TokenStore synchronizer 策略下保存令牌的存储，导出为该接口的 bean 会替换默认
+// 的内存存储，多实例部署时应该使用共享的存储。
+type TokenStore interface {
+	Get(ctx context.Context, session string) (string, error)
+	Put(ctx context.Context, session, token string) error
+}
+
+// memoryStore 保存在内存中的令牌，超过有效期后被删除。
+type memoryStore struct {
+	mutex  sync.Mutex
+	ttl    time.Duration
+	tokens map[string]memoryToken
+}
+
+type memoryToken struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemoryStore 返回令牌有效期为 ttl 的内存存储。
+func NewMemoryStore(ttl time.Duration) TokenStore {
+	return &memoryStore{ttl: ttl, tokens: make(map[string]memoryToken)}
+}
+
+func (s *memoryStore) Get(ctx context.Context, session string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, ok := s.tokens[session]
+	if !ok || time.Now().After(t.expires) {
+		delete(s.tokens, session)
+		return "", nil
+	}
+	return t.token, nil
+}
+
+func (s *memoryStore) Put(ctx context.Context, session, token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	for k, t := range s.tokens { // 顺便清理过期的令牌
+		if now.After(t.expires) {
+			delete(s.tokens, k)
+		}
+	}
+	s.tokens[session] = memoryToken{token: token, expires: now.Add(s.ttl)}
+	return nil
+}
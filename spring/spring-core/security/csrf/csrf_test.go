@@ -0,0 +1,188 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package csrf_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/security/csrf"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func defaultConfig(strategy string) csrf.Config {
+	return csrf.Config{
+		Strategy:      strategy,
+		CookieName:    "XSRF-TOKEN",
+		CookiePath:    "/",
+		SessionCookie: "SESSIONID",
+		TTL:           time.Hour,
+		HeaderName:    "X-XSRF-TOKEN",
+		FieldName:     "_csrf",
+		ExposeHeader:  "X-CSRF-TOKEN",
+		ExemptPaths:   []string{"/api/**", "/hooks/*"},
+	}
+}
+
+func newHandler(t *testing.T, config csrf.Config, token *string) http.Handler {
+	f, err := csrf.NewFilter(config)
+	assert.Nil(t, err)
+	f.OnInit()
+	return f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*token = csrf.Token(r.Context())
+	}))
+}
+
+func TestNewFilter(t *testing.T) {
+	_, err := csrf.NewFilter(csrf.Config{Strategy: "cookie"})
+	assert.Error(t, err, "csrf: unknown strategy \"cookie\"")
+}
+
+func TestFilter_DoubleSubmit(t *testing.T) {
+
+	var token string
+	h := newHandler(t, defaultConfig(csrf.DoubleSubmit), &token)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	cookies := w.Result().Cookies()
+	assert.Equal(t, len(cookies), 1)
+	assert.Equal(t, cookies[0].Name, "XSRF-TOKEN")
+	assert.Equal(t, cookies[0].Value, token)
+	assert.Equal(t, w.Header().Get("X-CSRF-TOKEN"), token)
+
+	// 已有令牌时不再生成
+	r := httptest.NewRequest(http.MethodGet, "/form", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, len(w.Result().Cookies()), 0)
+	assert.Equal(t, token, cookies[0].Value)
+
+	r = httptest.NewRequest(http.MethodPost, "/form", nil)
+	r.AddCookie(cookies[0])
+	r.Header.Set("X-XSRF-TOKEN", cookies[0].Value)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	form := url.Values{"_csrf": {cookies[0].Value}}
+	r = httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	r = httptest.NewRequest(http.MethodPost, "/form", nil)
+	r.AddCookie(cookies[0])
+	r.Header.Set("X-XSRF-TOKEN", "forged")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	// 没有 cookie 时即使提交了令牌也被拒绝
+	r = httptest.NewRequest(http.MethodDelete, "/form", nil)
+	r.Header.Set("X-XSRF-TOKEN", cookies[0].Value)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	for _, p := range []string{"/api/orders/1", "/api", "/hooks/github"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, p, nil))
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hooks/github/push", nil))
+	assert.Equal(t, w.Code, http.StatusForbidden)
+}
+
+func TestFilter_Synchronizer(t *testing.T) {
+
+	var token string
+	h := newHandler(t, defaultConfig(csrf.Synchronizer), &token)
+	session := &http.Cookie{Name: "SESSIONID", Value: "s1"}
+
+	// 没有会话时不生成令牌
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, token, "")
+
+	r := httptest.NewRequest(http.MethodGet, "/form", nil)
+	r.AddCookie(session)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, len(w.Result().Cookies()), 0)
+	assert.True(t, token != "")
+	first := token
+
+	r = httptest.NewRequest(http.MethodGet, "/form", nil)
+	r.AddCookie(session)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, token, first)
+
+	r = httptest.NewRequest(http.MethodPut, "/form", nil)
+	r.AddCookie(session)
+	r.Header.Set("X-XSRF-TOKEN", first)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	// 其他会话的令牌无效
+	r = httptest.NewRequest(http.MethodPut, "/form", nil)
+	r.AddCookie(&http.Cookie{Name: "SESSIONID", Value: "s2"})
+	r.Header.Set("X-XSRF-TOKEN", first)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := csrf.NewMemoryStore(20 * time.Millisecond)
+	assert.Nil(t, s.Put(ctx, "s1", "t1"))
+	token, err := s.Get(ctx, "s1")
+	assert.Nil(t, err)
+	assert.Equal(t, token, "t1")
+	time.Sleep(30 * time.Millisecond)
+	token, err = s.Get(ctx, "s1")
+	assert.Nil(t, err)
+	assert.Equal(t, token, "")
+}
+
+func TestTemplateField(t *testing.T) {
+
+	var field string
+	f, err := csrf.NewFilter(defaultConfig(csrf.DoubleSubmit))
+	assert.Nil(t, err)
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		field = string(csrf.TemplateField(r.Context()))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "abc"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, field, `<input type="hidden" name="_csrf" value="abc">`)
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package csrf
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+// Config CSRF 过滤器配置，通常绑定到 security.csrf 前缀的属性上。
+type Config struct {
+	Strategy      string        `value:"${strategy:=double-submit}"`     // double-submit 或者 synchronizer
+	CookieName    string        `value:"${cookie-name:=XSRF-TOKEN}"`     // double-submit 策略下保存令牌的 cookie
+	CookiePath    string        `value:"${cookie-path:=/}"`              // 令牌 cookie 的路径
+	CookieSecure  bool          `value:"${cookie-secure:=false}"`        // 令牌 cookie 是否只通过 HTTPS 发送
+	SessionCookie string        `value:"${session-cookie:=SESSIONID}"`   // synchronizer 策略下标识会话的 cookie
+	TTL           time.Duration `value:"${ttl:=12h}"`                    // synchronizer 策略下内存存储中令牌的有效期
+	HeaderName    string        `value:"${header-name:=X-XSRF-TOKEN}"`   // 提交令牌的请求头
+	FieldName     string        `value:"${field-name:=_csrf}"`           // 提交令牌的表单字段
+	ExposeHeader  string        `value:"${expose-header:=X-CSRF-TOKEN}"` // 返回令牌的响应头，为空时不返回
+	ExemptPaths   []string      `value:"${exempt-paths}"`                // 不进行检查的路径，支持 * 和以 /** 结尾的前缀
+}
+
+// Filter CSRF 防护过滤器，GET 、HEAD 、OPTIONS 和 TRACE 之外的请求必须提交有效
+// 的令牌，否则返回 403 。
+type Filter struct {
+	Store TokenStore `autowire:"?"`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) (*Filter, error) {
+	switch config.Strategy {
+	case DoubleSubmit, Synchronizer:
+	default:
+		return nil, fmt.Errorf("csrf: unknown strategy %q", config.Strategy)
+	}
+	return &Filter{config: &config}, nil
+}
+
+// OnInit synchronizer 策略下没有自定义的存储时使用内存存储。
+func (f *Filter) OnInit() {
+	if f.Store == nil && f.config.Strategy == Synchronizer {
+		f.Store = NewMemoryStore(f.config.TTL)
+	}
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	req, ok := f.protect(ctx.ResponseWriter(), ctx.Request())
+	if !ok {
+		return
+	}
+	ctx.SetRequest(req)
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, ok := f.protect(w, r); ok {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// exempt 判断请求路径是否不需要检查。
+func (f *Filter) exempt(p string) bool {
+	for _, pattern := range f.config.ExemptPaths {
+		if prefix := strings.TrimSuffix(pattern, "/**"); prefix != pattern {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// protect 检查请求提交的令牌，返回在 ctx 中保存了令牌的新请求，检查失败时返回
+// 403 响应并且 ok 为 false 。
+func (f *Filter) protect(w http.ResponseWriter, r *http.Request) (_ *http.Request, ok bool) {
+
+	if f.exempt(r.URL.Path) {
+		return r, true
+	}
+
+	token, err := f.load(r)
+	if err != nil {
+		log.Ctx(r.Context()).Errorf("csrf: load token error: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !safeMethod(r.Method) {
+		given := r.Header.Get(f.config.HeaderName)
+		if given == "" && f.config.FieldName != "" {
+			given = r.FormValue(f.config.FieldName)
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(given)) != 1 {
+			log.Ctx(r.Context()).Infof("csrf: invalid token for %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return nil, false
+		}
+	}
+
+	if token == "" {
+		if token, err = f.save(w, r); err != nil {
+			log.Ctx(r.Context()).Errorf("csrf: save token error: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
+	if token != "" && f.config.ExposeHeader != "" {
+		w.Header().Set(f.config.ExposeHeader, token)
+	}
+
+	ctx := context.WithValue(r.Context(), ctxTokenKey, token)
+	ctx = context.WithValue(ctx, ctxFieldKey, f.config.FieldName)
+	return r.WithContext(ctx), true
+}
+
+// session 返回 synchronizer 策略下请求所属的会话。
+func (f *Filter) session(r *http.Request) string {
+	if c, err := r.Cookie(f.config.SessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// load 返回请求已有的令牌，没有时返回空字符串。
+func (f *Filter) load(r *http.Request) (string, error) {
+	if f.config.Strategy == DoubleSubmit {
+		if c, err := r.Cookie(f.config.CookieName); err == nil {
+			return c.Value, nil
+		}
+		return "", nil
+	}
+	if s := f.session(r); s != "" {
+		return f.Store.Get(r.Context(), s)
+	}
+	return "", nil
+}
+
+// save 为请求生成新的令牌并保存，synchronizer 策略下请求没有会话时不生成令牌。
+func (f *Filter) save(w http.ResponseWriter, r *http.Request) (string, error) {
+	token := newToken()
+	if f.config.Strategy == DoubleSubmit {
+		// 前端需要读取 cookie 中的令牌并通过请求头提交，所以不能设置 HttpOnly 。
+		http.SetCookie(w, &http.Cookie{
+			Name:     f.config.CookieName,
+			Value:    token,
+			Path:     f.config.CookiePath,
+			Secure:   f.config.CookieSecure,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return token, nil
+	}
+	s := f.session(r)
+	if s == "" {
+		return "", nil
+	}
+	if err := f.Store.Put(r.Context(), s, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+// Config 认证过滤器配置，通常绑定到 security 前缀的属性上。
+type Config struct {
+	PermitAnonymous bool `value:"${permit-anonymous:=true}"` // 是否允许没有携带凭证的请求通过，由授权规则决定能否访问
+}
+
+// Filter 对请求进行认证并将认证结果保存到请求 ctx 中的过滤器。携带了无效凭证的
+// 请求总是被拒绝，没有携带凭证的请求是否被拒绝取决于 PermitAnonymous 配置。
+type Filter struct {
+	Providers []AuthenticationProvider `autowire:""`
+
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	manager *Manager
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) *Filter {
+	return &Filter{config: &config}
+}
+
+// OnInit 使用容器中的 AuthenticationProvider 创建认证链。
+func (f *Filter) OnInit() {
+	f.manager = NewManager(f.Providers...)
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	req, ok := f.authenticate(ctx.ResponseWriter(), ctx.Request())
+	if !ok {
+		return
+	}
+	if req != ctx.Request() {
+		ctx.SetRequest(req)
+	}
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, ok := f.authenticate(w, r); ok {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// authenticate 对请求进行认证，认证失败时返回 401 响应并且 ok 为 false 。请求
+// 没有 knife 缓存时返回携带了认证结果的新请求。
+func (f *Filter) authenticate(w http.ResponseWriter, r *http.Request) (_ *http.Request, ok bool) {
+
+	a, err := f.manager.Authenticate(r)
+	if err != nil {
+		if !errors.Is(err, ErrUnauthenticated) || !f.config.PermitAnonymous {
+			log.Ctx(r.Context()).Infof("authentication failed: %v", err)
+			f.unauthorized(w)
+			return nil, false
+		}
+		return r, true
+	}
+
+	ctx := r.Context()
+	knife.Set(ctx, authKey, a)
+	if AuthenticationOf(ctx) != a { // 没有 knife 缓存
+		r = r.WithContext(WithAuthentication(ctx, a))
+	}
+	return r, true
+}
+
+func (f *Filter) unauthorized(w http.ResponseWriter) {
+	if c := f.manager.challenges(); len(c) > 0 {
+		w.Header().Set("WWW-Authenticate", strings.Join(c, ", "))
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTConfig JWT 认证配置，通常绑定到 security.jwt 前缀的属性上。
+type JWTConfig struct {
+	Header           string        `value:"${header:=Authorization}"`    // 携带令牌的请求头，值的格式为 Bearer <token>
+	Secret           string        `value:"${secret:=}"`                 // HS256 、HS384 、HS512 算法的密钥
+	PublicKey        string        `value:"${public-key:=}"`             // RS256 、RS384 、RS512 算法的 PEM 格式公钥
+	Issuer           string        `value:"${issuer:=}"`                 // 令牌的签发者，为空时不校验
+	Audience         string        `value:"${audience:=}"`               // 令牌的接收者，为空时不校验
+	Leeway           time.Duration `value:"${leeway:=0}"`                // 校验过期时间时允许的时钟偏差
+	NameClaim        string        `value:"${name-claim:=sub}"`          // 用户名称所在的 claim
+	RolesClaim       string        `value:"${roles-claim:=roles}"`       // 用户角色所在的 claim
+	PermissionsClaim string        `value:"${permissions-claim:=scope}"` // 用户权限所在的 claim
+}
+
+// JWTProvider 使用 Bearer 令牌的 JWT 认证方式，支持 HMAC 和 RSA 签名算法。为了
+// 避免算法混淆攻击，只接受与所配置的密钥类型相匹配的算法。
+type JWTProvider struct {
+	config    *JWTConfig // 使用指针避免容器对其进行属性绑定
+	publicKey *rsa.PublicKey
+}
+
+// NewJWTProvider JWTProvider 的构造函数，secret 和 public-key 必须配置一个。
+func NewJWTProvider(config JWTConfig) (*JWTProvider, error) {
+	p := &JWTProvider{config: &config}
+	if config.PublicKey != "" {
+		key, err := parseRSAPublicKey(config.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		p.publicKey = key
+	} else if config.Secret == "" {
+		return nil, errors.New("security: jwt secret or public-key required")
+	}
+	return p, nil
+}
+
+func parseRSAPublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("security: invalid jwt public-key")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("security: jwt public-key is not rsa")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := key.(*rsa.PublicKey); ok {
+		return k, nil
+	}
+	return nil, errors.New("security: jwt public-key is not rsa")
+}
+
+// Challenge 返回认证失败时 WWW-Authenticate 响应头的值。
+func (p *JWTProvider) Challenge() string {
+	return "Bearer"
+}
+
+func (p *JWTProvider) Authenticate(r *http.Request) (*Authentication, error) {
+	token := r.Header.Get(p.config.Header)
+	if len(token) < 7 || !strings.EqualFold(token[:7], "Bearer ") {
+		return nil, nil
+	}
+	token = strings.TrimSpace(token[7:])
+	if strings.Count(token, ".") != 2 {
+		return nil, nil // 不是 JWT 格式的令牌，可能是其他 Bearer 令牌
+	}
+	return p.Parse(token)
+}
+
+// Parse 校验令牌的签名和时间等声明，返回令牌对应的认证结果。
+func (p *JWTProvider) Parse(token string) (*Authentication, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, badJWT("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, badJWT("malformed header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, badJWT("malformed signature")
+	}
+	if err = p.verify(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, badJWT("malformed claims")
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	if exp, ok := numericClaim(claims, "exp"); ok {
+		expiresAt = time.Unix(exp, 0)
+		if now.After(expiresAt.Add(p.config.Leeway)) {
+			return nil, badJWT("token expired")
+		}
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok {
+		if now.Add(p.config.Leeway).Before(time.Unix(nbf, 0)) {
+			return nil, badJWT("token not valid yet")
+		}
+	}
+	if p.config.Issuer != "" && claims["iss"] != p.config.Issuer {
+		return nil, badJWT("invalid issuer")
+	}
+	if p.config.Audience != "" && !contains(stringsClaim(claims, "aud"), p.config.Audience) {
+		return nil, badJWT("invalid audience")
+	}
+
+	name, _ := claims[p.config.NameClaim].(string)
+	if name == "" {
+		return nil, badJWT("missing " + p.config.NameClaim + " claim")
+	}
+
+	return &Authentication{
+		Principal: &Principal{
+			Name:        name,
+			Roles:       stringsClaim(claims, p.config.RolesClaim),
+			Permissions: stringsClaim(claims, p.config.PermissionsClaim),
+			Attributes:  claims,
+		},
+		Scheme:    "jwt",
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (p *JWTProvider) verify(alg, signed string, sig []byte) error {
+
+	var h func() hash.Hash
+	var c crypto.Hash
+	switch alg {
+	case "HS256", "RS256":
+		h, c = sha256.New, crypto.SHA256
+	case "HS384", "RS384":
+		h, c = sha512.New384, crypto.SHA384
+	case "HS512", "RS512":
+		h, c = sha512.New, crypto.SHA512
+	default:
+		return badJWT("unsupported algorithm " + alg)
+	}
+
+	if p.publicKey != nil {
+		if !strings.HasPrefix(alg, "RS") {
+			return badJWT("unexpected algorithm " + alg)
+		}
+		d := c.New()
+		d.Write([]byte(signed))
+		if rsa.VerifyPKCS1v15(p.publicKey, c, d.Sum(nil), sig) != nil {
+			return badJWT("invalid signature")
+		}
+		return nil
+	}
+
+	if !strings.HasPrefix(alg, "HS") {
+		return badJWT("unexpected algorithm " + alg)
+	}
+	m := hmac.New(h, []byte(p.config.Secret))
+	m.Write([]byte(signed))
+	if !hmac.Equal(m.Sum(nil), sig) {
+		return badJWT("invalid signature")
+	}
+	return nil
+}
+
+func badJWT(reason string) error {
+	return fmt.Errorf("%w: jwt %s", ErrBadCredentials, reason)
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func numericClaim(claims map[string]interface{}, key string) (int64, bool) {
+	f, ok := claims[key].(float64)
+	return int64(f), ok
+}
+
+// stringsClaim 返回字符串数组形式的 claim ，字符串形式的 claim 按照空格分割，
+// 例如 OAuth2 的 scope 。
+func stringsClaim(claims map[string]interface{}, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var ret []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+	return nil
+}
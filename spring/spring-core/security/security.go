@@ -0,0 +1,165 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package security 提供可插拔的认证机制。请求经过 Filter 时由 Manager 依次调用
+// 各个 AuthenticationProvider 进行认证，认证结果保存在请求的 ctx 中，无论是在
+// handler 还是在 service 中都可以通过 CurrentUser 获取当前用户。内置了 JWT 、
+// API key 和 HTTP basic 三种认证方式，导出为 AuthenticationProvider 接口的 bean
+// 会自动加入认证链。
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-spring/spring-stl/knife"
+)
+
+var (
+	// ErrBadCredentials 请求携带了凭证但是凭证无效。
+	ErrBadCredentials = errors.New("security: bad credentials")
+
+	// ErrUnauthenticated 请求没有携带任何凭证。
+	ErrUnauthenticated = errors.New("security: unauthenticated")
+)
+
+// Principal 经过认证的用户。
+type Principal struct {
+	Name        string                 // 用户名称
+	Roles       []string               // 用户的角色
+	Permissions []string               // 用户的权限
+	Attributes  map[string]interface{} // 其他属性，例如 JWT 的 claims
+}
+
+// HasRole 判断用户是否拥有角色 role 。
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasPermission 判断用户是否拥有权限 perm 。
+func (p *Principal) HasPermission(perm string) bool {
+	return contains(p.Permissions, perm)
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Authentication 认证结果。
+type Authentication struct {
+	Principal *Principal
+	Scheme    string    // 认证方式，例如 jwt 、apikey 、basic
+	ExpiresAt time.Time // 凭证的过期时间，零值表示不过期
+}
+
+// AuthenticationProvider 认证方式。请求没有携带该方式的凭证时返回 nil, nil ，
+// 从而交给认证链中的下一个 AuthenticationProvider 处理，凭证无效时返回的错误
+// 应该包装 ErrBadCredentials 。
+type AuthenticationProvider interface {
+	Authenticate(r *http.Request) (*Authentication, error)
+}
+
+// ProviderFunc 函数形式的 AuthenticationProvider 。
+type ProviderFunc func(r *http.Request) (*Authentication, error)
+
+func (f ProviderFunc) Authenticate(r *http.Request) (*Authentication, error) {
+	return f(r)
+}
+
+// Challenger 可以由 AuthenticationProvider 实现，返回认证失败时 WWW-Authenticate
+// 响应头的值。
+type Challenger interface {
+	Challenge() string
+}
+
+// Manager 按照顺序调用 AuthenticationProvider 的认证链。
+type Manager struct {
+	providers []AuthenticationProvider
+}
+
+// NewManager Manager 的构造函数。
+func NewManager(providers ...AuthenticationProvider) *Manager {
+	return &Manager{providers: providers}
+}
+
+// Authenticate 返回第一个认出请求凭证的 AuthenticationProvider 的认证结果，
+// 请求没有携带任何凭证时返回 ErrUnauthenticated 。
+func (m *Manager) Authenticate(r *http.Request) (*Authentication, error) {
+	for _, p := range m.providers {
+		a, err := p.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if a != nil {
+			return a, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+// challenges 返回认证链中各个 AuthenticationProvider 的 WWW-Authenticate 值。
+func (m *Manager) challenges() []string {
+	var ret []string
+	for _, p := range m.providers {
+		if c, ok := p.(Challenger); ok {
+			if s := c.Challenge(); s != "" {
+				ret = append(ret, s)
+			}
+		}
+	}
+	return ret
+}
+
+const authKey = "::authentication::"
+
+type ctxKey int
+
+const ctxAuthKey = ctxKey(0)
+
+// AuthenticationOf 返回 ctx 中保存的认证结果，没有时返回 nil 。
+func AuthenticationOf(ctx context.Context) *Authentication {
+	if a, ok := knife.Get(ctx, authKey).(*Authentication); ok {
+		return a
+	}
+	a, _ := ctx.Value(ctxAuthKey).(*Authentication)
+	return a
+}
+
+// WithAuthentication 返回保存了认证结果的 ctx ，用于在请求之外的场景，例如消费
+// 消息或者执行定时任务时，以指定的用户身份调用 service 。
+func WithAuthentication(ctx context.Context, a *Authentication) context.Context {
+	return context.WithValue(ctx, ctxAuthKey, a)
+}
+
+// CurrentUser 返回 ctx 中保存的当前用户，匿名访问时返回 nil 。
+func CurrentUser(ctx context.Context) *Principal {
+	if a := AuthenticationOf(ctx); a != nil {
+		return a.Principal
+	}
+	return nil
+}
+
+// IsAuthenticated 判断 ctx 中是否保存了经过认证的用户。
+func IsAuthenticated(ctx context.Context) bool {
+	return CurrentUser(ctx) != nil
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func segment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signHS256(claims map[string]interface{}, secret string) string {
+	s := segment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(claims)
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(s))
+	return s + "." + base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func signRS256(claims map[string]interface{}, key *rsa.PrivateKey) string {
+	s := segment(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + segment(claims)
+	sum := sha256.Sum256([]byte(s))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	return s + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwtConfig() security.JWTConfig {
+	return security.JWTConfig{
+		Header:           "Authorization",
+		Secret:           "secret",
+		NameClaim:        "sub",
+		RolesClaim:       "roles",
+		PermissionsClaim: "scope",
+	}
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestJWTProvider(t *testing.T) {
+
+	_, err := security.NewJWTProvider(security.JWTConfig{})
+	assert.Error(t, err, "jwt secret or public-key required")
+
+	config := jwtConfig()
+	config.Issuer = "go-spring"
+	config.Audience = "api"
+	p, err := security.NewJWTProvider(config)
+	assert.Nil(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signHS256(map[string]interface{}{
+		"sub":   "alice",
+		"iss":   "go-spring",
+		"aud":   []string{"api", "web"},
+		"exp":   exp,
+		"roles": []string{"admin"},
+		"scope": "read write",
+	}, "secret")
+
+	a, err := p.Authenticate(bearer(token))
+	assert.Nil(t, err)
+	assert.Equal(t, a.Scheme, "jwt")
+	assert.Equal(t, a.ExpiresAt.Unix(), exp)
+	assert.Equal(t, a.Principal.Name, "alice")
+	assert.True(t, a.Principal.HasRole("admin"))
+	assert.True(t, a.Principal.HasPermission("write"))
+
+	a, err = p.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, a == nil && err == nil)
+
+	a, err = p.Authenticate(bearer("opaque-token"))
+	assert.True(t, a == nil && err == nil)
+
+	testcases := []struct {
+		token string
+		err   string
+	}{
+		{signHS256(map[string]interface{}{"sub": "alice", "iss": "go-spring", "aud": "api"}, "other"), "jwt invalid signature"},
+		{signHS256(map[string]interface{}{"sub": "alice", "iss": "go-spring", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix()}, "secret"), "jwt token expired"},
+		{signHS256(map[string]interface{}{"sub": "alice", "iss": "go-spring", "aud": "api", "nbf": time.Now().Add(time.Minute).Unix()}, "secret"), "jwt token not valid yet"},
+		{signHS256(map[string]interface{}{"sub": "alice", "iss": "other", "aud": "api"}, "secret"), "jwt invalid issuer"},
+		{signHS256(map[string]interface{}{"sub": "alice", "iss": "go-spring", "aud": "web"}, "secret"), "jwt invalid audience"},
+		{signHS256(map[string]interface{}{"iss": "go-spring", "aud": "api"}, "secret"), "jwt missing sub claim"},
+		{segment(map[string]string{"alg": "none"}) + "." + segment(map[string]string{"sub": "alice"}) + ".", "jwt unsupported algorithm none"},
+	}
+	for _, c := range testcases {
+		_, err = p.Authenticate(bearer(c.token))
+		assert.True(t, errors.Is(err, security.ErrBadCredentials))
+		assert.Error(t, err, c.err)
+	}
+}
+
+func TestJWTProvider_RSA(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	config := jwtConfig()
+	config.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	p, err := security.NewJWTProvider(config)
+	assert.Nil(t, err)
+
+	a, err := p.Authenticate(bearer(signRS256(map[string]interface{}{"sub": "bob"}, key)))
+	assert.Nil(t, err)
+	assert.Equal(t, a.Principal.Name, "bob")
+
+	// 配置了公钥时不接受使用 HMAC 签名的令牌
+	_, err = p.Authenticate(bearer(signHS256(map[string]interface{}{"sub": "bob"}, "secret")))
+	assert.Error(t, err, "jwt unexpected algorithm HS256")
+}
+
+type apiKeyStore map[string]*security.Principal
+
+func (s apiKeyStore) Lookup(ctx context.Context, key string) (*security.Principal, error) {
+	return s[key], nil
+}
+
+func TestAPIKeyProvider(t *testing.T) {
+
+	sum := sha256.Sum256([]byte("k2"))
+	p := security.NewAPIKeyProvider(security.APIKeyConfig{
+		Header: "X-API-Key",
+		Query:  "api_key",
+		Keys: []security.APIKey{
+			{Key: "k1", Name: "svc-a", Roles: []string{"service"}},
+			{Key: "{sha256}" + hex.EncodeToString(sum[:]), Name: "svc-b"},
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", "k1")
+	a, err := p.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Scheme, "apikey")
+	assert.Equal(t, a.Principal.Name, "svc-a")
+	assert.True(t, a.Principal.HasRole("service"))
+
+	a, err = p.Authenticate(httptest.NewRequest(http.MethodGet, "/?api_key=k2", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, a.Principal.Name, "svc-b")
+
+	r.Header.Set("X-API-Key", "k3")
+	_, err = p.Authenticate(r)
+	assert.True(t, errors.Is(err, security.ErrBadCredentials))
+
+	p.Store = apiKeyStore{"k3": {Name: "svc-c"}}
+	a, err = p.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Principal.Name, "svc-c")
+}
+
+func TestBasicProvider(t *testing.T) {
+
+	p := security.NewBasicProvider(security.BasicConfig{
+		Realm: "demo",
+		Users: []security.User{{Name: "alice", Password: "pass", Roles: []string{"admin"}}},
+	})
+	assert.Equal(t, p.Challenge(), `Basic realm="demo"`)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("alice", "pass")
+	a, err := p.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Scheme, "basic")
+	assert.True(t, a.Principal.HasRole("admin"))
+
+	r.SetBasicAuth("alice", "wrong")
+	_, err = p.Authenticate(r)
+	assert.True(t, errors.Is(err, security.ErrBadCredentials))
+}
+
+func TestManager(t *testing.T) {
+
+	custom := security.ProviderFunc(func(r *http.Request) (*security.Authentication, error) {
+		if r.Header.Get("X-User") == "" {
+			return nil, nil
+		}
+		return &security.Authentication{Principal: &security.Principal{Name: r.Header.Get("X-User")}, Scheme: "custom"}, nil
+	})
+	p, err := security.NewJWTProvider(jwtConfig())
+	assert.Nil(t, err)
+	m := security.NewManager(p, custom)
+
+	_, err = m.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, errors.Is(err, security.ErrUnauthenticated))
+
+	r := bearer(signHS256(map[string]interface{}{"sub": "alice"}, "secret"))
+	r.Header.Set("X-User", "bob")
+	a, err := m.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Principal.Name, "alice")
+
+	r.Header.Del("Authorization")
+	a, err = m.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Scheme, "custom")
+}
+
+func TestFilter_Handler(t *testing.T) {
+
+	jwt, err := security.NewJWTProvider(jwtConfig())
+	assert.Nil(t, err)
+	basic := security.NewBasicProvider(security.BasicConfig{Realm: "demo"})
+
+	f := security.NewFilter(security.Config{PermitAnonymous: true})
+	f.Providers = []security.AuthenticationProvider{jwt, basic}
+	f.OnInit()
+
+	var user *security.Principal
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = security.CurrentUser(r.Context())
+		assert.Equal(t, security.IsAuthenticated(r.Context()), user != nil)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, bearer(signHS256(map[string]interface{}{"sub": "alice"}, "secret")))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, user.Name, "alice")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, user == nil)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, bearer(signHS256(map[string]interface{}{"sub": "alice"}, "other")))
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, w.Header().Get("WWW-Authenticate"), `Bearer, Basic realm="demo"`)
+
+	f = security.NewFilter(security.Config{PermitAnonymous: false})
+	f.OnInit()
+	w = httptest.NewRecorder()
+	f.Handler(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
+
+func TestWithAuthentication(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, security.CurrentUser(ctx) == nil)
+	a := &security.Authentication{Principal: &security.Principal{Name: "job"}}
+	ctx = security.WithAuthentication(ctx, a)
+	assert.Equal(t, security.AuthenticationOf(ctx), a)
+	assert.Equal(t, security.CurrentUser(ctx).Name, "job")
+}
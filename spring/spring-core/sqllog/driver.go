@@ -0,0 +1,337 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Open 打开数据库连接，拦截器未开启时等价于 sql.Open，否则在驱动层拦截所有的
+// SQL 语句。driverName 对应的驱动必须已经通过 sql.Register 注册。
+func Open(driverName string, dsn string, i *Interceptor) (*sql.DB, error) {
+
+	if i == nil || !i.config.Enabled {
+		return sql.Open(driverName, dsn)
+	}
+
+	// sql.Open 不会建立连接，这里只是借助它获取注册的驱动。
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := &wrappedDriver{d: db.Driver(), i: i}
+	if err = db.Close(); err != nil {
+		return nil, err
+	}
+
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(c), nil
+}
+
+// Wrap 返回拦截所有 SQL 语句的驱动。
+func Wrap(d driver.Driver, i *Interceptor) driver.Driver {
+	return &wrappedDriver{d: d, i: i}
+}
+
+type wrappedDriver struct {
+	d driver.Driver
+	i *Interceptor
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{c: c, i: d.i}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.d.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &wrappedConnector{c: c, d: d}, nil
+	}
+	return &dsnConnector{dsn: name, d: d}, nil
+}
+
+type wrappedConnector struct {
+	c driver.Connector
+	d *wrappedDriver
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{c: conn, i: c.d.i}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type dsnConnector struct {
+	dsn string
+	d   *wrappedDriver
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.d.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// wrappedConn 拦截连接上执行的语句，底层连接没有实现的可选接口返回
+// driver.ErrSkip，由 database/sql 回退到其他方式执行。
+type wrappedConn struct {
+	c driver.Conn
+	i *Interceptor
+}
+
+func (c *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if p, ok := c.c.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedStmt{s: s, query: query, i: c.i}, nil
+}
+
+func (c *wrappedConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *wrappedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := c.c.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		if opts.Isolation != 0 || opts.ReadOnly {
+			return nil, errors.New("sqllog: driver does not support non-default transaction options")
+		}
+		tx, err = c.c.Begin()
+	}
+	c.i.observe(ctx, "begin", "BEGIN", nil, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedTx{tx: tx, ctx: ctx, i: c.i}, nil
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.c.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	r, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.i.observe(ctx, "exec", query, values(args), start, err)
+	}
+	return r, err
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.c.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	r, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.i.observe(ctx, "query", query, values(args), start, err)
+	}
+	return r, err
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.c.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.c.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if v, ok := c.c.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.c.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+type wrappedTx struct {
+	tx  driver.Tx
+	ctx context.Context
+	i   *Interceptor
+}
+
+func (t *wrappedTx) Commit() error {
+	start := time.Now()
+	err := t.tx.Commit()
+	t.i.observe(t.ctx, "commit", "COMMIT", nil, start, err)
+	return err
+}
+
+func (t *wrappedTx) Rollback() error {
+	start := time.Now()
+	err := t.tx.Rollback()
+	t.i.observe(t.ctx, "rollback", "ROLLBACK", nil, start, err)
+	return err
+}
+
+type wrappedStmt struct {
+	s     driver.Stmt
+	query string
+	i     *Interceptor
+}
+
+func (s *wrappedStmt) Close() error {
+	return s.s.Close()
+}
+
+func (s *wrappedStmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+func (s *wrappedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	r, err := s.s.Exec(args)
+	s.i.observe(context.Background(), "exec", s.query, toInterfaces(args), start, err)
+	return r, err
+}
+
+func (s *wrappedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	r, err := s.s.Query(args)
+	s.i.observe(context.Background(), "query", s.query, toInterfaces(args), start, err)
+	return r, err
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		r   driver.Result
+		err error
+	)
+	if e, ok := s.s.(driver.StmtExecContext); ok {
+		r, err = e.ExecContext(ctx, args)
+	} else {
+		var vs []driver.Value
+		if vs, err = namedToValues(args); err == nil {
+			r, err = s.s.Exec(vs)
+		}
+	}
+	s.i.observe(ctx, "exec", s.query, values(args), start, err)
+	return r, err
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		r   driver.Rows
+		err error
+	)
+	if q, ok := s.s.(driver.StmtQueryContext); ok {
+		r, err = q.QueryContext(ctx, args)
+	} else {
+		var vs []driver.Value
+		if vs, err = namedToValues(args); err == nil {
+			r, err = s.s.Query(vs)
+		}
+	}
+	s.i.observe(ctx, "query", s.query, values(args), start, err)
+	return r, err
+}
+
+func (s *wrappedStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := s.s.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func values(args []driver.NamedValue) []interface{} {
+	ret := make([]interface{}, len(args))
+	for i, arg := range args {
+		ret[i] = arg.Value
+	}
+	return ret
+}
+
+func toInterfaces(args []driver.Value) []interface{} {
+	ret := make([]interface{}, len(args))
+	for i, arg := range args {
+		ret[i] = arg
+	}
+	return ret
+}
+
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	ret := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		ret[i] = arg.Value
+	}
+	return ret, nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqllog
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/metrics"
+)
+
+type metricsObserver struct {
+	latency *metrics.TimerVec
+	slow    *metrics.CounterVec
+}
+
+// NewMetricsObserver 创建将 SQL 语句的耗时和慢查询次数记录到 r 中的观察者。
+func NewMetricsObserver(r *metrics.Registry) Observer {
+	return &metricsObserver{
+		latency: r.Timer("db_query_seconds", "SQL statement latency in seconds.", "op", "status"),
+		slow:    r.Counter("db_slow_queries_total", "Total number of slow SQL statements.", "op"),
+	}
+}
+
+func (o *metricsObserver) ObserveQuery(ctx context.Context, q *Query) {
+	status := "ok"
+	if q.Err != nil {
+		status = "error"
+	}
+	o.latency.With(q.Op, status).Observe(q.Duration)
+	if q.Slow {
+		o.slow.With(q.Op).Inc()
+	}
+}
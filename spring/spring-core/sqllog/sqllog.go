@@ -0,0 +1,190 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sqllog 在 database/sql 驱动层拦截所有的 SQL 语句，记录慢查询及其参数，
+// 统计执行耗时的分布，并通过 Observer 接口将执行结果通知给监控、链路追踪等组件。
+package sqllog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// Config SQL 日志配置，通常绑定到 db.slowlog 前缀的属性上。
+type Config struct {
+	Enabled      bool          `value:"${enabled:=false}"`     // 是否拦截 SQL 语句
+	Threshold    time.Duration `value:"${threshold:=200ms}"`   // 慢查询的耗时阈值
+	LogAll       bool          `value:"${log-all:=false}"`     // 是否以 DEBUG 级别记录所有语句
+	MaskArgs     bool          `value:"${mask-args:=false}"`   // 是否隐藏语句的参数
+	MaxArgLength int           `value:"${max-arg-length:=64}"` // 参数在日志中的最大长度，0 表示不限制
+	Buckets      []float64     `value:"${buckets}"`            // 耗时分布的桶边界，单位为毫秒
+}
+
+// Query 一次 SQL 语句的执行结果。
+type Query struct {
+	Op       string        // 操作类型，例如 exec、query、begin、commit
+	SQL      string        // SQL 语句
+	Args     []interface{} // 语句的参数
+	Start    time.Time     // 开始执行的时间
+	Duration time.Duration // 执行耗时
+	Err      error         // 执行错误
+	Slow     bool          // 是否为慢查询
+}
+
+// Observer SQL 语句执行结果的观察者，例如统计指标或者为当前的链路追踪 span
+// 添加标签，实现该接口的 bean 会被自动注册到拦截器上。
+type Observer interface {
+	ObserveQuery(ctx context.Context, q *Query)
+}
+
+// ObserverFunc 函数形式的 Observer 。
+type ObserverFunc func(ctx context.Context, q *Query)
+
+func (f ObserverFunc) ObserveQuery(ctx context.Context, q *Query) {
+	f(ctx, q)
+}
+
+// DefaultBuckets 默认的耗时分布桶边界，单位为毫秒。
+var DefaultBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// Histogram 按照操作类型统计 SQL 语句的耗时分布。
+type Histogram struct {
+	buckets []float64
+	mutex   sync.Mutex
+	data    map[string]*HistogramData
+}
+
+// HistogramData 一种操作类型的耗时分布。
+type HistogramData struct {
+	Buckets []float64 // 桶边界，单位为毫秒
+	Counts  []uint64  // 每个桶的累计计数，最后一个元素为总数
+	Sum     float64   // 总耗时，单位为毫秒
+}
+
+// NewHistogram Histogram 的构造函数，buckets 为空时使用 DefaultBuckets 。
+func NewHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{buckets: buckets, data: make(map[string]*HistogramData)}
+}
+
+// ObserveQuery 记录一次语句的执行耗时。
+func (h *Histogram) ObserveQuery(ctx context.Context, q *Query) {
+	ms := float64(q.Duration) / float64(time.Millisecond)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	d, ok := h.data[q.Op]
+	if !ok {
+		d = &HistogramData{Buckets: h.buckets, Counts: make([]uint64, len(h.buckets)+1)}
+		h.data[q.Op] = d
+	}
+	for i, b := range h.buckets {
+		if ms <= b {
+			d.Counts[i]++
+		}
+	}
+	d.Counts[len(h.buckets)]++
+	d.Sum += ms
+}
+
+// Snapshot 返回每种操作类型的耗时分布的副本。
+func (h *Histogram) Snapshot() map[string]HistogramData {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ret := make(map[string]HistogramData, len(h.data))
+	for op, d := range h.data {
+		ret[op] = HistogramData{
+			Buckets: d.Buckets,
+			Counts:  append([]uint64(nil), d.Counts...),
+			Sum:     d.Sum,
+		}
+	}
+	return ret
+}
+
+// Interceptor SQL 语句拦截器，记录慢查询日志并通知所有的观察者。
+type Interceptor struct {
+	Observers []Observer `autowire:""`
+
+	config    *Config // 使用指针避免容器对其进行属性绑定
+	histogram *Histogram
+}
+
+// NewInterceptor Interceptor 的构造函数。
+func NewInterceptor(config Config) *Interceptor {
+	return &Interceptor{config: &config, histogram: NewHistogram(config.Buckets)}
+}
+
+// Histogram 返回拦截器统计的耗时分布。
+func (i *Interceptor) Histogram() *Histogram {
+	return i.histogram
+}
+
+func (i *Interceptor) observe(ctx context.Context, op string, query string, args []interface{}, start time.Time, err error) {
+
+	q := &Query{
+		Op:       op,
+		SQL:      query,
+		Args:     args,
+		Start:    start,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	q.Slow = i.config.Threshold > 0 && q.Duration >= i.config.Threshold
+
+	if q.Slow {
+		log.Ctx(ctx).Warnf("slow sql cost %v: %s %s", q.Duration, q.SQL, i.formatArgs(args))
+	} else if i.config.LogAll {
+		log.Ctx(ctx).Debugf("sql cost %v: %s %s", q.Duration, q.SQL, i.formatArgs(args))
+	}
+
+	i.histogram.ObserveQuery(ctx, q)
+
+	for _, o := range i.Observers {
+		o.ObserveQuery(ctx, q)
+	}
+}
+
+// formatArgs 返回参数的日志格式，开启隐藏参数时只显示参数的个数。
+func (i *Interceptor) formatArgs(args []interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if i.config.MaskArgs {
+		return fmt.Sprintf("args=[%d masked]", len(args))
+	}
+	ss := make([]string, len(args))
+	for j, arg := range args {
+		s := fmt.Sprintf("%v", arg)
+		if b, ok := arg.([]byte); ok {
+			s = fmt.Sprintf("[%d bytes]", len(b))
+		}
+		if n := i.config.MaxArgLength; n > 0 && len(s) > n {
+			s = s[:n] + "..."
+		}
+		ss[j] = s
+	}
+	return "args=[" + strings.Join(ss, ", ") + "]"
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqllog_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/sqllog"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "SLOW") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("sqllog-fake", fakeDriver{})
+}
+
+func TestOpen(t *testing.T) {
+
+	i := sqllog.NewInterceptor(sqllog.Config{
+		Enabled:   true,
+		Threshold: 10 * time.Millisecond,
+		MaskArgs:  true,
+	})
+
+	var queries []*sqllog.Query
+	i.Observers = append(i.Observers, sqllog.ObserverFunc(func(ctx context.Context, q *sqllog.Query) {
+		queries = append(queries, q)
+	}))
+
+	db, err := sqllog.Open("sqllog-fake", "", i)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("UPDATE user SET name = ? WHERE id = ?", "jim", 1)
+	assert.Nil(t, err)
+	_, err = db.Exec("SLOW UPDATE")
+	assert.Nil(t, err)
+
+	// 底层连接没有实现 QueryerContext 接口，语句通过 Prepare 执行。
+	rows, err := db.Query("SELECT id FROM user WHERE id = ?", 1)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	var ops []string
+	for _, q := range queries {
+		ops = append(ops, q.Op)
+	}
+	assert.Equal(t, ops, []string{"exec", "exec", "query", "begin", "commit"})
+	assert.Equal(t, queries[0].Args, []interface{}{"jim", int64(1)})
+	assert.False(t, queries[0].Slow)
+	assert.True(t, queries[1].Slow)
+
+	h := i.Histogram().Snapshot()
+	assert.Equal(t, h["exec"].Counts[len(h["exec"].Counts)-1], uint64(2))
+	assert.Equal(t, h["query"].Counts[len(h["query"].Counts)-1], uint64(1))
+}
+
+func TestConfig_Bind(t *testing.T) {
+	p := conf.New()
+	p.Set("db.slowlog.buckets[0]", 10)
+	p.Set("db.slowlog.buckets[1]", 100)
+	var c sqllog.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("db.slowlog")))
+	assert.Equal(t, c.Buckets, []float64{10, 100})
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package storage 提供了标准的对象存储接口，可以灵活适配 S3、MinIO、阿里云 OSS
+// 等各种对象存储服务。
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotExist 对象不存在时返回的错误。
+var ErrNotExist = errors.New("storage: object not exist")
+
+// Config 对象存储配置，通常绑定到 storage 前缀的属性上。
+type Config struct {
+	Type      string `value:"${type:=s3}"`      // 存储服务类型，例如 s3、oss
+	Endpoint  string `value:"${endpoint:=}"`    // 服务地址
+	Region    string `value:"${region:=}"`      // 区域
+	Bucket    string `value:"${bucket}"`        // 存储桶
+	AccessKey string `value:"${access-key:=}"`  // 访问密钥 ID
+	SecretKey string `value:"${secret-key:=}"`  // 访问密钥
+	UseSSL    bool   `value:"${use-ssl:=true}"` // 是否使用 HTTPS
+}
+
+// Object 对象的元信息。
+type Object struct {
+	Key          string    // 对象名称
+	Size         int64     // 对象大小
+	ETag         string    // 对象内容的标识
+	ContentType  string    // 对象的内容类型
+	LastModified time.Time // 最后修改时间
+}
+
+// Storage 对象存储接口，所有操作都作用于配置的存储桶。
+type Storage interface {
+
+	// Put 上传对象，size 为 -1 时表示大小未知，contentType 为空时由服务端决定。
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get 下载对象，对象不存在时返回 ErrNotExist，调用者负责关闭返回的 io.ReadCloser 。
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Stat 获取对象的元信息，对象不存在时返回 ErrNotExist 。
+	Stat(ctx context.Context, key string) (*Object, error)
+
+	// Delete 删除对象，对象不存在时不返回错误。
+	Delete(ctx context.Context, key string) error
+
+	// List 列出 prefix 开头的所有对象。
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Presign 生成预签名的访问地址，method 为 GET 或者 PUT 。
+	Presign(ctx context.Context, method string, key string, expires time.Duration) (string, error)
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+// Resolver 从请求中识别租户，无法识别时返回空字符串。
+type Resolver interface {
+	Resolve(r *http.Request) string
+}
+
+// ResolverFunc 函数形式的 Resolver 。
+type ResolverFunc func(r *http.Request) string
+
+func (f ResolverFunc) Resolve(r *http.Request) string {
+	return f(r)
+}
+
+// HeaderResolver 从请求头中识别租户。
+func HeaderResolver(header string) Resolver {
+	return ResolverFunc(func(r *http.Request) string {
+		return r.Header.Get(header)
+	})
+}
+
+// SubdomainResolver 从 domain 的子域名中识别租户，例如 acme.example.com 中的
+// acme ，只识别一级子域名。
+func SubdomainResolver(domain string) Resolver {
+	suffix := "." + strings.TrimPrefix(domain, ".")
+	return ResolverFunc(func(r *http.Request) string {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		s := strings.TrimSuffix(strings.ToLower(host), suffix)
+		if s == host || s == "" || strings.Contains(s, ".") {
+			return ""
+		}
+		return s
+	})
+}
+
+// ClaimResolver 从经过认证的用户的属性中识别租户，例如 JWT 的 claim ，需要在
+// security.Filter 之后执行。
+func ClaimResolver(claim string) Resolver {
+	return ResolverFunc(func(r *http.Request) string {
+		if p := security.CurrentUser(r.Context()); p != nil {
+			if s, ok := p.Attributes[claim].(string); ok {
+				return s
+			}
+		}
+		return ""
+	})
+}
+
+// Config 租户识别配置，通常绑定到 tenancy 前缀的属性上。
+type Config struct {
+	Resolvers []string `value:"${resolvers}"`           // 按顺序使用的识别方式，header 、subdomain 或者 claim ，为空时使用 header
+	Header    string   `value:"${header:=X-Tenant-ID}"` // header 方式使用的请求头
+	Domain    string   `value:"${domain:=}"`            // subdomain 方式使用的根域名
+	Claim     string   `value:"${claim:=tenant}"`       // claim 方式使用的用户属性
+	Required  bool     `value:"${required:=false}"`     // 是否拒绝无法识别租户的请求
+	Default   string   `value:"${default:=}"`           // 无法识别租户时使用的租户
+	Tenants   []string `value:"${tenants}"`             // 允许的租户，为空时不限制
+}
+
+// Filter 识别请求所属的租户并保存到请求 ctx 中的过滤器。
+type Filter struct {
+	Resolver Resolver `autowire:"?"` // 自定义的识别方式，优先于配置的识别方式
+
+	config    *Config // 使用指针避免容器对其进行属性绑定
+	resolvers []Resolver
+}
+
+// NewFilter Filter 的构造函数。
+func NewFilter(config Config) (*Filter, error) {
+	names := config.Resolvers
+	if len(names) == 0 {
+		names = []string{"header"}
+	}
+	f := &Filter{config: &config}
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "header":
+			f.resolvers = append(f.resolvers, HeaderResolver(config.Header))
+		case "subdomain":
+			if config.Domain == "" {
+				return nil, fmt.Errorf("tenancy: domain required by subdomain resolver")
+			}
+			f.resolvers = append(f.resolvers, SubdomainResolver(config.Domain))
+		case "claim":
+			f.resolvers = append(f.resolvers, ClaimResolver(config.Claim))
+		default:
+			return nil, fmt.Errorf("tenancy: unknown resolver %q", name)
+		}
+	}
+	return f, nil
+}
+
+// OnInit 把自定义的识别方式放在最前面。
+func (f *Filter) OnInit() {
+	if f.Resolver != nil {
+		f.resolvers = append([]Resolver{f.Resolver}, f.resolvers...)
+	}
+}
+
+func (f *Filter) Invoke(ctx web.Context, chain web.FilterChain) {
+	req, ok := f.bind(ctx.ResponseWriter(), ctx.Request())
+	if !ok {
+		return
+	}
+	if req != ctx.Request() {
+		ctx.SetRequest(req)
+	}
+	chain.Next(ctx)
+}
+
+// Handler 返回包装了 next 的 http.Handler ，用于没有使用 web 包的服务。
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, ok := f.bind(w, r); ok {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// resolve 按顺序使用各个识别方式识别租户。
+func (f *Filter) resolve(r *http.Request) string {
+	for _, resolver := range f.resolvers {
+		if id := resolver.Resolve(r); id != "" {
+			return id
+		}
+	}
+	return f.config.Default
+}
+
+// bind 识别请求所属的租户并保存到请求的 knife 缓存中，识别失败时返回 400 响应或
+// 者租户不允许时返回 403 响应，并且 ok 为 false 。请求没有 knife 缓存时返回携带
+// 了租户的新请求。
+func (f *Filter) bind(w http.ResponseWriter, r *http.Request) (_ *http.Request, ok bool) {
+
+	id := f.resolve(r)
+	if id == "" {
+		if f.config.Required {
+			http.Error(w, "tenant required", http.StatusBadRequest)
+			return nil, false
+		}
+		return r, true
+	}
+
+	if len(f.config.Tenants) > 0 && !contains(f.config.Tenants, id) {
+		log.Ctx(r.Context()).Infof("tenancy: unknown tenant %q", id)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return nil, false
+	}
+
+	ctx := r.Context()
+	knife.Set(ctx, tenantKey, id)
+	if ID(ctx) != id { // 没有 knife 缓存
+		r = r.WithContext(WithID(ctx, id))
+	}
+	return r, true
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// Prefix 租户专属属性的前缀，tenants.<id>.<key> 会覆盖该租户的 <key> 属性。
+const Prefix = "tenants"
+
+// Overlay 为每个租户在全局属性之上叠加专属的属性。
+type Overlay struct {
+	base  *conf.Properties
+	mutex sync.Mutex
+	cache map[string]*conf.Properties
+}
+
+// NewOverlay Overlay 的构造函数，base 是全局的属性列表。
+func NewOverlay(base *conf.Properties) *Overlay {
+	return &Overlay{base: base, cache: make(map[string]*conf.Properties)}
+}
+
+// Tenants 返回配置了专属属性的租户。
+func (o *Overlay) Tenants() []string {
+	m := make(map[string]struct{})
+	for _, k := range o.base.Keys() {
+		if s := strings.TrimPrefix(k, Prefix+"."); s != k {
+			if i := strings.IndexAny(s, ".["); i > 0 {
+				m[s[:i]] = struct{}{}
+			}
+		}
+	}
+	var ret []string
+	for id := range m {
+		ret = append(ret, id)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Properties 返回租户 id 的属性列表，结果会被缓存。
+func (o *Overlay) Properties(id string) *conf.Properties {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if p, ok := o.cache[id]; ok {
+		return p
+	}
+
+	base := o.base.Snapshot()
+	prefix := Prefix + "." + id + "."
+	m := make(map[string]interface{})
+	var overrides []string
+	for _, k := range base.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			overrides = append(overrides, k)
+		} else {
+			m[k] = base.Get(k)
+		}
+	}
+	for _, k := range overrides {
+		m[strings.TrimPrefix(k, prefix)] = base.Get(k)
+	}
+	p := conf.Map(m)
+	o.cache[id] = p
+	return p
+}
+
+// Prop 返回 ctx 中的租户的属性值，没有租户时返回全局的属性值。
+func (o *Overlay) Prop(ctx context.Context, key string, opts ...conf.GetOption) interface{} {
+	return o.of(ctx).Get(key, opts...)
+}
+
+// Bind 使用 ctx 中的租户的属性列表进行属性绑定，没有租户时使用全局的属性列表。
+func (o *Overlay) Bind(ctx context.Context, i interface{}, opts ...conf.BindOption) error {
+	return o.of(ctx).Bind(i, opts...)
+}
+
+func (o *Overlay) of(ctx context.Context) *conf.Properties {
+	if id := ID(ctx); id != "" {
+		return o.Properties(id)
+	}
+	return o.base
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tenancy 为 SaaS 应用提供多租户支持。Filter 从请求头、子域名或者 JWT
+// 的 claim 中识别租户并保存到请求的 knife 缓存中，Overlay 为每个租户在全局属性
+// 之上叠加 tenants.<id> 前缀下的专属属性，Router 则按照租户选择专属的 bean ，
+// 例如为每个租户路由到独立的数据源。
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-stl/knife"
+)
+
+var (
+	// ErrNoTenant ctx 中没有租户。
+	ErrNoTenant = errors.New("tenancy: no tenant in context")
+
+	// ErrUnknownTenant 租户不存在。
+	ErrUnknownTenant = errors.New("tenancy: unknown tenant")
+)
+
+const tenantKey = "::tenant::"
+
+type ctxKey int
+
+const ctxTenantKey = ctxKey(0)
+
+// ID 返回 ctx 中保存的租户 ID ，没有时返回空字符串。
+func ID(ctx context.Context) string {
+	if id, ok := knife.Get(ctx, tenantKey).(string); ok {
+		return id
+	}
+	id, _ := ctx.Value(ctxTenantKey).(string)
+	return id
+}
+
+// WithID 返回保存了租户 ID 的 ctx ，用于在请求之外的场景，例如消费消息或者执行
+// 定时任务时，以指定租户的身份调用 service 。
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxTenantKey, id)
+}
+
+// BeanName 返回租户专属 bean 的名称，使用该名称注册的 bean 可以通过 Router
+// 按照租户选择，也可以通过 autowire:"name@tenant" 直接注入。
+func BeanName(name, tenant string) string {
+	return name + "@" + tenant
+}
+
+// Router 按照 ctx 中的租户选择专属的 bean 。
+type Router[T any] struct {
+	mutex   sync.Mutex
+	beans   map[string]T
+	def     T
+	hasDef  bool
+	factory func(tenant string) (T, error)
+}
+
+// NewRouter 使用名称为 BeanName(name, tenant) 的 bean 创建 Router ，名称为
+// name 的 bean 作为没有专属 bean 的租户的默认值。beans 通常是容器注入的
+// map[string]T 类型的集合。
+func NewRouter[T any](name string, beans map[string]T) *Router[T] {
+	r := &Router[T]{beans: make(map[string]T)}
+	for k, b := range beans {
+		if k == name {
+			r.def, r.hasDef = b, true
+		} else if strings.HasPrefix(k, name+"@") {
+			r.beans[strings.TrimPrefix(k, name+"@")] = b
+		}
+	}
+	return r
+}
+
+// NewFactoryRouter 创建按需为租户创建 bean 的 Router ，创建成功的 bean 会被缓
+// 存，fn 通常使用 Overlay 绑定租户专属的配置。
+func NewFactoryRouter[T any](fn func(tenant string) (T, error)) *Router[T] {
+	return &Router[T]{beans: make(map[string]T), factory: fn}
+}
+
+// Get 返回 ctx 中的租户专属的 bean 。
+func (r *Router[T]) Get(ctx context.Context) (T, error) {
+	id := ID(ctx)
+	if id == "" {
+		var zero T
+		return zero, ErrNoTenant
+	}
+	return r.Tenant(id)
+}
+
+// Tenant 返回租户 id 专属的 bean 。
+func (r *Router[T]) Tenant(id string) (T, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if b, ok := r.beans[id]; ok {
+		return b, nil
+	}
+	if r.factory != nil {
+		b, err := r.factory(id)
+		if err != nil {
+			return b, err
+		}
+		r.beans[id] = b
+		return b, nil
+	}
+	if r.hasDef {
+		return r.def, nil
+	}
+	var zero T
+	return zero, fmt.Errorf("%w %q", ErrUnknownTenant, id)
+}
+
+// Each 遍历已有的租户专属 bean ，不包括默认值，例如用于在关闭时释放资源。
+func (r *Router[T]) Each(fn func(tenant string, b T)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for id, b := range r.beans {
+		fn(id, b)
+	}
+}
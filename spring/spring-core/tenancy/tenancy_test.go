@@ -0,0 +1,171 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/security"
+	"github.com/go-spring/spring-core/tenancy"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestResolvers(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com:8080/", nil)
+	r.Header.Set("X-Tenant-ID", "globex")
+	assert.Equal(t, tenancy.HeaderResolver("X-Tenant-ID").Resolve(r), "globex")
+	assert.Equal(t, tenancy.SubdomainResolver("example.com").Resolve(r), "acme")
+
+	for _, host := range []string{"example.com", "a.b.example.com", "acme.other.com"} {
+		r = httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		assert.Equal(t, tenancy.SubdomainResolver("example.com").Resolve(r), "")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, tenancy.ClaimResolver("tenant").Resolve(r), "")
+	ctx := security.WithAuthentication(r.Context(), &security.Authentication{
+		Principal: &security.Principal{Name: "alice", Attributes: map[string]interface{}{"tenant": "initech"}},
+	})
+	assert.Equal(t, tenancy.ClaimResolver("tenant").Resolve(r.WithContext(ctx)), "initech")
+}
+
+func TestFilter_Handler(t *testing.T) {
+
+	_, err := tenancy.NewFilter(tenancy.Config{Resolvers: []string{"cookie"}})
+	assert.Error(t, err, "tenancy: unknown resolver \"cookie\"")
+	_, err = tenancy.NewFilter(tenancy.Config{Resolvers: []string{"subdomain"}})
+	assert.Error(t, err, "tenancy: domain required by subdomain resolver")
+
+	f, err := tenancy.NewFilter(tenancy.Config{
+		Resolvers: []string{"header", "subdomain"},
+		Header:    "X-Tenant-ID",
+		Domain:    "example.com",
+		Required:  true,
+		Tenants:   []string{"acme", "globex"},
+	})
+	assert.Nil(t, err)
+	f.OnInit()
+
+	var tenant string
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = tenancy.ID(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, tenant, "acme")
+
+	r.Header.Set("X-Tenant-ID", "globex")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, tenant, "globex")
+
+	r.Header.Set("X-Tenant-ID", "initech")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	// 自定义的识别方式优先
+	f.Resolver = tenancy.ResolverFunc(func(r *http.Request) string { return "acme" })
+	f.OnInit()
+	r.Header.Set("X-Tenant-ID", "globex")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, tenant, "acme")
+}
+
+func TestOverlay(t *testing.T) {
+
+	p := conf.New()
+	p.Set("db.url", "mysql://shared")
+	p.Set("db.pool", 10)
+	p.Set("tenants.acme.db.url", "mysql://acme")
+	p.Set("tenants.globex.db.pool", 20)
+
+	o := tenancy.NewOverlay(p)
+	assert.Equal(t, o.Tenants(), []string{"acme", "globex"})
+
+	ctx := context.Background()
+	assert.Equal(t, o.Prop(ctx, "db.url"), "mysql://shared")
+	assert.Equal(t, o.Prop(tenancy.WithID(ctx, "acme"), "db.url"), "mysql://acme")
+
+	var c struct {
+		URL  string `value:"${url}"`
+		Pool int    `value:"${pool}"`
+	}
+	err := o.Bind(tenancy.WithID(ctx, "globex"), &c, conf.Key("db"))
+	assert.Nil(t, err)
+	assert.Equal(t, c.URL, "mysql://shared")
+	assert.Equal(t, c.Pool, 20)
+}
+
+type dataSource struct{ url string }
+
+func TestRouter(t *testing.T) {
+
+	r := tenancy.NewRouter("db", map[string]*dataSource{
+		"db":                              {url: "shared"},
+		tenancy.BeanName("db", "acme"):    {url: "acme"},
+		tenancy.BeanName("cache", "acme"): {url: "cache"},
+	})
+
+	_, err := r.Get(context.Background())
+	assert.Equal(t, err, tenancy.ErrNoTenant)
+
+	ds, err := r.Get(tenancy.WithID(context.Background(), "acme"))
+	assert.Nil(t, err)
+	assert.Equal(t, ds.url, "acme")
+
+	ds, err = r.Tenant("globex")
+	assert.Nil(t, err)
+	assert.Equal(t, ds.url, "shared")
+
+	calls := 0
+	r = tenancy.NewFactoryRouter(func(tenant string) (*dataSource, error) {
+		calls++
+		if tenant == "bad" {
+			return nil, tenancy.ErrUnknownTenant
+		}
+		return &dataSource{url: tenant}, nil
+	})
+	ds, err = r.Tenant("acme")
+	assert.Nil(t, err)
+	_, err = r.Tenant("acme")
+	assert.Nil(t, err)
+	assert.Equal(t, ds.url, "acme")
+	assert.Equal(t, calls, 1)
+	_, err = r.Tenant("bad")
+	assert.True(t, errors.Is(err, tenancy.ErrUnknownTenant))
+
+	var tenants []string
+	r.Each(func(tenant string, ds *dataSource) { tenants = append(tenants, tenant) })
+	assert.Equal(t, tenants, []string{"acme"})
+
+	r = tenancy.NewRouter("db", map[string]*dataSource{})
+	_, err = r.Tenant("acme")
+	assert.Error(t, err, "tenancy: unknown tenant \"acme\"")
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tuning 根据配置调整 Go 运行时的参数。GOMAXPROCS 默认按照 cgroup 的
+// CPU 配额设置 (与 automaxprocs 相同) ，避免容器中的应用按照宿主机的核数调度
+// 协程导致 CPU 被限流；GC 百分比和内存上限可以通过属性设置，内存上限也可以按照
+// cgroup 的内存限制的比例设置。生效的参数通过 runtime 管理端点查看。
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// GOMAXPROCS 的设置方式。
+const (
+	Auto = "auto" // 按照 cgroup 的 CPU 配额设置，没有配额时保持不变
+	Off  = "off"  // 保持不变
+)
+
+// Config 运行时参数配置，通常绑定到 runtime 前缀的属性上。
+type Config struct {
+	GOMAXPROCS       string  `value:"${gomaxprocs:=auto}"`            // auto 、off 或者具体的数值
+	MinProcs         int     `value:"${min-procs:=1}"`                // 按照 CPU 配额设置时的最小值
+	GCPercent        int     `value:"${gc-percent:=0}"`               // GC 百分比，0 表示不修改，-1 表示关闭 GC
+	MemoryLimit      int64   `value:"${memory-limit:=0}"`             // 内存上限的字节数，0 表示不修改
+	MemoryLimitRatio float64 `value:"${memory-limit-ratio:=0}"`       // 未设置内存上限时按照 cgroup 内存限制的比例设置，0 表示不设置
+	CgroupRoot       string  `value:"${cgroup-root:=/sys/fs/cgroup}"` // cgroup 文件系统的挂载点
+}
+
+// Tuner 应用运行时参数并记录生效的值，同时也是 runtime 管理端点。
+type Tuner struct {
+	config *Config // 使用指针避免容器对其进行属性绑定
+
+	cpuQuota    float64 // cgroup 的 CPU 配额，0 表示没有配额
+	memoryQuota int64   // cgroup 的内存限制，0 表示没有限制
+	procsSource string  // GOMAXPROCS 的来源：default 、cgroup 或者 config
+	gcPercent   int
+	memoryLimit int64
+}
+
+// New Tuner 的构造函数，校验配置后立即应用运行时参数，依赖 GOMAXPROCS 的 bean
+// (例如线程池) 应该声明对它的依赖。
+func New(config Config) (*Tuner, error) {
+	if config.MinProcs <= 0 {
+		config.MinProcs = 1
+	}
+	if config.MemoryLimit < 0 {
+		return nil, fmt.Errorf("tuning: memory-limit must not be negative")
+	}
+	if config.MemoryLimitRatio < 0 || config.MemoryLimitRatio > 1 {
+		return nil, fmt.Errorf("tuning: memory-limit-ratio must be between 0 and 1")
+	}
+	if config.GCPercent < -1 {
+		return nil, fmt.Errorf("tuning: invalid gc-percent %d", config.GCPercent)
+	}
+	t := &Tuner{config: &config}
+	if err := t.apply(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Tuner) apply() error {
+	c := t.config
+
+	if quota, ok := CPUQuota(c.CgroupRoot); ok {
+		t.cpuQuota = quota
+	}
+	if limit, ok := MemoryQuota(c.CgroupRoot); ok {
+		t.memoryQuota = limit
+	}
+
+	switch s := strings.TrimSpace(c.GOMAXPROCS); s {
+	case "", Off:
+		t.procsSource = "default"
+	case Auto:
+		if t.cpuQuota <= 0 {
+			t.procsSource = "default"
+			break
+		}
+		n := int(math.Floor(t.cpuQuota))
+		if n < c.MinProcs {
+			n = c.MinProcs
+		}
+		runtime.GOMAXPROCS(n)
+		t.procsSource = "cgroup"
+		log.Infof("tuning: GOMAXPROCS=%d (cpu quota %g)", n, t.cpuQuota)
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("tuning: invalid gomaxprocs %q", c.GOMAXPROCS)
+		}
+		runtime.GOMAXPROCS(n)
+		t.procsSource = "config"
+		log.Infof("tuning: GOMAXPROCS=%d", n)
+	}
+
+	if c.GCPercent != 0 {
+		debug.SetGCPercent(c.GCPercent)
+		log.Infof("tuning: gc percent %d", c.GCPercent)
+	}
+	t.gcPercent = gcPercent()
+
+	limit := c.MemoryLimit
+	if limit == 0 && c.MemoryLimitRatio > 0 && t.memoryQuota > 0 {
+		limit = int64(float64(t.memoryQuota) * c.MemoryLimitRatio)
+	}
+	if limit > 0 {
+		if !setMemoryLimit(limit) {
+			log.Warnf("tuning: memory limit requires go1.19 or later")
+		} else {
+			log.Infof("tuning: memory limit %d bytes", limit)
+		}
+	}
+	t.memoryLimit = memoryLimit()
+	return nil
+}
+
+// gcPercent 返回当前的 GC 百分比，debug 包没有只读取的函数，所以设置后立即恢复。
+func gcPercent() int {
+	n := debug.SetGCPercent(-1)
+	debug.SetGCPercent(n)
+	return n
+}
+
+// Values 生效的运行时参数。
+type Values struct {
+	GOMAXPROCS   int     `json:"gomaxprocs"`
+	ProcsSource  string  `json:"gomaxprocsSource"`
+	NumCPU       int     `json:"numCPU"`
+	CPUQuota     float64 `json:"cpuQuota,omitempty"`
+	GCPercent    int     `json:"gcPercent"`
+	MemoryLimit  int64   `json:"memoryLimit"` // -1 表示不支持设置内存上限
+	MemoryQuota  int64   `json:"memoryQuota,omitempty"`
+	GoVersion    string  `json:"goVersion"`
+	NumGoroutine int     `json:"goroutines"`
+}
+
+// Values 返回生效的运行时参数。
+func (t *Tuner) Values() Values {
+	return Values{
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		ProcsSource:  t.procsSource,
+		NumCPU:       runtime.NumCPU(),
+		CPUQuota:     t.cpuQuota,
+		GCPercent:    t.gcPercent,
+		MemoryLimit:  t.memoryLimit,
+		MemoryQuota:  t.memoryQuota,
+		GoVersion:    runtime.Version(),
+		NumGoroutine: runtime.NumGoroutine(),
+	}
+}
+
+// ID 返回端点的 ID 。
+func (t *Tuner) ID() string {
+	return "runtime"
+}
+
+func (t *Tuner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t.Values()); err != nil {
+		log.Error(err)
+	}
+}
+
+// CPUQuota 读取 root 下的 cgroup (优先 v2 ，其次 v1) 的 CPU 配额，返回值为可用的
+// CPU 核数，没有配额时 ok 为 false 。
+func CPUQuota(root string) (quota float64, ok bool) {
+
+	// cgroup v2: cpu.max 的内容为 "$MAX $PERIOD" ，$MAX 为 max 时表示没有配额。
+	if s, err := readFile(filepath.Join(root, "cpu.max")); err == nil {
+		fields := strings.Fields(s)
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return ratio(fields[0], fields[1])
+	}
+
+	// cgroup v1: cfs_quota_us 为 -1 时表示没有配额。
+	q, err := readFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	p, err := readFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return ratio(q, p)
+}
+
+func ratio(quota, period string) (float64, bool) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return float64(q) / float64(p), true
+}
+
+// unlimitedMemory cgroup v1 在没有内存限制时返回一个接近 int64 最大值的数。
+const unlimitedMemory = 1 << 62
+
+// MemoryQuota 读取 root 下的 cgroup (优先 v2 ，其次 v1) 的内存限制，没有限制时
+// ok 为 false 。
+func MemoryQuota(root string) (limit int64, ok bool) {
+	s, err := readFile(filepath.Join(root, "memory.max"))
+	if err != nil {
+		s, err = readFile(filepath.Join(root, "memory", "memory.limit_in_bytes"))
+		if err != nil {
+			return 0, false
+		}
+	}
+	if s == "max" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || n >= unlimitedMemory {
+		return 0, false
+	}
+	return n, true
+}
+
+func readFile(name string) (string, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,174 @@
+//go:build go1.19
+// +build go1.19
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tuning_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/go-spring/spring-core/tuning"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+		assert.Nil(t, os.WriteFile(file, []byte(content+"\n"), 0644))
+	}
+	return root
+}
+
+func TestCPUQuota(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		quota float64
+		ok    bool
+	}{
+		{files: map[string]string{}},
+		{files: map[string]string{"cpu.max": "max 100000"}},
+		{files: map[string]string{"cpu.max": "250000 100000"}, quota: 2.5, ok: true},
+		{files: map[string]string{"cpu.max": "bad"}},
+		{files: map[string]string{"cpu/cpu.cfs_quota_us": "-1", "cpu/cpu.cfs_period_us": "100000"}},
+		{files: map[string]string{"cpu/cpu.cfs_quota_us": "50000", "cpu/cpu.cfs_period_us": "100000"}, quota: 0.5, ok: true},
+	}
+	for _, c := range tests {
+		quota, ok := tuning.CPUQuota(writeFiles(t, c.files))
+		assert.Equal(t, ok, c.ok)
+		assert.Equal(t, quota, c.quota)
+	}
+}
+
+func TestMemoryQuota(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		limit int64
+		ok    bool
+	}{
+		{files: map[string]string{}},
+		{files: map[string]string{"memory.max": "max"}},
+		{files: map[string]string{"memory.max": "1073741824"}, limit: 1 << 30, ok: true},
+		{files: map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712"}},
+		{files: map[string]string{"memory/memory.limit_in_bytes": "536870912"}, limit: 1 << 29, ok: true},
+	}
+	for _, c := range tests {
+		limit, ok := tuning.MemoryQuota(writeFiles(t, c.files))
+		assert.Equal(t, ok, c.ok)
+		assert.Equal(t, limit, c.limit)
+	}
+}
+
+// restore 恢复测试修改的运行时参数。
+func restore(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	gc := debug.SetGCPercent(-1)
+	debug.SetGCPercent(gc)
+	limit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		runtime.GOMAXPROCS(procs)
+		debug.SetGCPercent(gc)
+		debug.SetMemoryLimit(limit)
+	})
+}
+
+func TestNew(t *testing.T) {
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := tuning.New(tuning.Config{GOMAXPROCS: "many"})
+		assert.Error(t, err, "tuning: invalid gomaxprocs \"many\"")
+		_, err = tuning.New(tuning.Config{MemoryLimitRatio: 2})
+		assert.Error(t, err, "tuning: memory-limit-ratio must be between 0 and 1")
+		_, err = tuning.New(tuning.Config{GCPercent: -2})
+		assert.Error(t, err, "tuning: invalid gc-percent -2")
+	})
+
+	t.Run("cgroup", func(t *testing.T) {
+		restore(t)
+		root := writeFiles(t, map[string]string{
+			"cpu.max":    "150000 100000",
+			"memory.max": "1073741824",
+		})
+		tuner, err := tuning.New(tuning.Config{
+			GOMAXPROCS:       tuning.Auto,
+			MinProcs:         1,
+			GCPercent:        50,
+			MemoryLimitRatio: 0.5,
+			CgroupRoot:       root,
+		})
+		assert.Nil(t, err)
+		v := tuner.Values()
+		assert.Equal(t, v.GOMAXPROCS, 1)
+		assert.Equal(t, v.ProcsSource, "cgroup")
+		assert.Equal(t, v.CPUQuota, 1.5)
+		assert.Equal(t, v.GCPercent, 50)
+		assert.Equal(t, v.MemoryQuota, int64(1<<30))
+		assert.Equal(t, v.MemoryLimit, int64(1<<29))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		restore(t)
+		tuner, err := tuning.New(tuning.Config{
+			GOMAXPROCS:  "3",
+			MemoryLimit: 1 << 28,
+			CgroupRoot:  t.TempDir(),
+		})
+		assert.Nil(t, err)
+		v := tuner.Values()
+		assert.Equal(t, runtime.GOMAXPROCS(0), 3)
+		assert.Equal(t, v.ProcsSource, "config")
+		assert.Equal(t, v.CPUQuota, float64(0))
+		assert.Equal(t, v.MemoryLimit, int64(1<<28))
+	})
+
+	t.Run("no quota", func(t *testing.T) {
+		restore(t)
+		procs := runtime.GOMAXPROCS(0)
+		tuner, err := tuning.New(tuning.Config{GOMAXPROCS: tuning.Auto, CgroupRoot: t.TempDir()})
+		assert.Nil(t, err)
+		assert.Equal(t, tuner.Values().GOMAXPROCS, procs)
+		assert.Equal(t, tuner.Values().ProcsSource, "default")
+	})
+}
+
+func TestTuner_ServeHTTP(t *testing.T) {
+	restore(t)
+	tuner, err := tuning.New(tuning.Config{GOMAXPROCS: tuning.Off, CgroupRoot: t.TempDir()})
+	assert.Nil(t, err)
+	assert.Equal(t, tuner.ID(), "runtime")
+
+	w := httptest.NewRecorder()
+	tuner.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runtime", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v tuning.Values
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &v))
+	assert.Equal(t, v.GOMAXPROCS, runtime.GOMAXPROCS(0))
+	assert.Equal(t, v.GoVersion, runtime.Version())
+
+	w = httptest.NewRecorder()
+	tuner.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/runtime", nil))
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Rule 校验规则，v 是非零值的字段值，param 是规则的参数，例如 min=3 中的 3 。
+type Rule func(v reflect.Value, param string) bool
+
+// FieldError 字段校验错误。
+type FieldError struct {
+	Field   string // 字段路径，例如 Items[0].Name
+	Label   string // 字段的显示名称，来自 label 标签，默认为字段名
+	Rule    string // 没有通过的规则
+	Param   string // 规则的参数
+	Message string // 翻译后的错误消息
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Errors 结构体的校验错误。
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Message
+	}
+	return strings.Join(s, "; ")
+}
+
+// Translate 使用 locale 语言的消息模板重新生成错误消息。
+func (e Errors) Translate(engine *Engine, locale string) Errors {
+	ret := make(Errors, len(e))
+	for i, err := range e {
+		c := *err
+		c.Message = engine.message(locale, &c)
+		ret[i] = &c
+	}
+	return ret
+}
+
+// Config 参数校验配置，通常绑定到 validator 前缀的属性上。
+type Config struct {
+	Tag    string `value:"${tag:=validate}"` // 校验规则使用的结构体标签
+	Locale string `value:"${locale:=en}"`    // 错误消息的语言
+}
+
+// Engine 基于结构体标签的参数校验器，标签的格式为 validate:"required,min=3"，
+// 多个规则之间使用逗号分隔。除了 required 之外的规则只校验非零值，嵌套的结构体、
+// 结构体指针以及它们的切片和 map 会被递归校验，实现了 interface{ Validate() error }
+// 的结构体在字段校验通过后还会调用该方法。
+type Engine struct {
+	config *Config // 使用指针避免容器对其进行属性绑定
+
+	mutex    sync.RWMutex
+	rules    map[string]Rule
+	messages map[string]map[string]*message // 注册时预先编译的消息模板
+}
+
+// NewEngine Engine 的构造函数。
+func NewEngine(config Config) *Engine {
+	e := &Engine{
+		config:   &config,
+		rules:    make(map[string]Rule),
+		messages: make(map[string]map[string]*message),
+	}
+	for name, r := range builtinRules {
+		e.rules[name] = r
+	}
+	for locale, m := range builtinMessages {
+		e.RegisterMessages(locale, m)
+	}
+	return e
+}
+
+// RegisterRule 注册自定义的校验规则。
+func (e *Engine) RegisterRule(name string, r Rule) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.rules[name] = r
+}
+
+// RegisterMessages 注册 locale 语言的消息模板，模板中可以使用 {field} 和
+// {param} 占位符，key 为规则名称，key 为 default 的模板用于没有模板的规则。
+// 模板在注册时编译，生成错误消息时不需要再扫描占位符。
+func (e *Engine) RegisterMessages(locale string, messages map[string]string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	m, ok := e.messages[locale]
+	if !ok {
+		m = make(map[string]*message)
+		e.messages[locale] = m
+	}
+	for k, s := range messages {
+		m[k] = compileMessage(s)
+	}
+}
+
+// Validate 校验结构体，i 可以是结构体或者结构体指针，失败时返回 Errors 类型的
+// 错误或者结构体 Validate 方法返回的错误。
+func (e *Engine) Validate(i interface{}) error {
+	return e.Struct(i)
+}
+
+// Struct 校验结构体，返回值同 Validate 方法。
+func (e *Engine) Struct(i interface{}) error {
+	var errs Errors
+	if err := e.validate(reflect.ValueOf(i), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (e *Engine) validate(v reflect.Value, path string, errs *Errors) error {
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.validate(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			p := fmt.Sprintf("%s[%v]", path, iter.Key().Interface())
+			if err := e.validate(iter.Value(), p, errs); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	n := len(*errs)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // 跳过未导出的字段
+			continue
+		}
+		fv := v.Field(i)
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+		if tag, ok := f.Tag.Lookup(e.config.Tag); ok && tag != "-" {
+			label := f.Tag.Get("label")
+			if label == "" {
+				label = f.Name
+			}
+			if err := e.check(fv, tag, fieldPath, label, errs); err != nil {
+				return err
+			}
+		}
+		if err := e.validate(fv, fieldPath, errs); err != nil {
+			return err
+		}
+	}
+
+	// 字段校验通过之后再调用结构体自身的校验方法
+	if len(*errs) > n || !v.CanInterface() {
+		return nil
+	}
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if s, ok := v.Interface().(interface{ Validate() error }); ok {
+		return s.Validate()
+	}
+	return nil
+}
+
+// check 使用标签中的规则校验字段值。
+func (e *Engine) check(v reflect.Value, tag, path, label string, errs *Errors) error {
+
+	// 指针字段的 required 只要求指针非空，其他规则校验指向的值
+	required := !v.IsZero()
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	for _, s := range strings.Split(tag, ",") {
+		name, param := s, ""
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			name, param = s[:i], s[i+1:]
+		}
+		if name == "" {
+			continue
+		}
+
+		if name == "required" {
+			if required {
+				continue
+			}
+		} else if v.IsZero() {
+			continue
+		}
+
+		e.mutex.RLock()
+		r, ok := e.rules[name]
+		e.mutex.RUnlock()
+		if !ok {
+			return fmt.Errorf("validator: unknown rule %q on %s", name, path)
+		}
+
+		if name == "required" || !r(v, param) {
+			err := &FieldError{Field: path, Label: label, Rule: name, Param: param}
+			err.Message = e.message(e.config.Locale, err)
+			*errs = append(*errs, err)
+			return nil // 每个字段只报告第一个错误
+		}
+	}
+	return nil
+}
+
+// message 使用 locale 语言的消息模板生成错误消息。
+func (e *Engine) message(locale string, err *FieldError) string {
+	e.mutex.RLock()
+	m := e.messages[locale]
+	if m == nil {
+		m = e.messages["en"]
+	}
+	msg, ok := m[err.Rule]
+	if !ok {
+		msg, ok = m["default"]
+	}
+	e.mutex.RUnlock()
+	if !ok {
+		return err.Label + " failed on " + strconv.Quote(err.Rule)
+	}
+	return msg.render(err.Label, err.Param)
+}
+
+// 消息模板中的占位符。
+const (
+	slotField = iota
+	slotParam
+)
+
+// slotLen 占位符的长度，{field} 和 {param} 的长度相同。
+const slotLen = len("{field}")
+
+// message 编译后的消息模板，texts 比 slots 多一个元素，生成消息时交替输出文本和
+// 占位符的值。
+type message struct {
+	texts []string
+	slots []int
+	size  int // 文本的总长度
+}
+
+// compileMessage 编译消息模板，找出其中所有的 {field} 和 {param} 占位符。
+func compileMessage(s string) *message {
+	msg := &message{size: len(s)}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' {
+			continue
+		}
+		var slot int
+		switch rest := s[i:]; {
+		case strings.HasPrefix(rest, "{field}"):
+			slot = slotField
+		case strings.HasPrefix(rest, "{param}"):
+			slot = slotParam
+		default:
+			continue
+		}
+		msg.texts = append(msg.texts, s[start:i])
+		msg.slots = append(msg.slots, slot)
+		msg.size -= slotLen
+		start = i + slotLen
+		i = start - 1
+	}
+	msg.texts = append(msg.texts, s[start:])
+	return msg
+}
+
+func (msg *message) render(field, param string) string {
+	if len(msg.slots) == 0 {
+		return msg.texts[0]
+	}
+	values := [2]string{slotField: field, slotParam: param}
+	var b strings.Builder
+	b.Grow(msg.size + len(msg.slots)*(len(field)+len(param)))
+	for i, slot := range msg.slots {
+		b.WriteString(msg.texts[i])
+		b.WriteString(values[slot])
+	}
+	b.WriteString(msg.texts[len(msg.slots)])
+	return b.String()
+}
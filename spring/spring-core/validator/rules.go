@@ -0,0 +1,174 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validator
+
+import (
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var builtinRules = map[string]Rule{
+	"required": func(v reflect.Value, param string) bool { return !v.IsZero() },
+	"min":      compare(func(n, p float64) bool { return n >= p }),
+	"max":      compare(func(n, p float64) bool { return n <= p }),
+	"len":      compare(func(n, p float64) bool { return n == p }),
+	"gt":       compare(func(n, p float64) bool { return n > p }),
+	"lt":       compare(func(n, p float64) bool { return n < p }),
+	"oneof":    oneOf,
+	"email":    stringRule(isEmail),
+	"url":      stringRule(isURL),
+	"alpha":    stringRule(func(s string) bool { return all(s, unicode.IsLetter) }),
+	"alphanum": stringRule(func(s string) bool {
+		return all(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	}),
+	"numeric": stringRule(func(s string) bool { _, err := strconv.ParseFloat(s, 64); return err == nil }),
+	"pattern": matchPattern,
+}
+
+var builtinMessages = map[string]map[string]string{
+	"en": {
+		"default":  "{field} is invalid",
+		"required": "{field} is required",
+		"min":      "{field} must be at least {param}",
+		"max":      "{field} must be at most {param}",
+		"len":      "{field} must be exactly {param}",
+		"gt":       "{field} must be greater than {param}",
+		"lt":       "{field} must be less than {param}",
+		"oneof":    "{field} must be one of [{param}]",
+		"email":    "{field} must be a valid email address",
+		"url":      "{field} must be a valid URL",
+		"alpha":    "{field} must contain only letters",
+		"alphanum": "{field} must contain only letters and digits",
+		"numeric":  "{field} must be numeric",
+		"pattern":  "{field} has an invalid format",
+	},
+	"zh": {
+		"default":  "{field}格式不正确",
+		"required": "{field}不能为空",
+		"min":      "{field}不能小于{param}",
+		"max":      "{field}不能大于{param}",
+		"len":      "{field}必须等于{param}",
+		"gt":       "{field}必须大于{param}",
+		"lt":       "{field}必须小于{param}",
+		"oneof":    "{field}必须是[{param}]中的一个",
+		"email":    "{field}必须是有效的邮箱地址",
+		"url":      "{field}必须是有效的 URL",
+		"alpha":    "{field}只能包含字母",
+		"alphanum": "{field}只能包含字母和数字",
+		"numeric":  "{field}必须是数字",
+		"pattern":  "{field}格式不正确",
+	},
+}
+
+// measure 返回用于比较的数值，字符串使用字符数，切片和 map 使用长度。
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compare(fn func(n, p float64) bool) Rule {
+	return func(v reflect.Value, param string) bool {
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false
+		}
+		n, ok := measure(v)
+		return ok && fn(n, p)
+	}
+}
+
+func oneOf(v reflect.Value, param string) bool {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return false
+	}
+	for _, o := range strings.Fields(param) {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
+
+func stringRule(fn func(s string) bool) Rule {
+	return func(v reflect.Value, param string) bool {
+		return v.Kind() == reflect.String && fn(v.String())
+	}
+}
+
+func all(s string, fn func(r rune) bool) bool {
+	for _, r := range s {
+		if !fn(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+var patterns sync.Map // 缓存编译后的正则表达式
+
+func matchPattern(v reflect.Value, param string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	var re *regexp.Regexp
+	if r, ok := patterns.Load(param); ok {
+		re = r.(*regexp.Regexp)
+	} else {
+		var err error
+		if re, err = regexp.Compile(param); err != nil {
+			return false
+		}
+		patterns.Store(param, re)
+	}
+	return re.MatchString(v.String())
+}
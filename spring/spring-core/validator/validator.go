@@ -14,7 +14,8 @@
  * limitations under the License.
  */
 
-// Package validator 提供了参数校验器接口。
+// Package validator 提供了参数校验器接口，以及基于结构体标签的默认实现 Engine 。
+// 参数校验器初始化之后，web 请求参数和配置结构体在绑定之后都会自动进行校验。
 package validator
 
 // Validator 参数校验器接口。
@@ -41,6 +42,11 @@ func (f funcValidator) Validate(i interface{}) error {
 	return f(i)
 }
 
+// Default 返回当前使用的参数校验器，没有初始化时返回 nil 。
+func Default() Validator {
+	return v
+}
+
 // Validate 参数校验。
 func Validate(i interface{}) error {
 	if v != nil {
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validator_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-core/validator"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type address struct {
+	City string `validate:"required" label:"城市"`
+}
+
+type user struct {
+	Name    string   `validate:"required,min=2,max=8"`
+	Email   string   `validate:"email"`
+	Age     int      `validate:"gt=0,lt=150"`
+	Role    string   `validate:"oneof=admin user"`
+	Code    string   `validate:"even"`
+	Home    *address `validate:"required"`
+	Others  []address
+	Tags    map[string]string `validate:"max=2"`
+	private string
+}
+
+type account struct {
+	Balance int
+}
+
+func (a *account) Validate() error {
+	if a.Balance < 0 {
+		return errors.New("balance must not be negative")
+	}
+	return nil
+}
+
+func newEngine(locale string) *validator.Engine {
+	e := validator.NewEngine(validator.Config{Tag: "validate", Locale: locale})
+	e.RegisterRule("even", func(v reflect.Value, param string) bool {
+		return len(v.String())%2 == 0
+	})
+	return e
+}
+
+func TestEngine(t *testing.T) {
+
+	e := newEngine("en")
+
+	t.Run("valid", func(t *testing.T) {
+		u := user{Name: "jim", Email: "jim@example.com", Age: 20, Role: "admin", Code: "ab", Home: &address{City: "bj"}}
+		assert.Nil(t, e.Struct(&u))
+	})
+
+	t.Run("zero values skip rules", func(t *testing.T) {
+		u := user{Name: "jim", Home: &address{City: "bj"}}
+		assert.Nil(t, e.Struct(u))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		u := user{
+			Name:   "j",
+			Email:  "jim",
+			Age:    200,
+			Role:   "root",
+			Code:   "abc",
+			Others: []address{{City: "sh"}, {}},
+			Tags:   map[string]string{"a": "", "b": "", "c": ""},
+		}
+		err := e.Struct(&u)
+		var errs validator.Errors
+		assert.True(t, errors.As(err, &errs))
+		var fields []string
+		for _, fe := range errs {
+			fields = append(fields, fe.Field+":"+fe.Rule)
+		}
+		assert.Equal(t, fields, []string{
+			"Name:min", "Email:email", "Age:lt", "Role:oneof",
+			"Code:even", "Home:required", "Others[1].City:required", "Tags:max",
+		})
+		assert.Equal(t, errs[0].Message, "Name must be at least 2")
+		assert.Equal(t, errs[4].Message, "Code is invalid")
+		assert.Equal(t, errs[6].Message, "城市 is required")
+	})
+
+	t.Run("translate", func(t *testing.T) {
+		err := e.Struct(&user{Name: "jim"})
+		errs := err.(validator.Errors).Translate(e, "zh")
+		assert.Equal(t, errs.Error(), "Home不能为空")
+	})
+
+	t.Run("validate method", func(t *testing.T) {
+		assert.Nil(t, e.Struct(&account{Balance: 1}))
+		assert.Error(t, e.Struct(&account{Balance: -1}), "balance must not be negative")
+	})
+
+	t.Run("unexported embedded", func(t *testing.T) {
+		s := struct {
+			account
+			Name string `validate:"required"`
+		}{account: account{Balance: -1}, Name: "a"}
+		assert.Error(t, e.Struct(&s), "balance must not be negative")
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		s := struct {
+			A string `validate:"foo"`
+		}{A: "a"}
+		assert.Error(t, e.Struct(s), "validator: unknown rule \"foo\" on A")
+	})
+}
+
+func TestLocale(t *testing.T) {
+	e := newEngine("zh")
+	e.RegisterMessages("zh", map[string]string{"even": "{field}的长度必须是偶数"})
+	err := e.Struct(&user{Name: "jim", Code: "a", Home: &address{City: "bj"}})
+	assert.Equal(t, err.Error(), "Code的长度必须是偶数")
+	err = e.Struct(&user{Name: "ji你好", Home: &address{}})
+	assert.True(t, strings.HasPrefix(err.Error(), "城市不能为空"))
+}
+
+func TestMessages(t *testing.T) {
+	e := newEngine("fr")
+	e.RegisterMessages("fr", map[string]string{
+		"min":     "{field} {x} {param}/{param} {field",
+		"default": "{{field}}",
+	})
+	s := struct {
+		Name string `validate:"min=3"`
+		Code string `validate:"even"`
+	}{Name: "ab", Code: "abc"}
+	errs := e.Struct(&s).(validator.Errors)
+	assert.Equal(t, errs[0].Message, "Name {x} 3/3 {field")
+	assert.Equal(t, errs[1].Message, "{Code}")
+
+	// 没有注册的语言使用英文的消息模板，没有模板的规则使用默认格式。
+	e = validator.NewEngine(validator.Config{Tag: "validate", Locale: "de"})
+	e.RegisterMessages("en", map[string]string{"default": ""})
+	e.RegisterRule("odd", func(v reflect.Value, param string) bool { return false })
+	errs = e.Struct(&struct {
+		A string `validate:"odd"`
+	}{A: "a"}).(validator.Errors)
+	assert.Equal(t, errs[0].Message, "")
+}
+
+func BenchmarkEngine_Message(b *testing.B) {
+	e := newEngine("zh")
+	s := struct {
+		Name string `validate:"min=3"`
+	}{Name: "ab"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = e.Struct(&s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validator.Init(newEngine("en"))
+	defer validator.Init(nil)
+	assert.Error(t, validator.Validate(&address{}), "城市 is required")
+}
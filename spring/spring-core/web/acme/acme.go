@@ -0,0 +1,227 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package acme 通过 ACME 协议 (例如 Let's Encrypt) 自动申请和续期 TLS 证书，
+// 支持 HTTP-01 和 TLS-ALPN-01 两种验证方式，证书可以缓存在本地目录或 Redis
+// 中，后者适用于多个实例共享证书的场景。所有选项都可以通过属性进行配置。
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-core/web"
+	xacme "golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ALPNProto TLS-ALPN-01 验证使用的应用层协议名称。
+const ALPNProto = xacme.ALPNProto
+
+// Config ACME 配置，通常绑定到 web.server.acme 前缀的属性上。
+type Config struct {
+	Domains      []string      `value:"${domains}"`                          // 允许申请证书的域名，不能为空
+	Email        string        `value:"${email:=}"`                          // 账号的联系邮箱，用于接收证书过期等通知
+	DirectoryURL string        `value:"${directory-url:=}"`                  // ACME 服务的目录地址，为空时使用 Let's Encrypt 生产环境
+	Cache        string        `value:"${cache:=disk}"`                      // 证书缓存方式，可选 disk 和 redis
+	CacheDir     string        `value:"${cache-dir:=certs}"`                 // 本地缓存的目录
+	CachePrefix  string        `value:"${cache-prefix:=acme:}"`              // Redis 缓存的 key 前缀
+	HTTPEnabled  bool          `value:"${http-challenge.enabled:=true}"`     // 是否开启 HTTP-01 验证
+	HTTPAddr     string        `value:"${http-challenge.addr:=:80}"`         // HTTP-01 验证服务器的监听地址
+	HTTPRedirect bool          `value:"${http-challenge.redirect:=true}"`    // 非验证请求是否重定向到 HTTPS
+	ALPNEnabled  bool          `value:"${tls-alpn-challenge.enabled:=true}"` // 是否开启 TLS-ALPN-01 验证
+	RenewBefore  time.Duration `value:"${renew-before:=720h}"`               // 证书过期前多久开始续期
+}
+
+// Manager 证书管理器，在 TLS 握手时按需申请证书，并在证书过期前自动续期。
+type Manager struct {
+	Redis redis.Client `autowire:"?"`
+
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	manager *autocert.Manager
+	server  *http.Server
+	wg      sync.WaitGroup
+}
+
+// New Manager 的构造函数。
+func New(config Config) (*Manager, error) {
+	if len(config.Domains) == 0 {
+		return nil, errors.New("acme: domains is empty")
+	}
+	if !config.HTTPEnabled && !config.ALPNEnabled {
+		return nil, errors.New("acme: at least one challenge type must be enabled")
+	}
+	switch config.Cache {
+	case "disk", "redis":
+	default:
+		return nil, fmt.Errorf("acme: unsupported cache %q", config.Cache)
+	}
+	domains := make([]string, len(config.Domains))
+	for i, d := range config.Domains {
+		domains[i] = normalize(d)
+	}
+	m := &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Email:       config.Email,
+		HostPolicy:  autocert.HostWhitelist(domains...),
+		RenewBefore: config.RenewBefore,
+	}
+	if config.DirectoryURL != "" {
+		m.Client = &xacme.Client{DirectoryURL: config.DirectoryURL}
+	}
+	return &Manager{config: &config, manager: m}, nil
+}
+
+// OnInit 创建证书缓存，开启 HTTP-01 验证时启动验证服务器。
+func (m *Manager) OnInit() error {
+
+	switch m.config.Cache {
+	case "disk":
+		m.manager.Cache = autocert.DirCache(m.config.CacheDir)
+	case "redis":
+		if m.Redis == nil {
+			return errors.New("acme: redis cache requires a redis.Client bean")
+		}
+		m.manager.Cache = NewRedisCache(m.Redis, m.config.CachePrefix)
+	}
+
+	if !m.config.HTTPEnabled {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", m.config.HTTPAddr)
+	if err != nil {
+		return err
+	}
+
+	var fallback http.Handler
+	if !m.config.HTTPRedirect {
+		fallback = http.NotFoundHandler()
+	}
+	m.server = &http.Server{Handler: m.HTTPHandler(fallback)}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		log.Infof("acme http challenge server started on %s", l.Addr())
+		if err := m.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("acme http challenge server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// OnDestroy 关闭 HTTP-01 验证服务器。
+func (m *Manager) OnDestroy() {
+	if m.server == nil {
+		return
+	}
+	if err := m.server.Shutdown(context.Background()); err != nil {
+		log.Error(err)
+	}
+	m.wg.Wait()
+}
+
+// TLSConfig 返回按需获取证书的 TLS 配置，关闭 TLS-ALPN-01 验证时不再声明
+// acme-tls/1 协议。
+func (m *Manager) TLSConfig() *tls.Config {
+	c := m.manager.TLSConfig()
+	c.GetCertificate = m.GetCertificate
+	if !m.config.ALPNEnabled {
+		var protos []string
+		for _, p := range c.NextProtos {
+			if p != ALPNProto {
+				protos = append(protos, p)
+			}
+		}
+		c.NextProtos = protos
+	}
+	return c
+}
+
+// GetCertificate 根据 TLS 握手信息获取证书，可以直接用于 tls.Config 。
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !m.config.ALPNEnabled && supportsALPN(hello) {
+		return nil, errors.New("acme: tls-alpn challenge is disabled")
+	}
+	return m.manager.GetCertificate(hello)
+}
+
+// HTTPHandler 返回处理 HTTP-01 验证请求的 http.Handler ，其他请求交给
+// fallback 处理，fallback 为 nil 时重定向到 HTTPS 。
+func (m *Manager) HTTPHandler(fallback http.Handler) http.Handler {
+	return m.manager.HTTPHandler(fallback)
+}
+
+// Configure 为 Web 容器开启 SSL 并使用自动管理的证书。
+func (m *Manager) Configure(config *web.ContainerConfig) {
+	config.EnableSSL = true
+	config.TLSConfig = m.TLSConfig()
+}
+
+// supportsALPN 返回是否为 TLS-ALPN-01 验证请求。
+func supportsALPN(hello *tls.ClientHelloInfo) bool {
+	return len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == ALPNProto
+}
+
+// RedisCache 基于 Redis 的证书缓存，多个实例可以共享同一份证书和账号密钥。
+type RedisCache struct {
+	client redis.Client
+	prefix string
+}
+
+// NewRedisCache RedisCache 的构造函数。
+func NewRedisCache(client redis.Client, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+// Get 获取缓存的数据，不存在时返回 autocert.ErrCacheMiss 。
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.client.Get(ctx, c.prefix+key)
+	if errors.Is(err, redis.ErrNil) {
+		return nil, autocert.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(v), nil
+}
+
+// Put 保存数据，证书由 Manager 负责续期，因此不设置过期时间。
+func (c *RedisCache) Put(ctx context.Context, key string, data []byte) error {
+	return c.client.Set(ctx, c.prefix+key, string(data), 0)
+}
+
+// Delete 删除缓存的数据。
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	_, err := c.client.Del(ctx, c.prefix+key)
+	return err
+}
+
+var _ autocert.Cache = (*RedisCache)(nil)
+
+// normalize 统一域名的格式。
+func normalize(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package acme_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/redis"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-core/web/acme"
+	"github.com/go-spring/spring-stl/assert"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type mapClient struct {
+	redis.Client
+	m map[string]string
+}
+
+func (c *mapClient) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := c.m[key]; ok {
+		return v, nil
+	}
+	return "", redis.ErrNil
+}
+
+func (c *mapClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.m[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *mapClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	var n int64
+	for _, k := range keys {
+		if _, ok := c.m[k]; ok {
+			delete(c.m, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func config() acme.Config {
+	return acme.Config{
+		Domains:      []string{"Example.COM."},
+		Cache:        "disk",
+		HTTPEnabled:  true,
+		HTTPAddr:     "127.0.0.1:0",
+		HTTPRedirect: true,
+		ALPNEnabled:  true,
+	}
+}
+
+func TestNew(t *testing.T) {
+
+	c := config()
+	c.Domains = nil
+	_, err := acme.New(c)
+	assert.Error(t, err, "acme: domains is empty")
+
+	c = config()
+	c.HTTPEnabled, c.ALPNEnabled = false, false
+	_, err = acme.New(c)
+	assert.Error(t, err, "acme: at least one challenge type must be enabled")
+
+	c = config()
+	c.Cache = "memcached"
+	_, err = acme.New(c)
+	assert.Error(t, err, "acme: unsupported cache \"memcached\"")
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	client := &mapClient{m: make(map[string]string)}
+	c := acme.NewRedisCache(client, "acme:")
+
+	_, err := c.Get(ctx, "example.com")
+	assert.Equal(t, err, autocert.ErrCacheMiss)
+
+	assert.Nil(t, c.Put(ctx, "example.com", []byte("pem")))
+	assert.Equal(t, client.m["acme:example.com"], "pem")
+
+	b, err := c.Get(ctx, "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "pem")
+
+	assert.Nil(t, c.Delete(ctx, "example.com"))
+	_, err = c.Get(ctx, "example.com")
+	assert.Equal(t, err, autocert.ErrCacheMiss)
+}
+
+func TestManager_OnInit(t *testing.T) {
+
+	t.Run("disk", func(t *testing.T) {
+		c := config()
+		c.CacheDir = t.TempDir()
+		m, err := acme.New(c)
+		assert.Nil(t, err)
+		assert.Nil(t, m.OnInit())
+		m.OnDestroy()
+	})
+
+	t.Run("redis without client", func(t *testing.T) {
+		c := config()
+		c.Cache = "redis"
+		m, err := acme.New(c)
+		assert.Nil(t, err)
+		assert.Error(t, m.OnInit(), "acme: redis cache requires a redis.Client bean")
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		c := config()
+		c.Cache = "redis"
+		c.HTTPEnabled = false
+		m, err := acme.New(c)
+		assert.Nil(t, err)
+		m.Redis = &mapClient{m: make(map[string]string)}
+		assert.Nil(t, m.OnInit())
+		m.OnDestroy()
+	})
+}
+
+func TestManager_TLSConfig(t *testing.T) {
+
+	m, err := acme.New(config())
+	assert.Nil(t, err)
+	assert.True(t, contains(m.TLSConfig().NextProtos, acme.ALPNProto))
+
+	_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.com"})
+	assert.Error(t, err, "not configured in HostWhitelist")
+
+	c := config()
+	c.ALPNEnabled = false
+	m, err = acme.New(c)
+	assert.Nil(t, err)
+	assert.False(t, contains(m.TLSConfig().NextProtos, acme.ALPNProto))
+
+	_, err = m.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      "example.com",
+		SupportedProtos: []string{acme.ALPNProto},
+	})
+	assert.Error(t, err, "acme: tls-alpn challenge is disabled")
+
+	var cfg web.ContainerConfig
+	m.Configure(&cfg)
+	assert.True(t, cfg.EnableSSL)
+	assert.False(t, contains(cfg.TLSConfig.NextProtos, acme.ALPNProto))
+}
+
+func TestManager_HTTPHandler(t *testing.T) {
+	m, err := acme.New(config())
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/index?a=1", nil)
+	w := httptest.NewRecorder()
+	m.HTTPHandler(nil).ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusFound)
+	assert.Equal(t, w.Header().Get("Location"), "https://example.com/index?a=1")
+
+	r = httptest.NewRequest(http.MethodGet, "http://other.com/.well-known/acme-challenge/token", nil)
+	w = httptest.NewRecorder()
+	m.HTTPHandler(nil).ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
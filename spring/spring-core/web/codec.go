@@ -0,0 +1,489 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Codec JSON 编解码器，可以通过注册 Codec 类型的 bean 替换为 sonic 、
+// jsoniter 等性能更好的实现。
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// stdCodec 基于 encoding/json 的编解码器。
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var jsonCodec Codec = stdCodec{}
+
+// JSONCodec 返回当前使用的 JSON 编解码器。
+func JSONCodec() Codec {
+	return jsonCodec
+}
+
+// SetJSONCodec 设置 JSON 编解码器，c 为 nil 时恢复为 encoding/json 。
+func SetJSONCodec(c Codec) {
+	if c == nil {
+		c = stdCodec{}
+	}
+	jsonCodec = c
+}
+
+// MarshalJSON 使用当前的 JSON 编解码器进行编码。
+func MarshalJSON(v interface{}) ([]byte, error) {
+	return jsonCodec.Marshal(v)
+}
+
+// MarshalJSONIndent 使用当前的 JSON 编解码器进行编码并进行缩进。
+func MarshalJSONIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	b, err := jsonCodec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, b, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 使用当前的 JSON 编解码器进行解码。
+func UnmarshalJSON(data []byte, v interface{}) error {
+	return jsonCodec.Unmarshal(data, v)
+}
+
+const (
+	NamingDefault = ""           // 使用字段名
+	NamingSnake   = "snake_case" // user_name
+	NamingCamel   = "camelCase"  // userName
+	NamingKebab   = "kebab-case" // user-name
+
+	TimeFormatUnix      = "unix"       // 秒级时间戳
+	TimeFormatUnixMilli = "unix-milli" // 毫秒级时间戳
+)
+
+// JSONConfig JSON 编解码配置，通常绑定到 web.json 前缀的属性上。
+type JSONConfig struct {
+	Naming       string `value:"${naming:=}"`             // 没有 json 标签的字段的命名方式
+	OmitDefaults bool   `value:"${omit-defaults:=false}"` // 是否忽略所有零值字段
+	TimeFormat   string `value:"${time-format:=}"`        // time.Time 的格式，支持 unix 和 unix-milli
+	EscapeHTML   bool   `value:"${escape-html:=true}"`    // 是否转义 HTML 字符
+}
+
+// NewJSONCodec 根据配置创建 JSON 编解码器，使用默认配置时直接返回 encoding/json
+// 的实现，否则返回按照配置进行编码的实现。
+func NewJSONCodec(config JSONConfig) (Codec, error) {
+	switch config.Naming {
+	case NamingDefault, NamingSnake, NamingCamel, NamingKebab:
+	default:
+		return nil, fmt.Errorf("unsupported json naming %q", config.Naming)
+	}
+	if config == (JSONConfig{EscapeHTML: true}) {
+		return stdCodec{}, nil
+	}
+	return &configCodec{config: &config}, nil
+}
+
+// jsonField 结构体字段的编码信息。
+type jsonField struct {
+	index     []int
+	name      string
+	omitEmpty bool
+}
+
+// configCodec 按照 JSONConfig 进行编解码，编码时自行遍历对象，解码时先将
+// 数据转换为 encoding/json 能够识别的格式再进行解码。
+type configCodec struct {
+	config *JSONConfig
+	fields sync.Map // reflect.Type -> []jsonField
+}
+
+func (c *configCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.encode(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (c *configCodec) encode(buf *bytes.Buffer, v reflect.Value) error {
+
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if v.Type() == timeType && c.config.TimeFormat != "" {
+		return c.leaf(buf, c.formatTime(v.Interface().(time.Time)))
+	}
+
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+		if v.Type().Implements(marshalerType) || v.Type().Implements(textMarshalerType) {
+			return c.leaf(buf, v.Interface())
+		}
+		if v.CanAddr() && (reflect.PtrTo(v.Type()).Implements(marshalerType) ||
+			reflect.PtrTo(v.Type()).Implements(textMarshalerType)) {
+			return c.leaf(buf, v.Addr().Interface())
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().Implements(marshalerType) {
+			return c.leaf(buf, v.Interface())
+		}
+		return c.encode(buf, v.Elem())
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		for _, f := range c.structFields(v.Type()) {
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil { // 嵌入的结构体指针为空
+				continue
+			}
+			if (f.omitEmpty || c.config.OmitDefaults) && isEmptyValue(fv) {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			if err = c.leaf(buf, f.name); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err = c.encode(buf, fv); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, k)
+			values[k] = iter.Value()
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := c.leaf(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := c.encode(buf, values[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				buf.WriteString("null")
+				return nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return c.leaf(buf, v.Interface()) // []byte 编码为 base64 字符串
+			}
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := c.encode(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	return c.leaf(buf, v.Interface())
+}
+
+// leaf 使用 encoding/json 编码不需要特殊处理的值。
+func (c *configCodec) leaf(buf *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(c.config.EscapeHTML)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // 去掉 Encode 添加的换行符
+	return nil
+}
+
+func (c *configCodec) formatTime(t time.Time) interface{} {
+	switch c.config.TimeFormat {
+	case TimeFormatUnix:
+		return t.Unix()
+	case TimeFormatUnixMilli:
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+	return t.Format(c.config.TimeFormat)
+}
+
+func (c *configCodec) parseTime(v interface{}) (time.Time, error) {
+	switch c.config.TimeFormat {
+	case TimeFormatUnix, TimeFormatUnixMilli:
+		n, ok := v.(json.Number)
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid timestamp %v", v)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if c.config.TimeFormat == TimeFormatUnix {
+			return time.Unix(i, 0), nil
+		}
+		return time.Unix(0, i*int64(time.Millisecond)), nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid time %v", v)
+	}
+	return time.ParseInLocation(c.config.TimeFormat, s, time.Local)
+}
+
+// structFields 返回结构体的可编码字段，匿名嵌入的结构体字段会被展开。
+func (c *configCodec) structFields(t reflect.Type) []jsonField {
+	if v, ok := c.fields.Load(t); ok {
+		return v.([]jsonField)
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for _, sub := range c.structFields(ft) {
+				sub.index = append([]int{i}, sub.index...)
+				fields = append(fields, sub)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = toNaming(f.Name, c.config.Naming)
+		}
+		fields = append(fields, jsonField{
+			index:     []int{i},
+			name:      name,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	c.fields.Store(t, fields)
+	return fields
+}
+
+func (c *configCodec) Unmarshal(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return json.Unmarshal(data, v) // 由 encoding/json 返回错误
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var raw interface{}
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+	raw, err := c.normalize(raw, t.Elem())
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(raw); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// normalize 将字段名和时间格式转换为 encoding/json 能够识别的格式。
+func (c *configCodec) normalize(raw interface{}, t reflect.Type) (interface{}, error) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType && c.config.TimeFormat != "" && raw != nil {
+		tm, err := c.parseTime(raw)
+		if err != nil {
+			return nil, err
+		}
+		return tm.Format(time.RFC3339Nano), nil
+	}
+
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
+		return raw, nil
+	}
+
+	switch r := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			m := make(map[string]interface{}, len(r))
+			for _, f := range c.structFields(t) {
+				val, ok := r[f.name]
+				if !ok {
+					continue
+				}
+				sf := t.FieldByIndex(f.index)
+				val, err := c.normalize(val, sf.Type)
+				if err != nil {
+					return nil, err
+				}
+				// 有 json 标签时使用标签名，否则使用字段名
+				key, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+				if key == "" {
+					key = sf.Name
+				}
+				m[key] = val
+			}
+			return m, nil
+		case reflect.Map:
+			for k, val := range r {
+				val, err := c.normalize(val, t.Elem())
+				if err != nil {
+					return nil, err
+				}
+				r[k] = val
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, val := range r {
+				val, err := c.normalize(val, t.Elem())
+				if err != nil {
+					return nil, err
+				}
+				r[i] = val
+			}
+		}
+	}
+	return raw, nil
+}
+
+// toNaming 按照命名方式转换字段名。
+func toNaming(name, naming string) string {
+	switch naming {
+	case NamingSnake:
+		return joinWords(name, '_')
+	case NamingKebab:
+		return joinWords(name, '-')
+	case NamingCamel:
+		r := []rune(name)
+		n := 0 // 开头连续大写字母的个数
+		for n < len(r) && unicode.IsUpper(r[n]) {
+			n++
+		}
+		if n > 1 && n < len(r) {
+			n-- // URLPath -> urlPath
+		}
+		for i := 0; i < n; i++ {
+			r[i] = unicode.ToLower(r[i])
+		}
+		return string(r)
+	}
+	return name
+}
+
+// joinWords 将驼峰格式的名称拆分为小写单词，使用 sep 连接，UserID -> user_id 。
+func joinWords(name string, sep rune) string {
+	r := []rune(name)
+	var sb strings.Builder
+	for i, c := range r {
+		if unicode.IsUpper(c) && i > 0 {
+			prev := r[i-1]
+			next := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				sb.WriteRune(sep)
+			}
+		}
+		sb.WriteRune(unicode.ToLower(c))
+	}
+	return sb.String()
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type jsonAudit struct {
+	CreatedAt time.Time
+}
+
+type jsonUser struct {
+	jsonAudit
+	UserID   int64
+	URLPath  string
+	Nickname string `json:"nick"`
+	Remark   string `json:",omitempty"`
+	Secret   string `json:"-"`
+	Tags     []string
+	Extra    map[string]int
+	Manager  *jsonUser
+	Raw      []byte
+}
+
+func TestJSONCodec(t *testing.T) {
+
+	created := time.Date(2021, 6, 1, 8, 30, 0, 0, time.Local)
+
+	t.Run("std", func(t *testing.T) {
+		c, err := web.NewJSONCodec(web.JSONConfig{EscapeHTML: true})
+		assert.Nil(t, err)
+		b, err := c.Marshal(map[string]int{"a": 1})
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), `{"a":1}`)
+	})
+
+	t.Run("unsupported naming", func(t *testing.T) {
+		_, err := web.NewJSONCodec(web.JSONConfig{Naming: "PascalCase"})
+		assert.Error(t, err, "unsupported json naming \"PascalCase\"")
+	})
+
+	t.Run("snake_case", func(t *testing.T) {
+		c, err := web.NewJSONCodec(web.JSONConfig{
+			Naming:     web.NamingSnake,
+			TimeFormat: "2006-01-02 15:04:05",
+		})
+		assert.Nil(t, err)
+		u := jsonUser{
+			jsonAudit: jsonAudit{CreatedAt: created},
+			UserID:    1,
+			URLPath:   "/a?b=<c>",
+			Nickname:  "jim",
+			Secret:    "x",
+			Extra:     map[string]int{"b": 2, "a": 1},
+			Manager:   &jsonUser{UserID: 2},
+			Raw:       []byte("hi"),
+		}
+		b, err := c.Marshal(u)
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), `{"created_at":"2021-06-01 08:30:00","user_id":1,"url_path":"/a?b=<c>","nick":"jim","tags":null,"extra":{"a":1,"b":2},"manager":{"created_at":"0001-01-01 00:00:00","user_id":2,"url_path":"","nick":"","tags":null,"extra":null,"manager":null,"raw":null},"raw":"aGk="}`)
+
+		var r jsonUser
+		err = c.Unmarshal(b, &r)
+		assert.Nil(t, err)
+		assert.Equal(t, r.CreatedAt.Equal(created), true)
+		assert.Equal(t, r.UserID, int64(1))
+		assert.Equal(t, r.URLPath, "/a?b=<c>")
+		assert.Equal(t, r.Nickname, "jim")
+		assert.Equal(t, r.Secret, "")
+		assert.Equal(t, r.Extra, map[string]int{"a": 1, "b": 2})
+		assert.Equal(t, r.Manager.UserID, int64(2))
+		assert.Equal(t, string(r.Raw), "hi")
+	})
+
+	t.Run("camelCase omit defaults", func(t *testing.T) {
+		c, err := web.NewJSONCodec(web.JSONConfig{
+			Naming:       web.NamingCamel,
+			OmitDefaults: true,
+			TimeFormat:   web.TimeFormatUnix,
+			EscapeHTML:   true,
+		})
+		assert.Nil(t, err)
+		b, err := c.Marshal(&jsonUser{
+			jsonAudit: jsonAudit{CreatedAt: created},
+			URLPath:   "<a>",
+			Tags:      []string{"x"},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), `{"createdAt":`+strconv.FormatInt(created.Unix(), 10)+
+			`,"urlPath":"\u003ca\u003e","tags":["x"]}`)
+	})
+
+	t.Run("kebab-case", func(t *testing.T) {
+		c, err := web.NewJSONCodec(web.JSONConfig{Naming: web.NamingKebab, EscapeHTML: true})
+		assert.Nil(t, err)
+		var r jsonUser
+		err = c.Unmarshal([]byte(`{"user-id":3,"url-path":"/x","tags":["a"]}`), &r)
+		assert.Nil(t, err)
+		assert.Equal(t, r.UserID, int64(3))
+		assert.Equal(t, r.URLPath, "/x")
+		assert.Equal(t, r.Tags, []string{"a"})
+	})
+
+	t.Run("global", func(t *testing.T) {
+		c, _ := web.NewJSONCodec(web.JSONConfig{Naming: web.NamingSnake, EscapeHTML: true})
+		web.SetJSONCodec(c)
+		defer web.SetJSONCodec(nil)
+		b, err := web.MarshalJSONIndent(struct{ UserID int }{1}, "", " ")
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), "{\n \"user_id\": 1\n}")
+	})
+}
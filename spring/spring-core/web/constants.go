@@ -19,6 +19,10 @@ package web
 const (
 	HeaderContentDisposition = "Content-Disposition"
 	HeaderContentType        = "Content-Type"
+	HeaderETag               = "ETag"
+	HeaderIfModifiedSince    = "If-Modified-Since"
+	HeaderIfNoneMatch        = "If-None-Match"
+	HeaderLastModified       = "Last-Modified"
 	HeaderXForwardedProto    = "X-Forwarded-Proto"
 	HeaderXForwardedProtocol = "X-Forwarded-Protocol"
 	HeaderXForwardedSsl      = "X-Forwarded-Ssl"
@@ -19,6 +19,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -50,8 +51,21 @@ type ContainerConfig struct {
 	CertFile  string // SSL 秘钥
 	BasePath  string // 根路径
 
+	// TLSConfig 自定义的 TLS 配置，例如通过 ACME 自动管理的证书，设置后
+	// 不再使用 KeyFile 和 CertFile 。
+	TLSConfig *tls.Config
+
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+
+	Router RouterConfig // 路由匹配策略
+
+	// Listeners 额外的监听器，例如其他 TCP 端口、unix socket 以及 systemd
+	// socket activation 传入的监听器。
+	Listeners []ListenerConfig `value:"${listeners}"`
+
+	// ReusePort 大于 0 时通过 SO_REUSEPORT 在主监听地址上创建多个监听器。
+	ReusePort int `value:"${reuse-port:=0}"`
 }
 
 // Container Web 容器
@@ -176,6 +190,16 @@ func (c *AbstractContainer) Start() error {
 	return nil
 }
 
+// PolicyHandler 返回按照路由匹配策略规范化请求路径之后再交给 next 处理的
+// http.Handler ，Web 服务器的实现应该在注册完所有路由之后使用它包装底层的服务器。
+func (c *AbstractContainer) PolicyHandler(next http.Handler) (http.Handler, error) {
+	p, err := NewPathPolicy(c.config.Router, c.Mappers())
+	if err != nil {
+		return nil, err
+	}
+	return p.Handler(next), nil
+}
+
 // Stop 停止 Web 容器
 func (c *AbstractContainer) Stop(ctx context.Context) error {
 	panic(util.UnimplementedMethod)
@@ -247,6 +271,31 @@ func WrapH(h http.Handler) Handler {
 	return &httpHandler{h}
 }
 
+// namedHandler 设置了名称的 Web 处理函数
+type namedHandler struct {
+	Handler
+	name string
+}
+
+func (h *namedHandler) handlerName() string {
+	return h.name
+}
+
+// HandlerName 返回 Web 处理函数的名称，优先使用 Mapper.Name 设置的名称，否则使
+// 用函数名称，h 为 nil 时返回空字符串。
+func HandlerName(h Handler) string {
+	if h == nil {
+		return ""
+	}
+	if n, ok := h.(interface{ handlerName() string }); ok {
+		if name := n.handlerName(); name != "" {
+			return name
+		}
+	}
+	_, _, fnName := h.FileLine()
+	return fnName
+}
+
 /////////////////// Web Filters //////////////////////
 
 // defaultLoggerFilter 全局的日志过滤器，Container 如果没有设置日志过滤器则会使用全局的日志过滤器
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewETag 根据响应体生成 ETag ，weak 为 true 时生成弱校验的 ETag 。
+func NewETag(body []byte, weak bool) string {
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// NotModified 根据条件请求头判断请求的资源是否没有修改，etag 为空或者 modified
+// 为零值时不参与判断。If-None-Match 存在时忽略 If-Modified-Since ，并且只有 GET
+// 和 HEAD 请求才可能返回 true 。
+func NotModified(r *http.Request, etag string, modified time.Time) bool {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if s := r.Header.Get(HeaderIfNoneMatch); s != "" {
+		return etag != "" && matchETag(s, etag)
+	}
+
+	if s := r.Header.Get(HeaderIfModifiedSince); s != "" && !modified.IsZero() {
+		t, err := http.ParseTime(s)
+		if err != nil {
+			return false
+		}
+		return !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// matchETag 使用弱比较判断 If-None-Match 的值是否包含 etag 。
+func matchETag(header string, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, s := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(s), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckNotModified 设置响应的 ETag 和 Last-Modified 头部，请求的资源没有修改时
+// 发送 304 响应并返回 true ，此时处理函数不应该再写入响应体。适用于能够低成本
+// 获得资源版本的处理函数，例如使用数据库中的版本号或者更新时间。
+func CheckNotModified(ctx Context, etag string, modified time.Time) bool {
+	if etag != "" {
+		ctx.Header(HeaderETag, etag)
+	}
+	if !modified.IsZero() {
+		ctx.Header(HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+	if NotModified(ctx.Request(), etag, modified) {
+		ctx.NoContent(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagHandler 根据响应体自动生成 ETag 的 Web 处理函数。
+type etagHandler struct {
+	Handler
+	weak bool
+}
+
+func (h *etagHandler) Invoke(ctx Context) {
+	switch ctx.Request().Method {
+	case http.MethodGet, http.MethodHead:
+		h.Handler.Invoke(&etagContext{webContext: ctx, weak: h.weak})
+	default:
+		h.Handler.Invoke(ctx)
+	}
+}
+
+// webContext 用于嵌入 Context ，避免字段名与 Context 方法冲突。
+type webContext = Context
+
+// etagContext 拦截处理函数的响应，先计算响应体的 ETag 再决定发送响应体还是发送
+// 304 响应。通过 ResponseWriter 直接写入的响应以及文件响应不会生成 ETag 。
+type etagContext struct {
+	webContext
+	weak bool
+}
+
+// send 成功的响应生成 ETag ，请求的资源没有修改时发送 304 响应，否则调用 fn
+// 发送响应体。
+func (c *etagContext) send(body []byte, fn func()) {
+	if s := c.ResponseWriter().Status(); s != 0 && s != http.StatusOK {
+		fn()
+		return
+	}
+	var modified time.Time
+	if s := c.ResponseWriter().Header().Get(HeaderLastModified); s != "" {
+		modified, _ = http.ParseTime(s)
+	}
+	if !CheckNotModified(c.webContext, NewETag(body, c.weak), modified) {
+		fn()
+	}
+}
+
+func (c *etagContext) String(format string, values ...interface{}) {
+	b := []byte(fmt.Sprintf(format, values...))
+	c.send(b, func() { c.webContext.Blob(MIMETextPlainCharsetUTF8, b) })
+}
+
+func (c *etagContext) HTML(html string) {
+	c.HTMLBlob([]byte(html))
+}
+
+func (c *etagContext) HTMLBlob(b []byte) {
+	c.send(b, func() { c.webContext.HTMLBlob(b) })
+}
+
+func (c *etagContext) JSON(i interface{}) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		panic(err)
+	}
+	c.JSONBlob(b)
+}
+
+func (c *etagContext) JSONBlob(b []byte) {
+	c.send(b, func() { c.webContext.JSONBlob(b) })
+}
+
+func (c *etagContext) XML(i interface{}) {
+	b, err := xml.Marshal(i)
+	if err != nil {
+		panic(err)
+	}
+	c.XMLBlob(b)
+}
+
+func (c *etagContext) XMLBlob(b []byte) {
+	c.send(b, func() { c.webContext.XMLBlob(b) })
+}
+
+func (c *etagContext) Blob(contentType string, b []byte) {
+	c.send(b, func() { c.webContext.Blob(contentType, b) })
+}
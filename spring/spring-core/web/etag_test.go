@@ -0,0 +1,116 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type webContext = web.Context
+
+// recorderContext 基于 httptest.ResponseRecorder 的最小 Context 实现。
+type recorderContext struct {
+	webContext
+	r *http.Request
+	w *httptest.ResponseRecorder
+}
+
+type recorderWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w recorderWriter) Status() int  { return w.Code }
+func (w recorderWriter) Size() int    { return w.ResponseRecorder.Body.Len() }
+func (w recorderWriter) Body() []byte { return w.ResponseRecorder.Body.Bytes() }
+
+func newRecorderContext(r *http.Request) *recorderContext {
+	return &recorderContext{r: r, w: httptest.NewRecorder()}
+}
+
+func (c *recorderContext) Request() *http.Request             { return c.r }
+func (c *recorderContext) ResponseWriter() web.ResponseWriter { return recorderWriter{c.w} }
+func (c *recorderContext) Header(key, value string)           { c.w.Header().Set(key, value) }
+func (c *recorderContext) NoContent(code int)                 { c.w.WriteHeader(code) }
+
+func (c *recorderContext) Status(code int) { c.w.WriteHeader(code) }
+
+func (c *recorderContext) String(format string, values ...interface{}) {
+	_, _ = fmt.Fprintf(c.w, format, values...)
+}
+
+func (c *recorderContext) JSONBlob(b []byte) {
+	c.w.Header().Set(web.HeaderContentType, web.MIMEApplicationJSONCharsetUTF8)
+	_, _ = c.w.Write(b)
+}
+
+func TestNotModified(t *testing.T) {
+
+	etag := web.NewETag([]byte("hello"), false)
+	assert.Equal(t, web.NewETag([]byte("hello"), true), "W/"+etag)
+
+	modified := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, web.NotModified(r, etag, modified))
+
+	r.Header.Set(web.HeaderIfNoneMatch, `"abc", W/`+etag)
+	assert.True(t, web.NotModified(r, etag, modified))
+
+	r.Header.Set(web.HeaderIfNoneMatch, "*")
+	assert.True(t, web.NotModified(r, etag, modified))
+
+	r.Header.Set(web.HeaderIfNoneMatch, `"abc"`)
+	r.Header.Set(web.HeaderIfModifiedSince, modified.Format(http.TimeFormat))
+	assert.False(t, web.NotModified(r, etag, modified))
+
+	r.Header.Del(web.HeaderIfNoneMatch)
+	assert.True(t, web.NotModified(r, etag, modified))
+	assert.False(t, web.NotModified(r, etag, modified.Add(time.Minute)))
+
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(web.HeaderIfNoneMatch, "*")
+	assert.False(t, web.NotModified(r, etag, modified))
+}
+
+func TestMapper_ETag(t *testing.T) {
+
+	m := web.NewMapper(web.MethodGet, "/users", web.FUNC(func(ctx web.Context) {
+		ctx.JSON(map[string]string{"name": "jim"})
+	}))
+	m.Name("user-list").ETag(false)
+	assert.Equal(t, web.HandlerName(m.Handler()), "user-list")
+
+	ctx := newRecorderContext(httptest.NewRequest(http.MethodGet, "/users", nil))
+	m.Handler().Invoke(ctx)
+	etag := ctx.w.Header().Get(web.HeaderETag)
+	assert.Equal(t, etag, web.NewETag([]byte(`{"name":"jim"}`), false))
+	assert.Equal(t, ctx.w.Body.String(), `{"name":"jim"}`)
+
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set(web.HeaderIfNoneMatch, etag)
+	ctx = newRecorderContext(r)
+	m.Handler().Invoke(ctx)
+	assert.Equal(t, ctx.w.Code, http.StatusNotModified)
+	assert.Equal(t, ctx.w.Body.Len(), 0)
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
 	"github.com/go-spring/spring-stl/util"
 )
 
@@ -54,3 +55,13 @@ func TestWrapH(t *testing.T) {
 
 	fmt.Println(web.WrapH(&Counter{}).FileLine())
 }
+
+func listUsers(ctx web.Context) {}
+
+func TestHandlerName(t *testing.T) {
+	assert.Equal(t, web.HandlerName(nil), "")
+	m := web.NewMapper(web.MethodGet, "/users", web.FUNC(listUsers))
+	assert.Equal(t, web.HandlerName(m.Handler()), "listUsers")
+	m.Name("users.list").Name("user-list")
+	assert.Equal(t, web.HandlerName(m.Handler()), "user-list")
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/log"
+)
+
+// ListenerConfig 额外监听器的配置，额外监听器和主监听器共享路由和过滤器。
+type ListenerConfig struct {
+	Network string `value:"${network:=tcp}"` // 网络类型，可选 tcp、unix 和 systemd
+	Address string `value:"${address}"`      // tcp 为 host:port，unix 为 socket 文件路径，systemd 为监听器的名称或序号
+	Mode    string `value:"${mode:=}"`       // unix socket 文件的权限，例如 0660
+	SSL     bool   `value:"${ssl:=false}"`   // 是否使用容器的 SSL 配置
+
+	// ReusePort 大于 0 时通过 SO_REUSEPORT 在同一地址上创建多个监听器，由内核
+	// 在它们之间分配新连接，适用于连接建立频繁的场景，只支持 tcp 类型。
+	ReusePort int `value:"${reuse-port:=0}"`
+}
+
+// Listen 根据配置创建监听器。systemd 类型使用 socket activation 传入的监听器，
+// 地址为空时使用第一个。
+func Listen(config ListenerConfig) (net.Listener, error) {
+	switch config.Network {
+	case "tcp", "tcp4", "tcp6":
+		return net.Listen(config.Network, config.Address)
+	case "unix":
+		return listenUnix(config)
+	case "systemd":
+		return listenSystemd(config.Address)
+	default:
+		return nil, fmt.Errorf("unsupported listener network %q", config.Network)
+	}
+}
+
+// listenUnix 创建 unix socket 监听器，会删除上次运行残留的 socket 文件。
+func listenUnix(config ListenerConfig) (net.Listener, error) {
+
+	if config.Address == "" {
+		return nil, errors.New("unix listener requires an address")
+	}
+
+	var mode os.FileMode
+	if config.Mode != "" {
+		m, err := strconv.ParseUint(config.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unix socket mode %q", config.Mode)
+		}
+		mode = os.FileMode(m)
+	}
+
+	if fi, err := os.Stat(config.Address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, err := net.DialTimeout("unix", config.Address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is in use", config.Address)
+		}
+		if err = os.Remove(config.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err = os.Chmod(config.Address, mode); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// listenFdsStart systemd 传入的第一个文件描述符。
+const listenFdsStart = 3
+
+var systemd struct {
+	once      sync.Once
+	err       error
+	names     []string
+	listeners []net.Listener
+	used      []bool
+	mutex     sync.Mutex
+}
+
+// loadSystemd 读取 systemd socket activation 传入的监听器，只在第一次调用时
+// 读取，读取之后删除相关的环境变量，避免被子进程继承。
+func loadSystemd() error {
+	systemd.once.Do(func() {
+
+		pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		names := os.Getenv("LISTEN_FDNAMES")
+
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+
+		if pid != os.Getpid() || n <= 0 {
+			systemd.err = errors.New("systemd socket activation is not available")
+			return
+		}
+
+		var fdNames []string
+		if names != "" {
+			fdNames = strings.Split(names, ":")
+		}
+
+		for i := 0; i < n; i++ {
+			name := strconv.Itoa(i)
+			if i < len(fdNames) && fdNames[i] != "" {
+				name = fdNames[i]
+			}
+			f := os.NewFile(uintptr(listenFdsStart+i), name)
+			l, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				systemd.err = fmt.Errorf("systemd listener %s: %w", name, err)
+				return
+			}
+			systemd.names = append(systemd.names, name)
+			systemd.listeners = append(systemd.listeners, l)
+		}
+		systemd.used = make([]bool, n)
+	})
+	return systemd.err
+}
+
+// listenSystemd 返回名称或者序号为 name 的 systemd 监听器，每个监听器只能使用
+// 一次。
+func listenSystemd(name string) (net.Listener, error) {
+
+	if err := loadSystemd(); err != nil {
+		return nil, err
+	}
+
+	systemd.mutex.Lock()
+	defer systemd.mutex.Unlock()
+
+	for i, s := range systemd.names {
+		if name != "" && name != s && name != strconv.Itoa(i) {
+			continue
+		}
+		if systemd.used[i] {
+			return nil, fmt.Errorf("systemd listener %s is already in use", s)
+		}
+		systemd.used[i] = true
+		return systemd.listeners[i], nil
+	}
+	return nil, fmt.Errorf("systemd listener %q not found", name)
+}
+
+// OnAccept 通过 ServeListeners 和 ServeReusePort 启动的监听器接受新连接时调用，
+// listener 为监听器的名称，开启 SO_REUSEPORT 时名称带有序号，可以用来观察连接
+// 在多个监听器之间的分布。
+var OnAccept = func(listener string) {}
+
+// acceptListener 接受新连接时调用 OnAccept 的监听器。
+type acceptListener struct {
+	net.Listener
+	name string
+}
+
+func (l *acceptListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		OnAccept(l.name)
+	}
+	return conn, err
+}
+
+// listenReusePort 通过 SO_REUSEPORT 在同一地址上创建 n 个监听器，由内核在它们
+// 之间分配新连接。地址使用随机端口时，其余的监听器绑定到第一个监听器的端口。
+func listenReusePort(network, address string, n int) ([]net.Listener, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	var listeners []net.Listener
+	for i := 0; i < n; i++ {
+		l, err := lc.Listen(context.Background(), network, address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		address = l.Addr().String()
+		name := fmt.Sprintf("%s://%s#%d", network, address, i)
+		listeners = append(listeners, &acceptListener{Listener: l, name: name})
+	}
+	return listeners, nil
+}
+
+// listen 根据配置创建监听器，开启 SO_REUSEPORT 时返回多个监听器。
+func listen(config ListenerConfig) ([]net.Listener, error) {
+	if config.ReusePort > 0 {
+		switch config.Network {
+		case "tcp", "tcp4", "tcp6":
+			return listenReusePort(config.Network, config.Address, config.ReusePort)
+		default:
+			return nil, fmt.Errorf("reuse-port is not supported by %s listener", config.Network)
+		}
+	}
+	l, err := Listen(config)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s://%s", l.Addr().Network(), l.Addr())
+	return []net.Listener{&acceptListener{Listener: l, name: name}}, nil
+}
+
+// serve 在监听器 l 上启动 server 。
+func serve(server *http.Server, config ContainerConfig, ssl bool, l net.Listener) error {
+	log.Infof("⇨ http server started on %s://%s", l.Addr().Network(), l.Addr())
+	var err error
+	if ssl && config.TLSConfig != nil {
+		err = server.ServeTLS(l, "", "")
+	} else if ssl {
+		err = server.ServeTLS(l, config.CertFile, config.KeyFile)
+	} else {
+		err = server.Serve(l)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Errorf("http server on %s://%s error: %v", l.Addr().Network(), l.Addr(), err)
+	}
+	return err
+}
+
+// checkSSL 检查 SSL 监听器是否有可用的证书。
+func checkSSL(config ContainerConfig, c ListenerConfig) error {
+	if c.SSL && config.TLSConfig == nil && (config.CertFile == "" || config.KeyFile == "") {
+		return fmt.Errorf("ssl listener %s://%s requires a tls config or cert files", c.Network, c.Address)
+	}
+	return nil
+}
+
+// ServeListeners 在配置的额外监听器上启动 server ，关闭 server 时一并关闭这些
+// 监听器。任一监听器创建失败时关闭已经创建的监听器并返回错误。
+func ServeListeners(server *http.Server, config ContainerConfig) error {
+
+	for _, c := range config.Listeners {
+		if err := checkSSL(config, c); err != nil {
+			return err
+		}
+	}
+
+	var (
+		listeners []net.Listener
+		ssl       []bool
+	)
+	for _, c := range config.Listeners {
+		ls, err := listen(c)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+		for _, l := range ls {
+			listeners = append(listeners, l)
+			ssl = append(ssl, c.SSL)
+		}
+	}
+
+	for i, l := range listeners {
+		go func(ssl bool, l net.Listener) {
+			_ = serve(server, config, ssl, l)
+		}(ssl[i], l)
+	}
+	return nil
+}
+
+// ServeReusePort 通过 SO_REUSEPORT 在主监听地址上创建 config.ReusePort 个监听
+// 器，并在这些监听器上启动 server ，直到 server 关闭或者任一监听器出错时返回。
+func ServeReusePort(server *http.Server, config ContainerConfig) error {
+
+	c := ListenerConfig{
+		Network:   "tcp",
+		Address:   net.JoinHostPort(config.IP, strconv.Itoa(config.Port)),
+		SSL:       config.EnableSSL,
+		ReusePort: config.ReusePort,
+	}
+	if err := checkSSL(config, c); err != nil {
+		return err
+	}
+
+	listeners, err := listen(c)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- serve(server, config, c.SSL, l)
+		}(l)
+	}
+	return <-errs
+}
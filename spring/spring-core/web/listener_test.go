@@ -0,0 +1,232 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+}
+
+func TestListen(t *testing.T) {
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := web.Listen(web.ListenerConfig{Network: "tcp", Address: "127.0.0.1:0"})
+		assert.Nil(t, err)
+		defer l.Close()
+		assert.Equal(t, l.Addr().Network(), "tcp")
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "web.sock")
+		l, err := web.Listen(web.ListenerConfig{Network: "unix", Address: path, Mode: "0600"})
+		assert.Nil(t, err)
+		fi, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, fi.Mode().Perm(), os.FileMode(0600))
+
+		_, err = web.Listen(web.ListenerConfig{Network: "unix", Address: path})
+		assert.Error(t, err, "unix socket .* is in use")
+
+		// 模拟进程异常退出时残留的 socket 文件
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		l, err = web.Listen(web.ListenerConfig{Network: "unix", Address: path})
+		assert.Nil(t, err)
+		l.Close()
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := web.Listen(web.ListenerConfig{Network: "udp"})
+		assert.Error(t, err, "unsupported listener network \"udp\"")
+		_, err = web.Listen(web.ListenerConfig{Network: "unix"})
+		assert.Error(t, err, "unix listener requires an address")
+		_, err = web.Listen(web.ListenerConfig{Network: "unix", Address: "a.sock", Mode: "rw"})
+		assert.Error(t, err, "invalid unix socket mode \"rw\"")
+		_, err = web.Listen(web.ListenerConfig{Network: "systemd"})
+		assert.Error(t, err, "systemd socket activation is not available")
+	})
+}
+
+func TestServeListeners(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "web.sock")
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+
+	err := web.ServeListeners(server, web.ContainerConfig{
+		Listeners: []web.ListenerConfig{{Network: "unix", Address: path, SSL: true}},
+	})
+	assert.Error(t, err, "ssl listener unix://.* requires a tls config or cert files")
+
+	err = web.ServeListeners(server, web.ContainerConfig{
+		Listeners: []web.ListenerConfig{
+			{Network: "unix", Address: path},
+			{Network: "udp"},
+		},
+	})
+	assert.Error(t, err, "unsupported listener network \"udp\"")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	err = web.ServeListeners(server, web.ContainerConfig{
+		Listeners: []web.ListenerConfig{{Network: "unix", Address: path}},
+	})
+	assert.Nil(t, err)
+
+	resp, err := unixClient(path).Get("http://unix/")
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, string(b), "ok")
+
+	assert.Nil(t, server.Shutdown(context.Background()))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListenerConfig_Bind(t *testing.T) {
+	p := conf.New()
+	p.Set("web.server.port", 8080)
+	p.Set("web.server.listeners[0].address", ":9090")
+	p.Set("web.server.listeners[1].network", "unix")
+	p.Set("web.server.listeners[1].address", "/run/app.sock")
+	p.Set("web.server.listeners[1].mode", "0660")
+	var c web.ContainerConfig
+	assert.Nil(t, p.Bind(&c, conf.Key("web.server")))
+	assert.Equal(t, c.Listeners, []web.ListenerConfig{
+		{Network: "tcp", Address: ":9090"},
+		{Network: "unix", Address: "/run/app.sock", Mode: "0660"},
+	})
+}
+
+// freeAddr 返回一个未被占用的本地地址。
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestReusePort(t *testing.T) {
+
+	var (
+		mutex    sync.Mutex
+		accepted = make(map[string]int)
+	)
+	web.OnAccept = func(listener string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		accepted[listener]++
+	}
+	defer func() { web.OnAccept = func(string) {} }()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	// 不复用连接，每次请求都由内核重新分配监听器
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(url string) {
+		resp, err := client.Get(url)
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, string(b), "ok")
+	}
+
+	count := func(addr string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		total := 0
+		for name, n := range accepted {
+			assert.Matches(t, name, "^tcp://"+regexp.QuoteMeta(addr)+"#[0-3]$")
+			total += n
+		}
+		return total
+	}
+
+	t.Run("listeners", func(t *testing.T) {
+		server := &http.Server{Handler: handler}
+		defer server.Shutdown(context.Background())
+
+		err := web.ServeListeners(server, web.ContainerConfig{
+			Listeners: []web.ListenerConfig{{Network: "unix", Address: "a.sock", ReusePort: 2}},
+		})
+		assert.Error(t, err, "reuse-port is not supported by unix listener")
+
+		addr := freeAddr(t)
+		err = web.ServeListeners(server, web.ContainerConfig{
+			Listeners: []web.ListenerConfig{{Network: "tcp", Address: addr, ReusePort: 4}},
+		})
+		assert.Nil(t, err)
+		for i := 0; i < 40; i++ {
+			get("http://" + addr + "/")
+		}
+		assert.Equal(t, count(addr), 40)
+
+		// 内核根据连接的四元组在监听器之间分配连接
+		mutex.Lock()
+		assert.True(t, len(accepted) > 1)
+		mutex.Unlock()
+	})
+
+	mutex.Lock()
+	accepted = make(map[string]int)
+	mutex.Unlock()
+
+	t.Run("main", func(t *testing.T) {
+		host, port, _ := net.SplitHostPort(freeAddr(t))
+		p, _ := strconv.Atoi(port)
+		config := web.ContainerConfig{IP: host, Port: p, ReusePort: 4}
+
+		server := &http.Server{Handler: handler}
+		errs := make(chan error, 1)
+		go func() { errs <- web.ServeReusePort(server, config) }()
+		time.Sleep(10 * time.Millisecond)
+
+		addr := net.JoinHostPort(host, port)
+		for i := 0; i < 40; i++ {
+			get("http://" + addr + "/")
+		}
+		assert.Equal(t, count(addr), 40)
+
+		assert.Nil(t, server.Shutdown(context.Background()))
+		assert.Equal(t, <-errs, http.ErrServerClosed)
+	})
+}
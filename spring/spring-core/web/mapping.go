@@ -84,6 +84,32 @@ func (m *Mapper) Handler() Handler {
 	return m.handler
 }
 
+// Name 设置处理函数的名称，用于指标和链路追踪的标签，参见 HandlerName 。
+func (m *Mapper) Name(name string) *Mapper {
+	if n, ok := m.handler.(*namedHandler); ok {
+		m.handler = n.Handler
+	}
+	m.handler = &namedHandler{Handler: m.handler, name: name}
+	return m
+}
+
+// ETag 为 GET 和 HEAD 请求的成功响应自动生成 ETag ，并且根据 If-None-Match 和
+// If-Modified-Since 请求头返回 304 响应，weak 为 true 时生成弱校验的 ETag 。只
+// 有通过 Context 的 JSON 、XML 、String 、HTML 和 Blob 系列方法发送的响应才会
+// 生成 ETag ，其他处理函数可以使用 CheckNotModified 函数。
+func (m *Mapper) ETag(weak bool) *Mapper {
+	h := &m.handler
+	if n, ok := m.handler.(*namedHandler); ok {
+		h = &n.Handler
+	}
+	if e, ok := (*h).(*etagHandler); ok {
+		e.weak = weak
+		return m
+	}
+	*h = &etagHandler{Handler: *h, weak: weak}
+	return m
+}
+
 // Operation 设置与 Mapper 绑定的 Operation 对象
 func (m *Mapper) Operation(op Operation) {
 	m.swagger = op
@@ -147,6 +173,7 @@ type Router interface {
 // router 路由注册接口的默认实现
 type router struct {
 	mappers []*Mapper
+	add     func(m *Mapper) // 添加 Mapper 时的回调，例如注册到版本路由上
 }
 
 // NewRouter router 的构造函数。
@@ -162,6 +189,9 @@ func (r *router) Mappers() []*Mapper {
 // AddMapper 添加一个 Mapper
 func (r *router) AddMapper(m *Mapper) {
 	r.mappers = append(r.mappers, m)
+	if r.add != nil {
+		r.add(m)
+	}
 }
 
 func (r *router) request(method uint32, path string, h Handler) *Mapper {
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 路径末尾斜杠的处理策略。
+const (
+	TrailingSlashStrict   = "strict"   // 严格匹配，/users/ 与 /users 是不同的路径
+	TrailingSlashStrip    = "strip"    // 忽略末尾的斜杠，直接转发到匹配的路由
+	TrailingSlashRedirect = "redirect" // 重定向到匹配的路由
+)
+
+// RouterConfig 路由匹配策略，通常绑定到 web.server.router 前缀的属性上。不同的
+// 前置代理对路径的规范化方式不同，可以通过这些策略统一服务的行为。
+type RouterConfig struct {
+	TrailingSlash    string `value:"${trailing-slash:=strict}"`  // 末尾斜杠的处理策略
+	CaseInsensitive  bool   `value:"${case-insensitive:=false}"` // 是否忽略路径的大小写
+	MethodNotAllowed string `value:"${method-not-allowed:=405}"` // 路径存在但方法不匹配时返回 405 还是 404 ，为空时由底层服务器决定
+}
+
+// routePattern 路由的匹配模式。
+type routePattern struct {
+	segments []string
+	method   uint32
+}
+
+// PathPolicy 在路由之前按照 RouterConfig 规范化请求路径的策略，由 Web 服务器的
+// 实现使用，参见 AbstractContainer.PolicyHandler 。
+type PathPolicy struct {
+	config RouterConfig
+	routes []*routePattern
+}
+
+// NewPathPolicy 根据 mappers 创建路由匹配策略。
+func NewPathPolicy(config RouterConfig, mappers []*Mapper) (*PathPolicy, error) {
+
+	switch config.TrailingSlash {
+	case "":
+		config.TrailingSlash = TrailingSlashStrict
+	case TrailingSlashStrict, TrailingSlashStrip, TrailingSlashRedirect:
+	default:
+		return nil, fmt.Errorf("unknown trailing slash policy %q", config.TrailingSlash)
+	}
+
+	switch config.MethodNotAllowed {
+	case "", "404", "405":
+	default:
+		return nil, fmt.Errorf("method-not-allowed should be 404 or 405 but %q", config.MethodNotAllowed)
+	}
+
+	p := &PathPolicy{config: config}
+	for _, m := range mappers {
+		path, _ := ToPathStyle(m.Path(), EchoPathStyle)
+		p.routes = append(p.routes, &routePattern{
+			segments: strings.Split(strings.TrimPrefix(path, "/"), "/"),
+			method:   m.Method(),
+		})
+	}
+	return p, nil
+}
+
+// enabled 返回策略是否需要处理请求。
+func (p *PathPolicy) enabled() bool {
+	return p.config.TrailingSlash != TrailingSlashStrict || p.config.CaseInsensitive || p.config.MethodNotAllowed != ""
+}
+
+// Handler 返回在路由之前规范化请求路径的 http.Handler 。
+func (p *PathPolicy) Handler(next http.Handler) http.Handler {
+	if !p.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		path, methods, slash := p.resolve(r.URL.Path)
+		if methods == 0 { // 交给底层服务器处理
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed := GetMethod(methods); !contains(allowed, r.Method) && p.config.MethodNotAllowed != "" {
+			if p.config.MethodNotAllowed == "404" {
+				http.NotFound(w, r)
+				return
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if path != r.URL.Path {
+			if slash && p.config.TrailingSlash == TrailingSlashRedirect {
+				u := *r.URL
+				u.Path, u.RawPath = path, ""
+				code := http.StatusMovedPermanently
+				if r.Method != http.MethodGet && r.Method != http.MethodHead {
+					code = http.StatusPermanentRedirect
+				}
+				http.Redirect(w, r, u.String(), code)
+				return
+			}
+			r.URL.Path, r.URL.RawPath = path, ""
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resolve 返回 path 匹配的路由的规范路径以及这些路由支持的方法，slash 表示是否
+// 通过调整末尾的斜杠才匹配成功，没有匹配的路由时 methods 为 0 。
+func (p *PathPolicy) resolve(path string) (canonical string, methods uint32, slash bool) {
+
+	candidates := []string{path}
+	if p.config.TrailingSlash != TrailingSlashStrict && path != "/" {
+		if strings.HasSuffix(path, "/") {
+			candidates = append(candidates, strings.TrimSuffix(path, "/"))
+		} else {
+			candidates = append(candidates, path+"/")
+		}
+	}
+
+	folds := []bool{false}
+	if p.config.CaseInsensitive {
+		folds = append(folds, true)
+	}
+
+	for i, c := range candidates {
+		segments := strings.Split(strings.TrimPrefix(c, "/"), "/")
+		for _, fold := range folds {
+			for _, r := range p.routes {
+				if s, ok := r.match(segments, fold); ok {
+					if methods == 0 {
+						canonical = s
+					}
+					if s == canonical {
+						methods |= r.method
+					}
+				}
+			}
+			if methods != 0 {
+				return canonical, methods, i > 0
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// match 判断路径的分段是否与路由匹配，fold 为 true 时忽略大小写，匹配成功时返回
+// 使用路由中静态分段的规范路径。
+func (r *routePattern) match(segments []string, fold bool) (string, bool) {
+	var sb strings.Builder
+	for i, s := range r.segments {
+		if s == "*" {
+			sb.WriteString("/" + strings.Join(segments[i:], "/"))
+			return sb.String(), true
+		}
+		if i >= len(segments) {
+			return "", false
+		}
+		switch {
+		case strings.HasPrefix(s, ":"):
+			if segments[i] == "" {
+				return "", false
+			}
+			sb.WriteString("/" + segments[i])
+		case s == segments[i], fold && strings.EqualFold(s, segments[i]):
+			sb.WriteString("/" + s)
+		default:
+			return "", false
+		}
+	}
+	if len(segments) != len(r.segments) {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestPathPolicy(t *testing.T) {
+
+	r := web.NewRouter()
+	r.GetMapping("/users/:id", func(ctx web.Context) {})
+	r.PostMapping("/Orders/{id}/", func(ctx web.Context) {})
+	r.GetMapping("/files/*", func(ctx web.Context) {})
+
+	// next 返回最终交给底层服务器的路径
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	})
+
+	serve := func(config web.RouterConfig, method, target string) *httptest.ResponseRecorder {
+		p, err := web.NewPathPolicy(config, r.Mappers())
+		assert.Nil(t, err)
+		w := httptest.NewRecorder()
+		p.Handler(next).ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	_, err := web.NewPathPolicy(web.RouterConfig{TrailingSlash: "ignore"}, nil)
+	assert.Error(t, err, "unknown trailing slash policy \"ignore\"")
+
+	t.Run("strict", func(t *testing.T) {
+		config := web.RouterConfig{}
+		assert.Equal(t, serve(config, http.MethodGet, "/users/1/").Body.String(), "/users/1/")
+		assert.Equal(t, serve(config, http.MethodGet, "/USERS/1").Body.String(), "/USERS/1")
+		assert.Equal(t, serve(config, http.MethodPost, "/users/1").Body.String(), "/users/1")
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		config := web.RouterConfig{TrailingSlash: web.TrailingSlashStrip}
+		assert.Equal(t, serve(config, http.MethodGet, "/users/1/").Body.String(), "/users/1")
+		assert.Equal(t, serve(config, http.MethodPost, "/Orders/2").Body.String(), "/Orders/2/")
+		assert.Equal(t, serve(config, http.MethodGet, "/files/a/b/").Body.String(), "/files/a/b/")
+		assert.Equal(t, serve(config, http.MethodGet, "/none/").Body.String(), "/none/")
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		config := web.RouterConfig{TrailingSlash: web.TrailingSlashRedirect}
+		w := serve(config, http.MethodGet, "/users/1/?a=1")
+		assert.Equal(t, w.Code, http.StatusMovedPermanently)
+		assert.Equal(t, w.Header().Get("Location"), "/users/1?a=1")
+		w = serve(config, http.MethodPost, "/Orders/2")
+		assert.Equal(t, w.Code, http.StatusPermanentRedirect)
+		assert.Equal(t, w.Header().Get("Location"), "/Orders/2/")
+	})
+
+	t.Run("case-insensitive", func(t *testing.T) {
+		config := web.RouterConfig{CaseInsensitive: true, TrailingSlash: web.TrailingSlashStrip}
+		assert.Equal(t, serve(config, http.MethodGet, "/USERS/Jim").Body.String(), "/users/Jim")
+		assert.Equal(t, serve(config, http.MethodPost, "/orders/2").Body.String(), "/Orders/2/")
+	})
+
+	t.Run("method-not-allowed", func(t *testing.T) {
+		config := web.RouterConfig{MethodNotAllowed: "405"}
+		w := serve(config, http.MethodDelete, "/users/1")
+		assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+		assert.Equal(t, w.Header().Get("Allow"), "GET")
+		config.MethodNotAllowed = "404"
+		assert.Equal(t, serve(config, http.MethodDelete, "/users/1").Code, http.StatusNotFound)
+		assert.Equal(t, serve(config, http.MethodGet, "/users/1").Code, http.StatusOK)
+	})
+}
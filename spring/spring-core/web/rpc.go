@@ -18,8 +18,10 @@ package web
 
 import (
 	"errors"
+	"net/http"
 	"reflect"
 
+	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-stl/util"
 )
 
@@ -40,6 +42,10 @@ func (b *bindHandler) call(ctx Context) interface{} {
 	// 反射创建需要绑定请求参数
 	bindVal := reflect.New(b.bindType.Elem())
 	if err := ctx.Bind(bindVal.Interface()); err != nil {
+		var errs validator.Errors
+		if errors.As(err, &errs) { // Bind 方法会进行参数校验
+			panic(NewHttpError(http.StatusBadRequest, err.Error()).SetInternal(err))
+		}
 		panic(err)
 	}
 
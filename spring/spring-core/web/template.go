@@ -0,0 +1,250 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// TemplateEngine 视图模板引擎。
+type TemplateEngine interface {
+	Render(w io.Writer, name string, model interface{}, locale string) error
+}
+
+// Translator 国际化消息的翻译接口，模板中可以通过 t 函数获取翻译后的消息。
+type Translator interface {
+	Translate(locale string, key string, args ...interface{}) string
+}
+
+var templateEngine TemplateEngine
+
+// SetTemplateEngine 设置 Render 函数使用的模板引擎。
+func SetTemplateEngine(e TemplateEngine) {
+	templateEngine = e
+}
+
+// Render 渲染名为 name 的视图并作为 HTML 响应返回，语言由请求的 lang 参数或者
+// Accept-Language 请求头决定。Maybe panic.
+func Render(ctx Context, name string, model interface{}) {
+	if templateEngine == nil {
+		panic(errors.New("template engine not configured"))
+	}
+	var buf bytes.Buffer
+	if err := templateEngine.Render(&buf, name, model, RequestLocale(ctx)); err != nil {
+		panic(err)
+	}
+	ctx.HTMLBlob(buf.Bytes())
+}
+
+// RequestLocale 返回请求的语言，优先使用 lang 参数，其次使用 Accept-Language
+// 请求头中的第一个语言。
+func RequestLocale(ctx Context) string {
+	if s := ctx.QueryParam("lang"); s != "" {
+		return s
+	}
+	s := ctx.GetHeader("Accept-Language")
+	if i := strings.IndexAny(s, ",;"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// TemplateConfig 模板引擎配置，通常绑定到 web.template 前缀的属性上。
+type TemplateConfig struct {
+	Dir       string `value:"${dir:=templates}"`   // 模板文件所在的目录
+	Extension string `value:"${extension:=.html}"` // 模板文件的扩展名
+	Layout    string `value:"${layout:=}"`         // 默认的布局模板，例如 layouts/base
+	Reload    bool   `value:"${reload:=false}"`    // 每次渲染时重新加载模板，用于开发环境
+}
+
+// HTMLTemplates 基于 html/template 的模板引擎。模板目录下 layouts 目录存放布局
+// 模板，partials 目录存放公共片段，其他的文件都是视图。模板名称是去掉扩展名的
+// 相对路径，每个视图与布局和公共片段单独组成一个模板集合，因此不同的视图可以定义
+// 同名的 block 。配置了布局模板时先渲染布局模板，布局模板可以通过
+// {{template "content" .}} 引用视图中定义的 content 模板。
+type HTMLTemplates struct {
+	Translator Translator `autowire:"?"`
+
+	config *TemplateConfig // 使用指针避免容器对其进行属性绑定
+	fsys   fs.FS
+	funcs  template.FuncMap
+
+	mutex sync.RWMutex
+	views map[string]*template.Template
+}
+
+// NewTemplateEngine 从本地文件系统的 config.Dir 目录加载模板。
+func NewTemplateEngine(config TemplateConfig) (*HTMLTemplates, error) {
+	return newHTMLTemplates(os.DirFS(config.Dir), config)
+}
+
+// NewHTMLTemplates 从 fsys 的 config.Dir 目录加载模板，fsys 可以是 embed.FS 。
+func NewHTMLTemplates(fsys fs.FS, config TemplateConfig) (*HTMLTemplates, error) {
+	sub, err := fs.Sub(fsys, config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return newHTMLTemplates(sub, config)
+}
+
+func newHTMLTemplates(fsys fs.FS, config TemplateConfig) (*HTMLTemplates, error) {
+	e := &HTMLTemplates{
+		config: &config,
+		fsys:   fsys,
+		funcs: template.FuncMap{
+			"t":      func(key string, args ...interface{}) string { return key },
+			"locale": func() string { return "" },
+			"safe":   func(s string) template.HTML { return template.HTML(s) },
+			"dict":   dict,
+		},
+	}
+	if err := e.load(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Funcs 添加模板函数，需要在渲染之前调用。
+func (e *HTMLTemplates) Funcs(funcs template.FuncMap) error {
+	e.mutex.Lock()
+	for k, fn := range funcs {
+		e.funcs[k] = fn
+	}
+	e.mutex.Unlock()
+	return e.load()
+}
+
+// load 加载所有的视图模板。
+func (e *HTMLTemplates) load() error {
+
+	var shared, views []string
+	err := fs.WalkDir(e.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != e.config.Extension {
+			return err
+		}
+		if strings.HasPrefix(p, "layouts/") || strings.HasPrefix(p, "partials/") {
+			shared = append(shared, p)
+		} else {
+			views = append(views, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.mutex.RLock()
+	funcs := make(template.FuncMap, len(e.funcs))
+	for k, fn := range e.funcs {
+		funcs[k] = fn
+	}
+	e.mutex.RUnlock()
+
+	base := template.New("").Funcs(funcs)
+	for _, p := range shared {
+		if err = e.parse(base, p); err != nil {
+			return err
+		}
+	}
+
+	m := make(map[string]*template.Template)
+	for _, p := range views {
+		t, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err = e.parse(t, p); err != nil {
+			return err
+		}
+		m[strings.TrimSuffix(p, e.config.Extension)] = t
+	}
+
+	e.mutex.Lock()
+	e.views = m
+	e.mutex.Unlock()
+	return nil
+}
+
+// parse 解析模板文件，模板名称为去掉扩展名的相对路径。
+func (e *HTMLTemplates) parse(t *template.Template, p string) error {
+	b, err := fs.ReadFile(e.fsys, p)
+	if err != nil {
+		return err
+	}
+	_, err = t.New(strings.TrimSuffix(p, e.config.Extension)).Parse(string(b))
+	return err
+}
+
+// Render 渲染名为 name 的视图，模板中的 t 函数返回 locale 语言的翻译结果。
+func (e *HTMLTemplates) Render(w io.Writer, name string, model interface{}, locale string) error {
+
+	if e.config.Reload {
+		if err := e.load(); err != nil {
+			return err
+		}
+	}
+
+	e.mutex.RLock()
+	v, ok := e.views[name]
+	e.mutex.RUnlock()
+	if !ok {
+		return errors.New("template " + name + " not found")
+	}
+
+	t, err := v.Clone()
+	if err != nil {
+		return err
+	}
+
+	t.Funcs(template.FuncMap{"locale": func() string { return locale }})
+	if e.Translator != nil {
+		t.Funcs(template.FuncMap{
+			"t": func(key string, args ...interface{}) string {
+				return e.Translator.Translate(locale, key, args...)
+			},
+		})
+	}
+
+	if e.config.Layout != "" && t.Lookup("content") != nil {
+		return t.ExecuteTemplate(w, e.config.Layout, model)
+	}
+	return t.ExecuteTemplate(w, name, model)
+}
+
+// dict 使用键值对创建 map ，用于向公共片段传递多个参数。
+func dict(kv ...interface{}) (map[string]interface{}, error) {
+	if len(kv)%2 != 0 {
+		return nil, errors.New("dict requires an even number of arguments")
+	}
+	m := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			return nil, errors.New("dict keys must be strings")
+		}
+		m[k] = kv[i+1]
+	}
+	return m, nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+type translator map[string]string
+
+func (t translator) Translate(locale string, key string, args ...interface{}) string {
+	return fmt.Sprintf(t[locale+"."+key], args...)
+}
+
+func TestHTMLTemplates(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"views/layouts/base.html":   {Data: []byte(`<html lang="{{locale}}"><title>{{block "title" .}}Admin{{end}}</title>{{template "content" .}}</html>`)},
+		"views/partials/user.html":  {Data: []byte(`<li>{{.Name}}</li>`)},
+		"views/users/list.html":     {Data: []byte(`{{define "title"}}{{t "users" (len .)}}{{end}}{{define "content"}}<ul>{{range .}}{{template "partials/user" .}}{{end}}</ul>{{end}}`)},
+		"views/users/detail.html":   {Data: []byte(`{{define "content"}}<p>{{.Name}}</p>{{end}}`)},
+		"views/fragments/raw.html":  {Data: []byte(`{{.}}|{{safe .}}`)},
+		"views/fragments/dict.html": {Data: []byte(`{{with dict "a" 1 "b" "x"}}{{.a}}{{.b}}{{end}}`)},
+		"views/readme.txt":          {Data: []byte(`ignored`)},
+	}
+
+	e, err := web.NewHTMLTemplates(fsys, web.TemplateConfig{
+		Dir:       "views",
+		Extension: ".html",
+		Layout:    "layouts/base",
+	})
+	assert.Nil(t, err)
+
+	type user struct{ Name string }
+	users := []user{{Name: "jim"}, {Name: "<tom>"}}
+
+	t.Run("layout", func(t *testing.T) {
+		var buf bytes.Buffer
+		err = e.Render(&buf, "users/list", users, "zh")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `<html lang="zh"><title>users</title><ul><li>jim</li><li>&lt;tom&gt;</li></ul></html>`)
+	})
+
+	t.Run("default block", func(t *testing.T) {
+		var buf bytes.Buffer
+		err = e.Render(&buf, "users/detail", users[0], "")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `<html lang=""><title>Admin</title><p>jim</p></html>`)
+	})
+
+	t.Run("without layout", func(t *testing.T) {
+		var buf bytes.Buffer
+		err = e.Render(&buf, "fragments/raw", "<b>", "")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `&lt;b&gt;|<b>`)
+		buf.Reset()
+		err = e.Render(&buf, "fragments/dict", nil, "")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `1x`)
+	})
+
+	t.Run("i18n", func(t *testing.T) {
+		e.Translator = translator{"zh.users": "%d 个用户"}
+		defer func() { e.Translator = nil }()
+		var buf bytes.Buffer
+		err = e.Render(&buf, "users/list", users, "zh")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `<html lang="zh"><title>2 个用户</title><ul><li>jim</li><li>&lt;tom&gt;</li></ul></html>`)
+	})
+
+	t.Run("funcs", func(t *testing.T) {
+		err = e.Funcs(template.FuncMap{"safe": func(s string) string { return "safe:" + s }})
+		assert.Nil(t, err)
+		var buf bytes.Buffer
+		err = e.Render(&buf, "fragments/raw", "x", "")
+		assert.Nil(t, err)
+		assert.Equal(t, buf.String(), `x|safe:x`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err = e.Render(&bytes.Buffer{}, "users/none", nil, "")
+		assert.Error(t, err, "template users/none not found")
+	})
+}
+
+func TestHTMLTemplates_Reload(t *testing.T) {
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.html")
+	assert.Nil(t, os.WriteFile(file, []byte(`v1`), 0644))
+
+	e, err := web.NewTemplateEngine(web.TemplateConfig{Dir: dir, Extension: ".html", Reload: true})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Render(&buf, "index", nil, ""))
+	assert.Equal(t, buf.String(), "v1")
+
+	assert.Nil(t, os.WriteFile(file, []byte(`v2`), 0644))
+	buf.Reset()
+	assert.Nil(t, e.Render(&buf, "index", nil, ""))
+	assert.Equal(t, buf.String(), "v2")
+}
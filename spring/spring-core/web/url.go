@@ -19,6 +19,7 @@ package web
 import (
 	"errors"
 	"strings"
+	"sync"
 )
 
 // 路由风格有 echo、gin 和 {} 三种，
@@ -182,3 +183,34 @@ func ToPathStyle(path string, style PathStyleEnum) (string, string) {
 	}
 	return p.String(), p.wildCardName()
 }
+
+// UnknownRoute 没有匹配到路由的请求的路由模板。
+const UnknownRoute = "UNKNOWN"
+
+var routeTemplates sync.Map // map[string]string
+
+// RouteTemplate 返回注册路径的规范化路由模板，统一为 /users/:id 这种 echo 风格，
+// 用作指标和链路追踪的标签时可以避免原始路径导致的标签值过多。path 为空时返回
+// UnknownRoute 。
+func RouteTemplate(path string) string {
+	if path == "" {
+		return UnknownRoute
+	}
+	if s, ok := routeTemplates.Load(path); ok {
+		return s.(string)
+	}
+	s := toRouteTemplate(path)
+	routeTemplates.Store(path, s)
+	return s
+}
+
+// toRouteTemplate 转换路径的风格，不合法的路径保持原样。
+func toRouteTemplate(path string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = path
+		}
+	}()
+	s, _ = ToPathStyle(path, EchoPathStyle)
+	return s
+}
@@ -133,3 +133,12 @@ func TestToPathStyle(t *testing.T) {
 		assert.Equal(t, wildCardName, "e")
 	})
 }
+
+func TestRouteTemplate(t *testing.T) {
+	assert.Equal(t, web.RouteTemplate(""), web.UnknownRoute)
+	assert.Equal(t, web.RouteTemplate("/users/:id"), "/users/:id")
+	assert.Equal(t, web.RouteTemplate("/users/{id}"), "/users/:id")
+	assert.Equal(t, web.RouteTemplate("/files/*path"), "/files/*")
+	assert.Equal(t, web.RouteTemplate("/files/{*:path}"), "/files/*")
+	assert.Equal(t, web.RouteTemplate("/bad/{id"), "/bad/{id")
+}
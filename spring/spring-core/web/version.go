@@ -0,0 +1,190 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API 版本的匹配策略。
+const (
+	VersionByPath      = "path"       // 路径前缀，例如 /v1/users
+	VersionByHeader    = "header"     // 请求头，例如 X-API-Version: 1
+	VersionByMediaType = "media-type" // Accept 的参数，例如 application/json;version=1
+)
+
+// VersionConfig API 版本配置，通常绑定到 web.versioning 前缀的属性上。
+type VersionConfig struct {
+	Strategy string `value:"${strategy:=path}"`        // 版本的匹配策略
+	Header   string `value:"${header:=X-API-Version}"` // header 策略使用的请求头
+	Param    string `value:"${param:=version}"`        // media-type 策略使用的参数名
+	Default  string `value:"${default:=}"`             // 请求没有携带版本时使用的版本，为空时返回 400
+}
+
+// Versions 按 API 版本注册处理函数的路由器。path 策略将版本作为路径前缀注册到
+// 路由上，header 和 media-type 策略将同一路由的所有版本合并为一个处理函数，在
+// 处理请求时根据请求携带的版本进行分发，版本号 v1 与 1 是等价的。
+type Versions struct {
+	r          Router
+	config     VersionConfig
+	routes     map[string]*versionRoute
+	deprecated map[string]*deprecation
+}
+
+// deprecation 废弃版本的信息。
+type deprecation struct {
+	sunset time.Time // 下线时间
+	link   string    // 迁移说明的链接
+}
+
+// NewVersions Versions 的构造函数。
+func NewVersions(r Router, config VersionConfig) (*Versions, error) {
+	switch config.Strategy {
+	case VersionByPath, VersionByHeader, VersionByMediaType:
+	default:
+		return nil, fmt.Errorf("unknown version strategy %q", config.Strategy)
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Version"
+	}
+	if config.Param == "" {
+		config.Param = "version"
+	}
+	return &Versions{
+		r:          r,
+		config:     config,
+		routes:     make(map[string]*versionRoute),
+		deprecated: make(map[string]*deprecation),
+	}, nil
+}
+
+// Version 返回注册 version 版本处理函数的路由器。
+func (v *Versions) Version(version string) Router {
+	version = normalizeVersion(version)
+	return &router{add: func(m *Mapper) { v.add(version, m) }}
+}
+
+// Deprecate 将 version 版本标记为废弃，该版本的响应会携带 Deprecation 头部，
+// sunset 不为零值时携带 Sunset 头部，link 不为空时携带 Link 头部。
+func (v *Versions) Deprecate(version string, sunset time.Time, link string) *Versions {
+	v.deprecated[normalizeVersion(version)] = &deprecation{sunset: sunset, link: link}
+	return v
+}
+
+// normalizeVersion 去掉版本号的 v 前缀。
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+}
+
+func (v *Versions) add(version string, m *Mapper) {
+
+	h := &versionHandler{v: v, version: version, m: m}
+	if v.config.Strategy == VersionByPath {
+		v.r.AddMapper(NewMapper(m.method, "/v"+version+m.path, h))
+		return
+	}
+
+	key := fmt.Sprintf("%d:%s", m.method, m.path)
+	r, ok := v.routes[key]
+	if !ok {
+		r = &versionRoute{v: v, first: h, handlers: make(map[string]*versionHandler)}
+		v.routes[key] = r
+		v.r.AddMapper(NewMapper(m.method, m.path, r))
+	}
+	if _, ok = r.handlers[version]; ok {
+		panic(fmt.Errorf("duplicate version %s for %s %s", version, GetMethod(m.method), m.path))
+	}
+	r.handlers[version] = h
+}
+
+// requestVersion 返回请求携带的版本，没有携带时返回默认版本。
+func (v *Versions) requestVersion(r *http.Request) string {
+	var s string
+	switch v.config.Strategy {
+	case VersionByHeader:
+		s = r.Header.Get(v.config.Header)
+	case VersionByMediaType:
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			if _, params, err := mime.ParseMediaType(accept); err == nil && params[v.config.Param] != "" {
+				s = params[v.config.Param]
+				break
+			}
+		}
+	}
+	if s == "" {
+		s = v.config.Default
+	}
+	return normalizeVersion(s)
+}
+
+// versionHandler 某个版本的处理函数，处理函数在请求时获取，因此注册之后通过
+// Mapper 设置的名称等属性仍然有效。
+type versionHandler struct {
+	v       *Versions
+	version string
+	m       *Mapper
+}
+
+func (h *versionHandler) Invoke(ctx Context) {
+	if d, ok := h.v.deprecated[h.version]; ok {
+		ctx.Header("Deprecation", "true")
+		if !d.sunset.IsZero() {
+			ctx.Header("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.link != "" {
+			ctx.Header("Link", "<"+d.link+`>; rel="deprecation"`)
+		}
+	}
+	h.m.Handler().Invoke(ctx)
+}
+
+func (h *versionHandler) FileLine() (file string, line int, fnName string) {
+	return h.m.Handler().FileLine()
+}
+
+func (h *versionHandler) handlerName() string {
+	return HandlerName(h.m.Handler())
+}
+
+// versionRoute 根据请求携带的版本分发到对应版本的处理函数。
+type versionRoute struct {
+	v        *Versions
+	first    *versionHandler
+	handlers map[string]*versionHandler
+}
+
+func (r *versionRoute) Invoke(ctx Context) {
+	version := r.v.requestVersion(ctx.Request())
+	if version == "" {
+		ErrorHandler(ctx, NewHttpError(http.StatusBadRequest, "missing API version"))
+		return
+	}
+	h, ok := r.handlers[version]
+	if !ok {
+		ErrorHandler(ctx, NewHttpError(http.StatusBadRequest, "unsupported API version "+version))
+		return
+	}
+	h.Invoke(ctx)
+}
+
+func (r *versionRoute) FileLine() (file string, line int, fnName string) {
+	return r.first.FileLine()
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestVersions(t *testing.T) {
+
+	_, err := web.NewVersions(web.NewRouter(), web.VersionConfig{Strategy: "query"})
+	assert.Error(t, err, "unknown version strategy \"query\"")
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	register := func(v *web.Versions) {
+		v.Version("v1").GetMapping("/users", func(ctx web.Context) { ctx.String("v1") })
+		v.Version("2").GetMapping("/users", func(ctx web.Context) { ctx.String("v2") }).Name("users-v2")
+		v.Deprecate("1", sunset, "https://example.com/v2")
+	}
+
+	invoke := func(h web.Handler, r *http.Request) *httptest.ResponseRecorder {
+		ctx := newRecorderContext(r)
+		h.Invoke(ctx)
+		return ctx.w
+	}
+
+	t.Run("path", func(t *testing.T) {
+		r := web.NewRouter()
+		v, err := web.NewVersions(r, web.VersionConfig{Strategy: web.VersionByPath})
+		assert.Nil(t, err)
+		register(v)
+
+		mappers := r.Mappers()
+		assert.Equal(t, len(mappers), 2)
+		assert.Equal(t, mappers[0].Path(), "/v1/users")
+		assert.Equal(t, mappers[1].Path(), "/v2/users")
+		assert.Equal(t, web.HandlerName(mappers[1].Handler()), "users-v2")
+
+		w := invoke(mappers[0].Handler(), httptest.NewRequest(http.MethodGet, "/v1/users", nil))
+		assert.Equal(t, w.Body.String(), "v1")
+		assert.Equal(t, w.Header().Get("Deprecation"), "true")
+		assert.Equal(t, w.Header().Get("Sunset"), "Tue, 01 Jan 2030 00:00:00 GMT")
+		assert.Equal(t, w.Header().Get("Link"), `<https://example.com/v2>; rel="deprecation"`)
+
+		w = invoke(mappers[1].Handler(), httptest.NewRequest(http.MethodGet, "/v2/users", nil))
+		assert.Equal(t, w.Body.String(), "v2")
+		assert.Equal(t, w.Header().Get("Deprecation"), "")
+	})
+
+	t.Run("header", func(t *testing.T) {
+		r := web.NewRouter()
+		v, err := web.NewVersions(r, web.VersionConfig{Strategy: web.VersionByHeader})
+		assert.Nil(t, err)
+		register(v)
+		assert.Panic(t, func() {
+			v.Version("v2").GetMapping("/users", func(ctx web.Context) {})
+		}, "duplicate version 2 for \\[GET\\] /users")
+
+		mappers := r.Mappers()
+		assert.Equal(t, len(mappers), 1)
+		assert.Equal(t, mappers[0].Path(), "/users")
+		h := mappers[0].Handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("X-API-Version", "v2")
+		assert.Equal(t, invoke(h, req).Body.String(), "v2")
+
+		req.Header.Set("X-API-Version", "1")
+		assert.Equal(t, invoke(h, req).Body.String(), "v1")
+
+		req.Header.Set("X-API-Version", "3")
+		w := invoke(h, req)
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		assert.Equal(t, w.Body.String(), "unsupported API version 3")
+
+		req.Header.Del("X-API-Version")
+		assert.Equal(t, invoke(h, req).Body.String(), "missing API version")
+	})
+
+	t.Run("media-type", func(t *testing.T) {
+		r := web.NewRouter()
+		v, err := web.NewVersions(r, web.VersionConfig{Strategy: web.VersionByMediaType, Default: "2"})
+		assert.Nil(t, err)
+		register(v)
+		h := r.Mappers()[0].Handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Accept", "text/html, application/json; version=1")
+		assert.Equal(t, invoke(h, req).Body.String(), "v1")
+
+		req.Header.Set("Accept", "application/json")
+		assert.Equal(t, invoke(h, req).Body.String(), "v2")
+	})
+}
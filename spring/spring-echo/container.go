@@ -48,6 +48,7 @@ type route struct {
 type Container struct {
 	*web.AbstractContainer
 	echoServer *echo.Echo
+	httpServer *http.Server     // 服务额外的监听器
 	routes     map[string]route // 记录所有通过 spring-echo 注册的路由
 }
 
@@ -108,7 +109,27 @@ func (c *Container) Start() error {
 		}
 	}
 
-	if cfg := c.Config(); cfg.EnableSSL {
+	cfg := c.Config()
+	if len(cfg.Listeners) > 0 || cfg.ReusePort > 0 {
+		c.httpServer = &http.Server{
+			Handler:      c.echoServer,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			TLSConfig:    cfg.TLSConfig,
+		}
+		if err = web.ServeListeners(c.httpServer, cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ReusePort > 0 {
+		err = web.ServeReusePort(c.httpServer, cfg)
+	} else if cfg.EnableSSL && cfg.TLSConfig != nil {
+		s := c.echoServer.TLSServer
+		s.Addr = c.Address()
+		s.TLSConfig = cfg.TLSConfig.Clone()
+		err = c.echoServer.StartServer(s)
+	} else if cfg.EnableSSL {
 		err = c.echoServer.StartTLS(c.Address(), cfg.CertFile, cfg.KeyFile)
 	} else {
 		err = c.echoServer.Start(c.Address())
@@ -120,6 +141,11 @@ func (c *Container) Start() error {
 
 // Stop 停止 Web 容器
 func (c *Container) Stop(ctx context.Context) error {
+	if c.httpServer != nil {
+		if err := c.httpServer.Shutdown(ctx); err != nil {
+			log.Infof("shutdown echo server on extra listeners return %s", errors.ToString(err))
+		}
+	}
 	err := c.echoServer.Shutdown(ctx)
 	log.Infof("shutdown echo server on %s return %s", c.Address(), errors.ToString(err))
 	return err
@@ -21,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -130,3 +132,44 @@ func TestContext_PathNotFound(t *testing.T) {
 	assert.Equal(t, response.StatusCode, http.StatusNotFound)
 	assert.Equal(t, string(b), "404 page not found")
 }
+
+func TestContainer_Listeners(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "web.sock")
+	c := SpringEcho.NewContainer(web.ContainerConfig{
+		Port:      8080,
+		Listeners: []web.ListenerConfig{{Network: "unix", Address: sock}},
+	})
+	c.GetMapping("/", func(ctx web.Context) {
+		ctx.String("ok")
+	})
+	go c.Start()
+	defer c.Stop(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	for _, c := range []*http.Client{http.DefaultClient, client} {
+		response, err := c.Get("http://127.0.0.1:8080/")
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, string(b), "ok")
+	}
+}
+
+func TestContainer_ReusePort(t *testing.T) {
+	c := SpringEcho.NewContainer(web.ContainerConfig{Port: 8080, ReusePort: 2})
+	c.GetMapping("/", func(ctx web.Context) {
+		ctx.String("ok")
+	})
+	go c.Start()
+	defer c.Stop(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	response, err := http.Get("http://127.0.0.1:8080/")
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, string(b), "ok")
+}
@@ -28,7 +28,6 @@ import (
 
 	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-core/web"
-	"github.com/go-spring/spring-stl/json"
 	"github.com/go-spring/spring-stl/knife"
 	"github.com/go-spring/spring-stl/util"
 	"github.com/labstack/echo"
@@ -283,7 +282,18 @@ func (ctx *Context) Bind(i interface{}) error {
 		return nil
 	}
 
-	if err := ctx.echoContext.Bind(i); err != nil {
+	// JSON 格式的请求体使用 web.JSONCodec 进行解码，其他格式交给 echo 处理。
+	if ctx.ContentType() == web.MIMEApplicationJSON {
+		b, err := ctx.GetRawData()
+		if err != nil {
+			return err
+		}
+		if len(b) > 0 {
+			if err = web.UnmarshalJSON(b, i); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+			}
+		}
+	} else if err := ctx.echoContext.Bind(i); err != nil {
 		return err
 	}
 	return validator.Validate(i)
@@ -342,7 +352,7 @@ func (ctx *Context) HTMLBlob(b []byte) {
 
 // JSON sends a JSON response.
 func (ctx *Context) JSON(i interface{}) {
-	b, err := json.Marshal(i)
+	b, err := web.MarshalJSON(i)
 	if err != nil {
 		panic(err)
 	}
@@ -351,7 +361,7 @@ func (ctx *Context) JSON(i interface{}) {
 
 // JSONPretty sends a pretty-print JSON.
 func (ctx *Context) JSONPretty(i interface{}, indent string) {
-	b, err := json.MarshalIndent(i, "", indent)
+	b, err := web.MarshalJSONIndent(i, "", indent)
 	if err != nil {
 		panic(err)
 	}
@@ -116,11 +116,20 @@ func (c *Container) Start() error {
 		Handler:      c.ginEngine,
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
+		TLSConfig:    cfg.TLSConfig,
+	}
+
+	if err = web.ServeListeners(c.httpServer, cfg); err != nil {
+		return err
 	}
 
 	log.Info("⇨ http server started on ", c.Address())
 
-	if cfg.EnableSSL {
+	if cfg.ReusePort > 0 {
+		err = web.ServeReusePort(c.httpServer, cfg)
+	} else if cfg.EnableSSL && cfg.TLSConfig != nil {
+		err = c.httpServer.ListenAndServeTLS("", "")
+	} else if cfg.EnableSSL {
 		err = c.httpServer.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
 	} else {
 		err = c.httpServer.ListenAndServe()
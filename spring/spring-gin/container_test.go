@@ -24,6 +24,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -130,3 +131,44 @@ func TestFilter_PanicWebHttpError(t *testing.T) {
 	fmt.Println(response.Status, string(b))
 	assert.Equal(t, response.StatusCode, http.StatusNotFound)
 }
+
+func TestContainer_Listeners(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "web.sock")
+	c := SpringGin.NewContainer(web.ContainerConfig{
+		Port:      8080,
+		Listeners: []web.ListenerConfig{{Network: "unix", Address: sock}},
+	})
+	c.GetMapping("/", func(ctx web.Context) {
+		ctx.String("ok")
+	})
+	go c.Start()
+	defer c.Stop(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	for _, c := range []*http.Client{http.DefaultClient, client} {
+		response, err := c.Get("http://127.0.0.1:8080/")
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, string(b), "ok")
+	}
+}
+
+func TestContainer_ReusePort(t *testing.T) {
+	c := SpringGin.NewContainer(web.ContainerConfig{Port: 8080, ReusePort: 2})
+	c.GetMapping("/", func(ctx web.Context) {
+		ctx.String("ok")
+	})
+	go c.Start()
+	defer c.Stop(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	response, err := http.Get("http://127.0.0.1:8080/")
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, string(b), "ok")
+}
@@ -26,9 +26,9 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-spring/spring-core/validator"
 	"github.com/go-spring/spring-core/web"
-	"github.com/go-spring/spring-stl/json"
 	"github.com/go-spring/spring-stl/knife"
 )
 
@@ -307,13 +307,32 @@ func (ctx *Context) Cookies() []*http.Cookie {
 
 // Bind binds the request body into provided type `i`.
 func (ctx *Context) Bind(i interface{}) error {
-	err := ctx.ginContext.ShouldBind(i)
-	if err != nil {
+	if err := ctx.bind(i); err != nil {
 		return err
 	}
 	return validator.Validate(i)
 }
 
+// bind JSON 格式的请求体使用 web.JSONCodec 进行解码，其他格式交给 gin 处理。
+func (ctx *Context) bind(i interface{}) error {
+	if ctx.ginContext.ContentType() != web.MIMEApplicationJSON {
+		return ctx.ginContext.ShouldBind(i)
+	}
+	b, err := ctx.GetRawData()
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		if err = web.UnmarshalJSON(b, i); err != nil {
+			return err
+		}
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(i)
+}
+
 // ResponseWriter returns `http.ResponseWriter`.
 func (ctx *Context) ResponseWriter() web.ResponseWriter {
 	return ctx.ginContext.Writer.(*responseWriter)
@@ -359,13 +378,17 @@ func (ctx *Context) HTMLBlob(b []byte) {
 
 // JSON sends a JSON response.
 func (ctx *Context) JSON(i interface{}) {
+	b, err := web.MarshalJSON(i)
+	if err != nil {
+		panic(err)
+	}
 	statusCode := ctx.ginContext.Writer.Status()
-	ctx.ginContext.JSON(statusCode, i)
+	ctx.ginContext.Data(statusCode, web.MIMEApplicationJSONCharsetUTF8, b)
 }
 
 // JSONPretty sends a pretty-print JSON.
 func (ctx *Context) JSONPretty(i interface{}, indent string) {
-	b, err := json.MarshalIndent(i, "", indent)
+	b, err := web.MarshalJSONIndent(i, "", indent)
 	if err != nil {
 		panic(err)
 	}
@@ -407,9 +430,9 @@ func (ctx *Context) JSONP(callback string, i interface{}) {
 			err  error
 		)
 		if _, pretty := ctx.QueryParams()["pretty"]; pretty {
-			data, err = json.MarshalIndent(i, "", "  ")
+			data, err = web.MarshalJSONIndent(i, "", "  ")
 		} else {
-			data, err = json.Marshal(i)
+			data, err = web.MarshalJSON(i)
 		}
 		if err == nil {
 			return err
@@ -0,0 +1,97 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// 泛型版本的断言函数，参数类型在编译期检查，避免了 interface{} 装箱导致的类型
+// 不一致问题，例如 Equal(t, int64(1), 1) 在编译期无法发现。Equal 等函数保留了
+// 原来的签名，因为很多调用方依赖其跨类型比较的行为。
+
+// Eq asserts that got and expect are equal as defined by ==.
+func Eq[T comparable](t *testing.T, got T, expect T) {
+	if got != expect {
+		fail(t, 1, "got %s but expect %s", show(got), show(expect))
+	}
+}
+
+// NotEq asserts that got and expect are not equal as defined by ==.
+func NotEq[T comparable](t *testing.T, got T, expect T) {
+	if got == expect {
+		fail(t, 1, "got %s but expect not equal", show(got))
+	}
+}
+
+// DeepEq asserts that got and expect are equal as defined by reflect.DeepEqual,
+// it is used for types that are not comparable.
+func DeepEq[T any](t *testing.T, got T, expect T) {
+	if !reflect.DeepEqual(got, expect) {
+		fail(t, 1, "got %v but expect %v", got, expect)
+	}
+}
+
+// EqSlice asserts that got and expect have the same length and elements, it
+// reports the first different element.
+func EqSlice[T comparable](t *testing.T, got []T, expect []T) {
+	if len(got) != len(expect) {
+		fail(t, 1, "got len %d but expect len %d, got %v but expect %v", len(got), len(expect), got, expect)
+		return
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			fail(t, 1, "got %s but expect %s at index %d", show(got[i]), show(expect[i]), i)
+			return
+		}
+	}
+}
+
+// EqMap asserts that got and expect have the same keys and values, it reports
+// the first different key.
+func EqMap[K comparable, V comparable](t *testing.T, got map[K]V, expect map[K]V) {
+	for k, ev := range expect {
+		gv, ok := got[k]
+		if !ok {
+			fail(t, 1, "key %s is missing", show(k))
+			return
+		}
+		if gv != ev {
+			fail(t, 1, "got %s but expect %s at key %s", show(gv), show(ev), show(k))
+			return
+		}
+	}
+	for k := range got {
+		if _, ok := expect[k]; !ok {
+			fail(t, 1, "key %s is unexpected", show(k))
+			return
+		}
+	}
+}
+
+// show 字符串加引号显示，以便区分空白字符。
+func show(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
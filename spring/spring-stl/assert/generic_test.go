@@ -0,0 +1,72 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-stl/assert"
+)
+
+func TestEq(t *testing.T) {
+	assert.Eq(t, 1, 1)
+	assert.Eq(t, "a", "a")
+	assert.Eq[int64](t, 1, 1)
+	checkFailed(t)
+}
+
+func TestNotEq(t *testing.T) {
+	assert.NotEq(t, 1, 0)
+	checkFailed(t)
+}
+
+func TestDeepEq(t *testing.T) {
+	assert.DeepEq(t, []string{"a"}, []string{"a"})
+	assert.DeepEq(t, map[string][]int{"a": {1}}, map[string][]int{"a": {1}})
+	checkFailed(t)
+}
+
+func TestEqSlice(t *testing.T) {
+	assert.EqSlice(t, []int{1, 2}, []int{1, 2})
+	assert.EqSlice(t, nil, []string{})
+	checkFailed(t)
+}
+
+func TestEqMap(t *testing.T) {
+	assert.EqMap(t, map[string]int{"a": 1}, map[string]int{"a": 1})
+	assert.EqMap(t, nil, map[string]bool{})
+	checkFailed(t)
+}
+
+func BenchmarkEqual(b *testing.B) {
+	t := &testing.T{}
+	b.Run("interface", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			assert.Equal(t, i, i)
+		}
+	})
+	b.Run("generic", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			assert.Eq(t, i, i)
+		}
+	})
+}
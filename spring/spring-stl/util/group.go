@@ -0,0 +1,107 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// PanicError goroutine 中发生的 panic 。
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// Errors 多个错误的集合。
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d errors occurred:", len(e))
+	for _, err := range e {
+		sb.WriteString("\n\t* ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Group 类似 errgroup.Group，等待一组 goroutine 结束并收集它们返回的错误以及发生
+// 的 panic，与 errgroup 不同的是它保留所有的错误而不只是第一个。Group 的零值可以
+// 直接使用，并且可以安全地并发使用。
+type Group struct {
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	errs    Errors
+	onError func(err error)
+}
+
+// OnError 设置出现错误时的回调函数，在出错的 goroutine 中调用，可以用来在出现
+// 第一个致命错误时触发程序关闭，需要在调用 Go 之前设置。
+func (g *Group) OnError(fn func(err error)) {
+	g.onError = fn
+}
+
+// Go 在新的 goroutine 中执行 fn，fn 返回的错误及发生的 panic 都会被记录下来。
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := g.run(fn); err != nil {
+			g.mutex.Lock()
+			g.errs = append(g.errs, err)
+			g.mutex.Unlock()
+			if g.onError != nil {
+				g.onError(err)
+			}
+		}
+	}()
+}
+
+func (g *Group) run(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// Wait 等待所有的 goroutine 结束，没有错误时返回 nil，否则返回 Errors 类型的错误。
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	return g.Err()
+}
+
+// Err 返回目前为止收集到的错误，没有错误时返回 nil 。
+func (g *Group) Err() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if len(g.errs) == 0 {
+		return nil
+	}
+	return append(Errors(nil), g.errs...)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/spring-stl/util"
+)
+
+func TestGroup(t *testing.T) {
+
+	var g util.Group
+	assert.Nil(t, g.Wait())
+
+	var count int32
+	g.OnError(func(err error) { atomic.AddInt32(&count, 1) })
+
+	g.Go(func() error { return nil })
+	g.Go(func() error { return errors.New("error") })
+	g.Go(func() error { panic("boom") })
+
+	err := g.Wait()
+	assert.Equal(t, atomic.LoadInt32(&count), int32(2))
+
+	var errs util.Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 2)
+	assert.Matches(t, err.Error(), "2 errors occurred:")
+
+	var pe *util.PanicError
+	for _, e := range errs {
+		if errors.As(e, &pe) {
+			break
+		}
+	}
+	assert.True(t, pe != nil)
+	assert.Equal(t, pe.Value, "boom")
+	assert.Matches(t, pe.Error(), "panic: boom")
+}
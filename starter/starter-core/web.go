@@ -30,4 +30,20 @@ type WebServerConfig struct {
 	BasePath     string `value:"${web.server.base-path:=/}"`      // 根路径
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+
+	// Listeners 额外的监听器，例如其他 TCP 端口、unix socket 以及 systemd
+	// socket activation 传入的监听器。
+	Listeners []ListenerConfig `value:"${web.server.listeners}"`
+
+	// ReusePort 大于 0 时通过 SO_REUSEPORT 在主监听地址上创建多个监听器。
+	ReusePort int `value:"${web.server.reuse-port:=0}"`
+}
+
+// ListenerConfig 额外的监听器配置，与 web.ListenerConfig 的字段一一对应。
+type ListenerConfig struct {
+	Network   string `value:"${network:=tcp}"`  // 网络类型，可选 tcp、unix 和 systemd
+	Address   string `value:"${address}"`       // tcp 为 host:port，unix 为 socket 文件路径，systemd 为监听器的名称或序号
+	Mode      string `value:"${mode:=}"`        // unix socket 文件的权限，例如 0660
+	SSL       bool   `value:"${ssl:=false}"`    // 是否使用容器的 SSL 配置
+	ReusePort int    `value:"${reuse-port:=0}"` // 大于 0 时通过 SO_REUSEPORT 在同一地址上创建多个监听器
 }
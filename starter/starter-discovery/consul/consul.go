@@ -0,0 +1,116 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package consul 基于 Consul 实现了服务注册与发现。
+package consul
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spring/spring-core/discovery"
+	"github.com/hashicorp/consul/api"
+)
+
+// Config Consul 配置，绑定到 discovery.consul 前缀的属性上。
+type Config struct {
+	Address         string        `value:"${address:=127.0.0.1:8500}"` // Consul agent 地址
+	Token           string        `value:"${token:=}"`                 // ACL token
+	CheckInterval   time.Duration `value:"${check-interval:=10s}"`     // 健康检查的间隔
+	CheckTimeout    time.Duration `value:"${check-timeout:=5s}"`       // 健康检查的超时
+	DeregisterAfter time.Duration `value:"${deregister-after:=1m}"`    // 健康检查失败多久后自动注销实例
+}
+
+// Registry 基于 Consul 的 discovery.Registry 和 discovery.Client 实现。
+type Registry struct {
+	client *api.Client
+	config *Config
+}
+
+// New 创建 Consul 客户端。
+func New(config Config) (*Registry, error) {
+	c := api.DefaultConfig()
+	c.Address = config.Address
+	c.Token = config.Token
+	client, err := api.NewClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{client: client, config: &config}, nil
+}
+
+// Register 注册服务实例，设置了健康检查地址时使用 HTTP 检查，否则使用 TCP 检查。
+func (r *Registry) Register(ctx context.Context, i *discovery.Instance) error {
+
+	meta := make(map[string]string)
+	for k, v := range i.Metadata {
+		meta[k] = v
+	}
+	meta[discovery.MetadataScheme] = i.Scheme
+
+	check := &api.AgentServiceCheck{
+		Interval:                       r.config.CheckInterval.String(),
+		Timeout:                        r.config.CheckTimeout.String(),
+		DeregisterCriticalServiceAfter: r.config.DeregisterAfter.String(),
+	}
+	if i.HealthCheck != "" {
+		check.HTTP = i.HealthCheck
+	} else {
+		check.TCP = i.Address()
+	}
+
+	return r.client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		ID:      i.ID,
+		Name:    i.Service,
+		Tags:    []string{i.Scheme},
+		Address: i.Host,
+		Port:    i.Port,
+		Meta:    meta,
+		Check:   check,
+	})
+}
+
+// Deregister 注销服务实例。
+func (r *Registry) Deregister(ctx context.Context, i *discovery.Instance) error {
+	return r.client.Agent().ServiceDeregister(i.ID)
+}
+
+// Instances 返回通过健康检查的服务实例。
+func (r *Registry) Instances(ctx context.Context, service string) ([]*discovery.Instance, error) {
+
+	opts := (&api.QueryOptions{}).WithContext(ctx)
+	entries, _, err := r.client.Health().Service(service, "", true, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*discovery.Instance, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		ret = append(ret, &discovery.Instance{
+			ID:       e.Service.ID,
+			Service:  e.Service.Service,
+			Scheme:   e.Service.Meta[discovery.MetadataScheme],
+			Host:     host,
+			Port:     e.Service.Port,
+			Metadata: e.Service.Meta,
+		})
+	}
+	return ret, nil
+}
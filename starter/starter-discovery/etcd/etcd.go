@@ -0,0 +1,152 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package etcd 基于 etcd 实现了服务注册与发现，实例信息以 JSON 格式保存在
+// {prefix}/{service}/{id} 键上，并通过租约保持存活。
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/discovery"
+	"github.com/go-spring/spring-core/log"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Config etcd 配置，绑定到 discovery.etcd 前缀的属性上。
+type Config struct {
+	Endpoints   []string      `value:"${endpoints}"`         // 节点地址，默认为 127.0.0.1:2379
+	Username    string        `value:"${username:=}"`        // 用户名
+	Password    string        `value:"${password:=}"`        // 密码
+	Prefix      string        `value:"${prefix:=/services}"` // 实例信息的键前缀
+	TTL         time.Duration `value:"${ttl:=10s}"`          // 租约的有效期
+	DialTimeout time.Duration `value:"${dial-timeout:=5s}"`  // 连接超时
+}
+
+// Registry 基于 etcd 的 discovery.Registry 和 discovery.Client 实现。
+type Registry struct {
+	client *clientv3.Client
+	config *Config
+	mutex  sync.Mutex
+	leases map[string]clientv3.LeaseID
+}
+
+// New 创建 etcd 客户端。
+func New(config Config) (*Registry, error) {
+
+	endpoints := config.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []string{"127.0.0.1:2379"}
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		DialTimeout: config.DialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{
+		client: client,
+		config: &config,
+		leases: make(map[string]clientv3.LeaseID),
+	}, nil
+}
+
+// Close 关闭 etcd 客户端。
+func (r *Registry) Close() {
+	if err := r.client.Close(); err != nil {
+		log.Error(err)
+	}
+}
+
+func (r *Registry) key(service, id string) string {
+	return path.Join(r.config.Prefix, service, id)
+}
+
+// Register 注册服务实例并在后台续约，应用异常退出时实例在租约到期后自动注销。
+func (r *Registry) Register(ctx context.Context, i *discovery.Instance) error {
+
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	lease, err := r.client.Grant(ctx, int64(r.config.TTL/time.Second))
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.client.Put(ctx, r.key(i.Service, i.ID), string(b), clientv3.WithLease(lease.ID)); err != nil {
+		return err
+	}
+
+	ch, err := r.client.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+
+	r.mutex.Lock()
+	r.leases[i.ID] = lease.ID
+	r.mutex.Unlock()
+	return nil
+}
+
+// Deregister 撤销租约，实例信息随之删除。
+func (r *Registry) Deregister(ctx context.Context, i *discovery.Instance) error {
+
+	r.mutex.Lock()
+	lease, ok := r.leases[i.ID]
+	delete(r.leases, i.ID)
+	r.mutex.Unlock()
+
+	if !ok {
+		_, err := r.client.Delete(ctx, r.key(i.Service, i.ID))
+		return err
+	}
+	_, err := r.client.Revoke(ctx, lease)
+	return err
+}
+
+// Instances 返回服务所有存活的实例。
+func (r *Registry) Instances(ctx context.Context, service string) ([]*discovery.Instance, error) {
+
+	resp, err := r.client.Get(ctx, r.key(service, "")+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*discovery.Instance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		i := new(discovery.Instance)
+		if err = json.Unmarshal(kv.Value, i); err != nil {
+			return nil, err
+		}
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
@@ -0,0 +1,15 @@
+module github.com/go-spring/starter-discovery
+
+go 1.14
+
+require (
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/hashicorp/consul/api v1.12.0
+	github.com/nacos-group/nacos-sdk-go v1.0.9
+	go.etcd.io/etcd/client/v3 v3.5.1
+	google.golang.org/grpc v1.41.0
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
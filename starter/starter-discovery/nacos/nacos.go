@@ -0,0 +1,152 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nacos 基于 Nacos 实现了服务注册与发现。
+package nacos
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-spring/spring-core/discovery"
+	"github.com/nacos-group/nacos-sdk-go/clients"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// Config Nacos 配置，绑定到 discovery.nacos 前缀的属性上。
+type Config struct {
+	Addrs     []string      `value:"${addrs}"`                       // 服务器地址，默认为 127.0.0.1:8848
+	Namespace string        `value:"${namespace:=}"`                 // 命名空间 ID
+	Group     string        `value:"${group:=DEFAULT_GROUP}"`        // 服务分组
+	Cluster   string        `value:"${cluster:=DEFAULT}"`            // 集群名称
+	Username  string        `value:"${username:=}"`                  // 用户名
+	Password  string        `value:"${password:=}"`                  // 密码
+	Timeout   time.Duration `value:"${timeout:=5s}"`                 // 请求超时
+	LogDir    string        `value:"${log-dir:=/tmp/nacos/log}"`     // SDK 日志目录
+	CacheDir  string        `value:"${cache-dir:=/tmp/nacos/cache}"` // SDK 缓存目录
+}
+
+// Registry 基于 Nacos 的 discovery.Registry 和 discovery.Client 实现。
+type Registry struct {
+	client naming_client.INamingClient
+	config *Config
+}
+
+// New 创建 Nacos 客户端。
+func New(config Config) (*Registry, error) {
+
+	addrs := config.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{"127.0.0.1:8848"}
+	}
+
+	var servers []constant.ServerConfig
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseUint(port, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, constant.ServerConfig{IpAddr: host, Port: p})
+	}
+
+	client, err := clients.NewNamingClient(vo.NacosClientParam{
+		ClientConfig: &constant.ClientConfig{
+			NamespaceId:         config.Namespace,
+			TimeoutMs:           uint64(config.Timeout / time.Millisecond),
+			Username:            config.Username,
+			Password:            config.Password,
+			LogDir:              config.LogDir,
+			CacheDir:            config.CacheDir,
+			NotLoadCacheAtStart: true,
+		},
+		ServerConfigs: servers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{client: client, config: &config}, nil
+}
+
+// Register 注册临时实例，由客户端心跳维持实例的健康状态。
+func (r *Registry) Register(ctx context.Context, i *discovery.Instance) error {
+
+	meta := make(map[string]string)
+	for k, v := range i.Metadata {
+		meta[k] = v
+	}
+	meta[discovery.MetadataScheme] = i.Scheme
+
+	_, err := r.client.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          i.Host,
+		Port:        uint64(i.Port),
+		ServiceName: i.Service,
+		Weight:      1,
+		Enable:      true,
+		Healthy:     true,
+		Ephemeral:   true,
+		Metadata:    meta,
+		ClusterName: r.config.Cluster,
+		GroupName:   r.config.Group,
+	})
+	return err
+}
+
+// Deregister 注销服务实例。
+func (r *Registry) Deregister(ctx context.Context, i *discovery.Instance) error {
+	_, err := r.client.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          i.Host,
+		Port:        uint64(i.Port),
+		ServiceName: i.Service,
+		Ephemeral:   true,
+		Cluster:     r.config.Cluster,
+		GroupName:   r.config.Group,
+	})
+	return err
+}
+
+// Instances 返回服务所有健康的实例。
+func (r *Registry) Instances(ctx context.Context, service string) ([]*discovery.Instance, error) {
+
+	instances, err := r.client.SelectInstances(vo.SelectInstancesParam{
+		ServiceName: service,
+		GroupName:   r.config.Group,
+		HealthyOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*discovery.Instance, 0, len(instances))
+	for _, i := range instances {
+		ret = append(ret, &discovery.Instance{
+			ID:       i.InstanceId,
+			Service:  service,
+			Scheme:   i.Metadata[discovery.MetadataScheme],
+			Host:     i.Ip,
+			Port:     int(i.Port),
+			Metadata: i.Metadata,
+		})
+	}
+	return ret, nil
+}
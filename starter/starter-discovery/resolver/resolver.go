@@ -0,0 +1,131 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resolver 实现了基于 discovery.Client 的 gRPC 地址解析器，通过
+// discovery:///{service} 格式的地址连接注册中心中的 gRPC 服务。
+package resolver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/discovery"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme gRPC 地址的协议名称。
+const Scheme = "discovery"
+
+// Builder gRPC 地址解析器的构造器，初始化时注册到 gRPC 的全局解析器中。
+type Builder struct {
+	client   discovery.Client
+	interval time.Duration
+}
+
+// NewBuilder Builder 的构造函数，interval 为刷新服务实例的间隔。
+func NewBuilder(client discovery.Client, interval time.Duration) *Builder {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Builder{client: client, interval: interval}
+}
+
+// OnInit 注册 gRPC 地址解析器。
+func (b *Builder) OnInit() {
+	resolver.Register(b)
+}
+
+// Scheme 返回地址的协议名称。
+func (b *Builder) Scheme() string {
+	return Scheme
+}
+
+// Build 创建解析 target 中服务名称的解析器。
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{
+		b:       b,
+		cc:      cc,
+		service: target.Endpoint,
+		cancel:  cancel,
+		resolve: make(chan struct{}, 1),
+	}
+	r.wg.Add(1)
+	go r.watch(ctx)
+	return r, nil
+}
+
+type discoveryResolver struct {
+	b       *Builder
+	cc      resolver.ClientConn
+	service string
+	cancel  context.CancelFunc
+	resolve chan struct{}
+	wg      sync.WaitGroup
+}
+
+// ResolveNow 立即刷新服务实例。
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+// Close 停止刷新服务实例。
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *discoveryResolver) watch(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.b.interval)
+	defer ticker.Stop()
+	for {
+		r.update(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.resolve:
+		}
+	}
+}
+
+func (r *discoveryResolver) update(ctx context.Context) {
+
+	instances, err := r.b.client.Instances(ctx, r.service)
+	if err != nil {
+		r.cc.ReportError(err)
+		return
+	}
+
+	instances = discovery.Filter(instances, discovery.SchemeGrpc)
+	if len(instances) == 0 {
+		r.cc.ReportError(discovery.ErrNoInstance)
+		return
+	}
+
+	var state resolver.State
+	for _, i := range instances {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: i.Address()})
+	}
+	if err = r.cc.UpdateState(state); err != nil {
+		r.cc.ReportError(err)
+	}
+}
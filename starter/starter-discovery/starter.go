@@ -0,0 +1,48 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterDiscovery
+
+import (
+	"github.com/go-spring/spring-core/discovery"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/starter-discovery/consul"
+	"github.com/go-spring/starter-discovery/etcd"
+	"github.com/go-spring/starter-discovery/nacos"
+	"github.com/go-spring/starter-discovery/resolver"
+)
+
+func init() {
+
+	gs.Provide(consul.New, "${discovery.consul}").
+		Export((*discovery.Registry)(nil), (*discovery.Client)(nil)).
+		On(cond.OnProperty("discovery.type", cond.HavingValue("consul"), cond.MatchIfMissing()))
+	gs.Provide(etcd.New, "${discovery.etcd}").
+		Destroy((*etcd.Registry).Close).
+		Export((*discovery.Registry)(nil), (*discovery.Client)(nil)).
+		On(cond.OnProperty("discovery.type", cond.HavingValue("etcd")))
+	gs.Provide(nacos.New, "${discovery.nacos}").
+		Export((*discovery.Registry)(nil), (*discovery.Client)(nil)).
+		On(cond.OnProperty("discovery.type", cond.HavingValue("nacos")))
+
+	gs.Provide(discovery.NewRegistrar, "", "${discovery}").
+		On(cond.OnProperty("discovery.register", cond.HavingValue("true"), cond.MatchIfMissing()))
+
+	gs.Provide(discovery.NewRoundRobin, "").
+		Export((*discovery.Balancer)(nil))
+	gs.Provide(resolver.NewBuilder, "", "${discovery.grpc.refresh-interval:=10s}")
+}
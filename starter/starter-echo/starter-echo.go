@@ -26,6 +26,25 @@ import (
 
 func init() {
 	gs.Provide(func(config StarterCore.WebServerConfig) web.Container {
-		return SpringEcho.NewContainer(web.ContainerConfig(config))
+		return SpringEcho.NewContainer(containerConfig(config))
 	})
 }
+
+// containerConfig 将 Web 服务器配置转换为容器配置。
+func containerConfig(config StarterCore.WebServerConfig) web.ContainerConfig {
+	c := web.ContainerConfig{
+		IP:           config.IP,
+		Port:         config.Port,
+		EnableSSL:    config.EnableSSL,
+		KeyFile:      config.KeyFile,
+		CertFile:     config.CertFile,
+		BasePath:     config.BasePath,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		ReusePort:    config.ReusePort,
+	}
+	for _, l := range config.Listeners {
+		c.Listeners = append(c.Listeners, web.ListenerConfig(l))
+	}
+	return c
+}
@@ -0,0 +1,12 @@
+module github.com/go-spring/starter-feature
+
+go 1.14
+
+require (
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/open-feature/go-sdk v1.10.0
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package openfeature 将 OpenFeature 客户端适配为 feature.Provider ，应用通过
+// openfeature.SetProvider 设置具体的 OpenFeature Provider ，例如 flagd 。
+package openfeature
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/feature"
+	"github.com/open-feature/go-sdk/pkg/openfeature"
+)
+
+// Provider 基于 OpenFeature 客户端的 feature.Provider 实现。
+type Provider struct {
+	client *openfeature.Client
+}
+
+// New 创建使用名为 name 的 OpenFeature 客户端的 Provider 。
+func New(name string) *Provider {
+	return &Provider{client: openfeature.NewClient(name)}
+}
+
+// Evaluate 实现 feature.Provider 接口，根据 def 的类型调用对应类型的求值方法，
+// 目标的 Key 作为 OpenFeature 的 targeting key 。
+func (p *Provider) Evaluate(ctx context.Context, flag string, def interface{}, t feature.Target) (interface{}, bool, error) {
+
+	attrs := make(map[string]interface{}, len(t.Attributes))
+	for k, v := range t.Attributes {
+		attrs[k] = v
+	}
+	evalCtx := openfeature.NewEvaluationContext(t.Key, attrs)
+
+	var (
+		value   interface{}
+		details openfeature.EvaluationDetails
+		err     error
+	)
+	switch v := def.(type) {
+	case bool:
+		var r openfeature.BooleanEvaluationDetails
+		r, err = p.client.BooleanValueDetails(ctx, flag, v, evalCtx)
+		value, details = r.Value, r.EvaluationDetails
+	case string:
+		var r openfeature.StringEvaluationDetails
+		r, err = p.client.StringValueDetails(ctx, flag, v, evalCtx)
+		value, details = r.Value, r.EvaluationDetails
+	case int64:
+		var r openfeature.IntEvaluationDetails
+		r, err = p.client.IntValueDetails(ctx, flag, v, evalCtx)
+		value, details = r.Value, r.EvaluationDetails
+	case float64:
+		var r openfeature.FloatEvaluationDetails
+		r, err = p.client.FloatValueDetails(ctx, flag, v, evalCtx)
+		value, details = r.Value, r.EvaluationDetails
+	default:
+		var r openfeature.InterfaceEvaluationDetails
+		r, err = p.client.ObjectValueDetails(ctx, flag, v, evalCtx)
+		value, details = r.Value, r.EvaluationDetails
+	}
+
+	if err != nil {
+		if details.ErrorCode == openfeature.FlagNotFoundCode {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return value, true, nil
+}
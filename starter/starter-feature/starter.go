@@ -0,0 +1,34 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterFeature
+
+import (
+	"github.com/go-spring/spring-core/feature"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/starter-feature/openfeature"
+	"github.com/go-spring/starter-feature/unleash"
+)
+
+func init() {
+	gs.Provide(unleash.New, "${feature.unleash}").
+		Export((*feature.Provider)(nil)).
+		On(cond.OnProperty("feature.provider", cond.HavingValue("unleash")))
+	gs.Provide(openfeature.New, "${feature.openfeature.client:=go-spring}").
+		Export((*feature.Provider)(nil)).
+		On(cond.OnProperty("feature.provider", cond.HavingValue("openfeature")))
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package unleash 通过 Unleash 的 Client API 拉取功能开关的配置并在本地计算开关
+// 的值，支持 default、userWithId、remoteAddress 以及各种灰度发布策略。
+package unleash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/feature"
+)
+
+// Config Unleash 配置，通常绑定到 feature.unleash 前缀的属性上。
+type Config struct {
+	URL        string        `value:"${url}"`                 // Unleash API 地址，例如 http://unleash:4242/api
+	AppName    string        `value:"${app-name:=go-spring}"` // 应用名称
+	InstanceID string        `value:"${instance-id:=}"`       // 实例 ID
+	APIToken   string        `value:"${api-token:=}"`         // 客户端 API token
+	Timeout    time.Duration `value:"${timeout:=5s}"`         // 请求超时时间
+}
+
+type constraint struct {
+	ContextName string   `json:"contextName"`
+	Operator    string   `json:"operator"`
+	Values      []string `json:"values"`
+}
+
+type strategy struct {
+	Name        string            `json:"name"`
+	Parameters  map[string]string `json:"parameters"`
+	Constraints []constraint      `json:"constraints"`
+}
+
+type toggle struct {
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	Strategies []strategy `json:"strategies"`
+}
+
+// Provider 基于 Unleash 的 feature.Provider 实现，开关配置在 Refresh 时拉取。
+type Provider struct {
+	config  *Config // 使用指针避免容器对其进行属性绑定
+	client  *http.Client
+	toggles atomic.Value // map[string]*toggle
+}
+
+// New Provider 的构造函数。
+func New(config Config) (*Provider, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("unleash url is required")
+	}
+	return &Provider{config: &config, client: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+// Refresh 拉取最新的开关配置。
+func (p *Provider) Refresh(ctx context.Context) error {
+
+	url := strings.TrimSuffix(p.config.URL, "/") + "/client/features"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("UNLEASH-APPNAME", p.config.AppName)
+	if p.config.InstanceID != "" {
+		req.Header.Set("UNLEASH-INSTANCEID", p.config.InstanceID)
+	}
+	if p.config.APIToken != "" {
+		req.Header.Set("Authorization", p.config.APIToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unleash returns %s: %s", resp.Status, b)
+	}
+
+	var r struct {
+		Features []*toggle `json:"features"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+
+	m := make(map[string]*toggle, len(r.Features))
+	for _, t := range r.Features {
+		m[t.Name] = t
+	}
+	p.toggles.Store(m)
+	return nil
+}
+
+// Evaluate 实现 feature.Provider 接口，Unleash 的开关只有打开和关闭两种值。
+func (p *Provider) Evaluate(ctx context.Context, flag string, def interface{}, t feature.Target) (interface{}, bool, error) {
+	m, _ := p.toggles.Load().(map[string]*toggle)
+	tg, ok := m[flag]
+	if !ok {
+		return nil, false, nil
+	}
+	return tg.enabled(t), true, nil
+}
+
+// enabled 开关打开并且任意一个策略匹配时返回 true ，没有策略时等同于 default 策略。
+func (tg *toggle) enabled(t feature.Target) bool {
+	if !tg.Enabled {
+		return false
+	}
+	if len(tg.Strategies) == 0 {
+		return true
+	}
+	for _, s := range tg.Strategies {
+		if s.matches(tg.Name, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *strategy) matches(flag string, t feature.Target) bool {
+
+	for _, c := range s.Constraints {
+		if !c.matches(t) {
+			return false
+		}
+	}
+
+	switch s.Name {
+	case "default":
+		return true
+	case "userWithId":
+		return t.Key != "" && contains(split(s.Parameters["userIds"]), t.Key)
+	case "remoteAddress":
+		ip := t.Attributes["remoteAddress"]
+		return ip != "" && contains(split(s.Parameters["IPs"]), ip)
+	case "flexibleRollout":
+		return rollout(s.Parameters["rollout"], s.Parameters["groupId"], flag, stickiness(s.Parameters["stickiness"], t))
+	case "gradualRolloutUserId":
+		if t.Key == "" {
+			return false
+		}
+		return rollout(s.Parameters["percentage"], s.Parameters["groupId"], flag, t.Key)
+	case "gradualRolloutSessionId":
+		id := t.Attributes["sessionId"]
+		if id == "" {
+			return false
+		}
+		return rollout(s.Parameters["percentage"], s.Parameters["groupId"], flag, id)
+	case "gradualRolloutRandom":
+		return rollout(s.Parameters["percentage"], "", flag, "")
+	}
+	return false // 不支持的策略
+}
+
+func (c *constraint) matches(t feature.Target) bool {
+	var v string
+	switch c.ContextName {
+	case "userId":
+		v = t.Key
+	default:
+		v = t.Attributes[c.ContextName]
+	}
+	switch c.Operator {
+	case "IN":
+		return contains(c.Values, v)
+	case "NOT_IN":
+		return !contains(c.Values, v)
+	}
+	return false // 不支持的操作符
+}
+
+// stickiness 返回灰度发布时用于分桶的值，没有可用的值时返回空字符串表示随机。
+func stickiness(name string, t feature.Target) string {
+	switch name {
+	case "", "default":
+		if t.Key != "" {
+			return t.Key
+		}
+		return t.Attributes["sessionId"]
+	case "userId":
+		return t.Key
+	case "random":
+		return ""
+	}
+	return t.Attributes[name]
+}
+
+// rollout 判断 id 是否落在百分比 percentage 内，id 为空时随机决定。
+func rollout(percentage, groupID, flag, id string) bool {
+	n, err := strconv.Atoi(percentage)
+	if err != nil || n <= 0 {
+		return false
+	}
+	if groupID == "" {
+		groupID = flag
+	}
+	if id == "" {
+		return rand.Intn(100) < n
+	}
+	return int(normalize(groupID+":"+id)) <= n
+}
+
+// normalize 返回 1 到 100 之间的分桶值，与 Unleash 官方 SDK 的算法一致。
+func normalize(s string) uint32 {
+	return murmur3([]byte(s), 0)%100 + 1
+}
+
+// murmur3 32 位的 MurmurHash3 算法。
+func murmur3(data []byte, seed uint32) uint32 {
+
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+		h = h<<13 | h>>19
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+func split(s string) []string {
+	var r []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/go-spring/spring-core v1.1.0-alpha
 	github.com/go-spring/spring-gin v1.1.0-alpha
+	github.com/go-spring/spring-stl v1.1.0-alpha
 	github.com/go-spring/starter-core v1.1.0-alpha
 	github.com/go-spring/starter-web v1.1.0-alpha
 )
@@ -12,6 +13,7 @@ require (
 //replace (
 //	github.com/go-spring/spring-core => ../../spring/spring-core
 //	github.com/go-spring/spring-gin => ../../spring/spring-gin
+//	github.com/go-spring/spring-stl => ../../spring/spring-stl
 //	github.com/go-spring/starter-core => ../starter-core
 //	github.com/go-spring/starter-web => ../starter-web
 //)
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterGin
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/starter-core"
+)
+
+func TestContainerConfig(t *testing.T) {
+
+	p := conf.New()
+	p.Set("web.server.port", "9090")
+	p.Set("web.server.reuse-port", "4")
+	p.Set("web.server.listeners[0].address", ":8443")
+	p.Set("web.server.listeners[0].ssl", "true")
+	p.Set("web.server.listeners[1].network", "unix")
+	p.Set("web.server.listeners[1].address", "/tmp/web.sock")
+	p.Set("web.server.listeners[1].mode", "0660")
+
+	var config StarterCore.WebServerConfig
+	assert.Nil(t, p.Bind(&config))
+
+	c := containerConfig(config)
+	assert.Equal(t, c.Port, 9090)
+	assert.Equal(t, c.ReusePort, 4)
+	assert.Equal(t, c.Listeners, []web.ListenerConfig{
+		{Network: "tcp", Address: ":8443", SSL: true},
+		{Network: "unix", Address: "/tmp/web.sock", Mode: "0660"},
+	})
+}
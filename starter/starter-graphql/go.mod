@@ -0,0 +1,14 @@
+module github.com/go-spring/starter-graphql
+
+go 1.14
+
+require (
+	github.com/99designs/gqlgen v0.14.0
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/go-spring/spring-stl v1.1.0-alpha
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//	github.com/go-spring/spring-stl => ../../spring/spring-stl
+//)
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graphql 将 gqlgen 生成的 GraphQL 服务挂载到 Web 服务器上，并为每个
+// 请求创建独立的 DataLoader 。
+package graphql
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+	"github.com/go-spring/spring-stl/knife"
+)
+
+// Config GraphQL 服务配置，绑定到 graphql 前缀的属性上。
+type Config struct {
+	Path            string `value:"${path:=/graphql}"`                       // GraphQL 服务的路径
+	Playground      bool   `value:"${playground:=false}"`                    // 是否开启 Playground 页面
+	PlaygroundPath  string `value:"${playground-path:=/graphql/playground}"` // Playground 页面的路径
+	Introspection   bool   `value:"${introspection:=true}"`                  // 是否允许查询 schema
+	ComplexityLimit int    `value:"${complexity-limit:=0}"`                  // 查询复杂度的上限，0 表示不限制
+}
+
+// LoaderFactory DataLoader 的工厂，以 bean 的形式导出后每个请求都会创建一个
+// 独立的 DataLoader，避免不同请求之间共享缓存。
+type LoaderFactory interface {
+
+	// LoaderName 返回 DataLoader 的名称。
+	LoaderName() string
+
+	// NewLoader 创建请求 ctx 使用的 DataLoader 。
+	NewLoader(ctx context.Context) interface{}
+}
+
+// Loader 返回当前请求名为 name 的 DataLoader，不存在时返回 nil 。
+func Loader(ctx context.Context, name string) interface{} {
+	return knife.Get(ctx, loaderKey(name))
+}
+
+func loaderKey(name string) string {
+	return "graphql.loader::" + name
+}
+
+// Server 将 GraphQL 服务挂载到 Web 服务器上，请求和普通的 Web 请求一样经过
+// Web 服务器的过滤器。
+type Server struct {
+	Router    web.Router               `autowire:""`
+	Schema    graphql.ExecutableSchema `autowire:""`
+	Factories []LoaderFactory          `autowire:""`
+
+	config *Config // 使用指针避免容器对其进行属性绑定
+}
+
+// NewServer Server 的构造函数。
+func NewServer(config Config) *Server {
+	return &Server{config: &config}
+}
+
+// OnInit 挂载 GraphQL 服务和 Playground 页面。
+func (s *Server) OnInit() {
+
+	srv := handler.New(s.Schema)
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	if s.config.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+	if s.config.ComplexityLimit > 0 {
+		srv.Use(extension.FixedComplexityLimit(s.config.ComplexityLimit))
+	}
+
+	h := s.withLoaders(srv)
+	s.Router.HandleRequest(web.MethodGetPost, s.config.Path, web.WrapH(h))
+	log.Infof("graphql mounted at %s", s.config.Path)
+
+	if s.config.Playground {
+		p := playground.Handler("GraphQL", s.config.Path)
+		s.Router.HandleGet(s.config.PlaygroundPath, web.WrapH(p))
+	}
+}
+
+// withLoaders 在请求开始时创建所有的 DataLoader 并保存到请求的 knife 缓存中，
+// Web 服务器在创建 web.Context 时已经为每个请求创建了 knife 缓存。
+func (s *Server) withLoaders(h http.Handler) http.Handler {
+	if len(s.Factories) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		for _, f := range s.Factories {
+			knife.Set(ctx, loaderKey(f.LoaderName()), f.NewLoader(ctx))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
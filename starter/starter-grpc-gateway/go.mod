@@ -0,0 +1,12 @@
+module github.com/go-spring/starter-grpc-gateway
+
+go 1.14
+
+require (
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.7.3
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
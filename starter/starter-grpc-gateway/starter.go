@@ -0,0 +1,100 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterGrpcGateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-spring/spring-core/grpc"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/web"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+func init() {
+	gs.Object(new(Gateway)).On(cond.OnBean((*grpc.Gateway)(nil)))
+}
+
+// Config gRPC-Gateway 配置。
+type Config struct {
+	Prefix string `value:"${prefix:=/}"` // 网关挂载的路径前缀
+}
+
+// Gateway 将通过 gs.GrpcGateway 注册的网关挂载到 Web 服务器上，REST 请求和普通
+// 的 Web 请求一样经过 Web 服务器的过滤器，然后以进程内调用的方式转发给同名的
+// gRPC 服务提供者。
+type Gateway struct {
+	Router   web.Router               `autowire:""`
+	Servers  map[string]*grpc.Server  `autowire:""`
+	Gateways map[string]*grpc.Gateway `autowire:""`
+	Config   Config                   `value:"${grpc.gateway}"`
+
+	mux *runtime.ServeMux
+}
+
+// OnInit 注册所有的网关并将其挂载到 Web 服务器上。
+func (g *Gateway) OnInit() error {
+
+	g.mux = runtime.NewServeMux()
+	ctx := context.Background()
+
+	for serviceName, gateway := range g.Gateways {
+		server, ok := g.Servers[serviceName]
+		if !ok {
+			return fmt.Errorf("can't find grpc server %q for gateway", serviceName)
+		}
+		if err := register(ctx, g.mux, gateway, server); err != nil {
+			return fmt.Errorf("register grpc gateway %q error: %w", serviceName, err)
+		}
+		log.Infof("grpc gateway %s mounted at %s", serviceName, g.Config.Prefix)
+	}
+
+	prefix := strings.TrimSuffix(g.Config.Prefix, "/")
+	var h http.Handler = g.mux
+	if prefix != "" {
+		h = http.StripPrefix(prefix, h)
+	}
+	g.Router.HandleRequest(web.MethodAny, prefix+"/*", web.WrapH(h))
+	return nil
+}
+
+// register 调用 grpc-gateway 自动生成的 RegisterXxxHandlerServer 函数。
+func register(ctx context.Context, mux *runtime.ServeMux, gateway *grpc.Gateway, server *grpc.Server) error {
+
+	fn := reflect.ValueOf(gateway.Register)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 3 || t.NumOut() != 1 {
+		return fmt.Errorf("%s should be func(context.Context, *runtime.ServeMux, XxxServer) error", t)
+	}
+
+	service := reflect.ValueOf(server.Service)
+	if !service.Type().AssignableTo(t.In(2)) {
+		return fmt.Errorf("%s can't be used as %s", service.Type(), t.In(2))
+	}
+
+	out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(mux), service})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
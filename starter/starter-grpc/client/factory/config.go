@@ -0,0 +1,137 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	PickFirst  = "pick_first"  // 使用第一个可用的地址
+	RoundRobin = "round_robin" // 在所有地址之间轮询
+)
+
+// RetryConfig 客户端重试策略的配置，grpc-go 需要设置环境变量 GRPC_GO_RETRY=on
+// 才会启用服务配置中的重试策略。
+type RetryConfig struct {
+	MaxAttempts          int           `value:"${max-attempts:=1}"`        // 最大调用次数，包含首次调用，小于 2 表示不重试
+	InitialBackoff       time.Duration `value:"${initial-backoff:=100ms}"` // 首次重试的等待时间
+	MaxBackoff           time.Duration `value:"${max-backoff:=1s}"`        // 重试等待时间的上限
+	BackoffMultiplier    float64       `value:"${backoff-multiplier:=2}"`  // 每次重试等待时间的倍数
+	RetryableStatusCodes []string      `value:"${retryable-status-codes}"` // 可以重试的响应码，为空时只重试 UNAVAILABLE
+}
+
+// KeepaliveConfig 客户端 keepalive 的配置。
+type KeepaliveConfig struct {
+	Time                time.Duration `value:"${time:=0}"`                      // 连接空闲多久后发送 ping ，0 表示不发送
+	Timeout             time.Duration `value:"${timeout:=20s}"`                 // 等待 ping 响应的超时时间
+	PermitWithoutStream bool          `value:"${permit-without-stream:=false}"` // 没有活动的流时是否发送 ping
+}
+
+// ClientConfig 客户端的配置，通常绑定到 grpc.client.<name> 前缀的属性上。
+type ClientConfig struct {
+	LoadBalancing  string          `value:"${load-balancing:=pick_first}"` // 负载均衡策略，pick_first 或者 round_robin
+	Retry          RetryConfig     `value:"${retry}"`
+	Keepalive      KeepaliveConfig `value:"${keepalive}"`
+	MaxRecvMsgSize int             `value:"${max-recv-msg-size:=0}"` // 接收消息的最大字节数，0 表示使用默认值
+	MaxSendMsgSize int             `value:"${max-send-msg-size:=0}"` // 发送消息的最大字节数，0 表示使用默认值
+}
+
+// ServiceConfig 返回客户端配置对应的 gRPC 服务配置。
+func (c *ClientConfig) ServiceConfig() (string, error) {
+
+	switch c.LoadBalancing {
+	case PickFirst, RoundRobin:
+	default:
+		return "", fmt.Errorf("unsupported load balancing policy %q", c.LoadBalancing)
+	}
+
+	sc := map[string]interface{}{
+		"loadBalancingConfig": []interface{}{
+			map[string]interface{}{c.LoadBalancing: map[string]interface{}{}},
+		},
+	}
+
+	if r := c.Retry; r.MaxAttempts > 1 {
+		codes := []string{"UNAVAILABLE"}
+		if len(r.RetryableStatusCodes) > 0 {
+			codes = make([]string, len(r.RetryableStatusCodes))
+			for i, s := range r.RetryableStatusCodes {
+				codes[i] = strings.ToUpper(s)
+			}
+		}
+		sc["methodConfig"] = []interface{}{
+			map[string]interface{}{
+				"name": []interface{}{map[string]interface{}{}},
+				"retryPolicy": map[string]interface{}{
+					"maxAttempts":          r.MaxAttempts,
+					"initialBackoff":       duration(r.InitialBackoff),
+					"maxBackoff":           duration(r.MaxBackoff),
+					"backoffMultiplier":    r.BackoffMultiplier,
+					"retryableStatusCodes": codes,
+				},
+			},
+		}
+	}
+
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// duration 返回服务配置格式的时间长度，例如 0.1s 。
+func duration(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// DialOptions 返回客户端配置对应的连接选项。
+func (c *ClientConfig) DialOptions() ([]grpc.DialOption, error) {
+
+	sc, err := c.ServiceConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithDefaultServiceConfig(sc)}
+
+	if k := c.Keepalive; k.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                k.Time,
+			Timeout:             k.Timeout,
+			PermitWithoutStream: k.PermitWithoutStream,
+		}))
+	}
+
+	var callOpts []grpc.CallOption
+	if c.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	return opts, nil
+}
@@ -17,11 +17,27 @@
 package factory
 
 import (
+	"fmt"
+
+	"github.com/go-spring/spring-core/resilience"
 	"github.com/go-spring/starter-core"
 	"google.golang.org/grpc"
 )
 
-// NewClient 根据配置创建 grpc.ClientConnInterface 对象
-func NewClient(config StarterCore.GrpcEndpointConfig) (grpc.ClientConnInterface, error) {
-	return grpc.Dial(config.Address, grpc.WithInsecure())
+// NewClient 根据配置创建 grpc.ClientConnInterface 对象，调用时检查 ctx 的时间
+// 预算，resilience.backends 下配置了同名后端时为客户端添加容错拦截器，负载均衡、
+// 重试策略、keepalive 以及消息大小等选项来自 grpc.client.<endpoint> 前缀的属性。
+func NewClient(endpoint string, config StarterCore.GrpcEndpointConfig, client ClientConfig, r *resilience.Registry) (grpc.ClientConnInterface, error) {
+	opts, err := client.DialOptions()
+	if err != nil {
+		return nil, fmt.Errorf("grpc client %s: %w", endpoint, err)
+	}
+	interceptors := []grpc.UnaryClientInterceptor{DeadlineInterceptor()}
+	if r != nil {
+		if b, ok := r.Lookup(endpoint); ok {
+			interceptors = append(interceptors, UnaryClientInterceptor(b))
+		}
+	}
+	opts = append(opts, grpc.WithInsecure(), grpc.WithChainUnaryInterceptor(interceptors...))
+	return grpc.Dial(config.Address, opts...)
 }
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package factory
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/deadline"
+	"github.com/go-spring/spring-core/resilience"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor 返回带有容错保护的 gRPC 客户端拦截器，只有表示后端
+// 故障的响应码才会计入断路器的失败次数并且被重试。
+func UnaryClientInterceptor(b *resilience.Backend) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return b.Execute(ctx, func(ctx context.Context) error {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || failure(err) {
+				return err
+			}
+			return resilience.Ignore(err)
+		})
+	}
+}
+
+// DeadlineInterceptor 返回检查时间预算的 gRPC 客户端拦截器，预算已经用完的
+// 调用直接返回 DeadlineExceeded 而不会发出，gRPC 会将 ctx 剩余的预算作为调用的
+// 超时时间传递给服务端。
+func DeadlineInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := deadline.Check(ctx); err != nil {
+			return status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// failure 判断 gRPC 错误是否是后端故障。
+func failure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unknown, codes.Aborted:
+		return true
+	}
+	return false
+}
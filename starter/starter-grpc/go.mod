@@ -6,6 +6,7 @@ require (
 	github.com/go-spring/spring-core v1.1.0-alpha
 	github.com/go-spring/spring-stl v1.1.0-alpha
 	github.com/go-spring/starter-core v1.1.0-alpha
+	github.com/golang/protobuf v1.5.2 // indirect
 	golang.org/x/lint v0.0.0-20190313153728-d0100b6bd8b3 // indirect
 	golang.org/x/tools v0.0.0-20190524140312-2c0ae7006135 // indirect
 	google.golang.org/grpc v1.31.0
@@ -25,27 +25,58 @@ import (
 
 	SpringGrpc "github.com/go-spring/spring-core/grpc"
 	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/health"
 	"github.com/go-spring/spring-core/log"
 	"github.com/go-spring/spring-stl/util"
 	"github.com/go-spring/starter-core"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 // Starter gRPC 服务器启动器
 type Starter struct {
-	config  StarterCore.GrpcServerConfig
-	server  *grpc.Server
-	Servers map[string]*SpringGrpc.Server `autowire:""`
+	config   StarterCore.GrpcServerConfig
+	services ServiceConfig
+	server   *grpc.Server
+	health   *healthServer
+	Servers  map[string]*SpringGrpc.Server `autowire:""`
+	Checker  *health.Checker               `autowire:"?"`
 }
 
 // NewStarter Starter 的构造函数
-func NewStarter(config StarterCore.GrpcServerConfig) *Starter {
+func NewStarter(config StarterCore.GrpcServerConfig, services ServiceConfig) *Starter {
 	return &Starter{
-		config: config,
-		server: grpc.NewServer(),
+		config:   config,
+		services: services,
+		server:   grpc.NewServer(),
 	}
 }
 
+// registerServices 根据配置注册健康检查服务和服务器反射服务。
+func (starter *Starter) registerServices() {
+	if starter.services.Health {
+		starter.health = &healthServer{
+			checker:  starter.Checker,
+			services: starter.serviceNames,
+			interval: starter.services.WatchInterval,
+		}
+		grpc_health_v1.RegisterHealthServer(starter.server, starter.health)
+	}
+	if starter.services.Reflection {
+		reflection.Register(starter.server)
+	}
+}
+
+// serviceNames 返回服务器上注册的所有服务的名称。
+func (starter *Starter) serviceNames() map[string]bool {
+	ret := make(map[string]bool)
+	for service := range starter.server.GetServiceInfo() {
+		ret[service] = true
+	}
+	return ret
+}
+
 func (starter *Starter) OnStartApp(ctx gs.AppContext) {
 
 	server := reflect.ValueOf(starter.server)
@@ -59,8 +90,13 @@ func (starter *Starter) OnStartApp(ctx gs.AppContext) {
 		fn.Call([]reflect.Value{server, service})
 	}
 
+	starter.registerServices()
+
 	for service, info := range starter.server.GetServiceInfo() {
-		srv := srvMap[service]
+		srv, ok := srvMap[service]
+		if !ok {
+			continue
+		}
 		for _, method := range info.Methods {
 			m, _ := srv.Type().MethodByName(method.Name)
 			fnPtr := m.Func.Pointer()
@@ -82,5 +118,8 @@ func (starter *Starter) OnStartApp(ctx gs.AppContext) {
 }
 
 func (starter *Starter) OnStopApp(ctx gs.AppContext) {
+	if starter.health != nil {
+		starter.health.shutdown()
+	}
 	starter.server.GracefulStop()
 }
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package factory
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/health"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceConfig gRPC 服务器内置服务的配置。
+type ServiceConfig struct {
+	Health        bool          `value:"${grpc.server.health.enabled:=true}"`      // 是否注册 grpc.health.v1 服务
+	WatchInterval time.Duration `value:"${grpc.server.health.watch-interval:=5s}"` // Watch 接口检查状态变化的间隔
+	Reflection    bool          `value:"${grpc.server.reflection.enabled:=true}"`  // 是否注册服务器反射服务
+}
+
+// healthServer 基于健康检查汇总结果实现的 grpc.health.v1 服务。服务名为空时
+// 返回全部指示器的汇总状态，服务名为 liveness 或者 readiness 时返回对应分组的
+// 汇总状态，服务名为已注册的 gRPC 服务时返回 readiness 分组的汇总状态。
+type healthServer struct {
+	checker  *health.Checker // 为空时总是返回 SERVING
+	services func() map[string]bool
+	interval time.Duration
+	stopped  int32
+}
+
+// shutdown 将所有服务标记为 NOT_SERVING ，在服务器优雅退出之前调用。
+func (s *healthServer) shutdown() {
+	atomic.StoreInt32(&s.stopped, 1)
+}
+
+func (s *healthServer) status(ctx context.Context, service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, bool) {
+
+	group := ""
+	switch service {
+	case "":
+	case health.GroupLiveness, health.GroupReadiness:
+		group = service
+	default:
+		if !s.services()[service] {
+			return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, false
+		}
+		group = health.GroupReadiness
+	}
+
+	if atomic.LoadInt32(&s.stopped) == 1 {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, true
+	}
+	if s.checker == nil {
+		return grpc_health_v1.HealthCheckResponse_SERVING, true
+	}
+
+	switch s.checker.Check(ctx, group).Status {
+	case health.StatusDown, health.StatusOutOfService:
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, true
+	default:
+		return grpc_health_v1.HealthCheckResponse_SERVING, true
+	}
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	st, ok := s.status(ctx, req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: st}, nil
+}
+
+// Watch 立即返回当前状态，之后按照固定的间隔检查，状态变化时发送新的状态。
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		st, _ := s.status(ctx, req.GetService())
+		if st != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: st}); err != nil {
+				return status.Error(codes.Canceled, "stream has ended")
+			}
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-ticker.C:
+		}
+	}
+}
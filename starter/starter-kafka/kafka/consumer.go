@@ -0,0 +1,327 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+)
+
+const (
+	HeaderOriginalTopic = "x-original-topic" // 死信消息的原始主题
+	HeaderException     = "x-exception"      // 死信消息的消费错误
+)
+
+// handler 订阅选项已经确定的消费者。
+type handler struct {
+	c    mq.Consumer
+	opts Options
+}
+
+// group 一个消费组，组内所有订阅共享同一个 sarama.ConsumerGroup 。
+type group struct {
+	l      *Listener
+	name   string
+	cg     sarama.ConsumerGroup
+	topics map[string][]*handler
+}
+
+// Listener 按照消费组管理所有的消费者。
+type Listener struct {
+	client   sarama.Client
+	config   *ConsumerConfig
+	producer mq.Producer // 用于发送死信消息
+	groups   map[string]*group
+}
+
+// NewListener Listener 的构造函数。
+func NewListener(client sarama.Client, config ConsumerConfig, producer mq.Producer) *Listener {
+	return &Listener{
+		client:   client,
+		config:   &config,
+		producer: producer,
+		groups:   make(map[string]*group),
+	}
+}
+
+// Add 添加消费者，消费者可以通过实现 Optional 接口指定订阅选项。
+func (l *Listener) Add(c mq.Consumer) error {
+
+	var opts Options
+	if o, ok := c.(Optional); ok {
+		opts = o.Options()
+	}
+
+	if opts.Group == "" {
+		opts.Group = l.config.Group
+	}
+	if opts.Group == "" {
+		return fmt.Errorf("no consumer group for topics %v", c.Topics())
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = l.config.Concurrency
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = l.config.MaxRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = l.config.Backoff
+	}
+
+	g, ok := l.groups[opts.Group]
+	if !ok {
+		g = &group{l: l, name: opts.Group, topics: make(map[string][]*handler)}
+		l.groups[opts.Group] = g
+	}
+
+	h := &handler{c: c, opts: opts}
+	for _, topic := range c.Topics() {
+		g.topics[topic] = append(g.topics[topic], h)
+	}
+	return nil
+}
+
+// BindConsumer 实现 mq.Binder 接口，将消费者绑定到主题 destination 。
+func (l *Listener) BindConsumer(destination string, group string, c mq.Consumer) error {
+	return l.Add(&subscription{
+		topics:  []string{destination},
+		fn:      c.Consume,
+		options: Options{Group: group},
+	})
+}
+
+// BindProducer 实现 mq.Binder 接口，返回发送到主题 destination 的消息生产者。
+func (l *Listener) BindProducer(destination string) (mq.Producer, error) {
+	return NewProducer(l.client, destination)
+}
+
+// Start 为每个消费组启动一个消费循环。
+func (l *Listener) Start(ctx gs.AppContext) error {
+	for _, g := range l.groups {
+		cg, err := sarama.NewConsumerGroupFromClient(g.name, l.client)
+		if err != nil {
+			return err
+		}
+		g.cg = cg
+		ctx.Go(g.run)
+	}
+	return nil
+}
+
+// Stop 关闭所有的消费组。
+func (l *Listener) Stop() {
+	for _, g := range l.groups {
+		if g.cg == nil {
+			continue
+		}
+		if err := g.cg.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func (g *group) run(ctx context.Context) {
+
+	var topics []string
+	for topic := range g.topics {
+		topics = append(topics, topic)
+	}
+
+	log.Infof("kafka group %s subscribe %v", g.name, topics)
+	for {
+		// 发生 rebalance 时 Consume 会返回，需要重新调用以获取新的分区。
+		err := g.cg.Consume(ctx, topics, g)
+		if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return
+		}
+		if err != nil {
+			log.Errorf("kafka group %s error: %v", g.name, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (g *group) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+func (g *group) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim 消费一个分区的消息。并发数大于 1 时按照消息的 key 将消息分发到
+// 不同的协程，只有在之前的消息全部消费完成后才会提交位点。
+func (g *group) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+
+	handlers := g.topics[claim.Topic()]
+
+	n := 1
+	for _, h := range handlers {
+		if h.opts.Concurrency > n {
+			n = h.opts.Concurrency
+		}
+	}
+
+	ctx := sess.Context()
+
+	if n == 1 {
+		for msg := range claim.Messages() {
+			g.handle(ctx, handlers, msg)
+			sess.MarkMessage(msg, "")
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan *sarama.ConsumerMessage, n)
+	queues := make([]chan *sarama.ConsumerMessage, n)
+	for i := range queues {
+		queues[i] = make(chan *sarama.ConsumerMessage)
+		wg.Add(1)
+		go func(q chan *sarama.ConsumerMessage) {
+			defer wg.Done()
+			for msg := range q {
+				g.handle(ctx, handlers, msg)
+				done <- msg
+			}
+		}(queues[i])
+	}
+
+	var pending []int64
+	acked := make(map[int64]bool)
+	ack := func(msg *sarama.ConsumerMessage) {
+		acked[msg.Offset] = true
+		for len(pending) > 0 && acked[pending[0]] {
+			delete(acked, pending[0])
+			sess.MarkOffset(claim.Topic(), claim.Partition(), pending[0]+1, "")
+			pending = pending[1:]
+		}
+	}
+
+	messages := claim.Messages()
+	for messages != nil || len(pending) > 0 {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				messages = nil
+				for _, q := range queues {
+					close(q)
+				}
+				continue
+			}
+			pending = append(pending, msg.Offset)
+			q := queues[partitionOf(msg, n)]
+			for sent := false; !sent; {
+				select {
+				case q <- msg:
+					sent = true
+				case m := <-done:
+					ack(m)
+				}
+			}
+		case m := <-done:
+			ack(m)
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// partitionOf 返回消息分配到的协程序号，相同 key 的消息总是分配到同一个协程。
+func partitionOf(msg *sarama.ConsumerMessage, n int) int {
+	if len(msg.Key) == 0 {
+		return int(msg.Offset % int64(n))
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(msg.Key)
+	return int(h.Sum32() % uint32(n))
+}
+
+func (g *group) handle(ctx context.Context, handlers []*handler, msg *sarama.ConsumerMessage) {
+	m := toMessage(msg)
+	for _, h := range handlers {
+		if err := g.consume(ctx, h, m); err != nil {
+			g.deadLetter(ctx, h, m, err)
+		}
+	}
+}
+
+// consume 消费消息，失败时按照指数退避的方式进行重试。
+func (g *group) consume(ctx context.Context, h *handler, msg mq.Message) error {
+	backoff := h.opts.Backoff
+	for i := 0; ; i++ {
+		err := h.c.Consume(ctx, msg)
+		if err == nil || i >= h.opts.MaxRetries {
+			return err
+		}
+		log.Ctx(ctx).Warnf("kafka consume %s retry %d error: %v", msg.Topic(), i+1, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; g.l.config.MaxBackoff > 0 && backoff > g.l.config.MaxBackoff {
+			backoff = g.l.config.MaxBackoff
+		}
+	}
+}
+
+// deadLetter 将消费失败的消息发送到死信主题，未设置死信主题时丢弃该消息。
+func (g *group) deadLetter(ctx context.Context, h *handler, msg mq.Message, err error) {
+
+	if h.opts.DeadLetter == "" || g.l.producer == nil {
+		log.Ctx(ctx).Errorf("kafka consume %s error: %v, message dropped", msg.Topic(), err)
+		return
+	}
+
+	m := mq.NewMessage().
+		WithTopic(h.opts.DeadLetter).
+		WithID(msg.ID()).
+		WithBody(msg.Body())
+	for k, v := range msg.Extra() {
+		m.WithExtra(k, v)
+	}
+	m.WithExtra(HeaderOriginalTopic, msg.Topic())
+	m.WithExtra(HeaderException, err.Error())
+
+	if e := g.l.producer.SendMessage(ctx, m); e != nil {
+		log.Ctx(ctx).Errorf("kafka send dead letter %s error: %v", h.opts.DeadLetter, e)
+	}
+}
+
+func toMessage(msg *sarama.ConsumerMessage) mq.Message {
+	m := mq.NewMessage().
+		WithTopic(msg.Topic).
+		WithID(string(msg.Key)).
+		WithBody(msg.Value)
+	for _, h := range msg.Headers {
+		m.WithExtra(string(h.Key), string(h.Value))
+	}
+	return m
+}
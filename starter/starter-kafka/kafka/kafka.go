@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kafka 基于 sarama 实现了 mq 包的消息生产者和消费者，支持消费组、
+// 分区内并发消费、失败重试以及死信主题。
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// Config Kafka 客户端配置，绑定到 kafka 前缀的属性上。
+type Config struct {
+	Brokers  []string       `value:"${brokers}"`              // 节点地址，默认为 127.0.0.1:9092
+	Version  string         `value:"${version:=2.1.0}"`       // Kafka 协议版本
+	ClientID string         `value:"${client-id:=go-spring}"` // 客户端标识
+	Consumer ConsumerConfig `value:"${consumer}"`
+	Producer ProducerConfig `value:"${producer}"`
+}
+
+// ConsumerConfig 消费者配置，订阅时未指定的选项使用这里的值。
+type ConsumerConfig struct {
+	Group         string        `value:"${group:=}"`                // 默认消费组
+	InitialOffset string        `value:"${initial-offset:=newest}"` // 没有提交过位点时从 newest 还是 oldest 开始消费
+	Concurrency   int           `value:"${concurrency:=1}"`         // 每个分区的并发消费数
+	MaxRetries    int           `value:"${max-retries:=3}"`         // 消费失败时的最大重试次数
+	Backoff       time.Duration `value:"${backoff:=100ms}"`         // 首次重试的等待时间，之后每次翻倍
+	MaxBackoff    time.Duration `value:"${max-backoff:=10s}"`       // 重试等待时间的上限
+}
+
+// ProducerConfig 生产者配置。
+type ProducerConfig struct {
+	RequiredAcks int           `value:"${required-acks:=-1}"` // 0 不等待，1 等待 leader，-1 等待所有副本
+	MaxRetries   int           `value:"${max-retries:=3}"`    // 发送失败时的最大重试次数
+	Timeout      time.Duration `value:"${timeout:=10s}"`      // 发送超时
+}
+
+// NewClient 创建 Kafka 客户端。
+func NewClient(config Config) (sarama.Client, error) {
+
+	brokers := config.Brokers
+	if len(brokers) == 0 {
+		brokers = []string{"127.0.0.1:9092"}
+	}
+
+	version, err := sarama.ParseKafkaVersion(config.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	c := sarama.NewConfig()
+	c.Version = version
+	c.ClientID = config.ClientID
+
+	switch config.Consumer.InitialOffset {
+	case "newest":
+		c.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case "oldest":
+		c.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		return nil, fmt.Errorf("unsupported initial offset %q", config.Consumer.InitialOffset)
+	}
+
+	c.Producer.RequiredAcks = sarama.RequiredAcks(config.Producer.RequiredAcks)
+	c.Producer.Retry.Max = config.Producer.MaxRetries
+	c.Producer.Timeout = config.Producer.Timeout
+	c.Producer.Return.Successes = true
+
+	log.Infof("open kafka %v", brokers)
+	return sarama.NewClient(brokers, c)
+}
+
+// CloseClient 关闭 Kafka 客户端。
+func CloseClient(client sarama.Client) {
+	log.Info("close kafka")
+	if err := client.Close(); err != nil {
+		log.Error(err)
+	}
+}
+
+// Producer 基于 sarama.SyncProducer 的消息生产者，topic 不为空时消息总是发送
+// 到该主题，否则发送到消息自身的主题。
+type Producer struct {
+	p     sarama.SyncProducer
+	topic string
+}
+
+// NewProducer 创建消息生产者。
+func NewProducer(client sarama.Client, topic string) (*Producer, error) {
+	p, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+	return &Producer{p: p, topic: topic}, nil
+}
+
+// RegisterProducer 注册名为 name 的消息生产者 bean，topic 为该生产者固定发送的
+// 主题，可以为空，使用时通过 `autowire:"name"` 按名称注入。
+func RegisterProducer(name string, topic string) *gs.BeanDefinition {
+	return gs.Provide(NewProducer, "", arg.Value(topic)).
+		Name(name).
+		Destroy((*Producer).Close).
+		Export((*mq.Producer)(nil))
+}
+
+// SendMessage 同步发送消息。
+func (p *Producer) SendMessage(ctx context.Context, msg mq.Message) error {
+	m := p.toProducerMessage(msg)
+	_, _, err := p.p.SendMessage(m)
+	return err
+}
+
+func (p *Producer) toProducerMessage(msg mq.Message) *sarama.ProducerMessage {
+
+	topic := p.topic
+	if topic == "" {
+		topic = msg.Topic()
+	}
+
+	m := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(msg.Body()),
+	}
+
+	if id := msg.ID(); id != "" {
+		m.Key = sarama.StringEncoder(id)
+	}
+
+	for k, v := range msg.Extra() {
+		m.Headers = append(m.Headers, sarama.RecordHeader{
+			Key:   []byte(k),
+			Value: []byte(v),
+		})
+	}
+	return m
+}
+
+// Close 关闭消息生产者。
+func (p *Producer) Close() {
+	if err := p.p.Close(); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/starter-kafka/kafka"
+)
+
+func TestConfig_Bind(t *testing.T) {
+
+	p := conf.New()
+	var c kafka.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("kafka")))
+	assert.Equal(t, len(c.Brokers), 0)
+	assert.Equal(t, c.Version, "2.1.0")
+
+	p.Set("kafka.brokers[0]", "10.0.0.1:9092")
+	p.Set("kafka.brokers[1]", "10.0.0.2:9092")
+	p.Set("kafka.consumer.group", "orders")
+	p.Set("kafka.producer.timeout", "3s")
+	assert.Nil(t, p.Bind(&c, conf.Key("kafka")))
+	assert.Equal(t, c.Brokers, []string{"10.0.0.1:9092", "10.0.0.2:9092"})
+	assert.Equal(t, c.Consumer.Group, "orders")
+	assert.Equal(t, c.Producer.Timeout, 3*time.Second)
+}
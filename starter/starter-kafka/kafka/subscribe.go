@@ -0,0 +1,97 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// Handler 消息处理函数。
+type Handler func(ctx context.Context, msg mq.Message) error
+
+// Options 订阅选项，零值表示使用 kafka.consumer 前缀下的全局配置。
+type Options struct {
+	Group       string        // 消费组
+	Concurrency int           // 每个分区的并发消费数
+	MaxRetries  int           // 消费失败时的最大重试次数，负数表示不重试
+	Backoff     time.Duration // 首次重试的等待时间
+	DeadLetter  string        // 重试失败后消息转发的死信主题，为空时丢弃消息
+}
+
+// Optional 可以由 mq.Consumer 实现的可选接口，用于为导出方式注册的消费者指定
+// 订阅选项。
+type Optional interface {
+	Options() Options
+}
+
+// Option 订阅选项的设置函数。
+type Option func(*Options)
+
+// Group 设置消费组。
+func Group(group string) Option {
+	return func(o *Options) { o.Group = group }
+}
+
+// Concurrency 设置每个分区的并发消费数，相同 key 的消息总是被顺序消费。
+func Concurrency(n int) Option {
+	return func(o *Options) { o.Concurrency = n }
+}
+
+// Retry 设置消费失败时的最大重试次数和首次重试的等待时间。
+func Retry(maxRetries int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.MaxRetries = maxRetries
+		o.Backoff = backoff
+	}
+}
+
+// DeadLetter 设置死信主题。
+func DeadLetter(topic string) Option {
+	return func(o *Options) { o.DeadLetter = topic }
+}
+
+// subscription Subscribe 方式注册的消费者。
+type subscription struct {
+	topics  []string
+	fn      Handler
+	options Options
+}
+
+func (s *subscription) Topics() []string {
+	return s.topics
+}
+
+func (s *subscription) Consume(ctx context.Context, msg mq.Message) error {
+	return s.fn(ctx, msg)
+}
+
+func (s *subscription) Options() Options {
+	return s.options
+}
+
+// Subscribe 注册订阅 topic 主题的消费者 bean 。
+func Subscribe(topic string, fn Handler, opts ...Option) *gs.BeanDefinition {
+	s := &subscription{topics: []string{topic}, fn: fn}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	return gs.Object(s).Export((*mq.Consumer)(nil))
+}
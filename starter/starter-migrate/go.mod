@@ -0,0 +1,9 @@
+module github.com/go-spring/starter-migrate
+
+go 1.14
+
+require github.com/go-spring/spring-core v1.1.0-alpha
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
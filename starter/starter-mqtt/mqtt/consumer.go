@@ -0,0 +1,161 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mqtt
+
+import (
+	"context"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// Handler 消息处理函数。
+type Handler func(ctx context.Context, msg mq.Message) error
+
+// Options 订阅选项。
+type Options struct {
+	QoS   *byte  // 服务质量等级，为空时使用 mqtt.qos 属性的值
+	Group string // 共享订阅的分组，同一分组内的订阅者竞争消费
+}
+
+// Optional 可以由 mq.Consumer 实现的可选接口，用于为导出方式注册的消费者指定
+// 订阅选项。
+type Optional interface {
+	Options() Options
+}
+
+// Option 订阅选项的设置函数。
+type Option func(*Options)
+
+// QoS 设置服务质量等级。
+func QoS(qos byte) Option {
+	return func(o *Options) { o.QoS = &qos }
+}
+
+// Group 设置共享订阅的分组，需要 broker 支持 $share 共享订阅。
+func Group(group string) Option {
+	return func(o *Options) { o.Group = group }
+}
+
+type consumer struct {
+	topics  []string
+	fn      Handler
+	options Options
+}
+
+func (c *consumer) Topics() []string {
+	return c.topics
+}
+
+func (c *consumer) Consume(ctx context.Context, msg mq.Message) error {
+	return c.fn(ctx, msg)
+}
+
+func (c *consumer) Options() Options {
+	return c.options
+}
+
+// Subscribe 注册订阅 topic 主题的消费者 bean，topic 可以包含 + 和 # 通配符。
+func Subscribe(topic string, fn Handler, opts ...Option) *gs.BeanDefinition {
+	c := &consumer{topics: []string{topic}, fn: fn}
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+	return gs.Object(c).Export((*mq.Consumer)(nil))
+}
+
+// Listener 管理所有消费者的订阅。
+type Listener struct {
+	c      *Client
+	topics []string
+	list   []mq.Consumer
+}
+
+// NewListener Listener 的构造函数。
+func NewListener(c *Client) *Listener {
+	return &Listener{c: c}
+}
+
+// Add 添加消费者，消费者可以通过实现 Optional 接口指定订阅选项。
+func (l *Listener) Add(c mq.Consumer) {
+	l.list = append(l.list, c)
+}
+
+// BindConsumer 实现 mq.Binder 接口，将消费者绑定到主题 destination，group
+// 不为空时使用共享订阅。
+func (l *Listener) BindConsumer(destination string, group string, c mq.Consumer) error {
+	l.Add(&consumer{
+		topics:  []string{destination},
+		fn:      c.Consume,
+		options: Options{Group: group},
+	})
+	return nil
+}
+
+// BindProducer 实现 mq.Binder 接口，返回发送到主题 destination 的消息生产者。
+func (l *Listener) BindProducer(destination string) (mq.Producer, error) {
+	return NewPublisher(l.c), nil
+}
+
+// Start 订阅所有消费者的主题。
+func (l *Listener) Start() error {
+	for _, c := range l.list {
+
+		var opts Options
+		if o, ok := c.(Optional); ok {
+			opts = o.Options()
+		}
+
+		qos := l.c.config.QoS
+		if opts.QoS != nil {
+			qos = *opts.QoS
+		}
+
+		for _, topic := range c.Topics() {
+			if opts.Group != "" {
+				topic = "$share/" + opts.Group + "/" + topic
+			}
+			if err := l.c.subscribe(topic, qos, l.callback(c)); err != nil {
+				return err
+			}
+			log.Infof("mqtt subscribe %s qos:%d", topic, qos)
+			l.topics = append(l.topics, topic)
+		}
+	}
+	return nil
+}
+
+func (l *Listener) callback(c mq.Consumer) mqtt.MessageHandler {
+	return func(_ mqtt.Client, msg mqtt.Message) {
+		ctx := context.Background()
+		if err := c.Consume(ctx, toMessage(msg)); err != nil {
+			log.Ctx(ctx).Errorf("mqtt consume %s error: %v", msg.Topic(), err)
+		}
+	}
+}
+
+// Stop 取消所有订阅。
+func (l *Listener) Stop() {
+	if len(l.topics) == 0 || !l.c.IsConnected() {
+		return
+	}
+	if err := l.c.unsubscribe(l.topics...); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mqtt 基于 paho.mqtt.golang 实现了 mq 包的消息生产者和消费者，
+// 连接断开后自动重连并恢复订阅。
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// Config MQTT 配置，绑定到 mqtt 前缀的属性上。
+type Config struct {
+	Brokers        []string      `value:"${brokers}"`              // 节点地址，默认为 tcp://127.0.0.1:1883
+	ClientID       string        `value:"${client-id:=}"`          // 客户端标识，为空时由 broker 分配
+	Username       string        `value:"${username:=}"`           // 用户名
+	Password       string        `value:"${password:=}"`           // 密码
+	CleanSession   bool          `value:"${clean-session:=true}"`  // 是否清除会话
+	KeepAlive      time.Duration `value:"${keep-alive:=30s}"`      // 心跳间隔
+	ConnectTimeout time.Duration `value:"${connect-timeout:=10s}"` // 连接超时
+	RetryInterval  time.Duration `value:"${retry-interval:=5s}"`   // 首次连接失败后的重试间隔
+	QoS            byte          `value:"${qos:=1}"`               // 默认的服务质量等级
+	Retained       bool          `value:"${retained:=false}"`      // 发布的消息是否为保留消息
+	PublishTimeout time.Duration `value:"${publish-timeout:=10s}"` // 发布消息的超时时间
+}
+
+// ErrTimeout 等待 broker 响应超时。
+var ErrTimeout = errors.New("mqtt: wait for broker timeout")
+
+type subscription struct {
+	qos byte
+	cb  mqtt.MessageHandler
+}
+
+// Client 由容器管理的 MQTT 客户端，记录所有的订阅并在重连后自动恢复。
+type Client struct {
+	mqtt.Client
+	config *Config
+	mutex  sync.Mutex
+	subs   map[string]subscription
+}
+
+// NewClient 创建 MQTT 客户端并连接 broker 。
+func NewClient(config Config) (*Client, error) {
+
+	brokers := config.Brokers
+	if len(brokers) == 0 {
+		brokers = []string{"tcp://127.0.0.1:1883"}
+	}
+
+	c := &Client{config: &config, subs: make(map[string]subscription)}
+
+	opts := mqtt.NewClientOptions()
+	for _, broker := range brokers {
+		opts.AddBroker(broker)
+	}
+	opts.SetClientID(config.ClientID)
+	opts.SetUsername(config.Username)
+	opts.SetPassword(config.Password)
+	opts.SetCleanSession(config.CleanSession)
+	opts.SetKeepAlive(config.KeepAlive)
+	opts.SetConnectTimeout(config.ConnectTimeout)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetConnectRetryInterval(config.RetryInterval)
+	opts.SetOnConnectHandler(c.onConnect)
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		log.Warnf("mqtt connection lost: %v", err)
+	})
+
+	log.Infof("open mqtt %v", brokers)
+	c.Client = mqtt.NewClient(opts)
+	t := c.Connect()
+	if !t.WaitTimeout(config.ConnectTimeout) {
+		return nil, ErrTimeout
+	}
+	if err := t.Error(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CloseClient 断开和 broker 的连接。
+func CloseClient(c *Client) {
+	log.Info("close mqtt")
+	c.Disconnect(uint(time.Second / time.Millisecond))
+}
+
+// onConnect 连接或者重连成功后恢复所有的订阅。
+func (c *Client) onConnect(_ mqtt.Client) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for topic, s := range c.subs {
+		t := c.Client.Subscribe(topic, s.qos, s.cb)
+		if t.Wait() && t.Error() != nil {
+			log.Errorf("mqtt resubscribe %s error: %v", topic, t.Error())
+		}
+	}
+}
+
+// subscribe 订阅主题并记录下来，以便重连后恢复订阅。
+func (c *Client) subscribe(topic string, qos byte, cb mqtt.MessageHandler) error {
+
+	c.mutex.Lock()
+	c.subs[topic] = subscription{qos: qos, cb: cb}
+	c.mutex.Unlock()
+
+	return c.wait(context.Background(), c.Client.Subscribe(topic, qos, cb))
+}
+
+// unsubscribe 取消订阅。
+func (c *Client) unsubscribe(topics ...string) error {
+
+	c.mutex.Lock()
+	for _, topic := range topics {
+		delete(c.subs, topic)
+	}
+	c.mutex.Unlock()
+
+	return c.wait(context.Background(), c.Client.Unsubscribe(topics...))
+}
+
+// wait 等待 broker 的响应。
+func (c *Client) wait(ctx context.Context, t mqtt.Token) error {
+	timeout := c.config.PublishTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return ErrTimeout
+	}
+}
+
+// Publisher 消息生产者，消息发送到消息自身的主题。
+type Publisher struct {
+	c        *Client
+	qos      byte
+	retained bool
+}
+
+// NewPublisher 创建使用默认服务质量等级的消息生产者。
+func NewPublisher(c *Client) *Publisher {
+	return &Publisher{c: c, qos: c.config.QoS, retained: c.config.Retained}
+}
+
+// SendMessage 发送消息，QoS 大于 0 时等待 broker 的确认。
+func (p *Publisher) SendMessage(ctx context.Context, msg mq.Message) error {
+	return p.c.wait(ctx, p.c.Publish(msg.Topic(), p.qos, p.retained, msg.Body()))
+}
+
+func toMessage(msg mqtt.Message) mq.Message {
+	return mq.NewMessage().
+		WithTopic(msg.Topic()).
+		WithID(strconv.Itoa(int(msg.MessageID()))).
+		WithBody(msg.Payload())
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mqtt_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/starter-mqtt/mqtt"
+)
+
+func TestConfig_Bind(t *testing.T) {
+
+	p := conf.New()
+	var c mqtt.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("mqtt")))
+	assert.Equal(t, len(c.Brokers), 0)
+
+	p.Set("mqtt.brokers[0]", "tcp://10.0.0.1:1883")
+	p.Set("mqtt.brokers[1]", "tcp://10.0.0.2:1883")
+	assert.Nil(t, p.Bind(&c, conf.Key("mqtt")))
+	assert.Equal(t, c.Brokers, []string{"tcp://10.0.0.1:1883", "tcp://10.0.0.2:1883"})
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterMqtt
+
+import (
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-stl/util"
+	"github.com/go-spring/starter-mqtt/mqtt"
+)
+
+// PublisherName 默认消息生产者的 bean 名称。
+const PublisherName = "mqttPublisher"
+
+func init() {
+	gs.Provide(mqtt.NewClient, "${mqtt}").
+		Destroy(mqtt.CloseClient).
+		On(cond.OnMissingBean((*mqtt.Client)(nil)))
+	gs.Provide(mqtt.NewPublisher).
+		Name(PublisherName).
+		Export((*mq.Producer)(nil))
+	gs.Provide(mqtt.NewListener).
+		Name("mqtt").
+		Export((*mq.Binder)(nil))
+	gs.Object(new(Starter)).Export(gs.AppEvent)
+}
+
+// Starter MQTT 消费者启动器，订阅所有导出为 mq.Consumer 的 bean、通过
+// gs.Consume 注册的消费者以及通过 mq.Binder 绑定的消费者。
+type Starter struct {
+	Listener      *mqtt.Listener `autowire:""`
+	Consumers     []mq.Consumer  `autowire:""`
+	BindConsumers *gs.Consumers  `autowire:""`
+}
+
+// OnStartApp 应用程序启动事件。
+func (starter *Starter) OnStartApp(ctx gs.AppContext) {
+
+	for _, c := range starter.Consumers {
+		starter.Listener.Add(c)
+	}
+
+	starter.BindConsumers.ForEach(func(c mq.Consumer) {
+		starter.Listener.Add(c)
+	})
+
+	err := starter.Listener.Start()
+	util.Panic(err).When(err != nil)
+}
+
+// OnStopApp 应用程序结束事件。
+func (starter *Starter) OnStopApp(ctx gs.AppContext) {
+	starter.Listener.Stop()
+}
@@ -0,0 +1,14 @@
+module github.com/go-spring/starter-nats
+
+go 1.14
+
+require (
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/go-spring/spring-stl v1.1.0-alpha
+	github.com/nats-io/nats.go v1.13.0
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//	github.com/go-spring/spring-stl => ../../spring/spring-stl
+//)
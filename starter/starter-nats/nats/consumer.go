@@ -0,0 +1,155 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nats
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/nats-io/nats.go"
+)
+
+// handler 订阅选项已经确定的消费者。
+type handler struct {
+	c    mq.Consumer
+	opts Options
+}
+
+// Listener 管理所有消费者的订阅，连接断开期间的订阅由 nats.go 在重连后自动恢复。
+type Listener struct {
+	conn     *nats.Conn
+	config   *Config
+	handlers []*handler
+	subs     []*nats.Subscription
+}
+
+// NewListener Listener 的构造函数。
+func NewListener(conn *nats.Conn, config Config) *Listener {
+	return &Listener{conn: conn, config: &config}
+}
+
+// Add 添加消费者，消费者可以通过实现 Optional 接口指定订阅选项。
+func (l *Listener) Add(c mq.Consumer) {
+	var opts Options
+	if o, ok := c.(Optional); ok {
+		opts = o.Options()
+	}
+	if opts.Queue == "" {
+		opts.Queue = l.config.QueueGroup
+	}
+	l.handlers = append(l.handlers, &handler{c: c, opts: opts})
+}
+
+// BindConsumer 实现 mq.Binder 接口，将消费者绑定到主题 destination，group
+// 作为队列组使用。
+func (l *Listener) BindConsumer(destination string, group string, c mq.Consumer) error {
+	l.Add(&subscription{
+		subjects: []string{destination},
+		fn:       c.Consume,
+		options:  Options{Queue: group},
+	})
+	return nil
+}
+
+// BindProducer 实现 mq.Binder 接口，返回发送到主题 destination 的消息生产者。
+func (l *Listener) BindProducer(destination string) (mq.Producer, error) {
+	return NewPublisher(l.conn, l.config.JetStream)
+}
+
+// Start 订阅所有消费者的主题。
+func (l *Listener) Start(ctx gs.AppContext) error {
+
+	var js nats.JetStreamContext
+	for _, h := range l.handlers {
+		for _, subject := range h.c.Topics() {
+
+			var (
+				err error
+				sub *nats.Subscription
+			)
+
+			cb := l.callback(h, subject)
+			if h.opts.Durable == "" {
+				sub, err = l.conn.QueueSubscribe(subject, h.opts.Queue, cb)
+			} else {
+				if js == nil {
+					if js, err = l.conn.JetStream(); err != nil {
+						return err
+					}
+				}
+				sub, err = js.QueueSubscribe(subject, h.opts.Queue, cb, nats.Durable(h.opts.Durable), nats.ManualAck())
+			}
+			if err != nil {
+				return err
+			}
+
+			log.Infof("nats subscribe %s queue:%q durable:%q", subject, h.opts.Queue, h.opts.Durable)
+			l.subs = append(l.subs, sub)
+		}
+	}
+	return nil
+}
+
+func (l *Listener) callback(h *handler, subject string) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		ctx := context.Background()
+
+		if h.opts.reply != nil {
+			resp := nats.NewMsg(msg.Reply)
+			data, err := h.opts.reply(ctx, msg.Data)
+			if err != nil {
+				log.Ctx(ctx).Errorf("nats reply %s error: %v", subject, err)
+				resp.Header.Set(HeaderException, err.Error())
+			} else {
+				resp.Data = data
+			}
+			if err = msg.RespondMsg(resp); err != nil {
+				log.Ctx(ctx).Errorf("nats respond %s error: %v", subject, err)
+			}
+			return
+		}
+
+		err := h.c.Consume(ctx, toMessage(msg))
+		if err != nil {
+			log.Ctx(ctx).Errorf("nats consume %s error: %v", subject, err)
+		}
+
+		if h.opts.Durable == "" {
+			return
+		}
+
+		if err == nil {
+			err = msg.Ack()
+		} else {
+			err = msg.Nak()
+		}
+		if err != nil {
+			log.Ctx(ctx).Errorf("nats ack %s error: %v", subject, err)
+		}
+	}
+}
+
+// Stop 取消所有订阅，已经收到的消息处理完成后返回。
+func (l *Listener) Stop() {
+	for _, sub := range l.subs {
+		if err := sub.Drain(); err != nil {
+			log.Error(err)
+		}
+	}
+}
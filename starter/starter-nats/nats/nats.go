@@ -0,0 +1,176 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nats 基于 nats.go 实现了 mq 包的消息生产者和消费者，支持队列组、
+// JetStream 持久化订阅以及请求-响应模式。
+package nats
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	HeaderMsgID     = nats.MsgIdHdr // 消息序号对应的消息头，JetStream 使用它进行消息去重
+	HeaderException = "x-exception" // 请求处理失败时响应中携带的错误信息
+)
+
+// Config NATS 配置，绑定到 nats 前缀的属性上。
+type Config struct {
+	URL            string        `value:"${url:=nats://127.0.0.1:4222}"` // 多个地址使用逗号分隔
+	Name           string        `value:"${name:=go-spring}"`            // 连接名称
+	Token          string        `value:"${token:=}"`
+	Username       string        `value:"${username:=}"`
+	Password       string        `value:"${password:=}"`
+	MaxReconnects  int           `value:"${max-reconnects:=-1}"`  // 最大重连次数，负数表示无限重连
+	ReconnectWait  time.Duration `value:"${reconnect-wait:=2s}"`  // 重连间隔
+	RequestTimeout time.Duration `value:"${request-timeout:=3s}"` // 请求-响应模式的默认超时时间
+	QueueGroup     string        `value:"${queue-group:=}"`       // 默认队列组，为空时每个订阅都会收到全部消息
+	JetStream      bool          `value:"${jetstream:=false}"`    // 是否通过 JetStream 发布消息
+}
+
+// NewConn 创建 NATS 连接，断线后自动重连直到容器关闭。
+func NewConn(config Config) (*nats.Conn, error) {
+	log.Infof("open nats %s", config.URL)
+	opts := []nats.Option{
+		nats.Name(config.Name),
+		nats.MaxReconnects(config.MaxReconnects),
+		nats.ReconnectWait(config.ReconnectWait),
+		nats.DisconnectErrHandler(func(c *nats.Conn, err error) {
+			if err != nil {
+				log.Warnf("nats disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			log.Infof("nats reconnected to %s", c.ConnectedUrl())
+		}),
+	}
+	if config.Token != "" {
+		opts = append(opts, nats.Token(config.Token))
+	}
+	if config.Username != "" {
+		opts = append(opts, nats.UserInfo(config.Username, config.Password))
+	}
+	return nats.Connect(config.URL, opts...)
+}
+
+// CloseConn 等待已经收到的消息处理完成后关闭 NATS 连接。
+func CloseConn(conn *nats.Conn) {
+	log.Info("close nats")
+	if err := conn.Drain(); err != nil {
+		log.Error(err)
+		conn.Close()
+	}
+}
+
+// Publisher 消息生产者，消息发送到消息自身的主题，开启 JetStream 时等待持久化
+// 确认。
+type Publisher struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewPublisher 创建消息生产者。
+func NewPublisher(conn *nats.Conn, jetStream bool) (*Publisher, error) {
+	p := &Publisher{conn: conn}
+	if jetStream {
+		js, err := conn.JetStream()
+		if err != nil {
+			return nil, err
+		}
+		p.js = js
+	}
+	return p, nil
+}
+
+// RegisterPublisher 注册名为 name 的消息生产者 bean，使用时通过 `autowire:"name"`
+// 按名称注入。
+func RegisterPublisher(name string, jetStream bool) *gs.BeanDefinition {
+	return gs.Provide(NewPublisher, "", arg.Value(jetStream)).
+		Name(name).
+		Export((*mq.Producer)(nil))
+}
+
+// SendMessage 发送消息。
+func (p *Publisher) SendMessage(ctx context.Context, msg mq.Message) error {
+	m := toNatsMsg(msg)
+	if p.js != nil {
+		_, err := p.js.PublishMsg(m, nats.Context(ctx))
+		return err
+	}
+	return p.conn.PublishMsg(m)
+}
+
+// Requester 请求-响应模式的客户端。
+type Requester struct {
+	conn    *nats.Conn
+	timeout time.Duration
+}
+
+// NewRequester 创建请求-响应模式的客户端，timeout 是 ctx 没有截止时间时使用的
+// 超时时间。
+func NewRequester(conn *nats.Conn, timeout time.Duration) *Requester {
+	return &Requester{conn: conn, timeout: timeout}
+}
+
+// Request 发送请求并等待响应。
+func (r *Requester) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok && r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	m, err := r.conn.RequestWithContext(ctx, subject, data)
+	if err != nil {
+		return nil, err
+	}
+	if s := m.Header.Get(HeaderException); s != "" {
+		return nil, errors.New(s)
+	}
+	return m.Data, nil
+}
+
+func toNatsMsg(msg mq.Message) *nats.Msg {
+	m := nats.NewMsg(msg.Topic())
+	m.Data = msg.Body()
+	if id := msg.ID(); id != "" {
+		m.Header.Set(HeaderMsgID, id)
+	}
+	for k, v := range msg.Extra() {
+		m.Header.Set(k, v)
+	}
+	return m
+}
+
+func toMessage(msg *nats.Msg) mq.Message {
+	m := mq.NewMessage().
+		WithTopic(msg.Subject).
+		WithID(msg.Header.Get(HeaderMsgID)).
+		WithBody(msg.Data)
+	for k := range msg.Header {
+		if k != HeaderMsgID {
+			m.WithExtra(k, msg.Header.Get(k))
+		}
+	}
+	return m
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nats
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/mq"
+)
+
+// Handler 消息处理函数。
+type Handler func(ctx context.Context, msg mq.Message) error
+
+// ReplyHandler 请求-响应模式的请求处理函数。
+type ReplyHandler func(ctx context.Context, req []byte) ([]byte, error)
+
+// Options 订阅选项，零值表示使用 nats 前缀下的全局配置。
+type Options struct {
+	Queue   string // 队列组，同一队列组内的订阅者竞争消费
+	Durable string // JetStream 持久化消费者名称，不为空时通过 JetStream 订阅
+	reply   ReplyHandler
+}
+
+// Optional 可以由 mq.Consumer 实现的可选接口，用于为导出方式注册的消费者指定
+// 订阅选项。
+type Optional interface {
+	Options() Options
+}
+
+// Option 订阅选项的设置函数。
+type Option func(*Options)
+
+// Queue 设置队列组。
+func Queue(queue string) Option {
+	return func(o *Options) { o.Queue = queue }
+}
+
+// Durable 设置 JetStream 持久化消费者名称。
+func Durable(name string) Option {
+	return func(o *Options) { o.Durable = name }
+}
+
+// subscription Subscribe 方式注册的消费者。
+type subscription struct {
+	subjects []string
+	fn       Handler
+	options  Options
+}
+
+func (s *subscription) Topics() []string {
+	return s.subjects
+}
+
+func (s *subscription) Consume(ctx context.Context, msg mq.Message) error {
+	return s.fn(ctx, msg)
+}
+
+func (s *subscription) Options() Options {
+	return s.options
+}
+
+// Subscribe 注册订阅 subject 主题的消费者 bean 。
+func Subscribe(subject string, fn Handler, opts ...Option) *gs.BeanDefinition {
+	s := &subscription{subjects: []string{subject}, fn: fn}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	return gs.Object(s).Export((*mq.Consumer)(nil))
+}
+
+// Reply 注册请求-响应模式的请求处理 bean，fn 的返回值作为响应发送给请求方。
+func Reply(subject string, fn ReplyHandler, opts ...Option) *gs.BeanDefinition {
+	s := &subscription{subjects: []string{subject}}
+	s.fn = func(ctx context.Context, msg mq.Message) error {
+		_, err := fn(ctx, msg.Body())
+		return err
+	}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	s.options.reply = fn
+	return gs.Object(s).Export((*mq.Consumer)(nil))
+}
@@ -14,33 +14,18 @@
  * limitations under the License.
  */
 
-package StarterRabbitMQProducer
+package StarterOutbox
 
 import (
-	"context"
+	"database/sql"
 
 	"github.com/go-spring/spring-core/gs"
-	"github.com/go-spring/spring-core/mq"
-	"github.com/go-spring/starter-rabbitmq/server"
-	"github.com/streadway/amqp"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/outbox"
 )
 
 func init() {
-	gs.Object(new(Sender)).Export((*mq.Producer)(nil))
-}
-
-type Sender struct {
-	Server *StarterRabbitMQServer.AMQPServer `autowire:""`
-}
-
-func (sender *Sender) SendMessage(ctx context.Context, msg mq.Message) error {
-	return sender.Server.Channel.Publish(
-		"",          // exchange
-		msg.Topic(), // routing key
-		false,       // mandatory
-		false,       // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        msg.Body(),
-		})
+	gs.Provide(outbox.New, "", "${db.outbox}").
+		On(cond.OnBean((*sql.DB)(nil)).
+			OnProperty("db.outbox.enabled", cond.HavingValue("true"), cond.MatchIfMissing()))
 }
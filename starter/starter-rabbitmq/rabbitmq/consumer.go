@@ -0,0 +1,174 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/streadway/amqp"
+)
+
+// handler 订阅选项已经确定的消费者。
+type handler struct {
+	c    mq.Consumer
+	opts Options
+}
+
+// Listener 管理所有消费者的 channel 和消费协程，每个消费者的每个队列使用独立的
+// channel 。
+type Listener struct {
+	conn     *Connection
+	config   *ConsumerConfig
+	handlers []*handler
+	channels []*amqp.Channel
+}
+
+// NewListener Listener 的构造函数。
+func NewListener(conn *Connection, config ConsumerConfig) *Listener {
+	return &Listener{conn: conn, config: &config}
+}
+
+// Add 添加消费者，消费者可以通过实现 Optional 接口指定订阅选项。
+func (l *Listener) Add(c mq.Consumer) error {
+
+	var opts Options
+	if o, ok := c.(Optional); ok {
+		opts = o.Options()
+	}
+
+	if opts.Prefetch <= 0 {
+		opts.Prefetch = l.config.Prefetch
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = l.config.Concurrency
+	}
+	if opts.Ack == "" {
+		opts.Ack = l.config.Ack
+	}
+
+	switch opts.Ack {
+	case AckAuto, AckOnSuccess, AckRequeue:
+	default:
+		return fmt.Errorf("unsupported ack policy %q for queues %v", opts.Ack, c.Topics())
+	}
+
+	l.handlers = append(l.handlers, &handler{c: c, opts: opts})
+	return nil
+}
+
+// BindConsumer 实现 mq.Binder 接口，将消费者绑定到队列 destination，同一队列
+// 的消费者天然构成竞争消费关系，因此忽略 group 参数。消费者实现了 mq.Concurrent
+// 接口时使用其指定的并发消费数。
+func (l *Listener) BindConsumer(destination string, group string, c mq.Consumer) error {
+	s := &subscription{queues: []string{destination}, fn: c.Consume}
+	if cc, ok := c.(mq.Concurrent); ok {
+		s.options.Concurrency = cc.Concurrency()
+	}
+	return l.Add(s)
+}
+
+// BindProducer 实现 mq.Binder 接口，返回通过默认交换机发送到队列 destination
+// 的消息生产者。
+func (l *Listener) BindProducer(destination string) (mq.Producer, error) {
+	return NewPublisher(l.conn, ""), nil
+}
+
+// Start 为每个消费者的每个队列启动消费协程。
+func (l *Listener) Start(ctx gs.AppContext) error {
+	for _, h := range l.handlers {
+		for _, queue := range h.c.Topics() {
+			if err := l.start(ctx, h, queue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Listener) start(ctx gs.AppContext, h *handler, queue string) error {
+
+	ch, err := l.conn.Channel()
+	if err != nil {
+		return err
+	}
+	l.channels = append(l.channels, ch)
+
+	if err = ch.Qos(h.opts.Prefetch, 0, false); err != nil {
+		return err
+	}
+
+	autoAck := h.opts.Ack == AckAuto
+	deliveries, err := ch.Consume(queue, "", autoAck, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("rabbitmq subscribe %s concurrency:%d ack:%s", queue, h.opts.Concurrency, h.opts.Ack)
+	for i := 0; i < h.opts.Concurrency; i++ {
+		ctx.Go(func(ctx context.Context) {
+			for d := range deliveries {
+				l.handle(ctx, h, queue, d)
+			}
+		})
+	}
+	return nil
+}
+
+func (l *Listener) handle(ctx context.Context, h *handler, queue string, d amqp.Delivery) {
+
+	err := h.c.Consume(ctx, toMessage(queue, d))
+	if err != nil {
+		log.Ctx(ctx).Errorf("rabbitmq consume %s error: %v", queue, err)
+	}
+
+	if h.opts.Ack == AckAuto {
+		return
+	}
+
+	if err == nil {
+		err = d.Ack(false)
+	} else {
+		err = d.Nack(false, h.opts.Ack == AckRequeue)
+	}
+	if err != nil {
+		log.Ctx(ctx).Errorf("rabbitmq ack %s error: %v", queue, err)
+	}
+}
+
+// Stop 关闭所有消费者的 channel，消费协程随之退出。
+func (l *Listener) Stop() {
+	for _, ch := range l.channels {
+		if err := ch.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func toMessage(queue string, d amqp.Delivery) mq.Message {
+	m := mq.NewMessage().
+		WithTopic(queue).
+		WithID(d.MessageId).
+		WithBody(d.Body)
+	for k, v := range d.Headers {
+		m.WithExtra(k, fmt.Sprint(v))
+	}
+	return m
+}
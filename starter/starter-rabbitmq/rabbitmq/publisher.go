@@ -0,0 +1,100 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/mq"
+	"github.com/streadway/amqp"
+)
+
+// ErrNack 消息被 broker 拒绝。
+var ErrNack = errors.New("rabbitmq: message nacked by broker")
+
+// ErrConfirmTimeout 等待发布确认超时。
+var ErrConfirmTimeout = errors.New("rabbitmq: wait for confirm timeout")
+
+// Publisher 消息生产者，消息发送到 exchange 交换机，消息的主题作为路由键。
+type Publisher struct {
+	conn     *Connection
+	exchange string
+}
+
+// NewPublisher 创建消息生产者。
+func NewPublisher(conn *Connection, exchange string) *Publisher {
+	return &Publisher{conn: conn, exchange: exchange}
+}
+
+// RegisterPublisher 注册名为 name 的消息生产者 bean，exchange 为空时使用默认
+// 交换机，此时消息的主题就是队列名称，使用时通过 `autowire:"name"` 按名称注入。
+func RegisterPublisher(name string, exchange string) *gs.BeanDefinition {
+	return gs.Provide(NewPublisher, "", arg.Value(exchange)).
+		Name(name).
+		Export((*mq.Producer)(nil))
+}
+
+// SendMessage 发送消息，开启发布确认时等待 broker 的确认结果。
+func (p *Publisher) SendMessage(ctx context.Context, msg mq.Message) (err error) {
+
+	ch, err := p.conn.get()
+	if err != nil {
+		return err
+	}
+	defer func() { p.conn.put(ch, err) }()
+
+	publishing := amqp.Publishing{
+		MessageId:    msg.ID(),
+		Body:         msg.Body(),
+		DeliveryMode: amqp.Persistent,
+	}
+	if extra := msg.Extra(); len(extra) > 0 {
+		publishing.Headers = amqp.Table{}
+		for k, v := range extra {
+			publishing.Headers[k] = v
+		}
+	}
+
+	err = ch.ch.Publish(p.exchange, msg.Topic(), false, false, publishing)
+	if err != nil || ch.confirms == nil {
+		return err
+	}
+
+	timeout := p.conn.config.Publisher.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	select {
+	case c, ok := <-ch.confirms:
+		if !ok {
+			return amqp.ErrClosed
+		}
+		if !c.Ack {
+			return ErrNack
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return ErrConfirmTimeout
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rabbitmq_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-stl/assert"
+	"github.com/go-spring/starter-rabbitmq/rabbitmq"
+)
+
+func TestConfig_Bind(t *testing.T) {
+
+	p := conf.New()
+	var c rabbitmq.Config
+	assert.Nil(t, p.Bind(&c, conf.Key("rabbitmq")))
+	assert.Equal(t, len(c.Exchanges), 0)
+	assert.Equal(t, len(c.Queues), 0)
+
+	p.Set("rabbitmq.exchanges[0].name", "orders")
+	p.Set("rabbitmq.exchanges[0].kind", "topic")
+	p.Set("rabbitmq.queues[0].name", "orders.created")
+	p.Set("rabbitmq.queues[0].bindings[0].exchange", "orders")
+	p.Set("rabbitmq.queues[0].bindings[0].routing-key", "order.created")
+	p.Set("rabbitmq.queues[1].name", "orders.dlq")
+	assert.Nil(t, p.Bind(&c, conf.Key("rabbitmq")))
+
+	assert.Equal(t, c.Exchanges, []rabbitmq.ExchangeConfig{
+		{Name: "orders", Kind: "topic", Durable: true},
+	})
+	assert.Equal(t, c.Queues, []rabbitmq.QueueConfig{
+		{
+			Name:     "orders.created",
+			Durable:  true,
+			Bindings: []rabbitmq.BindingConfig{{Exchange: "orders", RoutingKey: "order.created"}},
+		},
+		{
+			Name:     "orders.dlq",
+			Durable:  true,
+			Bindings: []rabbitmq.BindingConfig{},
+		},
+	})
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/mq"
+)
+
+const (
+	AckAuto      = "auto"       // 投递时自动确认，消费失败的消息会丢失
+	AckOnSuccess = "on-success" // 消费成功时确认，失败时拒绝且不重新入队，可以配合死信交换机使用
+	AckRequeue   = "requeue"    // 消费成功时确认，失败时拒绝并重新入队
+)
+
+// Handler 消息处理函数。
+type Handler func(ctx context.Context, msg mq.Message) error
+
+// Options 订阅选项，零值表示使用 rabbitmq.consumer 前缀下的全局配置。
+type Options struct {
+	Prefetch    int    // 未确认消息的最大数量
+	Concurrency int    // 并发消费数
+	Ack         string // 确认策略
+}
+
+// Optional 可以由 mq.Consumer 实现的可选接口，用于为导出方式注册的消费者指定
+// 订阅选项。
+type Optional interface {
+	Options() Options
+}
+
+// Option 订阅选项的设置函数。
+type Option func(*Options)
+
+// Prefetch 设置未确认消息的最大数量。
+func Prefetch(n int) Option {
+	return func(o *Options) { o.Prefetch = n }
+}
+
+// Concurrency 设置并发消费数。
+func Concurrency(n int) Option {
+	return func(o *Options) { o.Concurrency = n }
+}
+
+// Ack 设置确认策略。
+func Ack(policy string) Option {
+	return func(o *Options) { o.Ack = policy }
+}
+
+// subscription Subscribe 方式注册的消费者。
+type subscription struct {
+	queues  []string
+	fn      Handler
+	options Options
+}
+
+func (s *subscription) Topics() []string {
+	return s.queues
+}
+
+func (s *subscription) Consume(ctx context.Context, msg mq.Message) error {
+	return s.fn(ctx, msg)
+}
+
+func (s *subscription) Options() Options {
+	return s.options
+}
+
+// Subscribe 注册消费 queue 队列的消费者 bean 。
+func Subscribe(queue string, fn Handler, opts ...Option) *gs.BeanDefinition {
+	s := &subscription{queues: []string{queue}, fn: fn}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	return gs.Object(s).Export((*mq.Consumer)(nil))
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package factory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	SpringRedis "github.com/go-spring/spring-core/redis"
+)
+
+// client 基于 go-redis 的 SpringRedis.Client 实现，同时实现了 SpringRedis.PubSub 、
+// SpringRedis.Scripter 和 SpringRedis.Doer 接口。
+type client struct {
+	c redis.UniversalClient
+}
+
+// NewSpringClient 将 go-redis 客户端适配为 SpringRedis.Client 接口。
+func NewSpringClient(c redis.UniversalClient) SpringRedis.Client {
+	return &client{c: c}
+}
+
+func (r *client) Ping(ctx context.Context) error {
+	return r.c.Ping(ctx).Err()
+}
+
+func (r *client) Get(ctx context.Context, key string) (string, error) {
+	s, err := r.c.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", SpringRedis.ErrNil
+	}
+	return s, err
+}
+
+func (r *client) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return r.c.Set(ctx, key, value, expiration).Err()
+}
+
+func (r *client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.c.SetNX(ctx, key, value, expiration).Result()
+}
+
+func (r *client) Del(ctx context.Context, keys ...string) (int64, error) {
+	return r.c.Del(ctx, keys...).Result()
+}
+
+func (r *client) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return r.c.Exists(ctx, keys...).Result()
+}
+
+func (r *client) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return r.c.Expire(ctx, key, expiration).Result()
+}
+
+func (r *client) Incr(ctx context.Context, key string) (int64, error) {
+	return r.c.Incr(ctx, key).Result()
+}
+
+func (r *client) Publish(ctx context.Context, channel string, message string) error {
+	return r.c.Publish(ctx, channel, message).Err()
+}
+
+func (r *client) Subscribe(ctx context.Context, channel string, fn func(message string)) (func(), error) {
+	ps := r.c.Subscribe(ctx, channel)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range ps.Channel() {
+			fn(msg.Payload)
+		}
+	}()
+	return func() { _ = ps.Close() }, nil
+}
+
+// scriptErr 将脚本执行的错误转换为 SpringRedis 定义的错误。
+func scriptErr(err error) error {
+	if err == redis.Nil {
+		return SpringRedis.ErrNil
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		return SpringRedis.ErrNoScript
+	}
+	return err
+}
+
+func (r *client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := r.c.Eval(ctx, script, keys, args...).Result()
+	return v, scriptErr(err)
+}
+
+func (r *client) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := r.c.EvalSha(ctx, sha1, keys, args...).Result()
+	return v, scriptErr(err)
+}
+
+func (r *client) ScriptLoad(ctx context.Context, script string) (string, error) {
+	return r.c.ScriptLoad(ctx, script).Result()
+}
+
+func (r *client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	v, err := r.c.Do(ctx, args...).Result()
+	return v, scriptErr(err)
+}
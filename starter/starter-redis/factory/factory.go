@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package factory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/metrics"
+)
+
+const (
+	ModeStandalone = "standalone" // 单机模式
+	ModeSentinel   = "sentinel"   // 哨兵模式
+	ModeCluster    = "cluster"    // 集群模式
+)
+
+// Config Redis 客户端配置，绑定到 redis 前缀的属性上。
+type Config struct {
+	Mode          string        `value:"${mode:=standalone}"`      // 部署模式
+	Addrs         []string      `value:"${addrs}"`                 // 节点地址，默认为 127.0.0.1:6379
+	MasterName    string        `value:"${master-name:=}"`         // 哨兵模式的主节点名称
+	Username      string        `value:"${username:=}"`            // 用户名
+	Password      string        `value:"${password:=}"`            // 密码
+	Database      int           `value:"${database:=0}"`           // 数据库，集群模式不支持
+	PoolSize      int           `value:"${pool-size:=0}"`          // 连接池大小，0 表示使用默认值
+	DialTimeout   time.Duration `value:"${dial-timeout:=5s}"`      // 连接超时
+	ReadTimeout   time.Duration `value:"${read-timeout:=3s}"`      // 读超时
+	WriteTimeout  time.Duration `value:"${write-timeout:=3s}"`     // 写超时
+	SlowThreshold time.Duration `value:"${slow-threshold:=100ms}"` // 慢命令阈值
+}
+
+// NewClient 根据部署模式创建 Redis 客户端。
+func NewClient(config Config) (redis.UniversalClient, error) {
+
+	addrs := config.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{"127.0.0.1:6379"}
+	}
+
+	var client redis.UniversalClient
+	switch config.Mode {
+	case ModeStandalone:
+		client = redis.NewClient(&redis.Options{
+			Addr:         addrs[0],
+			Username:     config.Username,
+			Password:     config.Password,
+			DB:           config.Database,
+			PoolSize:     config.PoolSize,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+		})
+	case ModeSentinel:
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    config.MasterName,
+			SentinelAddrs: addrs,
+			Username:      config.Username,
+			Password:      config.Password,
+			DB:            config.Database,
+			PoolSize:      config.PoolSize,
+			DialTimeout:   config.DialTimeout,
+			ReadTimeout:   config.ReadTimeout,
+			WriteTimeout:  config.WriteTimeout,
+		})
+	case ModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Username:     config.Username,
+			Password:     config.Password,
+			PoolSize:     config.PoolSize,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported redis mode %q", config.Mode)
+	}
+
+	client.AddHook(&hook{
+		slowThreshold: config.SlowThreshold,
+		latency:       metrics.Default.Timer("redis_command_seconds", "Redis command latency in seconds.", "command", "status"),
+	})
+
+	log.Infof("open redis %s %v", config.Mode, addrs)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// CloseClient 关闭 Redis 客户端。
+func CloseClient(client redis.UniversalClient) {
+	log.Info("close redis")
+	if err := client.Close(); err != nil {
+		log.Error(err)
+	}
+}
+
+type startKey struct{}
+
+// hook 记录 Redis 命令的执行耗时，输出慢命令和错误日志。
+type hook struct {
+	slowThreshold time.Duration
+	latency       *metrics.TimerVec
+}
+
+func (h *hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h *hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.Name(), []redis.Cmder{cmd})
+	return nil
+}
+
+func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.observe(ctx, "pipeline", cmds)
+	return nil
+}
+
+func (h *hook) observe(ctx context.Context, name string, cmds []redis.Cmder) {
+
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	cost := time.Since(start)
+	if h.slowThreshold > 0 && cost >= h.slowThreshold {
+		log.Ctx(ctx).Warnf("redis slow command %s cost:%v", name, cost)
+	}
+
+	status := "ok"
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			log.Ctx(ctx).Errorf("redis command %s error: %v", cmd.Name(), err)
+			status = "error"
+		}
+	}
+	h.latency.With(name, status).Observe(cost)
+}
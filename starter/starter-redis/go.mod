@@ -0,0 +1,12 @@
+module github.com/go-spring/starter-redis
+
+go 1.14
+
+require (
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/go-spring/spring-core v1.1.0-alpha
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
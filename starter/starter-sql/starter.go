@@ -0,0 +1,53 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterSql
+
+import (
+	"database/sql"
+
+	"github.com/go-spring/spring-core/db"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/log"
+	"github.com/go-spring/spring-core/sqllog"
+)
+
+func init() {
+	gs.Provide(sqllog.NewInterceptor, "${db.slowlog}")
+	gs.Provide(sqllog.NewMetricsObserver, "").Export((*sqllog.Observer)(nil))
+	r := gs.Provide(db.NewRouter, "${db.routing}", "").
+		On(cond.OnProperty("db.routing.enabled", cond.HavingValue("true")))
+	gs.Provide((*db.Router).Primary, r.ID()).
+		On(cond.OnProperty("db.routing.enabled", cond.HavingValue("true")))
+	gs.Provide(openDB, "${db.driver}", "${db.url}", "").
+		Destroy(closeDB).
+		On(cond.OnProperty("db.driver").OnMissingBean((*sql.DB)(nil)))
+}
+
+// openDB 打开数据库连接，开启 db.slowlog.enabled 时拦截所有的 SQL 语句。
+func openDB(driverName string, dsn string, i *sqllog.Interceptor) (*sql.DB, error) {
+	log.Infof("open database %s", driverName)
+	return sqllog.Open(driverName, dsn, i)
+}
+
+// closeDB 关闭数据库连接。
+func closeDB(db *sql.DB) {
+	log.Info("close database")
+	if err := db.Close(); err != nil {
+		log.Error(err)
+	}
+}
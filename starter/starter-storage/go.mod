@@ -0,0 +1,14 @@
+module github.com/go-spring/starter-storage
+
+go 1.14
+
+require (
+	github.com/aliyun/aliyun-oss-go-sdk v2.2.0+incompatible
+	github.com/go-spring/spring-core v1.1.0-alpha
+	github.com/minio/minio-go/v7 v7.0.21
+	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac // indirect
+)
+
+//replace (
+//	github.com/go-spring/spring-core => ../../spring/spring-core
+//)
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package oss 基于阿里云 OSS SDK 实现的对象存储。
+package oss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-spring/spring-core/storage"
+)
+
+// Storage 阿里云 OSS 对象存储，OSS SDK 不支持 context.Context 参数，因此调用时
+// 会忽略 ctx 参数。
+type Storage struct {
+	bucket *oss.Bucket
+}
+
+// New 创建阿里云 OSS 对象存储。
+func New(config storage.Config) (*Storage, error) {
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("oss-%s.aliyuncs.com", config.Region)
+	}
+
+	client, err := oss.New(endpoint, config.AccessKey, config.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := client.Bucket(config.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{bucket: bucket}, nil
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	var opts []oss.Option
+	if contentType != "" {
+		opts = append(opts, oss.ContentType(contentType))
+	}
+	if size >= 0 {
+		opts = append(opts, oss.ContentLength(size))
+	}
+	return s.bucket.PutObject(key, r, opts...)
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := s.bucket.GetObject(key)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return r, nil
+}
+
+func (s *Storage) Stat(ctx context.Context, key string) (*storage.Object, error) {
+	h, err := s.bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	size, _ := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	lastModified, _ := http.ParseTime(h.Get("Last-Modified"))
+	return &storage.Object{
+		Key:          key,
+		Size:         size,
+		ETag:         h.Get("ETag"),
+		ContentType:  h.Get("Content-Type"),
+		LastModified: lastModified,
+	}, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	return s.bucket.DeleteObject(key)
+}
+
+func (s *Storage) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	var (
+		ret   []storage.Object
+		token string
+	)
+	for {
+		r, err := s.bucket.ListObjectsV2(oss.Prefix(prefix), oss.ContinuationToken(token))
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range r.Objects {
+			ret = append(ret, storage.Object{
+				Key:          o.Key,
+				Size:         o.Size,
+				ETag:         o.ETag,
+				LastModified: o.LastModified,
+			})
+		}
+		if !r.IsTruncated {
+			return ret, nil
+		}
+		token = r.NextContinuationToken
+	}
+}
+
+func (s *Storage) Presign(ctx context.Context, method string, key string, expires time.Duration) (string, error) {
+	switch method {
+	case http.MethodGet:
+		return s.bucket.SignURL(key, oss.HTTPGet, int64(expires/time.Second))
+	case http.MethodPut:
+		return s.bucket.SignURL(key, oss.HTTPPut, int64(expires/time.Second))
+	}
+	return "", fmt.Errorf("unsupported presign method %q", method)
+}
+
+func convertError(err error) error {
+	if e, ok := err.(oss.ServiceError); ok && e.StatusCode == http.StatusNotFound {
+		return storage.ErrNotExist
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package s3 基于 minio-go 实现了兼容 S3 协议的对象存储，适用于 AWS S3、MinIO
+// 以及其他兼容 S3 协议的存储服务。
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-spring/spring-core/storage"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Storage 兼容 S3 协议的对象存储。
+type Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+// New 创建兼容 S3 协议的对象存储。
+func New(config storage.Config) (*Storage, error) {
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{client: client, bucket: config.Bucket}, nil
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, opts)
+	return err
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	o, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	// GetObject 是惰性的，通过 Stat 提前发现对象不存在的情况。
+	if _, err = o.Stat(); err != nil {
+		_ = o.Close()
+		return nil, convertError(err)
+	}
+	return o, nil
+}
+
+func (s *Storage) Stat(ctx context.Context, key string) (*storage.Object, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return toObject(info), nil
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *Storage) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	var ret []storage.Object
+	opts := minio.ListObjectsOptions{Prefix: prefix, Recursive: true}
+	for info := range s.client.ListObjects(ctx, s.bucket, opts) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		ret = append(ret, *toObject(info))
+	}
+	return ret, nil
+}
+
+func (s *Storage) Presign(ctx context.Context, method string, key string, expires time.Duration) (string, error) {
+	switch method {
+	case http.MethodGet:
+		u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expires, nil)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	case http.MethodPut:
+		u, err := s.client.PresignedPutObject(ctx, s.bucket, key, expires)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("unsupported presign method %q", method)
+}
+
+func toObject(info minio.ObjectInfo) *storage.Object {
+	return &storage.Object{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
+
+func convertError(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return storage.ErrNotExist
+	}
+	return err
+}
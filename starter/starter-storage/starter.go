@@ -0,0 +1,34 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterStorage
+
+import (
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/storage"
+	"github.com/go-spring/starter-storage/oss"
+	"github.com/go-spring/starter-storage/s3"
+)
+
+func init() {
+	gs.Provide(s3.New, "${storage}").
+		Export((*storage.Storage)(nil)).
+		On(cond.OnProperty("storage.type", cond.HavingValue("s3"), cond.MatchIfMissing()))
+	gs.Provide(oss.New, "${storage}").
+		Export((*storage.Storage)(nil)).
+		On(cond.OnProperty("storage.type", cond.HavingValue("oss")))
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package StarterTemporal
+
+import (
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-stl/util"
+	"github.com/go-spring/starter-temporal/temporal"
+	"go.temporal.io/sdk/client"
+)
+
+func init() {
+	gs.Provide(temporal.NewClient, "${temporal}").
+		Destroy(temporal.CloseClient).
+		On(cond.OnMissingBean((*client.Client)(nil)))
+	gs.Provide(temporal.NewWorkers, "", "${temporal.worker}")
+	gs.Object(new(Starter)).Export(gs.AppEvent)
+}
+
+// Starter Temporal worker 启动器，应用启动时启动所有的 worker，应用停止时停止。
+type Starter struct {
+	Workers *temporal.Workers `autowire:""`
+}
+
+// OnStartApp 应用程序启动事件。
+func (starter *Starter) OnStartApp(ctx gs.AppContext) {
+	err := starter.Workers.Start()
+	util.Panic(err).When(err != nil)
+}
+
+// OnStopApp 应用程序结束事件。
+func (starter *Starter) OnStopApp(ctx gs.AppContext) {
+	starter.Workers.Stop()
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package temporal 在容器中管理 Temporal 客户端和 worker 的生命周期，导出为
+// Activities 的 bean 和通过 RegisterWorkflow 注册的工作流函数在应用启动时被注册
+// 到对应任务队列的 worker 上。
+package temporal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/log"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Config Temporal 配置，绑定到 temporal 前缀的属性上。
+type Config struct {
+	HostPort  string       `value:"${host-port:=127.0.0.1:7233}"` // 服务地址
+	Namespace string       `value:"${namespace:=default}"`        // 命名空间
+	Identity  string       `value:"${identity:=}"`                // 客户端标识，为空时由 SDK 生成
+	Worker    WorkerConfig `value:"${worker}"`
+}
+
+// WorkerConfig worker 配置，并发数为 0 时使用 SDK 的默认值。
+type WorkerConfig struct {
+	TaskQueue               string  `value:"${task-queue:=default}"`              // 默认任务队列
+	MaxConcurrentActivities int     `value:"${max-concurrent-activities:=0}"`     // 并发执行的 activity 数量上限
+	MaxConcurrentWorkflows  int     `value:"${max-concurrent-workflow-tasks:=0}"` // 并发执行的工作流任务数量上限
+	ActivityPollers         int     `value:"${activity-pollers:=0}"`              // 拉取 activity 任务的协程数
+	WorkflowPollers         int     `value:"${workflow-pollers:=0}"`              // 拉取工作流任务的协程数
+	ActivitiesPerSecond     float64 `value:"${activities-per-second:=0}"`         // 每秒执行的 activity 数量上限
+}
+
+// NewClient 创建 Temporal 客户端。
+func NewClient(config Config) (client.Client, error) {
+	log.Infof("open temporal %s namespace %s", config.HostPort, config.Namespace)
+	return client.NewClient(client.Options{
+		HostPort:  config.HostPort,
+		Namespace: config.Namespace,
+		Identity:  config.Identity,
+	})
+}
+
+// CloseClient 关闭 Temporal 客户端。
+func CloseClient(c client.Client) {
+	log.Info("close temporal")
+	c.Close()
+}
+
+// Activities 导出为该接口的 bean 的所有导出方法都会被注册为 activity，bean 可以
+// 通过实现 TaskQueue() string 方法指定所属的任务队列，例如:
+//
+//	gs.Object(new(OrderActivities)).Export(temporal.ActivitiesType)
+type Activities interface{}
+
+// ActivitiesType 导出 Activities 类型。
+var ActivitiesType = (*Activities)(nil)
+
+// Workflow 通过 RegisterWorkflow 注册的工作流函数。
+type Workflow struct {
+	Fn        interface{} // 工作流函数
+	Name      string      // 工作流名称，为空时使用函数名
+	TaskQueue string      // 任务队列，为空时使用默认任务队列
+}
+
+// WorkflowOption 工作流的注册选项。
+type WorkflowOption func(*Workflow)
+
+// WorkflowName 设置工作流名称。
+func WorkflowName(name string) WorkflowOption {
+	return func(w *Workflow) { w.Name = name }
+}
+
+// WorkflowTaskQueue 设置工作流所属的任务队列。
+func WorkflowTaskQueue(taskQueue string) WorkflowOption {
+	return func(w *Workflow) { w.TaskQueue = taskQueue }
+}
+
+// RegisterWorkflow 注册工作流函数。
+func RegisterWorkflow(fn interface{}, opts ...WorkflowOption) *gs.BeanDefinition {
+	w := &Workflow{Fn: fn}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return gs.Object(w)
+}
+
+// Workers 为每个任务队列创建一个 worker 。
+type Workers struct {
+	Workflows  []*Workflow  `autowire:""`
+	Activities []Activities `autowire:""`
+
+	client  client.Client
+	config  *WorkerConfig // 使用指针避免容器对其进行属性绑定
+	workers map[string]worker.Worker
+}
+
+// NewWorkers Workers 的构造函数。
+func NewWorkers(c client.Client, config WorkerConfig) *Workers {
+	return &Workers{
+		client:  c,
+		config:  &config,
+		workers: make(map[string]worker.Worker),
+	}
+}
+
+func (w *Workers) worker(taskQueue string) worker.Worker {
+	if taskQueue == "" {
+		taskQueue = w.config.TaskQueue
+	}
+	if r, ok := w.workers[taskQueue]; ok {
+		return r
+	}
+	r := worker.New(w.client, taskQueue, worker.Options{
+		MaxConcurrentActivityExecutionSize:     w.config.MaxConcurrentActivities,
+		MaxConcurrentWorkflowTaskExecutionSize: w.config.MaxConcurrentWorkflows,
+		MaxConcurrentActivityTaskPollers:       w.config.ActivityPollers,
+		MaxConcurrentWorkflowTaskPollers:       w.config.WorkflowPollers,
+		WorkerActivitiesPerSecond:              w.config.ActivitiesPerSecond,
+	})
+	w.workers[taskQueue] = r
+	return r
+}
+
+// Start 注册所有的工作流和 activity 并启动 worker 。
+func (w *Workers) Start() error {
+
+	for _, wf := range w.Workflows {
+		w.worker(wf.TaskQueue).RegisterWorkflowWithOptions(wf.Fn, workflow.RegisterOptions{Name: wf.Name})
+	}
+
+	for _, a := range w.Activities {
+		var taskQueue string
+		if t, ok := a.(interface{ TaskQueue() string }); ok {
+			taskQueue = t.TaskQueue()
+		}
+		w.worker(taskQueue).RegisterActivityWithOptions(a, activity.RegisterOptions{})
+	}
+
+	var taskQueues []string
+	for taskQueue := range w.workers {
+		taskQueues = append(taskQueues, taskQueue)
+	}
+	sort.Strings(taskQueues)
+
+	for _, taskQueue := range taskQueues {
+		log.Infof("start temporal worker %s", taskQueue)
+		if err := w.workers[taskQueue].Start(); err != nil {
+			return fmt.Errorf("start temporal worker %s error: %w", taskQueue, err)
+		}
+	}
+	return nil
+}
+
+// Stop 停止所有的 worker，等待正在执行的任务完成。
+func (w *Workers) Stop() {
+	for taskQueue, r := range w.workers {
+		log.Infof("stop temporal worker %s", taskQueue)
+		r.Stop()
+	}
+}